@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/migrations"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// The integration tests drive the real router from NewServer over a fresh
+// in-memory SQLite database. SMS goes to fakeSMS and ZarinPal's request and
+// verify calls go to fakeZarinpal, so no test reaches the network.
+
+const testPassword = "secret-pass-1"
+
+var zarinpal *fakeZarinpal
+
+func TestMain(m *testing.M) {
+	zarinpal = newFakeZarinpal()
+	dataDir, err := os.MkdirTemp("", "fitness-integration")
+	if err != nil {
+		log.Fatal(err)
+	}
+	env := map[string]string{
+		"APP_ENV":                    "test",
+		"DB_DRIVER":                  config.DriverSQLite,
+		"DB_PATH":                    ":memory:",
+		"JWT_SECRET":                 "integration-test-secret",
+		"ZARINPAL_MERCHANT_ID":       "00000000-0000-0000-0000-000000000000",
+		"ZARINPAL_API_BASE_URL":      zarinpal.srv.URL,
+		"ZARINPAL_CALLBACK_BASE_URL": "http://api.test",
+		"ZARINPAL_WEB_RESULT_URL":    "http://web.test/payment/result",
+		"SEED_DEMO_DATA":             "false",
+		"UPLOAD_DIR":                 filepath.Join(dataDir, "uploads"),
+		"PRIVACY_EXPORT_DIR":         filepath.Join(dataDir, "exports"),
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	if err := config.Load(); err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+
+	code := m.Run()
+	zarinpal.srv.Close()
+	os.RemoveAll(dataDir)
+	os.Exit(code)
+}
+
+// testServer is one NewServer instance with its own database.
+type testServer struct {
+	t      *testing.T
+	db     *gorm.DB
+	engine *gin.Engine
+	sms    *fakeSMS
+	seq    int
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	db, err := config.NewSQLiteGORM(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	sms := &fakeSMS{}
+	t.Cleanup(service.SetSMSSender(sms.send))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return &testServer{t: t, db: db, engine: NewServer(db).engine, sms: sms}
+}
+
+// do sends a JSON request; token may be empty and body nil.
+func (s *testServer) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var r io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		r = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.engine.ServeHTTP(rec, req)
+	return rec
+}
+
+// expect does a request, fails the test unless it answers with status and
+// decodes the JSON response into out when out is non-nil.
+func (s *testServer) expect(status int, method, path, token string, body, out any) {
+	s.t.Helper()
+	rec := s.do(method, path, token, body)
+	if rec.Code != status {
+		s.t.Fatalf("%s %s: status %d, want %d: %s", method, path, rec.Code, status, rec.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: decode response: %v: %s", method, path, err, rec.Body.String())
+		}
+	}
+}
+
+// nextPhone returns a phone number not used before on this server.
+func (s *testServer) nextPhone() string {
+	s.seq++
+	return fmt.Sprintf("0912%07d", s.seq)
+}
+
+// newUser stores a user with role and testPassword and returns it with an
+// access token from the password login endpoint. Coaches get an approved,
+// published profile so the /coach panel is open to them; admins are super
+// admins.
+func (s *testServer) newUser(role string) (*models.User, string) {
+	s.t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	phone := s.nextPhone()
+	user := &models.User{
+		Name:     role + " " + phone[len(phone)-3:],
+		Email:    phone + "@phone.local",
+		Phone:    phone,
+		Password: string(hashed),
+		Role:     role,
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	if role == models.RoleCoach {
+		profile := &models.CoachProfile{
+			UserID:      user.ID,
+			Slug:        fmt.Sprintf("coach-%d", user.ID),
+			DisplayName: user.Name,
+			Status:      models.CoachProfileStatusApproved,
+			IsPublished: true,
+			IsActive:    true,
+		}
+		if err := s.db.Create(profile).Error; err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	if role == models.RoleAdmin {
+		if err := s.db.Create(&models.AdminRoleAssignment{UserID: user.ID, Role: models.AdminRoleSuperAdmin}).Error; err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	return user, s.login(phone, testPassword)
+}
+
+func (s *testServer) login(identifier, password string) string {
+	s.t.Helper()
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	s.expect(http.StatusOK, http.MethodPost, "/auth/login/password", "",
+		gin.H{"identifier": identifier, "password": password}, &resp)
+	return resp.AccessToken
+}
+
+// newPlan stores an active plan sold by coachID.
+func (s *testServer) newPlan(coachID uint, priceCents int64) *models.ServicePlan {
+	s.t.Helper()
+	s.seq++
+	plan := &models.ServicePlan{
+		CoachID:      coachID,
+		Name:         fmt.Sprintf("plan %d", s.seq),
+		Type:         "both",
+		PriceCents:   priceCents,
+		DurationDays: 30,
+		IsActive:     true,
+	}
+	if err := s.db.Create(plan).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	return plan
+}
+
+// buyPlan checks planID out as the student behind token and pays for it,
+// returning the order ID.
+func (s *testServer) buyPlan(token string, planID uint) uint {
+	s.t.Helper()
+	var order struct {
+		OrderID           uint   `json:"orderId"`
+		PaymentGatewayURL string `json:"paymentGatewayUrl"`
+	}
+	s.expect(http.StatusCreated, http.MethodPost, "/orders/checkout", token,
+		gin.H{"items": []gin.H{{"planId": planID, "qty": 1}}}, &order)
+	if loc := s.payAndReturn(authorityOf(s.t, order.PaymentGatewayURL), true); !strings.Contains(loc, "status=success") {
+		s.t.Fatalf("payment did not succeed: %s", loc)
+	}
+	return order.OrderID
+}
+
+// payAndReturn completes payment for authority at the fake gateway and follows
+// ZarinPal's redirect back to our callback, returning the callback's redirect
+// target.
+func (s *testServer) payAndReturn(authority string, ok bool) string {
+	s.t.Helper()
+	callback := zarinpal.pay(s.t, authority, ok)
+	u, err := url.Parse(callback)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	rec := s.do(http.MethodGet, u.RequestURI(), "", nil)
+	if rec.Code != http.StatusFound {
+		s.t.Fatalf("callback: status %d: %s", rec.Code, rec.Body.String())
+	}
+	return rec.Header().Get("Location")
+}
+
+// fakeSMS records every message instead of sending it.
+type fakeSMS struct {
+	mu   sync.Mutex
+	sent []sentSMS
+}
+
+type sentSMS struct {
+	Receptor string
+	Template string
+	Tokens   []string
+}
+
+func (f *fakeSMS) send(receptor, template string, tokens ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, sentSMS{Receptor: receptor, Template: template, Tokens: append([]string(nil), tokens...)})
+	return nil
+}
+
+// last returns the newest message to receptor using template.
+func (f *fakeSMS) last(t *testing.T, receptor, template string) sentSMS {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.sent) - 1; i >= 0; i-- {
+		if f.sent[i].Receptor == receptor && f.sent[i].Template == template {
+			return f.sent[i]
+		}
+	}
+	t.Fatalf("no %s sms sent to %s (sent: %+v)", template, receptor, f.sent)
+	return sentSMS{}
+}
+
+// otp returns the newest OTP code texted to phone.
+func (f *fakeSMS) otp(t *testing.T, phone string) string {
+	t.Helper()
+	return f.last(t, phone, config.Get().SMS.OtpPattern).Tokens[0]
+}
+
+// fakeZarinpal serves the v4 request.json and verify.json endpoints. A payment
+// verifies only after pay marked it successful and only for the requested
+// amount.
+type fakeZarinpal struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	amount   int64
+	callback string
+	refID    int
+	paid     bool
+}
+
+func newFakeZarinpal() *fakeZarinpal {
+	f := &fakeZarinpal{payments: map[string]*fakePayment{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /request.json", f.request)
+	mux.HandleFunc("POST /verify.json", f.verify)
+	f.srv = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakeZarinpal) request(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Amount      int64  `json:"amount"`
+		CallbackURL string `json:"callback_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Amount <= 0 {
+		writeZarinpalError(w, -9, "validation error")
+		return
+	}
+	f.mu.Lock()
+	f.seq++
+	authority := fmt.Sprintf("A%035d", f.seq)
+	f.payments[authority] = &fakePayment{amount: body.Amount, callback: body.CallbackURL, refID: 700000 + f.seq}
+	f.mu.Unlock()
+	json.NewEncoder(w).Encode(gin.H{
+		"data":   gin.H{"code": 100, "message": "Success", "authority": authority},
+		"errors": []any{},
+	})
+}
+
+func (f *fakeZarinpal) verify(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Amount    int64  `json:"amount"`
+		Authority string `json:"authority"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	p, ok := f.payments[body.Authority]
+	f.mu.Unlock()
+	if !ok || !p.paid || p.amount != body.Amount {
+		writeZarinpalError(w, -51, "payment failed")
+		return
+	}
+	json.NewEncoder(w).Encode(gin.H{
+		"data":   gin.H{"code": 100, "message": "Verified", "ref_id": p.refID},
+		"errors": []any{},
+	})
+}
+
+func writeZarinpalError(w http.ResponseWriter, code int, msg string) {
+	json.NewEncoder(w).Encode(gin.H{"data": []any{}, "errors": gin.H{"code": code, "message": msg}})
+}
+
+// pay settles authority (or cancels it when ok is false) and returns the
+// callback URL ZarinPal would redirect the buyer to.
+func (f *fakeZarinpal) pay(t *testing.T, authority string, ok bool) string {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, found := f.payments[authority]
+	if !found {
+		t.Fatalf("unknown zarinpal authority %q", authority)
+	}
+	p.paid = ok
+	status := "NOK"
+	if ok {
+		status = "OK"
+	}
+	sep := "?"
+	if strings.Contains(p.callback, "?") {
+		sep = "&"
+	}
+	return p.callback + sep + url.Values{"Authority": {authority}, "Status": {status}}.Encode()
+}
+
+// authorityOf extracts the authority from a StartPay URL.
+func authorityOf(t *testing.T, paymentURL string) string {
+	t.Helper()
+	i := strings.LastIndex(paymentURL, "/")
+	if i < 0 || i == len(paymentURL)-1 {
+		t.Fatalf("no authority in payment url %q", paymentURL)
+	}
+	return paymentURL[i+1:]
+}
@@ -0,0 +1,811 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/bootstrap"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/pkg/totp"
+	"github.com/yourusername/fitness-management/internal/repository"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+func TestAuthOTPRegisterAndLogin(t *testing.T) {
+	s := newTestServer(t)
+	phone := s.nextPhone()
+
+	s.expect(http.StatusOK, http.MethodPost, "/auth/otp/request", "", gin.H{"phone": phone}, nil)
+	s.expect(http.StatusTooManyRequests, http.MethodPost, "/auth/otp/request", "", gin.H{"phone": phone}, nil)
+	code := s.sms.otp(t, phone)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	s.expect(http.StatusUnauthorized, http.MethodPost, "/auth/register", "",
+		gin.H{"phone": phone, "password": testPassword, "code": wrong}, nil)
+
+	var registered struct {
+		AccessToken string `json:"access_token"`
+		User        struct {
+			Role string `json:"role"`
+		} `json:"user"`
+	}
+	s.expect(http.StatusCreated, http.MethodPost, "/auth/register", "",
+		gin.H{"phone": phone, "password": testPassword, "code": code}, &registered)
+	if registered.User.Role != models.RoleStudent {
+		t.Fatalf("registered as %q, want student", registered.User.Role)
+	}
+	// The code is spent once used.
+	s.expect(http.StatusUnauthorized, http.MethodPost, "/auth/otp/verify", "", gin.H{"phone": phone, "code": code}, nil)
+
+	var me struct {
+		Phone string `json:"phone"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/auth/me", registered.AccessToken, nil, &me)
+	if me.Phone != phone {
+		t.Fatalf("/auth/me phone %q, want %q", me.Phone, phone)
+	}
+	s.expect(http.StatusUnauthorized, http.MethodGet, "/auth/me", "", nil, nil)
+	s.expect(http.StatusConflict, http.MethodPost, "/auth/register", "",
+		gin.H{"phone": phone, "password": testPassword, "code": "123456"}, nil)
+
+	s.login(phone, testPassword)
+	s.expect(http.StatusUnauthorized, http.MethodPost, "/auth/login/password", "",
+		gin.H{"identifier": phone, "password": "not-the-password"}, nil)
+}
+
+func TestRoleGuards(t *testing.T) {
+	s := newTestServer(t)
+	_, student := s.newUser(models.RoleStudent)
+	_, coach := s.newUser(models.RoleCoach)
+	_, admin := s.newUser(models.RoleAdmin)
+
+	s.expect(http.StatusForbidden, http.MethodGet, "/admin/users", student, nil, nil)
+	s.expect(http.StatusForbidden, http.MethodGet, "/admin/users", coach, nil, nil)
+	s.expect(http.StatusOK, http.MethodGet, "/admin/users", admin, nil, nil)
+	s.expect(http.StatusForbidden, http.MethodGet, "/coach/students", student, nil, nil)
+	s.expect(http.StatusOK, http.MethodGet, "/coach/students", coach, nil, nil)
+}
+
+func TestCheckoutAndPaymentCallback(t *testing.T) {
+	s := newTestServer(t)
+	coach, _ := s.newUser(models.RoleCoach)
+	plan := s.newPlan(coach.ID, 250_000)
+	_, token := s.newUser(models.RoleStudent)
+
+	var order struct {
+		OrderID           uint   `json:"orderId"`
+		Amount            int64  `json:"amount"`
+		PaymentGatewayURL string `json:"paymentGatewayUrl"`
+	}
+	checkout := gin.H{"items": []gin.H{{"planId": plan.ID, "qty": 1}}}
+	s.expect(http.StatusCreated, http.MethodPost, "/orders/checkout", token, checkout, &order)
+	if order.Amount != plan.PriceCents {
+		t.Fatalf("order amount %d, want %d", order.Amount, plan.PriceCents)
+	}
+
+	// A cancelled payment fails the order and grants nothing.
+	if loc := s.payAndReturn(authorityOf(t, order.PaymentGatewayURL), false); !strings.Contains(loc, "status=failed") {
+		t.Fatalf("cancelled payment redirected to %s", loc)
+	}
+	var status struct {
+		Status string `json:"status"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/orders/%d/status", order.OrderID), token, nil, &status)
+	if status.Status != "failed" {
+		t.Fatalf("order status %q after cancel, want failed", status.Status)
+	}
+
+	s.expect(http.StatusCreated, http.MethodPost, "/orders/checkout", token, checkout, &order)
+	authority := authorityOf(t, order.PaymentGatewayURL)
+	loc := s.payAndReturn(authority, true)
+	if !strings.HasPrefix(loc, config.Get().Payments.Zarinpal.WebResultURL) || !strings.Contains(loc, "status=success") {
+		t.Fatalf("paid order redirected to %s", loc)
+	}
+	// ZarinPal may call back twice; the second call must not grant twice.
+	if again := s.payAndReturn(authority, true); !strings.Contains(again, "status=success") {
+		t.Fatalf("repeated callback redirected to %s", again)
+	}
+
+	s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/orders/%d/status", order.OrderID), token, nil, &status)
+	if status.Status != "paid" {
+		t.Fatalf("order status %q, want paid", status.Status)
+	}
+	var current struct {
+		ActiveSubscription *struct {
+			Plan struct {
+				ID uint `json:"id"`
+			} `json:"plan"`
+		} `json:"active_subscription"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/subscriptions/current", token, nil, &current)
+	if current.ActiveSubscription == nil || current.ActiveSubscription.Plan.ID != plan.ID {
+		t.Fatalf("active subscription %+v, want plan %d", current.ActiveSubscription, plan.ID)
+	}
+	var subs int64
+	s.db.Model(&models.Subscription{}).Count(&subs)
+	if subs != 1 {
+		t.Fatalf("%d subscriptions created, want 1", subs)
+	}
+	s.expect(http.StatusConflict, http.MethodPost, "/orders/checkout", token, checkout, nil)
+}
+
+func TestFunnelLeadToPaid(t *testing.T) {
+	s := newTestServer(t)
+	coach, _ := s.newUser(models.RoleCoach)
+	plan := s.newPlan(coach.ID, 400_000)
+	funnel := &models.Funnel{Slug: "spring", Title: "Spring", CoachID: coach.ID, IsActive: true, FreeAccessEnabled: true}
+	if err := s.db.Create(funnel).Error; err != nil {
+		t.Fatal(err)
+	}
+	base := "/public/funnels/" + funnel.Slug
+	phone := s.nextPhone()
+
+	s.expect(http.StatusOK, http.MethodGet, base+"/config", "", nil, nil)
+	s.expect(http.StatusOK, http.MethodPost, base+"/otp/request", "", gin.H{"phone": phone}, nil)
+	var lead struct {
+		CheckoutToken string `json:"checkoutToken"`
+	}
+	s.expect(http.StatusCreated, http.MethodPost, base+"/leads", "", gin.H{
+		"firstName":     "Sara",
+		"lastName":      "Ahmadi",
+		"phone":         phone,
+		"otpCode":       s.sms.otp(t, phone),
+		"planId":        plan.ID,
+		"primaryGoal":   "weight_loss",
+		"activityLevel": "moderate",
+		"mainObstacle":  "motivation",
+	}, &lead)
+
+	checkoutPath := base + "/checkout/" + lead.CheckoutToken
+	s.expect(http.StatusBadRequest, http.MethodPost, checkoutPath+"/session", "", nil, nil)
+	var pay struct {
+		OrderID   uint   `json:"orderId"`
+		Authority string `json:"authority"`
+	}
+	s.expect(http.StatusOK, http.MethodPost, checkoutPath+"/pay", "", nil, &pay)
+	loc := s.payAndReturn(pay.Authority, true)
+	if !strings.Contains(loc, "/spring/success?token="+lead.CheckoutToken) {
+		t.Fatalf("paid lead redirected to %s", loc)
+	}
+
+	var checkout struct {
+		Status       string `json:"status"`
+		TrackingCode string `json:"trackingCode"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, checkoutPath, "", nil, &checkout)
+	if checkout.Status != models.FunnelStatusPaid || checkout.TrackingCode == "" {
+		t.Fatalf("lead after payment: %+v", checkout)
+	}
+
+	var session struct {
+		AccessToken string `json:"access_token"`
+	}
+	s.expect(http.StatusOK, http.MethodPost, checkoutPath+"/session", "", nil, &session)
+	var current struct {
+		ActiveSubscription *struct {
+			Plan struct {
+				ID uint `json:"id"`
+			} `json:"plan"`
+		} `json:"active_subscription"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/subscriptions/current", session.AccessToken, nil, &current)
+	if current.ActiveSubscription == nil || current.ActiveSubscription.Plan.ID != plan.ID {
+		t.Fatalf("funnel buyer subscription %+v, want plan %d", current.ActiveSubscription, plan.ID)
+	}
+}
+
+func TestProgramAssignmentAndTracking(t *testing.T) {
+	s := newTestServer(t)
+	coach, coachToken := s.newUser(models.RoleCoach)
+	_, otherCoach := s.newUser(models.RoleCoach)
+	student, token := s.newUser(models.RoleStudent)
+	s.buyPlan(token, s.newPlan(coach.ID, 300_000).ID)
+
+	program := gin.H{
+		"title":         "Push week",
+		"durationWeeks": 4,
+		"planByDay": gin.H{
+			"sat": gin.H{"workout": gin.H{"title": "Push", "exercises": []gin.H{
+				{"name": "Bench press", "sets": 3, "reps": "10"},
+				{"name": "Overhead press", "sets": 3, "reps": "8"},
+			}}},
+		},
+	}
+	path := fmt.Sprintf("/coach/students/%d/workout-programs", student.ID)
+	s.expect(http.StatusForbidden, http.MethodPost, path, otherCoach, program, nil)
+	s.expect(http.StatusCreated, http.MethodPost, path, coachToken, program, nil)
+	s.sms.last(t, student.Phone, config.Get().SMS.ProgramReadyPattern)
+
+	var current struct {
+		ActiveSubscription *struct {
+			ID uint `json:"id"`
+		} `json:"active_subscription"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/subscriptions/current", token, nil, &current)
+	if current.ActiveSubscription == nil {
+		t.Fatal("no active subscription after purchase")
+	}
+	subID := current.ActiveSubscription.ID
+
+	s.expect(http.StatusBadRequest, http.MethodPost, "/me/workout-sessions", token,
+		gin.H{"subscriptionId": subID, "dayKey": "sun"}, nil)
+	var logged struct {
+		ExerciseCount int `json:"exerciseCount"`
+	}
+	s.expect(http.StatusCreated, http.MethodPost, "/me/workout-sessions", token,
+		gin.H{"subscriptionId": subID, "dayKey": "sat", "durationMin": 45}, &logged)
+	if logged.ExerciseCount != 2 {
+		t.Fatalf("logged %d exercises, want 2", logged.ExerciseCount)
+	}
+	var history struct {
+		Items []struct {
+			DayKey string `json:"dayKey"`
+		} `json:"items"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/me/workout-history", token, nil, &history)
+	if len(history.Items) != 1 || history.Items[0].DayKey != "sat" {
+		t.Fatalf("workout history %+v", history.Items)
+	}
+
+	s.expect(http.StatusOK, http.MethodPost, "/me/tracking/weight", token, gin.H{"weight": 81.5}, nil)
+	rec := s.do(http.MethodGet, fmt.Sprintf("/coach/tracking/students/%d", student.ID), coachToken, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "81.5") {
+		t.Fatalf("coach tracking view: %d %s", rec.Code, rec.Body.String())
+	}
+	s.expect(http.StatusForbidden, http.MethodGet, fmt.Sprintf("/coach/tracking/students/%d", student.ID), otherCoach, nil, nil)
+}
+
+func TestAdminMutationsAreAudited(t *testing.T) {
+	s := newTestServer(t)
+	admin, adminToken := s.newUser(models.RoleAdmin)
+	coach, _ := s.newUser(models.RoleCoach)
+
+	coachPath := fmt.Sprintf("/admin/coaches/%d", coach.ID)
+	s.expect(http.StatusOK, http.MethodGet, coachPath, adminToken, nil, nil)
+	s.expect(http.StatusOK, http.MethodPatch, coachPath, adminToken, gin.H{"status": models.CoachProfileStatusReviewing}, nil)
+
+	var events struct {
+		Total int64 `json:"total"`
+		Items []struct {
+			ActorID    uint           `json:"actorId"`
+			ActorRole  string         `json:"actorRole"`
+			Action     string         `json:"action"`
+			TargetType string         `json:"targetType"`
+			TargetID   string         `json:"targetId"`
+			Before     map[string]any `json:"before"`
+			After      map[string]any `json:"after"`
+			Status     int            `json:"status"`
+			UserAgent  string         `json:"userAgent"`
+		} `json:"items"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/admin/audit-log", adminToken, nil, &events)
+	// Reads are not audited.
+	if events.Total != 1 {
+		t.Fatalf("%d audit events, want 1: %+v", events.Total, events.Items)
+	}
+	ev := events.Items[0]
+	if ev.ActorID != admin.ID || ev.ActorRole != models.RoleAdmin || ev.Action != "PATCH /admin/coaches/:id" ||
+		ev.TargetType != "coach" || ev.TargetID != fmt.Sprint(coach.ID) || ev.Status != http.StatusOK {
+		t.Fatalf("unexpected audit event %+v", ev)
+	}
+	if ev.Before["Status"] != models.CoachProfileStatusApproved || ev.After["Status"] != models.CoachProfileStatusReviewing ||
+		ev.Before["IsPublished"] != true || ev.After["IsPublished"] != false {
+		t.Fatalf("unexpected diff before=%v after=%v", ev.Before, ev.After)
+	}
+	if _, ok := ev.After["DisplayName"]; ok {
+		t.Fatalf("unchanged fields leaked into the diff: %v", ev.After)
+	}
+
+	s.expect(http.StatusOK, http.MethodGet, "/admin/audit-log?targetType=student", adminToken, nil, &events)
+	if events.Total != 0 {
+		t.Fatalf("targetType filter matched %d events", events.Total)
+	}
+	s.expect(http.StatusBadRequest, http.MethodGet, "/admin/audit-log?from=yesterday", adminToken, nil, nil)
+
+	rec := s.do(http.MethodGet, "/admin/audit-log/export?targetType=coach", adminToken, nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("export: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[1], "PATCH /admin/coaches/:id") {
+		t.Fatalf("export body:\n%s", rec.Body.String())
+	}
+}
+
+func TestAdminPermissions(t *testing.T) {
+	s := newTestServer(t)
+	boss, superToken := s.newUser(models.RoleAdmin)
+	staff, staffToken := s.newUser(models.RoleAdmin)
+	idle, idleToken := s.newUser(models.RoleAdmin)
+
+	rolesPath := fmt.Sprintf("/admin/users/%d/roles", staff.ID)
+	s.expect(http.StatusBadRequest, http.MethodPut, rolesPath, superToken, gin.H{"roles": []string{"janitor"}}, nil)
+	s.expect(http.StatusOK, http.MethodPut, rolesPath, superToken, gin.H{"roles": []string{models.AdminRoleSupport}}, nil)
+	s.expect(http.StatusForbidden, http.MethodPut, rolesPath, staffToken, gin.H{"roles": []string{models.AdminRoleSuperAdmin}}, nil)
+
+	var access struct {
+		Roles       []string `json:"roles"`
+		Permissions []string `json:"permissions"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/admin/me/permissions", staffToken, nil, &access)
+	perms := strings.Join(access.Permissions, ",")
+	if len(access.Roles) != 1 || !strings.Contains(perms, models.PermFunnelLeadsRead) ||
+		strings.Contains(perms, models.PermSiteSettingsWrite) || strings.Contains(perms, models.PermPaymentsRead) {
+		t.Fatalf("support access %+v", access)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/admin/funnel-leads", staffToken, nil, nil)
+	s.expect(http.StatusOK, http.MethodGet, "/admin/feedbacks", staffToken, nil, nil)
+	s.expect(http.StatusForbidden, http.MethodPut, "/admin/site-settings", staffToken, gin.H{}, nil)
+	s.expect(http.StatusForbidden, http.MethodDelete, "/admin/exercises/1", staffToken, nil, nil)
+	s.expect(http.StatusForbidden, http.MethodGet, "/admin/dashboard/monthly-sales", staffToken, nil, nil)
+
+	// An admin without roles can ask for its permissions and nothing else;
+	// this catches routes registered without RequirePermission.
+	s.expect(http.StatusOK, http.MethodPut, fmt.Sprintf("/admin/users/%d/roles", idle.ID), superToken, gin.H{"roles": []string{}}, nil)
+	for _, r := range s.engine.Routes() {
+		if !strings.HasPrefix(r.Path, "/admin/") || r.Path == "/admin/me/permissions" {
+			continue
+		}
+		path := r.Path
+		for _, param := range []string{":id", ":photoId", ":programId", ":templateId", ":experimentId"} {
+			path = strings.ReplaceAll(path, param, "1")
+		}
+		if rec := s.do(r.Method, path, idleToken, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s without roles: status %d, want 403", r.Method, r.Path, rec.Code)
+		}
+	}
+
+	// The last super admin keeps the role.
+	s.expect(http.StatusConflict, http.MethodPut, fmt.Sprintf("/admin/users/%d/roles", boss.ID), superToken, gin.H{"roles": []string{models.AdminRoleFinance}}, nil)
+}
+
+func TestAccountSuspension(t *testing.T) {
+	s := newTestServer(t)
+	_, adminToken := s.newUser(models.RoleAdmin)
+	student, studentToken := s.newUser(models.RoleStudent)
+	s.expect(http.StatusOK, http.MethodGet, "/me", studentToken, nil, nil)
+
+	statusPath := fmt.Sprintf("/admin/users/%d/status", student.ID)
+	s.expect(http.StatusBadRequest, http.MethodPut, statusPath, adminToken, gin.H{"status": "frozen"}, nil)
+	s.expect(http.StatusBadRequest, http.MethodPut, statusPath, adminToken,
+		gin.H{"status": models.UserStatusSuspended, "until": time.Now().Add(-time.Hour)}, nil)
+	s.expect(http.StatusOK, http.MethodPut, statusPath, adminToken,
+		gin.H{"status": models.UserStatusSuspended, "reason": "spam"}, nil)
+
+	// The token issued before the suspension stops working at once.
+	var blocked struct {
+		AccountStatus string `json:"accountStatus"`
+		Reason        string `json:"reason"`
+	}
+	s.expect(http.StatusForbidden, http.MethodGet, "/me", studentToken, nil, &blocked)
+	if blocked.AccountStatus != models.UserStatusSuspended || blocked.Reason != "spam" {
+		t.Fatalf("blocked response %+v", blocked)
+	}
+	s.expect(http.StatusForbidden, http.MethodPost, "/auth/login/password", "",
+		gin.H{"identifier": student.Phone, "password": testPassword}, nil)
+
+	var tokens, notes int64
+	s.db.Model(&models.RefreshToken{}).Where("user_id = ?", student.ID).Count(&tokens)
+	s.db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", student.ID, models.NotificationTypeAccountStatus).Count(&notes)
+	if tokens != 0 || notes != 1 {
+		t.Fatalf("after suspension: %d refresh tokens, %d notifications", tokens, notes)
+	}
+
+	s.expect(http.StatusOK, http.MethodPut, statusPath, adminToken, gin.H{"status": models.UserStatusActive}, nil)
+	s.expect(http.StatusOK, http.MethodGet, "/me", s.login(student.Phone, testPassword), nil, nil)
+
+	// A suspension lapses on its own once StatusUntil has passed.
+	lapsed, _ := s.newUser(models.RoleStudent)
+	past := time.Now().Add(-time.Minute)
+	if err := s.db.Model(lapsed).Updates(map[string]any{"account_status": models.UserStatusSuspended, "status_until": past}).Error; err != nil {
+		t.Fatal(err)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/me", s.login(lapsed.Phone, testPassword), nil, nil)
+}
+
+func TestAdminImpersonation(t *testing.T) {
+	s := newTestServer(t)
+	admin, adminToken := s.newUser(models.RoleAdmin)
+	otherAdmin, _ := s.newUser(models.RoleAdmin)
+	student, _ := s.newUser(models.RoleStudent)
+	coach, _ := s.newUser(models.RoleCoach)
+
+	s.expect(http.StatusBadRequest, http.MethodPost, fmt.Sprintf("/admin/users/%d/impersonate", otherAdmin.ID), adminToken, nil, nil)
+
+	var session struct {
+		AccessToken   string `json:"access_token"`
+		RefreshToken  string `json:"refresh_token"`
+		Impersonating bool   `json:"impersonating"`
+	}
+	s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/users/%d/impersonate", student.ID), adminToken, nil, &session)
+	if session.AccessToken == "" || session.RefreshToken != "" || !session.Impersonating {
+		t.Fatalf("impersonation session %+v", session)
+	}
+	var me struct {
+		ID             uint `json:"id"`
+		ImpersonatorID uint `json:"impersonatorId"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/auth/me", session.AccessToken, nil, &me)
+	if me.ID != student.ID || me.ImpersonatorID != admin.ID {
+		t.Fatalf("/auth/me while impersonating: %+v", me)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/me/programs", session.AccessToken, nil, nil)
+	s.expect(http.StatusForbidden, http.MethodPost, "/me/change-password", session.AccessToken,
+		gin.H{"currentPassword": testPassword, "newPassword": "another-secret"}, nil)
+	s.expect(http.StatusForbidden, http.MethodPost, "/orders/checkout", session.AccessToken, gin.H{"planId": 1}, nil)
+	// Payment history stays private.
+	s.expect(http.StatusForbidden, http.MethodGet, "/me/orders", session.AccessToken, nil, nil)
+
+	// The coach panel audits mutations itself; impersonated ones are still
+	// recorded once.
+	s.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/users/%d/impersonate", coach.ID), adminToken, nil, &session)
+	// Buying a marketplace template starts a payment, so it is refused
+	// before the listing is even looked up.
+	s.expect(http.StatusForbidden, http.MethodPost, "/coach/marketplace/templates/999/acquire", session.AccessToken, nil, nil)
+	// Deleting the coach's data is refused as well.
+	s.expect(http.StatusForbidden, http.MethodDelete, "/coach/plans/999", session.AccessToken, nil, nil)
+	s.do(http.MethodPut, "/coach/profile", session.AccessToken, gin.H{"bio": "hello"})
+
+	var events struct {
+		Total int64 `json:"total"`
+		Items []struct {
+			ActorID        uint   `json:"actorId"`
+			ImpersonatorID uint   `json:"impersonatorId"`
+			Action         string `json:"action"`
+		} `json:"items"`
+	}
+	s.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/audit-log?impersonatorId=%d", admin.ID), adminToken, nil, &events)
+	if events.Total != 8 {
+		t.Fatalf("%d impersonated requests audited, want 8: %+v", events.Total, events.Items)
+	}
+	if ev := events.Items[0]; ev.ActorID != coach.ID || ev.ImpersonatorID != admin.ID || ev.Action != "PUT /coach/profile" {
+		t.Fatalf("latest impersonated event %+v", ev)
+	}
+}
+
+type loginChallenge struct {
+	AccessToken    string   `json:"access_token"`
+	Challenge      string   `json:"challenge"`
+	ChallengeToken string   `json:"challenge_token"`
+	Methods        []string `json:"methods"`
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	s := newTestServer(t)
+	coach, coachToken := s.newUser(models.RoleCoach)
+	_, studentToken := s.newUser(models.RoleStudent)
+	s.expect(http.StatusForbidden, http.MethodPost, "/auth/2fa/setup", studentToken, nil, nil)
+
+	var setup struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauthUri"`
+	}
+	s.expect(http.StatusOK, http.MethodPost, "/auth/2fa/setup", coachToken, nil, &setup)
+	if !strings.HasPrefix(setup.OTPAuthURI, "otpauth://totp/") || !strings.Contains(setup.OTPAuthURI, setup.Secret) {
+		t.Fatalf("setup %+v", setup)
+	}
+	totpCode := func(offset time.Duration) string {
+		code, err := totp.Code(setup.Secret, time.Now().Add(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	s.expect(http.StatusBadRequest, http.MethodPost, "/auth/2fa/enable", coachToken, gin.H{"code": "000000"}, nil)
+	var recovery struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	s.expect(http.StatusOK, http.MethodPost, "/auth/2fa/enable", coachToken, gin.H{"code": totpCode(0)}, &recovery)
+	if len(recovery.RecoveryCodes) != 10 {
+		t.Fatalf("%d recovery codes", len(recovery.RecoveryCodes))
+	}
+
+	startLogin := func() loginChallenge {
+		var ch loginChallenge
+		s.expect(http.StatusOK, http.MethodPost, "/auth/login/password", "",
+			gin.H{"identifier": coach.Phone, "password": testPassword}, &ch)
+		if ch.AccessToken != "" || ch.Challenge != "two_factor" || len(ch.Methods) != 3 {
+			t.Fatalf("password login with TOTP on: %+v", ch)
+		}
+		return ch
+	}
+
+	// The enable step used the current period, so the next one is due.
+	ch := startLogin()
+	next := totpCode(30 * time.Second)
+	var session loginChallenge
+	s.expect(http.StatusOK, http.MethodPost, "/auth/login/2fa", "",
+		gin.H{"challenge_token": ch.ChallengeToken, "method": "totp", "code": next}, &session)
+	if session.AccessToken == "" {
+		t.Fatalf("no session after TOTP: %+v", session)
+	}
+	s.expect(http.StatusUnauthorized, http.MethodPost, "/auth/login/2fa", "",
+		gin.H{"challenge_token": ch.ChallengeToken, "method": "totp", "code": next}, nil)
+	// A challenge token is not an access token.
+	s.expect(http.StatusUnauthorized, http.MethodGet, "/auth/me", ch.ChallengeToken, nil, nil)
+
+	ch = startLogin()
+	s.expect(http.StatusOK, http.MethodPost, "/auth/login/2fa", "",
+		gin.H{"challenge_token": ch.ChallengeToken, "method": "recovery", "code": recovery.RecoveryCodes[0]}, nil)
+	s.expect(http.StatusUnauthorized, http.MethodPost, "/auth/login/2fa", "",
+		gin.H{"challenge_token": ch.ChallengeToken, "method": "recovery", "code": recovery.RecoveryCodes[0]}, nil)
+
+	ch = startLogin()
+	s.expect(http.StatusOK, http.MethodPost, "/auth/login/2fa/sms", "", gin.H{"challenge_token": ch.ChallengeToken}, nil)
+	s.expect(http.StatusOK, http.MethodPost, "/auth/login/2fa", "",
+		gin.H{"challenge_token": ch.ChallengeToken, "method": "sms", "code": s.sms.otp(t, coach.Phone)}, nil)
+
+	// After an SMS login code, SMS is no longer on offer as the second factor.
+	s.expect(http.StatusOK, http.MethodPost, "/auth/otp/request", "", gin.H{"phone": coach.Phone}, nil)
+	s.expect(http.StatusOK, http.MethodPost, "/auth/otp/verify", "",
+		gin.H{"phone": coach.Phone, "code": s.sms.otp(t, coach.Phone)}, &ch)
+	if ch.Challenge != "two_factor" || strings.Join(ch.Methods, ",") != "totp,recovery" {
+		t.Fatalf("OTP login with TOTP on: %+v", ch)
+	}
+}
+
+func TestDefaultAdminMustChangePassword(t *testing.T) {
+	s := newTestServer(t)
+	if err := bootstrap.SeedDefaultAdmin(s.db); err != nil {
+		t.Fatal(err)
+	}
+	var ch loginChallenge
+	s.expect(http.StatusOK, http.MethodPost, "/auth/login/password", "",
+		gin.H{"identifier": "09150000000", "password": "12345678"}, &ch)
+	if ch.AccessToken != "" || ch.Challenge != "password_change" {
+		t.Fatalf("default admin login: %+v", ch)
+	}
+	s.expect(http.StatusBadRequest, http.MethodPost, "/auth/login/password-change", "",
+		gin.H{"challenge_token": ch.ChallengeToken, "newPassword": "12345678"}, nil)
+	var session loginChallenge
+	s.expect(http.StatusOK, http.MethodPost, "/auth/login/password-change", "",
+		gin.H{"challenge_token": ch.ChallengeToken, "newPassword": "a-better-one"}, &session)
+	if session.AccessToken == "" {
+		t.Fatalf("no session after password change: %+v", session)
+	}
+	s.login("09150000000", "a-better-one")
+}
+
+func TestLoginThrottling(t *testing.T) {
+	s := newTestServer(t)
+
+	// Five wrong guesses burn an OTP code; the right one no longer works.
+	otpUser, _ := s.newUser(models.RoleStudent)
+	s.expect(http.StatusOK, http.MethodPost, "/auth/otp/request", "", gin.H{"phone": otpUser.Phone}, nil)
+	code := s.sms.otp(t, otpUser.Phone)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	for i := 0; i < 5; i++ {
+		s.expect(http.StatusUnauthorized, http.MethodPost, "/auth/otp/verify", "", gin.H{"phone": otpUser.Phone, "code": wrong}, nil)
+	}
+	s.expect(http.StatusUnauthorized, http.MethodPost, "/auth/otp/verify", "", gin.H{"phone": otpUser.Phone, "code": code}, nil)
+
+	// The fifth wrong password in a row locks password login, even for the
+	// right password.
+	student, _ := s.newUser(models.RoleStudent)
+	badLogin := gin.H{"identifier": student.Phone, "password": "not-the-password"}
+	for i := 0; i < 4; i++ {
+		s.expect(http.StatusUnauthorized, http.MethodPost, "/auth/login/password", "", badLogin, nil)
+	}
+	var locked struct {
+		RetryAfterSeconds int `json:"retry_after_seconds"`
+	}
+	s.expect(http.StatusTooManyRequests, http.MethodPost, "/auth/login/password", "", badLogin, &locked)
+	if locked.RetryAfterSeconds < 1 {
+		t.Fatalf("lockout response %+v", locked)
+	}
+	s.expect(http.StatusTooManyRequests, http.MethodPost, "/auth/login/password", "",
+		gin.H{"identifier": student.Phone, "password": testPassword}, nil)
+
+	// Once the lock has run out a good login clears the failure count.
+	if err := s.db.Model(student).Update("login_locked_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	s.login(student.Phone, testPassword)
+	var after models.User
+	if err := s.db.First(&after, student.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if after.FailedLoginCount != 0 || after.LoginLockedUntil != nil {
+		t.Fatalf("after good login: %d failures, locked until %v", after.FailedLoginCount, after.LoginLockedUntil)
+	}
+
+	// Public endpoints answer 429 once a client IP spends its budget; two
+	// budgets' worth of calls cover a window rolling over mid-loop. No
+	// proxies are trusted, so a made-up X-Forwarded-For on every call does
+	// not buy a fresh budget.
+	limited := false
+	for i := 0; i < 2*config.Get().RateLimit.HeartbeatPerMinute+1 && !limited; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/mobile/heartbeat", strings.NewReader(`{"deviceId":"d1","platform":"android"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, req)
+		limited = rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != ""
+	}
+	if !limited {
+		t.Fatal("heartbeat was never rate limited")
+	}
+}
+
+func TestDataExportAndAccountDeletion(t *testing.T) {
+	s := newTestServer(t)
+	student, token := s.newUser(models.RoleStudent)
+	if err := s.db.Model(student).Updates(map[string]any{"national_id": "0499370899", "medical_history": "asthma"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	photoDir := filepath.Join(config.GetUploadDir(), "users", fmt.Sprint(student.ID))
+	if err := os.MkdirAll(photoDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	photoFile := filepath.Join(photoDir, "front.jpg")
+	if err := os.WriteFile(photoFile, []byte("jpeg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	photo := &models.UserPhoto{UserID: student.ID, FilePath: fmt.Sprintf("/uploads/users/%d/front.jpg", student.ID), Type: "front", UploadedAt: time.Now()}
+	checkIn := &models.CheckIn{UserID: student.ID, CheckInDate: time.Now(), Weight: 80.5}
+	order := &models.Order{UserID: student.ID, Status: "paid", PaymentMethod: "demo", TrackingCode: "TRK-PRIVACY", TotalAmountCents: 990000, Note: "call me at home"}
+	lead := &models.FunnelLead{CheckoutToken: "privacy-lead", FirstName: "Sara", LastName: "Ahmadi", Phone: student.Phone,
+		PrimaryGoal: "weight_loss", MainObstacle: "motivation", AnalysisTitle: "Your plan", AnalysisBody: "Sara struggles with motivation"}
+	// A lead typed in the international format is the same person.
+	intlLead := &models.FunnelLead{CheckoutToken: "privacy-lead-intl", FirstName: "Sara", Phone: "+98" + student.Phone[1:]}
+	for _, row := range []any{photo, checkIn, order, lead, intlLead} {
+		if err := s.db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Admin edits of the profile and photo were audited with their contents.
+	audited := func(targetType string, targetID uint) *models.AuditEvent {
+		return &models.AuditEvent{ActorID: 1, ActorRole: models.RoleAdmin, Action: "PUT /admin/students/:id",
+			TargetType: targetType, TargetID: fmt.Sprint(targetID), Before: `{"phone":"` + student.Phone + `"}`,
+			After: `{"medicalHistory":"asthma"}`, Method: http.MethodPut, Path: "/admin/students", Status: http.StatusOK}
+	}
+	studentEvent, photoEvent, otherEvent := audited("student", student.ID), audited("user_photo", photo.ID), audited("student", student.ID+1)
+	// The student's own requests were audited with where they came from.
+	ownEvent := &models.AuditEvent{ActorID: student.ID, ActorRole: models.RoleStudent, Action: "PUT /me",
+		TargetType: "student", TargetID: fmt.Sprint(student.ID), IP: "203.0.113.7", UserAgent: "Mozilla/5.0 (iPhone)",
+		Method: http.MethodPut, Path: "/me", Status: http.StatusOK}
+	for _, row := range []any{studentEvent, photoEvent, otherEvent, ownEvent} {
+		if err := s.db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The export is built in the background; the list shows when it is ready.
+	s.expect(http.StatusAccepted, http.MethodPost, "/me/data-export", token, nil, nil)
+	var exports struct {
+		Items []struct {
+			ID          uint   `json:"id"`
+			Status      string `json:"status"`
+			DownloadURL string `json:"downloadUrl"`
+		} `json:"items"`
+	}
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		s.expect(http.StatusOK, http.MethodGet, "/me/data-exports", token, nil, &exports)
+		if len(exports.Items) == 1 && exports.Items[0].Status != models.DataExportStatusPending && exports.Items[0].Status != models.DataExportStatusProcessing {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("export not built: %+v", exports)
+		}
+	}
+	if exports.Items[0].Status != models.DataExportStatusReady {
+		t.Fatalf("export %+v", exports.Items[0])
+	}
+	rec := s.do(http.MethodGet, exports.Items[0].DownloadURL, token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download: %d %s", rec.Code, rec.Body.String())
+	}
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(raw)
+	}
+	if !strings.Contains(files["profile.json"], "0499370899") || !strings.Contains(files["check_ins.csv"], "80.5") ||
+		!strings.Contains(files["orders.csv"], "TRK-PRIVACY") || files[fmt.Sprintf("photos/%d_front.jpg", photo.ID)] != "jpeg" {
+		t.Fatalf("export contents: %v", files)
+	}
+
+	// Only students delete themselves here; the password must match and
+	// the deletion can be called off during the grace period.
+	_, coachToken := s.newUser(models.RoleCoach)
+	s.expect(http.StatusForbidden, http.MethodDelete, "/me", coachToken, gin.H{"password": testPassword}, nil)
+	s.expect(http.StatusBadRequest, http.MethodDelete, "/me", token, gin.H{"password": "wrong-password"}, nil)
+	var deletion struct {
+		Scheduled bool `json:"scheduled"`
+	}
+	s.expect(http.StatusAccepted, http.MethodDelete, "/me", token, gin.H{"password": testPassword}, &deletion)
+	if !deletion.Scheduled {
+		t.Fatalf("deletion %+v", deletion)
+	}
+	s.expect(http.StatusOK, http.MethodDelete, "/me/deletion", token, nil, &deletion)
+	s.expect(http.StatusConflict, http.MethodDelete, "/me/deletion", token, nil, nil)
+	s.expect(http.StatusAccepted, http.MethodDelete, "/me", token, gin.H{"password": testPassword}, nil)
+
+	// Past the grace period the account is anonymized: personal rows and
+	// photos are gone, the order stays for accounting.
+	if err := s.db.Model(student).Update("deletion_scheduled_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	privacy := service.NewPrivacyService(s.db, repository.NewUserRepository(s.db))
+	if err := privacy.RunDue(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var gone models.User
+	if err := s.db.Unscoped().First(&gone, student.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if gone.AnonymizedAt == nil || !gone.DeletedAt.Valid || gone.Phone == student.Phone || gone.NationalID != "" || gone.MedicalHistory != "" {
+		t.Fatalf("anonymized user %+v", gone)
+	}
+	var checkIns, photos int64
+	s.db.Unscoped().Model(&models.CheckIn{}).Where("user_id = ?", student.ID).Count(&checkIns)
+	s.db.Unscoped().Model(&models.UserPhoto{}).Where("user_id = ?", student.ID).Count(&photos)
+	if checkIns != 0 || photos != 0 {
+		t.Fatalf("left behind %d check-ins, %d photos", checkIns, photos)
+	}
+	if _, err := os.Stat(photoFile); !os.IsNotExist(err) {
+		t.Fatalf("photo file still there: %v", err)
+	}
+	var kept models.Order
+	if err := s.db.First(&kept, order.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if kept.UserID != student.ID || kept.TotalAmountCents != 990000 || kept.Note != "" {
+		t.Fatalf("kept order %+v", kept)
+	}
+	// Audit events about the account stay, without the personal data.
+	for _, ev := range []*models.AuditEvent{studentEvent, photoEvent, otherEvent, ownEvent} {
+		if err := s.db.First(ev, ev.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Contains(studentEvent.Before+studentEvent.After+photoEvent.Before+photoEvent.After, "asthma") ||
+		!strings.Contains(otherEvent.After, "asthma") {
+		t.Fatalf("audit payloads after deletion: %+v %+v %+v", studentEvent, photoEvent, otherEvent)
+	}
+	if ownEvent.IP != "" || ownEvent.UserAgent != "" || ownEvent.ActorID != student.ID {
+		t.Fatalf("student's own audit event after deletion %+v", ownEvent)
+	}
+	if err := s.db.First(lead, lead.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if lead.Phone != "" || lead.FirstName != "" || lead.PrimaryGoal != "" || lead.AnalysisBody != "" {
+		t.Fatalf("funnel lead after deletion %+v", lead)
+	}
+	if err := s.db.First(intlLead, intlLead.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if intlLead.Phone != "" || intlLead.FirstName != "" {
+		t.Fatalf("international-format lead after deletion %+v", intlLead)
+	}
+	s.expect(http.StatusUnauthorized, http.MethodPost, "/auth/login/password", "",
+		gin.H{"identifier": student.Phone, "password": testPassword}, nil)
+}
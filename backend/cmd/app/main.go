@@ -4,19 +4,22 @@ import (
 	"context"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
 	"github.com/yourusername/fitness-management/config"
 	_ "github.com/yourusername/fitness-management/docs"
+	"github.com/yourusername/fitness-management/internal/bootstrap"
 	"github.com/yourusername/fitness-management/internal/controllers"
 	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/migrations"
 	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/pkg/digits"
 	"github.com/yourusername/fitness-management/internal/repository"
 	"github.com/yourusername/fitness-management/internal/seed"
 	"github.com/yourusername/fitness-management/internal/service"
@@ -40,6 +43,11 @@ type Server struct {
 func NewServer(db *gorm.DB) *Server {
 	// Initialize Gin router
 	router := gin.Default()
+	// Client IPs feed rate limits and the audit log, so X-Forwarded-For only
+	// counts when it comes from our own load balancers.
+	if err := router.SetTrustedProxies(config.Get().Server.TrustedProxies); err != nil {
+		log.Fatalf("invalid server.trusted_proxies: %v", err)
+	}
 
 	// CORS — origins from config.yaml (cors.allowed_origins) or FRONTEND_ORIGIN env.
 	router.Use(cors.New(cors.Config{
@@ -74,9 +82,20 @@ func NewServer(db *gorm.DB) *Server {
 	mobileDeviceRepo := repository.NewMobileDeviceRepository(db)
 	mobileReleaseRepo := repository.NewMobileReleaseRepository(db)
 	funnelLeadRepo := repository.NewFunnelLeadRepository(db)
+	funnelRepo := repository.NewFunnelRepository(db)
+	funnelExperimentRepo := repository.NewFunnelExperimentRepository(db)
+	templateListingRepo := repository.NewTemplateListingRepository(db)
+	aiConversationRepo := repository.NewAIConversationRepository(db)
+	aiToolInvocationRepo := repository.NewAIToolInvocationRepository(db)
+	aiUsageRepo := repository.NewAIUsageRepository(db)
+	rateLimitRepo := repository.NewRateLimitRepository(db)
+	auditEventRepo := repository.NewAuditEventRepository(db)
+	adminRoleRepo := repository.NewAdminRoleRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, coachProfileRepo, refreshTokenRepo, otpRepo)
+	twoFactorService := service.NewTwoFactorService(twoFactorRepo, userRepo)
+	authService := service.NewAuthService(userRepo, coachProfileRepo, refreshTokenRepo, otpRepo, twoFactorService)
 	coachProfileService := service.NewCoachProfileService(coachProfileRepo, servicePlanRepo, coachAchievementRepo)
 	coachAchievementService := service.NewCoachAchievementService(coachAchievementRepo)
 	coachPlanService := service.NewCoachPlanService(servicePlanRepo)
@@ -84,7 +103,6 @@ func NewServer(db *gorm.DB) *Server {
 	checkoutService := service.NewCheckoutService(db, userRepo, servicePlanRepo, orderRepo, subscriptionRepo, coachProfileRepo, paymentService)
 	studentService := service.NewStudentService(userRepo, subscriptionRepo, servicePlanRepo, programRepo)
 	meService := service.NewMeService(db, userRepo, orderRepo, subscriptionRepo, servicePlanRepo, programRepo, exerciseRepo, foodRepo)
-	aiChatService := service.NewAIChatService(meService)
 	adminUserService := service.NewAdminUserService(db, subscriptionRepo, txRepo)
 	adminDashboardService := service.NewAdminDashboardService(db, subscriptionRepo, txRepo, coachProfileRepo)
 	adminStudentService := service.NewAdminStudentService(db, userRepo, subscriptionRepo, servicePlanRepo, coachProfileRepo)
@@ -96,13 +114,15 @@ func NewServer(db *gorm.DB) *Server {
 	siteSettingsService := service.NewSiteSettingsService(siteSettingsRepo)
 	feedbackService := service.NewFeedbackService(feedbackRepo)
 	ticketService := service.NewTicketService(userRepo, ticketRepo)
+	auditService := service.NewAuditService(auditEventRepo, userRepo)
+	adminAccessService := service.NewAdminAccessService(adminRoleRepo, userRepo)
+	accountStatusService := service.NewAccountStatusService(db, userRepo, refreshTokenRepo)
 
 	// Initialize handlers
 	authController := controllers.NewAuthController(authService, meService)
 	studentController := controllers.NewStudentController(studentService)
 	meController := controllers.NewMeController(meService)
 	meTicketController := controllers.NewMeTicketController(ticketService)
-	aiChatController := controllers.NewAIChatController(aiChatService)
 	adminUserController := controllers.NewAdminUserController(adminUserService)
 	adminDashboardController := controllers.NewAdminDashboardController(adminDashboardService)
 	adminStudentController := controllers.NewAdminStudentController(adminStudentService)
@@ -113,6 +133,10 @@ func NewServer(db *gorm.DB) *Server {
 	adminCoachController := controllers.NewAdminCoachController(adminCoachService)
 	adminExerciseController := controllers.NewAdminExerciseController(adminExerciseService)
 	adminTemplateController := controllers.NewAdminTemplateController(adminTemplateService)
+	adminAuditController := controllers.NewAdminAuditController(auditService)
+	adminAccessController := controllers.NewAdminAccessController(adminAccessService)
+	adminAccountStatusController := controllers.NewAdminAccountStatusController(accountStatusService)
+	twoFactorController := controllers.NewTwoFactorController(twoFactorService)
 	mobileAppController := controllers.NewMobileAppController(mobileAppService)
 	coachProfileController := controllers.NewCoachProfileController(coachProfileService)
 	coachAchievementController := controllers.NewCoachAchievementController(coachAchievementService)
@@ -124,8 +148,19 @@ func NewServer(db *gorm.DB) *Server {
 	coachDashboardService := service.NewCoachDashboardService(db, subscriptionRepo, orderRepo)
 	coachStudentController := controllers.NewCoachStudentController(coachStudentService)
 	coachProgramController := controllers.NewCoachProgramController(coachProgramService)
+	coachTemplateService := service.NewCoachTemplateService(templateRepo, templateListingRepo, exerciseRepo, subscriptionRepo, programRepo, coachStudentService)
+	coachTemplateController := controllers.NewCoachTemplateController(coachTemplateService)
+	templateSuggestionService := service.NewTemplateSuggestionService(templateRepo, exerciseRepo, userRepo, funnelLeadRepo, coachStudentService, coachProgramService)
+	coachTemplateSuggestionController := controllers.NewCoachTemplateSuggestionController(templateSuggestionService)
+	nutritionCalculatorService := service.NewNutritionCalculatorService(templateRepo, foodRepo, userRepo, funnelLeadRepo, coachStudentService, coachProgramService)
+	coachNutritionCalculatorController := controllers.NewCoachNutritionCalculatorController(nutritionCalculatorService)
+	templateMarketplaceService := service.NewTemplateMarketplaceService(db, templateListingRepo, templateRepo, userRepo, paymentService)
+	coachMarketplaceController := controllers.NewCoachMarketplaceController(templateMarketplaceService)
+	adminMarketplaceController := controllers.NewAdminMarketplaceController(templateMarketplaceService)
 	coachDashboardController := controllers.NewCoachDashboardController(coachDashboardService)
 	coachExerciseController := controllers.NewCoachExerciseController(adminExerciseService)
+	exerciseAlternativeService := service.NewExerciseAlternativeService(exerciseRepo, repository.NewExerciseSwapRepository(db), subscriptionRepo, programRepo, userRepo, coachStudentService)
+	exerciseAlternativeController := controllers.NewExerciseAlternativeController(exerciseAlternativeService)
 	coachFoodService := service.NewCoachFoodService(foodRepo)
 	coachFoodController := controllers.NewCoachFoodController(coachFoodService)
 	adminProgramService := service.NewAdminProgramService(subscriptionRepo, coachProgramService)
@@ -143,54 +178,107 @@ func NewServer(db *gorm.DB) *Server {
 	dailyFoodLogController := controllers.NewDailyFoodLogController(dailyFoodLogService)
 	meDashboardService := service.NewMeDashboardService(db, subscriptionRepo)
 	meDashboardController := controllers.NewMeDashboardController(meDashboardService)
+	aiToolbox := service.NewAIToolbox(trackingService, meService, meDashboardService, aiToolInvocationRepo)
+	rateLimiter := service.NewRateLimiterFromConfig(config.Get(), rateLimitRepo)
+	aiUsageService := service.NewAIUsageService(aiUsageRepo, subscriptionRepo, servicePlanRepo, coachProfileRepo, rateLimiter)
+	aiChatService := service.NewAIChatService(meService, aiConversationRepo, userRepo, service.NewAIKnowledgeBase(siteSettingsService), aiToolbox, aiUsageService)
+	aiChatController := controllers.NewAIChatController(aiChatService, aiUsageService)
+	coachAIService := service.NewCoachAIService(coachStudentService, trackingService, workoutHistoryService, ticketService, coachProgramService, aiUsageService, rateLimiter)
+	coachAIController := controllers.NewCoachAIController(coachAIService)
+	trainingVolumeService := service.NewTrainingVolumeService(db, exerciseRepo, coachStudentService)
+	trainingVolumeController := controllers.NewTrainingVolumeController(trainingVolumeService)
 	notificationService := service.NewNotificationService(notificationRepo)
 	notificationController := controllers.NewNotificationController(notificationService)
-	funnelService := service.NewFunnelService(funnelLeadRepo, coachProfileRepo, servicePlanRepo, userRepo, orderRepo, paymentService, authService)
+	funnelService := service.NewFunnelService(funnelRepo, funnelExperimentRepo, funnelLeadRepo, coachProfileRepo, servicePlanRepo, userRepo, orderRepo, paymentService, authService)
 	funnelController := controllers.NewFunnelController(funnelService)
 	adminFunnelController := controllers.NewAdminFunnelController(funnelService)
+	coachFunnelController := controllers.NewCoachFunnelController(funnelService)
+	funnelRecoveryService := service.NewFunnelRecoveryService(funnelRepo, funnelLeadRepo, repository.NewFunnelRecoveryRepository(db))
+	funnelRecoveryController := controllers.NewFunnelRecoveryController(funnelRecoveryService)
+	if config.Get().Funnel.Recovery.Enabled {
+		funnelRecoveryService.Start(context.Background())
+	}
+	privacyService := service.NewPrivacyService(db, userRepo)
+	privacyController := controllers.NewPrivacyController(privacyService)
+	privacyService.Start(context.Background())
+	humanVerifier := service.NewHumanVerifierFromConfig(config.Get(), rateLimiter)
+	humanCheckController := controllers.NewHumanCheckController(humanVerifier)
+
+	// Abuse protection for the public endpoints: per-IP budgets, a per-phone
+	// cap on texted codes, and the optional human check on bot-prone forms.
+	limits := config.Get().RateLimit
+	loginLimit := middleware.RateLimit(rateLimiter, "login", limits.LoginPerMinute, time.Minute, middleware.ClientIPKey)
+	otpLimit := middleware.RateLimit(rateLimiter, "otp", limits.OTPPerHour, time.Hour, middleware.ClientIPKey)
+	otpPhoneLimit := middleware.RateLimit(rateLimiter, "otp_phone", limits.OTPPerPhonePerHour, time.Hour, middleware.BodyFieldKey(digits.NormalizePhone, "phone"))
+	formLimit := middleware.RateLimit(rateLimiter, "forms", limits.FormsPerHour, time.Hour, middleware.ClientIPKey)
+	heartbeatLimit := middleware.RateLimit(rateLimiter, "heartbeat", limits.HeartbeatPerMinute, time.Minute, middleware.ClientIPKey)
+	requireHuman := middleware.RequireHuman(humanVerifier)
 
 	// Auth routes
-	router.POST("/auth/check-phone", authController.CheckPhone)
-	router.POST("/auth/register", authController.Register)
-	router.POST("/auth/register/coach", authController.RegisterCoach)
-	router.POST("/auth/login/password", authController.LoginWithPassword)
-	router.POST("/auth/otp/request", authController.RequestOTP)
-	router.POST("/auth/otp/verify", authController.VerifyOTP)
-	router.POST("/auth/forgot/send-otp", authController.ForgotSendOTP)
-	router.POST("/auth/reset-password", authController.ResetPasswordWithOTP)
+	router.POST("/auth/check-phone", loginLimit, authController.CheckPhone)
+	router.POST("/auth/register", loginLimit, authController.Register)
+	router.POST("/auth/register/coach", loginLimit, authController.RegisterCoach)
+	router.POST("/auth/login/password", loginLimit, authController.LoginWithPassword)
+	router.POST("/auth/otp/request", otpLimit, otpPhoneLimit, requireHuman, authController.RequestOTP)
+	router.POST("/auth/otp/verify", loginLimit, authController.VerifyOTP)
+	router.POST("/auth/login/2fa/sms", otpLimit, authController.SendTwoFactorSMS)
+	router.POST("/auth/login/2fa", loginLimit, authController.CompleteTwoFactor)
+	router.POST("/auth/login/password-change", loginLimit, authController.CompletePasswordChange)
+	router.POST("/auth/forgot/send-otp", otpLimit, otpPhoneLimit, requireHuman, authController.ForgotSendOTP)
+	router.POST("/auth/reset-password", loginLimit, authController.ResetPasswordWithOTP)
 
 	// Protected auth routes
 	authGroup := router.Group("/auth")
-	authGroup.Use(middleware.AuthMiddleware())
+	authGroup.Use(middleware.AuthMiddleware(accountStatusService), middleware.ImpersonationAudit(auditService))
 	{
 		authGroup.POST("/logout", authController.Logout)
 		authGroup.GET("/me", authController.Me)
-		authGroup.POST("/change-password", authController.ChangePassword)
+		authGroup.POST("/change-password", middleware.NoImpersonation(), authController.ChangePassword)
+		authGroup.GET("/2fa", twoFactorController.Status)
+		authGroup.POST("/2fa/setup", middleware.NoImpersonation(), twoFactorController.Setup)
+		authGroup.POST("/2fa/enable", middleware.NoImpersonation(), twoFactorController.Enable)
+		authGroup.POST("/2fa/disable", middleware.NoImpersonation(), twoFactorController.Disable)
+		authGroup.POST("/2fa/recovery-codes", middleware.NoImpersonation(), twoFactorController.RegenerateRecoveryCodes)
 	}
 
 	// Public routes (no auth)
 	router.GET("/site-settings", siteSettingsController.GetSiteSettingsPublic)
 	router.GET("/academy", siteSettingsController.GetAcademyPublic)
 	router.GET("/faq", siteSettingsController.GetFAQPublic)
-	router.POST("/mobile/heartbeat", mobileAppController.PublicHeartbeat)
-	router.POST("/feedbacks", feedbackController.CreateFeedback)
+	router.GET("/public/human-check", humanCheckController.Challenge)
+	router.POST("/mobile/heartbeat", heartbeatLimit, mobileAppController.PublicHeartbeat)
+	router.POST("/feedbacks", formLimit, requireHuman, feedbackController.CreateFeedback)
 	router.GET("/coaches", publicCoachController.ListCoaches)
 	router.GET("/coaches/:slug", publicCoachController.GetCoachBySlug)
 	router.GET("/coaches/:slug/plans", publicCoachController.GetCoachPlans)
 	router.GET("/public/funnel/config", funnelController.GetConfig)
-	router.POST("/public/funnel/otp/request", funnelController.RequestLeadOTP)
-	router.POST("/public/funnel/leads", funnelController.CreateLead)
+	router.POST("/public/funnel/otp/request", otpLimit, otpPhoneLimit, requireHuman, funnelController.RequestLeadOTP)
+	router.POST("/public/funnel/leads", formLimit, requireHuman, funnelController.CreateLead)
 	router.GET("/public/funnel/checkout/:token", funnelController.GetCheckout)
+	router.GET("/public/funnel/checkout/:token/resume", funnelRecoveryController.Resume)
 	router.POST("/public/funnel/checkout/:token/plan", funnelController.SelectPlan)
 	router.POST("/public/funnel/checkout/:token/pay", funnelController.PayDemo)
 	router.POST("/public/funnel/checkout/:token/free", funnelController.StartFreeAccess)
 	router.POST("/public/funnel/checkout/:token/session", funnelController.IssueSession)
+	router.GET("/public/funnels/:slug/config", funnelController.GetConfig)
+	router.POST("/public/funnels/:slug/otp/request", otpLimit, otpPhoneLimit, requireHuman, funnelController.RequestLeadOTP)
+	router.POST("/public/funnels/:slug/leads", formLimit, requireHuman, funnelController.CreateLead)
+	router.GET("/public/funnels/:slug/checkout/:token", funnelController.GetCheckout)
+	router.POST("/public/funnels/:slug/checkout/:token/plan", funnelController.SelectPlan)
+	router.POST("/public/funnels/:slug/checkout/:token/pay", funnelController.PayDemo)
+	router.POST("/public/funnels/:slug/checkout/:token/free", funnelController.StartFreeAccess)
+	router.POST("/public/funnels/:slug/checkout/:token/session", funnelController.IssueSession)
 	router.GET("/payments/zarinpal/callback", paymentController.ZarinpalCallback)
 	router.GET("/payments/result", paymentController.PaymentsResultPage)
 
 	// Coach panel routes
 	coachGroup := router.Group("/coach")
-	coachGroup.Use(middleware.AuthMiddleware(), middleware.CoachOnly())
+	coachGroup.Use(
+		middleware.AuthMiddleware(accountStatusService),
+		middleware.ImpersonationAudit(auditService),
+		middleware.CoachOnly(),
+		middleware.AuditTrail(auditService),
+	)
 	{
 		// Accessible before approval (profile completion flow)
 		coachGroup.GET("/profile", coachProfileController.GetProfile)
@@ -203,21 +291,35 @@ func NewServer(db *gorm.DB) *Server {
 		coachGroup.POST("/profile/achievements/image", coachAchievementController.UploadImage)
 		coachGroup.POST("/profile/achievements", coachAchievementController.CreateAchievement)
 		coachGroup.PUT("/profile/achievements/:id", coachAchievementController.UpdateAchievement)
-		coachGroup.DELETE("/profile/achievements/:id", coachAchievementController.DeleteAchievement)
+		coachGroup.DELETE("/profile/achievements/:id", middleware.NoImpersonation(), coachAchievementController.DeleteAchievement)
 	}
 
 	approvedCoachGroup := router.Group("/coach")
 	approvedCoachGroup.Use(
-		middleware.AuthMiddleware(),
+		middleware.AuthMiddleware(accountStatusService),
+		middleware.ImpersonationAudit(auditService),
 		middleware.CoachOnly(),
 		middleware.ApprovedCoachOnly(coachProfileRepo),
+		middleware.AuditTrail(auditService),
 	)
 	{
+		approvedCoachGroup.GET("/funnels", coachFunnelController.ListFunnels)
+		approvedCoachGroup.POST("/funnels", coachFunnelController.CreateFunnel)
+		approvedCoachGroup.GET("/funnels/:id", coachFunnelController.GetFunnel)
+		approvedCoachGroup.PUT("/funnels/:id", coachFunnelController.UpdateFunnel)
+		approvedCoachGroup.DELETE("/funnels/:id", middleware.NoImpersonation(), coachFunnelController.DeleteFunnel)
+		approvedCoachGroup.GET("/funnels/:id/stats", coachFunnelController.Stats)
+		approvedCoachGroup.GET("/funnels/:id/experiments", coachFunnelController.ListExperiments)
+		approvedCoachGroup.POST("/funnels/:id/experiments", coachFunnelController.CreateExperiment)
+		approvedCoachGroup.PUT("/funnels/:id/experiments/:experimentId", coachFunnelController.UpdateExperiment)
+		approvedCoachGroup.DELETE("/funnels/:id/experiments/:experimentId", middleware.NoImpersonation(), coachFunnelController.DeleteExperiment)
+		approvedCoachGroup.POST("/funnels/:id/experiments/:experimentId/start", coachFunnelController.StartExperiment)
+		approvedCoachGroup.POST("/funnels/:id/experiments/:experimentId/stop", coachFunnelController.StopExperiment)
 		approvedCoachGroup.GET("/plans", coachPlanController.ListPlans)
 		approvedCoachGroup.POST("/plans", coachPlanController.CreatePlan)
 		approvedCoachGroup.GET("/plans/:id", coachPlanController.GetPlanByID)
 		approvedCoachGroup.PATCH("/plans/:id", coachPlanController.UpdatePlan)
-		approvedCoachGroup.DELETE("/plans/:id", coachPlanController.DeletePlan)
+		approvedCoachGroup.DELETE("/plans/:id", middleware.NoImpersonation(), coachPlanController.DeletePlan)
 		approvedCoachGroup.GET("/students", coachStudentController.ListStudents)
 		approvedCoachGroup.GET("/students/:id", coachStudentController.GetStudentByID)
 		approvedCoachGroup.GET("/students/:id/programs", coachProgramController.GetStudentPrograms)
@@ -227,10 +329,35 @@ func NewServer(db *gorm.DB) *Server {
 		approvedCoachGroup.POST("/students/:id/nutrition-programs", coachProgramController.AssignNutritionProgram)
 		approvedCoachGroup.PATCH("/students/:id/nutrition-programs/:programId", coachProgramController.UpdateNutritionProgram)
 		approvedCoachGroup.POST("/students/:id/nutrition-programs/templates/:templateId", coachProgramController.AssignNutritionFromTemplate)
+		approvedCoachGroup.POST("/students/:id/workout-programs/save-as-template", coachTemplateController.SaveStudentWorkoutAsTemplate)
+		approvedCoachGroup.POST("/students/:id/nutrition-programs/save-as-template", coachTemplateController.SaveStudentNutritionAsTemplate)
+		approvedCoachGroup.GET("/students/:id/template-suggestions", coachTemplateSuggestionController.GetSuggestions)
+		approvedCoachGroup.POST("/students/:id/template-suggestions/assign-top", coachTemplateSuggestionController.AssignTop)
+		approvedCoachGroup.POST("/students/:id/nutrition-calculator", coachNutritionCalculatorController.CalculateForStudent)
+		approvedCoachGroup.POST("/students/:id/nutrition-programs/templates/:templateId/scaled", coachNutritionCalculatorController.AssignScaledTemplate)
+		approvedCoachGroup.POST("/nutrition-calculator", coachNutritionCalculatorController.Calculate)
+		approvedCoachGroup.GET("/nutrition-calculator/templates", coachNutritionCalculatorController.MatchTemplates)
 		approvedCoachGroup.GET("/workout-templates", coachProgramController.ListWorkoutTemplates)
+		approvedCoachGroup.POST("/workout-templates", coachTemplateController.CreateWorkoutTemplate)
 		approvedCoachGroup.GET("/workout-templates/:id", coachProgramController.GetWorkoutTemplate)
+		approvedCoachGroup.PUT("/workout-templates/:id", coachTemplateController.UpdateWorkoutTemplate)
+		approvedCoachGroup.DELETE("/workout-templates/:id", middleware.NoImpersonation(), coachTemplateController.DeleteWorkoutTemplate)
+		approvedCoachGroup.POST("/workout-templates/:id/duplicate", coachTemplateController.DuplicateWorkoutTemplate)
 		approvedCoachGroup.GET("/nutrition-templates", coachProgramController.ListNutritionTemplates)
+		approvedCoachGroup.POST("/nutrition-templates", coachTemplateController.CreateNutritionTemplate)
 		approvedCoachGroup.GET("/nutrition-templates/:id", coachProgramController.GetNutritionTemplate)
+		approvedCoachGroup.PUT("/nutrition-templates/:id", coachTemplateController.UpdateNutritionTemplate)
+		approvedCoachGroup.DELETE("/nutrition-templates/:id", middleware.NoImpersonation(), coachTemplateController.DeleteNutritionTemplate)
+		approvedCoachGroup.POST("/nutrition-templates/:id/duplicate", coachTemplateController.DuplicateNutritionTemplate)
+		approvedCoachGroup.POST("/nutrition-templates/:id/scale", coachNutritionCalculatorController.ScaleTemplate)
+		approvedCoachGroup.GET("/marketplace/templates", coachMarketplaceController.Browse)
+		approvedCoachGroup.GET("/marketplace/templates/:id", coachMarketplaceController.GetListing)
+		approvedCoachGroup.POST("/marketplace/templates/:id/acquire", middleware.NoImpersonation(), coachMarketplaceController.Acquire)
+		approvedCoachGroup.GET("/marketplace/listings", coachMarketplaceController.ListMyListings)
+		approvedCoachGroup.POST("/marketplace/listings", coachMarketplaceController.Publish)
+		approvedCoachGroup.PUT("/marketplace/listings/:id", coachMarketplaceController.UpdateListing)
+		approvedCoachGroup.DELETE("/marketplace/listings/:id", middleware.NoImpersonation(), coachMarketplaceController.Unpublish)
+		approvedCoachGroup.GET("/marketplace/report", middleware.NoImpersonation(), coachMarketplaceController.Report)
 		approvedCoachGroup.GET("/dashboard/stats", coachDashboardController.GetStats)
 		approvedCoachGroup.GET("/dashboard/recent-students", coachDashboardController.GetRecentStudents)
 		approvedCoachGroup.GET("/dashboard/top-students", coachDashboardController.GetTopStudents)
@@ -240,21 +367,33 @@ func NewServer(db *gorm.DB) *Server {
 		approvedCoachGroup.GET("/tickets/:id", coachTicketController.GetTicket)
 		approvedCoachGroup.PATCH("/tickets/:id/answer", coachTicketController.AnswerTicket)
 		approvedCoachGroup.PATCH("/tickets/:id/status", coachTicketController.UpdateTicketStatus)
+		approvedCoachGroup.POST("/ai/tickets/:id/reply-draft", coachAIController.DraftTicketReply)
+		approvedCoachGroup.POST("/ai/students/:id/checkin-summary", coachAIController.SummarizeStudent)
+		approvedCoachGroup.POST("/ai/students/:id/program-draft", coachAIController.DraftWorkoutProgram)
 		approvedCoachGroup.GET("/exercises/categories", coachExerciseController.ListCategories)
 		approvedCoachGroup.GET("/exercises", coachExerciseController.ListExercises)
 		approvedCoachGroup.GET("/foods", coachFoodController.ListFoods)
 		approvedCoachGroup.POST("/exercises", coachExerciseController.CreateExercise)
 		approvedCoachGroup.GET("/exercises/:id", coachExerciseController.GetExerciseByID)
+		approvedCoachGroup.GET("/exercises/:id/alternatives", exerciseAlternativeController.CoachAlternatives)
+		approvedCoachGroup.PUT("/students/:id/exercise-swaps/permission", exerciseAlternativeController.SetSwapPermission)
 		approvedCoachGroup.GET("/tracking/students", coachTrackingController.ListStudents)
 		approvedCoachGroup.GET("/tracking/students/:id", coachTrackingController.GetStudentTracking)
+		approvedCoachGroup.GET("/students/:id/training-volume", trainingVolumeController.GetStudentVolume)
 	}
 
 	// Student (user panel) routes - all protected
 	studentGroup := router.Group("/")
-	studentGroup.Use(middleware.AuthMiddleware())
+	studentGroup.Use(middleware.AuthMiddleware(accountStatusService), middleware.ImpersonationAudit(auditService))
 	{
 		studentGroup.GET("/me", meController.GetProfile)
 		studentGroup.PATCH("/me", meController.UpdateProfile)
+		studentGroup.DELETE("/me", middleware.NoImpersonation(), privacyController.DeleteAccount)
+		studentGroup.GET("/me/deletion", privacyController.DeletionStatus)
+		studentGroup.DELETE("/me/deletion", middleware.NoImpersonation(), privacyController.CancelDeletion)
+		studentGroup.POST("/me/data-export", middleware.NoImpersonation(), privacyController.RequestExport)
+		studentGroup.GET("/me/data-exports", privacyController.ListExports)
+		studentGroup.GET("/me/data-exports/:id/download", middleware.NoImpersonation(), privacyController.DownloadExport)
 		studentGroup.POST("/me/avatar", meController.UploadAvatar)
 		studentGroup.POST("/me/body-photos", meController.UploadBodyPhoto)
 		studentGroup.GET("/me/tracking", trackingController.GetMyTracking)
@@ -262,99 +401,143 @@ func NewServer(db *gorm.DB) *Server {
 		studentGroup.POST("/me/tracking/photos", trackingController.UploadTrackingPhoto)
 		studentGroup.GET("/me/workout-history", workoutHistoryController.ListHistory)
 		studentGroup.POST("/me/workout-sessions", workoutHistoryController.LogSession)
+		studentGroup.GET("/exercises/:id/alternatives", exerciseAlternativeController.Alternatives)
+		studentGroup.GET("/me/exercise-swaps", exerciseAlternativeController.ListMySwaps)
+		studentGroup.POST("/me/exercise-swaps", exerciseAlternativeController.CreateSwap)
+		studentGroup.DELETE("/me/exercise-swaps/:id", middleware.NoImpersonation(), exerciseAlternativeController.RevertSwap)
 		studentGroup.POST("/user/food-logs", dailyFoodLogController.CreateLog)
 		studentGroup.GET("/user/food-logs", dailyFoodLogController.ListByDate)
-		studentGroup.DELETE("/user/food-logs/:id", dailyFoodLogController.DeleteLog)
+		studentGroup.DELETE("/user/food-logs/:id", middleware.NoImpersonation(), dailyFoodLogController.DeleteLog)
 		studentGroup.GET("/user/foods", coachFoodController.ListFoods)
 		studentGroup.GET("/me/dashboard", meDashboardController.GetSummary)
 		studentGroup.GET("/me/records", meDashboardController.GetRecords)
-		studentGroup.POST("/me/change-password", authController.ChangePassword)
-		studentGroup.GET("/me/orders", meController.ListMyOrders)
-		studentGroup.GET("/me/orders/:id", meController.GetMyOrderByID)
+		studentGroup.GET("/me/training-volume", trainingVolumeController.GetMyVolume)
+		studentGroup.POST("/me/change-password", middleware.NoImpersonation(), authController.ChangePassword)
+		studentGroup.GET("/me/orders", middleware.NoImpersonation(), meController.ListMyOrders)
+		studentGroup.GET("/me/orders/:id", middleware.NoImpersonation(), meController.GetMyOrderByID)
 		studentGroup.GET("/me/programs", meController.ListMyPrograms)
 		studentGroup.GET("/me/programs/:id", meController.GetMyProgramByID)
 		studentGroup.GET("/me/tickets", meTicketController.ListTickets)
 		studentGroup.POST("/me/tickets", meTicketController.CreateTicket)
 		studentGroup.GET("/me/tickets/:id", meTicketController.GetTicket)
 		studentGroup.POST("/me/ai/chat", aiChatController.Chat)
+		studentGroup.POST("/me/ai/chat/stream", aiChatController.ChatStream)
+		studentGroup.GET("/me/ai/quota", aiChatController.Quota)
+		studentGroup.GET("/me/ai/conversations", aiChatController.ListConversations)
+		studentGroup.GET("/me/ai/conversations/:id", aiChatController.GetConversation)
+		studentGroup.DELETE("/me/ai/conversations/:id", middleware.NoImpersonation(), aiChatController.DeleteConversation)
+		studentGroup.POST("/me/ai/messages/:id/feedback", aiChatController.RateMessage)
 		studentGroup.POST("/me/mobile/heartbeat", mobileAppController.MeHeartbeat)
 		studentGroup.GET("/subscriptions/current", studentController.GetCurrentSubscription)
 		studentGroup.GET("/subscriptions", studentController.ListSubscriptions)
 		studentGroup.GET("/programs/current", studentController.GetCurrentPrograms)
-		studentGroup.POST("/orders/checkout", checkoutController.Checkout)
-		studentGroup.GET("/orders/:id/status", checkoutController.GetOrderStatus)
-		studentGroup.POST("/payments/zarinpal/request", paymentController.ZarinpalRequest)
+		studentGroup.POST("/orders/checkout", middleware.NoImpersonation(), checkoutController.Checkout)
+		studentGroup.GET("/orders/:id/status", middleware.NoImpersonation(), checkoutController.GetOrderStatus)
+		studentGroup.POST("/payments/zarinpal/request", middleware.NoImpersonation(), paymentController.ZarinpalRequest)
 	}
 
 	// Admin routes - protected and admin-only
 	adminGroup := router.Group("/admin")
-	adminGroup.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
+	adminGroup.Use(
+		middleware.AuthMiddleware(accountStatusService),
+		middleware.AdminOnly(),
+		middleware.AdminPermissions(adminAccessService),
+		middleware.AuditTrail(auditService),
+	)
 	{
-		adminGroup.GET("/dashboard/stats", adminDashboardController.GetStats)
-		adminGroup.GET("/dashboard/monthly-sales", adminDashboardController.GetMonthlySales)
-		adminGroup.GET("/users", adminUserController.ListUsers)
-		adminGroup.GET("/users/:id", adminUserController.GetUserDetails)
-		adminGroup.GET("/users/:id/programs", adminUserController.GetUserPrograms)
-		adminGroup.GET("/users/:id/body", adminUserController.GetUserBody)
-		adminGroup.POST("/users/:id/body/photos", adminUserController.UploadUserBodyPhoto)
-		adminGroup.DELETE("/users/:id/body/photos/:photoId", adminUserController.DeleteUserBodyPhoto)
-		adminGroup.GET("/students", adminStudentController.ListStudents)
-		adminGroup.GET("/students/:id", adminStudentController.GetStudentByID)
-		adminGroup.PATCH("/students/:id", adminStudentController.UpdateStudent)
-		adminGroup.GET("/plans", adminPlanController.ListPlans)
-		adminGroup.POST("/plans", adminPlanController.CreatePlan)
-		adminGroup.GET("/plans/:id", adminPlanController.GetPlanByID)
-		adminGroup.PATCH("/plans/:id", adminPlanController.UpdatePlan)
-		adminGroup.DELETE("/plans/:id", adminPlanController.DeletePlan)
-		adminGroup.GET("/site-settings", siteSettingsController.GetSiteSettingsAdmin)
-		adminGroup.PUT("/site-settings", siteSettingsController.UpdateSiteSettings)
-		adminGroup.POST("/site-settings/hero-image", siteSettingsController.UploadHeroImage)
-		adminGroup.GET("/academy", siteSettingsController.GetAcademyAdmin)
-		adminGroup.PUT("/academy", siteSettingsController.UpdateAcademyAdmin)
-		adminGroup.GET("/faq", siteSettingsController.GetFAQAdmin)
-		adminGroup.PUT("/faq", siteSettingsController.UpdateFAQAdmin)
-		adminGroup.POST("/content-media", siteSettingsController.UploadContentMedia)
-		adminGroup.GET("/feedbacks", adminFeedbackController.ListFeedbacks)
-		adminGroup.GET("/coaches", adminCoachController.ListCoaches)
-		adminGroup.GET("/coaches/:id", adminCoachController.GetCoachByID)
-		adminGroup.PATCH("/coaches/:id", adminCoachController.PatchCoach)
-		adminGroup.GET("/exercises", adminExerciseController.ListExercises)
-		adminGroup.GET("/exercises/categories", adminExerciseController.ListCategories)
-		adminGroup.POST("/exercises", adminExerciseController.CreateExercise)
-		adminGroup.GET("/exercises/:id", adminExerciseController.GetExerciseByID)
-		adminGroup.PATCH("/exercises/:id", adminExerciseController.UpdateExercise)
-		adminGroup.DELETE("/exercises/:id", adminExerciseController.DeleteExercise)
-		adminGroup.GET("/foods", adminFoodController.ListFoods)
-		adminGroup.GET("/workout-templates", adminTemplateController.ListWorkoutTemplates)
-		adminGroup.POST("/workout-templates", adminTemplateController.CreateWorkoutTemplate)
-		adminGroup.GET("/workout-templates/:id", adminTemplateController.GetWorkoutTemplate)
-		adminGroup.PUT("/workout-templates/:id", adminTemplateController.UpdateWorkoutTemplate)
-		adminGroup.DELETE("/workout-templates/:id", adminTemplateController.DeleteWorkoutTemplate)
-		adminGroup.GET("/nutrition-templates", adminTemplateController.ListNutritionTemplates)
-		adminGroup.POST("/nutrition-templates", adminTemplateController.CreateNutritionTemplate)
-		adminGroup.GET("/nutrition-templates/:id", adminTemplateController.GetNutritionTemplate)
-		adminGroup.PUT("/nutrition-templates/:id", adminTemplateController.UpdateNutritionTemplate)
-		adminGroup.DELETE("/nutrition-templates/:id", adminTemplateController.DeleteNutritionTemplate)
-		adminGroup.GET("/students/:id/programs", adminProgramController.GetStudentPrograms)
-		adminGroup.POST("/students/:id/workout-programs", adminProgramController.AssignWorkoutProgram)
-		adminGroup.PATCH("/students/:id/workout-programs/:programId", adminProgramController.UpdateWorkoutProgram)
-		adminGroup.POST("/students/:id/workout-programs/templates/:templateId", adminProgramController.AssignWorkoutFromTemplate)
-		adminGroup.POST("/students/:id/nutrition-programs", adminProgramController.AssignNutritionProgram)
-		adminGroup.PATCH("/students/:id/nutrition-programs/:programId", adminProgramController.UpdateNutritionProgram)
-		adminGroup.POST("/students/:id/nutrition-programs/templates/:templateId", adminProgramController.AssignNutritionFromTemplate)
-		adminGroup.GET("/assignable-workout-templates", adminProgramController.ListWorkoutTemplates)
-		adminGroup.GET("/assignable-nutrition-templates", adminProgramController.ListNutritionTemplates)
-		adminGroup.GET("/funnel-stats", adminFunnelController.Stats)
-		adminGroup.GET("/funnel-leads", adminFunnelController.ListLeads)
-		adminGroup.GET("/funnel-leads/:id", adminFunnelController.GetLead)
-		adminGroup.PATCH("/funnel-leads/:id", adminFunnelController.PatchLead)
-		adminGroup.DELETE("/funnel-leads/:id", adminFunnelController.DeleteLead)
-		adminGroup.GET("/mobile/overview", mobileAppController.Overview)
-		adminGroup.GET("/mobile/devices", mobileAppController.ListDevices)
-		adminGroup.GET("/mobile/releases", mobileAppController.ListReleases)
-		adminGroup.POST("/mobile/releases", mobileAppController.CreateRelease)
-		adminGroup.PATCH("/mobile/releases/:id", mobileAppController.UpdateRelease)
-		adminGroup.DELETE("/mobile/releases/:id", mobileAppController.DeleteRelease)
+		adminGroup.GET("/me/permissions", adminAccessController.MyPermissions)
+		adminGroup.GET("/roles", middleware.RequirePermission(models.PermAdminRolesManage), adminAccessController.ListRoles)
+		adminGroup.GET("/users/:id/roles", middleware.RequirePermission(models.PermAdminRolesManage), adminAccessController.GetUserRoles)
+		adminGroup.PUT("/users/:id/roles", middleware.RequirePermission(models.PermAdminRolesManage), adminAccessController.SetUserRoles)
+		adminGroup.GET("/dashboard/stats", middleware.RequirePermission(models.PermDashboardRead), adminDashboardController.GetStats)
+		adminGroup.GET("/dashboard/monthly-sales", middleware.RequirePermission(models.PermPaymentsRead), adminDashboardController.GetMonthlySales)
+		adminGroup.GET("/users", middleware.RequirePermission(models.PermUsersRead), adminUserController.ListUsers)
+		adminGroup.GET("/users/:id", middleware.RequirePermission(models.PermUsersRead), adminUserController.GetUserDetails)
+		adminGroup.GET("/users/:id/programs", middleware.RequirePermission(models.PermUsersRead), adminUserController.GetUserPrograms)
+		adminGroup.GET("/users/:id/body", middleware.RequirePermission(models.PermUsersRead), adminUserController.GetUserBody)
+		adminGroup.POST("/users/:id/body/photos", middleware.RequirePermission(models.PermUsersWrite), adminUserController.UploadUserBodyPhoto)
+		adminGroup.DELETE("/users/:id/body/photos/:photoId", middleware.RequirePermission(models.PermUsersWrite), adminUserController.DeleteUserBodyPhoto)
+		adminGroup.GET("/users/:id/status", middleware.RequirePermission(models.PermUsersRead), adminAccountStatusController.GetStatus)
+		adminGroup.PUT("/users/:id/status", middleware.RequirePermission(models.PermUsersSuspend), adminAccountStatusController.SetStatus)
+		adminGroup.POST("/users/:id/impersonate", middleware.RequirePermission(models.PermUsersImpersonate), authController.Impersonate)
+		adminGroup.GET("/students", middleware.RequirePermission(models.PermStudentsRead), adminStudentController.ListStudents)
+		adminGroup.GET("/students/:id", middleware.RequirePermission(models.PermStudentsRead), adminStudentController.GetStudentByID)
+		adminGroup.PATCH("/students/:id", middleware.RequirePermission(models.PermStudentsWrite), adminStudentController.UpdateStudent)
+		adminGroup.GET("/plans", middleware.RequirePermission(models.PermPlansRead), adminPlanController.ListPlans)
+		adminGroup.POST("/plans", middleware.RequirePermission(models.PermPlansWrite), adminPlanController.CreatePlan)
+		adminGroup.GET("/plans/:id", middleware.RequirePermission(models.PermPlansRead), adminPlanController.GetPlanByID)
+		adminGroup.PATCH("/plans/:id", middleware.RequirePermission(models.PermPlansWrite), adminPlanController.UpdatePlan)
+		adminGroup.DELETE("/plans/:id", middleware.RequirePermission(models.PermPlansWrite), adminPlanController.DeletePlan)
+		adminGroup.GET("/site-settings", middleware.RequirePermission(models.PermSiteSettingsRead), siteSettingsController.GetSiteSettingsAdmin)
+		adminGroup.PUT("/site-settings", middleware.RequirePermission(models.PermSiteSettingsWrite), siteSettingsController.UpdateSiteSettings)
+		adminGroup.POST("/site-settings/hero-image", middleware.RequirePermission(models.PermSiteSettingsWrite), siteSettingsController.UploadHeroImage)
+		adminGroup.GET("/academy", middleware.RequirePermission(models.PermSiteSettingsRead), siteSettingsController.GetAcademyAdmin)
+		adminGroup.PUT("/academy", middleware.RequirePermission(models.PermSiteSettingsWrite), siteSettingsController.UpdateAcademyAdmin)
+		adminGroup.GET("/faq", middleware.RequirePermission(models.PermSiteSettingsRead), siteSettingsController.GetFAQAdmin)
+		adminGroup.PUT("/faq", middleware.RequirePermission(models.PermSiteSettingsWrite), siteSettingsController.UpdateFAQAdmin)
+		adminGroup.POST("/content-media", middleware.RequirePermission(models.PermSiteSettingsWrite), siteSettingsController.UploadContentMedia)
+		adminGroup.GET("/feedbacks", middleware.RequirePermission(models.PermFeedbackRead), adminFeedbackController.ListFeedbacks)
+		adminGroup.GET("/ai/flagged-replies", middleware.RequirePermission(models.PermAIRead), aiChatController.ListFlaggedReplies)
+		adminGroup.GET("/ai/tool-invocations", middleware.RequirePermission(models.PermAIRead), aiChatController.ListToolInvocations)
+		adminGroup.GET("/ai/usage", middleware.RequirePermission(models.PermAIRead), aiChatController.UsageDashboard)
+		adminGroup.GET("/coaches", middleware.RequirePermission(models.PermCoachesRead), adminCoachController.ListCoaches)
+		adminGroup.GET("/coaches/:id", middleware.RequirePermission(models.PermCoachesRead), adminCoachController.GetCoachByID)
+		adminGroup.PATCH("/coaches/:id", middleware.RequirePermission(models.PermCoachesWrite), adminCoachController.PatchCoach)
+		adminGroup.GET("/exercises", middleware.RequirePermission(models.PermExercisesRead), adminExerciseController.ListExercises)
+		adminGroup.GET("/exercises/categories", middleware.RequirePermission(models.PermExercisesRead), adminExerciseController.ListCategories)
+		adminGroup.POST("/exercises", middleware.RequirePermission(models.PermExercisesWrite), adminExerciseController.CreateExercise)
+		adminGroup.GET("/exercises/:id", middleware.RequirePermission(models.PermExercisesRead), adminExerciseController.GetExerciseByID)
+		adminGroup.PATCH("/exercises/:id", middleware.RequirePermission(models.PermExercisesWrite), adminExerciseController.UpdateExercise)
+		adminGroup.DELETE("/exercises/:id", middleware.RequirePermission(models.PermExercisesWrite), adminExerciseController.DeleteExercise)
+		adminGroup.GET("/foods", middleware.RequirePermission(models.PermFoodsRead), adminFoodController.ListFoods)
+		adminGroup.GET("/workout-templates", middleware.RequirePermission(models.PermTemplatesRead), adminTemplateController.ListWorkoutTemplates)
+		adminGroup.POST("/workout-templates", middleware.RequirePermission(models.PermTemplatesWrite), adminTemplateController.CreateWorkoutTemplate)
+		adminGroup.GET("/workout-templates/:id", middleware.RequirePermission(models.PermTemplatesRead), adminTemplateController.GetWorkoutTemplate)
+		adminGroup.PUT("/workout-templates/:id", middleware.RequirePermission(models.PermTemplatesWrite), adminTemplateController.UpdateWorkoutTemplate)
+		adminGroup.DELETE("/workout-templates/:id", middleware.RequirePermission(models.PermTemplatesWrite), adminTemplateController.DeleteWorkoutTemplate)
+		adminGroup.GET("/nutrition-templates", middleware.RequirePermission(models.PermTemplatesRead), adminTemplateController.ListNutritionTemplates)
+		adminGroup.POST("/nutrition-templates", middleware.RequirePermission(models.PermTemplatesWrite), adminTemplateController.CreateNutritionTemplate)
+		adminGroup.GET("/nutrition-templates/:id", middleware.RequirePermission(models.PermTemplatesRead), adminTemplateController.GetNutritionTemplate)
+		adminGroup.PUT("/nutrition-templates/:id", middleware.RequirePermission(models.PermTemplatesWrite), adminTemplateController.UpdateNutritionTemplate)
+		adminGroup.DELETE("/nutrition-templates/:id", middleware.RequirePermission(models.PermTemplatesWrite), adminTemplateController.DeleteNutritionTemplate)
+		adminGroup.GET("/marketplace/listings", middleware.RequirePermission(models.PermMarketplaceRead), adminMarketplaceController.ListListings)
+		adminGroup.GET("/marketplace/listings/:id", middleware.RequirePermission(models.PermMarketplaceRead), adminMarketplaceController.GetListing)
+		adminGroup.POST("/marketplace/listings/:id/approve", middleware.RequirePermission(models.PermMarketplaceReview), adminMarketplaceController.ApproveListing)
+		adminGroup.POST("/marketplace/listings/:id/reject", middleware.RequirePermission(models.PermMarketplaceReview), adminMarketplaceController.RejectListing)
+		adminGroup.GET("/students/:id/programs", middleware.RequirePermission(models.PermProgramsRead), adminProgramController.GetStudentPrograms)
+		adminGroup.POST("/students/:id/workout-programs", middleware.RequirePermission(models.PermProgramsWrite), adminProgramController.AssignWorkoutProgram)
+		adminGroup.PATCH("/students/:id/workout-programs/:programId", middleware.RequirePermission(models.PermProgramsWrite), adminProgramController.UpdateWorkoutProgram)
+		adminGroup.POST("/students/:id/workout-programs/templates/:templateId", middleware.RequirePermission(models.PermProgramsWrite), adminProgramController.AssignWorkoutFromTemplate)
+		adminGroup.POST("/students/:id/nutrition-programs", middleware.RequirePermission(models.PermProgramsWrite), adminProgramController.AssignNutritionProgram)
+		adminGroup.PATCH("/students/:id/nutrition-programs/:programId", middleware.RequirePermission(models.PermProgramsWrite), adminProgramController.UpdateNutritionProgram)
+		adminGroup.POST("/students/:id/nutrition-programs/templates/:templateId", middleware.RequirePermission(models.PermProgramsWrite), adminProgramController.AssignNutritionFromTemplate)
+		adminGroup.GET("/assignable-workout-templates", middleware.RequirePermission(models.PermTemplatesRead), adminProgramController.ListWorkoutTemplates)
+		adminGroup.GET("/assignable-nutrition-templates", middleware.RequirePermission(models.PermTemplatesRead), adminProgramController.ListNutritionTemplates)
+		adminGroup.GET("/funnels", middleware.RequirePermission(models.PermFunnelsRead), adminFunnelController.ListFunnels)
+		adminGroup.POST("/funnels", middleware.RequirePermission(models.PermFunnelsWrite), adminFunnelController.CreateFunnel)
+		adminGroup.GET("/funnels/:id", middleware.RequirePermission(models.PermFunnelsRead), adminFunnelController.GetFunnel)
+		adminGroup.PUT("/funnels/:id", middleware.RequirePermission(models.PermFunnelsWrite), adminFunnelController.UpdateFunnel)
+		adminGroup.DELETE("/funnels/:id", middleware.RequirePermission(models.PermFunnelsWrite), adminFunnelController.DeleteFunnel)
+		adminGroup.GET("/funnels/:id/experiments", middleware.RequirePermission(models.PermFunnelsRead), adminFunnelController.ListExperiments)
+		adminGroup.POST("/funnels/:id/experiments", middleware.RequirePermission(models.PermFunnelsWrite), adminFunnelController.CreateExperiment)
+		adminGroup.PUT("/funnels/:id/experiments/:experimentId", middleware.RequirePermission(models.PermFunnelsWrite), adminFunnelController.UpdateExperiment)
+		adminGroup.DELETE("/funnels/:id/experiments/:experimentId", middleware.RequirePermission(models.PermFunnelsWrite), adminFunnelController.DeleteExperiment)
+		adminGroup.POST("/funnels/:id/experiments/:experimentId/start", middleware.RequirePermission(models.PermFunnelsWrite), adminFunnelController.StartExperiment)
+		adminGroup.POST("/funnels/:id/experiments/:experimentId/stop", middleware.RequirePermission(models.PermFunnelsWrite), adminFunnelController.StopExperiment)
+		adminGroup.GET("/funnel-stats", middleware.RequirePermission(models.PermFunnelsRead), adminFunnelController.Stats)
+		adminGroup.GET("/funnel-recovery/report", middleware.RequirePermission(models.PermFunnelsRead), funnelRecoveryController.Report)
+		adminGroup.GET("/funnel-leads", middleware.RequirePermission(models.PermFunnelLeadsRead), adminFunnelController.ListLeads)
+		adminGroup.GET("/funnel-leads/:id", middleware.RequirePermission(models.PermFunnelLeadsRead), adminFunnelController.GetLead)
+		adminGroup.PATCH("/funnel-leads/:id", middleware.RequirePermission(models.PermFunnelLeadsWrite), adminFunnelController.PatchLead)
+		adminGroup.DELETE("/funnel-leads/:id", middleware.RequirePermission(models.PermFunnelLeadsWrite), adminFunnelController.DeleteLead)
+		adminGroup.GET("/mobile/overview", middleware.RequirePermission(models.PermMobileRead), mobileAppController.Overview)
+		adminGroup.GET("/mobile/devices", middleware.RequirePermission(models.PermMobileRead), mobileAppController.ListDevices)
+		adminGroup.GET("/mobile/releases", middleware.RequirePermission(models.PermMobileRead), mobileAppController.ListReleases)
+		adminGroup.POST("/mobile/releases", middleware.RequirePermission(models.PermMobileWrite), mobileAppController.CreateRelease)
+		adminGroup.PATCH("/mobile/releases/:id", middleware.RequirePermission(models.PermMobileWrite), mobileAppController.UpdateRelease)
+		adminGroup.DELETE("/mobile/releases/:id", middleware.RequirePermission(models.PermMobileWrite), mobileAppController.DeleteRelease)
+		adminGroup.GET("/audit-log", middleware.RequirePermission(models.PermAuditRead), adminAuditController.ListEvents)
+		adminGroup.GET("/audit-log/export", middleware.RequirePermission(models.PermAuditRead), adminAuditController.ExportEvents)
 	}
 
 	// Serve uploaded files (e.g. user body photos) at /uploads/*
@@ -388,41 +571,47 @@ func main() {
 	}
 
 	cfg := config.Get()
-	log.Printf("config: app.env=%s server.port=%s db=%s@%s:%s/%s cors_origins=%d",
+	log.Printf("config: app.env=%s server.port=%s db=%s://%s@%s:%s/%s cors_origins=%d",
 		cfg.App.Env,
 		cfg.Server.Port,
+		cfg.Database.Driver,
 		cfg.Database.User,
 		cfg.Database.Host,
 		cfg.Database.Port,
 		cfg.Database.Name,
 		len(cfg.CORS.AllowedOrigins),
 	)
-	log.Printf("config: sms_delivery=%s zarinpal_sandbox=%v zarinpal_merchant=%v callback=%s openai_configured=%v",
+	log.Printf("config: sms_delivery=%s zarinpal_sandbox=%v zarinpal_merchant=%v callback=%s openai_provider=%s openai_key=%v",
 		service.SMSDeliveryMode(),
 		cfg.Payments.Zarinpal.Sandbox,
 		strings.TrimSpace(cfg.Payments.Zarinpal.MerchantID) != "",
 		cfg.Payments.Zarinpal.CallbackBaseURL,
+		cfg.OpenAI.Provider,
 		strings.TrimSpace(cfg.OpenAI.APIKey) != "",
 	)
 
-	db, err := config.NewMySQLGORM()
+	db, err := config.NewGORM()
 	if err != nil {
 		log.Fatalf("failed to initialize database: %v", err)
 	}
 
-	if err := runMigrations(db); err != nil {
-		log.Fatalf("failed to migrate database: %v", err)
+	// Schema changes ship as versioned migrations applied by `go run ./cmd/migrate up`
+	// before a deploy; replicas only refuse to start on an outdated schema.
+	if todo, err := migrations.Pending(db); err != nil {
+		log.Fatalf("failed to read schema_migrations: %v", err)
+	} else if len(todo) > 0 {
+		log.Fatalf("database has %d pending migrations (next %s_%s); run `go run ./cmd/migrate up`", len(todo), todo[0].Version, todo[0].Name)
 	}
-	if err := seedDefaultAdmin(db); err != nil {
+	if err := bootstrap.SeedDefaultAdmin(db); err != nil {
 		log.Fatalf("failed to seed default admin: %v", err)
 	}
 	if err := seed.EnsureSiteContact(context.Background(), db); err != nil {
 		log.Fatalf("failed to seed site contact: %v", err)
 	}
-	if err := seed.EnsureAliFunnel(context.Background(), db); err != nil {
-		log.Fatalf("failed to seed Ali funnel (funnel_1): %v", err)
-	}
 	if config.Get().Seed.DemoData {
+		if err := seed.EnsureAliFunnel(context.Background(), db); err != nil {
+			log.Fatalf("failed to seed Ali funnel: %v", err)
+		}
 		if err := seed.EnsureDemoData(context.Background(), db); err != nil {
 			log.Fatalf("failed to seed demo accounts: %v", err)
 		}
@@ -431,106 +620,10 @@ func main() {
 	if err := seed.SeedCatalogsFromConfig(context.Background(), db); err != nil {
 		log.Printf("WARNING: catalog seed failed: %v", err)
 	}
-	if err := maybeSeedDevData(db); err != nil {
+	if err := bootstrap.MaybeSeedDevData(db); err != nil {
 		log.Fatalf("failed to seed development data: %v", err)
 	}
 
 	server := NewServer(db)
 	server.Run()
 }
-
-func runMigrations(db *gorm.DB) error {
-	log.Println("starting GORM AutoMigrate for core models")
-	if err := db.AutoMigrate(models.AllModels()...); err != nil {
-		log.Printf("AutoMigrate encountered an error: %v", err)
-		return err
-	}
-	log.Println("GORM AutoMigrate completed successfully")
-
-	if err := db.Exec("UPDATE users SET goals = '[]' WHERE goals IS NULL OR goals = ''").Error; err != nil {
-		log.Printf("failed backfilling users.goals: %v", err)
-		return err
-	}
-
-	// Unpaid funnel leads must not store '' under unique tracking_code (MySQL duplicate).
-	if err := db.Exec("UPDATE funnel_leads SET tracking_code = NULL WHERE tracking_code = ''").Error; err != nil {
-		log.Printf("failed backfilling funnel_leads.tracking_code: %v", err)
-		return err
-	}
-
-	if err := db.Exec(
-		"UPDATE coach_profiles SET status = ? WHERE status IS NULL OR status = ''",
-		models.CoachProfileStatusPending,
-	).Error; err != nil {
-		log.Printf("failed normalizing coach_profiles.status: %v", err)
-		return err
-	}
-
-	// Legacy coaches that were already published before approval workflow existed.
-	if err := db.Exec(
-		"UPDATE coach_profiles SET status = ? WHERE status = ? AND is_published = ? AND is_active = ?",
-		models.CoachProfileStatusApproved,
-		models.CoachProfileStatusPending,
-		true,
-		true,
-	).Error; err != nil {
-		log.Printf("failed backfilling coach_profiles.status: %v", err)
-		return err
-	}
-
-	return nil
-}
-
-func seedDefaultAdmin(db *gorm.DB) error {
-	const (
-		adminName     = "admin"
-		adminEmail    = "admin@gmail.com"
-		adminPhone    = "09150000000"
-		adminPassword = "12345678"
-	)
-
-	var count int64
-	if err := db.Model(&models.User{}).Where("email = ?", adminEmail).Count(&count).Error; err != nil {
-		log.Printf("failed counting admin user: %v", err)
-		return err
-	}
-
-	if count > 0 {
-		log.Println("admin user already exists, skipping seeding")
-		return nil
-	}
-
-	hashed, err := bcrypt.GenerateFromPassword([]byte(adminPassword), bcrypt.DefaultCost)
-	if err != nil {
-		log.Printf("failed hashing admin password: %v", err)
-		return err
-	}
-
-	admin := &models.User{
-		Name:     adminName,
-		Email:    adminEmail,
-		Phone:    adminPhone,
-		Password: string(hashed),
-		Role:     models.RoleAdmin,
-	}
-
-	if err := db.Create(admin).Error; err != nil {
-		log.Printf("failed creating admin user: %v", err)
-		return err
-	}
-
-	log.Println("seeded default admin user with email admin@gmail.com")
-	return nil
-}
-
-func maybeSeedDevData(db *gorm.DB) error {
-	if !config.Get().Seed.DevData {
-		return nil
-	}
-
-	_, err := seed.RunDev(context.Background(), db, seed.RunDevOptions{})
-	if err != nil && strings.Contains(err.Error(), "dev seed blocked") {
-		return nil
-	}
-	return err
-}
\ No newline at end of file
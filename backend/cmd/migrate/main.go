@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/migrations"
+)
+
+const usage = `usage: migrate <command> [flags]
+
+commands:
+  up              apply every pending migration
+  down [-steps N] roll back the last N applied migrations (default 1)
+  status          list migrations and when each was applied
+  create <name>   scaffold internal/migrations/NNNN_<name>.go
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	steps := fs.Int("steps", 1, "number of migrations to roll back (down)")
+	dir := fs.String("dir", "internal/migrations", "migrations package directory (create)")
+	_ = fs.Parse(args)
+
+	if cmd == "create" {
+		if fs.NArg() != 1 {
+			log.Fatal("create needs exactly one name, e.g. `migrate create add_audit_events`")
+		}
+		path, err := migrations.Create(*dir, fs.Arg(0))
+		if err != nil {
+			log.Fatalf("create failed: %v", err)
+		}
+		log.Printf("created %s", path)
+		return
+	}
+
+	if err := config.Load(); err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	db, err := config.NewGORM()
+	if err != nil {
+		log.Fatalf("database connection failed: %v", err)
+	}
+
+	switch cmd {
+	case "up":
+		n, err := migrations.Up(db)
+		if err != nil {
+			log.Fatalf("applied %d, then failed: %v", n, err)
+		}
+		log.Printf("applied %d migrations", n)
+	case "down":
+		if *steps <= 0 {
+			log.Fatal("-steps must be positive")
+		}
+		n, err := migrations.Down(db, *steps)
+		if err != nil {
+			log.Fatalf("rolled back %d, then failed: %v", n, err)
+		}
+		log.Printf("rolled back %d migrations", n)
+	case "status":
+		rows, err := migrations.StatusOf(db)
+		if err != nil {
+			log.Fatalf("status failed: %v", err)
+		}
+		for _, st := range rows {
+			state := "pending"
+			if st.AppliedAt != nil {
+				state = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.Unknown {
+				state += " (not in this build)"
+			}
+			fmt.Printf("%s  %-40s %s\n", st.Version, st.Name, state)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -14,7 +14,7 @@ import (
 func main() {
 	devFlag := flag.Bool("dev", false, "load development/UI test fixtures (coaches, students, orders, programs)")
 	demoFlag := flag.Bool("demo", false, "ensure lightweight demo coaches/students/subscriptions + print logins")
-	aliFlag := flag.Bool("ali", false, "ensure Funnel 1 coach (علی رشیدآبادی) + VIP/CIP plans")
+	aliFlag := flag.Bool("ali", false, "ensure the علی رشیدآبادی coach, VIP/CIP plans and his funnel")
 	foodsFlag := flag.Bool("foods", false, "import food facts from CSV (default: data/Persian_food_facts.csv)")
 	templatesFlag := flag.Bool("templates", false, "import workout/nutrition templates from data/*.json")
 	catalogsFlag := flag.Bool("catalogs", false, "import exercises + foods + templates (same as startup seed.catalogs)")
@@ -26,7 +26,7 @@ func main() {
 		log.Fatalf("failed to load configuration: %v", err)
 	}
 
-	db, err := config.NewMySQLGORM()
+	db, err := config.NewGORM()
 	if err != nil {
 		log.Fatalf("database connection failed: %v", err)
 	}
@@ -48,7 +48,7 @@ func main() {
 		if err := seed.EnsureAliFunnel(ctx, db); err != nil {
 			log.Fatalf("ali funnel seed failed: %v", err)
 		}
-		log.Println("funnel_1: ali.rashidabadi@fitino.ir / 12345678")
+		log.Println("ali funnel (/ali-rashidabadi): ali.rashidabadi@fitino.ir / 12345678")
 		log.Println("  VIP 1,490,000 · CIP 2,900,000 (90 days)")
 		return
 	}
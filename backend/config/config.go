@@ -22,6 +22,10 @@ type Config struct {
 	Server struct {
 		Host string `mapstructure:"host"`
 		Port string `mapstructure:"port"`
+		// TrustedProxies lists the IPs or CIDRs of the load balancers in front
+		// of the API. X-Forwarded-For is only believed from these; empty means
+		// the client IP is always the connection's remote address.
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
 	} `mapstructure:"server"`
 
 	CORS struct {
@@ -31,23 +35,54 @@ type Config struct {
 	} `mapstructure:"cors"`
 
 	Database struct {
+		// Driver is mysql (default), postgres or sqlite. SQLite uses Path only;
+		// ":memory:" gives a private in-memory database (tests, quick local runs).
+		Driver   string `mapstructure:"driver"`
 		Host     string `mapstructure:"host"`
 		Port     string `mapstructure:"port"`
 		User     string `mapstructure:"user"`
 		Password string `mapstructure:"password"`
 		Name     string `mapstructure:"name"`
+		SSLMode  string `mapstructure:"sslmode"`
+		Path     string `mapstructure:"path"`
 	} `mapstructure:"database"`
 
 	JWT struct {
-		Secret                     string `mapstructure:"secret"`
-		AccessTokenDurationMinutes int    `mapstructure:"access_token_duration_minutes"`
-		RefreshTokenDurationDays   int    `mapstructure:"refresh_token_duration_days"`
+		Secret                            string `mapstructure:"secret"`
+		AccessTokenDurationMinutes        int    `mapstructure:"access_token_duration_minutes"`
+		RefreshTokenDurationDays          int    `mapstructure:"refresh_token_duration_days"`
+		ImpersonationTokenDurationMinutes int    `mapstructure:"impersonation_token_duration_minutes"`
 	} `mapstructure:"jwt"`
 
+	Auth struct {
+		// TwoFactorRoles lists the roles (comma-separated, e.g. "admin,coach")
+		// that must pass a second factor at login. Admins and coaches outside
+		// it may still turn on TOTP themselves.
+		TwoFactorRoles string `mapstructure:"two_factor_roles"`
+		TOTPIssuer     string `mapstructure:"totp_issuer"`
+		// After LoginLockoutThreshold failed sign-ins in a row, each further
+		// failure locks password login for LoginLockoutBaseSeconds, doubling
+		// every time up to LoginLockoutMaxMinutes.
+		LoginLockoutThreshold   int `mapstructure:"login_lockout_threshold"`
+		LoginLockoutBaseSeconds int `mapstructure:"login_lockout_base_seconds"`
+		LoginLockoutMaxMinutes  int `mapstructure:"login_lockout_max_minutes"`
+	} `mapstructure:"auth"`
+
 	Upload struct {
 		Dir string `mapstructure:"dir"`
 	} `mapstructure:"upload"`
 
+	// Privacy covers personal data exports and account deletion. ExportDir
+	// must not be served publicly; exports are downloadable for
+	// ExportTTLHours. Deleted accounts are anonymized after
+	// DeletionGraceDays, checked every IntervalSeconds.
+	Privacy struct {
+		ExportDir         string `mapstructure:"export_dir"`
+		ExportTTLHours    int    `mapstructure:"export_ttl_hours"`
+		DeletionGraceDays int    `mapstructure:"deletion_grace_days"`
+		IntervalSeconds   int    `mapstructure:"interval_seconds"`
+	} `mapstructure:"privacy"`
+
 	Seed struct {
 		DevData       bool `mapstructure:"dev_data"`
 		DemoData      bool `mapstructure:"demo_data"`
@@ -56,10 +91,23 @@ type Config struct {
 	} `mapstructure:"seed"`
 
 	Funnel struct {
-		// CoachSlug binds Funnel 1 (/ali-rashidabadi) to one coach.
-		// Plans/prices come from that coach's ServicePlan rows in DB.
-		// Funnel 2 (future) would be a separate route + binding.
-		CoachSlug string `mapstructure:"coach_slug"`
+		// DefaultSlug is the funnel served by the legacy /public/funnel/* routes.
+		// Funnels themselves (coach, plans, questions) are rows managed via the API.
+		DefaultSlug string `mapstructure:"default_slug"`
+
+		// Recovery texts leads that left checkout unpaid. One SMS per entry of
+		// DelaysMinutes (comma-separated, counted from lead creation); step
+		// DiscountStep (1-based, 0 = none) also offers DiscountPercent off for
+		// DiscountValidHours. Leads older than MaxAgeHours are left alone.
+		Recovery struct {
+			Enabled            bool   `mapstructure:"enabled"`
+			DelaysMinutes      string `mapstructure:"delays_minutes"`
+			DiscountStep       int    `mapstructure:"discount_step"`
+			DiscountPercent    int    `mapstructure:"discount_percent"`
+			DiscountValidHours int    `mapstructure:"discount_valid_hours"`
+			MaxAgeHours        int    `mapstructure:"max_age_hours"`
+			IntervalSeconds    int    `mapstructure:"interval_seconds"`
+		} `mapstructure:"recovery"`
 	} `mapstructure:"funnel"`
 
 	SMS struct {
@@ -67,8 +115,12 @@ type Config struct {
 		Originator               string `mapstructure:"originator"`
 		OtpPattern               string `mapstructure:"otp_pattern_code"`
 		ProgramReadyPattern      string `mapstructure:"program_ready_pattern_code"`
+		FunnelRecoveryPattern    string `mapstructure:"funnel_recovery_pattern_code"`
+		FunnelDiscountPattern    string `mapstructure:"funnel_recovery_discount_pattern_code"`
 		OtpTTLMinutes            int    `mapstructure:"otp_ttl_minutes"`
 		OtpResendCooldownSeconds int    `mapstructure:"otp_resend_cooldown_seconds"`
+		// After OtpMaxAttempts guesses a code is spent; the user has to request a new one.
+		OtpMaxAttempts int `mapstructure:"otp_max_attempts"`
 	} `mapstructure:"sms"`
 
 	Payments struct {
@@ -78,14 +130,55 @@ type Config struct {
 			CallbackBaseURL  string `mapstructure:"callback_base_url"`
 			WebResultURL     string `mapstructure:"web_result_url"`
 			MobileDeepLink   string `mapstructure:"mobile_deep_link_scheme"`
+			// APIBaseURL replaces the ZarinPal v4 payment API root (a mock gateway
+			// for staging or tests); empty picks sandbox or live.
+			APIBaseURL       string `mapstructure:"api_base_url"`
 		} `mapstructure:"zarinpal"`
 	} `mapstructure:"payments"`
 
 	OpenAI struct {
-		APIKey  string `mapstructure:"api_key"`
-		Model   string `mapstructure:"model"`
-		BaseURL string `mapstructure:"base_url"`
+		// Provider selects the LLM backend: openai (api.openai.com), openai_compatible
+		// (any Chat Completions server at base_url — GapGPT, vLLM, llama.cpp) or fake.
+		Provider             string  `mapstructure:"provider"`
+		APIKey               string  `mapstructure:"api_key"`
+		Model                string  `mapstructure:"model"`
+		BaseURL              string  `mapstructure:"base_url"`
+		Temperature          float64 `mapstructure:"temperature"`
+		MaxTokens            int     `mapstructure:"max_tokens"`
+		TimeoutSeconds       int     `mapstructure:"timeout_seconds"`
+		StreamTimeoutSeconds int     `mapstructure:"stream_timeout_seconds"`
+		// Prices in USD per million tokens, for the admin AI cost dashboard.
+		PromptCostPerMillion     float64 `mapstructure:"prompt_cost_per_million"`
+		CompletionCostPerMillion float64 `mapstructure:"completion_cost_per_million"`
 	} `mapstructure:"openai"`
+
+	// AI holds assistant usage limits, in messages. Free users have no active
+	// subscription; paid defaults apply to plans whose own AI quota is 0.
+	// -1 means unlimited.
+	AI struct {
+		RatePerMinute       int `mapstructure:"rate_per_minute"`
+		FreeDailyMessages   int `mapstructure:"free_daily_messages"`
+		FreeMonthlyMessages int `mapstructure:"free_monthly_messages"`
+		PaidDailyMessages   int `mapstructure:"paid_daily_messages"`
+		PaidMonthlyMessages int `mapstructure:"paid_monthly_messages"`
+	} `mapstructure:"ai"`
+
+	RateLimit struct {
+		// Store is where limiter counters live: db (shared by all replicas,
+		// survives restarts) or memory (single process, e.g. local dev).
+		Store string `mapstructure:"store"`
+		// Per-client-IP request budgets of the public endpoints; 0 turns a
+		// limit off. OTPPerPhonePerHour also caps codes sent to one number.
+		LoginPerMinute     int `mapstructure:"login_per_minute"`
+		OTPPerHour         int `mapstructure:"otp_per_hour"`
+		OTPPerPhonePerHour int `mapstructure:"otp_per_phone_per_hour"`
+		FormsPerHour       int `mapstructure:"forms_per_hour"`
+		HeartbeatPerMinute int `mapstructure:"heartbeat_per_minute"`
+		// HumanCheck guards bot-prone forms: "" (off) or pow (proof of work
+		// with PowDifficulty leading zero bits).
+		HumanCheck    string `mapstructure:"human_check"`
+		PowDifficulty int    `mapstructure:"pow_difficulty"`
+	} `mapstructure:"rate_limit"`
 }
 
 var (
@@ -208,62 +301,147 @@ func setDefaults() {
 	})
 	viper.SetDefault("cors.allow_localhost", false)
 	viper.SetDefault("cors.allow_credentials", false)
+	viper.SetDefault("database.driver", DriverMySQL)
 	viper.SetDefault("database.host", "localhost")
-	viper.SetDefault("database.port", "3306")
 	viper.SetDefault("database.user", "fitino")
 	viper.SetDefault("database.password", "")
 	viper.SetDefault("database.name", "fitness_db")
+	viper.SetDefault("database.sslmode", "disable")
+	viper.SetDefault("database.path", "fitness.db")
 	viper.SetDefault("jwt.secret", "change-me-in-production")
 	viper.SetDefault("jwt.access_token_duration_minutes", 15)
 	viper.SetDefault("jwt.refresh_token_duration_days", 7)
+	viper.SetDefault("jwt.impersonation_token_duration_minutes", 10)
+	viper.SetDefault("auth.totp_issuer", "Fitinoo")
+	viper.SetDefault("auth.login_lockout_threshold", 5)
+	viper.SetDefault("auth.login_lockout_base_seconds", 30)
+	viper.SetDefault("auth.login_lockout_max_minutes", 60)
 	viper.SetDefault("upload.dir", "uploads")
+	viper.SetDefault("privacy.export_dir", "exports")
+	viper.SetDefault("privacy.export_ttl_hours", 72)
+	viper.SetDefault("privacy.deletion_grace_days", 14)
+	viper.SetDefault("privacy.interval_seconds", 600)
 	viper.SetDefault("seed.dev_data", false)
 	viper.SetDefault("seed.demo_data", true)
 	viper.SetDefault("seed.catalogs", true)
 	viper.SetDefault("seed.catalogs_force", false)
 	viper.SetDefault("sms.otp_pattern_code", "fittino-otp")
 	viper.SetDefault("sms.program_ready_pattern_code", "fittino-program")
+	viper.SetDefault("sms.funnel_recovery_pattern_code", "fittino-recovery")
+	viper.SetDefault("sms.funnel_recovery_discount_pattern_code", "fittino-recovery-discount")
+	viper.SetDefault("funnel.recovery.enabled", false)
+	viper.SetDefault("funnel.recovery.delays_minutes", "60,1440")
+	viper.SetDefault("funnel.recovery.discount_step", 2)
+	viper.SetDefault("funnel.recovery.discount_percent", 10)
+	viper.SetDefault("funnel.recovery.discount_valid_hours", 48)
+	viper.SetDefault("funnel.recovery.max_age_hours", 168)
+	viper.SetDefault("funnel.recovery.interval_seconds", 300)
 	viper.SetDefault("sms.otp_ttl_minutes", 10)
 	viper.SetDefault("sms.otp_resend_cooldown_seconds", 60)
+	viper.SetDefault("sms.otp_max_attempts", 5)
 	viper.SetDefault("payments.zarinpal.sandbox", false)
 	viper.SetDefault("payments.zarinpal.callback_base_url", "https://api.fitinoo.ir")
 	viper.SetDefault("payments.zarinpal.web_result_url", "https://fitinoo.ir/payment/result")
 	viper.SetDefault("payments.zarinpal.mobile_deep_link_scheme", "fitinoo")
 	viper.SetDefault("openai.model", "gemini-3.1-flash-lite")
 	viper.SetDefault("openai.base_url", "https://api.gapgpt.app/v1")
+	viper.SetDefault("openai.provider", "openai_compatible")
+	viper.SetDefault("openai.temperature", 0.4)
+	viper.SetDefault("openai.max_tokens", 500)
+	viper.SetDefault("openai.timeout_seconds", 45)
+	viper.SetDefault("openai.stream_timeout_seconds", 120)
+	viper.SetDefault("openai.prompt_cost_per_million", 0.1)
+	viper.SetDefault("openai.completion_cost_per_million", 0.4)
+	viper.SetDefault("ai.rate_per_minute", 20)
+	viper.SetDefault("ai.free_daily_messages", 5)
+	viper.SetDefault("ai.free_monthly_messages", 50)
+	viper.SetDefault("ai.paid_daily_messages", 50)
+	viper.SetDefault("ai.paid_monthly_messages", 1000)
+	viper.SetDefault("rate_limit.store", "db")
+	viper.SetDefault("rate_limit.login_per_minute", 20)
+	viper.SetDefault("rate_limit.otp_per_hour", 30)
+	viper.SetDefault("rate_limit.otp_per_phone_per_hour", 8)
+	viper.SetDefault("rate_limit.forms_per_hour", 30)
+	viper.SetDefault("rate_limit.heartbeat_per_minute", 12)
+	viper.SetDefault("rate_limit.pow_difficulty", 18)
 }
 
 func bindEnvKeys() {
 	_ = viper.BindEnv("app.env", "APP_ENV")
 	_ = viper.BindEnv("server.port", "PORT")
+	_ = viper.BindEnv("server.trusted_proxies", "TRUSTED_PROXIES")
+	_ = viper.BindEnv("database.driver", "DB_DRIVER")
 	_ = viper.BindEnv("database.host", "DB_HOST")
 	_ = viper.BindEnv("database.port", "DB_PORT")
 	_ = viper.BindEnv("database.user", "DB_USER")
 	_ = viper.BindEnv("database.password", "DB_PASSWORD")
 	_ = viper.BindEnv("database.name", "DB_NAME")
+	_ = viper.BindEnv("database.sslmode", "DB_SSLMODE")
+	_ = viper.BindEnv("database.path", "DB_PATH")
 	_ = viper.BindEnv("jwt.secret", "JWT_SECRET")
 	_ = viper.BindEnv("jwt.access_token_duration_minutes", "ACCESS_TOKEN_DURATION_MINUTES")
 	_ = viper.BindEnv("jwt.refresh_token_duration_days", "REFRESH_TOKEN_DURATION_DAYS")
+	_ = viper.BindEnv("jwt.impersonation_token_duration_minutes", "IMPERSONATION_TOKEN_DURATION_MINUTES")
+	_ = viper.BindEnv("auth.two_factor_roles", "TWO_FACTOR_ROLES")
+	_ = viper.BindEnv("auth.totp_issuer", "TOTP_ISSUER")
+	_ = viper.BindEnv("auth.login_lockout_threshold", "LOGIN_LOCKOUT_THRESHOLD")
+	_ = viper.BindEnv("auth.login_lockout_base_seconds", "LOGIN_LOCKOUT_BASE_SECONDS")
+	_ = viper.BindEnv("auth.login_lockout_max_minutes", "LOGIN_LOCKOUT_MAX_MINUTES")
 	_ = viper.BindEnv("upload.dir", "UPLOAD_DIR")
+	_ = viper.BindEnv("privacy.export_dir", "PRIVACY_EXPORT_DIR")
+	_ = viper.BindEnv("privacy.export_ttl_hours", "PRIVACY_EXPORT_TTL_HOURS")
+	_ = viper.BindEnv("privacy.deletion_grace_days", "PRIVACY_DELETION_GRACE_DAYS")
+	_ = viper.BindEnv("privacy.interval_seconds", "PRIVACY_INTERVAL_SECONDS")
 	_ = viper.BindEnv("seed.dev_data", "SEED_DEV_DATA")
 	_ = viper.BindEnv("seed.demo_data", "SEED_DEMO_DATA")
 	_ = viper.BindEnv("seed.catalogs", "SEED_CATALOGS")
 	_ = viper.BindEnv("seed.catalogs_force", "SEED_CATALOGS_FORCE")
-	_ = viper.BindEnv("funnel.coach_slug", "FUNNEL_COACH_SLUG")
+	_ = viper.BindEnv("funnel.default_slug", "FUNNEL_DEFAULT_SLUG")
+	_ = viper.BindEnv("funnel.recovery.enabled", "FUNNEL_RECOVERY_ENABLED")
+	_ = viper.BindEnv("funnel.recovery.delays_minutes", "FUNNEL_RECOVERY_DELAYS_MINUTES")
+	_ = viper.BindEnv("funnel.recovery.discount_step", "FUNNEL_RECOVERY_DISCOUNT_STEP")
+	_ = viper.BindEnv("funnel.recovery.discount_percent", "FUNNEL_RECOVERY_DISCOUNT_PERCENT")
+	_ = viper.BindEnv("funnel.recovery.discount_valid_hours", "FUNNEL_RECOVERY_DISCOUNT_VALID_HOURS")
+	_ = viper.BindEnv("funnel.recovery.max_age_hours", "FUNNEL_RECOVERY_MAX_AGE_HOURS")
+	_ = viper.BindEnv("funnel.recovery.interval_seconds", "FUNNEL_RECOVERY_INTERVAL_SECONDS")
 	_ = viper.BindEnv("sms.api_key", "SMS_API_KEY")
 	_ = viper.BindEnv("sms.originator", "SMS_ORIGINATOR")
 	_ = viper.BindEnv("sms.otp_pattern_code", "SMS_OTP_PATTERN_CODE")
 	_ = viper.BindEnv("sms.program_ready_pattern_code", "SMS_PROGRAM_READY_PATTERN_CODE")
+	_ = viper.BindEnv("sms.funnel_recovery_pattern_code", "SMS_FUNNEL_RECOVERY_PATTERN_CODE")
+	_ = viper.BindEnv("sms.funnel_recovery_discount_pattern_code", "SMS_FUNNEL_RECOVERY_DISCOUNT_PATTERN_CODE")
 	_ = viper.BindEnv("sms.otp_ttl_minutes", "SMS_OTP_TTL_MINUTES")
 	_ = viper.BindEnv("sms.otp_resend_cooldown_seconds", "SMS_OTP_RESEND_COOLDOWN_SECONDS")
+	_ = viper.BindEnv("sms.otp_max_attempts", "SMS_OTP_MAX_ATTEMPTS")
 	_ = viper.BindEnv("payments.zarinpal.merchant_id", "ZARINPAL_MERCHANT_ID")
 	_ = viper.BindEnv("payments.zarinpal.sandbox", "ZARINPAL_SANDBOX")
 	_ = viper.BindEnv("payments.zarinpal.callback_base_url", "ZARINPAL_CALLBACK_BASE_URL")
 	_ = viper.BindEnv("payments.zarinpal.web_result_url", "ZARINPAL_WEB_RESULT_URL")
 	_ = viper.BindEnv("payments.zarinpal.mobile_deep_link_scheme", "ZARINPAL_MOBILE_DEEP_LINK_SCHEME")
+	_ = viper.BindEnv("payments.zarinpal.api_base_url", "ZARINPAL_API_BASE_URL")
 	_ = viper.BindEnv("openai.api_key", "OPENAI_API_KEY")
 	_ = viper.BindEnv("openai.model", "OPENAI_MODEL")
 	_ = viper.BindEnv("openai.base_url", "OPENAI_BASE_URL")
+	_ = viper.BindEnv("openai.provider", "OPENAI_PROVIDER")
+	_ = viper.BindEnv("openai.temperature", "OPENAI_TEMPERATURE")
+	_ = viper.BindEnv("openai.max_tokens", "OPENAI_MAX_TOKENS")
+	_ = viper.BindEnv("openai.timeout_seconds", "OPENAI_TIMEOUT_SECONDS")
+	_ = viper.BindEnv("openai.stream_timeout_seconds", "OPENAI_STREAM_TIMEOUT_SECONDS")
+	_ = viper.BindEnv("openai.prompt_cost_per_million", "OPENAI_PROMPT_COST_PER_MILLION")
+	_ = viper.BindEnv("openai.completion_cost_per_million", "OPENAI_COMPLETION_COST_PER_MILLION")
+	_ = viper.BindEnv("ai.rate_per_minute", "AI_RATE_PER_MINUTE")
+	_ = viper.BindEnv("ai.free_daily_messages", "AI_FREE_DAILY_MESSAGES")
+	_ = viper.BindEnv("ai.free_monthly_messages", "AI_FREE_MONTHLY_MESSAGES")
+	_ = viper.BindEnv("ai.paid_daily_messages", "AI_PAID_DAILY_MESSAGES")
+	_ = viper.BindEnv("ai.paid_monthly_messages", "AI_PAID_MONTHLY_MESSAGES")
+	_ = viper.BindEnv("rate_limit.store", "RATE_LIMIT_STORE")
+	_ = viper.BindEnv("rate_limit.login_per_minute", "RATE_LIMIT_LOGIN_PER_MINUTE")
+	_ = viper.BindEnv("rate_limit.otp_per_hour", "RATE_LIMIT_OTP_PER_HOUR")
+	_ = viper.BindEnv("rate_limit.otp_per_phone_per_hour", "RATE_LIMIT_OTP_PER_PHONE_PER_HOUR")
+	_ = viper.BindEnv("rate_limit.forms_per_hour", "RATE_LIMIT_FORMS_PER_HOUR")
+	_ = viper.BindEnv("rate_limit.heartbeat_per_minute", "RATE_LIMIT_HEARTBEAT_PER_MINUTE")
+	_ = viper.BindEnv("rate_limit.human_check", "RATE_LIMIT_HUMAN_CHECK")
+	_ = viper.BindEnv("rate_limit.pow_difficulty", "RATE_LIMIT_POW_DIFFICULTY")
 }
 
 func applyLegacyOverrides(c *Config) {
@@ -280,6 +458,20 @@ func normalize(c *Config) {
 	if c.Server.Port == "" {
 		c.Server.Port = "8088"
 	}
+	c.Server.TrustedProxies = splitCSV(strings.Join(c.Server.TrustedProxies, ","))
+
+	c.Database.Driver = strings.ToLower(strings.TrimSpace(c.Database.Driver))
+	if c.Database.Driver == "" {
+		c.Database.Driver = DriverMySQL
+	}
+	if strings.TrimSpace(c.Database.Port) == "" {
+		switch c.Database.Driver {
+		case DriverPostgres:
+			c.Database.Port = "5432"
+		case DriverMySQL:
+			c.Database.Port = "3306"
+		}
+	}
 
 	if c.JWT.Secret == "" {
 		log.Println("warning: jwt.secret is empty; using an insecure default for development")
@@ -295,6 +487,18 @@ func normalize(c *Config) {
 	if c.Upload.Dir == "" {
 		c.Upload.Dir = "uploads"
 	}
+	if c.Privacy.ExportDir == "" {
+		c.Privacy.ExportDir = "exports"
+	}
+	if c.Privacy.ExportTTLHours <= 0 {
+		c.Privacy.ExportTTLHours = 72
+	}
+	if c.Privacy.DeletionGraceDays < 0 {
+		c.Privacy.DeletionGraceDays = 14
+	}
+	if c.Privacy.IntervalSeconds <= 0 {
+		c.Privacy.IntervalSeconds = 600
+	}
 
 	if c.SMS.OtpPattern == "" {
 		c.SMS.OtpPattern = "fittino-otp"
@@ -302,9 +506,28 @@ func normalize(c *Config) {
 	if c.SMS.ProgramReadyPattern == "" {
 		c.SMS.ProgramReadyPattern = "fittino-program"
 	}
+	if c.SMS.FunnelRecoveryPattern == "" {
+		c.SMS.FunnelRecoveryPattern = "fittino-recovery"
+	}
+	if c.SMS.FunnelDiscountPattern == "" {
+		c.SMS.FunnelDiscountPattern = "fittino-recovery-discount"
+	}
 	if c.SMS.OtpTTLMinutes <= 0 {
 		c.SMS.OtpTTLMinutes = 10
 	}
+
+	if c.Funnel.Recovery.DiscountPercent < 0 || c.Funnel.Recovery.DiscountPercent >= 100 {
+		c.Funnel.Recovery.DiscountPercent = 0
+	}
+	if c.Funnel.Recovery.DiscountValidHours <= 0 {
+		c.Funnel.Recovery.DiscountValidHours = 48
+	}
+	if c.Funnel.Recovery.MaxAgeHours <= 0 {
+		c.Funnel.Recovery.MaxAgeHours = 168
+	}
+	if c.Funnel.Recovery.IntervalSeconds < 30 {
+		c.Funnel.Recovery.IntervalSeconds = 300
+	}
 	if c.SMS.OtpResendCooldownSeconds <= 0 {
 		c.SMS.OtpResendCooldownSeconds = 60
 	}
@@ -323,6 +546,7 @@ func normalize(c *Config) {
 		log.Printf("WARNING: payments.zarinpal.callback_base_url invalid %q — using https://api.fitinoo.ir", c.Payments.Zarinpal.CallbackBaseURL)
 		c.Payments.Zarinpal.CallbackBaseURL = "https://api.fitinoo.ir"
 	}
+	c.Payments.Zarinpal.APIBaseURL = strings.TrimRight(strings.TrimSpace(c.Payments.Zarinpal.APIBaseURL), "/")
 	c.Payments.Zarinpal.WebResultURL = strings.TrimSpace(c.Payments.Zarinpal.WebResultURL)
 	if c.Payments.Zarinpal.WebResultURL == "" {
 		c.Payments.Zarinpal.WebResultURL = "https://fitinoo.ir/payment/result"
@@ -337,6 +561,52 @@ func normalize(c *Config) {
 	if c.OpenAI.BaseURL == "" {
 		c.OpenAI.BaseURL = "https://api.gapgpt.app/v1"
 	}
+	c.OpenAI.Provider = strings.ToLower(strings.TrimSpace(c.OpenAI.Provider))
+	if c.OpenAI.Provider == "" {
+		c.OpenAI.Provider = "openai_compatible"
+	}
+	if c.OpenAI.Temperature < 0 || c.OpenAI.Temperature > 2 {
+		log.Printf("WARNING: openai.temperature %.2f out of range — using 0.4", c.OpenAI.Temperature)
+		c.OpenAI.Temperature = 0.4
+	}
+	if c.OpenAI.MaxTokens <= 0 {
+		c.OpenAI.MaxTokens = 500
+	}
+	if c.OpenAI.TimeoutSeconds <= 0 {
+		c.OpenAI.TimeoutSeconds = 45
+	}
+	if c.OpenAI.StreamTimeoutSeconds <= 0 {
+		c.OpenAI.StreamTimeoutSeconds = 120
+	}
+	if c.OpenAI.PromptCostPerMillion < 0 {
+		c.OpenAI.PromptCostPerMillion = 0
+	}
+	if c.OpenAI.CompletionCostPerMillion < 0 {
+		c.OpenAI.CompletionCostPerMillion = 0
+	}
+	if c.AI.RatePerMinute <= 0 {
+		c.AI.RatePerMinute = 20
+	}
+	c.RateLimit.Store = strings.ToLower(strings.TrimSpace(c.RateLimit.Store))
+	if c.RateLimit.Store != "memory" {
+		c.RateLimit.Store = "db"
+	}
+	c.RateLimit.HumanCheck = strings.ToLower(strings.TrimSpace(c.RateLimit.HumanCheck))
+	if c.RateLimit.PowDifficulty <= 0 {
+		c.RateLimit.PowDifficulty = 18
+	}
+	if c.SMS.OtpMaxAttempts <= 0 {
+		c.SMS.OtpMaxAttempts = 5
+	}
+	if c.Auth.LoginLockoutThreshold <= 0 {
+		c.Auth.LoginLockoutThreshold = 5
+	}
+	if c.Auth.LoginLockoutBaseSeconds <= 0 {
+		c.Auth.LoginLockoutBaseSeconds = 30
+	}
+	if c.Auth.LoginLockoutMaxMinutes <= 0 {
+		c.Auth.LoginLockoutMaxMinutes = 60
+	}
 
 	// Dev: force ZarinPal sandbox so local never hits live merchant.
 	// Prod: leave yaml/env as-is, but warn loudly if sandbox is still on.
@@ -488,3 +758,20 @@ func GetRefreshTokenDuration() time.Duration {
 	days := Get().JWT.RefreshTokenDurationDays
 	return time.Duration(days) * 24 * time.Hour
 }
+
+// TwoFactorRequired reports whether config forces a second factor on role.
+func TwoFactorRequired(role string) bool {
+	for _, r := range splitCSV(Get().Auth.TwoFactorRoles) {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetImpersonationTokenDuration returns the lifetime of admin impersonation
+// tokens. They cannot be refreshed.
+func GetImpersonationTokenDuration() time.Duration {
+	minutes := Get().JWT.ImpersonationTokenDurationMinutes
+	return time.Duration(minutes) * time.Minute
+}
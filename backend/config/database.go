@@ -3,16 +3,47 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"regexp"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-// NewMySQLGORM initializes a GORM DB connection using environment variables.
+// Supported values of database.driver.
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+var dbNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// NewGORM opens the database selected by database.driver.
+func NewGORM() (*gorm.DB, error) {
+	MustLoad()
+	dbCfg := Get().Database
+	switch dbCfg.Driver {
+	case DriverMySQL:
+		return NewMySQLGORM()
+	case DriverPostgres:
+		return NewPostgresGORM()
+	case DriverSQLite:
+		return NewSQLiteGORM(dbCfg.Path)
+	default:
+		return nil, fmt.Errorf("unsupported database.driver %q: use mysql, postgres or sqlite", dbCfg.Driver)
+	}
+}
+
+// NewMySQLGORM initializes a GORM DB connection using environment variables,
+// creating the database when it does not exist.
 //
 // Expected env vars:
 //   - DB_HOST
@@ -20,8 +51,6 @@ import (
 //   - DB_USER
 //   - DB_PASSWORD
 //   - DB_NAME
-var dbNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
-
 func NewMySQLGORM() (*gorm.DB, error) {
 	MustLoad()
 	dbCfg := Get().Database
@@ -59,28 +88,153 @@ func NewMySQLGORM() (*gorm.DB, error) {
 		user, password, host, port, dbName,
 	)
 
-	gormLogger := logger.New(
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: newGORMLogger(logger.Info)})
+	if err != nil {
+		return nil, fmt.Errorf("opening mysql connection: %w", err)
+	}
+	if err := configurePool(db); err != nil {
+		return nil, err
+	}
+
+	log.Println("MySQL database connection established")
+
+	return db, nil
+}
+
+// NewPostgresGORM connects to PostgreSQL with the same database.* settings as
+// MySQL plus database.sslmode, creating the database when it does not exist.
+func NewPostgresGORM() (*gorm.DB, error) {
+	MustLoad()
+	dbCfg := Get().Database
+
+	host := dbCfg.Host
+	port := dbCfg.Port
+	user := dbCfg.User
+	dbName := dbCfg.Name
+	sslMode := dbCfg.SSLMode
+
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "5432"
+	}
+	if user == "" {
+		user = "postgres"
+	}
+	if dbName == "" {
+		dbName = "fitness_db"
+	}
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	if !dbNamePattern.MatchString(dbName) {
+		return nil, fmt.Errorf("invalid DB_NAME %q: use only letters, numbers, and underscores", dbName)
+	}
+
+	dsn := func(name string) string {
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(user, dbCfg.Password),
+			Host:     host + ":" + port,
+			Path:     "/" + name,
+			RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+		}
+		return u.String()
+	}
+
+	if err := ensurePostgresDatabaseExists(dsn("postgres"), dbName); err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn(dbName)), &gorm.Config{Logger: newGORMLogger(logger.Info)})
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres connection: %w", err)
+	}
+	if err := configurePool(db); err != nil {
+		return nil, err
+	}
+
+	log.Println("PostgreSQL database connection established")
+
+	return db, nil
+}
+
+var sqliteMemorySeq atomic.Int64
+
+// NewSQLiteGORM opens the SQLite file at path. ":memory:" opens a fresh
+// in-memory database private to the returned handle, which lives as long as
+// the handle keeps a connection open.
+func NewSQLiteGORM(path string) (*gorm.DB, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		path = "fitness.db"
+	}
+
+	memory := path == ":memory:"
+	dsn := path
+	level := logger.Info
+	if memory {
+		// A named shared-cache database lets every pooled connection see the
+		// same tables; the sequence keeps separate handles apart.
+		dsn = fmt.Sprintf("file:fitness_mem_%d?mode=memory&cache=shared", sqliteMemorySeq.Add(1))
+		level = logger.Warn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	dsn += sep + "_busy_timeout=5000"
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: newGORMLogger(level)})
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("getting generic DB from gorm: %w", err)
+	}
+	if memory {
+		// The database disappears with its last connection; never let the pool
+		// drop to zero.
+		sqlDB.SetMaxIdleConns(4)
+		sqlDB.SetConnMaxIdleTime(0)
+		sqlDB.SetConnMaxLifetime(0)
+	} else {
+		// One writer at a time; WAL keeps readers from blocking on it.
+		sqlDB.SetMaxOpenConns(1)
+		if err := db.Exec("PRAGMA journal_mode=WAL").Error; err != nil {
+			return nil, fmt.Errorf("enabling sqlite WAL: %w", err)
+		}
+	}
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("pinging database: %w", err)
+	}
+
+	if !memory {
+		log.Printf("SQLite database %q ready", path)
+	}
+	return db, nil
+}
+
+func newGORMLogger(level logger.LogLevel) logger.Interface {
+	return logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags),
 		logger.Config{
 			SlowThreshold:             time.Second,
-			LogLevel:                  logger.Info,
+			LogLevel:                  level,
 			IgnoreRecordNotFoundError: true,
 			Colorful:                  true,
 		},
 	)
+}
 
-	gormConfig := &gorm.Config{
-		Logger: gormLogger,
-	}
-
-	db, err := gorm.Open(mysql.Open(dsn), gormConfig)
-	if err != nil {
-		return nil, fmt.Errorf("opening mysql connection: %w", err)
-	}
-
+// configurePool applies the server pool defaults and checks the connection.
+func configurePool(db *gorm.DB) error {
 	sqlDB, err := db.DB()
 	if err != nil {
-		return nil, fmt.Errorf("getting generic DB from gorm: %w", err)
+		return fmt.Errorf("getting generic DB from gorm: %w", err)
 	}
 
 	// Reasonable defaults; can be tuned later.
@@ -89,12 +243,9 @@ func NewMySQLGORM() (*gorm.DB, error) {
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
 	if err := sqlDB.Ping(); err != nil {
-		return nil, fmt.Errorf("pinging database: %w", err)
+		return fmt.Errorf("pinging database: %w", err)
 	}
-
-	log.Println("MySQL database connection established")
-
-	return db, nil
+	return nil
 }
 
 // ensureDatabaseExists connects to the MySQL server (without a default schema) and
@@ -135,3 +286,31 @@ func ensureDatabaseExists(host, port, user, password, dbName string) error {
 	log.Printf("database %q ready (created if it did not exist)", dbName)
 	return nil
 }
+
+// ensurePostgresDatabaseExists connects to the maintenance database at
+// adminDSN and creates dbName when missing. PostgreSQL has no CREATE DATABASE
+// IF NOT EXISTS, hence the lookup first.
+func ensurePostgresDatabaseExists(adminDSN, dbName string) error {
+	db, err := gorm.Open(postgres.Open(adminDSN), &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+	if err != nil {
+		return fmt.Errorf("connecting to PostgreSQL server: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("getting generic DB from gorm: %w", err)
+	}
+	defer sqlDB.Close()
+
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM pg_database WHERE datname = ?", dbName).Scan(&count).Error; err != nil {
+		return fmt.Errorf("looking up database %q: %w", dbName, err)
+	}
+	if count == 0 {
+		if err := db.Exec(fmt.Sprintf(`CREATE DATABASE "%s" ENCODING 'UTF8'`, dbName)).Error; err != nil {
+			return fmt.Errorf("creating database %q: %w", dbName, err)
+		}
+	}
+
+	log.Printf("database %q ready (created if it did not exist)", dbName)
+	return nil
+}
@@ -2070,7 +2070,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Filter: paid, pending, failed, refunded, or empty",
+                        "description": "Filter: paid, pending, failed, refunded, refund_pending, or empty",
                         "name": "status",
                         "in": "query"
                     }
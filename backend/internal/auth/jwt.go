@@ -11,9 +11,57 @@ import (
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Role   string `json:"role"`
+	// ImpersonatorID is the admin acting as UserID; zero for normal sessions.
+	ImpersonatorID uint `json:"impersonatorId,omitempty"`
+	// Purpose marks a login challenge token (see GenerateChallengeToken).
+	// Such tokens are not access tokens.
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Login challenge purposes.
+const (
+	PurposeTwoFactor      = "two_factor"
+	PurposePasswordChange = "password_change"
+)
+
+// ChallengeTokenDuration is how long a login challenge stays open.
+const ChallengeTokenDuration = 5 * time.Minute
+
+// GenerateChallengeToken issues the token a half-finished login hands back to
+// finish the remaining step named by purpose.
+func GenerateChallengeToken(userID uint, role, purpose string) (string, time.Time, error) {
+	expiration := time.Now().Add(ChallengeTokenDuration)
+	claims := Claims{
+		UserID:  userID,
+		Role:    role,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiration),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(config.GetJWTSecret())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiration, nil
+}
+
+// ParseChallengeToken parses a challenge token issued for purpose.
+func ParseChallengeToken(tokenStr, purpose string) (*Claims, error) {
+	claims, err := ParseToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
 func GenerateAccessToken(userID uint, role string) (string, time.Time, error) {
 	expiration := time.Now().Add(config.GetAccessTokenDuration())
 	claims := Claims{
@@ -33,6 +81,28 @@ func GenerateAccessToken(userID uint, role string) (string, time.Time, error) {
 	return signed, expiration, nil
 }
 
+// GenerateImpersonationToken issues a short-lived access token for userID on
+// behalf of the admin impersonatorID. There is no refresh token to go with it.
+func GenerateImpersonationToken(userID uint, role string, impersonatorID uint) (string, time.Time, error) {
+	expiration := time.Now().Add(config.GetImpersonationTokenDuration())
+	claims := Claims{
+		UserID:         userID,
+		Role:           role,
+		ImpersonatorID: impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiration),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(config.GetJWTSecret())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiration, nil
+}
+
 func GenerateRefreshToken(userID uint, role string) (string, time.Time, error) {
 	expiration := time.Now().Add(config.GetRefreshTokenDuration())
 	claims := Claims{
@@ -9,30 +9,19 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/migrations"
 	"github.com/yourusername/fitness-management/internal/models"
 	"github.com/yourusername/fitness-management/internal/seed"
 )
 
-// RunMigrations applies GORM AutoMigrate for all registered models.
+// RunMigrations applies every pending versioned migration. Only one-off tools
+// call it; the API server refuses to start until `cmd/migrate up` has run.
 func RunMigrations(db *gorm.DB) error {
-	log.Println("starting GORM AutoMigrate for core models")
-	if err := db.AutoMigrate(models.AllModels()...); err != nil {
-		log.Printf("AutoMigrate encountered an error: %v", err)
-		return err
-	}
-	log.Println("GORM AutoMigrate completed successfully")
-
-	if err := db.Exec("UPDATE users SET goals = '[]' WHERE goals IS NULL OR goals = ''").Error; err != nil {
-		log.Printf("failed backfilling users.goals: %v", err)
-		return err
-	}
-
-	// Unpaid funnel leads must not store '' under unique tracking_code (MySQL duplicate).
-	if err := db.Exec("UPDATE funnel_leads SET tracking_code = NULL WHERE tracking_code = ''").Error; err != nil {
-		log.Printf("failed backfilling funnel_leads.tracking_code: %v", err)
+	n, err := migrations.Up(db)
+	if err != nil {
 		return err
 	}
-
+	log.Printf("applied %d migrations", n)
 	return nil
 }
 
@@ -62,18 +51,25 @@ func SeedDefaultAdmin(db *gorm.DB) error {
 		return err
 	}
 
+	// The well-known password only gets the operator through the first
+	// login, which then demands a new one.
 	admin := &models.User{
-		Name:     adminName,
-		Email:    adminEmail,
-		Phone:    adminPhone,
-		Password: string(hashed),
-		Role:     models.RoleAdmin,
+		Name:               adminName,
+		Email:              adminEmail,
+		Phone:              adminPhone,
+		Password:           string(hashed),
+		Role:               models.RoleAdmin,
+		MustChangePassword: true,
 	}
 
 	if err := db.Create(admin).Error; err != nil {
 		log.Printf("failed creating admin user: %v", err)
 		return err
 	}
+	if err := db.Create(&models.AdminRoleAssignment{UserID: admin.ID, Role: models.AdminRoleSuperAdmin}).Error; err != nil {
+		log.Printf("failed granting super admin role: %v", err)
+		return err
+	}
 
 	log.Println("seeded default admin user with email admin@gmail.com")
 	return nil
@@ -97,8 +93,8 @@ func SeedCatalogs(db *gorm.DB) error {
 	return seed.SeedCatalogsFromConfig(context.Background(), db)
 }
 
-// PrepareDatabase runs migrations, default admin seed, Ali funnel coach/plans,
-// reference catalogs, and optional dev fixtures.
+// PrepareDatabase runs migrations, default admin seed, legacy funnel lead
+// backfill, reference catalogs, and optional demo/dev fixtures.
 func PrepareDatabase(db *gorm.DB) error {
 	if err := RunMigrations(db); err != nil {
 		return err
@@ -109,10 +105,10 @@ func PrepareDatabase(db *gorm.DB) error {
 	if err := seed.EnsureSiteContact(context.Background(), db); err != nil {
 		return err
 	}
-	if err := seed.EnsureAliFunnel(context.Background(), db); err != nil {
-		return err
-	}
 	if config.Get().Seed.DemoData {
+		if err := seed.EnsureAliFunnel(context.Background(), db); err != nil {
+			return err
+		}
 		if err := seed.EnsureDemoData(context.Background(), db); err != nil {
 			return err
 		}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+type AdminAccessController struct {
+	accessService service.AdminAccessService
+}
+
+func NewAdminAccessController(s service.AdminAccessService) *AdminAccessController {
+	return &AdminAccessController{accessService: s}
+}
+
+type adminRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// MyPermissions godoc
+// @Summary Effective roles and permissions of the calling admin
+// @Description The panel uses this to hide controls the admin cannot use.
+// @Tags admin-access
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.AdminAccessDTO
+// @Failure 401 {object} map[string]string
+// @Router /admin/me/permissions [get]
+func (h *AdminAccessController) MyPermissions(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	out, err := h.accessService.Access(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ListRoles godoc
+// @Summary Admin roles and the permissions each grants
+// @Tags admin-access
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.AdminRoleCatalogDTO
+// @Router /admin/roles [get]
+func (h *AdminAccessController) ListRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, h.accessService.Catalog())
+}
+
+// GetUserRoles godoc
+// @Summary Roles and permissions of an admin user
+// @Tags admin-access
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Admin user ID"
+// @Success 200 {object} service.AdminAccessDTO
+// @Failure 400 {object} map[string]string
+// @Router /admin/users/{id}/roles [get]
+func (h *AdminAccessController) GetUserRoles(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	out, err := h.accessService.Access(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// SetUserRoles godoc
+// @Summary Replace the roles of an admin user
+// @Tags admin-access
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Admin user ID"
+// @Param body body adminRolesRequest true "super_admin | support | content_editor | finance"
+// @Success 200 {object} service.AdminAccessDTO
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/users/{id}/roles [put]
+func (h *AdminAccessController) SetUserRoles(c *gin.Context) {
+	actorID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req adminRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	out, err := h.accessService.SetRoles(c.Request.Context(), actorID, uint(id), req.Roles)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAdminRoleInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "نقش نامعتبر است"})
+		case errors.Is(err, service.ErrAdminUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "کاربر ادمین یافت نشد"})
+		case errors.Is(err, service.ErrLastSuperAdminRole):
+			c.JSON(http.StatusConflict, gin.H{"error": "حداقل یک مدیر کل باید باقی بماند"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+type AdminAccountStatusController struct {
+	statusService service.AccountStatusService
+}
+
+func NewAdminAccountStatusController(s service.AccountStatusService) *AdminAccountStatusController {
+	return &AdminAccountStatusController{statusService: s}
+}
+
+// GetStatus godoc
+// @Summary Account status of a user (admin)
+// @Tags admin-users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} service.AccountStatusDTO
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/status [get]
+func (h *AdminAccountStatusController) GetStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	out, err := h.statusService.GetStatus(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// SetStatus godoc
+// @Summary Suspend, ban or reactivate a user (admin)
+// @Description Suspending or banning signs the user out everywhere and notifies them.
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param body body service.AccountStatusRequest true "status: active | suspended | banned; until (RFC 3339) for suspensions"
+// @Success 200 {object} service.AccountStatusDTO
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/users/{id}/status [put]
+func (h *AdminAccountStatusController) SetStatus(c *gin.Context) {
+	actorID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if uint(id) == actorID {
+		c.JSON(http.StatusConflict, gin.H{"error": "امکان تغییر وضعیت حساب خودتان وجود ندارد"})
+		return
+	}
+	var req service.AccountStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	out, err := h.statusService.SetStatus(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *AdminAccountStatusController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAccountStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "وضعیت یا تاریخ پایان نامعتبر است"})
+	case errors.Is(err, service.ErrAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "کاربر یافت نشد"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+type AdminAuditController struct {
+	auditService service.AuditService
+}
+
+func NewAdminAuditController(auditService service.AuditService) *AdminAuditController {
+	return &AdminAuditController{auditService: auditService}
+}
+
+// ListEvents godoc
+// @Summary Audit log of admin and coach mutations (admin)
+// @Tags admin-audit
+// @Produce json
+// @Security BearerAuth
+// @Param actorId query int false "Filter by acting user"
+// @Param role query string false "admin | coach"
+// @Param impersonatorId query int false "Requests an admin made while impersonating"
+// @Param action query string false "Exact action, e.g. PATCH /admin/coaches/:id"
+// @Param targetType query string false "e.g. coach, student, funnel_lead, exercise"
+// @Param targetId query string false "Target ID"
+// @Param from query string false "YYYY-MM-DD"
+// @Param to query string false "YYYY-MM-DD (inclusive)"
+// @Param q query string false "Search action, path and changed values"
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Success 200 {object} service.AuditEventListResponse
+// @Failure 400 {object} map[string]string
+// @Router /admin/audit-log [get]
+func (h *AdminAuditController) ListEvents(c *gin.Context) {
+	page := parseIntQuery(c.Query("page"), 1, 1<<20)
+	pageSize := parseIntQuery(c.Query("pageSize"), 20, 100)
+	out, err := h.auditService.List(c.Request.Context(), auditLogQuery(c), page, pageSize)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ExportEvents godoc
+// @Summary Export the filtered audit log as CSV (admin)
+// @Tags admin-audit
+// @Produce text/csv
+// @Security BearerAuth
+// @Param actorId query int false "Filter by acting user"
+// @Param role query string false "admin | coach"
+// @Param impersonatorId query int false "Requests an admin made while impersonating"
+// @Param action query string false "Exact action"
+// @Param targetType query string false "Target type"
+// @Param targetId query string false "Target ID"
+// @Param from query string false "YYYY-MM-DD"
+// @Param to query string false "YYYY-MM-DD (inclusive)"
+// @Param q query string false "Search action, path and changed values"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Router /admin/audit-log/export [get]
+func (h *AdminAuditController) ExportEvents(c *gin.Context) {
+	// The BOM lets spreadsheet apps read Persian names as UTF-8.
+	buf := bytes.NewBufferString("\ufeff")
+	if err := h.auditService.ExportCSV(c.Request.Context(), auditLogQuery(c), buf); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	filename := fmt.Sprintf("audit-log-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+func auditLogQuery(c *gin.Context) service.AuditLogQuery {
+	return service.AuditLogQuery{
+		ActorID:        uint(parseIntQuery(c.Query("actorId"), 0, 1<<31)),
+		ActorRole:      c.Query("role"),
+		ImpersonatorID: uint(parseIntQuery(c.Query("impersonatorId"), 0, 1<<31)),
+		Action:         c.Query("action"),
+		TargetType:     c.Query("targetType"),
+		TargetID:       c.Query("targetId"),
+		From:           c.Query("from"),
+		To:             c.Query("to"),
+		Query:          c.Query("q"),
+	}
+}
+
+func (h *AdminAuditController) handleError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrAuditInvalidRange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "بازه تاریخ نامعتبر است"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// AdminMarketplaceController moderates coach template listings before they go live.
+type AdminMarketplaceController struct {
+	svc service.TemplateMarketplaceService
+}
+
+func NewAdminMarketplaceController(svc service.TemplateMarketplaceService) *AdminMarketplaceController {
+	return &AdminMarketplaceController{svc: svc}
+}
+
+func (h *AdminMarketplaceController) ListListings(c *gin.Context) {
+	page, pageSize := marketplacePaging(c)
+	resp, err := h.svc.AdminList(c.Request.Context(), page, pageSize, c.DefaultQuery("status", "pending"), c.Query("query"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AdminMarketplaceController) GetListing(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	item, err := h.svc.AdminGet(c.Request.Context(), uint(id))
+	if err != nil {
+		handleMarketplaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *AdminMarketplaceController) ApproveListing(c *gin.Context) {
+	h.review(c, true)
+}
+
+func (h *AdminMarketplaceController) RejectListing(c *gin.Context) {
+	h.review(c, false)
+}
+
+func (h *AdminMarketplaceController) review(c *gin.Context, approve bool) {
+	adminID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req service.TemplateListingReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	var item *service.TemplateListingDTO
+	if approve {
+		item, err = h.svc.Approve(c.Request.Context(), adminID, uint(id), req.Note)
+	} else {
+		item, err = h.svc.Reject(c.Request.Context(), adminID, uint(id), req.Note)
+	}
+	if err != nil {
+		handleMarketplaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
@@ -177,6 +177,8 @@ func (h *AdminProgramController) handleError(c *gin.Context, err error) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 	case errors.Is(err, service.ErrCoachTemplateNotFound):
 		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+	case errors.Is(err, service.ErrCoachTemplateForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 	case errors.Is(err, service.ErrCoachProgramNotFound):
 		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
 	default:
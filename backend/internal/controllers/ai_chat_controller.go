@@ -3,6 +3,7 @@ package controllers
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
@@ -11,11 +12,12 @@ import (
 )
 
 type AIChatController struct {
-	aiService *service.AIChatService
+	aiService    *service.AIChatService
+	usageService service.AIUsageService
 }
 
-func NewAIChatController(aiService *service.AIChatService) *AIChatController {
-	return &AIChatController{aiService: aiService}
+func NewAIChatController(aiService *service.AIChatService, usageService service.AIUsageService) *AIChatController {
+	return &AIChatController{aiService: aiService, usageService: usageService}
 }
 
 // Chat godoc
@@ -28,6 +30,7 @@ func NewAIChatController(aiService *service.AIChatService) *AIChatController {
 // @Success 200 {object} service.AIChatResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 429 {object} map[string]string
 // @Failure 503 {object} map[string]string
 // @Failure 502 {object} map[string]string
@@ -47,20 +50,309 @@ func (h *AIChatController) Chat(c *gin.Context) {
 
 	out, err := h.aiService.Chat(c.Request.Context(), userID, &req)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrAIInvalidInput):
-			c.JSON(http.StatusBadRequest, gin.H{"error": "پیام نامعتبر است"})
-		case errors.Is(err, service.ErrAIRateLimited):
-			c.JSON(http.StatusTooManyRequests, gin.H{"error": "لطفاً کمی صبر کنید و دوباره تلاش کنید"})
-		case errors.Is(err, service.ErrAINotConfigured):
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "دستیار هوشمند فعلاً پیکربندی نشده است"})
-		case errors.Is(err, service.ErrAIUpstream):
-			c.JSON(http.StatusBadGateway, gin.H{"error": "ارتباط با سرویس هوش مصنوعی برقرار نشد"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "خطای داخلی دستیار"})
+		status, msg := aiChatErrorResponse(err)
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// ChatStream godoc
+// @Summary Fitino AI assistant chat, streamed as Server-Sent Events (student)
+// @Description Emits `delta` events ({"text"}) as the reply is generated, then one `done`
+// @Description event with the stored AIChatResponse. When `done.replaced` is true a guardrail
+// @Description swapped the streamed text and clients must show `done.reply` instead.
+// @Description Failures after streaming started arrive as an `error` event.
+// @Tags me-ai
+// @Accept json
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param body body service.AIChatRequest true "Chat request"
+// @Success 200 {object} service.AIChatResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /me/ai/chat/stream [post]
+func (h *AIChatController) ChatStream(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req service.AIChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	// Headers go out with the first event so errors raised before any output
+	// (validation, rate limit, …) still get a proper status code.
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+	}
+	send := func(event string, data any) {
+		start()
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	out, err := h.aiService.ChatStream(c.Request.Context(), userID, &req, func(delta string) error {
+		send("delta", gin.H{"text": delta})
+		return c.Request.Context().Err()
+	})
+	if err != nil {
+		status, msg := aiChatErrorResponse(err)
+		if !started {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+		send("error", gin.H{"error": msg})
+		return
+	}
+	send("done", out)
+}
+
+func aiChatErrorResponse(err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrAIInvalidInput):
+		return http.StatusBadRequest, "پیام نامعتبر است"
+	case errors.Is(err, service.ErrAIConversationNotFound):
+		return http.StatusNotFound, "گفتگو یافت نشد"
+	case errors.Is(err, service.ErrAIRateLimited):
+		return http.StatusTooManyRequests, "لطفاً کمی صبر کنید و دوباره تلاش کنید"
+	case errors.Is(err, service.ErrAIQuotaExceeded):
+		return http.StatusTooManyRequests, "سهمیه پیام‌های دستیار هوشمند شما تمام شده است"
+	case errors.Is(err, service.ErrAINotConfigured):
+		return http.StatusServiceUnavailable, "دستیار هوشمند فعلاً پیکربندی نشده است"
+	case errors.Is(err, service.ErrAIUpstream):
+		return http.StatusBadGateway, "ارتباط با سرویس هوش مصنوعی برقرار نشد"
+	default:
+		return http.StatusInternalServerError, "خطای داخلی دستیار"
+	}
+}
+
+// ListConversations godoc
+// @Summary List my AI assistant conversations
+// @Tags me-ai
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Success 200 {object} service.AIConversationListResponse
+// @Router /me/ai/conversations [get]
+func (h *AIChatController) ListConversations(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	page := parseIntQuery(c.Query("page"), 1, 1<<20)
+	pageSize := parseIntQuery(c.Query("pageSize"), 20, 100)
+	out, err := h.aiService.ListConversations(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "خطای داخلی دستیار"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// GetConversation godoc
+// @Summary Get an AI conversation with its messages (resume)
+// @Tags me-ai
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Conversation ID"
+// @Success 200 {object} service.AIConversationDetailDTO
+// @Failure 404 {object} map[string]string
+// @Router /me/ai/conversations/{id} [get]
+func (h *AIChatController) GetConversation(c *gin.Context) {
+	userID, id, ok := parseAIUserAndID(c)
+	if !ok {
+		return
+	}
+	out, err := h.aiService.GetConversation(c.Request.Context(), userID, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteConversation godoc
+// @Summary Delete an AI conversation
+// @Tags me-ai
+// @Security BearerAuth
+// @Param id path int true "Conversation ID"
+// @Success 200 {object} map[string]bool
+// @Failure 404 {object} map[string]string
+// @Router /me/ai/conversations/{id} [delete]
+func (h *AIChatController) DeleteConversation(c *gin.Context) {
+	userID, id, ok := parseAIUserAndID(c)
+	if !ok {
+		return
+	}
+	if err := h.aiService.DeleteConversation(c.Request.Context(), userID, id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// RateMessage godoc
+// @Summary Thumbs up/down feedback on an assistant reply
+// @Tags me-ai
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Message ID"
+// @Param body body service.AIMessageFeedbackRequest true "Feedback"
+// @Success 200 {object} service.AIMessageDTO
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /me/ai/messages/{id}/feedback [post]
+func (h *AIChatController) RateMessage(c *gin.Context) {
+	userID, id, ok := parseAIUserAndID(c)
+	if !ok {
+		return
+	}
+	var req service.AIMessageFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	out, err := h.aiService.RateMessage(c.Request.Context(), userID, id, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ListFlaggedReplies godoc
+// @Summary Assistant replies flagged by guardrails (admin moderation)
+// @Tags admin-ai
+// @Produce json
+// @Security BearerAuth
+// @Param reason query string false "steroid_prompt | steroid_reply | program_prescription"
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Success 200 {object} service.AIFlaggedReplyListResponse
+// @Router /admin/ai/flagged-replies [get]
+func (h *AIChatController) ListFlaggedReplies(c *gin.Context) {
+	page := parseIntQuery(c.Query("page"), 1, 1<<20)
+	pageSize := parseIntQuery(c.Query("pageSize"), 20, 100)
+	out, err := h.aiService.ListFlaggedReplies(c.Request.Context(), c.Query("reason"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Quota godoc
+// @Summary My AI assistant message quota and usage
+// @Tags me-ai
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.AIQuotaDTO
+// @Router /me/ai/quota [get]
+func (h *AIChatController) Quota(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	out, err := h.usageService.Quota(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// UsageDashboard godoc
+// @Summary AI assistant usage and cost by day and coach cohort (admin)
+// @Tags admin-ai
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "YYYY-MM-DD (default: 29 days before to)"
+// @Param to query string false "YYYY-MM-DD (default: today)"
+// @Success 200 {object} service.AIUsageDashboard
+// @Failure 400 {object} map[string]string
+// @Router /admin/ai/usage [get]
+func (h *AIChatController) UsageDashboard(c *gin.Context) {
+	out, err := h.usageService.Dashboard(c.Request.Context(), c.Query("from"), c.Query("to"))
+	if err != nil {
+		if errors.Is(err, service.ErrAIInvalidDateRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "بازه تاریخ نامعتبر است"})
+			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, out)
+}
 
+// ListToolInvocations godoc
+// @Summary Audit trail of assistant tool calls (admin)
+// @Tags admin-ai
+// @Produce json
+// @Security BearerAuth
+// @Param userId query int false "Filter by user"
+// @Param tool query string false "get_tracking_status | get_my_programs | get_personal_records | get_order_status"
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Success 200 {object} service.AIToolInvocationListResponse
+// @Router /admin/ai/tool-invocations [get]
+func (h *AIChatController) ListToolInvocations(c *gin.Context) {
+	page := parseIntQuery(c.Query("page"), 1, 1<<20)
+	pageSize := parseIntQuery(c.Query("pageSize"), 20, 100)
+	userID := parseIntQuery(c.Query("userId"), 0, 1<<31)
+	out, err := h.aiService.ListToolInvocations(c.Request.Context(), uint(userID), c.Query("tool"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, out)
 }
+
+func parseAIUserAndID(c *gin.Context) (uint, uint, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, 0, false
+	}
+	return userID, uint(id), true
+}
+
+func (h *AIChatController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAIConversationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "گفتگو یافت نشد"})
+	case errors.Is(err, service.ErrAIMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "پیام یافت نشد"})
+	case errors.Is(err, service.ErrAIInvalidFeedback):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "بازخورد نامعتبر است"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "خطای داخلی دستیار"})
+	}
+}
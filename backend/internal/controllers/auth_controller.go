@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -106,6 +107,40 @@ type meResponse struct {
 	AvatarURL         string    `json:"avatarUrl,omitempty"`
 	IsProfileComplete bool      `json:"isProfileComplete"`
 	CreatedAt         time.Time `json:"created_at"`
+	// ImpersonatorID is set while an admin is signed in as this user.
+	ImpersonatorID uint `json:"impersonatorId,omitempty"`
+}
+
+// loginChallengeResponse replaces authResponse when the login needs another
+// step: challenge is two_factor (answer with one of methods at
+// /auth/login/2fa) or password_change (/auth/login/password-change).
+type loginChallengeResponse struct {
+	Challenge      string    `json:"challenge"`
+	ChallengeToken string    `json:"challenge_token"`
+	Methods        []string  `json:"methods,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type twoFactorSMSRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+type twoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Method         string `json:"method" binding:"required"` // totp | recovery | sms
+	Code           string `json:"code" binding:"required"`
+}
+
+type passwordChangeLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	NewPassword    string `json:"newPassword" binding:"required,min=8"`
+}
+
+type impersonationResponse struct {
+	User          authUserResponse `json:"user"`
+	AccessToken   string           `json:"access_token"`
+	ExpiresAt     time.Time        `json:"expires_at"`
+	Impersonating bool             `json:"impersonating"`
 }
 
 type changePasswordRequest struct {
@@ -131,6 +166,141 @@ func (h *AuthController) handleOTPRequestError(c *gin.Context, err error) bool {
 	return false
 }
 
+// handleLoginLocked answers 429 while password login is locked.
+func handleLoginLocked(c *gin.Context, err error) bool {
+	var locked *service.LoginLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(locked.RetryAfterSeconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":               fmt.Sprintf("به دلیل تلاش‌های ناموفق، ورود تا %d ثانیه دیگر ممکن نیست", locked.RetryAfterSeconds),
+		"retry_after_seconds": locked.RetryAfterSeconds,
+	})
+	return true
+}
+
+func writeLoginChallenge(c *gin.Context, ch *service.LoginChallenge) {
+	c.JSON(http.StatusOK, loginChallengeResponse{
+		Challenge:      ch.Purpose,
+		ChallengeToken: ch.Token,
+		Methods:        ch.Methods,
+		ExpiresAt:      ch.ExpiresAt,
+	})
+}
+
+// writeAuthResult answers with the session tokens or the next challenge.
+func (h *AuthController) writeAuthResult(c *gin.Context, result *service.AuthResult) {
+	if result.Challenge != nil {
+		writeLoginChallenge(c, result.Challenge)
+		return
+	}
+	userResp, err := h.buildAuthUserResponse(c, result.User)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, authResponse{
+		User:         userResp,
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+	})
+}
+
+// handleChallengeError maps errors of the login challenge endpoints.
+func (h *AuthController) handleChallengeError(c *gin.Context, err error) {
+	var blocked *service.AccountBlockedError
+	switch {
+	case errors.Is(err, service.ErrInvalidChallenge):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "مهلت ورود تمام شده است؛ دوباره وارد شوید"})
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "کد تایید نامعتبر است"})
+	case errors.Is(err, service.ErrSamePassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "رمز عبور جدید باید با رمز فعلی متفاوت باشد"})
+	case errors.As(err, &blocked):
+		c.JSON(http.StatusForbidden, middleware.AccountBlockedBody(blocked))
+	default:
+		if handleLoginLocked(c, err) || h.handleOTPRequestError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// SendTwoFactorSMS godoc
+// @Summary Text a second-factor code for a pending login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body twoFactorSMSRequest true "Challenge from the login response"
+// @Success 200 {object} messageResponse
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/login/2fa/sms [post]
+func (h *AuthController) SendTwoFactorSMS(c *gin.Context) {
+	var req twoFactorSMSRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.authService.SendTwoFactorSMS(c.Request.Context(), req.ChallengeToken); err != nil {
+		h.handleChallengeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, messageResponse{Message: "کد ارسال شد"})
+}
+
+// CompleteTwoFactor godoc
+// @Summary Finish a login with the second factor
+// @Description Answers a two_factor challenge with an authenticator code, a recovery code or an SMS code. Returns tokens, or a password_change challenge.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body twoFactorLoginRequest true "Challenge and code"
+// @Success 200 {object} authResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/login/2fa [post]
+func (h *AuthController) CompleteTwoFactor(c *gin.Context) {
+	var req twoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := h.authService.CompleteTwoFactor(c.Request.Context(), req.ChallengeToken, req.Method, req.Code)
+	if err != nil {
+		h.handleChallengeError(c, err)
+		return
+	}
+	h.writeAuthResult(c, result)
+}
+
+// CompletePasswordChange godoc
+// @Summary Set a new password to finish a login
+// @Description Answers a password_change challenge, e.g. for the seeded default admin.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body passwordChangeLoginRequest true "Challenge and new password"
+// @Success 200 {object} authResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/login/password-change [post]
+func (h *AuthController) CompletePasswordChange(c *gin.Context) {
+	var req passwordChangeLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := h.authService.CompletePasswordChange(c.Request.Context(), req.ChallengeToken, req.NewPassword)
+	if err != nil {
+		h.handleChallengeError(c, err)
+		return
+	}
+	h.writeAuthResult(c, result)
+}
+
 func (h *AuthController) buildAuthUserResponse(c *gin.Context, user *models.User) (authUserResponse, error) {
 	complete, err := h.meService.IsProfileComplete(c.Request.Context(), user)
 	if err != nil {
@@ -270,6 +440,8 @@ func (h *AuthController) RegisterCoach(c *gin.Context) {
 // @Success 200 {object} authResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /auth/login/password [post]
 func (h *AuthController) LoginWithPassword(c *gin.Context) {
@@ -285,10 +457,23 @@ func (h *AuthController) LoginWithPassword(c *gin.Context) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
+		var blocked *service.AccountBlockedError
+		if errors.As(err, &blocked) {
+			c.JSON(http.StatusForbidden, middleware.AccountBlockedBody(blocked))
+			return
+		}
+		if handleLoginLocked(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if result.Challenge != nil {
+		writeLoginChallenge(c, result.Challenge)
+		return
+	}
+
 	userResp, err := h.buildAuthUserResponse(c, result.User)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -341,6 +526,7 @@ func (h *AuthController) RequestOTP(c *gin.Context) {
 // @Success 200 {object} authResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /auth/otp/verify [post]
 func (h *AuthController) VerifyOTP(c *gin.Context) {
@@ -360,10 +546,24 @@ func (h *AuthController) VerifyOTP(c *gin.Context) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "حسابی با این شماره یافت نشد"})
 			return
 		}
+		if errors.Is(err, service.ErrTwoFactorSetupRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "برای این حساب ورود با رمز عبور لازم است"})
+			return
+		}
+		var blocked *service.AccountBlockedError
+		if errors.As(err, &blocked) {
+			c.JSON(http.StatusForbidden, middleware.AccountBlockedBody(blocked))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if result.Challenge != nil {
+		writeLoginChallenge(c, result.Challenge)
+		return
+	}
+
 	userResp, err := h.buildAuthUserResponse(c, result.User)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -503,10 +703,64 @@ func (h *AuthController) Me(c *gin.Context) {
 		IsProfileComplete: complete,
 		CreatedAt:         user.CreatedAt,
 	}
+	resp.ImpersonatorID, _ = middleware.GetImpersonatorID(c)
 
 	c.JSON(http.StatusOK, resp)
 }
 
+// Impersonate godoc
+// @Summary Sign in as another user (admin)
+// @Description Issues a short-lived access token for the user, marked with the admin's ID. There is no refresh token; payments and password changes are blocked and every request is audited.
+// @Tags admin-users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} impersonationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/impersonate [post]
+func (h *AuthController) Impersonate(c *gin.Context) {
+	adminID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	result, err := h.authService.Impersonate(c.Request.Context(), adminID, uint(id))
+	if err != nil {
+		var blocked *service.AccountBlockedError
+		switch {
+		case errors.Is(err, service.ErrAccountNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "کاربر یافت نشد"})
+		case errors.Is(err, service.ErrCannotImpersonate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ورود به جای این کاربر مجاز نیست"})
+		case errors.As(err, &blocked):
+			c.JSON(http.StatusForbidden, middleware.AccountBlockedBody(blocked))
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	userResp, err := h.buildAuthUserResponse(c, result.User)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, impersonationResponse{
+		User:          userResp,
+		AccessToken:   result.AccessToken,
+		ExpiresAt:     result.ExpiresAt,
+		Impersonating: true,
+	})
+}
+
 // ChangePassword godoc
 // @Summary Change current user's password
 // @Description Change password by providing current and new password
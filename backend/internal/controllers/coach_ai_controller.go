@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// CoachAIController serves AI drafts for coaches. Drafts are returned only; the
+// coach applies them through the regular ticket and program endpoints.
+type CoachAIController struct {
+	coachAIService service.CoachAIService
+}
+
+func NewCoachAIController(s service.CoachAIService) *CoachAIController {
+	return &CoachAIController{coachAIService: s}
+}
+
+// DraftTicketReply godoc
+// @Summary Draft a reply to a student ticket with AI (coach)
+// @Description Nothing is sent: the coach edits the draft and answers via PATCH /coach/tickets/{id}/answer.
+// @Tags coach-ai
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Ticket ID"
+// @Param body body service.CoachAIDraftRequest false "Optional instructions"
+// @Success 200 {object} service.CoachAITicketReplyDraft
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /coach/ai/tickets/{id}/reply-draft [post]
+func (h *CoachAIController) DraftTicketReply(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ticket id"})
+		return
+	}
+	var req service.CoachAIDraftRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+
+	out, err := h.coachAIService.DraftTicketReply(c.Request.Context(), coachID, uint(id), &req)
+	if err != nil {
+		status, msg := coachAIErrorResponse(err)
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// SummarizeStudent godoc
+// @Summary Summarize a student's recent tracking and workout logs with AI (coach)
+// @Tags coach-ai
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Student ID"
+// @Param body body service.CoachAISummaryRequest false "Weeks to cover (default 4, max 12)"
+// @Success 200 {object} service.CoachAIStudentSummary
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /coach/ai/students/{id}/checkin-summary [post]
+func (h *CoachAIController) SummarizeStudent(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid student id"})
+		return
+	}
+	var req service.CoachAISummaryRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+
+	out, err := h.coachAIService.SummarizeStudent(c.Request.Context(), coachID, uint(id), &req)
+	if err != nil {
+		status, msg := coachAIErrorResponse(err)
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DraftWorkoutProgram godoc
+// @Summary Draft a workout program from a template adjusted for the student with AI (coach)
+// @Description The draft is not saved. `program` has the body shape of
+// @Description POST /coach/students/{id}/workout-programs; review `warnings` before assigning it.
+// @Tags coach-ai
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Student ID"
+// @Param body body service.CoachAIProgramDraftRequest true "Template and optional instructions"
+// @Success 200 {object} service.CoachAIProgramDraft
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /coach/ai/students/{id}/program-draft [post]
+func (h *CoachAIController) DraftWorkoutProgram(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid student id"})
+		return
+	}
+	var req service.CoachAIProgramDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	out, err := h.coachAIService.DraftWorkoutProgram(c.Request.Context(), coachID, uint(id), &req)
+	if err != nil {
+		status, msg := coachAIErrorResponse(err)
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func coachAIErrorResponse(err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrTicketNotFound):
+		return http.StatusNotFound, "تیکت یافت نشد"
+	case errors.Is(err, service.ErrCoachStudentNotFound):
+		return http.StatusNotFound, "شاگرد یافت نشد"
+	case errors.Is(err, service.ErrCoachStudentForbidden):
+		return http.StatusForbidden, "این شاگرد متعلق به شما نیست"
+	case errors.Is(err, service.ErrCoachTemplateNotFound):
+		return http.StatusNotFound, "قالب یافت نشد"
+	case errors.Is(err, service.ErrCoachTemplateForbidden):
+		return http.StatusForbidden, "دسترسی به این قالب ندارید"
+	case errors.Is(err, service.ErrAIInvalidInput):
+		return http.StatusBadRequest, "توضیحات بیش از حد طولانی است"
+	case errors.Is(err, service.ErrCoachAIInvalidDraft):
+		return http.StatusBadGateway, "پیش‌نویس معتبری تولید نشد؛ دوباره تلاش کنید"
+	default:
+		return aiChatErrorResponse(err)
+	}
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// CoachMarketplaceController exposes the coach-to-coach template marketplace:
+// browsing and acquiring approved listings, and publishing one's own templates.
+type CoachMarketplaceController struct {
+	svc service.TemplateMarketplaceService
+}
+
+func NewCoachMarketplaceController(svc service.TemplateMarketplaceService) *CoachMarketplaceController {
+	return &CoachMarketplaceController{svc: svc}
+}
+
+func (h *CoachMarketplaceController) Browse(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	page, pageSize := marketplacePaging(c)
+	resp, err := h.svc.Browse(c.Request.Context(), coachID, page, pageSize, c.Query("query"), c.Query("kind"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *CoachMarketplaceController) GetListing(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	item, err := h.svc.GetListing(c.Request.Context(), coachID, id)
+	if err != nil {
+		handleMarketplaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *CoachMarketplaceController) Acquire(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	resp, err := h.svc.Acquire(c.Request.Context(), coachID, id)
+	if err != nil {
+		handleMarketplaceError(c, err)
+		return
+	}
+	status := http.StatusOK
+	if resp.Acquired {
+		status = http.StatusCreated
+	}
+	c.JSON(status, resp)
+}
+
+func (h *CoachMarketplaceController) ListMyListings(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	items, err := h.svc.ListMyListings(c.Request.Context(), coachID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *CoachMarketplaceController) Publish(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req service.TemplateListingUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.svc.Publish(c.Request.Context(), coachID, &req)
+	if err != nil {
+		handleMarketplaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *CoachMarketplaceController) UpdateListing(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	var req service.TemplateListingUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.svc.UpdateListing(c.Request.Context(), coachID, id, &req)
+	if err != nil {
+		handleMarketplaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *CoachMarketplaceController) Unpublish(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Unpublish(c.Request.Context(), coachID, id); err != nil {
+		handleMarketplaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *CoachMarketplaceController) Report(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	report, err := h.svc.Report(c.Request.Context(), coachID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *CoachMarketplaceController) parseCoachAndID(c *gin.Context) (uint, uint, bool) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, 0, false
+	}
+	return coachID, uint(id), true
+}
+
+func marketplacePaging(c *gin.Context) (int, int) {
+	page := 1
+	if p := c.Query("page"); p != "" {
+		if v, err := strconv.Atoi(p); err == nil && v > 0 {
+			page = v
+		}
+	}
+	pageSize := 20
+	if ps := c.Query("pageSize"); ps != "" {
+		if v, err := strconv.Atoi(ps); err == nil && v > 0 && v <= 100 {
+			pageSize = v
+		}
+	}
+	return page, pageSize
+}
+
+func handleMarketplaceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrListingNotFound), errors.Is(err, service.ErrCoachTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrListingForbidden), errors.Is(err, service.ErrCoachTemplateForbidden),
+		errors.Is(err, service.ErrListingResale):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrListingAlreadyOpen), errors.Is(err, service.ErrListingAlreadyAcquired),
+		errors.Is(err, service.ErrListingNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPaymentGatewayFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// CoachNutritionCalculatorController computes calorie/macro targets and fits
+// nutrition templates to them.
+type CoachNutritionCalculatorController struct {
+	svc service.NutritionCalculatorService
+}
+
+func NewCoachNutritionCalculatorController(svc service.NutritionCalculatorService) *CoachNutritionCalculatorController {
+	return &CoachNutritionCalculatorController{svc: svc}
+}
+
+func (h *CoachNutritionCalculatorController) Calculate(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req service.NutritionCalculatorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.svc.Calculate(c.Request.Context(), coachID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *CoachNutritionCalculatorController) CalculateForStudent(c *gin.Context) {
+	coachID, studentID, ok := h.parseCoachAndParam(c, "id", "invalid student id")
+	if !ok {
+		return
+	}
+	var req service.NutritionCalculatorRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	resp, err := h.svc.CalculateForStudent(c.Request.Context(), coachID, studentID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *CoachNutritionCalculatorController) MatchTemplates(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	calories, err := strconv.Atoi(c.Query("calories"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid calories"})
+		return
+	}
+	tolerance, _ := strconv.Atoi(c.Query("tolerance"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit > 50 {
+		limit = 50
+	}
+	items, err := h.svc.MatchTemplates(c.Request.Context(), coachID, calories, tolerance, limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+type scaleNutritionTemplateRequest struct {
+	TargetCalories int `json:"targetCalories" binding:"required"`
+}
+
+func (h *CoachNutritionCalculatorController) ScaleTemplate(c *gin.Context) {
+	coachID, templateID, ok := h.parseCoachAndParam(c, "id", "invalid id")
+	if !ok {
+		return
+	}
+	var req scaleNutritionTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.svc.ScaleTemplate(c.Request.Context(), coachID, templateID, req.TargetCalories)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *CoachNutritionCalculatorController) AssignScaledTemplate(c *gin.Context) {
+	coachID, studentID, ok := h.parseCoachAndParam(c, "id", "invalid student id")
+	if !ok {
+		return
+	}
+	templateID, err := strconv.ParseUint(c.Param("templateId"), 10, 64)
+	if err != nil || templateID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id"})
+		return
+	}
+	var req service.ScaleNutritionTemplateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	resp, err := h.svc.AssignScaledTemplate(c.Request.Context(), coachID, studentID, uint(templateID), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *CoachNutritionCalculatorController) parseCoachAndParam(c *gin.Context, param, invalidMsg string) (uint, uint, bool) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidMsg})
+		return 0, 0, false
+	}
+	return coachID, uint(id), true
+}
+
+func (h *CoachNutritionCalculatorController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCoachStudentForbidden), errors.Is(err, service.ErrCoachTemplateForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCoachTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCoachNoActiveSubscription), errors.Is(err, service.ErrCalculatorMissingInput),
+		errors.Is(err, service.ErrCalculatorInvalidFormula), errors.Is(err, service.ErrCalculatorInvalidActivity),
+		errors.Is(err, service.ErrCalculatorInvalidStrategy), errors.Is(err, service.ErrCalculatorInvalidSplit),
+		errors.Is(err, service.ErrCalculatorInvalidTarget), errors.Is(err, service.ErrCalculatorTemplateNoEnergy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
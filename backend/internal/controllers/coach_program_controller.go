@@ -101,12 +101,14 @@ func (h *CoachProgramController) UpdateNutritionProgram(c *gin.Context) {
 }
 
 func (h *CoachProgramController) ListWorkoutTemplates(c *gin.Context) {
-	if _, err := middleware.GetUserID(c); err != nil {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 	page, pageSize := parseOptionalPage(c)
-	resp, err := h.programService.ListWorkoutTemplates(c.Request.Context(), page, pageSize, c.Query("query"))
+	source := c.DefaultQuery("source", "all")
+	resp, err := h.programService.ListWorkoutTemplates(c.Request.Context(), coachID, page, pageSize, c.Query("query"), source)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -115,12 +117,14 @@ func (h *CoachProgramController) ListWorkoutTemplates(c *gin.Context) {
 }
 
 func (h *CoachProgramController) ListNutritionTemplates(c *gin.Context) {
-	if _, err := middleware.GetUserID(c); err != nil {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 	page, pageSize := parseOptionalPage(c)
-	resp, err := h.programService.ListNutritionTemplates(c.Request.Context(), page, pageSize, c.Query("query"))
+	source := c.DefaultQuery("source", "all")
+	resp, err := h.programService.ListNutritionTemplates(c.Request.Context(), coachID, page, pageSize, c.Query("query"), source)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -129,7 +133,8 @@ func (h *CoachProgramController) ListNutritionTemplates(c *gin.Context) {
 }
 
 func (h *CoachProgramController) GetWorkoutTemplate(c *gin.Context) {
-	if _, err := middleware.GetUserID(c); err != nil {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
@@ -138,7 +143,7 @@ func (h *CoachProgramController) GetWorkoutTemplate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	item, err := h.programService.GetWorkoutTemplate(c.Request.Context(), uint(id))
+	item, err := h.programService.GetWorkoutTemplate(c.Request.Context(), coachID, uint(id))
 	if err != nil {
 		h.handleProgramError(c, err)
 		return
@@ -147,7 +152,8 @@ func (h *CoachProgramController) GetWorkoutTemplate(c *gin.Context) {
 }
 
 func (h *CoachProgramController) GetNutritionTemplate(c *gin.Context) {
-	if _, err := middleware.GetUserID(c); err != nil {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
@@ -156,7 +162,7 @@ func (h *CoachProgramController) GetNutritionTemplate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	item, err := h.programService.GetNutritionTemplate(c.Request.Context(), uint(id))
+	item, err := h.programService.GetNutritionTemplate(c.Request.Context(), coachID, uint(id))
 	if err != nil {
 		h.handleProgramError(c, err)
 		return
@@ -259,6 +265,8 @@ func (h *CoachProgramController) handleProgramError(c *gin.Context, err error) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
 	case errors.Is(err, service.ErrCoachTemplateNotFound):
 		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+	case errors.Is(err, service.ErrCoachTemplateForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// CoachTemplateController exposes the coach's private template library
+// (create/edit/duplicate/delete and save-from-student).
+type CoachTemplateController struct {
+	templateService service.CoachTemplateService
+}
+
+func NewCoachTemplateController(s service.CoachTemplateService) *CoachTemplateController {
+	return &CoachTemplateController{templateService: s}
+}
+
+func (h *CoachTemplateController) CreateWorkoutTemplate(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req service.AdminWorkoutTemplateUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.templateService.CreateWorkoutTemplate(c.Request.Context(), coachID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *CoachTemplateController) UpdateWorkoutTemplate(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	var req service.AdminWorkoutTemplateUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.templateService.UpdateWorkoutTemplate(c.Request.Context(), coachID, id, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *CoachTemplateController) DuplicateWorkoutTemplate(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	item, err := h.templateService.DuplicateWorkoutTemplate(c.Request.Context(), coachID, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *CoachTemplateController) DeleteWorkoutTemplate(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	if err := h.templateService.DeleteWorkoutTemplate(c.Request.Context(), coachID, id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *CoachTemplateController) SaveStudentWorkoutAsTemplate(c *gin.Context) {
+	coachID, studentID, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	var req service.AdminWorkoutTemplateUpsertRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	item, err := h.templateService.SaveStudentWorkoutAsTemplate(c.Request.Context(), coachID, studentID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *CoachTemplateController) CreateNutritionTemplate(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req service.AdminNutritionTemplateUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.templateService.CreateNutritionTemplate(c.Request.Context(), coachID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *CoachTemplateController) UpdateNutritionTemplate(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	var req service.AdminNutritionTemplateUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item, err := h.templateService.UpdateNutritionTemplate(c.Request.Context(), coachID, id, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *CoachTemplateController) DuplicateNutritionTemplate(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	item, err := h.templateService.DuplicateNutritionTemplate(c.Request.Context(), coachID, id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *CoachTemplateController) DeleteNutritionTemplate(c *gin.Context) {
+	coachID, id, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	if err := h.templateService.DeleteNutritionTemplate(c.Request.Context(), coachID, id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *CoachTemplateController) SaveStudentNutritionAsTemplate(c *gin.Context) {
+	coachID, studentID, ok := h.parseCoachAndID(c)
+	if !ok {
+		return
+	}
+	var req service.AdminNutritionTemplateUpsertRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	item, err := h.templateService.SaveStudentNutritionAsTemplate(c.Request.Context(), coachID, studentID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *CoachTemplateController) parseCoachAndID(c *gin.Context) (uint, uint, bool) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, 0, false
+	}
+	return coachID, uint(id), true
+}
+
+func (h *CoachTemplateController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCoachTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+	case errors.Is(err, service.ErrCoachTemplateForbidden), errors.Is(err, service.ErrCoachStudentForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCoachNoActiveSubscription), errors.Is(err, service.ErrCoachNoProgramToSave):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// CoachTemplateSuggestionController ranks templates for a student and can assign the top pick.
+type CoachTemplateSuggestionController struct {
+	svc service.TemplateSuggestionService
+}
+
+func NewCoachTemplateSuggestionController(svc service.TemplateSuggestionService) *CoachTemplateSuggestionController {
+	return &CoachTemplateSuggestionController{svc: svc}
+}
+
+func (h *CoachTemplateSuggestionController) GetSuggestions(c *gin.Context) {
+	coachID, studentID, ok := h.parseCoachAndStudent(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit > 50 {
+		limit = 50
+	}
+	resp, err := h.svc.Suggest(c.Request.Context(), coachID, studentID, limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type assignTopSuggestionRequest struct {
+	Kind string `json:"kind" binding:"required,oneof=workout nutrition"`
+}
+
+func (h *CoachTemplateSuggestionController) AssignTop(c *gin.Context) {
+	coachID, studentID, ok := h.parseCoachAndStudent(c)
+	if !ok {
+		return
+	}
+	var req assignTopSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.svc.AssignTop(c.Request.Context(), coachID, studentID, req.Kind)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *CoachTemplateSuggestionController) parseCoachAndStudent(c *gin.Context) (uint, uint, bool) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, 0, false
+	}
+	studentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || studentID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid student id"})
+		return 0, 0, false
+	}
+	return coachID, uint(studentID), true
+}
+
+func (h *CoachTemplateSuggestionController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCoachStudentForbidden), errors.Is(err, service.ErrCoachTemplateForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNoTemplateSuggestion), errors.Is(err, service.ErrCoachTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// ExerciseAlternativeController serves exercise substitutes and student exercise
+// swaps, plus the coach switch that allows swapping.
+type ExerciseAlternativeController struct {
+	svc service.ExerciseAlternativeService
+}
+
+func NewExerciseAlternativeController(svc service.ExerciseAlternativeService) *ExerciseAlternativeController {
+	return &ExerciseAlternativeController{svc: svc}
+}
+
+// Alternatives handles GET /exercises/:id/alternatives?equipment=a,b&location=home&limit=
+func (h *ExerciseAlternativeController) Alternatives(c *gin.Context) {
+	userID, id, ok := h.parseUserAndID(c)
+	if !ok {
+		return
+	}
+	resp, err := h.svc.Alternatives(c.Request.Context(), userID, id, alternativeOptions(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CoachAlternatives handles GET /coach/exercises/:id/alternatives?studentId=
+func (h *ExerciseAlternativeController) CoachAlternatives(c *gin.Context) {
+	coachID, id, ok := h.parseUserAndID(c)
+	if !ok {
+		return
+	}
+	var studentID uint
+	if v := c.Query("studentId"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid student id"})
+			return
+		}
+		studentID = uint(n)
+	}
+	resp, err := h.svc.CoachAlternatives(c.Request.Context(), coachID, id, studentID, alternativeOptions(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *ExerciseAlternativeController) ListMySwaps(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	resp, err := h.svc.ListMySwaps(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *ExerciseAlternativeController) CreateSwap(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req service.ExerciseSwapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	swap, err := h.svc.SwapExercise(c.Request.Context(), userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, swap)
+}
+
+func (h *ExerciseAlternativeController) RevertSwap(c *gin.Context) {
+	userID, id, ok := h.parseUserAndID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.RevertSwap(c.Request.Context(), userID, id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+type exerciseSwapPermissionRequest struct {
+	Allowed *bool `json:"allowed" binding:"required"`
+}
+
+// SetSwapPermission handles PUT /coach/students/:id/exercise-swaps/permission
+func (h *ExerciseAlternativeController) SetSwapPermission(c *gin.Context) {
+	coachID, studentID, ok := h.parseUserAndID(c)
+	if !ok {
+		return
+	}
+	var req exerciseSwapPermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.svc.SetSwapPermission(c.Request.Context(), coachID, studentID, *req.Allowed)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func alternativeOptions(c *gin.Context) service.ExerciseAlternativeOptions {
+	opts := service.ExerciseAlternativeOptions{Location: c.Query("location")}
+	if eq := strings.TrimSpace(c.Query("equipment")); eq != "" {
+		opts.Equipment = strings.Split(eq, ",")
+	}
+	opts.Limit, _ = strconv.Atoi(c.Query("limit"))
+	return opts
+}
+
+func (h *ExerciseAlternativeController) parseUserAndID(c *gin.Context) (uint, uint, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, 0, false
+	}
+	return userID, uint(id), true
+}
+
+func (h *ExerciseAlternativeController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrExerciseNotFound), errors.Is(err, service.ErrExerciseSwapNotFound),
+		errors.Is(err, service.ErrExerciseSwapInvalidItem):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrExerciseForbidden), errors.Is(err, service.ErrCoachStudentForbidden),
+		errors.Is(err, service.ErrExerciseSwapNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrExerciseSwapNotAlternative), errors.Is(err, service.ErrCoachNoActiveSubscription):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/yourusername/fitness-management/internal/middleware"
 	"github.com/yourusername/fitness-management/internal/models"
 	"github.com/yourusername/fitness-management/internal/service"
 )
@@ -21,8 +22,38 @@ func NewFunnelController(s service.FunnelService) *FunnelController {
 	return &FunnelController{funnelService: s}
 }
 
+// funnelSlug is the :slug route param; the legacy /public/funnel/* routes have
+// none and serve the configured default funnel.
+func funnelSlug(c *gin.Context) string {
+	if slug := c.Param("slug"); slug != "" {
+		return slug
+	}
+	return service.LegacyFunnelSlug()
+}
+
+// funnelVisitorCookie holds the visitor id that keeps A/B assignment sticky.
+// Clients that cannot keep cookies send the config's visitorId back instead.
+const funnelVisitorCookie = "funnel_vid"
+
+func funnelVisitorID(c *gin.Context) string {
+	if v := c.Query("visitorId"); v != "" {
+		return v
+	}
+	v, _ := c.Cookie(funnelVisitorCookie)
+	return v
+}
+
 func (h *FunnelController) GetConfig(c *gin.Context) {
-	cfg := h.funnelService.GetConfig(c.Request.Context())
+	cfg, err := h.funnelService.GetConfig(c.Request.Context(), funnelSlug(c), funnelVisitorID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrFunnelNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "فانل یافت نشد"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.SetCookie(funnelVisitorCookie, cfg.VisitorID, 365*24*3600, "/", "", false, true)
 	c.JSON(http.StatusOK, cfg)
 }
 
@@ -32,7 +63,7 @@ func (h *FunnelController) RequestLeadOTP(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
-	if err := h.funnelService.RequestLeadOTP(c.Request.Context(), req.Phone); err != nil {
+	if err := h.funnelService.RequestLeadOTP(c.Request.Context(), funnelSlug(c), req.Phone); err != nil {
 		var cooldownErr *service.OTPCooldownError
 		if errors.As(err, &cooldownErr) {
 			c.JSON(http.StatusTooManyRequests, gin.H{
@@ -41,6 +72,10 @@ func (h *FunnelController) RequestLeadOTP(c *gin.Context) {
 			})
 			return
 		}
+		if errors.Is(err, service.ErrFunnelNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "فانل یافت نشد"})
+			return
+		}
 		if errors.Is(err, service.ErrFunnelInvalidInput) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "شماره موبایل نامعتبر است"})
 			return
@@ -62,9 +97,15 @@ func (h *FunnelController) CreateLead(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.funnelService.CreateLead(c.Request.Context(), &req)
+	if req.VisitorID == "" {
+		req.VisitorID = funnelVisitorID(c)
+	}
+
+	resp, err := h.funnelService.CreateLead(c.Request.Context(), funnelSlug(c), &req)
 	if err != nil {
 		switch {
+		case errors.Is(err, service.ErrFunnelNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "فانل یافت نشد"})
 		case errors.Is(err, service.ErrFunnelInvalidOTP):
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "کد تایید نامعتبر یا منقضی است", "code": "invalid_otp"})
 		case errors.Is(err, service.ErrFunnelInvalidInput):
@@ -85,9 +126,9 @@ func (h *FunnelController) CreateLead(c *gin.Context) {
 
 func (h *FunnelController) GetCheckout(c *gin.Context) {
 	token := c.Param("token")
-	resp, err := h.funnelService.GetCheckout(c.Request.Context(), token)
+	resp, err := h.funnelService.GetCheckout(c.Request.Context(), funnelSlug(c), token)
 	if err != nil {
-		if errors.Is(err, service.ErrFunnelLeadNotFound) {
+		if errors.Is(err, service.ErrFunnelLeadNotFound) || errors.Is(err, service.ErrFunnelNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "lead not found"})
 			return
 		}
@@ -105,10 +146,10 @@ func (h *FunnelController) SelectPlan(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.funnelService.SelectPlan(c.Request.Context(), token, &req)
+	resp, err := h.funnelService.SelectPlan(c.Request.Context(), funnelSlug(c), token, &req)
 	if err != nil {
 		switch {
-		case errors.Is(err, service.ErrFunnelLeadNotFound):
+		case errors.Is(err, service.ErrFunnelLeadNotFound), errors.Is(err, service.ErrFunnelNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "lead not found"})
 		case errors.Is(err, service.ErrFunnelAlreadyPaid):
 			c.JSON(http.StatusConflict, gin.H{"error": "already paid"})
@@ -124,10 +165,10 @@ func (h *FunnelController) SelectPlan(c *gin.Context) {
 
 func (h *FunnelController) PayDemo(c *gin.Context) {
 	token := c.Param("token")
-	resp, err := h.funnelService.StartPayment(c.Request.Context(), token)
+	resp, err := h.funnelService.StartPayment(c.Request.Context(), funnelSlug(c), token)
 	if err != nil {
 		switch {
-		case errors.Is(err, service.ErrFunnelLeadNotFound):
+		case errors.Is(err, service.ErrFunnelLeadNotFound), errors.Is(err, service.ErrFunnelNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "lead not found"})
 		case errors.Is(err, service.ErrFunnelAlreadyPaid):
 			c.JSON(http.StatusConflict, gin.H{"error": "already paid"})
@@ -175,15 +216,20 @@ func writeFunnelAuthSession(c *gin.Context, result *service.AuthResult) {
 
 func (h *FunnelController) StartFreeAccess(c *gin.Context) {
 	token := c.Param("token")
-	result, err := h.funnelService.StartFreeAccess(c.Request.Context(), token)
+	result, err := h.funnelService.StartFreeAccess(c.Request.Context(), funnelSlug(c), token)
 	if err != nil {
+		var blocked *service.AccountBlockedError
 		switch {
-		case errors.Is(err, service.ErrFunnelLeadNotFound):
+		case errors.Is(err, service.ErrFunnelLeadNotFound), errors.Is(err, service.ErrFunnelNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "سفارش یافت نشد"})
+		case errors.Is(err, service.ErrFunnelFreeAccessOff):
+			c.JSON(http.StatusForbidden, gin.H{"error": "شروع رایگان در این فانل فعال نیست"})
 		case errors.Is(err, service.ErrFunnelInvalidStatus):
 			c.JSON(http.StatusBadRequest, gin.H{"error": "امکان شروع رایگان برای این سفارش نیست"})
 		case errors.Is(err, service.ErrCheckoutNotStudent):
 			c.JSON(http.StatusConflict, gin.H{"error": "این شماره مربوط به حساب دانشجو نیست"})
+		case errors.As(err, &blocked):
+			c.JSON(http.StatusForbidden, middleware.AccountBlockedBody(blocked))
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
@@ -194,13 +240,16 @@ func (h *FunnelController) StartFreeAccess(c *gin.Context) {
 
 func (h *FunnelController) IssueSession(c *gin.Context) {
 	token := c.Param("token")
-	result, err := h.funnelService.IssueSession(c.Request.Context(), token)
+	result, err := h.funnelService.IssueSession(c.Request.Context(), funnelSlug(c), token)
 	if err != nil {
+		var blocked *service.AccountBlockedError
 		switch {
-		case errors.Is(err, service.ErrFunnelLeadNotFound):
+		case errors.Is(err, service.ErrFunnelLeadNotFound), errors.Is(err, service.ErrFunnelNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "سفارش یافت نشد"})
 		case errors.Is(err, service.ErrFunnelInvalidStatus):
 			c.JSON(http.StatusBadRequest, gin.H{"error": "پرداخت هنوز تایید نشده است"})
+		case errors.As(err, &blocked):
+			c.JSON(http.StatusForbidden, middleware.AccountBlockedBody(blocked))
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
@@ -237,7 +286,7 @@ func (h *AdminFunnelController) ListLeads(c *gin.Context) {
 
 	query := c.Query("query")
 
-	resp, err := h.funnelService.ListLeads(c.Request.Context(), status, query, page, pageSize)
+	resp, err := h.funnelService.ListLeads(c.Request.Context(), funnelIDQuery(c), status, query, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -310,10 +359,350 @@ func (h *AdminFunnelController) DeleteLead(c *gin.Context) {
 }
 
 func (h *AdminFunnelController) Stats(c *gin.Context) {
-	stats, err := h.funnelService.GetStats(c.Request.Context())
+	stats, err := h.funnelService.GetStats(c.Request.Context(), funnelIDQuery(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// funnelIDQuery reads the optional ?funnelId= filter; 0 means every funnel.
+func funnelIDQuery(c *gin.Context) uint {
+	id, _ := strconv.ParseUint(c.Query("funnelId"), 10, 32)
+	return uint(id)
+}
+
+func (h *AdminFunnelController) ListFunnels(c *gin.Context) {
+	listFunnels(c, h.funnelService, 0)
+}
+
+func (h *AdminFunnelController) GetFunnel(c *gin.Context) {
+	getFunnel(c, h.funnelService, 0)
+}
+
+func (h *AdminFunnelController) CreateFunnel(c *gin.Context) {
+	createFunnel(c, h.funnelService, 0)
+}
+
+func (h *AdminFunnelController) UpdateFunnel(c *gin.Context) {
+	updateFunnel(c, h.funnelService, 0)
+}
+
+func (h *AdminFunnelController) DeleteFunnel(c *gin.Context) {
+	deleteFunnel(c, h.funnelService, 0)
+}
+
+func (h *AdminFunnelController) ListExperiments(c *gin.Context) {
+	listExperiments(c, h.funnelService, 0)
+}
+
+func (h *AdminFunnelController) CreateExperiment(c *gin.Context) {
+	saveExperiment(c, h.funnelService, 0)
+}
+
+func (h *AdminFunnelController) UpdateExperiment(c *gin.Context) {
+	saveExperiment(c, h.funnelService, 0)
+}
+
+func (h *AdminFunnelController) DeleteExperiment(c *gin.Context) {
+	changeExperiment(c, h.funnelService, 0, "delete")
+}
+
+func (h *AdminFunnelController) StartExperiment(c *gin.Context) {
+	changeExperiment(c, h.funnelService, 0, "start")
+}
+
+func (h *AdminFunnelController) StopExperiment(c *gin.Context) {
+	changeExperiment(c, h.funnelService, 0, "stop")
+}
+
+// CoachFunnelController lets an approved coach manage their own funnels.
+type CoachFunnelController struct {
+	funnelService service.FunnelService
+}
+
+func NewCoachFunnelController(s service.FunnelService) *CoachFunnelController {
+	return &CoachFunnelController{funnelService: s}
+}
+
+func (h *CoachFunnelController) ListFunnels(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		listFunnels(c, h.funnelService, coachID)
+	}
+}
+
+func (h *CoachFunnelController) GetFunnel(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		getFunnel(c, h.funnelService, coachID)
+	}
+}
+
+func (h *CoachFunnelController) CreateFunnel(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		createFunnel(c, h.funnelService, coachID)
+	}
+}
+
+func (h *CoachFunnelController) UpdateFunnel(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		updateFunnel(c, h.funnelService, coachID)
+	}
+}
+
+func (h *CoachFunnelController) DeleteFunnel(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		deleteFunnel(c, h.funnelService, coachID)
+	}
+}
+
+// Stats returns the KPI cards and A/B results of one of the coach's funnels.
+func (h *CoachFunnelController) Stats(c *gin.Context) {
+	coachID, ok := funnelCoachID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if _, err := h.funnelService.GetFunnel(c.Request.Context(), coachID, uint(id)); err != nil {
+		writeFunnelManageError(c, err)
+		return
+	}
+	stats, err := h.funnelService.GetStats(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, stats)
 }
+
+func (h *CoachFunnelController) ListExperiments(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		listExperiments(c, h.funnelService, coachID)
+	}
+}
+
+func (h *CoachFunnelController) CreateExperiment(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		saveExperiment(c, h.funnelService, coachID)
+	}
+}
+
+func (h *CoachFunnelController) UpdateExperiment(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		saveExperiment(c, h.funnelService, coachID)
+	}
+}
+
+func (h *CoachFunnelController) DeleteExperiment(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		changeExperiment(c, h.funnelService, coachID, "delete")
+	}
+}
+
+func (h *CoachFunnelController) StartExperiment(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		changeExperiment(c, h.funnelService, coachID, "start")
+	}
+}
+
+func (h *CoachFunnelController) StopExperiment(c *gin.Context) {
+	if coachID, ok := funnelCoachID(c); ok {
+		changeExperiment(c, h.funnelService, coachID, "stop")
+	}
+}
+
+func funnelCoachID(c *gin.Context) (uint, bool) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, false
+	}
+	return coachID, true
+}
+
+// The handlers below serve both admins (coachID 0) and coaches.
+
+func listFunnels(c *gin.Context, svc service.FunnelService, coachID uint) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("pageSize"))
+	resp, err := svc.ListFunnels(c.Request.Context(), coachID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func getFunnel(c *gin.Context, svc service.FunnelService, coachID uint) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	resp, err := svc.GetFunnel(c.Request.Context(), coachID, uint(id))
+	if err != nil {
+		writeFunnelManageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func createFunnel(c *gin.Context, svc service.FunnelService, coachID uint) {
+	actorID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req service.FunnelUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	resp, err := svc.CreateFunnel(c.Request.Context(), coachID, actorID, &req)
+	if err != nil {
+		writeFunnelManageError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func updateFunnel(c *gin.Context, svc service.FunnelService, coachID uint) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req service.FunnelUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	resp, err := svc.UpdateFunnel(c.Request.Context(), coachID, uint(id), &req)
+	if err != nil {
+		writeFunnelManageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func deleteFunnel(c *gin.Context, svc service.FunnelService, coachID uint) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := svc.DeleteFunnel(c.Request.Context(), coachID, uint(id)); err != nil {
+		writeFunnelManageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func listExperiments(c *gin.Context, svc service.FunnelService, coachID uint) {
+	funnelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	items, err := svc.ListExperiments(c.Request.Context(), coachID, uint(funnelID))
+	if err != nil {
+		writeFunnelManageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// saveExperiment creates an experiment, or updates the draft named by
+// :experimentId when the route has one.
+func saveExperiment(c *gin.Context, svc service.FunnelService, coachID uint) {
+	funnelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req service.FunnelExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if c.Param("experimentId") == "" {
+		resp, err := svc.CreateExperiment(c.Request.Context(), coachID, uint(funnelID), &req)
+		if err != nil {
+			writeFunnelManageError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, resp)
+		return
+	}
+	expID, err := strconv.ParseUint(c.Param("experimentId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid experiment id"})
+		return
+	}
+	resp, err := svc.UpdateExperiment(c.Request.Context(), coachID, uint(funnelID), uint(expID), &req)
+	if err != nil {
+		writeFunnelManageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// changeExperiment runs action ("start", "stop" or "delete") on :experimentId.
+func changeExperiment(c *gin.Context, svc service.FunnelService, coachID uint, action string) {
+	funnelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	expID, err := strconv.ParseUint(c.Param("experimentId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid experiment id"})
+		return
+	}
+	ctx := c.Request.Context()
+	var resp *service.FunnelExperimentDTO
+	switch action {
+	case "start":
+		resp, err = svc.StartExperiment(ctx, coachID, uint(funnelID), uint(expID))
+	case "stop":
+		resp, err = svc.StopExperiment(ctx, coachID, uint(funnelID), uint(expID))
+	default:
+		err = svc.DeleteExperiment(ctx, coachID, uint(funnelID), uint(expID))
+	}
+	if err != nil {
+		writeFunnelManageError(c, err)
+		return
+	}
+	if resp == nil {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func writeFunnelManageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrFunnelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "فانل یافت نشد"})
+	case errors.Is(err, service.ErrFunnelForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "این فانل متعلق به شما نیست"})
+	case errors.Is(err, service.ErrFunnelSlugTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "این آدرس فانل قبلاً استفاده شده است"})
+	case errors.Is(err, service.ErrFunnelInvalidDef):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "تنظیمات فانل نامعتبر است"})
+	case errors.Is(err, service.ErrFunnelExperimentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "آزمایش یافت نشد"})
+	case errors.Is(err, service.ErrFunnelExperimentInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "تنظیمات آزمایش نامعتبر است"})
+	case errors.Is(err, service.ErrFunnelExperimentConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "آزمایش دیگری روی این فانل در حال اجراست"})
+	case errors.Is(err, service.ErrFunnelExperimentStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": "این تغییر برای وضعیت فعلی آزمایش مجاز نیست"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
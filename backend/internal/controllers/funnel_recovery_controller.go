@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+type FunnelRecoveryController struct {
+	recoveryService service.FunnelRecoveryService
+}
+
+func NewFunnelRecoveryController(s service.FunnelRecoveryService) *FunnelRecoveryController {
+	return &FunnelRecoveryController{recoveryService: s}
+}
+
+// Resume is the link in recovery SMS; it sends the lead back to the site
+// checkout page of their funnel.
+func (h *FunnelRecoveryController) Resume(c *gin.Context) {
+	target, err := h.recoveryService.ResumeURL(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrFunnelLeadNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "lead not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// Report returns per-step recovery conversion for ?from=&to= (YYYY-MM-DD) and
+// an optional ?funnelId=.
+func (h *FunnelRecoveryController) Report(c *gin.Context) {
+	out, err := h.recoveryService.Report(c.Request.Context(), funnelIDQuery(c), c.Query("from"), c.Query("to"))
+	if err != nil {
+		if errors.Is(err, service.ErrFunnelRecoveryRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "بازه تاریخ نامعتبر است"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
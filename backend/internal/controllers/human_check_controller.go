@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+type HumanCheckController struct {
+	verifier service.HumanVerifier
+}
+
+// NewHumanCheckController serves challenges of verifier; nil means the
+// human check is off.
+func NewHumanCheckController(verifier service.HumanVerifier) *HumanCheckController {
+	return &HumanCheckController{verifier: verifier}
+}
+
+// Challenge godoc
+// @Summary Challenge for bot-protected forms
+// @Description When enabled, feedback, funnel lead and OTP requests must carry the answer: for kind pow, a nonce such that SHA-256(challenge + ":" + nonce) starts with difficulty zero bits, sent in the X-Pow-Challenge and X-Pow-Nonce headers. Each challenge is single-use.
+// @Tags public
+// @Produce json
+// @Success 200 {object} service.HumanChallenge
+// @Router /public/human-check [get]
+func (h *HumanCheckController) Challenge(c *gin.Context) {
+	if h.verifier == nil {
+		c.JSON(http.StatusOK, gin.H{"kind": "none"})
+		return
+	}
+	out, err := h.verifier.Challenge(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
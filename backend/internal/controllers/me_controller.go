@@ -189,7 +189,7 @@ func (h *MeController) UploadBodyPhoto(c *gin.Context) {
 // @Security BearerAuth
 // @Param page query int false "Page (default 1)"
 // @Param pageSize query int false "Page size (default 10)"
-// @Param status query string false "Filter: paid, pending, failed, refunded, or empty"
+// @Param status query string false "Filter: paid, pending, failed, refunded, refund_pending, or empty"
 // @Success 200 {object} service.MeOrderListResponse
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+type PrivacyController struct {
+	privacyService service.PrivacyService
+}
+
+func NewPrivacyController(s service.PrivacyService) *PrivacyController {
+	return &PrivacyController{privacyService: s}
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// RequestExport godoc
+// @Summary Request a copy of my personal data
+// @Description Builds a ZIP with the profile, check-ins, workout and food logs, orders, tickets and photos in the background. Poll GET /me/data-exports for the download link.
+// @Tags me-privacy
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} service.DataExportDTO
+// @Router /me/data-export [post]
+func (h *PrivacyController) RequestExport(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	out, err := h.privacyService.RequestExport(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, out)
+}
+
+// ListExports godoc
+// @Summary My personal data exports
+// @Tags me-privacy
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} service.DataExportDTO
+// @Router /me/data-exports [get]
+func (h *PrivacyController) ListExports(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	out, err := h.privacyService.ListExports(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": out})
+}
+
+// DownloadExport godoc
+// @Summary Download a ready personal data export
+// @Tags me-privacy
+// @Produce application/zip
+// @Security BearerAuth
+// @Param id path int true "Export ID"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /me/data-exports/{id}/download [get]
+func (h *PrivacyController) DownloadExport(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	path, err := h.privacyService.ExportFile(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.FileAttachment(path, fmt.Sprintf("fitinoo-data-%s.zip", time.Now().Format("20060102")))
+}
+
+// DeletionStatus godoc
+// @Summary Whether my account is scheduled for deletion
+// @Tags me-privacy
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.AccountDeletionDTO
+// @Router /me/deletion [get]
+func (h *PrivacyController) DeletionStatus(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	out, err := h.privacyService.DeletionStatus(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteAccount godoc
+// @Summary Delete my account
+// @Description Schedules the account for deletion after a grace period, during which it can be cancelled. Personal data and photos are then erased; orders and payments are kept anonymized for accounting.
+// @Tags me-privacy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body deleteAccountRequest true "Current password"
+// @Success 202 {object} service.AccountDeletionDTO
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /me [delete]
+func (h *PrivacyController) DeleteAccount(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req deleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	out, err := h.privacyService.RequestDeletion(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, out)
+}
+
+// CancelDeletion godoc
+// @Summary Cancel the scheduled deletion of my account
+// @Tags me-privacy
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.AccountDeletionDTO
+// @Failure 409 {object} map[string]string
+// @Router /me/deletion [delete]
+func (h *PrivacyController) CancelDeletion(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	out, err := h.privacyService.CancelDeletion(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *PrivacyController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDataExportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "فایل خروجی یافت نشد"})
+	case errors.Is(err, service.ErrDataExportNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": "فایل خروجی آماده نیست یا منقضی شده است"})
+	case errors.Is(err, service.ErrInvalidPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "رمز عبور فعلی نادرست است"})
+	case errors.Is(err, service.ErrAccountDeletionNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "حذف حساب فقط برای شاگردان از این مسیر ممکن است؛ با پشتیبانی تماس بگیرید"})
+	case errors.Is(err, service.ErrAccountDeletionNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "درخواست حذف حسابی ثبت نشده است"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
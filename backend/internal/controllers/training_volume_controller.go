@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// TrainingVolumeController serves weekly muscle volume, strength trends and balance
+// for the student (own data) and for coaches (a student's data).
+type TrainingVolumeController struct {
+	svc service.TrainingVolumeService
+}
+
+func NewTrainingVolumeController(svc service.TrainingVolumeService) *TrainingVolumeController {
+	return &TrainingVolumeController{svc: svc}
+}
+
+// GetMyVolume handles GET /me/training-volume?weeks=
+func (h *TrainingVolumeController) GetMyVolume(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	weeks := parseIntQuery(c.Query("weeks"), 8, 26)
+	resp, err := h.svc.Report(c.Request.Context(), userID, weeks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetStudentVolume handles GET /coach/students/:id/training-volume?weeks=
+func (h *TrainingVolumeController) GetStudentVolume(c *gin.Context) {
+	coachID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	studentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || studentID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid student id"})
+		return
+	}
+	weeks := parseIntQuery(c.Query("weeks"), 8, 26)
+	resp, err := h.svc.StudentReport(c.Request.Context(), coachID, uint(studentID), weeks)
+	if err != nil {
+		if errors.Is(err, service.ErrCoachStudentForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
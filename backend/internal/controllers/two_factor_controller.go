@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/middleware"
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+type TwoFactorController struct {
+	twoFactorService service.TwoFactorService
+}
+
+func NewTwoFactorController(s service.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{twoFactorService: s}
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type twoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	// Code is an authenticator code or a recovery code.
+	Code string `json:"code" binding:"required"`
+}
+
+// Status godoc
+// @Summary Two-factor state of the current user
+// @Tags auth-2fa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.TwoFactorStatusDTO
+// @Router /auth/2fa [get]
+func (h *TwoFactorController) Status(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	out, err := h.twoFactorService.Status(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Setup godoc
+// @Summary Start TOTP enrollment (admin, coach)
+// @Description Returns a new secret and its otpauth:// URI for the QR code. Nothing changes at login until /auth/2fa/enable.
+// @Tags auth-2fa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.TwoFactorSetupDTO
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/2fa/setup [post]
+func (h *TwoFactorController) Setup(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	out, err := h.twoFactorService.BeginSetup(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Enable godoc
+// @Summary Confirm TOTP enrollment with a code from the app
+// @Tags auth-2fa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body twoFactorCodeRequest true "Authenticator code"
+// @Success 200 {object} service.TwoFactorRecoveryCodesDTO
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/2fa/enable [post]
+func (h *TwoFactorController) Enable(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	out, err := h.twoFactorService.Enable(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Disable godoc
+// @Summary Turn TOTP off
+// @Tags auth-2fa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body twoFactorDisableRequest true "Password and a current code"
+// @Success 200 {object} messageResponse
+// @Failure 400 {object} map[string]string
+// @Router /auth/2fa/disable [post]
+func (h *TwoFactorController) Disable(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req twoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.twoFactorService.Disable(c.Request.Context(), userID, req.Password, req.Code); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, messageResponse{Message: "ورود دو مرحله‌ای غیرفعال شد"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Replace all recovery codes
+// @Tags auth-2fa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body twoFactorCodeRequest true "Authenticator code"
+// @Success 200 {object} service.TwoFactorRecoveryCodesDTO
+// @Failure 400 {object} map[string]string
+// @Router /auth/2fa/recovery-codes [post]
+func (h *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	out, err := h.twoFactorService.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *TwoFactorController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTwoFactorNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "ورود دو مرحله‌ای فقط برای مدیران و مربیان است"})
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "ورود دو مرحله‌ای از قبل فعال است"})
+	case errors.Is(err, service.ErrTwoFactorSetupMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ابتدا راه‌اندازی را شروع کنید"})
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ورود دو مرحله‌ای فعال نیست"})
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "کد تایید نامعتبر است"})
+	case errors.Is(err, service.ErrInvalidPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "رمز عبور فعلی اشتباه است"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -6,9 +6,14 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/service"
 )
 
-// AdminOnly ensures the current user has role admin (super-admin).
+// ContextPermissionsKey holds the admin's permissions as map[string]bool.
+const ContextPermissionsKey = "adminPermissions"
+
+// AdminOnly ensures the current user has role admin. What the admin may do
+// is left to RequirePermission.
 func AdminOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		roleVal, exists := c.Get(ContextRoleKey)
@@ -25,3 +30,45 @@ func AdminOnly() gin.HandlerFunc {
 	}
 }
 
+// AdminPermissions loads the admin's effective permissions for
+// RequirePermission. Use after AdminOnly.
+func AdminPermissions(access service.AdminAccessService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		dto, err := access.Access(c.Request.Context(), userID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		perms := make(map[string]bool, len(dto.Permissions))
+		for _, p := range dto.Permissions {
+			perms[p] = true
+		}
+		c.Set(ContextPermissionsKey, perms)
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the admin holds permission. Use after
+// AdminPermissions.
+func HasPermission(c *gin.Context, permission string) bool {
+	val, _ := c.Get(ContextPermissionsKey)
+	perms, _ := val.(map[string]bool)
+	return perms[permission]
+}
+
+// RequirePermission lets the request through only when the admin holds
+// permission, e.g. RequirePermission("funnel.leads.read").
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden", "permission": permission})
+			return
+		}
+		c.Next()
+	}
+}
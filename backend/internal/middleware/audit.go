@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// AuditTrail records every mutating request of the group as an AuditEvent,
+// failed ones included. It must run after AuthMiddleware. The target defaults
+// to the route's first path parameter (PATCH /admin/coaches/:id → coach);
+// services refine it and add the before/after diff via service.AuditChange.
+// Requests already audited by ImpersonationAudit pass straight through.
+func AuditTrail(audit service.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if service.AuditEntryFrom(c.Request.Context()) != nil {
+			c.Next()
+			return
+		}
+		recordAudit(c, audit, 0)
+	}
+}
+
+// recordAudit runs the rest of the chain with an audit entry in the request
+// context and stores the entry afterwards.
+func recordAudit(c *gin.Context, audit service.AuditService, impersonatorID uint) {
+	actorID, _ := GetUserID(c)
+	entry := &service.AuditEntry{
+		ActorID:        actorID,
+		ActorRole:      c.GetString(ContextRoleKey),
+		ImpersonatorID: impersonatorID,
+		Action:         c.Request.Method + " " + c.FullPath(),
+		Method:         c.Request.Method,
+		Path:           c.Request.URL.Path,
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	entry.TargetType, entry.TargetID = routeTarget(c)
+	c.Request = c.Request.WithContext(service.WithAuditEntry(c.Request.Context(), entry))
+
+	c.Next()
+
+	entry.Status = c.Writer.Status()
+	// The client may be gone by now; the event is still worth keeping.
+	if err := audit.Record(context.WithoutCancel(c.Request.Context()), entry); err != nil {
+		log.Printf("audit: recording %s %s by user %d: %v", entry.Method, entry.Path, entry.ActorID, err)
+	}
+}
+
+// routeTarget derives the target from the route pattern: the segment before
+// the first parameter names the type and the parameter is the ID. Routes
+// without parameters target their last segment with no ID.
+func routeTarget(c *gin.Context) (string, string) {
+	segments := strings.Split(strings.Trim(c.FullPath(), "/"), "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") && i > 0 {
+			return singularTarget(segments[i-1]), c.Param(seg[1:])
+		}
+	}
+	if len(segments) == 0 {
+		return "", ""
+	}
+	return singularTarget(segments[len(segments)-1]), ""
+}
+
+// singularTarget turns a route segment such as "funnel-leads" into
+// "funnel_lead".
+func singularTarget(seg string) string {
+	seg = strings.ReplaceAll(seg, "-", "_")
+	switch {
+	case strings.HasSuffix(seg, "ies"):
+		return strings.TrimSuffix(seg, "ies") + "y"
+	case strings.HasSuffix(seg, "ss"):
+		return seg
+	case strings.HasSuffix(seg, "s"):
+		return strings.TrimSuffix(seg, "s")
+	}
+	return seg
+}
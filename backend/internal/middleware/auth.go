@@ -6,13 +6,17 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/yourusername/fitness-management/internal/auth"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/service"
 )
 
 const (
-	ContextUserIDKey = "userID"
-	ContextRoleKey   = "role"
+	ContextUserIDKey       = "userID"
+	ContextRoleKey         = "role"
+	ContextImpersonatorKey = "impersonatorID"
 )
 
 var ErrNoUserID = errors.New("user id not in context")
@@ -30,9 +34,21 @@ func GetUserID(c *gin.Context) (uint, error) {
 	return id, nil
 }
 
+// GetImpersonatorID returns the admin acting through an impersonation token,
+// or false for a normal session.
+func GetImpersonatorID(c *gin.Context) (uint, bool) {
+	id, ok := c.Get(ContextImpersonatorKey)
+	if !ok {
+		return 0, false
+	}
+	adminID, ok := id.(uint)
+	return adminID, ok && adminID > 0
+}
+
 // AuthMiddleware validates the access token and injects user id and role into Gin context.
 // Accepts "Authorization: Bearer <token>" or "Authorization: <token>" (for Swagger / clients that send only the token).
-func AuthMiddleware() gin.HandlerFunc {
+// Tokens of suspended, banned or deleted users are rejected even before they expire.
+func AuthMiddleware(accounts service.AccountGuard) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := strings.TrimSpace(c.GetHeader("Authorization"))
 		if authHeader == "" {
@@ -53,14 +69,56 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 		claims, err := auth.ParseToken(tokenStr)
+		if err == nil && claims.Purpose != "" {
+			err = jwt.ErrTokenInvalidClaims
+		}
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
 			return
 		}
+		// An impersonation token dies with the admin account behind it.
+		if claims.ImpersonatorID > 0 {
+			if err := accounts.CheckAccount(c.Request.Context(), claims.ImpersonatorID); err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+				return
+			}
+		}
+		if err := accounts.CheckAccount(c.Request.Context(), claims.UserID); err != nil {
+			var blocked *service.AccountBlockedError
+			switch {
+			case errors.As(err, &blocked):
+				c.AbortWithStatusJSON(http.StatusForbidden, AccountBlockedBody(blocked))
+			case errors.Is(err, service.ErrAccountNotFound):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			default:
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
 
 		c.Set(ContextUserIDKey, claims.UserID)
 		c.Set(ContextRoleKey, claims.Role)
+		if claims.ImpersonatorID > 0 {
+			c.Set(ContextImpersonatorKey, claims.ImpersonatorID)
+		}
 
 		c.Next()
 	}
 }
+
+// AccountBlockedBody is the 403 body for a suspended or banned account, shared
+// by the auth pipeline and the login endpoints.
+func AccountBlockedBody(blocked *service.AccountBlockedError) gin.H {
+	msg := "حساب کاربری شما تعلیق شده است"
+	if blocked.Status == models.UserStatusBanned {
+		msg = "حساب کاربری شما مسدود شده است"
+	}
+	body := gin.H{"error": msg, "accountStatus": blocked.Status}
+	if blocked.Reason != "" {
+		body["reason"] = blocked.Reason
+	}
+	if blocked.Until != nil {
+		body["until"] = blocked.Until
+	}
+	return body
+}
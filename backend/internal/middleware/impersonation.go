@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// ImpersonationAudit records every request made with an impersonation token,
+// reads included. It must run right after AuthMiddleware; AuditTrail further
+// down the chain leaves such requests to it.
+func ImpersonationAudit(audit service.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, ok := GetImpersonatorID(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		recordAudit(c, audit, adminID)
+	}
+}
+
+// NoImpersonation blocks a route, such as a payment, the user's payment
+// history, a deletion or a password change, for admins acting as another user.
+func NoImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetImpersonatorID(c); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":         "این عملیات در حالت ورود به جای کاربر مجاز نیست",
+				"impersonating": true,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/fitness-management/internal/service"
+)
+
+// rateLimitBodyPeekLimit caps how much of a request body a key function reads.
+const rateLimitBodyPeekLimit = 64 << 10
+
+// RateLimitKey picks the bucket a request counts against; "" lets the
+// request through uncounted.
+type RateLimitKey func(c *gin.Context) string
+
+// ClientIPKey buckets requests by client IP.
+func ClientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// BodyFieldKey buckets requests by the first non-empty string among the
+// JSON body fields, passed through normalize (e.g. digits.NormalizePhone).
+// The body is left in place for the handler.
+func BodyFieldKey(normalize func(string) string, fields ...string) RateLimitKey {
+	return func(c *gin.Context) string {
+		if c.Request.Body == nil {
+			return ""
+		}
+		raw, err := io.ReadAll(io.LimitReader(c.Request.Body, rateLimitBodyPeekLimit))
+		if err != nil {
+			return ""
+		}
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(raw), c.Request.Body))
+
+		var body map[string]any
+		if json.Unmarshal(raw, &body) != nil {
+			return ""
+		}
+		for _, field := range fields {
+			v, _ := body[field].(string)
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			if normalize != nil {
+				v = normalize(v)
+			}
+			if v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+}
+
+// RateLimit allows limit requests per window and bucket; the rest get 429
+// with Retry-After. Routes sharing name share buckets. A limit of 0 turns
+// the check off. Limiter failures let the request through.
+func RateLimit(limiter service.RateLimiter, name string, limit int, window time.Duration, key RateLimitKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		bucket := key(c)
+		if bucket == "" {
+			c.Next()
+			return
+		}
+		ok, err := limiter.Allow(c.Request.Context(), "http:"+name+":"+bucket, limit, window)
+		if err != nil {
+			log.Printf("rate limit %s: %v", name, err)
+			c.Next()
+			return
+		}
+		if ok {
+			c.Next()
+			return
+		}
+		now := time.Now()
+		retryAfter := int((now.Truncate(window).Add(window).Sub(now) + time.Second - 1) / time.Second)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":               fmt.Sprintf("تعداد درخواست‌ها بیش از حد مجاز است؛ لطفاً %d ثانیه دیگر تلاش کنید", retryAfter),
+			"retry_after_seconds": retryAfter,
+		})
+	}
+}
+
+// RequireHuman asks verifier for proof that a person sent the request. A nil
+// verifier (human_check off) lets everything through, as do verifier
+// failures other than a rejected proof.
+func RequireHuman(verifier service.HumanVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verifier == nil {
+			c.Next()
+			return
+		}
+		if err := verifier.Verify(c.Request.Context(), c.Request); err != nil {
+			if !errors.Is(err, service.ErrHumanCheckFailed) {
+				log.Printf("human check %s: %v", verifier.Kind(), err)
+				c.Next()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":       "تایید انسان بودن ناموفق بود؛ دوباره تلاش کنید",
+				"human_check": verifier.Kind(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
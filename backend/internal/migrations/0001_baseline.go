@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/migrations/baseline"
+)
+
+// The baseline is the schema that startup AutoMigrate used to maintain. On a
+// database created that way it only adds what is missing. It migrates the
+// frozen structs in package baseline, never the live models, so every later
+// column and table comes from its own migration.
+func init() {
+	register(Migration{
+		Version: "0001",
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(baseline.Models()...)
+		},
+	})
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: "0002",
+		Name:    "backfill_user_goals",
+		Up: func(tx *gorm.DB) error {
+			// PostgreSQL json has no = operator and cannot hold '' anyway.
+			if tx.Dialector.Name() == "postgres" {
+				return tx.Exec("UPDATE users SET goals = '[]' WHERE goals IS NULL").Error
+			}
+			return tx.Exec("UPDATE users SET goals = '[]' WHERE goals IS NULL OR goals = ''").Error
+		},
+		Down: noop,
+	})
+}
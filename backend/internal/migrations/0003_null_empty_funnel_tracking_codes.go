@@ -0,0 +1,16 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Unpaid funnel leads must not store an empty string under the unique
+// tracking_code index.
+func init() {
+	register(Migration{
+		Version: "0003",
+		Name:    "null_empty_funnel_tracking_codes",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("UPDATE funnel_leads SET tracking_code = NULL WHERE tracking_code = ''").Error
+		},
+		Down: noop,
+	})
+}
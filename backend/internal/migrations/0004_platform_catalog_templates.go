@@ -0,0 +1,19 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Catalog imports (positive source_id) are platform templates; coach_id marks
+// coach-private ones only.
+func init() {
+	register(Migration{
+		Version: "0004",
+		Name:    "platform_catalog_templates",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("UPDATE workout_templates SET coach_id = NULL WHERE source_id > 0 AND coach_id IS NOT NULL").Error; err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE nutrition_templates SET coach_id = NULL WHERE source_id > 0 AND coach_id IS NOT NULL").Error
+		},
+		Down: noop,
+	})
+}
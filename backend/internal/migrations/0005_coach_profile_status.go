@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// Coach profiles predating the approval workflow get a status; the ones that
+// were already published and active count as approved.
+func init() {
+	register(Migration{
+		Version: "0005",
+		Name:    "coach_profile_status",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec(
+				"UPDATE coach_profiles SET status = ? WHERE status IS NULL OR status = ''",
+				models.CoachProfileStatusPending,
+			).Error; err != nil {
+				return err
+			}
+			return tx.Exec(
+				"UPDATE coach_profiles SET status = ? WHERE status = ? AND is_published = ? AND is_active = ?",
+				models.CoachProfileStatusApproved,
+				models.CoachProfileStatusPending,
+				true,
+				true,
+			).Error
+		},
+		Down: noop,
+	})
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// adminRoleAssignment0007 is the admin_role_assignments table as this
+// migration creates it.
+type adminRoleAssignment0007 struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	UserID    uint   `gorm:"uniqueIndex:idx_admin_role_assignment;not null"`
+	Role      string `gorm:"size:40;uniqueIndex:idx_admin_role_assignment;not null"`
+	GrantedBy *uint
+}
+
+func (adminRoleAssignment0007) TableName() string { return "admin_role_assignments" }
+
+// Admins predating admin roles keep full access as super admins.
+func init() {
+	register(Migration{
+		Version: "0007",
+		Name:    "admin_roles",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&adminRoleAssignment0007{}); err != nil {
+				return err
+			}
+			return tx.Exec(`
+				INSERT INTO admin_role_assignments (user_id, role, created_at)
+				SELECT u.id, ?, ?
+				FROM users u
+				WHERE u.role = ? AND u.deleted_at IS NULL
+				  AND NOT EXISTS (SELECT 1 FROM admin_role_assignments a WHERE a.user_id = u.id AND a.role = ?)`,
+				models.AdminRoleSuperAdmin, time.Now(), models.RoleAdmin, models.AdminRoleSuperAdmin,
+			).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&adminRoleAssignment0007{})
+		},
+	})
+}
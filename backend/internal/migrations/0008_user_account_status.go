@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// users0008 holds the columns this migration adds to users.
+type users0008 struct {
+	AccountStatus   string     `gorm:"column:account_status;size:20;not null;default:active;index"`
+	StatusReason    string     `gorm:"column:status_reason;size:500"`
+	StatusUntil     *time.Time `gorm:"column:status_until"`
+	StatusChangedAt *time.Time `gorm:"column:status_changed_at"`
+}
+
+func (users0008) TableName() string { return "users" }
+
+// Users gain an account status; everyone starts out active.
+func init() {
+	register(Migration{
+		Version: "0008",
+		Name:    "user_account_status",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&users0008{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, &users0008{}, "status_changed_at", "status_until", "status_reason", "account_status")
+		},
+	})
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// auditEvents0009 holds the column this migration adds to audit_events.
+type auditEvents0009 struct {
+	ImpersonatorID *uint `gorm:"index"`
+}
+
+func (auditEvents0009) TableName() string { return "audit_events" }
+
+// Audit events remember the admin behind an impersonated request.
+func init() {
+	register(Migration{
+		Version: "0009",
+		Name:    "audit_impersonator",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&auditEvents0009{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, &auditEvents0009{}, "impersonator_id")
+		},
+	})
+}
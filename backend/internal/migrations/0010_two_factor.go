@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// users0010 holds the column this migration adds to users.
+type users0010 struct {
+	MustChangePassword bool `gorm:"column:must_change_password;not null;default:false"`
+}
+
+func (users0010) TableName() string { return "users" }
+
+// userTwoFactor0010 and twoFactorRecoveryCode0010 are the tables as this
+// migration creates them.
+type userTwoFactor0010 struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	UserID    uint   `gorm:"uniqueIndex;not null"`
+	Secret    string `gorm:"size:64;not null"`
+	LastStep  int64  `gorm:"not null;default:0"`
+	EnabledAt *time.Time
+}
+
+func (userTwoFactor0010) TableName() string { return "user_two_factors" }
+
+type twoFactorRecoveryCode0010 struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	UserID   uint   `gorm:"index;not null"`
+	CodeHash string `gorm:"size:64;not null"`
+	UsedAt   *time.Time
+}
+
+func (twoFactorRecoveryCode0010) TableName() string { return "two_factor_recovery_codes" }
+
+// TOTP enrollment and recovery codes, plus the forced password change. A
+// default admin still on the password bootstrap.SeedDefaultAdmin gave it has
+// to pick a new one at next login.
+func init() {
+	register(Migration{
+		Version: "0010",
+		Name:    "two_factor",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&users0010{}, &userTwoFactor0010{}, &twoFactorRecoveryCode0010{}); err != nil {
+				return err
+			}
+			var admin struct {
+				ID       uint
+				Password string
+			}
+			err := tx.Table("users").Select("id, password").
+				Where("email = ? AND role = ? AND deleted_at IS NULL", "admin@gmail.com", models.RoleAdmin).
+				Take(&admin).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("12345678")) != nil {
+				return nil
+			}
+			return tx.Table("users").Where("id = ?", admin.ID).Update("must_change_password", true).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&twoFactorRecoveryCode0010{}, &userTwoFactor0010{}); err != nil {
+				return err
+			}
+			return dropColumns(tx, &users0010{}, "must_change_password")
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// users0011 and otpCodes0011 hold the columns this migration adds.
+type users0011 struct {
+	FailedLoginCount int        `gorm:"column:failed_login_count;not null;default:0"`
+	LoginLockedUntil *time.Time `gorm:"column:login_locked_until"`
+}
+
+func (users0011) TableName() string { return "users" }
+
+type otpCodes0011 struct {
+	Attempts int `gorm:"not null;default:0"`
+}
+
+func (otpCodes0011) TableName() string { return "otp_codes" }
+
+// OTP codes count wrong guesses and users count failed sign-ins for the
+// progressive password lockout.
+func init() {
+	register(Migration{
+		Version: "0011",
+		Name:    "login_throttling",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&users0011{}, &otpCodes0011{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropColumns(tx, &users0011{}, "login_locked_until", "failed_login_count"); err != nil {
+				return err
+			}
+			return dropColumns(tx, &otpCodes0011{}, "attempts")
+		},
+	})
+}
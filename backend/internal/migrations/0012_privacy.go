@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// users0012 holds the columns this migration adds to users.
+type users0012 struct {
+	DeletionScheduledAt *time.Time `gorm:"column:deletion_scheduled_at;index"`
+	AnonymizedAt        *time.Time `gorm:"column:anonymized_at"`
+}
+
+func (users0012) TableName() string { return "users" }
+
+// dataExport0012 is the data_exports table as this migration creates it.
+type dataExport0012 struct {
+	gorm.Model
+
+	UserID      uint   `gorm:"not null;index"`
+	Status      string `gorm:"size:20;not null;index"`
+	FilePath    string `gorm:"size:512"`
+	SizeBytes   int64  `gorm:"not null;default:0"`
+	Error       string `gorm:"size:500"`
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time `gorm:"index"`
+}
+
+func (dataExport0012) TableName() string { return "data_exports" }
+
+// Personal data exports, and scheduled account deletion on users.
+func init() {
+	register(Migration{
+		Version: "0012",
+		Name:    "privacy",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&users0012{}, &dataExport0012{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&dataExport0012{}); err != nil {
+				return err
+			}
+			return dropColumns(tx, &users0012{}, "anonymized_at", "deletion_scheduled_at")
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// workoutTemplates0013 and nutritionTemplates0013 hold the column this
+// migration adds to both template tables.
+type workoutTemplates0013 struct {
+	AcquiredListingID uint `gorm:"index;not null;default:0"`
+}
+
+func (workoutTemplates0013) TableName() string { return "workout_templates" }
+
+type nutritionTemplates0013 struct {
+	AcquiredListingID uint `gorm:"index;not null;default:0"`
+}
+
+func (nutritionTemplates0013) TableName() string { return "nutrition_templates" }
+
+// Templates remember the marketplace listing they were acquired from, so
+// bought copies cannot be listed again. Copies acquired before this are
+// found through their acquisition rows; earlier duplicates of them are not.
+func init() {
+	register(Migration{
+		Version: "0013",
+		Name:    "template_provenance",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&workoutTemplates0013{}, &nutritionTemplates0013{}); err != nil {
+				return err
+			}
+			for table, kind := range map[string]string{
+				"workout_templates":   models.TemplateKindWorkout,
+				"nutrition_templates": models.TemplateKindNutrition,
+			} {
+				err := tx.Exec(`UPDATE `+table+` SET acquired_listing_id = (
+					SELECT MAX(a.listing_id) FROM template_acquisitions a
+					WHERE a.template_kind = ? AND a.template_id = `+table+`.id
+				) WHERE id IN (SELECT template_id FROM template_acquisitions WHERE template_kind = ?)`, kind, kind).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropColumns(tx, &workoutTemplates0013{}, "acquired_listing_id"); err != nil {
+				return err
+			}
+			return dropColumns(tx, &nutritionTemplates0013{}, "acquired_listing_id")
+		},
+	})
+}
@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// Leads captured before funnels existed (funnel_id = 0) move to the legacy
+// funnel of the coach they were captured for: the funnel whose slug is that
+// coach's profile slug, which is what the legacy /public/funnel/* routes
+// served. The funnel is created when needed. Leads whose coach has no profile
+// fail the step so it stays pending until the data is fixed.
+func init() {
+	register(Migration{
+		Version: "0014",
+		Name:    "legacy_funnel_leads",
+		Up: func(tx *gorm.DB) error {
+			var coachIDs []uint
+			if err := tx.Model(&models.FunnelLead{}).Where("funnel_id = 0").
+				Distinct().Pluck("coach_id", &coachIDs).Error; err != nil {
+				return err
+			}
+			for _, coachID := range coachIDs {
+				funnel, err := legacyFunnelFor(tx, coachID)
+				if err != nil {
+					return err
+				}
+				res := tx.Model(&models.FunnelLead{}).Where("funnel_id = 0 AND coach_id = ?", coachID).Update("funnel_id", funnel.ID)
+				if res.Error != nil {
+					return res.Error
+				}
+				log.Printf("legacy funnel: attached %d leads to funnel %q (id=%d)", res.RowsAffected, funnel.Slug, funnel.ID)
+			}
+			return nil
+		},
+		Down: noop,
+	})
+}
+
+func legacyFunnelFor(tx *gorm.DB, coachID uint) (*models.Funnel, error) {
+	var profile models.CoachProfile
+	if err := tx.Where("user_id = ?", coachID).First(&profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("legacy funnel leads of coach %d: coach has no profile", coachID)
+		}
+		return nil, err
+	}
+
+	var funnel models.Funnel
+	err := tx.Where("slug = ?", profile.Slug).First(&funnel).Error
+	if err == nil {
+		if funnel.CoachID != coachID {
+			return nil, fmt.Errorf("legacy funnel leads of coach %d: funnel %q belongs to coach %d", coachID, funnel.Slug, funnel.CoachID)
+		}
+		return &funnel, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	title := strings.TrimSpace(profile.DisplayName)
+	if title == "" {
+		title = profile.Slug
+	}
+	funnel = models.Funnel{
+		Slug:              profile.Slug,
+		Title:             "فانل " + title,
+		CoachID:           coachID,
+		FreeAccessEnabled: true,
+		IsActive:          true,
+	}
+	if err := tx.Create(&funnel).Error; err != nil {
+		return nil, err
+	}
+	return &funnel, nil
+}
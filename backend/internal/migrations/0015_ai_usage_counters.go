@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// aiUsageCounter0015 is the ai_usage_counters table as this migration creates it.
+type aiUsageCounter0015 struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID uint   `gorm:"uniqueIndex:idx_ai_usage_counter_period,priority:1;not null"`
+	Period string `gorm:"size:10;uniqueIndex:idx_ai_usage_counter_period,priority:2;not null"`
+	Used   int64  `gorm:"not null;default:0"`
+}
+
+func (aiUsageCounter0015) TableName() string { return "ai_usage_counters" }
+
+// Per-period counters of reserved chat turns, which the AI quotas are enforced
+// on. They start empty: a counter is seeded from ai_usages when first used.
+func init() {
+	register(Migration{
+		Version: "0015",
+		Name:    "ai_usage_counters",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&aiUsageCounter0015{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&aiUsageCounter0015{})
+		},
+	})
+}
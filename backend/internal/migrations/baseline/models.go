@@ -0,0 +1,847 @@
+// Package baseline freezes the schema of migration 0001: the models as they
+// were when versioned migrations replaced startup AutoMigrate. These structs
+// only describe tables and must never change; schema changes go in a new
+// migration instead.
+package baseline
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Models lists the baseline tables in creation order.
+func Models() []any {
+	return []any{
+		&User{},
+		&CoachProfile{},
+		&CoachAchievement{},
+		&ServicePlan{},
+		&Subscription{},
+		&Transaction{},
+		&RefreshToken{},
+		&UserPhoto{},
+		&WorkoutProgram{},
+		&NutritionProgram{},
+		&ProgramItem{},
+		&ProgramItemSet{},
+		&NutritionItem{},
+		&CheckIn{},
+		&Notification{},
+		&Order{},
+		&OrderItem{},
+		&SiteSettings{},
+		&Feedback{},
+		&Ticket{},
+		&OtpCode{},
+		&Exercise{},
+		&Food{},
+		&DailyFoodLog{},
+		&WorkoutSession{},
+		&Funnel{},
+		&FunnelExperiment{},
+		&FunnelLead{},
+		&FunnelRecoveryMessage{},
+		&WorkoutSetLog{},
+		&ExerciseSwap{},
+		&WorkoutTemplate{},
+		&TemplateProgramItem{},
+		&TemplateProgramItemSet{},
+		&NutritionTemplate{},
+		&TemplateMeal{},
+		&TemplateMealItem{},
+		&TemplateListing{},
+		&TemplateAcquisition{},
+		&AIConversation{},
+		&AIMessage{},
+		&AIToolInvocation{},
+		&AIUsage{},
+		&RateLimitCounter{},
+		&MobileDevice{},
+		&MobileStoreRelease{},
+	}
+}
+
+type User struct {
+	gorm.Model
+	Name            string     `gorm:"size:255;not null"`
+	Email           string     `gorm:"size:255;uniqueIndex;not null"`
+	Phone           string     `gorm:"size:255;uniqueIndex;not null"`
+	Password        string     `gorm:"size:255;not null"`
+	Role            string     `gorm:"type:varchar(20);not null;default:'student'"`
+	AvatarURL       string     `gorm:"column:avatar_url;size:512"`
+	LastActiveAt    *time.Time `gorm:"index"`
+	HeightCm        *float64   `gorm:"column:height_cm"`
+	WeightKg        *float64   `gorm:"column:weight_kg"`
+	CoachStatus     string     `gorm:"column:coach_status;size:20"`
+	AssignedCoachID *uint      `gorm:"column:assigned_coach_id;index"`
+
+	// Extended student profile (onboarding)
+	BirthDate           *time.Time `gorm:"column:birth_date;type:date"`
+	NationalID          string     `gorm:"column:national_id;size:10"`
+	Gender              string     `gorm:"column:gender;size:20"`
+	Goals               string     `gorm:"column:goals;type:json"`
+	PrimaryGoal         string     `gorm:"column:primary_goal;size:100"`
+	TargetWeightKg      *float64   `gorm:"column:target_weight_kg"`
+	BodyCondition       string     `gorm:"column:body_condition;size:50"`
+	BodyFatPercent      *float64   `gorm:"column:body_fat_percent"`
+	MedicalHistory      string     `gorm:"column:medical_history;type:text"`
+	Injuries            string     `gorm:"column:injuries;type:text"`
+	PhysicalLimitations string     `gorm:"column:physical_limitations;type:text"`
+}
+
+type CoachProfile struct {
+	gorm.Model
+	UserID uint `gorm:"uniqueIndex;not null"`
+
+	Slug        string `gorm:"size:100;uniqueIndex;not null"`
+	DisplayName string `gorm:"size:255;not null"`
+	Title       string `gorm:"size:255"`
+
+	Bio           string `gorm:"type:text"`
+	AboutCoach    string `gorm:"type:text"`
+	Specialty     string `gorm:"size:255"`
+	AvatarURL     string `gorm:"size:500"`
+	CoverImageURL string `gorm:"size:500"`
+
+	ContactPhone string `gorm:"size:50"`
+	Instagram    string `gorm:"size:255"`
+	Telegram     string `gorm:"size:100"`
+	WhatsApp     string `gorm:"size:50"`
+	Website      string `gorm:"size:255"`
+
+	NationalID string `gorm:"size:10"`
+	City       string `gorm:"size:100"`
+
+	// Status: pending | reviewing | approved
+	Status string `gorm:"size:20;not null;default:pending;index"`
+
+	IsPublished bool `gorm:"not null;default:false"`
+	IsActive    bool `gorm:"not null;default:true"`
+}
+
+type CoachAchievement struct {
+	gorm.Model
+	CoachUserID uint   `gorm:"not null;index"`
+	Type        string `gorm:"size:30;not null;index"`
+	Title       string `gorm:"size:255;not null"`
+	Issuer      string `gorm:"size:255"`
+	Year        *int
+	Description string `gorm:"type:text"`
+	ImageURL    string `gorm:"size:500"`
+	SortOrder   int    `gorm:"not null;default:0"`
+	IsVisible   bool   `gorm:"not null;default:true"`
+}
+
+type ServicePlan struct {
+	gorm.Model
+
+	CoachID uint `gorm:"index;not null;default:0"` // owner coach (0 = legacy/platform)
+
+	// Core naming / presentation
+	Name         string `gorm:"size:255;uniqueIndex;not null"` // main title (maps to frontend title)
+	Subtitle     string `gorm:"size:255"`                      // optional subtitle
+	CourseName   string `gorm:"size:255"`                      // e.g. دوره / course name
+	Description  string `gorm:"type:text"`                     // optional long description
+	FeaturesText string `gorm:"type:text"`                     // multiline bullet-style text for UI
+
+	// Typing / categorisation
+	// Type is kept for backward compatibility (e.g. "workout", "nutrition", "both").
+	Type string `gorm:"size:50;not null"`
+
+	// Pricing
+	PriceCents         int64 `gorm:"not null"`               // base price in cents
+	DiscountPriceCents int64 `gorm:"not null;default:0"`     // discounted price in cents (0 = no discount)
+	DiscountPercent    int   `gorm:"not null;default:0"`     // redundant but useful for fast display
+	DurationDays       int   `gorm:"not null"`               // plan duration for dashboard / student UI
+	IsPopular          bool  `gorm:"not null;default:false"` // for highlighting in UI
+	IsActive           bool  `gorm:"not null;default:true"`  // soft-enable/disable plan
+
+	// AI assistant quotas for subscribers of this plan, in messages.
+	// 0 = platform default (ai.paid_* in config), -1 = unlimited.
+	AIDailyMessages   int `gorm:"not null;default:0"`
+	AIMonthlyMessages int `gorm:"not null;default:0"`
+}
+
+type Subscription struct {
+	gorm.Model
+	UserID               uint      `gorm:"not null;index"`
+	CoachID              uint      `gorm:"index;not null;default:0"`
+	ServicePlanID        uint      `gorm:"not null;index"`
+	StartsAt             time.Time `gorm:"not null"`
+	EndsAt               *time.Time
+	LastCheckInDate      *time.Time
+	NextCheckInDueDate   *time.Time
+	CheckinFrequencyDays int `gorm:"default:14"`
+	// AllowExerciseSwaps lets the student replace program exercises with approved alternatives.
+	AllowExerciseSwaps bool `gorm:"not null;default:false"`
+}
+
+type Transaction struct {
+	gorm.Model
+	OrderID        uint      `gorm:"index"`
+	SubscriptionID uint      `gorm:"index"`
+	UserID         uint      `gorm:"index;not null"`
+	AmountCents    int64     `gorm:"not null"`
+	Status         string    `gorm:"size:50;not null"`
+	Reference      string    `gorm:"size:255;uniqueIndex"`
+	Gateway        string    `gorm:"size:30"`
+	Date           time.Time `gorm:"not null"`
+}
+
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint      `gorm:"not null;index"`
+	Token     string    `gorm:"size:512;not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+type UserPhoto struct {
+	gorm.Model
+	UserID         uint       `gorm:"not null;index"`
+	SubscriptionID uint       `gorm:"index"`
+	FilePath       string     `gorm:"size:512;not null"`
+	UploadedAt     time.Time  `gorm:"not null"`
+	Type           string     `gorm:"size:50"`
+	Notes          string     `gorm:"type:text"`
+	CheckInDate    *time.Time `gorm:"index"` // nil for initial registration photos, non-nil for check-in photos
+}
+
+type WorkoutProgram struct {
+	gorm.Model
+	SubscriptionID uint      `gorm:"index;not null"`
+	CoachID        uint      `gorm:"index;not null"`
+	Version        int       `gorm:"not null;default:1"`
+	Title          string    `gorm:"size:255"`
+	Notes          string    `gorm:"type:text"`
+	DurationWeeks  int       `gorm:"not null;default:4"`
+	IsActive       bool      `gorm:"not null;default:true"`
+	LastUpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+type NutritionProgram struct {
+	gorm.Model
+	SubscriptionID uint      `gorm:"index;not null"`
+	CoachID        uint      `gorm:"index;not null"`
+	Version        int       `gorm:"not null;default:1"`
+	Title          string    `gorm:"size:255"`
+	Notes          string    `gorm:"type:text"`
+	DurationWeeks  int       `gorm:"not null;default:4"`
+	IsActive       bool      `gorm:"not null;default:true"`
+	LastUpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+type ProgramItem struct {
+	gorm.Model
+	WorkoutProgramID uint   `gorm:"not null;index"`
+	WeekNumber       int    `gorm:"not null"`
+	DayNumber        int    `gorm:"not null"`
+	OrderIndex       int    `gorm:"not null"`
+	Exercise         string `gorm:"size:255;not null"`
+	ExerciseID       *uint  `gorm:"index"`
+	// Sets and Reps are legacy aggregate fields kept for backward compatibility.
+	// When SetsDetails is populated it takes precedence for reads and writes.
+	Sets        int
+	Reps        string           `gorm:"size:100"`
+	RestTime    string           `gorm:"size:100"`
+	Tempo       string           `gorm:"size:50"`
+	Notes       string           `gorm:"type:text"`
+	SetsDetails []ProgramItemSet `gorm:"foreignKey:ProgramItemID;constraint:OnDelete:CASCADE;"`
+	// SupersetID links exercises that are performed back-to-back (shared UUID).
+	SupersetID *string `gorm:"size:36;index"`
+	// WorkoutSystemType: normal, superset, giant_set, circuit, etc.
+	WorkoutSystemType string `gorm:"size:32;not null;default:normal"`
+}
+
+type ProgramItemSet struct {
+	gorm.Model
+	ProgramItemID uint   `gorm:"not null;index"`
+	SetNumber     int    `gorm:"not null"`
+	Reps          string `gorm:"size:100"`
+	IsAMRAP       bool   `gorm:"not null;default:false"`
+}
+
+type NutritionItem struct {
+	gorm.Model
+	NutritionProgramID uint    `gorm:"not null;index"`
+	DayNumber          int     `gorm:"not null"`
+	MealNumber         int     `gorm:"not null"`
+	OrderIndex         int     `gorm:"not null"`
+	MealSlot           string  `gorm:"size:20;index"` // breakfast|lunch|dinner|snack1|snack2|snack3
+	FoodID             *uint   `gorm:"index"`
+	Food               string  `gorm:"size:255;not null"`
+	Quantity           string  `gorm:"size:100"`
+	Multiplier         float64 `gorm:"not null;default:1"`
+	Calories           int
+	Protein            float64
+	Carbs              float64
+	Fat                float64
+	Notes              string `gorm:"type:text"`
+}
+
+type CheckIn struct {
+	gorm.Model
+	UserID         uint      `gorm:"not null;index"`
+	SubscriptionID uint      `gorm:"index"` // optional; allows associating check-in to a specific subscription
+	CheckInDate    time.Time `gorm:"not null;index"`
+	Weight         float64
+	Waist          float64
+	Chest          float64
+	Hip            float64
+	Notes          string `gorm:"type:text"`
+}
+
+type Notification struct {
+	gorm.Model
+	UserID  uint   `gorm:"not null;index"`
+	Type    string `gorm:"size:50;not null"` // e.g. program_updated, checkin_reminder, message_from_coach
+	Title   string `gorm:"size:255"`
+	Message string `gorm:"type:text"`
+	IsRead  bool   `gorm:"default:false"`
+	ReadAt  *time.Time
+}
+
+type Order struct {
+	gorm.Model
+
+	UserID  uint `gorm:"index;not null"`
+	CoachID uint `gorm:"index;not null;default:0"`
+
+	// Status: pending | paid | failed | refunded
+	Status string `gorm:"size:20;not null"`
+
+	// PaymentMethod: e.g. "درگاه آنلاین"
+	PaymentMethod string `gorm:"size:100;not null"`
+
+	// TrackingCode is a human‑readable code like "TRX-7A21B3".
+	TrackingCode string `gorm:"size:100;uniqueIndex"`
+
+	DiscountPercent int    `gorm:"not null;default:0"`
+	Note            string `gorm:"type:text"`
+
+	// TotalAmountCents stores the final payable amount in smallest currency unit.
+	TotalAmountCents int64 `gorm:"not null"`
+
+	// PaidAt is set when payment succeeds.
+	PaidAt *time.Time
+
+	// Payment gateway fields (ZarinPal)
+	PaymentGateway   string `gorm:"size:30;index"`
+	GatewayAuthority string `gorm:"size:100;index"`
+	GatewayRefID     string `gorm:"size:100"`
+}
+
+type OrderItem struct {
+	gorm.Model
+
+	OrderID uint `gorm:"index;not null"`
+
+	// ItemType: "program" | "template" | "addon" | ...
+	ItemType string `gorm:"size:20;not null"`
+
+	// PlanID links to ServicePlan when the item is a sellable plan.
+	PlanID uint `gorm:"index"`
+
+	// TemplateListingID links to TemplateListing when the item is a marketplace template.
+	TemplateListingID uint `gorm:"index;not null;default:0"`
+
+	// RefID is an optional external reference (e.g. p1, a1) used by the frontend.
+	RefID string `gorm:"size:100"`
+
+	Title string `gorm:"size:255;not null"`
+	Qty   int    `gorm:"not null;default:1"`
+
+	UnitPriceCents int64 `gorm:"not null"`
+	LineTotalCents int64 `gorm:"not null"` // normally Qty * UnitPriceCents
+}
+
+type SiteSettings struct {
+	gorm.Model
+
+	// HeroImageURL stores the URL of the main hero image (if any).
+	HeroImageURL string `gorm:"size:512"`
+
+	// ShowCoachesSection controls visibility of the landing coaches/programs block.
+	// Default false so the multi-coach UI can stay off until coaches are ready.
+	ShowCoachesSection bool `gorm:"not null;default:false" json:"showCoachesSection"`
+
+	// JSON blobs are used to keep the structure flexible while still typed.
+	// They follow the shapes defined in frontend/docs/frontend-overview.md.
+	// json.RawMessage is an alias for []byte and works well with GORM for JSON columns.
+	FeatureBullets json.RawMessage `gorm:"type:json"` // { title: string, items: string[] }
+	Stats          json.RawMessage `gorm:"type:json"` // [{ id, value, label }]
+	Steps          json.RawMessage `gorm:"type:json"` // [{ id, title, text }]
+	Pillars        json.RawMessage `gorm:"type:json"` // [{ id, icon, title, desc }]
+	ContactInfo    json.RawMessage `gorm:"type:json"` // { address, phone, email, instagram, telegram, whatsapp }
+	AcademyItems   json.RawMessage `gorm:"type:json"` // [{ id,type,title,description,category,featured,duration,src,cover }]
+	FAQGroups      json.RawMessage `gorm:"type:json"` // [{ id,title,items:[{q,a}] }]
+}
+
+type Feedback struct {
+	gorm.Model
+
+	FullName string `gorm:"size:255;not null"`
+	Email    string `gorm:"size:255;not null"`
+	Phone    string `gorm:"size:50"`
+	Message  string `gorm:"type:text;not null"`
+}
+
+type Ticket struct {
+	gorm.Model
+
+	StudentID uint `gorm:"index;not null"`
+	CoachID   uint `gorm:"index;not null"`
+
+	Title    string `gorm:"size:255;not null"`
+	Priority string `gorm:"size:20;not null;default:normal"` // low | normal | high
+
+	Status string `gorm:"size:30;not null;default:pending"` // pending | in_review | answered | closed
+
+	Message string `gorm:"type:text;not null"`
+
+	Answer     string     `gorm:"type:text"`
+	AnsweredAt *time.Time `gorm:"index"`
+}
+
+type OtpCode struct {
+	gorm.Model
+
+	Phone     string    `gorm:"size:50;index;not null"`
+	Code      string    `gorm:"size:10;not null"`
+	Purpose   string    `gorm:"size:50;index;not null"` // e.g. "login", "password_reset"
+	ExpiresAt time.Time `gorm:"index;not null"`
+	UsedAt    *time.Time
+}
+
+type Exercise struct {
+	gorm.Model
+	ExternalID       string `gorm:"column:external_id;size:20;uniqueIndex;not null"`
+	CoachID          *uint  `gorm:"column:coach_id;index"` // nil = global dataset; set = coach-owned custom exercise
+	Name             string `gorm:"size:255;not null"`
+	Category         string `gorm:"size:100;index"`
+	BodyPart         string `gorm:"column:body_part;size:100;index"`
+	Equipment        string `gorm:"size:100;index"`
+	Description      string `gorm:"type:text"`
+	InstructionSteps string `gorm:"column:instruction_steps;type:json"`
+	MuscleGroup      string `gorm:"column:muscle_group;size:100"`
+	Target           string `gorm:"size:100;index"`
+	SecondaryMuscles string `gorm:"column:secondary_muscles;type:json"`
+	// Contraindications lists injury regions (knee, back, shoulder, ...) the exercise
+	// should be avoided with, stored as a JSON array.
+	Contraindications string `gorm:"column:contraindications;type:text"`
+	ImagePath         string `gorm:"column:image_path;size:500"`
+	GifPath           string `gorm:"column:gif_path;size:500"`
+	IsActive          bool   `gorm:"not null;default:true"`
+}
+
+type Food struct {
+	gorm.Model
+	ExternalID string  `gorm:"column:external_id;size:64;uniqueIndex;not null"`
+	Name       string  `gorm:"size:255;not null;index"`
+	Unit       string  `gorm:"size:50;not null"`
+	Amount     float64 `gorm:"not null"`
+	Calories   float64
+	Fat        float64
+	Protein    float64
+	Carbs      float64
+	Fiber      *float64
+	Sugar      *float64
+}
+
+type DailyFoodLog struct {
+	gorm.Model
+	UserID   uint      `gorm:"not null;index:idx_daily_food_log_user_date,priority:1"`
+	LogDate  time.Time `gorm:"not null;index:idx_daily_food_log_user_date,priority:2"`
+	FoodID   *uint     `gorm:"index"`
+	FoodName string    `gorm:"size:255;not null"`
+	Quantity string    `gorm:"size:100"`
+	// MealType: breakfast | lunch | dinner | snack (empty = legacy / uncategorized)
+	MealType string `gorm:"size:32;index"`
+	Calories float64
+	Protein  float64
+	Carbs    float64
+	Fat      float64
+}
+
+type WorkoutSession struct {
+	gorm.Model
+	UserID           uint      `gorm:"not null;index"`
+	SubscriptionID   uint      `gorm:"not null;index"`
+	WorkoutProgramID uint      `gorm:"index"`
+	ProgramTitle     string    `gorm:"size:255"`
+	DayKey           string    `gorm:"size:10;not null;index"`
+	DayLabel         string    `gorm:"size:50"`
+	ExerciseCount    int       `gorm:"not null;default:0"`
+	DurationMin      int       `gorm:"not null;default:0"`
+	Notes            string    `gorm:"type:text"`
+	CompletedAt      time.Time `gorm:"not null;index"`
+}
+
+type Funnel struct {
+	gorm.Model
+
+	Slug    string `gorm:"size:80;uniqueIndex;not null"`
+	Title   string `gorm:"size:255;not null"`
+	CoachID uint   `gorm:"index;not null"` // coach user ID
+
+	// PlanIDs is a JSON array of the ServicePlan IDs offered; empty offers all
+	// of the coach's active plans.
+	PlanIDs string `gorm:"type:text"`
+	// Questions is the JSON question set; empty uses the built-in set.
+	Questions string `gorm:"type:text"`
+	// AnalysisCopy is a JSON object of scenario → {title, body}.
+	AnalysisCopy string `gorm:"type:text"`
+
+	// FreeAccessEnabled lets a lead open a student account without paying.
+	FreeAccessEnabled bool `gorm:"not null;default:true"`
+	IsActive          bool `gorm:"not null;default:true"`
+
+	CreatedByID uint `gorm:"not null;default:0"`
+}
+
+func (Funnel) TableName() string {
+	return "funnels"
+}
+
+type FunnelExperiment struct {
+	gorm.Model
+
+	FunnelID uint   `gorm:"index;not null"`
+	Name     string `gorm:"size:120;not null"`
+	Status   string `gorm:"size:20;index;not null;default:'draft'"`
+	// Variants is a JSON array of {key, name, weight, planOrder, defaultPlanId,
+	// headline, analysisCopy, questionOrder}; the first variant is the control.
+	Variants string `gorm:"type:text;not null"`
+
+	StartedAt *time.Time
+	EndedAt   *time.Time
+}
+
+func (FunnelExperiment) TableName() string {
+	return "funnel_experiments"
+}
+
+type FunnelLead struct {
+	gorm.Model
+
+	FunnelID      uint   `gorm:"index;not null;default:0"`
+	CheckoutToken string `gorm:"size:64;uniqueIndex;not null"`
+	CoachID       uint   `gorm:"index;not null;default:0"`
+	CoachName     string `gorm:"size:120;not null"`
+
+	FirstName string `gorm:"size:60;not null"`
+	LastName  string `gorm:"size:60;not null"`
+	Phone     string `gorm:"size:20;index;not null"`
+
+	PrimaryGoal        string `gorm:"size:30;not null"`
+	ActivityLevel      string `gorm:"size:30;not null"`
+	TrainingEnv        string `gorm:"size:30"`
+	Experience         string `gorm:"size:30"`
+	NutritionChallenge string `gorm:"size:30"`
+	MainObstacle       string `gorm:"size:30;not null"`
+	Commitment         string `gorm:"size:30"`
+	Scenario           string `gorm:"size:20;not null"`
+
+	AnalysisTitle string `gorm:"size:255;not null"`
+	AnalysisBody  string `gorm:"type:text;not null"`
+
+	ServicePlanID uint   `gorm:"index;not null;default:0"`
+	PackageKey    string `gorm:"size:40;not null;default:''"` // stringified ServicePlanID (UI key)
+	PackageTitle  string `gorm:"size:255;not null"`
+	AmountCents   int64  `gorm:"not null"`
+	Status        string `gorm:"size:30;not null;index"`
+	// OrderID links the lead to a real ZarinPal checkout order (0 until pay starts).
+	OrderID uint `gorm:"index;not null;default:0"`
+	// TrackingCode is set only after payment. Use pointer so unpaid leads store NULL
+	// (MySQL unique index allows multiple NULLs; empty string '' would collide).
+	TrackingCode  *string `gorm:"size:100;uniqueIndex"`
+	PaymentMethod string  `gorm:"size:100"`
+	UTMSource     string  `gorm:"size:120"`
+	UTMCampaign   string  `gorm:"size:120"`
+	// ExperimentID/VariantKey record the A/B variant the lead saw (0/'' = none).
+	ExperimentID uint   `gorm:"index;not null;default:0"`
+	VariantKey   string `gorm:"size:40;not null;default:''"`
+
+	// Abandoned-checkout recovery: RecoveryStep counts the reminder SMS sent;
+	// RecoveredAt/RecoveredByStep attribute a payment made after one.
+	RecoveryStep              int `gorm:"not null;default:0"`
+	RecoverySentAt            *time.Time
+	RecoveryDiscountPercent   int `gorm:"not null;default:0"`
+	RecoveryDiscountExpiresAt *time.Time
+	RecoveredAt               *time.Time `gorm:"index"`
+	RecoveredByStep           int        `gorm:"not null;default:0"`
+
+	PaidAt      *time.Time
+	ContactedAt *time.Time
+}
+
+func (FunnelLead) TableName() string {
+	return "funnel_leads"
+}
+
+type FunnelRecoveryMessage struct {
+	gorm.Model
+
+	LeadID          uint   `gorm:"index;not null"`
+	FunnelID        uint   `gorm:"index;not null;default:0"`
+	Step            int    `gorm:"not null"`
+	DiscountPercent int    `gorm:"not null;default:0"`
+	Status          string `gorm:"size:20;not null"`
+	Error           string `gorm:"size:255"`
+}
+
+func (FunnelRecoveryMessage) TableName() string {
+	return "funnel_recovery_messages"
+}
+
+type WorkoutSetLog struct {
+	gorm.Model
+	UserID           uint      `gorm:"not null;index"`
+	WorkoutSessionID uint      `gorm:"index"`
+	SubscriptionID   uint      `gorm:"index"`
+	ExerciseName     string    `gorm:"size:255;not null"`
+	ExerciseID       *uint     `gorm:"index"`
+	SetNumber        int       `gorm:"not null;default:1"`
+	WeightKg         float64   `gorm:"not null;default:0"`
+	Reps             int       `gorm:"not null;default:0"`
+	PerformedAt      time.Time `gorm:"not null;index"`
+}
+
+type ExerciseSwap struct {
+	gorm.Model
+
+	UserID           uint `gorm:"index;not null"`
+	SubscriptionID   uint `gorm:"index;not null"`
+	WorkoutProgramID uint `gorm:"index;not null"`
+	ProgramItemID    uint `gorm:"index;not null"`
+
+	OriginalExerciseID    *uint  `gorm:"index"`
+	OriginalExercise      string `gorm:"size:255;not null"`
+	ReplacementExerciseID uint   `gorm:"index;not null"`
+	ReplacementExercise   string `gorm:"size:255;not null"`
+	Reason                string `gorm:"size:255"`
+}
+
+type WorkoutTemplate struct {
+	gorm.Model
+	SourceID int                   `gorm:"uniqueIndex;not null"`
+	Title    string                `gorm:"size:255;not null"`
+	Type     string                `gorm:"size:50"`
+	Gender   string                `gorm:"size:50"`
+	Location string                `gorm:"size:100"`
+	DayCount int                   `gorm:"not null;default:1"`
+	Target   string                `gorm:"size:100"`
+	Injury   string                `gorm:"size:100"`
+	Level    string                `gorm:"size:100"`
+	CoachID  *uint                 `gorm:"index"`
+	Items    []TemplateProgramItem `gorm:"foreignKey:WorkoutTemplateID;constraint:OnDelete:CASCADE;"`
+}
+
+type TemplateProgramItem struct {
+	gorm.Model
+	WorkoutTemplateID uint                     `gorm:"not null;index"`
+	DayNumber         int                      `gorm:"not null"`
+	OrderIndex        int                      `gorm:"not null"`
+	ExerciseID        *uint                    `gorm:"index"`
+	Exercise          string                   `gorm:"size:255;not null"`
+	Notes             string                   `gorm:"type:text"`
+	SupersetID        *string                  `gorm:"size:36;index"`
+	WorkoutSystemType string                   `gorm:"size:32;not null;default:normal"`
+	SetsDetails       []TemplateProgramItemSet `gorm:"foreignKey:TemplateProgramItemID;constraint:OnDelete:CASCADE;"`
+}
+
+type TemplateProgramItemSet struct {
+	gorm.Model
+	TemplateProgramItemID uint   `gorm:"not null;index"`
+	SetNumber             int    `gorm:"not null"`
+	SetType               string `gorm:"size:100"`
+	Reps                  string `gorm:"size:100"`
+	IsAMRAP               bool   `gorm:"not null;default:false"`
+	SetHash               string `gorm:"size:32;index"`
+}
+
+type NutritionTemplate struct {
+	gorm.Model
+	SourceID    int    `gorm:"uniqueIndex;not null"`
+	Title       string `gorm:"size:255;not null"`
+	Type        string `gorm:"size:50"`
+	Gender      string `gorm:"size:50"`
+	Target      string `gorm:"size:100"`
+	Limitation  string `gorm:"size:100"`
+	Calorie     int
+	Description string         `gorm:"type:text"`
+	IsPro       bool           `gorm:"not null;default:false"`
+	Version     int            `gorm:"not null;default:1"`
+	CoachID     *uint          `gorm:"index"`
+	Meals       []TemplateMeal `gorm:"foreignKey:NutritionTemplateID;constraint:OnDelete:CASCADE;"`
+}
+
+type TemplateMeal struct {
+	gorm.Model
+	NutritionTemplateID uint   `gorm:"not null;index"`
+	MealOrder           int    `gorm:"not null"`
+	MealName            string `gorm:"size:100;not null"`
+	MealCalorie         int
+	StartTime           string             `gorm:"size:20"`
+	EndTime             string             `gorm:"size:20"`
+	Items               []TemplateMealItem `gorm:"foreignKey:TemplateMealID;constraint:OnDelete:CASCADE;"`
+}
+
+type TemplateMealItem struct {
+	gorm.Model
+	TemplateMealID uint   `gorm:"not null;index"`
+	MenuName       string `gorm:"size:255"`
+	OrderIndex     int    `gorm:"not null"`
+	FoodID         *uint  `gorm:"index"`
+	FoodName       string `gorm:"size:255;not null"`
+	FoodImage      string `gorm:"size:500"`
+	Unit           string `gorm:"size:50"`
+	Value          float64
+	Description    string `gorm:"type:text"`
+}
+
+type TemplateListing struct {
+	gorm.Model
+
+	AuthorID     uint   `gorm:"index;not null"`
+	TemplateKind string `gorm:"size:20;not null;index:idx_listing_template"` // workout | nutrition
+	TemplateID   uint   `gorm:"not null;index:idx_listing_template"`
+
+	Title       string `gorm:"size:255;not null"`
+	Description string `gorm:"type:text"`
+	// PriceCents is 0 for free listings; paid ones go through Order + ZarinPal.
+	PriceCents int64 `gorm:"not null;default:0"`
+
+	// Status: pending | approved | rejected | unlisted
+	Status       string `gorm:"size:20;not null;index;default:pending"`
+	ReviewNote   string `gorm:"type:text"`
+	ReviewedByID *uint
+	ReviewedAt   *time.Time
+}
+
+type TemplateAcquisition struct {
+	gorm.Model
+
+	ListingID uint `gorm:"not null;uniqueIndex:idx_acquisition_listing_buyer"`
+	BuyerID   uint `gorm:"not null;uniqueIndex:idx_acquisition_listing_buyer;index"`
+	AuthorID  uint `gorm:"index;not null"`
+
+	// TemplateID is the buyer's copy (WorkoutTemplate or NutritionTemplate per TemplateKind).
+	TemplateKind string `gorm:"size:20;not null"`
+	TemplateID   uint   `gorm:"not null"`
+
+	// OrderID is 0 for free listings.
+	OrderID    uint  `gorm:"index;not null;default:0"`
+	PriceCents int64 `gorm:"not null;default:0"`
+}
+
+type AIConversation struct {
+	gorm.Model
+
+	UserID        uint      `gorm:"index;not null"`
+	Title         string    `gorm:"size:255;not null"`
+	PagePath      string    `gorm:"size:255"`
+	MessageCount  int       `gorm:"not null;default:0"`
+	LastMessageAt time.Time `gorm:"index;not null"`
+}
+
+type AIMessage struct {
+	gorm.Model
+
+	ConversationID uint   `gorm:"index;not null"`
+	UserID         uint   `gorm:"index;not null"`
+	Role           string `gorm:"size:20;not null"` // user | assistant
+	Content        string `gorm:"type:text;not null"`
+	TokenEstimate  int    `gorm:"not null;default:0"`
+	ReplyToID      *uint  `gorm:"index"`
+	// Sources is a JSON array of the knowledge documents the reply cited.
+	Sources string `gorm:"type:text"`
+
+	Flagged        bool   `gorm:"index;not null;default:false"`
+	FlagReason     string `gorm:"size:40"`
+	FlaggedContent string `gorm:"type:text"`
+
+	Rating       int8   `gorm:"not null;default:0"` // 1 thumbs up | -1 thumbs down
+	FeedbackNote string `gorm:"size:500"`
+	FeedbackAt   *time.Time
+}
+
+type AIToolInvocation struct {
+	gorm.Model
+
+	UserID         uint   `gorm:"index;not null"`
+	ConversationID *uint  `gorm:"index"`
+	MessageID      *uint  `gorm:"index"`
+	Tool           string `gorm:"size:60;index;not null"`
+	Arguments      string `gorm:"type:text"`
+	Status         string `gorm:"size:20;not null"` // ok | error
+	Error          string `gorm:"size:255"`
+	ResultBytes    int    `gorm:"not null;default:0"`
+	DurationMs     int64  `gorm:"not null;default:0"`
+}
+
+type AIUsage struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"index:idx_ai_usage_user_created,priority:2"`
+
+	UserID         uint   `gorm:"index:idx_ai_usage_user_created,priority:1;not null"`
+	CoachID        uint   `gorm:"index;not null;default:0"` // coach of the active subscription (0 = none)
+	ServicePlanID  uint   `gorm:"not null;default:0"`       // 0 = free user
+	ConversationID uint   `gorm:"index;not null"`
+	MessageID      uint   `gorm:"not null"`
+	Day            string `gorm:"size:10;index;not null"` // 2006-01-02, server local time
+	Feature        string `gorm:"size:20;not null;default:chat"`
+	Provider       string `gorm:"size:40"`
+	Model          string `gorm:"size:100"`
+
+	PromptTokens     int `gorm:"not null;default:0"`
+	CompletionTokens int `gorm:"not null;default:0"`
+	// TokensEstimated is set when the provider did not report usage and the
+	// counts are local estimates.
+	TokensEstimated bool  `gorm:"not null;default:false"`
+	CostMicroUSD    int64 `gorm:"not null;default:0"`
+}
+
+type RateLimitCounter struct {
+	ID uint `gorm:"primaryKey"`
+	// BucketKey is the limit key plus the window start, e.g. "ai:user:7:1760000000".
+	BucketKey string    `gorm:"size:191;uniqueIndex;not null"`
+	Hits      int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+type MobileDevice struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      *uint     `gorm:"index" json:"userId,omitempty"`
+	DeviceID    string    `gorm:"size:128;uniqueIndex;not null" json:"deviceId"`
+	Store       string    `gorm:"size:32;index;not null" json:"store"`    // myket|bazaar|play|appstore
+	Platform    string    `gorm:"size:16;index;not null" json:"platform"` // android|ios
+	AppVersion  string    `gorm:"size:32" json:"appVersion"`
+	BuildNumber string    `gorm:"size:32" json:"buildNumber"`
+	OSVersion   string    `gorm:"size:64" json:"osVersion"`
+	Model       string    `gorm:"size:128" json:"model"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	LastSeenAt  time.Time `gorm:"index" json:"lastSeenAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func (MobileDevice) TableName() string { return "mobile_devices" }
+
+type MobileStoreRelease struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	Store            string     `gorm:"size:32;index;not null" json:"store"`
+	VersionName      string     `gorm:"size:32;not null" json:"versionName"`
+	VersionCode      int        `gorm:"not null;default:1" json:"versionCode"`
+	ReleaseNotes     string     `gorm:"type:text" json:"releaseNotes"`
+	IsPublished      bool       `gorm:"not null;default:false" json:"isPublished"`
+	DownloadURL      string     `gorm:"size:512" json:"downloadUrl"`
+	MinOS            string     `gorm:"size:32" json:"minOs"`
+	InstallsReported int64      `gorm:"not null;default:0" json:"installsReported"`
+	ReleasedAt       *time.Time `json:"releasedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+}
+
+func (MobileStoreRelease) TableName() string { return "mobile_store_releases" }
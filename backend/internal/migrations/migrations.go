@@ -0,0 +1,263 @@
+// Package migrations holds the versioned schema migrations and the runner
+// behind cmd/migrate. Each migration lives in its own NNNN_name.go file and
+// registers itself from init; versions apply in ascending order and are
+// recorded in the schema_migrations table. Migrations never AutoMigrate the
+// live models: each one declares frozen structs for the tables and columns it
+// adds, so replaying the history always builds the same schema.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIrreversible = errors.New("migration cannot be rolled back")
+	ErrInvalidName  = errors.New("migration name must be lowercase letters, digits and underscores")
+)
+
+// Migration is one schema step. Down is nil for steps that cannot be undone.
+// MySQL commits DDL implicitly, so Up and Down should be safe to re-run after
+// a partial failure (AutoMigrate, IF EXISTS, idempotent UPDATEs).
+type Migration struct {
+	Version string // zero-padded, e.g. "0003"
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is a row of schema_migrations: one applied version.
+type SchemaMigration struct {
+	Version   string `gorm:"primaryKey;size:32"`
+	Name      string `gorm:"size:191;not null"`
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status reports one migration for `migrate status`. Unknown marks versions
+// recorded in the database that this build does not ship.
+type Status struct {
+	Version   string
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+var registry []Migration
+
+// register adds m to the set; called from each migration file's init.
+func register(m Migration) {
+	for _, existing := range registry {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("migrations: duplicate version %s (%s, %s)", m.Version, existing.Name, m.Name))
+		}
+	}
+	registry = append(registry, m)
+	sort.Slice(registry, func(i, j int) bool { return registry[i].Version < registry[j].Version })
+}
+
+// All returns every registered migration in version order.
+func All() []Migration {
+	return append([]Migration(nil), registry...)
+}
+
+func noop(*gorm.DB) error { return nil }
+
+// dropColumns removes those of columns that model's table still has.
+func dropColumns(tx *gorm.DB, model any, columns ...string) error {
+	for _, col := range columns {
+		if !tx.Migrator().HasColumn(model, col) {
+			continue
+		}
+		if err := tx.Migrator().DropColumn(model, col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureTable(db *gorm.DB) error {
+	return db.AutoMigrate(&SchemaMigration{})
+}
+
+func applied(db *gorm.DB) (map[string]SchemaMigration, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	var rows []SchemaMigration
+	if err := db.Order("version ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]SchemaMigration, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+// pending returns the migrations in all that are not in done, in order.
+func pending(all []Migration, done map[string]SchemaMigration) []Migration {
+	var out []Migration
+	for _, m := range all {
+		if _, ok := done[m.Version]; !ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Pending returns the migrations not yet applied to db.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	return pending(registry, done), nil
+}
+
+// Up applies every pending migration, stopping at the first failure.
+func Up(db *gorm.DB) (int, error) {
+	todo, err := Pending(db)
+	if err != nil {
+		return 0, err
+	}
+	for i, m := range todo {
+		log.Printf("migrate: applying %s_%s", m.Version, m.Name)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("migration %s_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return len(todo), nil
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+func Down(db *gorm.DB, steps int) (int, error) {
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+	byVersion := make(map[string]Migration, len(registry))
+	for _, m := range registry {
+		byVersion[m.Version] = m
+	}
+	versions := make([]string, 0, len(done))
+	for v := range done {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+
+	for i := 0; i < steps && i < len(versions); i++ {
+		m, ok := byVersion[versions[i]]
+		if !ok {
+			return i, fmt.Errorf("migration %s is applied but not known to this build", versions[i])
+		}
+		if m.Down == nil {
+			return i, fmt.Errorf("migration %s_%s: %w", m.Version, m.Name, ErrIrreversible)
+		}
+		log.Printf("migrate: rolling back %s_%s", m.Version, m.Name)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("migration %s_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return min(steps, len(versions)), nil
+}
+
+// StatusOf lists every known migration plus any unknown applied version.
+func StatusOf(db *gorm.DB) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(registry))
+	for _, m := range registry {
+		st := Status{Version: m.Version, Name: m.Name}
+		if row, ok := done[m.Version]; ok {
+			at := row.AppliedAt
+			st.AppliedAt = &at
+			delete(done, m.Version)
+		}
+		out = append(out, st)
+	}
+	for _, row := range done {
+		at := row.AppliedAt
+		out = append(out, Status{Version: row.Version, Name: row.Name, AppliedAt: &at, Unknown: true})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+var (
+	migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+	migrationFilePattern = regexp.MustCompile(`^(\d{4})_[a-z0-9_]+\.go$`)
+)
+
+// Create writes an empty NNNN_name.go migration into dir, numbered after the
+// highest version found there, and returns its path.
+func Create(dir, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !migrationNamePattern.MatchString(name) {
+		return "", ErrInvalidName
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	next := 1
+	for _, e := range entries {
+		if m := migrationFilePattern.FindStringSubmatch(e.Name()); m != nil {
+			if n, _ := strconv.Atoi(m[1]); n >= next {
+				next = n + 1
+			}
+		}
+	}
+	version := fmt.Sprintf("%04d", next)
+	path := filepath.Join(dir, version+"_"+name+".go")
+	src := fmt.Sprintf(migrationTemplate, version, name)
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+const migrationTemplate = `package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: %q,
+		Name:    %q,
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`
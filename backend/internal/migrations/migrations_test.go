@@ -0,0 +1,170 @@
+package migrations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+func TestRegistryIsOrderedAndComplete(t *testing.T) {
+	all := All()
+	if len(all) == 0 || all[0].Version != "0001" {
+		t.Fatal("baseline must be the first migration")
+	}
+	for i, m := range all {
+		if m.Up == nil {
+			t.Fatalf("%s_%s has no Up step", m.Version, m.Name)
+		}
+		if i > 0 && all[i-1].Version >= m.Version {
+			t.Fatalf("versions out of order at %s", m.Version)
+		}
+	}
+
+	done := map[string]SchemaMigration{"0001": {Version: "0001"}, "0003": {Version: "0003"}}
+	todo := pending(all, done)
+	if len(todo) != len(all)-2 || todo[0].Version != "0002" {
+		t.Fatalf("pending should skip applied versions, got %d starting %s", len(todo), todo[0].Version)
+	}
+}
+
+func TestCreateNumbersAfterHighestFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"0001_baseline.go", "0007_add_things.go", "migrations.go"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("package migrations\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, err := Create(dir, "Add_Audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "0008_add_audit.go" {
+		t.Fatalf("got %s", path)
+	}
+	if _, err := Create(dir, "bad-name"); err != ErrInvalidName {
+		t.Fatalf("want ErrInvalidName, got %v", err)
+	}
+}
+
+func TestUpAndDownOnSQLite(t *testing.T) {
+	db, err := config.NewSQLiteGORM(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := Up(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(All()) {
+		t.Fatalf("applied %d of %d", n, len(All()))
+	}
+	if todo, err := Pending(db); err != nil || len(todo) != 0 {
+		t.Fatalf("nothing should be pending, got %d (%v)", len(todo), err)
+	}
+	if n, err := Up(db); err != nil || n != 0 {
+		t.Fatalf("second up should be a no-op, got %d (%v)", n, err)
+	}
+
+	// Roll back to the baseline, which refuses to go further.
+	if _, err := Down(db, len(All())-1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Down(db, 1); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("baseline rollback should be refused, got %v", err)
+	}
+	rows, err := StatusOf(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].AppliedAt == nil || rows[1].AppliedAt != nil {
+		t.Fatalf("only the baseline should remain applied: %+v", rows[:2])
+	}
+}
+
+// The migrations alone must produce every table, column and index of the live
+// models: a model change without a migration fails here.
+func TestMigrationsMatchModels(t *testing.T) {
+	db, err := config.NewSQLiteGORM(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+	m := db.Migrator()
+	for _, model := range models.AllModels() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		if !m.HasTable(model) {
+			t.Errorf("no migration creates %s", stmt.Schema.Table)
+			continue
+		}
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName != "" && !m.HasColumn(model, f.DBName) {
+				t.Errorf("no migration adds %s.%s", stmt.Schema.Table, f.DBName)
+			}
+		}
+		for _, idx := range stmt.Schema.ParseIndexes() {
+			if !m.HasIndex(model, idx.Name) {
+				t.Errorf("no migration creates index %s on %s", idx.Name, stmt.Schema.Table)
+			}
+		}
+	}
+}
+
+func TestLegacyFunnelLeadsBackfill(t *testing.T) {
+	db, err := config.NewSQLiteGORM(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var step Migration
+	for _, m := range All() {
+		if m.Name == "legacy_funnel_leads" {
+			step = m
+		}
+	}
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Where("version = ?", step.Version).Delete(&SchemaMigration{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	lead := &models.FunnelLead{CheckoutToken: "legacy", CoachID: 7, FirstName: "Sara", LastName: "Ahmadi", Phone: "09120000000"}
+	if err := db.Create(lead).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the coach's profile the step fails and stays pending.
+	if _, err := Up(db); err == nil {
+		t.Fatal("backfill without the lead's coach should fail")
+	}
+	if todo, err := Pending(db); err != nil || len(todo) != 1 || todo[0].Version != step.Version {
+		t.Fatalf("backfill should still be pending, got %+v (%v)", todo, err)
+	}
+
+	profile := &models.CoachProfile{UserID: 7, Slug: "ali-rashidabadi", DisplayName: "Ali"}
+	if err := db.Create(profile).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+	var funnel models.Funnel
+	if err := db.Where("slug = ?", profile.Slug).First(&funnel).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(lead, lead.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if funnel.CoachID != profile.UserID || lead.FunnelID != funnel.ID {
+		t.Fatalf("funnel %+v, lead attached to %d", funnel, lead.FunnelID)
+	}
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Admin permissions name one capability of the admin panel. Every /admin route
+// requires one through middleware.RequirePermission.
+const (
+	PermDashboardRead     = "dashboard.read"
+	PermPaymentsRead      = "payments.read"
+	PermUsersRead         = "users.read"
+	PermUsersWrite        = "users.write"
+	PermUsersSuspend      = "users.suspend"
+	PermUsersImpersonate  = "users.impersonate"
+	PermStudentsRead      = "students.read"
+	PermStudentsWrite     = "students.write"
+	PermProgramsRead      = "programs.read"
+	PermProgramsWrite     = "programs.write"
+	PermPlansRead         = "plans.read"
+	PermPlansWrite        = "plans.write"
+	PermCoachesRead       = "coaches.read"
+	PermCoachesWrite      = "coaches.write"
+	PermSiteSettingsRead  = "site.settings.read"
+	PermSiteSettingsWrite = "site.settings.write"
+	PermFeedbackRead      = "feedback.read"
+	PermAIRead            = "ai.read"
+	PermExercisesRead     = "exercises.read"
+	PermExercisesWrite    = "exercises.write"
+	PermFoodsRead         = "foods.read"
+	PermTemplatesRead     = "templates.read"
+	PermTemplatesWrite    = "templates.write"
+	PermMarketplaceRead   = "marketplace.read"
+	PermMarketplaceReview = "marketplace.review"
+	PermFunnelsRead       = "funnels.read"
+	PermFunnelsWrite      = "funnels.write"
+	PermFunnelLeadsRead   = "funnel.leads.read"
+	PermFunnelLeadsWrite  = "funnel.leads.write"
+	PermMobileRead        = "mobile.read"
+	PermMobileWrite       = "mobile.write"
+	PermAuditRead         = "audit.read"
+	PermAdminRolesManage  = "admin.roles.manage"
+)
+
+// AllAdminPermissions lists every admin permission, sorted.
+func AllAdminPermissions() []string {
+	perms := []string{
+		PermDashboardRead, PermPaymentsRead,
+		PermUsersRead, PermUsersWrite, PermUsersSuspend, PermUsersImpersonate,
+		PermStudentsRead, PermStudentsWrite,
+		PermProgramsRead, PermProgramsWrite,
+		PermPlansRead, PermPlansWrite,
+		PermCoachesRead, PermCoachesWrite,
+		PermSiteSettingsRead, PermSiteSettingsWrite,
+		PermFeedbackRead, PermAIRead,
+		PermExercisesRead, PermExercisesWrite, PermFoodsRead,
+		PermTemplatesRead, PermTemplatesWrite,
+		PermMarketplaceRead, PermMarketplaceReview,
+		PermFunnelsRead, PermFunnelsWrite,
+		PermFunnelLeadsRead, PermFunnelLeadsWrite,
+		PermMobileRead, PermMobileWrite,
+		PermAuditRead, PermAdminRolesManage,
+	}
+	sort.Strings(perms)
+	return perms
+}
+
+// Admin roles bundle permissions. They are fixed in code; only their
+// assignment to users is stored.
+const (
+	AdminRoleSuperAdmin    = "super_admin"
+	AdminRoleSupport       = "support"
+	AdminRoleContentEditor = "content_editor"
+	AdminRoleFinance       = "finance"
+)
+
+// AdminRoleNames lists the admin roles in display order.
+func AdminRoleNames() []string {
+	return []string{AdminRoleSuperAdmin, AdminRoleSupport, AdminRoleContentEditor, AdminRoleFinance}
+}
+
+// AdminRolePermissions returns the permissions role grants, or nil for an
+// unknown role.
+func AdminRolePermissions(role string) []string {
+	switch role {
+	case AdminRoleSuperAdmin:
+		return AllAdminPermissions()
+	case AdminRoleSupport:
+		// Answers students and leads and may sign in as them; no content
+		// edits and no payment data.
+		return []string{
+			PermUsersRead, PermUsersImpersonate, PermStudentsRead, PermProgramsRead, PermPlansRead, PermCoachesRead,
+			PermFeedbackRead, PermAIRead, PermFunnelLeadsRead, PermFunnelLeadsWrite,
+			PermExercisesRead, PermFoodsRead, PermTemplatesRead,
+		}
+	case AdminRoleContentEditor:
+		return []string{
+			PermSiteSettingsRead, PermSiteSettingsWrite,
+			PermExercisesRead, PermExercisesWrite, PermFoodsRead,
+			PermTemplatesRead, PermTemplatesWrite,
+			PermMarketplaceRead, PermMarketplaceReview,
+			PermFunnelsRead, PermFunnelsWrite,
+			PermPlansRead, PermCoachesRead,
+		}
+	case AdminRoleFinance:
+		return []string{
+			PermDashboardRead, PermPaymentsRead, PermPlansRead, PermUsersRead, PermStudentsRead,
+			PermCoachesRead, PermFunnelsRead, PermFunnelLeadsRead, PermAIRead,
+		}
+	default:
+		return nil
+	}
+}
+
+// IsValidAdminRole reports whether role is a known admin role.
+func IsValidAdminRole(role string) bool {
+	return AdminRolePermissions(role) != nil
+}
+
+// AdminRoleAssignment grants an admin user one admin role. A user with role
+// admin and no assignment can open the panel but use none of it.
+type AdminRoleAssignment struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	UserID    uint   `gorm:"uniqueIndex:idx_admin_role_assignment;not null"`
+	Role      string `gorm:"size:40;uniqueIndex:idx_admin_role_assignment;not null"`
+	GrantedBy *uint
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AI message roles.
+const (
+	AIRoleUser      = "user"
+	AIRoleAssistant = "assistant"
+)
+
+// Reasons an assistant reply is flagged for admin moderation.
+const (
+	AIFlagSteroidPrompt       = "steroid_prompt"       // user asked about PEDs; canned refusal sent
+	AIFlagSteroidReply        = "steroid_reply"        // model output mentioned PEDs; replaced
+	AIFlagProgramPrescription = "program_prescription" // model output looked like a program/diet; replaced
+)
+
+// AIConversation is one assistant chat thread of a user. History sent to the model
+// is assembled from its messages server-side.
+type AIConversation struct {
+	gorm.Model
+
+	UserID        uint      `gorm:"index;not null"`
+	Title         string    `gorm:"size:255;not null"`
+	PagePath      string    `gorm:"size:255"`
+	MessageCount  int       `gorm:"not null;default:0"`
+	LastMessageAt time.Time `gorm:"index;not null"`
+}
+
+// AIMessage is a single turn in an AIConversation. Assistant replies point at the
+// user message they answer (ReplyToID). When a guardrail replaced the model output,
+// Content is what the user saw and FlaggedContent keeps the original for review.
+type AIMessage struct {
+	gorm.Model
+
+	ConversationID uint   `gorm:"index;not null"`
+	UserID         uint   `gorm:"index;not null"`
+	Role           string `gorm:"size:20;not null"` // user | assistant
+	Content        string `gorm:"type:text;not null"`
+	TokenEstimate  int    `gorm:"not null;default:0"`
+	ReplyToID      *uint  `gorm:"index"`
+	// Sources is a JSON array of the knowledge documents the reply cited.
+	Sources string `gorm:"type:text"`
+
+	Flagged        bool   `gorm:"index;not null;default:false"`
+	FlagReason     string `gorm:"size:40"`
+	FlaggedContent string `gorm:"type:text"`
+
+	Rating       int8   `gorm:"not null;default:0"` // 1 thumbs up | -1 thumbs down
+	FeedbackNote string `gorm:"size:500"`
+	FeedbackAt   *time.Time
+}
+
+// AI tool invocation outcomes.
+const (
+	AIToolStatusOK    = "ok"
+	AIToolStatusError = "error"
+)
+
+// AIToolInvocation audits one read-only tool call the assistant made on behalf of
+// a user. It is written when the call runs; ConversationID and MessageID are
+// filled in once the reply is stored, and stay nil when the turn failed.
+type AIToolInvocation struct {
+	gorm.Model
+
+	UserID         uint   `gorm:"index;not null"`
+	ConversationID *uint  `gorm:"index"`
+	MessageID      *uint  `gorm:"index"`
+	Tool           string `gorm:"size:60;index;not null"`
+	Arguments      string `gorm:"type:text"`
+	Status         string `gorm:"size:20;not null"` // ok | error
+	Error          string `gorm:"size:255"`
+	ResultBytes    int    `gorm:"not null;default:0"`
+	DurationMs     int64  `gorm:"not null;default:0"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// AI features usage is recorded for. Only chat turns count towards the
+// student quotas.
+const (
+	AIUsageFeatureChat       = "chat"
+	AIUsageFeatureCoachDraft = "coach_draft"
+)
+
+// AIUsage is the accounting row of one answered assistant turn: which plan and
+// coach cohort the user was on, the tokens the provider reported and what they
+// cost. Daily and monthly quotas are counted from these rows; a chat row is
+// reserved when the turn is admitted and the tokens are filled in once it is
+// answered.
+type AIUsage struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"index:idx_ai_usage_user_created,priority:2"`
+
+	UserID         uint   `gorm:"index:idx_ai_usage_user_created,priority:1;not null"`
+	CoachID        uint   `gorm:"index;not null;default:0"` // coach of the active subscription (0 = none)
+	ServicePlanID  uint   `gorm:"not null;default:0"`       // 0 = free user
+	ConversationID uint   `gorm:"index;not null"`
+	MessageID      uint   `gorm:"not null"`
+	Day            string `gorm:"size:10;index;not null"` // 2006-01-02, server local time
+	Feature        string `gorm:"size:20;not null;default:chat"`
+	Provider       string `gorm:"size:40"`
+	Model          string `gorm:"size:100"`
+
+	PromptTokens     int `gorm:"not null;default:0"`
+	CompletionTokens int `gorm:"not null;default:0"`
+	// TokensEstimated is set when the provider did not report usage and the
+	// counts are local estimates.
+	TokensEstimated bool  `gorm:"not null;default:false"`
+	CostMicroUSD    int64 `gorm:"not null;default:0"`
+}
+
+// AIUsageCounter is the number of chat turns a user has reserved in one quota
+// period. Admitting a turn bumps the counters of its periods with a
+// conditional update, which is what keeps parallel turns within the quota.
+type AIUsageCounter struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID uint   `gorm:"uniqueIndex:idx_ai_usage_counter_period,priority:1;not null"`
+	Period string `gorm:"size:10;uniqueIndex:idx_ai_usage_counter_period,priority:2;not null"` // 2006-01-02 (day) or 2006-01 (month)
+	Used   int64  `gorm:"not null;default:0"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// AuditEvent records one privileged mutation made through the admin or coach
+// panel, or any request made while an admin impersonates a user. Events are
+// append-only: there is no UpdatedAt or soft delete. Only Before and After are
+// ever rewritten, redacted when the account they describe is deleted.
+type AuditEvent struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	ActorID   uint   `gorm:"index;not null"`
+	ActorRole string `gorm:"size:20;index;not null"`
+	// ImpersonatorID is the admin who acted as ActorID, if any.
+	ImpersonatorID *uint `gorm:"index"`
+	// Action is "METHOD /route/:pattern" unless a service names it.
+	Action     string `gorm:"size:160;index;not null"`
+	TargetType string `gorm:"size:60;index"`
+	TargetID   string `gorm:"size:64;index"`
+	// Before and After hold JSON objects with only the fields that changed.
+	// BEFORE is reserved in MySQL, hence the column names.
+	Before string `gorm:"column:before_json;type:text"`
+	After  string `gorm:"column:after_json;type:text"`
+
+	Method    string `gorm:"size:10;not null"`
+	Path      string `gorm:"size:255;not null"`
+	Status    int    `gorm:"not null"`
+	IP        string `gorm:"size:64"`
+	UserAgent string `gorm:"size:255"`
+}
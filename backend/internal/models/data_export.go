@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DataExportStatusPending    = "pending"
+	DataExportStatusProcessing = "processing"
+	DataExportStatusReady      = "ready"
+	DataExportStatusFailed     = "failed"
+	DataExportStatusExpired    = "expired"
+)
+
+// DataExport is a user's request for a ZIP of their personal data. FilePath
+// points into privacy.export_dir and is cleared once the export expires.
+type DataExport struct {
+	gorm.Model
+
+	UserID      uint   `gorm:"not null;index"`
+	Status      string `gorm:"size:20;not null;index"`
+	FilePath    string `gorm:"size:512"`
+	SizeBytes   int64  `gorm:"not null;default:0"`
+	Error       string `gorm:"size:500"`
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time `gorm:"index"`
+}
@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
 
 type Exercise struct {
 	gorm.Model
@@ -15,7 +19,21 @@ type Exercise struct {
 	MuscleGroup      string `gorm:"column:muscle_group;size:100"`
 	Target           string `gorm:"size:100;index"`
 	SecondaryMuscles string `gorm:"column:secondary_muscles;type:json"`
-	ImagePath        string `gorm:"column:image_path;size:500"`
-	GifPath          string `gorm:"column:gif_path;size:500"`
-	IsActive         bool   `gorm:"not null;default:true"`
+	// Contraindications lists injury regions (knee, back, shoulder, ...) the exercise
+	// should be avoided with, stored as a JSON array.
+	Contraindications string `gorm:"column:contraindications;type:text"`
+	ImagePath         string `gorm:"column:image_path;size:500"`
+	GifPath           string `gorm:"column:gif_path;size:500"`
+	IsActive          bool   `gorm:"not null;default:true"`
+}
+
+// BeforeSave keeps the JSON columns valid JSON on every driver.
+func (e *Exercise) BeforeSave(tx *gorm.DB) error {
+	if strings.TrimSpace(e.InstructionSteps) == "" {
+		e.InstructionSteps = "[]"
+	}
+	if strings.TrimSpace(e.SecondaryMuscles) == "" {
+		e.SecondaryMuscles = "[]"
+	}
+	return nil
 }
@@ -0,0 +1,21 @@
+package models
+
+import "gorm.io/gorm"
+
+// ExerciseSwap records a student replacing one program item's exercise with an
+// approved alternative. Reverting a swap soft-deletes it; swaps on items removed by a
+// later program edit simply stop matching any live ProgramItem.
+type ExerciseSwap struct {
+	gorm.Model
+
+	UserID           uint `gorm:"index;not null"`
+	SubscriptionID   uint `gorm:"index;not null"`
+	WorkoutProgramID uint `gorm:"index;not null"`
+	ProgramItemID    uint `gorm:"index;not null"`
+
+	OriginalExerciseID    *uint  `gorm:"index"`
+	OriginalExercise      string `gorm:"size:255;not null"`
+	ReplacementExerciseID uint   `gorm:"index;not null"`
+	ReplacementExercise   string `gorm:"size:255;not null"`
+	Reason                string `gorm:"size:255"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "gorm.io/gorm"
+
+// Funnel is a public sales funnel served at /public/funnels/:slug. A guest
+// answers the question set, sees the analysis for their scenario and checks out
+// one of the coach's eligible plans. Coaches own their funnels; admins can
+// create one for any coach.
+type Funnel struct {
+	gorm.Model
+
+	Slug    string `gorm:"size:80;uniqueIndex;not null"`
+	Title   string `gorm:"size:255;not null"`
+	CoachID uint   `gorm:"index;not null"` // coach user ID
+
+	// PlanIDs is a JSON array of the ServicePlan IDs offered; empty offers all
+	// of the coach's active plans.
+	PlanIDs string `gorm:"type:text"`
+	// Questions is the JSON question set; empty uses the built-in set.
+	Questions string `gorm:"type:text"`
+	// AnalysisCopy is a JSON object of scenario → {title, body}.
+	AnalysisCopy string `gorm:"type:text"`
+
+	// FreeAccessEnabled lets a lead open a student account without paying.
+	FreeAccessEnabled bool `gorm:"not null;default:true"`
+	IsActive          bool `gorm:"not null;default:true"`
+
+	CreatedByID uint `gorm:"not null;default:0"`
+}
+
+func (Funnel) TableName() string {
+	return "funnels"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	FunnelExperimentDraft   = "draft"
+	FunnelExperimentRunning = "running"
+	FunnelExperimentStopped = "stopped"
+)
+
+// FunnelExperiment is an A/B test on one funnel. Visitors are split across the
+// weighted variants; each variant may reorder plans and questions, pick the
+// default plan and override the headline or analysis copy. At most one
+// experiment per funnel runs at a time.
+type FunnelExperiment struct {
+	gorm.Model
+
+	FunnelID uint   `gorm:"index;not null"`
+	Name     string `gorm:"size:120;not null"`
+	Status   string `gorm:"size:20;index;not null;default:'draft'"`
+	// Variants is a JSON array of {key, name, weight, planOrder, defaultPlanId,
+	// headline, analysisCopy, questionOrder}; the first variant is the control.
+	Variants string `gorm:"type:text;not null"`
+
+	StartedAt *time.Time
+	EndedAt   *time.Time
+}
+
+func (FunnelExperiment) TableName() string {
+	return "funnel_experiments"
+}
@@ -13,10 +13,11 @@ const (
 	FunnelStatusContacted      = "contacted"
 )
 
-// FunnelLead stores guest submissions from the /ali-rashidabadi sales funnel.
+// FunnelLead stores guest submissions from a sales funnel.
 type FunnelLead struct {
 	gorm.Model
 
+	FunnelID      uint   `gorm:"index;not null;default:0"`
 	CheckoutToken string `gorm:"size:64;uniqueIndex;not null"`
 	CoachID       uint   `gorm:"index;not null;default:0"`
 	CoachName     string `gorm:"size:120;not null"`
@@ -32,7 +33,7 @@ type FunnelLead struct {
 	NutritionChallenge string `gorm:"size:30"`
 	MainObstacle       string `gorm:"size:30;not null"`
 	Commitment         string `gorm:"size:30"`
-	Scenario           string `gorm:"size:1;not null"`
+	Scenario           string `gorm:"size:20;not null"`
 
 	AnalysisTitle string `gorm:"size:255;not null"`
 	AnalysisBody  string `gorm:"type:text;not null"`
@@ -50,6 +51,18 @@ type FunnelLead struct {
 	PaymentMethod string  `gorm:"size:100"`
 	UTMSource     string  `gorm:"size:120"`
 	UTMCampaign   string  `gorm:"size:120"`
+	// ExperimentID/VariantKey record the A/B variant the lead saw (0/'' = none).
+	ExperimentID uint   `gorm:"index;not null;default:0"`
+	VariantKey   string `gorm:"size:40;not null;default:''"`
+
+	// Abandoned-checkout recovery: RecoveryStep counts the reminder SMS sent;
+	// RecoveredAt/RecoveredByStep attribute a payment made after one.
+	RecoveryStep              int `gorm:"not null;default:0"`
+	RecoverySentAt            *time.Time
+	RecoveryDiscountPercent   int `gorm:"not null;default:0"`
+	RecoveryDiscountExpiresAt *time.Time
+	RecoveredAt               *time.Time `gorm:"index"`
+	RecoveredByStep           int        `gorm:"not null;default:0"`
 
 	PaidAt      *time.Time
 	ContactedAt *time.Time
@@ -58,3 +71,32 @@ type FunnelLead struct {
 func (FunnelLead) TableName() string {
 	return "funnel_leads"
 }
+
+// RecoveryDiscountActive returns the recovery discount percent still valid at now.
+func (l *FunnelLead) RecoveryDiscountActive(now time.Time) int {
+	if l.RecoveryDiscountPercent <= 0 || l.RecoveryDiscountExpiresAt == nil || !now.Before(*l.RecoveryDiscountExpiresAt) {
+		return 0
+	}
+	return l.RecoveryDiscountPercent
+}
+
+const (
+	FunnelRecoverySent   = "sent"
+	FunnelRecoveryFailed = "failed"
+)
+
+// FunnelRecoveryMessage logs one abandoned-checkout SMS attempt.
+type FunnelRecoveryMessage struct {
+	gorm.Model
+
+	LeadID          uint   `gorm:"index;not null"`
+	FunnelID        uint   `gorm:"index;not null;default:0"`
+	Step            int    `gorm:"not null"`
+	DiscountPercent int    `gorm:"not null;default:0"`
+	Status          string `gorm:"size:20;not null"`
+	Error           string `gorm:"size:255"`
+}
+
+func (FunnelRecoveryMessage) TableName() string {
+	return "funnel_recovery_messages"
+}
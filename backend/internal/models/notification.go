@@ -11,6 +11,9 @@ const (
 	NotificationTypeProgramUpdated    = "program_updated"
 	NotificationTypeCheckInReminder   = "checkin_reminder"
 	NotificationTypeMessageFromCoach  = "message_from_coach"
+	NotificationTypeMarketplace       = "marketplace"
+	NotificationTypeAccountStatus     = "account_status"
+	NotificationTypePrivacy           = "privacy"
 )
 
 // Notification represents a single user-targeted notification.
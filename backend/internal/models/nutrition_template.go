@@ -16,7 +16,10 @@ type NutritionTemplate struct {
 	IsPro       bool   `gorm:"not null;default:false"`
 	Version     int    `gorm:"not null;default:1"`
 	CoachID     *uint  `gorm:"index"`
-	Meals       []TemplateMeal `gorm:"foreignKey:NutritionTemplateID;constraint:OnDelete:CASCADE;"`
+	// AcquiredListingID is the marketplace listing this copy (or a duplicate of
+	// it) came from; 0 for the coach's own work. Such copies cannot be listed.
+	AcquiredListingID uint           `gorm:"index;not null;default:0"`
+	Meals             []TemplateMeal `gorm:"foreignKey:NutritionTemplateID;constraint:OnDelete:CASCADE;"`
 }
 
 // TemplateMeal is one meal slot (e.g. breakfast) inside a nutrition template.
@@ -15,7 +15,8 @@ type Order struct {
 	UserID  uint `gorm:"index;not null"`
 	CoachID uint `gorm:"index;not null;default:0"`
 
-	// Status: pending | paid | failed | refunded
+	// Status: pending | paid | failed | refunded | refund_pending (paid but
+	// nothing delivered, awaiting a manual refund)
 	Status string `gorm:"size:20;not null"`
 
 	// PaymentMethod: e.g. "درگاه آنلاین"
@@ -46,12 +47,15 @@ type OrderItem struct {
 
 	OrderID uint `gorm:"index;not null"`
 
-	// ItemType: "program" | "addon" | ...
+	// ItemType: "program" | "template" | "addon" | ...
 	ItemType string `gorm:"size:20;not null"`
 
 	// PlanID links to ServicePlan when the item is a sellable plan.
 	PlanID uint `gorm:"index"`
 
+	// TemplateListingID links to TemplateListing when the item is a marketplace template.
+	TemplateListingID uint `gorm:"index;not null;default:0"`
+
 	// RefID is an optional external reference (e.g. p1, a1) used by the frontend.
 	RefID string `gorm:"size:100"`
 
@@ -15,5 +15,9 @@ type OtpCode struct {
 	Purpose  string    `gorm:"size:50;index;not null"` // e.g. "login", "password_reset"
 	ExpiresAt time.Time `gorm:"index;not null"`
 	UsedAt   *time.Time
+
+	// Attempts counts guesses at the code; none are checked past the
+	// configured max.
+	Attempts int `gorm:"not null;default:0"`
 }
 
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RateLimitCounter is one fixed window of a rate limit key. Rows live in the
+// database so limits hold across restarts and every app replica shares them.
+type RateLimitCounter struct {
+	ID uint `gorm:"primaryKey"`
+	// BucketKey is the limit key plus the window start, e.g. "ai:user:7:1760000000".
+	BucketKey string    `gorm:"size:191;uniqueIndex;not null"`
+	Hits      int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
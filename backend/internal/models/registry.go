@@ -29,15 +29,32 @@ func AllModels() []any {
 		&Food{},
 		&DailyFoodLog{},
 		&WorkoutSession{},
+		&Funnel{},
+		&FunnelExperiment{},
 		&FunnelLead{},
+		&FunnelRecoveryMessage{},
 		&WorkoutSetLog{},
+		&ExerciseSwap{},
 		&WorkoutTemplate{},
 		&TemplateProgramItem{},
 		&TemplateProgramItemSet{},
 		&NutritionTemplate{},
 		&TemplateMeal{},
 		&TemplateMealItem{},
+		&TemplateListing{},
+		&TemplateAcquisition{},
+		&AIConversation{},
+		&AIMessage{},
+		&AIToolInvocation{},
+		&AIUsage{},
+		&AIUsageCounter{},
+		&RateLimitCounter{},
 		&MobileDevice{},
 		&MobileStoreRelease{},
+		&AuditEvent{},
+		&AdminRoleAssignment{},
+		&UserTwoFactor{},
+		&TwoFactorRecoveryCode{},
+		&DataExport{},
 	}
 }
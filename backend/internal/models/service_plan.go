@@ -28,5 +28,10 @@ type ServicePlan struct {
 	DurationDays       int   `gorm:"not null"`                    // plan duration for dashboard / student UI
 	IsPopular          bool  `gorm:"not null;default:false"`      // for highlighting in UI
 	IsActive           bool  `gorm:"not null;default:true"`       // soft-enable/disable plan
+
+	// AI assistant quotas for subscribers of this plan, in messages.
+	// 0 = platform default (ai.paid_* in config), -1 = unlimited.
+	AIDailyMessages   int `gorm:"not null;default:0"`
+	AIMonthlyMessages int `gorm:"not null;default:0"`
 }
 
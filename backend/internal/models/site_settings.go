@@ -32,3 +32,17 @@ type SiteSettings struct {
 	FAQGroups      json.RawMessage `gorm:"type:json"` // [{ id,title,items:[{q,a}] }]
 }
 
+
+// BeforeSave stores empty blobs as NULL; an empty string is not valid JSON on
+// any driver.
+func (s *SiteSettings) BeforeSave(tx *gorm.DB) error {
+	for _, blob := range []*json.RawMessage{
+		&s.FeatureBullets, &s.Stats, &s.Steps, &s.Pillars,
+		&s.ContactInfo, &s.AcademyItems, &s.FAQGroups,
+	} {
+		if len(*blob) == 0 {
+			*blob = nil
+		}
+	}
+	return nil
+}
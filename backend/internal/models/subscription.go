@@ -16,4 +16,6 @@ type Subscription struct {
 	LastCheckInDate     *time.Time
 	NextCheckInDueDate  *time.Time
 	CheckinFrequencyDays int       `gorm:"default:14"`
+	// AllowExerciseSwaps lets the student replace program exercises with approved alternatives.
+	AllowExerciseSwaps bool `gorm:"not null;default:false"`
 }
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TemplateKindWorkout   = "workout"
+	TemplateKindNutrition = "nutrition"
+)
+
+const (
+	TemplateListingStatusPending  = "pending"
+	TemplateListingStatusApproved = "approved"
+	TemplateListingStatusRejected = "rejected"
+	TemplateListingStatusUnlisted = "unlisted"
+)
+
+// OrderItemTypeTemplate marks an order line that buys a marketplace template listing.
+const OrderItemTypeTemplate = "template"
+
+// TemplateListing publishes one of a coach's own templates to the coach marketplace.
+// Listings become visible to other coaches only after admin approval. Buyers get a copy
+// of the live template, so editing the listing or the template sends an approved listing
+// back to pending, and deleting the template unlists it.
+type TemplateListing struct {
+	gorm.Model
+
+	AuthorID     uint   `gorm:"index;not null"`
+	TemplateKind string `gorm:"size:20;not null;index:idx_listing_template"` // workout | nutrition
+	TemplateID   uint   `gorm:"not null;index:idx_listing_template"`
+
+	Title       string `gorm:"size:255;not null"`
+	Description string `gorm:"type:text"`
+	// PriceCents is 0 for free listings; paid ones go through Order + ZarinPal.
+	PriceCents int64 `gorm:"not null;default:0"`
+
+	// Status: pending | approved | rejected | unlisted
+	Status       string `gorm:"size:20;not null;index;default:pending"`
+	ReviewNote   string `gorm:"type:text"`
+	ReviewedByID *uint
+	ReviewedAt   *time.Time
+}
+
+// TemplateAcquisition records one coach receiving a copy of a listing into their library.
+type TemplateAcquisition struct {
+	gorm.Model
+
+	ListingID uint `gorm:"not null;uniqueIndex:idx_acquisition_listing_buyer"`
+	BuyerID   uint `gorm:"not null;uniqueIndex:idx_acquisition_listing_buyer;index"`
+	AuthorID  uint `gorm:"index;not null"`
+
+	// TemplateID is the buyer's copy (WorkoutTemplate or NutritionTemplate per TemplateKind).
+	TemplateKind string `gorm:"size:20;not null"`
+	TemplateID   uint   `gorm:"not null"`
+
+	// OrderID is 0 for free listings.
+	OrderID    uint  `gorm:"index;not null;default:0"`
+	PriceCents int64 `gorm:"not null;default:0"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Two-factor methods offered at the login challenge.
+const (
+	TwoFactorMethodTOTP     = "totp"
+	TwoFactorMethodRecovery = "recovery"
+	TwoFactorMethodSMS      = "sms"
+)
+
+// UserTwoFactor is a user's TOTP enrollment. It exists from setup on;
+// EnabledAt is set once the user has proven the authenticator app works.
+type UserTwoFactor struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	UserID uint   `gorm:"uniqueIndex;not null"`
+	Secret string `gorm:"size:64;not null"` // base32
+	// LastStep is the last accepted TOTP time step; older or equal steps are
+	// refused so a code works once.
+	LastStep  int64 `gorm:"not null;default:0"`
+	EnabledAt *time.Time
+}
+
+// TwoFactorRecoveryCode is a single-use code that stands in for the
+// authenticator app. Only its SHA-256 is stored.
+type TwoFactorRecoveryCode struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	UserID   uint   `gorm:"index;not null"`
+	CodeHash string `gorm:"size:64;not null"`
+	UsedAt   *time.Time
+}
@@ -33,9 +33,32 @@ type User struct {
 	MedicalHistory      string     `gorm:"column:medical_history;type:text"`
 	Injuries            string     `gorm:"column:injuries;type:text"`
 	PhysicalLimitations string     `gorm:"column:physical_limitations;type:text"`
+
+	// Account status (see UserStatus*). StatusUntil ends a suspension; nil
+	// means until lifted.
+	AccountStatus   string     `gorm:"column:account_status;size:20;not null;default:active;index"`
+	StatusReason    string     `gorm:"column:status_reason;size:500"`
+	StatusUntil     *time.Time `gorm:"column:status_until"`
+	StatusChangedAt *time.Time `gorm:"column:status_changed_at"`
+
+	// MustChangePassword holds the session back until a new password is set
+	// (the seeded default admin starts this way).
+	MustChangePassword bool `gorm:"column:must_change_password;not null;default:false"`
+
+	// Consecutive failed sign-ins; past the lockout threshold each further
+	// failure locks password login for longer, until LoginLockedUntil.
+	FailedLoginCount int        `gorm:"column:failed_login_count;not null;default:0"`
+	LoginLockedUntil *time.Time `gorm:"column:login_locked_until"`
+
+	// Account deletion: the user asked for it and it runs at
+	// DeletionScheduledAt unless cancelled. AnonymizedAt marks the rows left
+	// behind, with personal fields wiped.
+	DeletionScheduledAt *time.Time `gorm:"column:deletion_scheduled_at;index"`
+	AnonymizedAt        *time.Time `gorm:"column:anonymized_at"`
 }
 
-// BeforeSave ensures JSON columns always contain valid JSON for MySQL.
+// BeforeSave ensures JSON columns always contain valid JSON; MySQL and
+// PostgreSQL both reject an empty string.
 func (u *User) BeforeSave(tx *gorm.DB) error {
 	if strings.TrimSpace(u.Goals) == "" {
 		u.Goals = "[]"
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// User account statuses. Suspended and banned users cannot log in or use
+// any authenticated endpoint.
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
+)
+
+// IsValidUserStatus reports whether status is a known account status.
+func IsValidUserStatus(status string) bool {
+	switch status {
+	case UserStatusActive, UserStatusSuspended, UserStatusBanned:
+		return true
+	default:
+		return false
+	}
+}
+
+// EffectiveStatus is the account status at now: a suspension past its
+// StatusUntil counts as active again.
+func (u *User) EffectiveStatus(now time.Time) string {
+	switch u.AccountStatus {
+	case UserStatusBanned:
+		return UserStatusBanned
+	case UserStatusSuspended:
+		if u.StatusUntil != nil && !now.Before(*u.StatusUntil) {
+			return UserStatusActive
+		}
+		return UserStatusSuspended
+	default:
+		return UserStatusActive
+	}
+}
@@ -15,7 +15,10 @@ type WorkoutTemplate struct {
 	Injury    string `gorm:"size:100"`
 	Level     string `gorm:"size:100"`
 	CoachID   *uint  `gorm:"index"`
-	Items     []TemplateProgramItem `gorm:"foreignKey:WorkoutTemplateID;constraint:OnDelete:CASCADE;"`
+	// AcquiredListingID is the marketplace listing this copy (or a duplicate of
+	// it) came from; 0 for the coach's own work. Such copies cannot be listed.
+	AcquiredListingID uint                  `gorm:"index;not null;default:0"`
+	Items             []TemplateProgramItem `gorm:"foreignKey:WorkoutTemplateID;constraint:OnDelete:CASCADE;"`
 }
 
 // TemplateProgramItem is one exercise slot inside a workout template day.
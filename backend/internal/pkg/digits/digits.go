@@ -28,3 +28,21 @@ func NormalizePhone(phone string) string {
 	p = strings.ReplaceAll(p, ")", "")
 	return p
 }
+
+// PhoneVariants returns the spellings an Iranian number may have been stored
+// under after NormalizePhone: 09…, 9…, +989…, 989… and 00989…. Numbers that
+// are not Iranian mobiles come back alone.
+func PhoneVariants(phone string) []string {
+	p := NormalizePhone(phone)
+	national := p
+	for _, prefix := range []string{"+98", "0098", "98", "0"} {
+		if rest, ok := strings.CutPrefix(p, prefix); ok && len(rest) == 10 && strings.HasPrefix(rest, "9") {
+			national = rest
+			break
+		}
+	}
+	if len(national) != 10 || !strings.HasPrefix(national, "9") {
+		return []string{p}
+	}
+	return []string{"0" + national, national, "+98" + national, "98" + national, "0098" + national}
+}
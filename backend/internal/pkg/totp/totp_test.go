@@ -0,0 +1,45 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B, SHA-1 column.
+func TestRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		if got := hotp(key, uint64(Step(time.Unix(tc.unix, 0))), 8); got != tc.want {
+			t.Errorf("T=%d: got %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, now.Add(-Period))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, ok := Validate(secret, code, now)
+	if !ok || step != Step(now)-1 {
+		t.Fatalf("previous period code: step=%d ok=%v", step, ok)
+	}
+	if _, ok := Validate(secret, code, now.Add(2*Period)); ok {
+		t.Fatal("code accepted outside the skew window")
+	}
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+type AdminRoleRepository interface {
+	// RolesOf returns the admin roles assigned to userID, sorted.
+	RolesOf(ctx context.Context, userID uint) ([]string, error)
+	// Replace makes roles the exact set assigned to userID. grantedBy is 0
+	// for grants made by the system.
+	Replace(ctx context.Context, userID uint, roles []string, grantedBy uint) error
+	// CountAdminsWithRole counts non-deleted admin users holding role.
+	CountAdminsWithRole(ctx context.Context, role string) (int64, error)
+}
+
+type adminRoleRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminRoleRepository(db *gorm.DB) AdminRoleRepository {
+	return &adminRoleRepository{db: db}
+}
+
+func (r *adminRoleRepository) RolesOf(ctx context.Context, userID uint) ([]string, error) {
+	var roles []string
+	err := r.db.WithContext(ctx).Model(&models.AdminRoleAssignment{}).
+		Where("user_id = ?", userID).
+		Order("role").
+		Pluck("role", &roles).Error
+	return roles, err
+}
+
+func (r *adminRoleRepository) Replace(ctx context.Context, userID uint, roles []string, grantedBy uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.AdminRoleAssignment{}).Error; err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			return nil
+		}
+		rows := make([]models.AdminRoleAssignment, 0, len(roles))
+		for _, role := range roles {
+			row := models.AdminRoleAssignment{UserID: userID, Role: role}
+			if grantedBy > 0 {
+				by := grantedBy
+				row.GrantedBy = &by
+			}
+			rows = append(rows, row)
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+func (r *adminRoleRepository) CountAdminsWithRole(ctx context.Context, role string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.AdminRoleAssignment{}).
+		Joins("JOIN users ON users.id = admin_role_assignments.user_id AND users.deleted_at IS NULL").
+		Where("admin_role_assignments.role = ? AND users.role = ?", role, models.RoleAdmin).
+		Count(&count).Error
+	return count, err
+}
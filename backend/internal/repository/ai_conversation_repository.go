@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"gorm.io/gorm"
+)
+
+type AIConversationRepository interface {
+	CreateConversation(ctx context.Context, conv *models.AIConversation) error
+	FindConversation(ctx context.Context, id uint) (*models.AIConversation, error)
+	ListConversations(ctx context.Context, userID uint, page, pageSize int) ([]models.AIConversation, int64, error)
+	DeleteConversation(ctx context.Context, id uint) error
+	// AppendMessages stores a user turn and the assistant reply to it (wiring
+	// ReplyToID) and bumps the conversation counters.
+	AppendMessages(ctx context.Context, conv *models.AIConversation, user, reply *models.AIMessage) error
+	// RecentMessages returns up to limit latest messages of a conversation, oldest first.
+	RecentMessages(ctx context.Context, conversationID uint, limit int) ([]models.AIMessage, error)
+	ListMessages(ctx context.Context, conversationID uint) ([]models.AIMessage, error)
+	FindMessage(ctx context.Context, id uint) (*models.AIMessage, error)
+	// FindMessagesByIDs also returns soft-deleted messages (used by moderation).
+	FindMessagesByIDs(ctx context.Context, ids []uint) ([]models.AIMessage, error)
+	SetFeedback(ctx context.Context, id uint, rating int8, note string) error
+	ListFlagged(ctx context.Context, reason string, page, pageSize int) ([]models.AIMessage, int64, error)
+}
+
+type aiConversationRepository struct {
+	db *gorm.DB
+}
+
+func NewAIConversationRepository(db *gorm.DB) AIConversationRepository {
+	return &aiConversationRepository{db: db}
+}
+
+func (r *aiConversationRepository) CreateConversation(ctx context.Context, conv *models.AIConversation) error {
+	return r.db.WithContext(ctx).Create(conv).Error
+}
+
+func (r *aiConversationRepository) FindConversation(ctx context.Context, id uint) (*models.AIConversation, error) {
+	var conv models.AIConversation
+	if err := r.db.WithContext(ctx).First(&conv, id).Error; err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+func (r *aiConversationRepository) ListConversations(ctx context.Context, userID uint, page, pageSize int) ([]models.AIConversation, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.AIConversation{}).Where("user_id = ?", userID)
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	var list []models.AIConversation
+	if err := db.Order("last_message_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+func (r *aiConversationRepository) DeleteConversation(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", id).Delete(&models.AIMessage{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.AIConversation{}, id).Error
+	})
+}
+
+func (r *aiConversationRepository) AppendMessages(ctx context.Context, conv *models.AIConversation, user, reply *models.AIMessage) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		user.ConversationID = conv.ID
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		reply.ConversationID = conv.ID
+		reply.ReplyToID = &user.ID
+		if err := tx.Create(reply).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		conv.MessageCount += 2
+		conv.LastMessageAt = now
+		return tx.Model(&models.AIConversation{}).Where("id = ?", conv.ID).Updates(map[string]any{
+			"message_count":   gorm.Expr("message_count + ?", 2),
+			"last_message_at": now,
+		}).Error
+	})
+}
+
+func (r *aiConversationRepository) RecentMessages(ctx context.Context, conversationID uint, limit int) ([]models.AIMessage, error) {
+	var list []models.AIMessage
+	if err := r.db.WithContext(ctx).
+		Where("conversation_id = ?", conversationID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list, nil
+}
+
+func (r *aiConversationRepository) ListMessages(ctx context.Context, conversationID uint) ([]models.AIMessage, error) {
+	var list []models.AIMessage
+	err := r.db.WithContext(ctx).Where("conversation_id = ?", conversationID).Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *aiConversationRepository) FindMessage(ctx context.Context, id uint) (*models.AIMessage, error) {
+	var m models.AIMessage
+	if err := r.db.WithContext(ctx).First(&m, id).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *aiConversationRepository) FindMessagesByIDs(ctx context.Context, ids []uint) ([]models.AIMessage, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var list []models.AIMessage
+	err := r.db.WithContext(ctx).Unscoped().Where("id IN ?", ids).Find(&list).Error
+	return list, err
+}
+
+func (r *aiConversationRepository) SetFeedback(ctx context.Context, id uint, rating int8, note string) error {
+	var at *time.Time
+	if rating != 0 {
+		now := time.Now()
+		at = &now
+	}
+	return r.db.WithContext(ctx).Model(&models.AIMessage{}).Where("id = ?", id).Updates(map[string]any{
+		"rating":        rating,
+		"feedback_note": note,
+		"feedback_at":   at,
+	}).Error
+}
+
+// ListFlagged includes messages of conversations the user has since deleted so
+// moderation history is not lost.
+func (r *aiConversationRepository) ListFlagged(ctx context.Context, reason string, page, pageSize int) ([]models.AIMessage, int64, error) {
+	db := r.db.WithContext(ctx).Unscoped().Model(&models.AIMessage{}).Where("flagged = ?", true)
+	if reason != "" {
+		db = db.Where("flag_reason = ?", reason)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	var list []models.AIMessage
+	if err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
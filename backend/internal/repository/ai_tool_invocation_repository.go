@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"gorm.io/gorm"
+)
+
+type AIToolInvocationRepository interface {
+	Create(ctx context.Context, inv *models.AIToolInvocation) error
+	// Link attaches invocations to the conversation turn they were made for.
+	Link(ctx context.Context, ids []uint, conversationID, messageID uint) error
+	List(ctx context.Context, userID uint, tool string, page, pageSize int) ([]models.AIToolInvocation, int64, error)
+}
+
+type aiToolInvocationRepository struct {
+	db *gorm.DB
+}
+
+func NewAIToolInvocationRepository(db *gorm.DB) AIToolInvocationRepository {
+	return &aiToolInvocationRepository{db: db}
+}
+
+func (r *aiToolInvocationRepository) Create(ctx context.Context, inv *models.AIToolInvocation) error {
+	return r.db.WithContext(ctx).Create(inv).Error
+}
+
+func (r *aiToolInvocationRepository) Link(ctx context.Context, ids []uint, conversationID, messageID uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.AIToolInvocation{}).Where("id IN ?", ids).Updates(map[string]any{
+		"conversation_id": conversationID,
+		"message_id":      messageID,
+	}).Error
+}
+
+func (r *aiToolInvocationRepository) List(ctx context.Context, userID uint, tool string, page, pageSize int) ([]models.AIToolInvocation, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.AIToolInvocation{})
+	if userID > 0 {
+		db = db.Where("user_id = ?", userID)
+	}
+	if tool != "" {
+		db = db.Where("tool = ?", tool)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	var list []models.AIToolInvocation
+	if err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
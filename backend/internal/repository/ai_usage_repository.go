@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AIUsageRepository interface {
+	Create(ctx context.Context, usage *models.AIUsage) error
+	// Reserve creates the chat row for a turn and takes one turn from the
+	// counter of every limit's period, unless one of them is used up, in which
+	// case nothing is written and it returns false.
+	Reserve(ctx context.Context, usage *models.AIUsage, limits ...AIUsageLimit) (bool, error)
+	// Complete fills in the conversation, provider and token fields of a
+	// reserved row.
+	Complete(ctx context.Context, usage *models.AIUsage) error
+	// Release deletes a reserved row and gives its turn back to the counters of
+	// periods.
+	Release(ctx context.Context, id uint, periods ...string) error
+	// CountByUserSince counts the user's chat turns; coach drafts are not quota'd.
+	CountByUserSince(ctx context.Context, userID uint, since time.Time) (int64, error)
+	// TotalsByDay and TotalsByCoach aggregate usage for days in [fromDay, toDay]
+	// (2006-01-02, inclusive).
+	TotalsByDay(ctx context.Context, fromDay, toDay string) ([]AIUsageDayRow, error)
+	TotalsByCoach(ctx context.Context, fromDay, toDay string) ([]AIUsageCoachRow, error)
+}
+
+// AIUsageLimit caps the user's chat turns in Period, which starts at Since, at
+// Max. Period keys the counter row, e.g. "2026-10-19" or "2026-10".
+type AIUsageLimit struct {
+	Period string
+	Since  time.Time
+	Max    int64
+}
+
+var errAIUsageLimitReached = errors.New("ai usage limit reached")
+
+type AIUsageDayRow struct {
+	Day              string
+	Messages         int64
+	Users            int64
+	PromptTokens     int64
+	CompletionTokens int64
+	CostMicroUSD     int64
+}
+
+type AIUsageCoachRow struct {
+	CoachID          uint
+	Messages         int64
+	Users            int64
+	PromptTokens     int64
+	CompletionTokens int64
+	CostMicroUSD     int64
+}
+
+type aiUsageRepository struct {
+	db *gorm.DB
+}
+
+func NewAIUsageRepository(db *gorm.DB) AIUsageRepository {
+	return &aiUsageRepository{db: db}
+}
+
+const aiUsageTotalsSelect = "COUNT(*) AS messages, COUNT(DISTINCT user_id) AS users, " +
+	"COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, COALESCE(SUM(completion_tokens), 0) AS completion_tokens, " +
+	"COALESCE(SUM(cost_micro_usd), 0) AS cost_micro_usd"
+
+func (r *aiUsageRepository) Create(ctx context.Context, usage *models.AIUsage) error {
+	return r.db.WithContext(ctx).Create(usage).Error
+}
+
+func (r *aiUsageRepository) Reserve(ctx context.Context, usage *models.AIUsage, limits ...AIUsageLimit) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, l := range limits {
+			ok, err := takeAIUsageTurn(tx, usage.UserID, l)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				// Rolls back the turns already taken from the other periods.
+				return errAIUsageLimitReached
+			}
+		}
+		return tx.Create(usage).Error
+	})
+	if errors.Is(err, errAIUsageLimitReached) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// takeAIUsageTurn bumps the user's counter for l.Period unless it is already at
+// l.Max. The check and the increment are one UPDATE, so concurrent turns cannot
+// both take the last slot.
+func takeAIUsageTurn(tx *gorm.DB, userID uint, l AIUsageLimit) (bool, error) {
+	var exists int64
+	if err := tx.Model(&models.AIUsageCounter{}).Where("user_id = ? AND period = ?", userID, l.Period).
+		Count(&exists).Error; err != nil {
+		return false, err
+	}
+	if exists == 0 {
+		// A new counter starts from the turns already recorded in the period.
+		used, err := countChatSince(tx, userID, l.Since)
+		if err != nil {
+			return false, err
+		}
+		counter := &models.AIUsageCounter{UserID: userID, Period: l.Period, Used: used}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(counter).Error; err != nil {
+			return false, err
+		}
+	}
+	res := tx.Model(&models.AIUsageCounter{}).
+		Where("user_id = ? AND period = ? AND used < ?", userID, l.Period, l.Max).
+		Update("used", gorm.Expr("used + 1"))
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *aiUsageRepository) Complete(ctx context.Context, usage *models.AIUsage) error {
+	return r.db.WithContext(ctx).Model(&models.AIUsage{ID: usage.ID}).
+		Select("ConversationID", "MessageID", "Provider", "Model",
+			"PromptTokens", "CompletionTokens", "TokensEstimated", "CostMicroUSD").
+		Updates(usage).Error
+}
+
+func (r *aiUsageRepository) Release(ctx context.Context, id uint, periods ...string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var usage models.AIUsage
+		if err := tx.First(&usage, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		res := tx.Delete(&usage)
+		if res.Error != nil || res.RowsAffected == 0 || len(periods) == 0 {
+			return res.Error
+		}
+		return tx.Model(&models.AIUsageCounter{}).
+			Where("user_id = ? AND period IN ? AND used > 0", usage.UserID, periods).
+			Update("used", gorm.Expr("used - 1")).Error
+	})
+}
+
+func (r *aiUsageRepository) CountByUserSince(ctx context.Context, userID uint, since time.Time) (int64, error) {
+	return countChatSince(r.db.WithContext(ctx), userID, since)
+}
+
+func countChatSince(db *gorm.DB, userID uint, since time.Time) (int64, error) {
+	var n int64
+	err := db.Model(&models.AIUsage{}).
+		Where("user_id = ? AND created_at >= ? AND feature = ?", userID, since, models.AIUsageFeatureChat).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *aiUsageRepository) TotalsByDay(ctx context.Context, fromDay, toDay string) ([]AIUsageDayRow, error) {
+	var rows []AIUsageDayRow
+	err := r.db.WithContext(ctx).Model(&models.AIUsage{}).
+		Select("day, "+aiUsageTotalsSelect).
+		Where("day >= ? AND day <= ?", fromDay, toDay).
+		Group("day").Order("day ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *aiUsageRepository) TotalsByCoach(ctx context.Context, fromDay, toDay string) ([]AIUsageCoachRow, error) {
+	var rows []AIUsageCoachRow
+	err := r.db.WithContext(ctx).Model(&models.AIUsage{}).
+		Select("coach_id, "+aiUsageTotalsSelect).
+		Where("day >= ? AND day <= ?", fromDay, toDay).
+		Group("coach_id").Order("cost_micro_usd DESC").
+		Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// AuditEventFilter narrows the admin audit log. Zero values match everything;
+// From is inclusive and To exclusive.
+type AuditEventFilter struct {
+	ActorID        uint
+	ActorRole      string
+	ImpersonatorID uint
+	Action         string
+	TargetType     string
+	TargetID       string
+	From           time.Time
+	To             time.Time
+	// Query matches action, path or the before/after JSON.
+	Query string
+}
+
+type AuditEventRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	List(ctx context.Context, filter AuditEventFilter, page, pageSize int) ([]models.AuditEvent, int64, error)
+}
+
+type auditEventRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditEventRepository(db *gorm.DB) AuditEventRepository {
+	return &auditEventRepository{db: db}
+}
+
+func (r *auditEventRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *auditEventRepository) List(ctx context.Context, filter AuditEventFilter, page, pageSize int) ([]models.AuditEvent, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.AuditEvent{})
+	if filter.ActorID > 0 {
+		db = db.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.ActorRole != "" {
+		db = db.Where("actor_role = ?", filter.ActorRole)
+	}
+	if filter.ImpersonatorID > 0 {
+		db = db.Where("impersonator_id = ?", filter.ImpersonatorID)
+	}
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		db = db.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		db = db.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.From.IsZero() {
+		db = db.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		db = db.Where("created_at < ?", filter.To)
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		like := "%" + q + "%"
+		db = db.Where("action LIKE ? OR path LIKE ? OR before_json LIKE ? OR after_json LIKE ?", like, like, like, like)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	var list []models.AuditEvent
+	if err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
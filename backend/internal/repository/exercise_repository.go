@@ -29,6 +29,9 @@ type ExerciseRepository interface {
 	FindByNames(ctx context.Context, names []string) ([]models.Exercise, error)
 	ListWithGif(ctx context.Context) ([]models.Exercise, error)
 	UpsertByExternalID(ctx context.Context, e *models.Exercise) error
+	// ListAlternativeCandidates returns active exercises sharing the target muscle or
+	// body part of ex (ex itself excluded), scoped like the coach catalog when coachID is set.
+	ListAlternativeCandidates(ctx context.Context, ex *models.Exercise, coachID *uint) ([]models.Exercise, error)
 }
 
 type exerciseRepository struct {
@@ -168,5 +171,32 @@ func (r *exerciseRepository) UpsertByExternalID(ctx context.Context, e *models.E
 	}
 	e.ID = existing.ID
 	e.CreatedAt = existing.CreatedAt
+	// Contraindications are curated in the admin panel, not in the catalog file.
+	if e.Contraindications == "" {
+		e.Contraindications = existing.Contraindications
+	}
 	return r.db.WithContext(ctx).Save(e).Error
 }
+
+func (r *exerciseRepository) ListAlternativeCandidates(ctx context.Context, ex *models.Exercise, coachID *uint) ([]models.Exercise, error) {
+	db := r.db.WithContext(ctx).Model(&models.Exercise{}).
+		Where("id <> ? AND is_active = ?", ex.ID, true)
+	switch {
+	case ex.Target != "" && ex.BodyPart != "":
+		db = db.Where("target = ? OR body_part = ?", ex.Target, ex.BodyPart)
+	case ex.Target != "":
+		db = db.Where("target = ?", ex.Target)
+	case ex.BodyPart != "":
+		db = db.Where("body_part = ?", ex.BodyPart)
+	default:
+		return nil, nil
+	}
+	if coachID != nil {
+		db = db.Where("coach_id IS NULL OR coach_id = ?", *coachID)
+	} else {
+		db = db.Where("coach_id IS NULL")
+	}
+	var list []models.Exercise
+	err := db.Order("id ASC").Limit(500).Find(&list).Error
+	return list, err
+}
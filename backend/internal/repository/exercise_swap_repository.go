@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"gorm.io/gorm"
+)
+
+type ExerciseSwapRepository interface {
+	Create(ctx context.Context, swap *models.ExerciseSwap) error
+	Delete(ctx context.Context, id uint) error
+	FindByID(ctx context.Context, id uint) (*models.ExerciseSwap, error)
+	// FindByProgramItem returns the current swap for a program item, if any.
+	FindByProgramItem(ctx context.Context, programItemID uint) (*models.ExerciseSwap, error)
+	// ListByProgram returns swaps whose program item still exists, newest first.
+	ListByProgram(ctx context.Context, workoutProgramID uint) ([]models.ExerciseSwap, error)
+	FindProgramItem(ctx context.Context, id uint) (*models.ProgramItem, error)
+}
+
+type exerciseSwapRepository struct {
+	db *gorm.DB
+}
+
+func NewExerciseSwapRepository(db *gorm.DB) ExerciseSwapRepository {
+	return &exerciseSwapRepository{db: db}
+}
+
+func (r *exerciseSwapRepository) Create(ctx context.Context, swap *models.ExerciseSwap) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("program_item_id = ?", swap.ProgramItemID).Delete(&models.ExerciseSwap{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(swap).Error
+	})
+}
+
+func (r *exerciseSwapRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.ExerciseSwap{}, id).Error
+}
+
+func (r *exerciseSwapRepository) FindByID(ctx context.Context, id uint) (*models.ExerciseSwap, error) {
+	var swap models.ExerciseSwap
+	if err := r.db.WithContext(ctx).First(&swap, id).Error; err != nil {
+		return nil, err
+	}
+	return &swap, nil
+}
+
+func (r *exerciseSwapRepository) FindByProgramItem(ctx context.Context, programItemID uint) (*models.ExerciseSwap, error) {
+	var swap models.ExerciseSwap
+	err := r.db.WithContext(ctx).
+		Where("program_item_id = ?", programItemID).
+		Order("id DESC").
+		First(&swap).Error
+	if err != nil {
+		return nil, err
+	}
+	return &swap, nil
+}
+
+func (r *exerciseSwapRepository) ListByProgram(ctx context.Context, workoutProgramID uint) ([]models.ExerciseSwap, error) {
+	var swaps []models.ExerciseSwap
+	err := r.db.WithContext(ctx).
+		Where("workout_program_id = ?", workoutProgramID).
+		Where("program_item_id IN (?)", r.db.Model(&models.ProgramItem{}).
+			Select("id").Where("workout_program_id = ?", workoutProgramID)).
+		Order("created_at DESC, id DESC").
+		Find(&swaps).Error
+	return swaps, err
+}
+
+func (r *exerciseSwapRepository) FindProgramItem(ctx context.Context, id uint) (*models.ProgramItem, error) {
+	var item models.ProgramItem
+	if err := r.db.WithContext(ctx).First(&item, id).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
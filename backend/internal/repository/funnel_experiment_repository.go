@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+type FunnelExperimentRepository interface {
+	Create(ctx context.Context, exp *models.FunnelExperiment) error
+	Update(ctx context.Context, exp *models.FunnelExperiment) error
+	Delete(ctx context.Context, id uint) error
+	FindByID(ctx context.Context, id uint) (*models.FunnelExperiment, error)
+	// ListByFunnel returns the funnel's experiments newest first.
+	ListByFunnel(ctx context.Context, funnelID uint) ([]models.FunnelExperiment, error)
+	FindRunningByFunnel(ctx context.Context, funnelID uint) (*models.FunnelExperiment, error)
+}
+
+type funnelExperimentRepository struct {
+	db *gorm.DB
+}
+
+func NewFunnelExperimentRepository(db *gorm.DB) FunnelExperimentRepository {
+	return &funnelExperimentRepository{db: db}
+}
+
+func (r *funnelExperimentRepository) Create(ctx context.Context, exp *models.FunnelExperiment) error {
+	return r.db.WithContext(ctx).Create(exp).Error
+}
+
+func (r *funnelExperimentRepository) Update(ctx context.Context, exp *models.FunnelExperiment) error {
+	return r.db.WithContext(ctx).Save(exp).Error
+}
+
+func (r *funnelExperimentRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.FunnelExperiment{}, id).Error
+}
+
+func (r *funnelExperimentRepository) FindByID(ctx context.Context, id uint) (*models.FunnelExperiment, error) {
+	var exp models.FunnelExperiment
+	if err := r.db.WithContext(ctx).First(&exp, id).Error; err != nil {
+		return nil, err
+	}
+	return &exp, nil
+}
+
+func (r *funnelExperimentRepository) ListByFunnel(ctx context.Context, funnelID uint) ([]models.FunnelExperiment, error) {
+	var out []models.FunnelExperiment
+	err := r.db.WithContext(ctx).Where("funnel_id = ?", funnelID).Order("id DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *funnelExperimentRepository) FindRunningByFunnel(ctx context.Context, funnelID uint) (*models.FunnelExperiment, error) {
+	var exp models.FunnelExperiment
+	err := r.db.WithContext(ctx).
+		Where("funnel_id = ? AND status = ?", funnelID, models.FunnelExperimentRunning).
+		Order("id DESC").
+		First(&exp).Error
+	if err != nil {
+		return nil, err
+	}
+	return &exp, nil
+}
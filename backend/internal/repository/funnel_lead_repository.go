@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -20,13 +21,26 @@ type FunnelStats struct {
 	PaidRevenue  int64
 }
 
+// FunnelVariantStats holds one A/B variant's counters. Paid includes leads that
+// were contacted after paying.
+type FunnelVariantStats struct {
+	VariantKey  string
+	Total       int64
+	Paid        int64
+	PaidRevenue int64
+}
+
 type FunnelLeadRepository interface {
 	Create(ctx context.Context, lead *models.FunnelLead) error
 	FindByCheckoutToken(ctx context.Context, token string) (*models.FunnelLead, error)
 	FindByOrderID(ctx context.Context, orderID uint) (*models.FunnelLead, error)
-	FindLatestPendingByPhone(ctx context.Context, phone string) (*models.FunnelLead, error)
+	// FindLatestPendingByPhone returns the phone's newest unpaid lead in funnelID.
+	FindLatestPendingByPhone(ctx context.Context, funnelID uint, phone string) (*models.FunnelLead, error)
+	// FindLatestByPhone returns the most recent lead for a phone regardless of status.
+	FindLatestByPhone(ctx context.Context, phone string) (*models.FunnelLead, error)
 	Update(ctx context.Context, lead *models.FunnelLead) error
-	List(ctx context.Context, status string, query string, page, pageSize int) ([]models.FunnelLead, int64, error)
+	// List and Stats cover every funnel when funnelID is 0.
+	List(ctx context.Context, funnelID uint, status string, query string, page, pageSize int) ([]models.FunnelLead, int64, error)
 	FindByID(ctx context.Context, id uint) (*models.FunnelLead, error)
 	Delete(ctx context.Context, id uint) error
 	// RegisteredPhones returns phone->userID for any of the given phones that
@@ -35,7 +49,8 @@ type FunnelLeadRepository interface {
 	// PhoneHasActiveSubscription reports whether the phone belongs to a user
 	// with a non-expired subscription (paid / assigned program).
 	PhoneHasActiveSubscription(ctx context.Context, phone string) (bool, error)
-	Stats(ctx context.Context) (*FunnelStats, error)
+	Stats(ctx context.Context, funnelID uint) (*FunnelStats, error)
+	VariantStats(ctx context.Context, experimentID uint) ([]FunnelVariantStats, error)
 }
 
 type funnelLeadRepository struct {
@@ -69,10 +84,18 @@ func (r *funnelLeadRepository) FindByOrderID(ctx context.Context, orderID uint)
 	return &lead, nil
 }
 
-func (r *funnelLeadRepository) FindLatestPendingByPhone(ctx context.Context, phone string) (*models.FunnelLead, error) {
+func (r *funnelLeadRepository) FindLatestByPhone(ctx context.Context, phone string) (*models.FunnelLead, error) {
+	var lead models.FunnelLead
+	if err := r.db.WithContext(ctx).Where("phone = ?", phone).Order("created_at DESC").First(&lead).Error; err != nil {
+		return nil, err
+	}
+	return &lead, nil
+}
+
+func (r *funnelLeadRepository) FindLatestPendingByPhone(ctx context.Context, funnelID uint, phone string) (*models.FunnelLead, error) {
 	var lead models.FunnelLead
 	err := r.db.WithContext(ctx).
-		Where("phone = ? AND status = ?", phone, models.FunnelStatusPendingPayment).
+		Where("funnel_id = ? AND phone = ? AND status = ?", funnelID, phone, models.FunnelStatusPendingPayment).
 		Order("created_at DESC").
 		First(&lead).Error
 	if err != nil {
@@ -85,17 +108,22 @@ func (r *funnelLeadRepository) Update(ctx context.Context, lead *models.FunnelLe
 	return r.db.WithContext(ctx).Save(lead).Error
 }
 
-func (r *funnelLeadRepository) List(ctx context.Context, status string, query string, page, pageSize int) ([]models.FunnelLead, int64, error) {
+func (r *funnelLeadRepository) List(ctx context.Context, funnelID uint, status string, query string, page, pageSize int) ([]models.FunnelLead, int64, error) {
 	db := r.db.WithContext(ctx).Model(&models.FunnelLead{})
+	if funnelID > 0 {
+		db = db.Where("funnel_id = ?", funnelID)
+	}
 	if status != "" && status != "all" {
 		db = db.Where("status = ?", status)
 	}
 	if q := strings.TrimSpace(query); q != "" {
 		like := "%" + q + "%"
-		db = db.Where(
-			"first_name LIKE ? OR last_name LIKE ? OR phone LIKE ? OR CONCAT(first_name, ' ', last_name) LIKE ?",
-			like, like, like, like,
-		)
+		cond := r.db.Where("first_name LIKE ? OR last_name LIKE ? OR phone LIKE ?", like, like, like)
+		// "first last" queries; string concatenation is not portable SQL.
+		if first, last, ok := strings.Cut(q, " "); ok {
+			cond = cond.Or("first_name LIKE ? AND last_name LIKE ?", "%"+first+"%", "%"+strings.TrimSpace(last)+"%")
+		}
+		db = db.Where(cond)
 	}
 
 	var total int64
@@ -160,34 +188,41 @@ func (r *funnelLeadRepository) PhoneHasActiveSubscription(ctx context.Context, p
 		JOIN users u ON u.id = s.user_id AND u.deleted_at IS NULL
 		WHERE u.phone = ?
 		  AND s.deleted_at IS NULL
-		  AND (s.ends_at IS NULL OR s.ends_at > NOW())
-	`, phone).Scan(&count).Error
+		  AND (s.ends_at IS NULL OR s.ends_at > ?)
+	`, phone, time.Now()).Scan(&count).Error
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func (r *funnelLeadRepository) Stats(ctx context.Context) (*FunnelStats, error) {
+func (r *funnelLeadRepository) Stats(ctx context.Context, funnelID uint) (*FunnelStats, error) {
 	db := r.db.WithContext(ctx)
+	leads := func() *gorm.DB {
+		q := db.Model(&models.FunnelLead{})
+		if funnelID > 0 {
+			q = q.Where("funnel_id = ?", funnelID)
+		}
+		return q
+	}
 	var s FunnelStats
 
-	if err := db.Model(&models.FunnelLead{}).Count(&s.Total).Error; err != nil {
+	if err := leads().Count(&s.Total).Error; err != nil {
 		return nil, err
 	}
-	if err := db.Model(&models.FunnelLead{}).Where("status = ?", models.FunnelStatusPaid).Count(&s.Paid).Error; err != nil {
+	if err := leads().Where("status = ?", models.FunnelStatusPaid).Count(&s.Paid).Error; err != nil {
 		return nil, err
 	}
-	if err := db.Model(&models.FunnelLead{}).Where("status = ?", models.FunnelStatusPendingPayment).Count(&s.Pending).Error; err != nil {
+	if err := leads().Where("status = ?", models.FunnelStatusPendingPayment).Count(&s.Pending).Error; err != nil {
 		return nil, err
 	}
-	if err := db.Model(&models.FunnelLead{}).Where("status = ?", models.FunnelStatusContacted).Count(&s.Contacted).Error; err != nil {
+	if err := leads().Where("status = ?", models.FunnelStatusContacted).Count(&s.Contacted).Error; err != nil {
 		return nil, err
 	}
-	if err := db.Model(&models.FunnelLead{}).Distinct("phone").Count(&s.UniquePeople).Error; err != nil {
+	if err := leads().Distinct("phone").Count(&s.UniquePeople).Error; err != nil {
 		return nil, err
 	}
-	if err := db.Model(&models.FunnelLead{}).
+	if err := leads().
 		Where("status = ?", models.FunnelStatusPaid).
 		Select("COALESCE(SUM(amount_cents), 0)").
 		Scan(&s.PaidRevenue).Error; err != nil {
@@ -198,9 +233,23 @@ func (r *funnelLeadRepository) Stats(ctx context.Context) (*FunnelStats, error)
 		Raw(`SELECT COUNT(DISTINCT fl.phone)
 		     FROM funnel_leads fl
 		     JOIN users u ON u.phone = fl.phone AND u.deleted_at IS NULL
-		     WHERE fl.deleted_at IS NULL`).
+		     WHERE fl.deleted_at IS NULL AND (? = 0 OR fl.funnel_id = ?)`, funnelID, funnelID).
 		Scan(&s.Converted).Error; err != nil {
 		return nil, err
 	}
 	return &s, nil
 }
+
+func (r *funnelLeadRepository) VariantStats(ctx context.Context, experimentID uint) ([]FunnelVariantStats, error) {
+	paid := []string{models.FunnelStatusPaid, models.FunnelStatusContacted}
+	var out []FunnelVariantStats
+	err := r.db.WithContext(ctx).Model(&models.FunnelLead{}).
+		Select(`variant_key,
+			COUNT(*) AS total,
+			COALESCE(SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END), 0) AS paid,
+			COALESCE(SUM(CASE WHEN status IN ? THEN amount_cents ELSE 0 END), 0) AS paid_revenue`, paid, paid).
+		Where("experiment_id = ?", experimentID).
+		Group("variant_key").
+		Scan(&out).Error
+	return out, err
+}
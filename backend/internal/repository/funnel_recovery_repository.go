@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// FunnelRecoveryStepStats holds one reminder step's counters for the report.
+type FunnelRecoveryStepStats struct {
+	Step             int
+	Sent             int64
+	Failed           int64
+	Recovered        int64
+	RecoveredRevenue int64
+}
+
+type FunnelRecoveryRepository interface {
+	// ListDue returns unpaid leads created in [createdAfter, createdBefore]
+	// that have received exactly step reminders, oldest first.
+	ListDue(ctx context.Context, step int, createdAfter, createdBefore time.Time, limit int) ([]models.FunnelLead, error)
+	// ClaimStep advances the lead from step to step+1 if it is still unpaid and
+	// nobody else claimed it; false means skip it.
+	ClaimStep(ctx context.Context, leadID uint, step, discountPercent int, discountExpiresAt *time.Time, now time.Time) (bool, error)
+	CreateMessage(ctx context.Context, msg *models.FunnelRecoveryMessage) error
+	// StepStats covers messages sent and recoveries made in [from, to); funnelID 0
+	// covers every funnel.
+	StepStats(ctx context.Context, funnelID uint, from, to time.Time) ([]FunnelRecoveryStepStats, error)
+}
+
+type funnelRecoveryRepository struct {
+	db *gorm.DB
+}
+
+func NewFunnelRecoveryRepository(db *gorm.DB) FunnelRecoveryRepository {
+	return &funnelRecoveryRepository{db: db}
+}
+
+func (r *funnelRecoveryRepository) ListDue(ctx context.Context, step int, createdAfter, createdBefore time.Time, limit int) ([]models.FunnelLead, error) {
+	var leads []models.FunnelLead
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND recovery_step = ?", models.FunnelStatusPendingPayment, step).
+		Where("created_at >= ? AND created_at <= ?", createdAfter, createdBefore).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&leads).Error
+	return leads, err
+}
+
+func (r *funnelRecoveryRepository) ClaimStep(ctx context.Context, leadID uint, step, discountPercent int, discountExpiresAt *time.Time, now time.Time) (bool, error) {
+	updates := map[string]interface{}{
+		"recovery_step":    step + 1,
+		"recovery_sent_at": now,
+	}
+	if discountPercent > 0 {
+		updates["recovery_discount_percent"] = discountPercent
+		updates["recovery_discount_expires_at"] = discountExpiresAt
+	}
+	res := r.db.WithContext(ctx).Model(&models.FunnelLead{}).
+		Where("id = ? AND status = ? AND recovery_step = ?", leadID, models.FunnelStatusPendingPayment, step).
+		Updates(updates)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *funnelRecoveryRepository) CreateMessage(ctx context.Context, msg *models.FunnelRecoveryMessage) error {
+	return r.db.WithContext(ctx).Create(msg).Error
+}
+
+func (r *funnelRecoveryRepository) StepStats(ctx context.Context, funnelID uint, from, to time.Time) ([]FunnelRecoveryStepStats, error) {
+	db := r.db.WithContext(ctx)
+
+	var sent []struct {
+		Step   int
+		Sent   int64
+		Failed int64
+	}
+	q := db.Model(&models.FunnelRecoveryMessage{}).
+		Select(`step,
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS sent,
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS failed`,
+			models.FunnelRecoverySent, models.FunnelRecoveryFailed).
+		Where("created_at >= ? AND created_at < ?", from, to)
+	if funnelID > 0 {
+		q = q.Where("funnel_id = ?", funnelID)
+	}
+	if err := q.Group("step").Scan(&sent).Error; err != nil {
+		return nil, err
+	}
+
+	var recovered []struct {
+		Step    int
+		Count   int64
+		Revenue int64
+	}
+	q = db.Model(&models.FunnelLead{}).
+		Select("recovered_by_step AS step, COUNT(*) AS count, COALESCE(SUM(amount_cents), 0) AS revenue").
+		Where("recovered_at >= ? AND recovered_at < ?", from, to)
+	if funnelID > 0 {
+		q = q.Where("funnel_id = ?", funnelID)
+	}
+	if err := q.Group("recovered_by_step").Scan(&recovered).Error; err != nil {
+		return nil, err
+	}
+
+	byStep := map[int]*FunnelRecoveryStepStats{}
+	get := func(step int) *FunnelRecoveryStepStats {
+		if _, ok := byStep[step]; !ok {
+			byStep[step] = &FunnelRecoveryStepStats{Step: step}
+		}
+		return byStep[step]
+	}
+	for _, s := range sent {
+		row := get(s.Step)
+		row.Sent, row.Failed = s.Sent, s.Failed
+	}
+	for _, rc := range recovered {
+		row := get(rc.Step)
+		row.Recovered, row.RecoveredRevenue = rc.Count, rc.Revenue
+	}
+
+	out := make([]FunnelRecoveryStepStats, 0, len(byStep))
+	for _, row := range byStep {
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Step < out[j].Step })
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+type FunnelRepository interface {
+	Create(ctx context.Context, funnel *models.Funnel) error
+	Update(ctx context.Context, funnel *models.Funnel) error
+	Delete(ctx context.Context, id uint) error
+	FindByID(ctx context.Context, id uint) (*models.Funnel, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Funnel, error)
+	// List returns funnels newest first; coachID 0 lists every coach's funnels.
+	List(ctx context.Context, coachID uint, page, pageSize int) ([]models.Funnel, int64, error)
+}
+
+type funnelRepository struct {
+	db *gorm.DB
+}
+
+func NewFunnelRepository(db *gorm.DB) FunnelRepository {
+	return &funnelRepository{db: db}
+}
+
+func (r *funnelRepository) Create(ctx context.Context, funnel *models.Funnel) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(funnel).Error; err != nil {
+			return err
+		}
+		// Create skips false booleans in favour of the column default (true).
+		return tx.Model(funnel).Select("free_access_enabled", "is_active").Updates(funnel).Error
+	})
+}
+
+func (r *funnelRepository) Update(ctx context.Context, funnel *models.Funnel) error {
+	return r.db.WithContext(ctx).Save(funnel).Error
+}
+
+func (r *funnelRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Funnel{}, id).Error
+}
+
+func (r *funnelRepository) FindByID(ctx context.Context, id uint) (*models.Funnel, error) {
+	var funnel models.Funnel
+	if err := r.db.WithContext(ctx).First(&funnel, id).Error; err != nil {
+		return nil, err
+	}
+	return &funnel, nil
+}
+
+func (r *funnelRepository) FindBySlug(ctx context.Context, slug string) (*models.Funnel, error) {
+	var funnel models.Funnel
+	if err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&funnel).Error; err != nil {
+		return nil, err
+	}
+	return &funnel, nil
+}
+
+func (r *funnelRepository) List(ctx context.Context, coachID uint, page, pageSize int) ([]models.Funnel, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	db := r.db.WithContext(ctx).Model(&models.Funnel{})
+	if coachID > 0 {
+		db = db.Where("coach_id = ?", coachID)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var funnels []models.Funnel
+	if err := db.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&funnels).Error; err != nil {
+		return nil, 0, err
+	}
+	return funnels, total, nil
+}
@@ -13,7 +13,14 @@ type OtpRepository interface {
 	Create(ctx context.Context, code *models.OtpCode) error
 	FindValidByPhoneAndPurpose(ctx context.Context, phone, purpose string, now time.Time) (*models.OtpCode, error)
 	FindLatestByPhoneAndPurpose(ctx context.Context, phone, purpose string) (*models.OtpCode, error)
-	MarkUsed(ctx context.Context, id uint, usedAt time.Time) error
+	// MarkUsed consumes the code unless it is already used, and reports
+	// whether this call consumed it, so a code opens one session only.
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	// ClaimAttempt counts one guess at the code, in the same statement that
+	// checks it is unused and has had fewer than maxAttempts guesses, so
+	// parallel guesses cannot exceed the limit. It reports false when the
+	// guess may not be checked.
+	ClaimAttempt(ctx context.Context, id uint, maxAttempts int) (bool, error)
 	InvalidatePrevious(ctx context.Context, phone, purpose string, usedAt time.Time) error
 }
 
@@ -53,11 +60,26 @@ func (r *otpRepository) FindLatestByPhoneAndPurpose(ctx context.Context, phone,
 	return &otp, nil
 }
 
-func (r *otpRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) error {
-	return r.db.WithContext(ctx).
+func (r *otpRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).
 		Model(&models.OtpCode{}).
-		Where("id = ?", id).
-		Update("used_at", usedAt).Error
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *otpRepository) ClaimAttempt(ctx context.Context, id uint, maxAttempts int) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.OtpCode{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
 }
 
 func (r *otpRepository) InvalidatePrevious(ctx context.Context, phone, purpose string, usedAt time.Time) error {
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+func TestOtpCodeIsConsumedOnce(t *testing.T) {
+	db, err := config.NewSQLiteGORM(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.OtpCode{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	repo := NewOtpRepository(db)
+	code := &models.OtpCode{Phone: "09120000000", Code: "123456", Purpose: "login", ExpiresAt: time.Now().Add(time.Minute)}
+	if err := repo.Create(ctx, code); err != nil {
+		t.Fatal(err)
+	}
+
+	if used, err := repo.MarkUsed(ctx, code.ID, time.Now()); err != nil || !used {
+		t.Fatalf("first use: %v (%v)", used, err)
+	}
+	if used, err := repo.MarkUsed(ctx, code.ID, time.Now()); err != nil || used {
+		t.Fatalf("second use of the same code should be refused: %v (%v)", used, err)
+	}
+	if ok, err := repo.ClaimAttempt(ctx, code.ID, 5); err != nil || ok {
+		t.Fatalf("a used code takes no more guesses: %v (%v)", ok, err)
+	}
+}
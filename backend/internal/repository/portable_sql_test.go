@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// TestPortableQueriesOnSQLite runs the queries that used MySQL-only SQL
+// against SQLite.
+func TestPortableQueriesOnSQLite(t *testing.T) {
+	db, err := config.NewSQLiteGORM(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Subscription{}, &models.Transaction{},
+		&models.FunnelLead{}, &models.RateLimitCounter{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for i, month := range []time.Month{time.March, time.March, time.November} {
+		tx := &models.Transaction{UserID: 1, AmountCents: 1000, Status: "paid",
+			Reference: string(rune('a' + i)), Date: time.Date(2025, month, 10, 12, 0, 0, 0, time.UTC)}
+		if err := db.Create(tx).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	months, err := NewTransactionRepository(db).GetMonthlyAggregatesByYear(ctx, 2025)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if months[2].Count != 2 || months[2].Sum != 2000 || months[10].Count != 1 {
+		t.Fatalf("unexpected monthly aggregates %+v", months)
+	}
+
+	limiter := NewRateLimitRepository(db)
+	for want := 1; want <= 2; want++ {
+		hits, err := limiter.Hit(ctx, "k", time.Now().Add(time.Minute))
+		if err != nil || hits != want {
+			t.Fatalf("hit %d: got %d (%v)", want, hits, err)
+		}
+	}
+
+	leads := NewFunnelLeadRepository(db)
+	if err := db.Create(&models.FunnelLead{FirstName: "Sara", LastName: "Ahmadi", Phone: "09120000000",
+		CheckoutToken: "t", Status: models.FunnelStatusPendingPayment}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, total, err := leads.List(ctx, 0, "", "sara ahm", 1, 10); err != nil || total != 1 {
+		t.Fatalf("full-name search: total=%d err=%v", total, err)
+	}
+
+	user := &models.User{Name: "Sara", Email: "s@example.com", Phone: "09120000000", Password: "x", Role: models.RoleStudent}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	ends := time.Now().Add(24 * time.Hour)
+	if err := db.Create(&models.Subscription{UserID: user.ID, ServicePlanID: 1, StartsAt: time.Now(), EndsAt: &ends}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := leads.PhoneHasActiveSubscription(ctx, "09120000000"); err != nil || !ok {
+		t.Fatalf("active subscription not found: %v %v", ok, err)
+	}
+}
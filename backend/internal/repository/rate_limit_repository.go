@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RateLimitRepository interface {
+	// Hit increments the counter of bucketKey (creating it with expiresAt) and
+	// returns the new hit count.
+	Hit(ctx context.Context, bucketKey string, expiresAt time.Time) (int, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type rateLimitRepository struct {
+	db *gorm.DB
+}
+
+func NewRateLimitRepository(db *gorm.DB) RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
+
+func (r *rateLimitRepository) Hit(ctx context.Context, bucketKey string, expiresAt time.Time) (int, error) {
+	db := r.db.WithContext(ctx)
+	row := &models.RateLimitCounter{BucketKey: bucketKey, Hits: 1, ExpiresAt: expiresAt}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bucket_key"}},
+		DoUpdates: clause.Assignments(map[string]any{"hits": gorm.Expr("rate_limit_counters.hits + 1")}),
+	}).Create(row).Error; err != nil {
+		return 0, err
+	}
+	var hits int
+	if err := db.Model(&models.RateLimitCounter{}).Where("bucket_key = ?", bucketKey).
+		Select("hits").Scan(&hits).Error; err != nil {
+		return 0, err
+	}
+	return hits, nil
+}
+
+func (r *rateLimitRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.RateLimitCounter{})
+	return res.RowsAffected, res.Error
+}
@@ -21,6 +21,7 @@ type SubscriptionRepository interface {
 	FindCurrentByUserIDAndCoachID(ctx context.Context, userID, coachID uint, now time.Time) (*models.Subscription, error)
 	CountStudentsByCoachID(ctx context.Context, coachID uint) (int64, error)
 	CountActiveSubscriptionsByCoachID(ctx context.Context, coachID uint, now time.Time) (int64, error)
+	SetAllowExerciseSwaps(ctx context.Context, id uint, allowed bool) error
 }
 
 type subscriptionRepository struct {
@@ -143,3 +144,9 @@ func (r *subscriptionRepository) CountActiveSubscriptionsByCoachID(ctx context.C
 		Count(&count).Error
 	return count, err
 }
+
+func (r *subscriptionRepository) SetAllowExerciseSwaps(ctx context.Context, id uint, allowed bool) error {
+	return r.db.WithContext(ctx).Model(&models.Subscription{}).
+		Where("id = ?", id).
+		Update("allow_exercise_swaps", allowed).Error
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// TemplateListingFilter narrows marketplace listing queries.
+type TemplateListingFilter struct {
+	Status   string // "" = any
+	Kind     string // "" = any
+	Query    string
+	AuthorID uint // 0 = any
+}
+
+// TemplateListingStats aggregates acquisitions of one listing.
+type TemplateListingStats struct {
+	ListingID      uint
+	Acquisitions   int64
+	RevenueCents   int64
+	LastAcquiredAt *time.Time
+}
+
+type TemplateListingRepository interface {
+	Create(ctx context.Context, listing *models.TemplateListing) error
+	Save(ctx context.Context, listing *models.TemplateListing) error
+	FindByID(ctx context.Context, id uint) (*models.TemplateListing, error)
+	// FindOpenByTemplate returns the pending or approved listing for a template, if any.
+	FindOpenByTemplate(ctx context.Context, kind string, templateID uint) (*models.TemplateListing, error)
+	// ReopenByTemplate sends an approved listing of the template back to
+	// pending review, after the author changed the template's content.
+	ReopenByTemplate(ctx context.Context, kind string, templateID uint) error
+	// UnlistByTemplate takes the template's open listings off the marketplace.
+	UnlistByTemplate(ctx context.Context, kind string, templateID uint) error
+	List(ctx context.Context, filter TemplateListingFilter, page, pageSize int) ([]models.TemplateListing, int64, error)
+	ListByAuthor(ctx context.Context, authorID uint) ([]models.TemplateListing, error)
+
+	FindAcquisition(ctx context.Context, listingID, buyerID uint) (*models.TemplateAcquisition, error)
+	AcquiredListingIDs(ctx context.Context, buyerID uint, listingIDs []uint) (map[uint]bool, error)
+	StatsByAuthor(ctx context.Context, authorID uint) (map[uint]TemplateListingStats, error)
+	ListAcquisitionsByAuthorSince(ctx context.Context, authorID uint, since time.Time) ([]models.TemplateAcquisition, error)
+}
+
+type templateListingRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplateListingRepository(db *gorm.DB) TemplateListingRepository {
+	return &templateListingRepository{db: db}
+}
+
+func (r *templateListingRepository) Create(ctx context.Context, listing *models.TemplateListing) error {
+	return r.db.WithContext(ctx).Create(listing).Error
+}
+
+func (r *templateListingRepository) Save(ctx context.Context, listing *models.TemplateListing) error {
+	return r.db.WithContext(ctx).Save(listing).Error
+}
+
+func (r *templateListingRepository) FindByID(ctx context.Context, id uint) (*models.TemplateListing, error) {
+	var listing models.TemplateListing
+	if err := r.db.WithContext(ctx).First(&listing, id).Error; err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+
+func (r *templateListingRepository) FindOpenByTemplate(ctx context.Context, kind string, templateID uint) (*models.TemplateListing, error) {
+	var listing models.TemplateListing
+	err := r.db.WithContext(ctx).
+		Where("template_kind = ? AND template_id = ? AND status IN ?", kind, templateID,
+			[]string{models.TemplateListingStatusPending, models.TemplateListingStatusApproved}).
+		First(&listing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+
+func (r *templateListingRepository) ReopenByTemplate(ctx context.Context, kind string, templateID uint) error {
+	return r.db.WithContext(ctx).Model(&models.TemplateListing{}).
+		Where("template_kind = ? AND template_id = ? AND status = ?", kind, templateID, models.TemplateListingStatusApproved).
+		Updates(map[string]any{
+			"status":         models.TemplateListingStatusPending,
+			"review_note":    "",
+			"reviewed_at":    nil,
+			"reviewed_by_id": nil,
+		}).Error
+}
+
+func (r *templateListingRepository) UnlistByTemplate(ctx context.Context, kind string, templateID uint) error {
+	return r.db.WithContext(ctx).Model(&models.TemplateListing{}).
+		Where("template_kind = ? AND template_id = ? AND status IN ?", kind, templateID,
+			[]string{models.TemplateListingStatusPending, models.TemplateListingStatusApproved}).
+		Update("status", models.TemplateListingStatusUnlisted).Error
+}
+
+func (r *templateListingRepository) List(ctx context.Context, filter TemplateListingFilter, page, pageSize int) ([]models.TemplateListing, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.TemplateListing{})
+	if filter.Status != "" && filter.Status != "all" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	if filter.Kind != "" && filter.Kind != "all" {
+		db = db.Where("template_kind = ?", filter.Kind)
+	}
+	if filter.AuthorID > 0 {
+		db = db.Where("author_id = ?", filter.AuthorID)
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		like := "%" + q + "%"
+		db = db.Where("title LIKE ? OR description LIKE ?", like, like)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	var list []models.TemplateListing
+	if err := db.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+func (r *templateListingRepository) ListByAuthor(ctx context.Context, authorID uint) ([]models.TemplateListing, error) {
+	var list []models.TemplateListing
+	if err := r.db.WithContext(ctx).Where("author_id = ?", authorID).Order("created_at DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *templateListingRepository) FindAcquisition(ctx context.Context, listingID, buyerID uint) (*models.TemplateAcquisition, error) {
+	var acq models.TemplateAcquisition
+	if err := r.db.WithContext(ctx).Where("listing_id = ? AND buyer_id = ?", listingID, buyerID).First(&acq).Error; err != nil {
+		return nil, err
+	}
+	return &acq, nil
+}
+
+func (r *templateListingRepository) AcquiredListingIDs(ctx context.Context, buyerID uint, listingIDs []uint) (map[uint]bool, error) {
+	out := map[uint]bool{}
+	if len(listingIDs) == 0 {
+		return out, nil
+	}
+	var ids []uint
+	if err := r.db.WithContext(ctx).Model(&models.TemplateAcquisition{}).
+		Where("buyer_id = ? AND listing_id IN ?", buyerID, listingIDs).
+		Pluck("listing_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		out[id] = true
+	}
+	return out, nil
+}
+
+// StatsByAuthor adds up acquisitions in Go: MAX(created_at) comes back from
+// SQLite as text, which does not scan into a time.
+func (r *templateListingRepository) StatsByAuthor(ctx context.Context, authorID uint) (map[uint]TemplateListingStats, error) {
+	var rows []models.TemplateAcquisition
+	err := r.db.WithContext(ctx).
+		Select("listing_id", "price_cents", "created_at").
+		Where("author_id = ?", authorID).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := map[uint]TemplateListingStats{}
+	for _, row := range rows {
+		st := out[row.ListingID]
+		st.ListingID = row.ListingID
+		st.Acquisitions++
+		st.RevenueCents += row.PriceCents
+		if st.LastAcquiredAt == nil || row.CreatedAt.After(*st.LastAcquiredAt) {
+			at := row.CreatedAt
+			st.LastAcquiredAt = &at
+		}
+		out[row.ListingID] = st
+	}
+	return out, nil
+}
+
+func (r *templateListingRepository) ListAcquisitionsByAuthorSince(ctx context.Context, authorID uint, since time.Time) ([]models.TemplateAcquisition, error) {
+	var list []models.TemplateAcquisition
+	if err := r.db.WithContext(ctx).
+		Where("author_id = ? AND created_at >= ?", authorID, since).
+		Order("created_at ASC").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -8,6 +8,17 @@ import (
 	"gorm.io/gorm"
 )
 
+// TemplateListFilter scopes template listings. CoachID/Source mirror
+// ExerciseListFilter: platform templates have no owner, coach templates are private.
+type TemplateListFilter struct {
+	Query   string
+	CoachID *uint  // when set, limits listing to platform + this coach's templates
+	Source  string // all | mine | platform
+	// CalorieMin/CalorieMax bound NutritionTemplate.Calorie when > 0; ignored for workout templates.
+	CalorieMin int
+	CalorieMax int
+}
+
 type TemplateRepository interface {
 	WorkoutTemplateExistsBySourceID(ctx context.Context, sourceID int) (bool, error)
 	NutritionTemplateExistsBySourceID(ctx context.Context, sourceID int) (bool, error)
@@ -15,10 +26,13 @@ type TemplateRepository interface {
 	CreateNutritionTemplate(ctx context.Context, template *models.NutritionTemplate) error
 	FindWorkoutTemplateByID(ctx context.Context, id uint) (*models.WorkoutTemplate, error)
 	FindNutritionTemplateByID(ctx context.Context, id uint) (*models.NutritionTemplate, error)
-	ListWorkoutTemplates(ctx context.Context) ([]models.WorkoutTemplate, error)
-	ListNutritionTemplates(ctx context.Context) ([]models.NutritionTemplate, error)
-	ListWorkoutTemplatesPaged(ctx context.Context, page, pageSize int, query string) ([]models.WorkoutTemplate, int64, error)
-	ListNutritionTemplatesPaged(ctx context.Context, page, pageSize int, query string) ([]models.NutritionTemplate, int64, error)
+	ListWorkoutTemplates(ctx context.Context, filter TemplateListFilter) ([]models.WorkoutTemplate, error)
+	ListNutritionTemplates(ctx context.Context, filter TemplateListFilter) ([]models.NutritionTemplate, error)
+	ListWorkoutTemplatesPaged(ctx context.Context, page, pageSize int, filter TemplateListFilter) ([]models.WorkoutTemplate, int64, error)
+	ListNutritionTemplatesPaged(ctx context.Context, page, pageSize int, filter TemplateListFilter) ([]models.NutritionTemplate, int64, error)
+	// ListWorkoutTemplateItems loads the exercise slots of many templates at once (no sets).
+	ListWorkoutTemplateItems(ctx context.Context, templateIDs []uint) ([]models.TemplateProgramItem, error)
+	CountNutritionTemplateMeals(ctx context.Context, templateIDs []uint) (map[uint]int, error)
 	UpdateWorkoutTemplateMeta(ctx context.Context, template *models.WorkoutTemplate) error
 	ReplaceWorkoutTemplateItems(ctx context.Context, templateID uint, items []models.TemplateProgramItem) error
 	DeleteWorkoutTemplate(ctx context.Context, id uint) error
@@ -37,6 +51,32 @@ func NewTemplateRepository(db *gorm.DB) TemplateRepository {
 	return &templateRepository{db: db}
 }
 
+func applyTemplateOwnerFilter(db *gorm.DB, filter TemplateListFilter) *gorm.DB {
+	switch strings.ToLower(strings.TrimSpace(filter.Source)) {
+	case "mine":
+		if filter.CoachID == nil {
+			return db.Where("1 = 0")
+		}
+		return db.Where("coach_id = ?", *filter.CoachID)
+	case "platform":
+		return db.Where("coach_id IS NULL")
+	}
+	if filter.CoachID != nil {
+		return db.Where("coach_id IS NULL OR coach_id = ?", *filter.CoachID)
+	}
+	return db
+}
+
+func applyTemplateCalorieFilter(db *gorm.DB, filter TemplateListFilter) *gorm.DB {
+	if filter.CalorieMin > 0 {
+		db = db.Where("calorie >= ?", filter.CalorieMin)
+	}
+	if filter.CalorieMax > 0 {
+		db = db.Where("calorie <= ?", filter.CalorieMax)
+	}
+	return db
+}
+
 func (r *templateRepository) WorkoutTemplateExistsBySourceID(ctx context.Context, sourceID int) (bool, error) {
 	var count int64
 	err := r.db.WithContext(ctx).
@@ -95,33 +135,32 @@ func (r *templateRepository) FindNutritionTemplateByID(ctx context.Context, id u
 	return &template, nil
 }
 
-func (r *templateRepository) ListWorkoutTemplates(ctx context.Context) ([]models.WorkoutTemplate, error) {
+func (r *templateRepository) ListWorkoutTemplates(ctx context.Context, filter TemplateListFilter) ([]models.WorkoutTemplate, error) {
 	var templates []models.WorkoutTemplate
-	err := r.db.WithContext(ctx).
-		Model(&models.WorkoutTemplate{}).
+	err := applyTemplateOwnerFilter(r.db.WithContext(ctx).Model(&models.WorkoutTemplate{}), filter).
 		Order("title ASC, id ASC").
 		Find(&templates).Error
 	return templates, err
 }
 
-func (r *templateRepository) ListNutritionTemplates(ctx context.Context) ([]models.NutritionTemplate, error) {
+func (r *templateRepository) ListNutritionTemplates(ctx context.Context, filter TemplateListFilter) ([]models.NutritionTemplate, error) {
 	var templates []models.NutritionTemplate
-	err := r.db.WithContext(ctx).
-		Model(&models.NutritionTemplate{}).
+	db := applyTemplateOwnerFilter(r.db.WithContext(ctx).Model(&models.NutritionTemplate{}), filter)
+	err := applyTemplateCalorieFilter(db, filter).
 		Order("title ASC, id ASC").
 		Find(&templates).Error
 	return templates, err
 }
 
-func (r *templateRepository) ListWorkoutTemplatesPaged(ctx context.Context, page, pageSize int, query string) ([]models.WorkoutTemplate, int64, error) {
+func (r *templateRepository) ListWorkoutTemplatesPaged(ctx context.Context, page, pageSize int, filter TemplateListFilter) ([]models.WorkoutTemplate, int64, error) {
 	if page <= 0 {
 		page = 1
 	}
 	if pageSize <= 0 {
 		pageSize = 20
 	}
-	db := r.db.WithContext(ctx).Model(&models.WorkoutTemplate{})
-	if q := strings.TrimSpace(query); q != "" {
+	db := applyTemplateOwnerFilter(r.db.WithContext(ctx).Model(&models.WorkoutTemplate{}), filter)
+	if q := strings.TrimSpace(filter.Query); q != "" {
 		like := "%" + q + "%"
 		db = db.Where("title LIKE ? OR target LIKE ? OR level LIKE ? OR gender LIKE ? OR location LIKE ?",
 			like, like, like, like, like)
@@ -138,6 +177,41 @@ func (r *templateRepository) ListWorkoutTemplatesPaged(ctx context.Context, page
 	return templates, total, err
 }
 
+func (r *templateRepository) ListWorkoutTemplateItems(ctx context.Context, templateIDs []uint) ([]models.TemplateProgramItem, error) {
+	if len(templateIDs) == 0 {
+		return nil, nil
+	}
+	var items []models.TemplateProgramItem
+	err := r.db.WithContext(ctx).
+		Where("workout_template_id IN ?", templateIDs).
+		Order("workout_template_id ASC, day_number ASC, order_index ASC").
+		Find(&items).Error
+	return items, err
+}
+
+func (r *templateRepository) CountNutritionTemplateMeals(ctx context.Context, templateIDs []uint) (map[uint]int, error) {
+	out := map[uint]int{}
+	if len(templateIDs) == 0 {
+		return out, nil
+	}
+	var rows []struct {
+		NutritionTemplateID uint
+		Count               int
+	}
+	err := r.db.WithContext(ctx).Model(&models.TemplateMeal{}).
+		Select("nutrition_template_id, COUNT(*) AS count").
+		Where("nutrition_template_id IN ?", templateIDs).
+		Group("nutrition_template_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		out[row.NutritionTemplateID] = row.Count
+	}
+	return out, nil
+}
+
 func (r *templateRepository) UpdateWorkoutTemplateMeta(ctx context.Context, template *models.WorkoutTemplate) error {
 	return r.db.WithContext(ctx).Model(template).
 		Select("Title", "Type", "Gender", "Location", "DayCount", "Target", "Injury", "Level", "UpdatedAt").
@@ -212,15 +286,16 @@ func (r *templateRepository) NextManualWorkoutSourceID(ctx context.Context) (int
 	return next, nil
 }
 
-func (r *templateRepository) ListNutritionTemplatesPaged(ctx context.Context, page, pageSize int, query string) ([]models.NutritionTemplate, int64, error) {
+func (r *templateRepository) ListNutritionTemplatesPaged(ctx context.Context, page, pageSize int, filter TemplateListFilter) ([]models.NutritionTemplate, int64, error) {
 	if page <= 0 {
 		page = 1
 	}
 	if pageSize <= 0 {
 		pageSize = 20
 	}
-	db := r.db.WithContext(ctx).Model(&models.NutritionTemplate{})
-	if q := strings.TrimSpace(query); q != "" {
+	db := applyTemplateOwnerFilter(r.db.WithContext(ctx).Model(&models.NutritionTemplate{}), filter)
+	db = applyTemplateCalorieFilter(db, filter)
+	if q := strings.TrimSpace(filter.Query); q != "" {
 		like := "%" + q + "%"
 		db = db.Where("title LIKE ? OR target LIKE ? OR gender LIKE ? OR type LIKE ? OR limitation LIKE ?",
 			like, like, like, like, like)
@@ -74,11 +74,12 @@ func (r *transactionRepository) GetMonthlyAggregatesByYear(ctx context.Context,
 		Sum   int64
 	}
 	var rows []row
+	month := monthOf(r.db, "date")
 	err := r.db.WithContext(ctx).
 		Model(&models.Transaction{}).
-		Select("MONTH(date) AS month, COUNT(*) AS count, COALESCE(SUM(amount_cents),0) AS sum").
+		Select(month + " AS month, COUNT(*) AS count, COALESCE(SUM(amount_cents),0) AS sum").
 		Where("date >= ? AND date < ?", start, end).
-		Group("MONTH(date)").
+		Group(month).
 		Find(&rows).Error
 	if err != nil {
 		return nil, err
@@ -98,3 +99,15 @@ func (r *transactionRepository) GetMonthlyAggregatesByYear(ctx context.Context,
 	return out, nil
 }
 
+// monthOf is the SQL for the month number (1-12) of a time column; each
+// supported driver spells it differently.
+func monthOf(db *gorm.DB, column string) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "CAST(EXTRACT(MONTH FROM " + column + ") AS INTEGER)"
+	case "sqlite":
+		return "CAST(strftime('%m', " + column + ") AS INTEGER)"
+	default:
+		return "MONTH(" + column + ")"
+	}
+}
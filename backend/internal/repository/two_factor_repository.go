@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+type TwoFactorRepository interface {
+	// Find returns the TOTP enrollment of userID, enabled or not.
+	Find(ctx context.Context, userID uint) (*models.UserTwoFactor, error)
+	Save(ctx context.Context, tf *models.UserTwoFactor) error
+	// AdvanceStep records step as used unless an equal or later one already
+	// was, and reports whether it did.
+	AdvanceStep(ctx context.Context, userID uint, step int64) (bool, error)
+	// Delete removes the enrollment and every recovery code of userID.
+	Delete(ctx context.Context, userID uint) error
+	// ReplaceRecoveryCodes swaps all recovery codes of userID for hashes.
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, hashes []string) error
+	// UseRecoveryCode marks the unused code with hash as used and reports
+	// whether there was one.
+	UseRecoveryCode(ctx context.Context, userID uint, hash string, now time.Time) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+}
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+func (r *twoFactorRepository) Find(ctx context.Context, userID uint) (*models.UserTwoFactor, error) {
+	var tf models.UserTwoFactor
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&tf).Error; err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+func (r *twoFactorRepository) Save(ctx context.Context, tf *models.UserTwoFactor) error {
+	return r.db.WithContext(ctx).Save(tf).Error
+}
+
+func (r *twoFactorRepository) AdvanceStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *twoFactorRepository) Delete(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserTwoFactor{}).Error
+	})
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, hashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		rows := make([]models.TwoFactorRecoveryCode, 0, len(hashes))
+		for _, h := range hashes {
+			rows = append(rows, models.TwoFactorRecoveryCode{UserID: userID, CodeHash: h})
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID uint, hash string, now time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *twoFactorRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&n).Error
+	return n, err
+}
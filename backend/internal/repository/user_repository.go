@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/yourusername/fitness-management/internal/models"
 	"gorm.io/gorm"
@@ -15,7 +16,13 @@ type UserRepository interface {
 	FindByIdentifier(ctx context.Context, identifier string) (*models.User, error)
 	FindByID(ctx context.Context, id uint) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
+	// UpdatePassword also lifts a pending forced password change and any
+	// login lockout.
 	UpdatePassword(ctx context.Context, id uint, hashedPassword string) error
+	// RecordLoginFailure bumps the failed sign-in counter and returns it.
+	RecordLoginFailure(ctx context.Context, id uint) (int, error)
+	LockLogin(ctx context.Context, id uint, until time.Time) error
+	ResetLoginFailures(ctx context.Context, id uint) error
 }
 
 type userRepository struct {
@@ -74,5 +81,39 @@ func (r *userRepository) UpdatePassword(ctx context.Context, id uint, hashedPass
 	return r.db.WithContext(ctx).
 		Model(&models.User{}).
 		Where("id = ?", id).
-		Update("password", hashedPassword).Error
+		Updates(map[string]any{
+			"password":             hashedPassword,
+			"must_change_password": false,
+			"failed_login_count":   0,
+			"login_locked_until":   nil,
+		}).Error
+}
+
+func (r *userRepository) RecordLoginFailure(ctx context.Context, id uint) (int, error) {
+	if err := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Update("failed_login_count", gorm.Expr("failed_login_count + 1")).Error; err != nil {
+		return 0, err
+	}
+	var count int
+	err := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Pluck("failed_login_count", &count).Error
+	return count, err
+}
+
+func (r *userRepository) LockLogin(ctx context.Context, id uint, until time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Update("login_locked_until", until).Error
+}
+
+func (r *userRepository) ResetLoginFailures(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND (failed_login_count <> 0 OR login_locked_until IS NOT NULL)", id).
+		Updates(map[string]any{"failed_login_count": 0, "login_locked_until": nil}).Error
 }
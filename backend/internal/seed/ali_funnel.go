@@ -9,14 +9,12 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
-	"github.com/yourusername/fitness-management/config"
 	"github.com/yourusername/fitness-management/internal/models"
-	"github.com/yourusername/fitness-management/internal/pkg/slug"
 )
 
 const (
-	AliFunnelKey         = "funnel_1"
 	AliFunnelCoachSlug   = "ali-rashidabadi"
+	aliFunnelTitle       = "فانل علی رشیدآبادی"
 	aliFunnelCoachEmail  = "ali.rashidabadi@fitino.ir"
 	aliFunnelCoachPhone  = "09151111111"
 	aliFunnelCoachPass   = "12345678"
@@ -25,14 +23,12 @@ const (
 	aliCIPPlanName       = "پلن CIP"
 )
 
-// EnsureAliFunnel seeds Funnel 1 coach (علی رشیدآبادی) + VIP/CIP ServicePlans.
-// Idempotent — safe on every API / seed startup (production included).
+// EnsureAliFunnel seeds the علی رشیدآبادی coach, his VIP/CIP ServicePlans and
+// the funnel selling them (slug ali-rashidabadi). Idempotent; runs with demo
+// data or `seed -ali`.
 func EnsureAliFunnel(ctx context.Context, db *gorm.DB) error {
 	_ = ctx
 	coachSlug := AliFunnelCoachSlug
-	if v := strings.TrimSpace(config.Get().Funnel.CoachSlug); v != "" {
-		coachSlug = slug.Normalize(v)
-	}
 
 	user, err := ensureAliFunnelUser(db, coachSlug)
 	if err != nil {
@@ -41,11 +37,15 @@ func EnsureAliFunnel(ctx context.Context, db *gorm.DB) error {
 	if err := ensureAliFunnelProfile(db, user.ID, coachSlug); err != nil {
 		return err
 	}
-	if err := ensureAliFunnelPlans(db, user.ID); err != nil {
+	planIDs, err := ensureAliFunnelPlans(db, user.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := upsertFunnel(db, coachSlug, aliFunnelTitle, user.ID, planIDs); err != nil {
 		return err
 	}
 
-	log.Printf("funnel_1 ready: coach=%s slug=%s plans=VIP(%d)+CIP(%d)",
+	log.Printf("ali funnel ready: coach=%s slug=%s plans=VIP(%d)+CIP(%d)",
 		aliFunnelDisplayName, coachSlug, 1_490_000, 2_900_000)
 	return nil
 }
@@ -73,7 +73,7 @@ func ensureAliFunnelUser(db *gorm.DB, coachSlug string) (*models.User, error) {
 			return nil, herr
 		}
 		user.Password = string(hashed)
-		log.Printf("reclaimed funnel_1 coach user id=%d for slug=%s → %s", user.ID, coachSlug, aliFunnelCoachEmail)
+		log.Printf("reclaimed ali funnel coach user id=%d for slug=%s → %s", user.ID, coachSlug, aliFunnelCoachEmail)
 		return syncAliFunnelUser(db, &user)
 	} else if e != nil && e != gorm.ErrRecordNotFound {
 		return nil, e
@@ -96,7 +96,7 @@ func ensureAliFunnelUser(db *gorm.DB, coachSlug string) (*models.User, error) {
 	if err := db.Create(&user).Error; err != nil {
 		return nil, err
 	}
-	log.Printf("seeded funnel_1 coach user %s (password: %s)", aliFunnelCoachEmail, aliFunnelCoachPass)
+	log.Printf("seeded ali funnel coach user %s (password: %s)", aliFunnelCoachEmail, aliFunnelCoachPass)
 	return &user, nil
 }
 
@@ -130,7 +130,7 @@ func ensureAliFunnelProfile(db *gorm.DB, userID uint, coachSlug string) error {
 		// Slug already taken by another coach → reclaim for funnel user.
 		err = db.Where("slug = ?", coachSlug).First(&profile).Error
 		if err == nil {
-			log.Printf("reclaimed funnel_1 coach_profile id=%d slug=%s → user_id=%d", profile.ID, coachSlug, userID)
+			log.Printf("reclaimed ali funnel coach_profile id=%d slug=%s → user_id=%d", profile.ID, coachSlug, userID)
 			profile.UserID = userID
 		} else if err == gorm.ErrRecordNotFound {
 			profile = models.CoachProfile{
@@ -190,7 +190,7 @@ func ensureAliFunnelProfile(db *gorm.DB, userID uint, coachSlug string) error {
 	return db.Save(&profile).Error
 }
 
-func ensureAliFunnelPlans(db *gorm.DB, coachUserID uint) error {
+func ensureAliFunnelPlans(db *gorm.DB, coachUserID uint) ([]uint, error) {
 	// Card UI shows first 2 lines — both plans must surface support;
 	// only the support channel differs (panel/ticket vs direct coach).
 	vipFeatures := strings.Join([]string{
@@ -237,12 +237,15 @@ func ensureAliFunnelPlans(db *gorm.DB, coachUserID uint) error {
 		},
 	}
 
+	ids := make([]uint, 0, len(plans))
 	for _, spec := range plans {
-		if err := upsertAliPlan(db, coachUserID, spec.name, spec.subtitle, spec.courseName, spec.description, spec.features, spec.price, spec.popular); err != nil {
-			return err
+		id, err := upsertAliPlan(db, coachUserID, spec.name, spec.subtitle, spec.courseName, spec.description, spec.features, spec.price, spec.popular)
+		if err != nil {
+			return nil, err
 		}
+		ids = append(ids, id)
 	}
-	return nil
+	return ids, nil
 }
 
 func upsertAliPlan(
@@ -251,11 +254,11 @@ func upsertAliPlan(
 	name, subtitle, courseName, description, features string,
 	price int64,
 	popular bool,
-) error {
+) (uint, error) {
 	var plan models.ServicePlan
 	err := db.Where("coach_id = ? AND name = ?", coachUserID, name).First(&plan).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
-		return err
+		return 0, err
 	}
 	if err == gorm.ErrRecordNotFound {
 		// Name is globally unique — reclaim orphaned row with same name if any.
@@ -264,7 +267,7 @@ func upsertAliPlan(
 			plan = orphan
 			plan.CoachID = coachUserID
 		} else if e != gorm.ErrRecordNotFound {
-			return e
+			return 0, e
 		} else {
 			plan = models.ServicePlan{
 				CoachID: coachUserID,
@@ -289,7 +292,9 @@ func upsertAliPlan(
 	plan.IsActive = true
 
 	if plan.ID == 0 {
-		return db.Create(&plan).Error
+		err = db.Create(&plan).Error
+	} else {
+		err = db.Save(&plan).Error
 	}
-	return db.Save(&plan).Error
+	return plan.ID, err
 }
@@ -0,0 +1,38 @@
+package seed
+
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+// upsertFunnel creates the funnel with funnelSlug or rebinds it to coachID and
+// planIDs. Questions and analysis copy are left to the funnel editor.
+func upsertFunnel(db *gorm.DB, funnelSlug, title string, coachID uint, planIDs []uint) (*models.Funnel, error) {
+	plans := ""
+	if len(planIDs) > 0 {
+		b, _ := json.Marshal(planIDs)
+		plans = string(b)
+	}
+	var funnel models.Funnel
+	err := db.Where("slug = ?", funnelSlug).First(&funnel).Error
+	if err == gorm.ErrRecordNotFound {
+		funnel = models.Funnel{
+			Slug:              funnelSlug,
+			Title:             title,
+			CoachID:           coachID,
+			PlanIDs:           plans,
+			FreeAccessEnabled: true,
+			IsActive:          true,
+		}
+		return &funnel, db.Create(&funnel).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	funnel.CoachID = coachID
+	funnel.PlanIDs = plans
+	return &funnel, db.Save(&funnel).Error
+}
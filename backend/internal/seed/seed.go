@@ -51,8 +51,9 @@ func RunDev(ctx context.Context, db *gorm.DB, opts RunDevOptions) (*gormseed.Res
 	return res, nil
 }
 
-// syncAutoIncrement bumps AUTO_INCREMENT past explicitly seeded IDs so future
-// inserts do not collide with fixture primary keys.
+// syncAutoIncrement bumps the id sequence past explicitly seeded IDs so future
+// inserts do not collide with fixture primary keys. SQLite needs nothing: its
+// rowid already continues from MAX(id).
 func syncAutoIncrement(ctx context.Context, db *gorm.DB) error {
 	tables := []string{
 		"users", "coach_profiles", "service_plans", "subscriptions",
@@ -60,11 +61,17 @@ func syncAutoIncrement(ctx context.Context, db *gorm.DB) error {
 		"nutrition_programs", "nutrition_items", "site_settings",
 		"feedbacks", "check_ins", "workout_sessions", "transactions", "notifications",
 	}
+	var pattern string
+	switch db.Dialector.Name() {
+	case "mysql":
+		pattern = "SET @seed_ai := (SELECT IFNULL(MAX(id), 0) + 1 FROM `%[1]s`); SET @seed_sql := CONCAT('ALTER TABLE `%[1]s` AUTO_INCREMENT = ', @seed_ai); PREPARE seed_stmt FROM @seed_sql; EXECUTE seed_stmt; DEALLOCATE PREPARE seed_stmt;"
+	case "postgres":
+		pattern = `SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM "%[1]s"`
+	default:
+		return nil
+	}
 	for _, table := range tables {
-		stmt := fmt.Sprintf(
-			"SET @seed_ai := (SELECT IFNULL(MAX(id), 0) + 1 FROM `%s`); SET @seed_sql := CONCAT('ALTER TABLE `%s` AUTO_INCREMENT = ', @seed_ai); PREPARE seed_stmt FROM @seed_sql; EXECUTE seed_stmt; DEALLOCATE PREPARE seed_stmt;",
-			table, table,
-		)
+		stmt := fmt.Sprintf(pattern, table)
 		if err := db.WithContext(ctx).Exec(stmt).Error; err != nil {
 			return fmt.Errorf("table %s: %w", table, err)
 		}
@@ -181,6 +181,8 @@ func seedWorkoutTemplates(
 			continue
 		}
 
+		// Catalog imports are platform templates: creatorId refers to the source
+		// site's users, so CoachID (coach-private ownership) is left empty.
 		template := &models.WorkoutTemplate{
 			SourceID: src.ID,
 			Title:    strings.TrimSpace(src.Title),
@@ -191,7 +193,6 @@ func seedWorkoutTemplates(
 			Target:   strings.TrimSpace(src.Target),
 			Injury:   strings.TrimSpace(src.Injury),
 			Level:    strings.TrimSpace(src.Level),
-			CoachID:  mapCreatorID(src.CreatorID),
 		}
 		if template.DayCount <= 0 {
 			template.DayCount = len(src.Days)
@@ -337,7 +338,6 @@ func seedNutritionTemplates(
 			Description: strings.TrimSpace(src.Description),
 			IsPro:       src.IsPro,
 			Version:     version,
-			CoachID:     mapCreatorID(src.CreatorID),
 		}
 
 		meals := buildNutritionTemplateMeals(ctx, foodRepo, src.Data, &unmatchedFoods)
@@ -414,14 +414,6 @@ func buildNutritionTemplateMeals(
 	return meals
 }
 
-func mapCreatorID(creatorID *int) *uint {
-	if creatorID == nil || *creatorID <= 0 {
-		return nil
-	}
-	id := uint(*creatorID)
-	return &id
-}
-
 func mapExerciseSystemType(system string, systemID int) string {
 	s := strings.TrimSpace(strings.ToLower(system))
 	switch {
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+// accountStatusCacheTTL bounds how long another replica may keep serving a
+// user who was just suspended; this replica drops its entry right away.
+const accountStatusCacheTTL = 15 * time.Second
+
+var (
+	ErrAccountNotFound      = errors.New("account not found")
+	ErrInvalidAccountStatus = errors.New("invalid account status")
+)
+
+// AccountBlockedError is returned for a suspended or banned account.
+type AccountBlockedError struct {
+	Status string
+	Reason string
+	Until  *time.Time
+}
+
+func (e *AccountBlockedError) Error() string {
+	return fmt.Sprintf("account %s", e.Status)
+}
+
+// accountBlocked returns the block on u at now, or nil when u may sign in.
+func accountBlocked(u *models.User, now time.Time) *AccountBlockedError {
+	status := u.EffectiveStatus(now)
+	if status == models.UserStatusActive {
+		return nil
+	}
+	return &AccountBlockedError{Status: status, Reason: u.StatusReason, Until: u.StatusUntil}
+}
+
+// AccountGuard is what the auth pipeline asks before trusting a token.
+type AccountGuard interface {
+	// CheckAccount returns *AccountBlockedError for suspended and banned
+	// users and ErrAccountNotFound for deleted ones.
+	CheckAccount(ctx context.Context, userID uint) error
+}
+
+// AccountStatusRequest is the admin's status change. Until applies to
+// suspensions only; nil suspends until lifted.
+type AccountStatusRequest struct {
+	Status string     `json:"status" binding:"required"`
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
+// AccountStatusDTO is a user's current account status.
+type AccountStatusDTO struct {
+	UserID    uint       `json:"userId"`
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+	ChangedAt *time.Time `json:"changedAt,omitempty"`
+}
+
+type AccountStatusService interface {
+	AccountGuard
+	GetStatus(ctx context.Context, userID uint) (*AccountStatusDTO, error)
+	// SetStatus changes the account status. Suspending or banning revokes
+	// every refresh token of the user; the user is notified either way.
+	SetStatus(ctx context.Context, userID uint, req *AccountStatusRequest) (*AccountStatusDTO, error)
+}
+
+type accountStatusEntry struct {
+	user    *models.User // nil when the account does not exist
+	expires time.Time
+}
+
+type accountStatusService struct {
+	db               *gorm.DB
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	now              func() time.Time
+
+	mu    sync.Mutex
+	cache map[uint]accountStatusEntry
+}
+
+func NewAccountStatusService(db *gorm.DB, userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository) AccountStatusService {
+	return &accountStatusService{
+		db:               db,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		now:              time.Now,
+		cache:            map[uint]accountStatusEntry{},
+	}
+}
+
+func (s *accountStatusService) CheckAccount(ctx context.Context, userID uint) error {
+	now := s.now()
+	s.mu.Lock()
+	entry, ok := s.cache[userID]
+	s.mu.Unlock()
+	if !ok || now.After(entry.expires) {
+		user, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		entry = accountStatusEntry{user: user, expires: now.Add(accountStatusCacheTTL)}
+		s.mu.Lock()
+		s.cache[userID] = entry
+		s.mu.Unlock()
+	}
+	if entry.user == nil {
+		return ErrAccountNotFound
+	}
+	if blocked := accountBlocked(entry.user, now); blocked != nil {
+		return blocked
+	}
+	return nil
+}
+
+func (s *accountStatusService) forget(userID uint) {
+	s.mu.Lock()
+	delete(s.cache, userID)
+	s.mu.Unlock()
+}
+
+func (s *accountStatusService) GetStatus(ctx context.Context, userID uint) (*AccountStatusDTO, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return s.toDTO(user), nil
+}
+
+func (s *accountStatusService) SetStatus(ctx context.Context, userID uint, req *AccountStatusRequest) (*AccountStatusDTO, error) {
+	status := strings.TrimSpace(req.Status)
+	if !models.IsValidUserStatus(status) {
+		return nil, ErrInvalidAccountStatus
+	}
+	now := s.now()
+	until := req.Until
+	if status != models.UserStatusSuspended {
+		until = nil
+	} else if until != nil && !until.After(now) {
+		return nil, ErrInvalidAccountStatus
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if status == models.UserStatusActive {
+		reason = ""
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	before := s.toDTO(user)
+
+	if err := s.db.WithContext(ctx).Model(user).Updates(map[string]any{
+		"account_status":    status,
+		"status_reason":     truncateRunes(reason, 500),
+		"status_until":      until,
+		"status_changed_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	user.AccountStatus, user.StatusReason, user.StatusUntil, user.StatusChangedAt = status, reason, until, &now
+	s.forget(userID)
+
+	if status != models.UserStatusActive {
+		if err := s.refreshTokenRepo.DeleteByUserID(ctx, userID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	s.notify(ctx, user)
+
+	after := s.toDTO(user)
+	AuditChange(ctx, "user_status", userID, before, after)
+	return after, nil
+}
+
+func (s *accountStatusService) notify(ctx context.Context, user *models.User) {
+	title, message := "حساب کاربری شما فعال شد", "دسترسی شما به حساب کاربری دوباره برقرار شد."
+	switch user.AccountStatus {
+	case models.UserStatusSuspended:
+		title, message = "حساب کاربری شما تعلیق شد", "دسترسی شما به حساب کاربری به طور موقت قطع شده است."
+	case models.UserStatusBanned:
+		title, message = "حساب کاربری شما مسدود شد", "دسترسی شما به حساب کاربری به طور دائم قطع شده است."
+	}
+	if user.StatusReason != "" {
+		message += " دلیل: " + user.StatusReason
+	}
+	n := &models.Notification{
+		UserID:  user.ID,
+		Type:    models.NotificationTypeAccountStatus,
+		Title:   title,
+		Message: message,
+	}
+	if err := s.db.WithContext(ctx).Create(n).Error; err != nil {
+		log.Printf("notify: create account_status notification failed user=%d err=%v", user.ID, err)
+	}
+}
+
+func (s *accountStatusService) toDTO(u *models.User) *AccountStatusDTO {
+	dto := &AccountStatusDTO{UserID: u.ID, Status: u.EffectiveStatus(s.now()), ChangedAt: u.StatusChangedAt}
+	if dto.Status != models.UserStatusActive {
+		dto.Reason, dto.Until = u.StatusReason, u.StatusUntil
+	}
+	return dto
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+var (
+	ErrAdminRoleInvalid   = errors.New("unknown admin role")
+	ErrAdminUserNotFound  = errors.New("admin user not found")
+	ErrLastSuperAdminRole = errors.New("cannot remove the last super admin")
+)
+
+// AdminAccessDTO is an admin's roles and the permissions they add up to.
+type AdminAccessDTO struct {
+	UserID      uint     `json:"userId"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// AdminRoleDTO describes one admin role for the role editor.
+type AdminRoleDTO struct {
+	Key         string   `json:"key"`
+	Permissions []string `json:"permissions"`
+}
+
+type AdminRoleCatalogDTO struct {
+	Roles       []AdminRoleDTO `json:"roles"`
+	Permissions []string       `json:"permissions"`
+}
+
+type AdminAccessService interface {
+	// Access returns the effective roles and permissions of an admin user.
+	Access(ctx context.Context, userID uint) (*AdminAccessDTO, error)
+	Catalog() *AdminRoleCatalogDTO
+	// SetRoles replaces the admin roles of userID. The last super admin
+	// cannot lose the role.
+	SetRoles(ctx context.Context, actorID, userID uint, roles []string) (*AdminAccessDTO, error)
+}
+
+type adminAccessService struct {
+	repo     repository.AdminRoleRepository
+	userRepo repository.UserRepository
+}
+
+func NewAdminAccessService(repo repository.AdminRoleRepository, userRepo repository.UserRepository) AdminAccessService {
+	return &adminAccessService{repo: repo, userRepo: userRepo}
+}
+
+func (s *adminAccessService) Access(ctx context.Context, userID uint) (*AdminAccessDTO, error) {
+	roles, err := s.repo.RolesOf(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return adminAccess(userID, roles), nil
+}
+
+func adminAccess(userID uint, roles []string) *AdminAccessDTO {
+	set := map[string]bool{}
+	for _, role := range roles {
+		for _, perm := range models.AdminRolePermissions(role) {
+			set[perm] = true
+		}
+	}
+	perms := make([]string, 0, len(set))
+	for perm := range set {
+		perms = append(perms, perm)
+	}
+	sort.Strings(perms)
+	if roles == nil {
+		roles = []string{}
+	}
+	return &AdminAccessDTO{UserID: userID, Roles: roles, Permissions: perms}
+}
+
+func (s *adminAccessService) Catalog() *AdminRoleCatalogDTO {
+	out := &AdminRoleCatalogDTO{Permissions: models.AllAdminPermissions()}
+	for _, role := range models.AdminRoleNames() {
+		perms := append([]string(nil), models.AdminRolePermissions(role)...)
+		sort.Strings(perms)
+		out.Roles = append(out.Roles, AdminRoleDTO{Key: role, Permissions: perms})
+	}
+	return out
+}
+
+func (s *adminAccessService) SetRoles(ctx context.Context, actorID, userID uint, roles []string) (*AdminAccessDTO, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAdminUserNotFound
+		}
+		return nil, err
+	}
+	if user.Role != models.RoleAdmin {
+		return nil, ErrAdminUserNotFound
+	}
+
+	seen := map[string]bool{}
+	next := make([]string, 0, len(roles))
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if !models.IsValidAdminRole(role) {
+			return nil, ErrAdminRoleInvalid
+		}
+		if !seen[role] {
+			seen[role] = true
+			next = append(next, role)
+		}
+	}
+	sort.Strings(next)
+
+	current, err := s.repo.RolesOf(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(current, models.AdminRoleSuperAdmin) && !seen[models.AdminRoleSuperAdmin] {
+		count, err := s.repo.CountAdminsWithRole(ctx, models.AdminRoleSuperAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if count <= 1 {
+			return nil, ErrLastSuperAdminRole
+		}
+	}
+
+	if err := s.repo.Replace(ctx, userID, next, actorID); err != nil {
+		return nil, err
+	}
+	AuditChange(ctx, "admin_roles", userID, map[string]any{"roles": current}, map[string]any{"roles": next})
+	return adminAccess(userID, next), nil
+}
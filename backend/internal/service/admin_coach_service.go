@@ -175,6 +175,7 @@ func (s *adminCoachService) UpdateCoach(ctx context.Context, coachUserID uint, r
 		}
 		return nil, err
 	}
+	before := *profile
 	if req.Status != nil {
 		status := *req.Status
 		if !models.IsValidCoachProfileStatus(status) {
@@ -218,6 +219,7 @@ func (s *adminCoachService) UpdateCoach(ctx context.Context, coachUserID uint, r
 	if err := s.coachRepo.Update(ctx, profile); err != nil {
 		return nil, err
 	}
+	AuditChange(ctx, "coach", coachUserID, before, profile)
 	return s.GetCoachByID(ctx, coachUserID)
 }
 
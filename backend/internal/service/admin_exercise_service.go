@@ -17,6 +17,7 @@ import (
 
 var ErrExerciseNotFound = errors.New("exercise not found")
 var ErrExerciseForbidden = errors.New("exercise not accessible")
+var ErrInvalidContraindication = errors.New("invalid contraindication region")
 
 type AdminExerciseItem struct {
 	ID               uint     `json:"id"`
@@ -32,11 +33,13 @@ type AdminExerciseItem struct {
 	MuscleGroup      string   `json:"muscleGroup"`
 	Target           string   `json:"target"`
 	SecondaryMuscles []string `json:"secondaryMuscles"`
-	ImagePath        string   `json:"imagePath"`
-	GifPath          string   `json:"gifPath"`
-	ImageURL         string   `json:"imageUrl"`
-	GifURL           string   `json:"gifUrl"`
-	IsActive         bool     `json:"isActive"`
+	// Contraindications are injury regions: knee | back | shoulder | wrist | neck | ankle.
+	Contraindications []string `json:"contraindications"`
+	ImagePath         string   `json:"imagePath"`
+	GifPath           string   `json:"gifPath"`
+	ImageURL          string   `json:"imageUrl"`
+	GifURL            string   `json:"gifUrl"`
+	IsActive          bool     `json:"isActive"`
 }
 
 type AdminExerciseListResponse struct {
@@ -58,34 +61,36 @@ type CoachExerciseCreateRequest struct {
 }
 
 type AdminExerciseCreateRequest struct {
-	ExternalID       string   `json:"externalId"`
-	Name             string   `json:"name"`
-	Category         string   `json:"category"`
-	BodyPart         string   `json:"bodyPart"`
-	Equipment        string   `json:"equipment"`
-	Description      string   `json:"description"`
-	InstructionSteps []string `json:"instructionSteps"`
-	MuscleGroup      string   `json:"muscleGroup"`
-	Target           string   `json:"target"`
-	SecondaryMuscles []string `json:"secondaryMuscles"`
-	ImagePath        string   `json:"imagePath"`
-	GifPath          string   `json:"gifPath"`
-	IsActive         *bool    `json:"isActive"`
+	ExternalID        string   `json:"externalId"`
+	Name              string   `json:"name"`
+	Category          string   `json:"category"`
+	BodyPart          string   `json:"bodyPart"`
+	Equipment         string   `json:"equipment"`
+	Description       string   `json:"description"`
+	InstructionSteps  []string `json:"instructionSteps"`
+	MuscleGroup       string   `json:"muscleGroup"`
+	Target            string   `json:"target"`
+	SecondaryMuscles  []string `json:"secondaryMuscles"`
+	Contraindications []string `json:"contraindications"`
+	ImagePath         string   `json:"imagePath"`
+	GifPath           string   `json:"gifPath"`
+	IsActive          *bool    `json:"isActive"`
 }
 
 type AdminExerciseUpdateRequest struct {
-	Name             *string  `json:"name"`
-	Category         *string  `json:"category"`
-	BodyPart         *string  `json:"bodyPart"`
-	Equipment        *string  `json:"equipment"`
-	Description      *string  `json:"description"`
-	InstructionSteps []string `json:"instructionSteps"`
-	MuscleGroup      *string  `json:"muscleGroup"`
-	Target           *string  `json:"target"`
-	SecondaryMuscles []string `json:"secondaryMuscles"`
-	ImagePath        *string  `json:"imagePath"`
-	GifPath          *string  `json:"gifPath"`
-	IsActive         *bool    `json:"isActive"`
+	Name              *string  `json:"name"`
+	Category          *string  `json:"category"`
+	BodyPart          *string  `json:"bodyPart"`
+	Equipment         *string  `json:"equipment"`
+	Description       *string  `json:"description"`
+	InstructionSteps  []string `json:"instructionSteps"`
+	MuscleGroup       *string  `json:"muscleGroup"`
+	Target            *string  `json:"target"`
+	SecondaryMuscles  []string `json:"secondaryMuscles"`
+	Contraindications []string `json:"contraindications"`
+	ImagePath         *string  `json:"imagePath"`
+	GifPath           *string  `json:"gifPath"`
+	IsActive          *bool    `json:"isActive"`
 }
 
 type AdminExerciseService interface {
@@ -133,25 +138,49 @@ func encodeStringSlice(items []string) string {
 
 func exerciseToItem(e *models.Exercise) AdminExerciseItem {
 	return AdminExerciseItem{
-		ID:               e.ID,
-		ExternalID:       e.ExternalID,
-		CoachID:          e.CoachID,
-		IsCustom:         e.CoachID != nil,
-		Name:             e.Name,
-		Category:         e.Category,
-		BodyPart:         e.BodyPart,
-		Equipment:        e.Equipment,
-		Description:      e.Description,
-		InstructionSteps: decodeStringSlice(e.InstructionSteps),
-		MuscleGroup:      e.MuscleGroup,
-		Target:           e.Target,
-		SecondaryMuscles: decodeStringSlice(e.SecondaryMuscles),
-		ImagePath:        e.ImagePath,
-		GifPath:          e.GifPath,
-		ImageURL:         exerciseMediaURL(e.ImagePath),
-		GifURL:           exerciseMediaURL(e.GifPath),
-		IsActive:         e.IsActive,
+		ID:                e.ID,
+		ExternalID:        e.ExternalID,
+		CoachID:           e.CoachID,
+		IsCustom:          e.CoachID != nil,
+		Name:              e.Name,
+		Category:          e.Category,
+		BodyPart:          e.BodyPart,
+		Equipment:         e.Equipment,
+		Description:       e.Description,
+		InstructionSteps:  decodeStringSlice(e.InstructionSteps),
+		MuscleGroup:       e.MuscleGroup,
+		Target:            e.Target,
+		SecondaryMuscles:  decodeStringSlice(e.SecondaryMuscles),
+		Contraindications: decodeStringSlice(e.Contraindications),
+		ImagePath:         e.ImagePath,
+		GifPath:           e.GifPath,
+		ImageURL:          exerciseMediaURL(e.ImagePath),
+		GifURL:            exerciseMediaURL(e.GifPath),
+		IsActive:          e.IsActive,
+	}
+}
+
+// normalizeContraindications validates regions against the injury regions used for
+// matching student injuries and returns them deduplicated in canonical order.
+func normalizeContraindications(regions []string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, r := range regions {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if _, ok := injuryRegionLabels[r]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidContraindication, r)
+		}
+		seen[r] = true
+	}
+	out := []string{}
+	for _, r := range injuryRegionOrder {
+		if seen[r] {
+			out = append(out, r)
+		}
 	}
+	return out, nil
 }
 
 func coachExerciseFilter(coachID uint, source string) repository.ExerciseListFilter {
@@ -353,21 +382,26 @@ func (s *adminExerciseService) CreateExercise(ctx context.Context, req *AdminExe
 	if req.IsActive != nil {
 		isActive = *req.IsActive
 	}
+	contraindications, err := normalizeContraindications(req.Contraindications)
+	if err != nil {
+		return nil, err
+	}
 
 	e := &models.Exercise{
-		ExternalID:       externalID,
-		Name:             name,
-		Category:         strings.TrimSpace(req.Category),
-		BodyPart:         strings.TrimSpace(req.BodyPart),
-		Equipment:        strings.TrimSpace(req.Equipment),
-		Description:      strings.TrimSpace(req.Description),
-		InstructionSteps: encodeStringSlice(req.InstructionSteps),
-		MuscleGroup:      strings.TrimSpace(req.MuscleGroup),
-		Target:           strings.TrimSpace(req.Target),
-		SecondaryMuscles: encodeStringSlice(req.SecondaryMuscles),
-		ImagePath:        strings.TrimSpace(req.ImagePath),
-		GifPath:          strings.TrimSpace(req.GifPath),
-		IsActive:         isActive,
+		ExternalID:        externalID,
+		Name:              name,
+		Category:          strings.TrimSpace(req.Category),
+		BodyPart:          strings.TrimSpace(req.BodyPart),
+		Equipment:         strings.TrimSpace(req.Equipment),
+		Description:       strings.TrimSpace(req.Description),
+		InstructionSteps:  encodeStringSlice(req.InstructionSteps),
+		MuscleGroup:       strings.TrimSpace(req.MuscleGroup),
+		Target:            strings.TrimSpace(req.Target),
+		SecondaryMuscles:  encodeStringSlice(req.SecondaryMuscles),
+		Contraindications: encodeStringSlice(contraindications),
+		ImagePath:         strings.TrimSpace(req.ImagePath),
+		GifPath:           strings.TrimSpace(req.GifPath),
+		IsActive:          isActive,
 	}
 
 	if err := s.repo.Create(ctx, e); err != nil {
@@ -417,6 +451,13 @@ func (s *adminExerciseService) UpdateExercise(ctx context.Context, id uint, req
 	if req.SecondaryMuscles != nil {
 		e.SecondaryMuscles = encodeStringSlice(req.SecondaryMuscles)
 	}
+	if req.Contraindications != nil {
+		contraindications, err := normalizeContraindications(req.Contraindications)
+		if err != nil {
+			return nil, err
+		}
+		e.Contraindications = encodeStringSlice(contraindications)
+	}
 	if req.ImagePath != nil {
 		e.ImagePath = strings.TrimSpace(*req.ImagePath)
 	}
@@ -435,12 +476,16 @@ func (s *adminExerciseService) UpdateExercise(ctx context.Context, id uint, req
 }
 
 func (s *adminExerciseService) DeleteExercise(ctx context.Context, id uint) error {
-	_, err := s.repo.FindByID(ctx, id)
+	e, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return ErrExerciseNotFound
 		}
 		return err
 	}
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	AuditChange(ctx, "exercise", id, e, nil)
+	return nil
 }
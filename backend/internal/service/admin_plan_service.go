@@ -21,6 +21,9 @@ type AdminPlanItem struct {
 	DiscountPercent int       `json:"discountPercent"`
 	DurationDays    int       `json:"durationDays"`
 	IsPopular       bool      `json:"isPopular"`
+	// AI assistant quotas in messages: 0 = platform default, -1 = unlimited.
+	AIDailyMessages   int       `json:"aiDailyMessages"`
+	AIMonthlyMessages int       `json:"aiMonthlyMessages"`
 	CoachID         uint      `json:"coachId"`
 	CoachName       string    `json:"coachName"`
 	CreatedAt       time.Time `json:"createdAt"`
@@ -41,6 +44,9 @@ type AdminPlanDetail struct {
 	DiscountPercent int       `json:"discountPercent"`
 	DurationDays    int       `json:"durationDays"`
 	IsPopular       bool      `json:"isPopular"`
+	// AI assistant quotas in messages: 0 = platform default, -1 = unlimited.
+	AIDailyMessages   int       `json:"aiDailyMessages"`
+	AIMonthlyMessages int       `json:"aiMonthlyMessages"`
 	CoachID         uint      `json:"coachId"`
 	CoachName       string    `json:"coachName"`
 	CreatedAt       time.Time `json:"createdAt"`
@@ -68,6 +74,9 @@ type AdminPlanCreateRequest struct {
 	DiscountPercent int    `json:"discountPercent"`
 	DurationDays    int    `json:"durationDays"`
 	IsPopular       bool   `json:"isPopular"`
+	// AI assistant quotas in messages: 0 = platform default, -1 = unlimited.
+	AIDailyMessages   int `json:"aiDailyMessages"`
+	AIMonthlyMessages int `json:"aiMonthlyMessages"`
 }
 
 // AdminPlanUpdateRequest for PATCH /admin/plans/:id (partial; same fields as create).
@@ -83,6 +92,8 @@ type AdminPlanUpdateRequest struct {
 	DiscountPercent *int    `json:"discountPercent"`
 	DurationDays    *int    `json:"durationDays"`
 	IsPopular       *bool   `json:"isPopular"`
+	AIDailyMessages   *int `json:"aiDailyMessages"`
+	AIMonthlyMessages *int `json:"aiMonthlyMessages"`
 }
 
 type AdminPlanService interface {
@@ -115,6 +126,8 @@ func planToItem(p *models.ServicePlan) AdminPlanItem {
 		DiscountPercent: p.DiscountPercent,
 		DurationDays:    p.DurationDays,
 		IsPopular:       p.IsPopular,
+		AIDailyMessages:   p.AIDailyMessages,
+		AIMonthlyMessages: p.AIMonthlyMessages,
 		CoachID:         p.CoachID,
 		CreatedAt:       p.CreatedAt,
 		UpdatedAt:       p.UpdatedAt,
@@ -135,6 +148,8 @@ func planToDetail(p *models.ServicePlan) AdminPlanDetail {
 		DiscountPercent: p.DiscountPercent,
 		DurationDays:    p.DurationDays,
 		IsPopular:       p.IsPopular,
+		AIDailyMessages:   p.AIDailyMessages,
+		AIMonthlyMessages: p.AIMonthlyMessages,
 		CreatedAt:       p.CreatedAt,
 		UpdatedAt:       p.UpdatedAt,
 	}
@@ -199,6 +214,8 @@ func (s *adminPlanService) CreatePlan(ctx context.Context, req *AdminPlanCreateR
 		DurationDays:       req.DurationDays,
 		IsPopular:          req.IsPopular,
 		IsActive:           true,
+		AIDailyMessages:    clampAIQuota(req.AIDailyMessages),
+		AIMonthlyMessages:  clampAIQuota(req.AIMonthlyMessages),
 	}
 	if plan.DurationDays <= 0 {
 		plan.DurationDays = 30
@@ -248,6 +265,12 @@ func (s *adminPlanService) UpdatePlan(ctx context.Context, id uint, req *AdminPl
 	if req.IsPopular != nil {
 		p.IsPopular = *req.IsPopular
 	}
+	if req.AIDailyMessages != nil {
+		p.AIDailyMessages = clampAIQuota(*req.AIDailyMessages)
+	}
+	if req.AIMonthlyMessages != nil {
+		p.AIMonthlyMessages = clampAIQuota(*req.AIMonthlyMessages)
+	}
 	if err := s.planRepo.Update(ctx, p); err != nil {
 		return nil, err
 	}
@@ -255,6 +278,14 @@ func (s *adminPlanService) UpdatePlan(ctx context.Context, id uint, req *AdminPl
 	return &detail, nil
 }
 
+// clampAIQuota maps any negative quota to -1 (unlimited).
+func clampAIQuota(v int) int {
+	if v < 0 {
+		return AIQuotaUnlimited
+	}
+	return v
+}
+
 func (s *adminPlanService) DeletePlan(ctx context.Context, id uint) error {
 	return s.planRepo.Delete(ctx, id)
 }
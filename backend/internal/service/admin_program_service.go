@@ -110,9 +110,9 @@ func (s *adminProgramService) AssignNutritionFromTemplate(ctx context.Context, s
 }
 
 func (s *adminProgramService) ListWorkoutTemplates(ctx context.Context) (*TemplateListResponse, error) {
-	return s.coachProgramSvc.ListWorkoutTemplates(ctx, 0, 0, "")
+	return s.coachProgramSvc.ListWorkoutTemplates(ctx, 0, 0, 0, "", "platform")
 }
 
 func (s *adminProgramService) ListNutritionTemplates(ctx context.Context) (*TemplateListResponse, error) {
-	return s.coachProgramSvc.ListNutritionTemplates(ctx, 0, 0, "")
+	return s.coachProgramSvc.ListNutritionTemplates(ctx, 0, 0, 0, "", "platform")
 }
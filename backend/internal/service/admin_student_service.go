@@ -215,13 +215,16 @@ func (s *adminStudentService) UpdateStudent(ctx context.Context, id uint, req *A
 	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return err
 	}
+	before, after := map[string]any{}, map[string]any{}
 
 	if req.Status != nil {
 		statusVal := strings.TrimSpace(*req.Status)
 		if statusVal == "pending" || statusVal == "active" {
+			before["coachStatus"] = user.CoachStatus
 			if err := s.db.WithContext(ctx).Model(&user).Update("coach_status", statusVal).Error; err != nil {
 				return err
 			}
+			after["coachStatus"] = statusVal
 		}
 	}
 
@@ -246,6 +249,8 @@ func (s *adminStudentService) UpdateStudent(ctx context.Context, id uint, req *A
 		if err := s.db.WithContext(ctx).Create(&sub).Error; err != nil {
 			return err
 		}
+		after["subscription"] = sub
 	}
+	AuditChange(ctx, "student", id, before, after)
 	return nil
 }
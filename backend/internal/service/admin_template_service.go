@@ -35,6 +35,8 @@ type AdminTemplateItemDTO struct {
 
 type AdminWorkoutTemplateSummary struct {
 	ID       uint   `json:"id"`
+	CoachID  *uint  `json:"coachId,omitempty"`
+	IsCustom bool   `json:"isCustom"`
 	Title    string `json:"title"`
 	Type     string `json:"type"`
 	Gender   string `json:"gender"`
@@ -92,6 +94,8 @@ type AdminNutritionMealDTO struct {
 
 type AdminNutritionTemplateSummary struct {
 	ID         uint   `json:"id"`
+	CoachID    *uint  `json:"coachId,omitempty"`
+	IsCustom   bool   `json:"isCustom"`
 	Title      string `json:"title"`
 	Type       string `json:"type"`
 	Gender     string `json:"gender"`
@@ -156,7 +160,7 @@ func NewAdminTemplateService(
 }
 
 func (s *adminTemplateService) ListWorkoutTemplates(ctx context.Context, page, pageSize int, query string) (*AdminWorkoutTemplateListResponse, error) {
-	list, total, err := s.templateRepo.ListWorkoutTemplatesPaged(ctx, page, pageSize, query)
+	list, total, err := s.templateRepo.ListWorkoutTemplatesPaged(ctx, page, pageSize, repository.TemplateListFilter{Query: query})
 	if err != nil {
 		return nil, err
 	}
@@ -165,11 +169,7 @@ func (s *adminTemplateService) ListWorkoutTemplates(ctx context.Context, page, p
 		var c int64
 		_ = s.db.WithContext(ctx).Model(&models.TemplateProgramItem{}).
 			Where("workout_template_id = ?", t.ID).Count(&c)
-		items = append(items, AdminWorkoutTemplateSummary{
-			ID: t.ID, Title: t.Title, Type: t.Type, Gender: t.Gender,
-			Location: t.Location, DayCount: t.DayCount, Target: t.Target,
-			Level: t.Level, Injury: t.Injury, ItemCount: int(c),
-		})
+		items = append(items, workoutTemplateSummary(&t, int(c)))
 	}
 	if page <= 0 {
 		page = 1
@@ -192,11 +192,38 @@ func (s *adminTemplateService) GetWorkoutTemplate(ctx context.Context, id uint)
 }
 
 func (s *adminTemplateService) CreateWorkoutTemplate(ctx context.Context, req *AdminWorkoutTemplateUpsertRequest) (*AdminWorkoutTemplateDetail, error) {
+	t, err := newWorkoutTemplate(ctx, s.templateRepo, nil, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.CreateWorkoutTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return s.GetWorkoutTemplate(ctx, t.ID)
+}
+
+func (s *adminTemplateService) UpdateWorkoutTemplate(ctx context.Context, id uint, req *AdminWorkoutTemplateUpsertRequest) (*AdminWorkoutTemplateDetail, error) {
+	t, err := s.templateRepo.FindWorkoutTemplateByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkoutTemplateNotFound
+		}
+		return nil, err
+	}
+	if err := updateWorkoutTemplate(ctx, s.templateRepo, t, req); err != nil {
+		return nil, err
+	}
+	return s.GetWorkoutTemplate(ctx, id)
+}
+
+// newWorkoutTemplate validates req and builds an unsaved template with a fresh
+// manual source id. coachID is nil for platform templates.
+func newWorkoutTemplate(ctx context.Context, templateRepo repository.TemplateRepository, coachID *uint, req *AdminWorkoutTemplateUpsertRequest) (*models.WorkoutTemplate, error) {
 	title := strings.TrimSpace(req.Title)
 	if title == "" {
 		return nil, errors.New("title is required")
 	}
-	sourceID, err := s.templateRepo.NextManualWorkoutSourceID(ctx)
+	sourceID, err := templateRepo.NextManualWorkoutSourceID(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -207,8 +234,7 @@ func (s *adminTemplateService) CreateWorkoutTemplate(ctx context.Context, req *A
 	if dayCount <= 0 {
 		dayCount = 1
 	}
-	items := s.mapItems(req.Items)
-	t := &models.WorkoutTemplate{
+	return &models.WorkoutTemplate{
 		SourceID: sourceID,
 		Title:    title,
 		Type:     strings.TrimSpace(req.Type),
@@ -218,25 +244,17 @@ func (s *adminTemplateService) CreateWorkoutTemplate(ctx context.Context, req *A
 		Target:   strings.TrimSpace(req.Target),
 		Injury:   strings.TrimSpace(req.Injury),
 		Level:    strings.TrimSpace(req.Level),
-		Items:    items,
-	}
-	if err := s.templateRepo.CreateWorkoutTemplate(ctx, t); err != nil {
-		return nil, err
-	}
-	return s.GetWorkoutTemplate(ctx, t.ID)
+		CoachID:  coachID,
+		Items:    mapWorkoutTemplateItems(req.Items),
+	}, nil
 }
 
-func (s *adminTemplateService) UpdateWorkoutTemplate(ctx context.Context, id uint, req *AdminWorkoutTemplateUpsertRequest) (*AdminWorkoutTemplateDetail, error) {
-	t, err := s.templateRepo.FindWorkoutTemplateByID(ctx, id)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrWorkoutTemplateNotFound
-		}
-		return nil, err
-	}
+// updateWorkoutTemplate applies req to an existing template. Items are only
+// replaced when the request carries them.
+func updateWorkoutTemplate(ctx context.Context, templateRepo repository.TemplateRepository, t *models.WorkoutTemplate, req *AdminWorkoutTemplateUpsertRequest) error {
 	title := strings.TrimSpace(req.Title)
 	if title == "" {
-		return nil, errors.New("title is required")
+		return errors.New("title is required")
 	}
 	dayCount := req.DayCount
 	if dayCount <= 0 {
@@ -253,15 +271,13 @@ func (s *adminTemplateService) UpdateWorkoutTemplate(ctx context.Context, id uin
 	t.Target = strings.TrimSpace(req.Target)
 	t.Injury = strings.TrimSpace(req.Injury)
 	t.Level = strings.TrimSpace(req.Level)
-	if err := s.templateRepo.UpdateWorkoutTemplateMeta(ctx, t); err != nil {
-		return nil, err
+	if err := templateRepo.UpdateWorkoutTemplateMeta(ctx, t); err != nil {
+		return err
 	}
 	if req.Items != nil {
-		if err := s.templateRepo.ReplaceWorkoutTemplateItems(ctx, id, s.mapItems(req.Items)); err != nil {
-			return nil, err
-		}
+		return templateRepo.ReplaceWorkoutTemplateItems(ctx, t.ID, mapWorkoutTemplateItems(req.Items))
 	}
-	return s.GetWorkoutTemplate(ctx, id)
+	return nil
 }
 
 func (s *adminTemplateService) DeleteWorkoutTemplate(ctx context.Context, id uint) error {
@@ -275,7 +291,7 @@ func (s *adminTemplateService) DeleteWorkoutTemplate(ctx context.Context, id uin
 	return s.templateRepo.DeleteWorkoutTemplate(ctx, id)
 }
 
-func (s *adminTemplateService) mapItems(in []AdminTemplateItemDTO) []models.TemplateProgramItem {
+func mapWorkoutTemplateItems(in []AdminTemplateItemDTO) []models.TemplateProgramItem {
 	out := make([]models.TemplateProgramItem, 0, len(in))
 	for i, it := range in {
 		name := strings.TrimSpace(it.Exercise)
@@ -374,12 +390,17 @@ func workoutTemplateToDetail(ctx context.Context, exerciseRepo repository.Exerci
 		items = append(items, dto)
 	}
 	return &AdminWorkoutTemplateDetail{
-		AdminWorkoutTemplateSummary: AdminWorkoutTemplateSummary{
-			ID: t.ID, Title: t.Title, Type: t.Type, Gender: t.Gender,
-			Location: t.Location, DayCount: t.DayCount, Target: t.Target,
-			Level: t.Level, Injury: t.Injury, ItemCount: len(items),
-		},
-		Items: items,
+		AdminWorkoutTemplateSummary: workoutTemplateSummary(t, len(items)),
+		Items:                       items,
+	}
+}
+
+func workoutTemplateSummary(t *models.WorkoutTemplate, itemCount int) AdminWorkoutTemplateSummary {
+	return AdminWorkoutTemplateSummary{
+		ID: t.ID, CoachID: t.CoachID, IsCustom: t.CoachID != nil,
+		Title: t.Title, Type: t.Type, Gender: t.Gender,
+		Location: t.Location, DayCount: t.DayCount, Target: t.Target,
+		Level: t.Level, Injury: t.Injury, ItemCount: itemCount,
 	}
 }
 
@@ -394,7 +415,7 @@ func maxDayFromItems(items []AdminTemplateItemDTO) int {
 }
 
 func (s *adminTemplateService) ListNutritionTemplates(ctx context.Context, page, pageSize int, query string) (*AdminNutritionTemplateListResponse, error) {
-	list, total, err := s.templateRepo.ListNutritionTemplatesPaged(ctx, page, pageSize, query)
+	list, total, err := s.templateRepo.ListNutritionTemplatesPaged(ctx, page, pageSize, repository.TemplateListFilter{Query: query})
 	if err != nil {
 		return nil, err
 	}
@@ -403,11 +424,7 @@ func (s *adminTemplateService) ListNutritionTemplates(ctx context.Context, page,
 		var c int64
 		_ = s.db.WithContext(ctx).Model(&models.TemplateMeal{}).
 			Where("nutrition_template_id = ?", t.ID).Count(&c)
-		items = append(items, AdminNutritionTemplateSummary{
-			ID: t.ID, Title: t.Title, Type: t.Type, Gender: t.Gender,
-			Target: t.Target, Limitation: t.Limitation, Calorie: t.Calorie,
-			IsPro: t.IsPro, MealCount: int(c),
-		})
+		items = append(items, nutritionTemplateSummary(&t, int(c)))
 	}
 	if page <= 0 {
 		page = 1
@@ -430,15 +447,42 @@ func (s *adminTemplateService) GetNutritionTemplate(ctx context.Context, id uint
 }
 
 func (s *adminTemplateService) CreateNutritionTemplate(ctx context.Context, req *AdminNutritionTemplateUpsertRequest) (*AdminNutritionTemplateDetail, error) {
+	t, err := newNutritionTemplate(ctx, s.templateRepo, nil, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.CreateNutritionTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return s.GetNutritionTemplate(ctx, t.ID)
+}
+
+func (s *adminTemplateService) UpdateNutritionTemplate(ctx context.Context, id uint, req *AdminNutritionTemplateUpsertRequest) (*AdminNutritionTemplateDetail, error) {
+	t, err := s.templateRepo.FindNutritionTemplateByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNutritionTemplateNotFound
+		}
+		return nil, err
+	}
+	if err := updateNutritionTemplate(ctx, s.templateRepo, t, req); err != nil {
+		return nil, err
+	}
+	return s.GetNutritionTemplate(ctx, id)
+}
+
+// newNutritionTemplate validates req and builds an unsaved template with a fresh
+// manual source id. coachID is nil for platform templates.
+func newNutritionTemplate(ctx context.Context, templateRepo repository.TemplateRepository, coachID *uint, req *AdminNutritionTemplateUpsertRequest) (*models.NutritionTemplate, error) {
 	title := strings.TrimSpace(req.Title)
 	if title == "" {
 		return nil, errors.New("title is required")
 	}
-	sourceID, err := s.templateRepo.NextManualNutritionSourceID(ctx)
+	sourceID, err := templateRepo.NextManualNutritionSourceID(ctx)
 	if err != nil {
 		return nil, err
 	}
-	t := &models.NutritionTemplate{
+	return &models.NutritionTemplate{
 		SourceID:    sourceID,
 		Title:       title,
 		Type:        strings.TrimSpace(req.Type),
@@ -449,25 +493,17 @@ func (s *adminTemplateService) CreateNutritionTemplate(ctx context.Context, req
 		Description: strings.TrimSpace(req.Description),
 		IsPro:       req.IsPro,
 		Version:     1,
+		CoachID:     coachID,
 		Meals:       mapNutritionMeals(req.Meals),
-	}
-	if err := s.templateRepo.CreateNutritionTemplate(ctx, t); err != nil {
-		return nil, err
-	}
-	return s.GetNutritionTemplate(ctx, t.ID)
+	}, nil
 }
 
-func (s *adminTemplateService) UpdateNutritionTemplate(ctx context.Context, id uint, req *AdminNutritionTemplateUpsertRequest) (*AdminNutritionTemplateDetail, error) {
-	t, err := s.templateRepo.FindNutritionTemplateByID(ctx, id)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrNutritionTemplateNotFound
-		}
-		return nil, err
-	}
+// updateNutritionTemplate applies req to an existing template. Meals are only
+// replaced when the request carries them.
+func updateNutritionTemplate(ctx context.Context, templateRepo repository.TemplateRepository, t *models.NutritionTemplate, req *AdminNutritionTemplateUpsertRequest) error {
 	title := strings.TrimSpace(req.Title)
 	if title == "" {
-		return nil, errors.New("title is required")
+		return errors.New("title is required")
 	}
 	t.Title = title
 	t.Type = strings.TrimSpace(req.Type)
@@ -477,15 +513,13 @@ func (s *adminTemplateService) UpdateNutritionTemplate(ctx context.Context, id u
 	t.Calorie = req.Calorie
 	t.Description = strings.TrimSpace(req.Description)
 	t.IsPro = req.IsPro
-	if err := s.templateRepo.UpdateNutritionTemplateMeta(ctx, t); err != nil {
-		return nil, err
+	if err := templateRepo.UpdateNutritionTemplateMeta(ctx, t); err != nil {
+		return err
 	}
 	if req.Meals != nil {
-		if err := s.templateRepo.ReplaceNutritionTemplateMeals(ctx, id, mapNutritionMeals(req.Meals)); err != nil {
-			return nil, err
-		}
+		return templateRepo.ReplaceNutritionTemplateMeals(ctx, t.ID, mapNutritionMeals(req.Meals))
 	}
-	return s.GetNutritionTemplate(ctx, id)
+	return nil
 }
 
 func (s *adminTemplateService) DeleteNutritionTemplate(ctx context.Context, id uint) error {
@@ -573,12 +607,17 @@ func nutritionTemplateToDetail(t *models.NutritionTemplate) *AdminNutritionTempl
 		meals = append(meals, dto)
 	}
 	return &AdminNutritionTemplateDetail{
-		AdminNutritionTemplateSummary: AdminNutritionTemplateSummary{
-			ID: t.ID, Title: t.Title, Type: t.Type, Gender: t.Gender,
-			Target: t.Target, Limitation: t.Limitation, Calorie: t.Calorie,
-			IsPro: t.IsPro, MealCount: len(meals),
-		},
-		Description: t.Description,
-		Meals:       meals,
+		AdminNutritionTemplateSummary: nutritionTemplateSummary(t, len(meals)),
+		Description:                   t.Description,
+		Meals:                         meals,
+	}
+}
+
+func nutritionTemplateSummary(t *models.NutritionTemplate, mealCount int) AdminNutritionTemplateSummary {
+	return AdminNutritionTemplateSummary{
+		ID: t.ID, CoachID: t.CoachID, IsCustom: t.CoachID != nil,
+		Title: t.Title, Type: t.Type, Gender: t.Gender,
+		Target: t.Target, Limitation: t.Limitation, Calorie: t.Calorie,
+		IsPro: t.IsPro, MealCount: mealCount,
 	}
 }
@@ -24,6 +24,7 @@ type AdminUserSummary struct {
 	ActiveProgram bool      `json:"activeProgram"`
 	ProgramsCount int64     `json:"programsCount"`
 	OrdersCount   int64     `json:"ordersCount"`
+	AccountStatus string    `json:"accountStatus"` // active | suspended | banned
 	CreatedAt     time.Time `json:"createdAt"`
 }
 
@@ -159,6 +160,7 @@ func (s *adminUserService) ListUsers(ctx context.Context, page, pageSize int, qu
 			ActiveProgram: active,
 			ProgramsCount: progCount,
 			OrdersCount:   ordersCount,
+			AccountStatus: u.EffectiveStatus(now),
 			CreatedAt:     u.CreatedAt,
 		}
 		summary.FirstName, summary.LastName = splitName(u.Name)
@@ -196,6 +198,7 @@ func (s *adminUserService) GetUserDetails(ctx context.Context, id uint) (*AdminU
 		ActiveProgram: active,
 		ProgramsCount: progCount,
 		OrdersCount:   ordersCount,
+		AccountStatus: user.EffectiveStatus(now),
 		CreatedAt:     user.CreatedAt,
 	}
 
@@ -467,6 +470,7 @@ func (s *adminUserService) AddUserBodyPhoto(ctx context.Context, userID uint, fi
 		_ = os.Remove(fullPath)
 		return nil, err
 	}
+	AuditChange(ctx, "user_photo", photo.ID, nil, photo)
 
 	return &AdminUserPhoto{
 		ID:   photo.ID,
@@ -493,6 +497,7 @@ func (s *adminUserService) DeleteUserBodyPhoto(ctx context.Context, userID uint,
 	if err := s.db.WithContext(ctx).Delete(&photo).Error; err != nil {
 		return err
 	}
+	AuditChange(ctx, "user_photo", photo.ID, photo, nil)
 	return nil
 }
 
@@ -1,19 +1,20 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
 	"strings"
-	"sync"
 	"time"
 	"unicode/utf8"
 
+	"gorm.io/gorm"
+
 	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
 )
 
 var (
@@ -21,13 +22,21 @@ var (
 	ErrAIRateLimited   = errors.New("ai rate limited")
 	ErrAIInvalidInput  = errors.New("invalid ai input")
 	ErrAIUpstream      = errors.New("openai upstream error")
+
+	ErrAIConversationNotFound = errors.New("ai conversation not found")
+	ErrAIMessageNotFound      = errors.New("ai message not found")
+	ErrAIInvalidFeedback      = errors.New("invalid ai feedback")
 )
 
 const (
-	aiMaxMessageRunes   = 1200
-	aiMaxHistoryMessages = 12
-	aiRateWindow         = time.Minute
-	aiRateMaxPerWindow   = 20
+	aiMaxMessageRunes = 1200
+
+	// History sent to the model is the newest stored turns that fit the token
+	// budget; aiHistoryFetchLimit only bounds the query.
+	aiHistoryTokenBudget     = 1500
+	aiHistoryFetchLimit      = 40
+	aiMessageTokenOverhead   = 4
+	aiConversationTitleRunes = 60
 )
 
 // Fixed guardrail replies — never invent programs / PEDs.
@@ -38,43 +47,168 @@ const (
 	aiDevMockMsg         = "این پاسخ تستی محیط توسعه است (کلید هوش مصنوعی تنظیم نشده). در پروداکشن با OPENAI_API_KEY پاسخ واقعی از مدل دریافت می‌شود. بپرس درباره ورود، پنل شاگرد، مربی یا پرداخت — من فقط راهنمای امکانات فیتینو هستم."
 )
 
-func aiDevMockReply(userMessage string) string {
-	_ = userMessage
-	return aiDevMockMsg
-}
-
 type AIChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
 }
 
+// AIChatRequest starts a new conversation, or continues ConversationID. History is
+// rebuilt from stored messages; clients no longer send it.
 type AIChatRequest struct {
-	Message  string          `json:"message" binding:"required"`
-	History  []AIChatMessage `json:"history"`
-	PagePath string          `json:"pagePath"`
+	Message        string `json:"message" binding:"required"`
+	ConversationID *uint  `json:"conversationId"`
+	PagePath       string `json:"pagePath"`
 }
 
 type AIChatResponse struct {
-	Reply string `json:"reply"`
+	Reply          string `json:"reply"`
+	ConversationID uint   `json:"conversationId"`
+	MessageID      uint   `json:"messageId"`
+	// Sources are the FAQ/academy/help documents the reply cited.
+	Sources []AISource `json:"sources"`
+	// Replaced is set when a guardrail swapped out streamed text: clients must show
+	// Reply instead of what they accumulated.
+	Replaced bool `json:"replaced,omitempty"`
+}
+
+type AIConversationDTO struct {
+	ID            uint      `json:"id"`
+	Title         string    `json:"title"`
+	PagePath      string    `json:"pagePath,omitempty"`
+	MessageCount  int       `json:"messageCount"`
+	LastMessageAt time.Time `json:"lastMessageAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type AIConversationListResponse struct {
+	Items    []AIConversationDTO `json:"items"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"pageSize"`
+	Total    int64               `json:"total"`
+}
+
+type AIMessageDTO struct {
+	ID           uint       `json:"id"`
+	Role         string     `json:"role"`
+	Content      string     `json:"content"`
+	Sources      []AISource `json:"sources,omitempty"`
+	Rating       string     `json:"rating,omitempty"` // up | down
+	FeedbackNote string     `json:"feedbackNote,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+type AIConversationDetailDTO struct {
+	Conversation AIConversationDTO `json:"conversation"`
+	Messages     []AIMessageDTO    `json:"messages"`
+}
+
+// AIMessageFeedbackRequest rates an assistant reply; rating "none" clears it.
+type AIMessageFeedbackRequest struct {
+	Rating string `json:"rating" binding:"required"` // up | down | none
+	Note   string `json:"note"`
+}
+
+// AIFlaggedReplyDTO is one row of the admin moderation view.
+type AIFlaggedReplyDTO struct {
+	MessageID      uint      `json:"messageId"`
+	ConversationID uint      `json:"conversationId"`
+	UserID         uint      `json:"userId"`
+	UserName       string    `json:"userName"`
+	Prompt         string    `json:"prompt"`
+	Reply          string    `json:"reply"`
+	OriginalReply  string    `json:"originalReply,omitempty"`
+	FlagReason     string    `json:"flagReason"`
+	Rating         string    `json:"rating,omitempty"`
+	FeedbackNote   string    `json:"feedbackNote,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type AIFlaggedReplyListResponse struct {
+	Items    []AIFlaggedReplyDTO `json:"items"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"pageSize"`
+	Total    int64               `json:"total"`
+}
+
+// AIToolInvocationDTO is one row of the admin tool-call audit view.
+type AIToolInvocationDTO struct {
+	ID             uint      `json:"id"`
+	UserID         uint      `json:"userId"`
+	UserName       string    `json:"userName"`
+	ConversationID *uint     `json:"conversationId,omitempty"`
+	MessageID      *uint     `json:"messageId,omitempty"`
+	Tool           string    `json:"tool"`
+	Arguments      string    `json:"arguments"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	ResultBytes    int       `json:"resultBytes"`
+	DurationMs     int64     `json:"durationMs"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type AIToolInvocationListResponse struct {
+	Items    []AIToolInvocationDTO `json:"items"`
+	Page     int                   `json:"page"`
+	PageSize int                   `json:"pageSize"`
+	Total    int64                 `json:"total"`
 }
 
 type AIChatService struct {
 	meService MeService
-	client    *http.Client
-
-	mu    sync.Mutex
-	rates map[uint][]time.Time
+	convRepo  repository.AIConversationRepository
+	userRepo  repository.UserRepository
+	knowledge *AIKnowledgeBase
+	tools     *AIToolbox     // nil disables tool calling
+	usage     AIUsageService // nil disables rate limits, quotas and accounting
+	// provider is nil when the configured backend is unusable (providerErr says why).
+	provider    LLMProvider
+	providerErr error
 }
 
-func NewAIChatService(meService MeService) *AIChatService {
+func NewAIChatService(meService MeService, convRepo repository.AIConversationRepository, userRepo repository.UserRepository, knowledge *AIKnowledgeBase, tools *AIToolbox, usage AIUsageService) *AIChatService {
+	provider, err := NewLLMProviderFromConfig(config.Get())
+	if err != nil {
+		log.Printf("ai chat: %v", err)
+	}
 	return &AIChatService{
-		meService: meService,
-		client:    &http.Client{Timeout: 45 * time.Second},
-		rates:     make(map[uint][]time.Time),
+		meService:   meService,
+		convRepo:    convRepo,
+		userRepo:    userRepo,
+		knowledge:   knowledge,
+		tools:       tools,
+		usage:       usage,
+		provider:    provider,
+		providerErr: err,
 	}
 }
 
+// aiReply is what Chat decided to answer, before it is stored.
+type aiReply struct {
+	content    string
+	flagReason string
+	original   string // model output replaced by a guardrail
+	sources    []AISource
+	// toolInvocations are the audit rows of tool calls made for this reply.
+	toolInvocations []uint
+	usage           aiTurnUsage
+}
+
 func (s *AIChatService) Chat(ctx context.Context, userID uint, req *AIChatRequest) (*AIChatResponse, error) {
+	return s.chat(ctx, userID, req, nil)
+}
+
+// ChatStream is Chat with the model reply delivered through onDelta as it is
+// generated. Guardrails still apply: generation stops as soon as the streamed text
+// trips one, and the returned response carries the replacement with Replaced set.
+// Canned replies that never reach the model are delivered as a single delta.
+func (s *AIChatService) ChatStream(ctx context.Context, userID uint, req *AIChatRequest, onDelta func(string) error) (*AIChatResponse, error) {
+	if onDelta == nil {
+		return nil, ErrAIInvalidInput
+	}
+	return s.chat(ctx, userID, req, onDelta)
+}
+
+func (s *AIChatService) chat(ctx context.Context, userID uint, req *AIChatRequest, onDelta func(string) error) (*AIChatResponse, error) {
 	if req == nil {
 		return nil, ErrAIInvalidInput
 	}
@@ -84,110 +218,493 @@ func (s *AIChatService) Chat(ctx context.Context, userID uint, req *AIChatReques
 		return nil, ErrAIInvalidInput
 	}
 
-	if !s.allow(userID) {
-		return nil, ErrAIRateLimited
+	conv, err := s.conversationFor(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var qc *aiQuotaContext
+	answered := false
+	if s.usage != nil {
+		if qc, err = s.usage.admit(ctx, userID); err != nil {
+			return nil, err
+		}
+		// Give the reserved turn back if it fails before an answer is stored.
+		defer func() {
+			if !answered {
+				s.usage.release(ctx, qc)
+			}
+		}()
 	}
 
+	reply, streamed, err := s.reply(ctx, userID, conv, message, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	if onDelta != nil && !streamed {
+		if err := onDelta(reply.content); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := s.record(ctx, userID, conv, message, reply)
+	if err != nil {
+		return nil, err
+	}
+	answered = true
+	if s.usage != nil {
+		s.usage.record(ctx, userID, qc, resp.ConversationID, resp.MessageID, reply.usage)
+	}
+	resp.Replaced = streamed && reply.original != ""
+	return resp, nil
+}
+
+// reply decides the answer to message. streamed reports whether model output
+// already went out through onDelta.
+func (s *AIChatService) reply(ctx context.Context, userID uint, conv *models.AIConversation, message string, onDelta func(string) error) (reply *aiReply, streamed bool, err error) {
 	// Hard guardrails before calling the model.
 	if hitsSteroidTopic(message) {
-		return &AIChatResponse{Reply: aiSteroidRefuseMsg}, nil
+		return &aiReply{content: aiSteroidRefuseMsg, flagReason: models.AIFlagSteroidPrompt}, false, nil
 	}
 	if hitsProgramOrDietTopic(message) {
-		return &AIChatResponse{Reply: aiProgramRedirectMsg}, nil
+		return &aiReply{content: aiProgramRedirectMsg}, false, nil
 	}
 
-	cfg := config.Get()
-	if strings.TrimSpace(cfg.OpenAI.APIKey) == "" {
-		if config.IsDevelopment() {
-			return &AIChatResponse{Reply: aiDevMockReply(message)}, nil
-		}
-		return nil, ErrAINotConfigured
+	if s.provider == nil {
+		return nil, false, ErrAINotConfigured
 	}
 
 	profile, err := s.meService.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var docs []aiRetrievedDoc
+	if s.knowledge != nil {
+		docs = s.knowledge.Search(ctx, message, aiRetrievalTopK)
+	}
+	system := buildFitinoSystemPrompt(profile, conv.PagePath) + buildAISourcesPrompt(docs)
+	messages := []LLMMessage{
+		{Role: "system", Content: system},
+	}
+	history, err := s.history(ctx, conv)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, m := range history {
+		messages = append(messages, LLMMessage{Role: m.Role, Content: m.Content})
+	}
+	messages = append(messages, LLMMessage{Role: "user", Content: message})
+
+	// Stop generating (and forwarding) as soon as the text so far trips a
+	// guardrail; the post-filter below then replaces it.
+	var sofar strings.Builder
+	stopped := false
+	forward := func(delta string) error {
+		sofar.WriteString(delta)
+		if t := sofar.String(); hitsSteroidTopic(t) || looksLikeProgramPrescription(t) {
+			stopped = true
+			return ErrLLMStreamStopped
+		}
+		streamed = true
+		return onDelta(delta)
+	}
+
+	// Let the model look up the user's own data through the toolbox, then answer.
+	// Text produced alongside tool calls is kept so the stored reply matches what
+	// was streamed.
+	llmReq := LLMRequest{Messages: messages}
+	var text strings.Builder
+	var toolIDs []uint
+	usage := aiTurnUsage{provider: s.provider.Name(), model: config.Get().OpenAI.Model}
+	for round := 0; ; round++ {
+		llmReq.Tools = nil
+		if s.tools != nil && round < aiMaxToolRounds {
+			llmReq.Tools = s.tools.Definitions()
+		}
+		var completion *LLMResponse
+		if onDelta == nil {
+			completion, err = s.provider.Complete(ctx, llmReq)
+		} else {
+			completion, err = s.provider.Stream(ctx, llmReq, forward)
+		}
+		if err != nil {
+			return nil, streamed, err
+		}
+		usage.add(llmReq, completion)
+		text.WriteString(completion.Content)
+		if stopped || len(completion.ToolCalls) == 0 || llmReq.Tools == nil {
+			break
+		}
+		llmReq.Messages = append(llmReq.Messages, LLMMessage{Role: "assistant", Content: completion.Content, ToolCalls: completion.ToolCalls})
+		for _, call := range completion.ToolCalls {
+			result, id := s.tools.Invoke(ctx, userID, call)
+			if id != 0 {
+				toolIDs = append(toolIDs, id)
+			}
+			llmReq.Messages = append(llmReq.Messages, LLMMessage{Role: "tool", Content: result, ToolCallID: call.ID})
+		}
+	}
+
+	reply = &aiReply{toolInvocations: toolIDs, usage: usage}
+	out := strings.TrimSpace(text.String())
+	switch {
+	case out == "":
+		reply.content = aiOutOfScopeMsg
+		return reply, false, nil
+	// Post-filter: if the model slipped into program/diet/PED content, replace.
+	// This also covers program data a tool returned.
+	case hitsSteroidTopic(out):
+		reply.content, reply.flagReason, reply.original = aiSteroidRefuseMsg, models.AIFlagSteroidReply, out
+	case looksLikeProgramPrescription(out):
+		reply.content, reply.flagReason, reply.original = aiProgramRedirectMsg, models.AIFlagProgramPrescription, out
+	default:
+		reply.content, reply.sources = out, citedAISources(out, docs)
+	}
+	return reply, streamed, nil
+}
+
+// conversationFor loads the conversation being continued, or prepares an unsaved
+// one that record persists once there is a reply.
+func (s *AIChatService) conversationFor(ctx context.Context, userID uint, req *AIChatRequest) (*models.AIConversation, error) {
+	pagePath := strings.TrimSpace(req.PagePath)
+	if utf8.RuneCountInString(pagePath) > 255 {
+		pagePath = string([]rune(pagePath)[:255])
+	}
+	if req.ConversationID == nil || *req.ConversationID == 0 {
+		return &models.AIConversation{UserID: userID, PagePath: pagePath}, nil
+	}
+	conv, err := s.ownConversation(ctx, userID, *req.ConversationID)
 	if err != nil {
 		return nil, err
 	}
+	if pagePath != "" {
+		conv.PagePath = pagePath
+	}
+	return conv, nil
+}
 
-	system := buildFitinoSystemPrompt(profile, strings.TrimSpace(req.PagePath))
-	messages := []map[string]string{
-		{"role": "system", "content": system},
+func (s *AIChatService) ownConversation(ctx context.Context, userID, id uint) (*models.AIConversation, error) {
+	conv, err := s.convRepo.FindConversation(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAIConversationNotFound
+		}
+		return nil, err
 	}
-	for _, m := range sanitizeHistory(req.History) {
-		messages = append(messages, map[string]string{
-			"role":    m.Role,
-			"content": m.Content,
-		})
+	if conv.UserID != userID {
+		return nil, ErrAIConversationNotFound
 	}
-	messages = append(messages, map[string]string{
-		"role":    "user",
-		"content": message,
-	})
+	return conv, nil
+}
 
-	reply, err := s.callOpenAI(ctx, cfg, messages)
+func (s *AIChatService) history(ctx context.Context, conv *models.AIConversation) ([]AIChatMessage, error) {
+	if conv.ID == 0 {
+		return nil, nil
+	}
+	msgs, err := s.convRepo.RecentMessages(ctx, conv.ID, aiHistoryFetchLimit)
 	if err != nil {
 		return nil, err
 	}
+	return trimHistoryToBudget(msgs, aiHistoryTokenBudget), nil
+}
 
-	reply = strings.TrimSpace(reply)
-	if reply == "" {
-		return &AIChatResponse{Reply: aiOutOfScopeMsg}, nil
+func (s *AIChatService) record(ctx context.Context, userID uint, conv *models.AIConversation, message string, reply *aiReply) (*AIChatResponse, error) {
+	if conv.ID == 0 {
+		conv.Title = aiConversationTitle(message)
+		conv.LastMessageAt = time.Now()
+		if err := s.convRepo.CreateConversation(ctx, conv); err != nil {
+			return nil, err
+		}
+	}
+	userMsg := &models.AIMessage{
+		UserID:        userID,
+		Role:          models.AIRoleUser,
+		Content:       message,
+		TokenEstimate: estimateAITokens(message),
+	}
+	replyMsg := &models.AIMessage{
+		UserID:         userID,
+		Role:           models.AIRoleAssistant,
+		Content:        reply.content,
+		TokenEstimate:  estimateAITokens(reply.content),
+		Flagged:        reply.flagReason != "",
+		FlagReason:     reply.flagReason,
+		FlaggedContent: reply.original,
+		Sources:        encodeAISources(reply.sources),
+	}
+	if err := s.convRepo.AppendMessages(ctx, conv, userMsg, replyMsg); err != nil {
+		return nil, err
+	}
+	if s.tools != nil {
+		s.tools.Link(ctx, reply.toolInvocations, conv.ID, replyMsg.ID)
+	}
+	sources := reply.sources
+	if sources == nil {
+		sources = []AISource{}
 	}
+	return &AIChatResponse{Reply: reply.content, ConversationID: conv.ID, MessageID: replyMsg.ID, Sources: sources}, nil
+}
 
-	// Post-filter: if the model slipped into program/diet/PED content, replace.
-	if hitsSteroidTopic(reply) {
-		return &AIChatResponse{Reply: aiSteroidRefuseMsg}, nil
+func (s *AIChatService) ListConversations(ctx context.Context, userID uint, page, pageSize int) (*AIConversationListResponse, error) {
+	list, total, err := s.convRepo.ListConversations(ctx, userID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]AIConversationDTO, 0, len(list))
+	for i := range list {
+		items = append(items, aiConversationToDTO(&list[i]))
 	}
-	if looksLikeProgramPrescription(reply) {
-		return &AIChatResponse{Reply: aiProgramRedirectMsg}, nil
+	return &AIConversationListResponse{Items: items, Page: page, PageSize: pageSize, Total: total}, nil
+}
+
+func (s *AIChatService) GetConversation(ctx context.Context, userID, id uint) (*AIConversationDetailDTO, error) {
+	conv, err := s.ownConversation(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := s.convRepo.ListMessages(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := &AIConversationDetailDTO{Conversation: aiConversationToDTO(conv), Messages: make([]AIMessageDTO, 0, len(msgs))}
+	for i := range msgs {
+		out.Messages = append(out.Messages, aiMessageToDTO(&msgs[i]))
 	}
+	return out, nil
+}
 
-	return &AIChatResponse{Reply: reply}, nil
+func (s *AIChatService) DeleteConversation(ctx context.Context, userID, id uint) error {
+	conv, err := s.ownConversation(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	return s.convRepo.DeleteConversation(ctx, conv.ID)
 }
 
-func (s *AIChatService) allow(userID uint) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// RateMessage stores thumbs up/down feedback on one of the user's assistant replies.
+func (s *AIChatService) RateMessage(ctx context.Context, userID, messageID uint, req *AIMessageFeedbackRequest) (*AIMessageDTO, error) {
+	var rating int8
+	switch strings.ToLower(strings.TrimSpace(req.Rating)) {
+	case "up":
+		rating = 1
+	case "down":
+		rating = -1
+	case "none":
+		rating = 0
+	default:
+		return nil, ErrAIInvalidFeedback
+	}
+	note := strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(note) > 500 {
+		return nil, ErrAIInvalidFeedback
+	}
+	if rating == 0 {
+		note = ""
+	}
 
-	now := time.Now()
-	cutoff := now.Add(-aiRateWindow)
-	kept := make([]time.Time, 0, len(s.rates[userID]))
-	for _, t := range s.rates[userID] {
-		if t.After(cutoff) {
-			kept = append(kept, t)
+	msg, err := s.convRepo.FindMessage(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAIMessageNotFound
 		}
+		return nil, err
+	}
+	if msg.UserID != userID || msg.Role != models.AIRoleAssistant {
+		return nil, ErrAIMessageNotFound
 	}
-	if len(kept) >= aiRateMaxPerWindow {
-		s.rates[userID] = kept
-		return false
+	if err := s.convRepo.SetFeedback(ctx, msg.ID, rating, note); err != nil {
+		return nil, err
 	}
-	s.rates[userID] = append(kept, now)
-	return true
+	msg.Rating, msg.FeedbackNote = rating, note
+	dto := aiMessageToDTO(msg)
+	return &dto, nil
 }
 
-func sanitizeHistory(history []AIChatMessage) []AIChatMessage {
-	if len(history) == 0 {
-		return nil
+// ListFlaggedReplies is the admin moderation view of guardrail-flagged replies,
+// each paired with the prompt that produced it.
+func (s *AIChatService) ListFlaggedReplies(ctx context.Context, reason string, page, pageSize int) (*AIFlaggedReplyListResponse, error) {
+	list, total, err := s.convRepo.ListFlagged(ctx, strings.TrimSpace(reason), page, pageSize)
+	if err != nil {
+		return nil, err
 	}
-	start := 0
-	if len(history) > aiMaxHistoryMessages {
-		start = len(history) - aiMaxHistoryMessages
+	promptIDs := make([]uint, 0, len(list))
+	for _, m := range list {
+		if m.ReplyToID != nil {
+			promptIDs = append(promptIDs, *m.ReplyToID)
+		}
 	}
-	out := make([]AIChatMessage, 0, len(history)-start)
-	for _, m := range history[start:] {
-		role := strings.ToLower(strings.TrimSpace(m.Role))
-		content := strings.TrimSpace(m.Content)
-		if content == "" || utf8.RuneCountInString(content) > aiMaxMessageRunes {
-			continue
+	prompts, err := s.convRepo.FindMessagesByIDs(ctx, promptIDs)
+	if err != nil {
+		return nil, err
+	}
+	promptByID := make(map[uint]string, len(prompts))
+	for _, p := range prompts {
+		promptByID[p.ID] = p.Content
+	}
+
+	names := map[uint]string{}
+	items := make([]AIFlaggedReplyDTO, 0, len(list))
+	for _, m := range list {
+		item := AIFlaggedReplyDTO{
+			MessageID:      m.ID,
+			ConversationID: m.ConversationID,
+			UserID:         m.UserID,
+			UserName:       s.userName(ctx, m.UserID, names),
+			Reply:          m.Content,
+			OriginalReply:  m.FlaggedContent,
+			FlagReason:     m.FlagReason,
+			Rating:         aiRatingLabel(m.Rating),
+			FeedbackNote:   m.FeedbackNote,
+			CreatedAt:      m.CreatedAt,
+		}
+		if m.ReplyToID != nil {
+			item.Prompt = promptByID[*m.ReplyToID]
+		}
+		items = append(items, item)
+	}
+	return &AIFlaggedReplyListResponse{Items: items, Page: page, PageSize: pageSize, Total: total}, nil
+}
+
+// ListToolInvocations is the admin audit trail of assistant tool calls, optionally
+// filtered by user and tool.
+func (s *AIChatService) ListToolInvocations(ctx context.Context, userID uint, tool string, page, pageSize int) (*AIToolInvocationListResponse, error) {
+	out := &AIToolInvocationListResponse{Items: []AIToolInvocationDTO{}, Page: page, PageSize: pageSize}
+	if s.tools == nil || s.tools.audit == nil {
+		return out, nil
+	}
+	list, total, err := s.tools.audit.List(ctx, userID, strings.TrimSpace(tool), page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	names := map[uint]string{}
+	for _, inv := range list {
+		out.Items = append(out.Items, AIToolInvocationDTO{
+			ID:             inv.ID,
+			UserID:         inv.UserID,
+			UserName:       s.userName(ctx, inv.UserID, names),
+			ConversationID: inv.ConversationID,
+			MessageID:      inv.MessageID,
+			Tool:           inv.Tool,
+			Arguments:      inv.Arguments,
+			Status:         inv.Status,
+			Error:          inv.Error,
+			ResultBytes:    inv.ResultBytes,
+			DurationMs:     inv.DurationMs,
+			CreatedAt:      inv.CreatedAt,
+		})
+	}
+	out.Total = total
+	return out, nil
+}
+
+func (s *AIChatService) userName(ctx context.Context, id uint, cache map[uint]string) string {
+	if name, ok := cache[id]; ok {
+		return name
+	}
+	name := ""
+	if u, err := s.userRepo.FindByID(ctx, id); err == nil && u != nil {
+		name = strings.TrimSpace(u.Name)
+	}
+	cache[id] = name
+	return name
+}
+
+// estimateAITokens approximates the model token count of text. Persian averages
+// roughly three runes per token; each message also carries a few tokens of framing.
+func estimateAITokens(text string) int {
+	return (utf8.RuneCountInString(text)+2)/3 + aiMessageTokenOverhead
+}
+
+// trimHistoryToBudget keeps the newest messages whose estimated tokens fit in budget.
+// msgs must be oldest first.
+func trimHistoryToBudget(msgs []models.AIMessage, budget int) []AIChatMessage {
+	used := 0
+	start := len(msgs)
+	for i := len(msgs) - 1; i >= 0; i-- {
+		tokens := msgs[i].TokenEstimate
+		if tokens <= 0 {
+			tokens = estimateAITokens(msgs[i].Content)
 		}
-		if role != "user" && role != "assistant" {
+		if used+tokens > budget {
+			break
+		}
+		used += tokens
+		start = i
+	}
+	out := make([]AIChatMessage, 0, len(msgs)-start)
+	for _, m := range msgs[start:] {
+		if m.Role != models.AIRoleUser && m.Role != models.AIRoleAssistant {
 			continue
 		}
-		out = append(out, AIChatMessage{Role: role, Content: content})
+		if content := strings.TrimSpace(m.Content); content != "" {
+			out = append(out, AIChatMessage{Role: m.Role, Content: content})
+		}
+	}
+	// Don't open the history with an orphaned reply.
+	if len(out) > 0 && out[0].Role == models.AIRoleAssistant {
+		out = out[1:]
+	}
+	return out
+}
+
+func aiConversationTitle(message string) string {
+	title := strings.Join(strings.Fields(message), " ")
+	if r := []rune(title); len(r) > aiConversationTitleRunes {
+		title = string(r[:aiConversationTitleRunes]) + "…"
+	}
+	return title
+}
+
+func aiConversationToDTO(c *models.AIConversation) AIConversationDTO {
+	return AIConversationDTO{
+		ID:            c.ID,
+		Title:         c.Title,
+		PagePath:      c.PagePath,
+		MessageCount:  c.MessageCount,
+		LastMessageAt: c.LastMessageAt,
+		CreatedAt:     c.CreatedAt,
 	}
+}
+
+func aiMessageToDTO(m *models.AIMessage) AIMessageDTO {
+	return AIMessageDTO{
+		ID:           m.ID,
+		Role:         m.Role,
+		Content:      m.Content,
+		Sources:      decodeAISources(m.Sources),
+		Rating:       aiRatingLabel(m.Rating),
+		FeedbackNote: m.FeedbackNote,
+		CreatedAt:    m.CreatedAt,
+	}
+}
+
+func encodeAISources(sources []AISource) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	raw, _ := json.Marshal(sources)
+	return string(raw)
+}
+
+func decodeAISources(raw string) []AISource {
+	if raw == "" {
+		return nil
+	}
+	var out []AISource
+	_ = json.Unmarshal([]byte(raw), &out)
 	return out
 }
 
+func aiRatingLabel(r int8) string {
+	switch {
+	case r > 0:
+		return "up"
+	case r < 0:
+		return "down"
+	}
+	return ""
+}
+
 func buildFitinoSystemPrompt(profile *MeProfileDTO, pagePath string) string {
 	var b strings.Builder
 	b.WriteString(`تو «دستیار فیتینو» هستی — راهنمای فارسی‌زبان داخل اپلیکیشن فیتینو (Fitino / fitinoo.ir).
@@ -208,6 +725,7 @@ func buildFitinoSystemPrompt(profile *MeProfileDTO, pagePath string) string {
 4) درباره استروئید، سارمز، داروهای نیروزا، دوپینگ یا روش‌های خطرناک هیچ راهنمایی نده؛ رد کن و به مربی‌های فیتینو ارجاع بده.
 5) اطلاعات پزشکی حساس کاربر را بازگو یا تشخیص نده؛ فقط در حد راهنمایی ناوبری اپ کمک کن.
 6) پاسخ‌ها کوتاه، واضح، دوستانه و به فارسی باشه. در صورت نیاز مسیر منو را بگو (مثلاً حساب من ← ارتباط با مربی).
+7) برای سوال درباره داده‌های خود کاربر (موعد پایش، روزهای برنامه، رکوردها، وضعیت سفارش) از ابزارها استفاده کن و فقط همان چیزی را گزارش کن که ابزار برگرداند یا مربی تعیین کرده؛ چیزی به برنامه اضافه یا در آن تغییر نده و ست و تکرار پیشنهاد نکن.
 
 `)
 
@@ -321,66 +839,3 @@ func looksLikeProgramPrescription(text string) bool {
 	}
 	return hits >= 2
 }
-
-type openAIChatRequest struct {
-	Model       string              `json:"model"`
-	Messages    []map[string]string `json:"messages"`
-	Temperature float32             `json:"temperature"`
-	MaxTokens   int                 `json:"max_tokens"`
-}
-
-type openAIChatResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-	Error *struct {
-		Message string `json:"message"`
-	} `json:"error"`
-}
-
-func (s *AIChatService) callOpenAI(ctx context.Context, cfg config.Config, messages []map[string]string) (string, error) {
-	oai := cfg.OpenAI
-	payload := openAIChatRequest{
-		Model:       oai.Model,
-		Messages:    messages,
-		Temperature: 0.4,
-		MaxTokens:   500,
-	}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
-
-	url := oai.BaseURL + "/chat/completions"
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+oai.APIKey)
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("%w: %v", ErrAIUpstream, err)
-	}
-	defer resp.Body.Close()
-
-	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-	var parsed openAIChatResponse
-	if err := json.Unmarshal(raw, &parsed); err != nil {
-		return "", fmt.Errorf("%w: invalid response", ErrAIUpstream)
-	}
-	if resp.StatusCode >= 300 {
-		msg := "upstream error"
-		if parsed.Error != nil && parsed.Error.Message != "" {
-			msg = parsed.Error.Message
-		}
-		return "", fmt.Errorf("%w: %s", ErrAIUpstream, msg)
-	}
-	if len(parsed.Choices) == 0 {
-		return "", fmt.Errorf("%w: empty choices", ErrAIUpstream)
-	}
-	return parsed.Choices[0].Message.Content, nil
-}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+func TestTrimHistoryToBudget(t *testing.T) {
+	long := strings.Repeat("ا", 300) // ~104 tokens
+	msgs := []models.AIMessage{
+		{Role: models.AIRoleUser, Content: long},
+		{Role: models.AIRoleAssistant, Content: long},
+		{Role: models.AIRoleUser, Content: "سلام"},
+		{Role: models.AIRoleAssistant, Content: "سلام! چطور کمک کنم؟", TokenEstimate: 10},
+	}
+
+	got := trimHistoryToBudget(msgs, 100)
+	if len(got) != 2 || got[0].Content != "سلام" {
+		t.Fatalf("expected only the newest turn to fit, got %+v", got)
+	}
+
+	// The long assistant reply fits but its prompt does not: don't start on a reply.
+	got = trimHistoryToBudget(msgs, 200)
+	if len(got) != 2 || got[0].Content != "سلام" {
+		t.Fatalf("history must not open with an orphaned reply, got %+v", got)
+	}
+
+	if got := trimHistoryToBudget(msgs, 10000); len(got) != 4 {
+		t.Fatalf("everything fits a large budget, got %d", len(got))
+	}
+}
+
+type stubMeService struct{ MeService }
+
+func (stubMeService) GetProfile(ctx context.Context, userID uint) (*MeProfileDTO, error) {
+	return &MeProfileDTO{}, nil
+}
+
+// memoryAIConversationRepo keeps just enough state for a new-conversation turn.
+type memoryAIConversationRepo struct {
+	repository.AIConversationRepository
+	messages []models.AIMessage
+}
+
+func (r *memoryAIConversationRepo) CreateConversation(ctx context.Context, conv *models.AIConversation) error {
+	conv.ID = 1
+	return nil
+}
+
+func (r *memoryAIConversationRepo) AppendMessages(ctx context.Context, conv *models.AIConversation, user, reply *models.AIMessage) error {
+	user.ID, reply.ID = uint(len(r.messages)+1), uint(len(r.messages)+2)
+	reply.ReplyToID = &user.ID
+	r.messages = append(r.messages, *user, *reply)
+	return nil
+}
+
+func TestChatStreamReplacesPrescription(t *testing.T) {
+	repo := &memoryAIConversationRepo{}
+	svc := &AIChatService{
+		meService: stubMeService{},
+		convRepo:  repo,
+		provider:  NewFakeLLMProvider("روز ۱: اسکوات ۴ ست x ۱۰ تکرار: ۱۰ و بعد پرس سینه"),
+	}
+	var streamed strings.Builder
+	resp, err := svc.ChatStream(context.Background(), 7, &AIChatRequest{Message: "پنل مربی کجاست؟"}, func(d string) error {
+		streamed.WriteString(d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Replaced || resp.Reply != aiProgramRedirectMsg || resp.ConversationID != 1 {
+		t.Fatalf("prescription should be replaced, got %+v", resp)
+	}
+	if looksLikeProgramPrescription(streamed.String()) {
+		t.Fatalf("stream should stop before the prescription is complete, sent %q", streamed.String())
+	}
+	if len(repo.messages) != 2 {
+		t.Fatalf("expected user+assistant messages stored, got %d", len(repo.messages))
+	}
+	if reply := repo.messages[1]; !reply.Flagged || reply.FlagReason != models.AIFlagProgramPrescription || reply.FlaggedContent == "" {
+		t.Fatalf("replacement should be flagged with the original kept, got %+v", reply)
+	}
+}
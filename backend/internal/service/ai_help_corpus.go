@@ -0,0 +1,94 @@
+package service
+
+// aiHelpArticle is a curated how-to the assistant can ground answers in. Unlike
+// FAQ and academy content it is not admin-editable: it documents app behavior
+// and changes together with the code.
+type aiHelpArticle struct {
+	ID    string
+	Title string
+	Path  string
+	Body  string
+}
+
+var aiHelpArticles = []aiHelpArticle{
+	{
+		ID:    "tracking-photos",
+		Title: "آپلود عکس‌های پایش (چک‌این)",
+		Path:  "/user/tracking",
+		Body: "عکس‌های پایش از تب پایش ثبت می‌شوند. در هر دوره پایش سه عکس جلو، پشت و بغل خواسته می‌شود. " +
+			"روی کارت هر زاویه بزنید، عکس را از گالری یا دوربین انتخاب کنید و آپلود کنید. برای آپلود عکس پایش اشتراک فعال لازم است. " +
+			"فاصله دوره‌های پایش را اشتراک فعال شما تعیین می‌کند؛ اگر موعد عکس گذشته باشد، در داشبورد هشدار عقب‌افتادگی نمایش داده می‌شود. " +
+			"عکس‌ها فقط برای شما و مربی‌تان قابل مشاهده‌اند.",
+	},
+	{
+		ID:    "tracking-weight",
+		Title: "ثبت وزن",
+		Path:  "/user/tracking",
+		Body: "برای ثبت وزن به تب پایش بروید و دکمه ثبت وزن را بزنید. وزن باید بین ۲۰ تا ۳۰۰ کیلوگرم باشد. " +
+			"نمودار روند وزن در همان صفحه و در داشبورد نمایش داده می‌شود و مربی هم آن را می‌بیند.",
+	},
+	{
+		ID:    "profile-body-photos",
+		Title: "عکس بدن در پروفایل",
+		Path:  "/user/profile",
+		Body: "عکس‌های اولیه بدن (جلو، پشت، بغل) اختیاری‌اند و از حساب من ← پروفایل آپلود می‌شوند. " +
+			"این عکس‌ها با عکس‌های دوره‌ای پایش فرق دارند و فقط وضعیت شروع را نشان می‌دهند.",
+	},
+	{
+		ID:    "workout-log",
+		Title: "ثبت جلسه تمرین و تاریخچه",
+		Path:  "/user/my-programs",
+		Body: "از تب تمرین ← برنامه‌های من، روز تمرین را باز کنید و برای هر حرکت وزنه و تکرار هر ست را وارد کنید، سپس جلسه را ثبت کنید. " +
+			"جلسات ثبت‌شده در تاریخچه تمرینات دیده می‌شوند و رکوردهای شخصی و حجم تمرینی هفتگی هر عضله از روی همین ثبت‌ها محاسبه می‌شود.",
+	},
+	{
+		ID:    "exercise-swap",
+		Title: "جایگزینی حرکت در برنامه",
+		Path:  "/user/my-programs",
+		Body: "اگر مربی اجازه جایگزینی حرکت را فعال کرده باشد، در جزئیات برنامه کنار هر حرکت گزینه حرکت جایگزین نمایش داده می‌شود. " +
+			"فقط از بین جایگزین‌های پیشنهادی می‌توانید انتخاب کنید و هر زمان می‌توانید جایگزینی را لغو کنید. " +
+			"اگر این گزینه را نمی‌بینید، از طریق تیکت از مربی بخواهید آن را فعال کند.",
+	},
+	{
+		ID:    "food-diary",
+		Title: "کالری‌شمار و دفترچه غذا",
+		Path:  "/user/food-diary",
+		Body: "در بخش تغذیه ← کالری‌شمار، غذا را جستجو کنید، مقدار را وارد کنید و به وعده اضافه کنید. " +
+			"مجموع کالری و درشت‌مغذی‌های روز نسبت به اهدافی که مربی تعیین کرده نمایش داده می‌شود.",
+	},
+	{
+		ID:    "tickets",
+		Title: "ارتباط با مربی از طریق تیکت",
+		Path:  "/user/contact",
+		Body: "از حساب من ← ارتباط با مربی، تیکت جدید بسازید، عنوان و اولویت را انتخاب کنید و پیام را دقیق بنویسید. " +
+			"وضعیت تیکت (در انتظار، در حال بررسی، پاسخ داده شده، بسته) در همان صفحه دیده می‌شود و پاسخ مربی اعلان می‌شود.",
+	},
+	{
+		ID:    "orders-payment",
+		Title: "سفارش، پرداخت و فعال شدن برنامه",
+		Path:  "/user/orders",
+		Body: "پرداخت از طریق درگاه زرین‌پال انجام می‌شود. پس از پرداخت موفق، سفارش در حساب من ← سفارش‌ها با وضعیت پرداخت‌شده دیده می‌شود و اشتراک فعال می‌شود. " +
+			"برنامه را مربی بعد از فعال شدن اشتراک آماده می‌کند و ممکن است چند ساعت طول بکشد. اگر مبلغ کسر شد ولی سفارش پرداخت‌نشده ماند، تیکت بزنید.",
+	},
+	{
+		ID:    "profile-complete",
+		Title: "تکمیل پروفایل",
+		Path:  "/user/profile",
+		Body: "درصد تکمیل پروفایل در داشبورد نمایش داده می‌شود. قد، وزن، هدف، سطح تمرینی، آسیب‌ها و سوابق پزشکی را از حساب من ← پروفایل تکمیل کنید تا مربی برنامه دقیق‌تری بنویسد. " +
+			"همه این موارد اختیاری‌اند ولی کامل بودن آن‌ها به مربی کمک می‌کند.",
+	},
+	{
+		ID:    "password-login",
+		Title: "ورود، رمز عبور و کد پیامکی",
+		Path:  "/login",
+		Body: "ورود با شماره موبایل انجام می‌شود؛ با رمز عبور یا کد یک‌بارمصرف پیامکی. کد پیامکی چند دقیقه اعتبار دارد و ارسال مجدد پس از یک دقیقه ممکن است. " +
+			"برای تغییر رمز از حساب من ← تغییر رمز عبور اقدام کنید.",
+	},
+	{
+		ID:    "ai-assistant",
+		Title: "دستیار هوشمند فیتینو",
+		Path:  "/user/ai",
+		Body: "دستیار فیتینو فقط درباره امکانات اپ و مسیر استفاده راهنمایی می‌کند و برنامه تمرین یا رژیم نمی‌نویسد. " +
+			"گفتگوهای قبلی در فهرست گفتگوها ذخیره می‌شوند و می‌توانید آن‌ها را ادامه دهید یا حذف کنید. با دکمه‌های لایک و دیس‌لایک کیفیت پاسخ را اعلام کنید.",
+	},
+}
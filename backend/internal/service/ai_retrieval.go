@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Source kinds the assistant can cite.
+const (
+	AISourceFAQ     = "faq"
+	AISourceAcademy = "academy"
+	AISourceHelp    = "help"
+)
+
+const (
+	aiRetrievalTopK = 4
+	// aiKnowledgeTTL bounds how stale the index may get after an admin edits the
+	// FAQ or academy; rebuilding is cheap (tens of documents).
+	aiKnowledgeTTL     = 5 * time.Minute
+	aiSourceSnippetMax = 600
+	bm25K1             = 1.2
+	bm25B              = 0.75
+)
+
+// AISource identifies a knowledge document an assistant reply was grounded in.
+type AISource struct {
+	ID    string `json:"id"` // e.g. faq:start:2, academy:p-1, help:tracking-photos
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+	URL   string `json:"url,omitempty"`
+}
+
+type aiDocument struct {
+	Source AISource
+	Text   string
+}
+
+type aiRetrievedDoc struct {
+	aiDocument
+	Score float64
+}
+
+// bm25Index is an in-memory Okapi BM25 index. It is immutable once built.
+type bm25Index struct {
+	docs   []aiDocument
+	tf     []map[string]int
+	lens   []int
+	avgLen float64
+	df     map[string]int
+}
+
+func newBM25Index(docs []aiDocument) *bm25Index {
+	idx := &bm25Index{docs: docs, tf: make([]map[string]int, len(docs)), lens: make([]int, len(docs)), df: map[string]int{}}
+	total := 0
+	for i, d := range docs {
+		terms := aiTokenize(d.Source.Title + " " + d.Text)
+		tf := make(map[string]int, len(terms))
+		for _, t := range terms {
+			tf[t]++
+		}
+		for t := range tf {
+			idx.df[t]++
+		}
+		idx.tf[i], idx.lens[i] = tf, len(terms)
+		total += len(terms)
+	}
+	if len(docs) > 0 {
+		idx.avgLen = float64(total) / float64(len(docs))
+	}
+	return idx
+}
+
+// search returns up to k documents with a positive score, best first.
+func (idx *bm25Index) search(query string, k int) []aiRetrievedDoc {
+	terms := aiTokenize(query)
+	if len(terms) == 0 || len(idx.docs) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	n := float64(len(idx.docs))
+	scores := make([]float64, len(idx.docs))
+	for _, t := range terms {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		df := float64(idx.df[t])
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for i, tf := range idx.tf {
+			f := float64(tf[t])
+			if f == 0 {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(idx.lens[i])/idx.avgLen
+			scores[i] += idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+	}
+
+	out := make([]aiRetrievedDoc, 0, k)
+	for i, sc := range scores {
+		if sc > 0 {
+			out = append(out, aiRetrievedDoc{aiDocument: idx.docs[i], Score: sc})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if len(out) > k {
+		out = out[:k]
+	}
+	return out
+}
+
+// aiStopwords are high-frequency Persian/English words that carry no topic.
+var aiStopwords = map[string]bool{
+	"و": true, "در": true, "به": true, "از": true, "که": true, "را": true, "با": true, "این": true, "آن": true,
+	"است": true, "هست": true, "برای": true, "یک": true, "تا": true, "هم": true, "یا": true, "اگر": true, "من": true,
+	"شما": true, "ما": true, "چطور": true, "چگونه": true, "کجا": true, "چرا": true, "چه": true, "چی": true, "آیا": true,
+	"می": true, "نمی": true, "ها": true, "های": true, "ای": true, "ی": true, "شود": true, "کنم": true, "کنید": true,
+	"کرد": true, "کنیم": true, "باید": true, "دارم": true, "بود": true, "رو": true, "بر": true,
+	"the": true, "a": true, "an": true, "to": true, "of": true, "and": true, "in": true, "how": true, "do": true, "i": true, "is": true,
+}
+
+var aiCharFold = strings.NewReplacer(
+	"ي", "ی", "ى", "ی", "ك", "ک", "ة", "ه", "ۀ", "ه", "أ", "ا", "إ", "ا", "ؤ", "و",
+	"\u200c", " ", // ZWNJ splits «عکس‌های» into «عکس» + «های»
+	"۰", "0", "۱", "1", "۲", "2", "۳", "3", "۴", "4", "۵", "5", "۶", "6", "۷", "7", "۸", "8", "۹", "9",
+)
+
+// aiTokenize normalizes Persian spelling variants and splits text into index terms.
+func aiTokenize(text string) []string {
+	text = strings.ToLower(aiCharFold.Replace(text))
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		f = strings.TrimFunc(f, func(r rune) bool { return unicode.Is(unicode.Mn, r) })
+		if f == "" || aiStopwords[f] {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// AIKnowledgeBase indexes FAQ entries, academy items and the curated help corpus
+// for retrieval-grounded assistant answers. The index is rebuilt lazily once it
+// is older than aiKnowledgeTTL.
+type AIKnowledgeBase struct {
+	siteSettings SiteSettingsService
+
+	mu      sync.Mutex
+	index   *bm25Index
+	builtAt time.Time
+}
+
+func NewAIKnowledgeBase(siteSettings SiteSettingsService) *AIKnowledgeBase {
+	return &AIKnowledgeBase{siteSettings: siteSettings}
+}
+
+// Search returns the top matches for query. On a FAQ/academy load failure it
+// keeps serving the previous index (or the help corpus alone).
+func (kb *AIKnowledgeBase) Search(ctx context.Context, query string, k int) []aiRetrievedDoc {
+	return kb.currentIndex(ctx).search(query, k)
+}
+
+func (kb *AIKnowledgeBase) currentIndex(ctx context.Context) *bm25Index {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if kb.index != nil && time.Since(kb.builtAt) < aiKnowledgeTTL {
+		return kb.index
+	}
+	docs, err := kb.loadDocuments(ctx)
+	if err != nil && kb.index != nil {
+		return kb.index
+	}
+	kb.index, kb.builtAt = newBM25Index(docs), time.Now()
+	return kb.index
+}
+
+func (kb *AIKnowledgeBase) loadDocuments(ctx context.Context) ([]aiDocument, error) {
+	docs := make([]aiDocument, 0, len(aiHelpArticles)+32)
+	for _, a := range aiHelpArticles {
+		docs = append(docs, aiDocument{
+			Source: AISource{ID: AISourceHelp + ":" + a.ID, Kind: AISourceHelp, Title: a.Title, URL: a.Path},
+			Text:   a.Body,
+		})
+	}
+	if kb.siteSettings == nil {
+		return docs, nil
+	}
+	settings, err := kb.siteSettings.Get(ctx)
+	if err != nil {
+		return docs, err
+	}
+	for _, g := range settings.FAQGroups {
+		for i, item := range g.Items {
+			if strings.TrimSpace(item.Q) == "" || strings.TrimSpace(item.A) == "" {
+				continue
+			}
+			docs = append(docs, aiDocument{
+				Source: AISource{
+					ID:    AISourceFAQ + ":" + g.ID + ":" + strconv.Itoa(i),
+					Kind:  AISourceFAQ,
+					Title: item.Q,
+					URL:   "/user/faq",
+				},
+				Text: g.Title + " — " + item.A,
+			})
+		}
+	}
+	for _, item := range settings.AcademyItems {
+		if strings.TrimSpace(item.Title) == "" {
+			continue
+		}
+		parts := make([]string, 0, 3)
+		for _, p := range []string{item.Category, item.Description, item.Body} {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+		text := strings.Join(parts, " — ")
+		docs = append(docs, aiDocument{
+			Source: AISource{ID: AISourceAcademy + ":" + item.ID, Kind: AISourceAcademy, Title: item.Title, URL: "/user/academy"},
+			Text:   text,
+		})
+	}
+	return docs, nil
+}
+
+// buildAISourcesPrompt renders retrieved documents as numbered references for
+// the system prompt; the model cites them as [n].
+func buildAISourcesPrompt(docs []aiRetrievedDoc) string {
+	if len(docs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nمنابع راهنمای فیتینو مرتبط با سوال کاربر (پاسخ را بر این‌ها بنا کن، چیزی خلاف آن‌ها نگو، و بعد از هر جمله‌ای که از منبعی گرفتی شماره‌اش را مثل [1] بیاور):\n")
+	for i, d := range docs {
+		text := d.Text
+		if r := []rune(text); len(r) > aiSourceSnippetMax {
+			text = string(r[:aiSourceSnippetMax]) + "…"
+		}
+		b.WriteString("[" + strconv.Itoa(i+1) + "] " + d.Source.Title + "\n" + text + "\n")
+	}
+	return b.String()
+}
+
+// citedAISources returns the documents referenced as [n] in reply, in order of
+// first citation.
+func citedAISources(reply string, docs []aiRetrievedDoc) []AISource {
+	out := []AISource{}
+	if len(docs) == 0 {
+		return out
+	}
+	seen := map[int]bool{}
+	reply = aiCharFold.Replace(reply) // Persian digits inside [۱]
+	for i := 0; i < len(reply); i++ {
+		if reply[i] != '[' {
+			continue
+		}
+		j := i + 1
+		n := 0
+		for j < len(reply) && reply[j] >= '0' && reply[j] <= '9' && j-i <= 3 {
+			n = n*10 + int(reply[j]-'0')
+			j++
+		}
+		if j < len(reply) && reply[j] == ']' && j > i+1 && n >= 1 && n <= len(docs) && !seen[n] {
+			seen[n] = true
+			out = append(out, docs[n-1].Source)
+		}
+	}
+	return out
+}
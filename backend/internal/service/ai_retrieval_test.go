@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAIKnowledgeBaseFindsHelpArticle(t *testing.T) {
+	kb := NewAIKnowledgeBase(nil)
+	docs := kb.Search(context.Background(), "چطور عکس‌های چک‌این رو آپلود کنم؟", aiRetrievalTopK)
+	if len(docs) == 0 || docs[0].Source.ID != "help:tracking-photos" {
+		t.Fatalf("expected tracking photos article first, got %+v", docs)
+	}
+	if got := kb.Search(context.Background(), "و در به از", aiRetrievalTopK); len(got) != 0 {
+		t.Fatalf("stopword-only query should match nothing, got %d", len(got))
+	}
+}
+
+func TestAITokenizeNormalizesPersian(t *testing.T) {
+	got := aiTokenize("عكس‌هاي پايش ۳ تا")
+	want := []string{"عکس", "پایش", "3"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %q want %q", got, want)
+		}
+	}
+}
+
+func TestCitedAISources(t *testing.T) {
+	docs := []aiRetrievedDoc{
+		{aiDocument: aiDocument{Source: AISource{ID: "help:a"}}},
+		{aiDocument: aiDocument{Source: AISource{ID: "faq:b"}}},
+	}
+	got := citedAISources("از تب پایش [۲] و بعد [2][1]، نه [7] و نه [x]", docs)
+	if len(got) != 2 || got[0].ID != "faq:b" || got[1].ID != "help:a" {
+		t.Fatalf("unexpected citations %+v", got)
+	}
+}
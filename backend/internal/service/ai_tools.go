@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+// Tools the assistant can call. All of them are read-only and act on the
+// authenticated user only: the user ID comes from the session, never from the
+// model-supplied arguments.
+const (
+	AIToolTrackingStatus  = "get_tracking_status"
+	AIToolMyPrograms      = "get_my_programs"
+	AIToolPersonalRecords = "get_personal_records"
+	AIToolOrderStatus     = "get_order_status"
+)
+
+const (
+	// aiMaxToolRounds bounds model→tool→model round trips per reply; the last
+	// request is sent without tools so the model has to answer.
+	aiMaxToolRounds      = 3
+	aiToolResultMaxBytes = 4000
+	aiToolArgsAuditMax   = 1000
+	aiToolRecordsMax     = 10
+	aiToolRecentOrders   = 5
+	aiToolRecentWeighIns = 5
+)
+
+type aiToolHandler func(ctx context.Context, userID uint, args json.RawMessage) (any, error)
+
+// AIToolbox exposes the user's own tracking, programs, records and orders to the
+// assistant and audits every call.
+type AIToolbox struct {
+	tracking  TrackingService
+	me        MeService
+	dashboard MeDashboardService
+	audit     repository.AIToolInvocationRepository
+
+	handlers map[string]aiToolHandler
+}
+
+func NewAIToolbox(tracking TrackingService, me MeService, dashboard MeDashboardService, audit repository.AIToolInvocationRepository) *AIToolbox {
+	t := &AIToolbox{tracking: tracking, me: me, dashboard: dashboard, audit: audit}
+	t.handlers = map[string]aiToolHandler{
+		AIToolTrackingStatus:  t.trackingStatus,
+		AIToolMyPrograms:      t.myPrograms,
+		AIToolPersonalRecords: t.personalRecords,
+		AIToolOrderStatus:     t.orderStatus,
+	}
+	return t
+}
+
+var aiToolDefinitions = []LLMTool{
+	{
+		Name:        AIToolTrackingStatus,
+		Description: "وضعیت پایش کاربر: موعد بعدی ثبت وزن و عکس (چک‌این)، فاصله دوره‌ها، ثبت‌های دوره فعلی، هشدارهای عقب‌افتادگی و آخرین وزن‌ها.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{}}`),
+	},
+	{
+		Name:        AIToolMyPrograms,
+		Description: "برنامه‌های کاربر با وضعیت و روزهای باقی‌مانده. با program_id، روزهای تمرین/استراحت هفته و عنوان و حرکات تمرین هر روز همان برنامه را برمی‌گرداند.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"program_id":{"type":"integer","description":"شناسه برنامه از فهرست برنامه‌ها"}}}`),
+	},
+	{
+		Name:        AIToolPersonalRecords,
+		Description: "رکوردهای شخصی کاربر در حرکات (بهترین وزنه، تکرار و یک‌تکرار بیشینه تخمینی) از روی جلسات ثبت‌شده.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"limit":{"type":"integer","minimum":1,"maximum":10}}}`),
+	},
+	{
+		Name:        AIToolOrderStatus,
+		Description: "وضعیت سفارش‌های کاربر. با order_id همان سفارش، وگرنه آخرین سفارش‌ها.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"order_id":{"type":"integer"}}}`),
+	},
+}
+
+// Definitions returns the tool declarations offered to the model.
+func (t *AIToolbox) Definitions() []LLMTool {
+	return aiToolDefinitions
+}
+
+// Invoke runs call for userID and returns the JSON handed back to the model along
+// with the audit row ID (0 when auditing failed). Failures are reported to the
+// model as {"error": ...} rather than aborting the reply.
+func (t *AIToolbox) Invoke(ctx context.Context, userID uint, call LLMToolCall) (string, uint) {
+	start := time.Now()
+	var (
+		result any
+		err    error
+	)
+	if h, ok := t.handlers[call.Name]; ok {
+		args := json.RawMessage(call.Arguments)
+		if len(args) == 0 || !json.Valid(args) {
+			args = json.RawMessage(`{}`)
+		}
+		result, err = h(ctx, userID, args)
+	} else {
+		err = errors.New("unknown tool")
+	}
+
+	var content string
+	if err != nil {
+		msg := err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			msg = "not found"
+		}
+		content = aiToolJSON(map[string]string{"error": msg})
+	} else {
+		content = aiToolJSON(result)
+	}
+
+	inv := &models.AIToolInvocation{
+		UserID:      userID,
+		Tool:        call.Name,
+		Arguments:   truncateRunes(call.Arguments, aiToolArgsAuditMax),
+		Status:      models.AIToolStatusOK,
+		ResultBytes: len(content),
+		DurationMs:  time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		inv.Status = models.AIToolStatusError
+		inv.Error = truncateRunes(err.Error(), 255)
+	}
+	if t.audit == nil {
+		return content, 0
+	}
+	if aerr := t.audit.Create(ctx, inv); aerr != nil {
+		log.Printf("ai tool audit: %v", aerr)
+		return content, 0
+	}
+	return content, inv.ID
+}
+
+// Link attaches the audit rows of one reply to the stored conversation turn.
+func (t *AIToolbox) Link(ctx context.Context, ids []uint, conversationID, messageID uint) {
+	if t.audit == nil || len(ids) == 0 {
+		return
+	}
+	if err := t.audit.Link(ctx, ids, conversationID, messageID); err != nil {
+		log.Printf("ai tool audit link: %v", err)
+	}
+}
+
+func (t *AIToolbox) trackingStatus(ctx context.Context, userID uint, _ json.RawMessage) (any, error) {
+	st, err := t.tracking.GetMyTracking(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	weights := st.WeightHistory
+	if len(weights) > aiToolRecentWeighIns {
+		weights = weights[len(weights)-aiToolRecentWeighIns:]
+	}
+	// Photo URLs stay out of the model context.
+	return map[string]any{
+		"nextDueDate":     st.NextDueDate,
+		"frequencyDays":   st.FrequencyDays,
+		"weightSubmitted": st.WeightSubmitted,
+		"photosSubmitted": st.PhotosSubmitted,
+		"alerts":          st.Alerts,
+		"lastWeightKg":    st.LastWeightKg,
+		"recentWeights":   weights,
+	}, nil
+}
+
+type aiToolProgramDay struct {
+	Day       string   `json:"day"`
+	Workout   string   `json:"workout,omitempty"`
+	Exercises []string `json:"exercises,omitempty"`
+	Targets   []string `json:"targets,omitempty"`
+}
+
+func (t *AIToolbox) myPrograms(ctx context.Context, userID uint, raw json.RawMessage) (any, error) {
+	var args struct {
+		ProgramID uint `json:"program_id"`
+	}
+	_ = json.Unmarshal(raw, &args)
+	if args.ProgramID == 0 {
+		res, err := t.me.ListMyPrograms(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]map[string]any, 0, len(res.Programs))
+		for _, p := range res.Programs {
+			items = append(items, map[string]any{
+				"id":            p.ID,
+				"title":         p.Title,
+				"type":          p.Type,
+				"status":        p.Status,
+				"startDate":     p.StartDate.Format("2006-01-02"),
+				"durationDays":  p.DurationDays,
+				"remainingDays": p.RemainingDays,
+				"coachName":     p.CoachName,
+			})
+		}
+		return map[string]any{"programs": items}, nil
+	}
+
+	p, err := t.me.GetMyProgramByID(ctx, userID, args.ProgramID)
+	if err != nil {
+		return nil, err
+	}
+	// Only what the coach scheduled and which exercises go where; sets, reps and
+	// nutrition details are left out so the model has nothing to re-prescribe.
+	out := map[string]any{"id": p.ID, "title": p.Title, "status": p.Status, "remainingDays": p.RemainingDays}
+	if p.Schedule != nil {
+		out["weeklyWorkoutDays"] = p.Schedule.Weekly
+		out["restDays"] = p.Schedule.RestDays
+	}
+	days := make([]aiToolProgramDay, 0, len(p.PlanByDay))
+	for _, day := range aiToolDayOrder(p) {
+		plan := p.PlanByDay[day]
+		if plan.Workout == nil {
+			continue
+		}
+		d := aiToolProgramDay{Day: day, Workout: plan.Workout.Title}
+		for _, ex := range plan.Workout.Exercises {
+			d.Exercises = append(d.Exercises, ex.Name)
+			if ex.Target != "" && !containsFold(d.Targets, ex.Target) {
+				d.Targets = append(d.Targets, ex.Target)
+			}
+		}
+		days = append(days, d)
+	}
+	out["days"] = days
+	return out, nil
+}
+
+// aiToolDayOrder lists plan days in schedule order, then any the schedule omits.
+func aiToolDayOrder(p *MeProgramDetailDTO) []string {
+	seen := map[string]bool{}
+	var order []string
+	if p.Schedule != nil {
+		for _, d := range append(append([]string{}, p.Schedule.Weekly...), p.Schedule.RestDays...) {
+			if _, ok := p.PlanByDay[d]; ok && !seen[d] {
+				seen[d] = true
+				order = append(order, d)
+			}
+		}
+	}
+	rest := make([]string, 0, len(p.PlanByDay))
+	for d := range p.PlanByDay {
+		if !seen[d] {
+			rest = append(rest, d)
+		}
+	}
+	slices.Sort(rest)
+	return append(order, rest...)
+}
+
+func (t *AIToolbox) personalRecords(ctx context.Context, userID uint, raw json.RawMessage) (any, error) {
+	var args struct {
+		Limit int `json:"limit"`
+	}
+	_ = json.Unmarshal(raw, &args)
+	if args.Limit <= 0 || args.Limit > aiToolRecordsMax {
+		args.Limit = aiToolRecordsMax
+	}
+	prs, err := t.dashboard.PersonalRecords(ctx, userID, args.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"records": prs}, nil
+}
+
+func (t *AIToolbox) orderStatus(ctx context.Context, userID uint, raw json.RawMessage) (any, error) {
+	var args struct {
+		OrderID uint `json:"order_id"`
+	}
+	_ = json.Unmarshal(raw, &args)
+	if args.OrderID > 0 {
+		o, err := t.me.GetMyOrderByID(ctx, userID, args.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		return aiToolOrder(o), nil
+	}
+	res, err := t.me.ListMyOrders(ctx, userID, 1, aiToolRecentOrders, "")
+	if err != nil {
+		return nil, err
+	}
+	items := make([]map[string]any, 0, len(res.Items))
+	for i := range res.Items {
+		items = append(items, aiToolOrder(&res.Items[i]))
+	}
+	return map[string]any{"orders": items, "total": res.Total}, nil
+}
+
+func aiToolOrder(o *MeOrderDTO) map[string]any {
+	titles := make([]string, 0, len(o.Items))
+	for _, it := range o.Items {
+		titles = append(titles, it.Title)
+	}
+	return map[string]any{
+		"id":           o.ID,
+		"createdAt":    o.CreatedAt.Format("2006-01-02"),
+		"status":       o.Status,
+		"trackingCode": o.TrackingCode,
+		"items":        titles,
+		"coachName":    o.CoachName,
+	}
+}
+
+// aiToolJSON encodes a tool result, truncating it to aiToolResultMaxBytes.
+func aiToolJSON(v any) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return `{"error":"encode failed"}`
+	}
+	if len(raw) <= aiToolResultMaxBytes {
+		return string(raw)
+	}
+	cut := aiToolResultMaxBytes
+	for cut > 0 && !utf8.RuneStart(raw[cut]) {
+		cut--
+	}
+	return string(raw[:cut]) + "…(truncated)"
+}
+
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+type stubTrackingService struct {
+	TrackingService
+	userIDs []uint
+}
+
+func (s *stubTrackingService) GetMyTracking(ctx context.Context, userID uint) (*TrackingStatusDTO, error) {
+	s.userIDs = append(s.userIDs, userID)
+	return &TrackingStatusDTO{NextDueDate: "2026-11-02", FrequencyDays: 14}, nil
+}
+
+type memoryAIToolInvocationRepo struct {
+	repository.AIToolInvocationRepository
+	rows []models.AIToolInvocation
+}
+
+func (r *memoryAIToolInvocationRepo) Create(ctx context.Context, inv *models.AIToolInvocation) error {
+	inv.ID = uint(len(r.rows) + 1)
+	r.rows = append(r.rows, *inv)
+	return nil
+}
+
+func (r *memoryAIToolInvocationRepo) Link(ctx context.Context, ids []uint, conversationID, messageID uint) error {
+	for _, id := range ids {
+		r.rows[id-1].ConversationID, r.rows[id-1].MessageID = &conversationID, &messageID
+	}
+	return nil
+}
+
+func TestChatToolCallIsScopedAndAudited(t *testing.T) {
+	tracking := &stubTrackingService{}
+	audit := &memoryAIToolInvocationRepo{}
+	provider := NewFakeLLMProvider("چک‌این بعدی شما ۲ نوامبر است.")
+	// The model tries to read someone else's data; the user ID must come from the session.
+	provider.QueueToolCalls(LLMToolCall{ID: "call_1", Name: AIToolTrackingStatus, Arguments: `{"user_id": 99}`})
+	svc := &AIChatService{
+		meService: stubMeService{},
+		convRepo:  &memoryAIConversationRepo{},
+		tools:     NewAIToolbox(tracking, nil, nil, audit),
+		provider:  provider,
+	}
+
+	resp, err := svc.Chat(context.Background(), 7, &AIChatRequest{Message: "چک‌این بعدی من کی است؟"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracking.userIDs) != 1 || tracking.userIDs[0] != 7 {
+		t.Fatalf("tool must run for the session user only, got %v", tracking.userIDs)
+	}
+	if len(audit.rows) != 1 {
+		t.Fatalf("expected one audited invocation, got %d", len(audit.rows))
+	}
+	inv := audit.rows[0]
+	if inv.UserID != 7 || inv.Tool != AIToolTrackingStatus || inv.Status != models.AIToolStatusOK {
+		t.Fatalf("unexpected audit row %+v", inv)
+	}
+	if inv.MessageID == nil || *inv.MessageID != resp.MessageID || inv.ConversationID == nil || *inv.ConversationID != resp.ConversationID {
+		t.Fatalf("audit row should be linked to the stored reply, got %+v", inv)
+	}
+
+	// Second request carries the tool result back to the model.
+	if len(provider.Requests) != 2 {
+		t.Fatalf("expected a follow-up request after the tool call, got %d", len(provider.Requests))
+	}
+	msgs := provider.Requests[1].Messages
+	last := msgs[len(msgs)-1]
+	if last.Role != "tool" || last.ToolCallID != "call_1" || !strings.Contains(last.Content, "2026-11-02") {
+		t.Fatalf("tool result not passed back, got %+v", last)
+	}
+}
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+var (
+	ErrAIQuotaExceeded    = errors.New("ai quota exceeded")
+	ErrAIInvalidDateRange = errors.New("invalid ai usage date range")
+)
+
+const (
+	AIQuotaTierFree = "free"
+	AIQuotaTierPaid = "paid"
+
+	// AIQuotaUnlimited as a limit disables the check.
+	AIQuotaUnlimited = -1
+
+	aiUsageDefaultDays = 30
+	aiUsageMaxDays     = 366
+	aiDayLayout        = "2006-01-02"
+	aiMonthLayout      = "2006-01"
+)
+
+// AIQuotaDTO is the user's assistant allowance for GET /me/ai/quota.
+type AIQuotaDTO struct {
+	Tier           string    `json:"tier"` // free | paid
+	PlanID         uint      `json:"planId,omitempty"`
+	PlanName       string    `json:"planName,omitempty"`
+	DailyLimit     int       `json:"dailyLimit"` // -1 = unlimited
+	DailyUsed      int64     `json:"dailyUsed"`
+	MonthlyLimit   int       `json:"monthlyLimit"`
+	MonthlyUsed    int64     `json:"monthlyUsed"`
+	DailyResetAt   time.Time `json:"dailyResetAt"`
+	MonthlyResetAt time.Time `json:"monthlyResetAt"`
+}
+
+type AIUsageTotalsDTO struct {
+	Messages         int64   `json:"messages"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+type AIUsageDayDTO struct {
+	Date  string `json:"date"`
+	Users int64  `json:"users"`
+	AIUsageTotalsDTO
+}
+
+// AIUsageCoachDTO is the usage of one coach cohort: users whose active
+// subscription was with that coach. CoachID 0 covers free users and platform plans.
+type AIUsageCoachDTO struct {
+	CoachID   uint   `json:"coachId"`
+	CoachName string `json:"coachName"`
+	Users     int64  `json:"users"`
+	AIUsageTotalsDTO
+}
+
+// AIUsageDashboard is the admin AI cost view for GET /admin/ai/usage.
+type AIUsageDashboard struct {
+	From                     string            `json:"from"`
+	To                       string            `json:"to"`
+	Totals                   AIUsageTotalsDTO  `json:"totals"`
+	ByDay                    []AIUsageDayDTO   `json:"byDay"`
+	ByCoach                  []AIUsageCoachDTO `json:"byCoach"`
+	PromptCostPerMillion     float64           `json:"promptCostPerMillion"`
+	CompletionCostPerMillion float64           `json:"completionCostPerMillion"`
+}
+
+// aiQuotaContext is the plan a chat turn was admitted under; it is stored with
+// the usage row. usageID is the row admit reserved for the turn and periods the
+// quota counters it took the turn from.
+type aiQuotaContext struct {
+	planID  uint
+	coachID uint
+	usageID uint
+	periods []string
+}
+
+// aiTurnUsage is what one assistant turn consumed.
+type aiTurnUsage struct {
+	feature          string // models.AIUsageFeature*; empty means chat
+	provider, model  string
+	promptTokens     int
+	completionTokens int
+	estimated        bool
+}
+
+// add accounts one model call. Calls whose usage the provider did not report
+// (e.g. a stream cut short by a guardrail) are estimated locally.
+func (u *aiTurnUsage) add(req LLMRequest, resp *LLMResponse) {
+	if resp.PromptTokens > 0 || resp.CompletionTokens > 0 {
+		u.promptTokens += resp.PromptTokens
+		u.completionTokens += resp.CompletionTokens
+		return
+	}
+	for _, m := range req.Messages {
+		u.promptTokens += estimateAITokens(m.Content)
+	}
+	u.completionTokens += estimateAITokens(resp.Content)
+	u.estimated = true
+}
+
+type AIUsageService interface {
+	// admit checks the per-minute rate limit and reserves the turn against the
+	// daily/monthly quotas. It returns ErrAIRateLimited or ErrAIQuotaExceeded.
+	admit(ctx context.Context, userID uint) (*aiQuotaContext, error)
+	record(ctx context.Context, userID uint, qc *aiQuotaContext, conversationID, messageID uint, usage aiTurnUsage)
+	// release gives back the reservation of a turn that was not answered.
+	release(ctx context.Context, qc *aiQuotaContext)
+	Quota(ctx context.Context, userID uint) (*AIQuotaDTO, error)
+	Dashboard(ctx context.Context, from, to string) (*AIUsageDashboard, error)
+}
+
+type aiUsageService struct {
+	usageRepo repository.AIUsageRepository
+	subRepo   repository.SubscriptionRepository
+	planRepo  repository.ServicePlanRepository
+	coachRepo repository.CoachProfileRepository
+	limiter   RateLimiter
+}
+
+func NewAIUsageService(usageRepo repository.AIUsageRepository, subRepo repository.SubscriptionRepository, planRepo repository.ServicePlanRepository, coachRepo repository.CoachProfileRepository, limiter RateLimiter) AIUsageService {
+	return &aiUsageService{usageRepo: usageRepo, subRepo: subRepo, planRepo: planRepo, coachRepo: coachRepo, limiter: limiter}
+}
+
+func (s *aiUsageService) admit(ctx context.Context, userID uint) (*aiQuotaContext, error) {
+	cfg := config.Get()
+	if s.limiter != nil {
+		ok, err := s.limiter.Allow(ctx, "ai:user:"+strconv.FormatUint(uint64(userID), 10), cfg.AI.RatePerMinute, time.Minute)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrAIRateLimited
+		}
+	}
+	quota, qc, err := s.quota(ctx, userID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// The counts in quota are only a snapshot; Reserve takes the turn from
+	// the period counters with conditional updates so parallel requests
+	// cannot both pass.
+	now := time.Now()
+	dayStart, monthStart := aiQuotaPeriods(now)
+	var limits []repository.AIUsageLimit
+	if quota.DailyLimit != AIQuotaUnlimited {
+		limits = append(limits, repository.AIUsageLimit{Period: dayStart.Format(aiDayLayout), Since: dayStart, Max: int64(quota.DailyLimit)})
+	}
+	if quota.MonthlyLimit != AIQuotaUnlimited {
+		limits = append(limits, repository.AIUsageLimit{Period: monthStart.Format(aiMonthLayout), Since: monthStart, Max: int64(quota.MonthlyLimit)})
+	}
+	row := &models.AIUsage{
+		UserID:        userID,
+		CoachID:       qc.coachID,
+		ServicePlanID: qc.planID,
+		Day:           now.Format(aiDayLayout),
+		Feature:       models.AIUsageFeatureChat,
+	}
+	ok, err := s.usageRepo.Reserve(ctx, row, limits...)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrAIQuotaExceeded
+	}
+	qc.usageID = row.ID
+	for _, l := range limits {
+		qc.periods = append(qc.periods, l.Period)
+	}
+	return qc, nil
+}
+
+// record stores the accounting row of an answered turn, filling in the row admit
+// reserved when there is one. Failures are logged: the user already has the reply.
+func (s *aiUsageService) record(ctx context.Context, userID uint, qc *aiQuotaContext, conversationID, messageID uint, usage aiTurnUsage) {
+	cfg := config.Get()
+	row := &models.AIUsage{
+		UserID:           userID,
+		ConversationID:   conversationID,
+		MessageID:        messageID,
+		Day:              time.Now().Format(aiDayLayout),
+		Feature:          usage.feature,
+		Provider:         usage.provider,
+		Model:            usage.model,
+		PromptTokens:     usage.promptTokens,
+		CompletionTokens: usage.completionTokens,
+		TokensEstimated:  usage.estimated,
+		CostMicroUSD:     aiCostMicroUSD(usage.promptTokens, usage.completionTokens, cfg.OpenAI.PromptCostPerMillion, cfg.OpenAI.CompletionCostPerMillion),
+	}
+	if row.Feature == "" {
+		row.Feature = models.AIUsageFeatureChat
+	}
+	if qc != nil {
+		row.ServicePlanID, row.CoachID = qc.planID, qc.coachID
+	}
+	var err error
+	if qc != nil && qc.usageID != 0 {
+		row.ID = qc.usageID
+		err = s.usageRepo.Complete(ctx, row)
+	} else {
+		err = s.usageRepo.Create(ctx, row)
+	}
+	if err != nil {
+		log.Printf("ai usage: record user %d: %v", userID, err)
+	}
+}
+
+func (s *aiUsageService) release(ctx context.Context, qc *aiQuotaContext) {
+	if qc == nil || qc.usageID == 0 {
+		return
+	}
+	if err := s.usageRepo.Release(ctx, qc.usageID, qc.periods...); err != nil {
+		log.Printf("ai usage: release reservation %d: %v", qc.usageID, err)
+	}
+}
+
+// aiCostMicroUSD prices a turn. A price per million tokens in USD is exactly the
+// price per token in micro-dollars.
+func aiCostMicroUSD(promptTokens, completionTokens int, promptPerMillion, completionPerMillion float64) int64 {
+	return int64(math.Round(float64(promptTokens)*promptPerMillion + float64(completionTokens)*completionPerMillion))
+}
+
+func (s *aiUsageService) Quota(ctx context.Context, userID uint) (*AIQuotaDTO, error) {
+	quota, _, err := s.quota(ctx, userID, config.Get())
+	return quota, err
+}
+
+func (s *aiUsageService) quota(ctx context.Context, userID uint, cfg config.Config) (*AIQuotaDTO, *aiQuotaContext, error) {
+	now := time.Now()
+	out := &AIQuotaDTO{
+		Tier:         AIQuotaTierFree,
+		DailyLimit:   cfg.AI.FreeDailyMessages,
+		MonthlyLimit: cfg.AI.FreeMonthlyMessages,
+	}
+	qc := &aiQuotaContext{}
+
+	sub, err := s.subRepo.FindCurrentByUserID(ctx, userID, now)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+	if sub != nil {
+		out.Tier = AIQuotaTierPaid
+		out.DailyLimit, out.MonthlyLimit = cfg.AI.PaidDailyMessages, cfg.AI.PaidMonthlyMessages
+		qc.planID, qc.coachID = sub.ServicePlanID, sub.CoachID
+		if plan, err := s.planRepo.FindByID(ctx, sub.ServicePlanID); err == nil {
+			out.PlanID, out.PlanName = plan.ID, plan.Name
+			if plan.AIDailyMessages != 0 {
+				out.DailyLimit = plan.AIDailyMessages
+			}
+			if plan.AIMonthlyMessages != 0 {
+				out.MonthlyLimit = plan.AIMonthlyMessages
+			}
+			if qc.coachID == 0 {
+				qc.coachID = plan.CoachID
+			}
+		}
+	}
+	out.DailyLimit, out.MonthlyLimit = clampAIQuota(out.DailyLimit), clampAIQuota(out.MonthlyLimit)
+
+	dayStart, monthStart := aiQuotaPeriods(now)
+	out.DailyResetAt, out.MonthlyResetAt = dayStart.AddDate(0, 0, 1), monthStart.AddDate(0, 1, 0)
+	if out.DailyUsed, err = s.usageRepo.CountByUserSince(ctx, userID, dayStart); err != nil {
+		return nil, nil, err
+	}
+	if out.MonthlyUsed, err = s.usageRepo.CountByUserSince(ctx, userID, monthStart); err != nil {
+		return nil, nil, err
+	}
+	return out, qc, nil
+}
+
+// aiQuotaPeriods returns the start of the current quota day and month.
+func aiQuotaPeriods(now time.Time) (dayStart, monthStart time.Time) {
+	dayStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return dayStart, monthStart
+}
+
+func (s *aiUsageService) Dashboard(ctx context.Context, from, to string) (*AIUsageDashboard, error) {
+	fromDay, toDay, err := parseAIUsageRange(from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.usageRepo.TotalsByDay(ctx, fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+	coaches, err := s.usageRepo.TotalsByCoach(ctx, fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.Get()
+	out := &AIUsageDashboard{
+		From:                     fromDay,
+		To:                       toDay,
+		ByDay:                    make([]AIUsageDayDTO, 0, len(days)),
+		ByCoach:                  make([]AIUsageCoachDTO, 0, len(coaches)),
+		PromptCostPerMillion:     cfg.OpenAI.PromptCostPerMillion,
+		CompletionCostPerMillion: cfg.OpenAI.CompletionCostPerMillion,
+	}
+	var totalMicros int64
+	for _, d := range days {
+		out.ByDay = append(out.ByDay, AIUsageDayDTO{
+			Date:             d.Day,
+			Users:            d.Users,
+			AIUsageTotalsDTO: aiUsageTotals(d.Messages, d.PromptTokens, d.CompletionTokens, d.CostMicroUSD),
+		})
+		out.Totals.Messages += d.Messages
+		out.Totals.PromptTokens += d.PromptTokens
+		out.Totals.CompletionTokens += d.CompletionTokens
+		totalMicros += d.CostMicroUSD
+	}
+	out.Totals.CostUSD = microToUSD(totalMicros)
+	for _, c := range coaches {
+		out.ByCoach = append(out.ByCoach, AIUsageCoachDTO{
+			CoachID:          c.CoachID,
+			CoachName:        s.coachName(ctx, c.CoachID),
+			Users:            c.Users,
+			AIUsageTotalsDTO: aiUsageTotals(c.Messages, c.PromptTokens, c.CompletionTokens, c.CostMicroUSD),
+		})
+	}
+	return out, nil
+}
+
+func (s *aiUsageService) coachName(ctx context.Context, coachID uint) string {
+	if coachID == 0 {
+		return "بدون مربی"
+	}
+	profile, err := s.coachRepo.FindByUserID(ctx, coachID)
+	if err != nil || profile == nil {
+		return ""
+	}
+	return profile.DisplayName
+}
+
+func aiUsageTotals(messages, promptTokens, completionTokens, costMicros int64) AIUsageTotalsDTO {
+	return AIUsageTotalsDTO{
+		Messages:         messages,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		CostUSD:          microToUSD(costMicros),
+	}
+}
+
+func microToUSD(micros int64) float64 {
+	return roundTo(float64(micros)/1e6, 0.0001)
+}
+
+// parseAIUsageRange validates an inclusive YYYY-MM-DD range, defaulting to the
+// last aiUsageDefaultDays days.
+func parseAIUsageRange(from, to string, now time.Time) (string, string, error) {
+	end := now
+	if strings.TrimSpace(to) != "" {
+		t, err := time.ParseInLocation(aiDayLayout, strings.TrimSpace(to), now.Location())
+		if err != nil {
+			return "", "", ErrAIInvalidDateRange
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -(aiUsageDefaultDays - 1))
+	if strings.TrimSpace(from) != "" {
+		t, err := time.ParseInLocation(aiDayLayout, strings.TrimSpace(from), now.Location())
+		if err != nil {
+			return "", "", ErrAIInvalidDateRange
+		}
+		start = t
+	}
+	fromDay, toDay := start.Format(aiDayLayout), end.Format(aiDayLayout)
+	if fromDay > toDay || end.Sub(start) > aiUsageMaxDays*24*time.Hour {
+		return "", "", ErrAIInvalidDateRange
+	}
+	return fromDay, toDay, nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+type memoryAIUsageRepo struct {
+	repository.AIUsageRepository
+	rows []models.AIUsage
+}
+
+func (r *memoryAIUsageRepo) Create(ctx context.Context, usage *models.AIUsage) error {
+	usage.CreatedAt = time.Now()
+	r.rows = append(r.rows, *usage)
+	return nil
+}
+
+func (r *memoryAIUsageRepo) Reserve(ctx context.Context, usage *models.AIUsage, limits ...repository.AIUsageLimit) (bool, error) {
+	for _, l := range limits {
+		if n, _ := r.CountByUserSince(ctx, usage.UserID, l.Since); n >= l.Max {
+			return false, nil
+		}
+	}
+	usage.ID = uint(len(r.rows) + 1)
+	return true, r.Create(ctx, usage)
+}
+
+func (r *memoryAIUsageRepo) Complete(ctx context.Context, usage *models.AIUsage) error {
+	for i := range r.rows {
+		if r.rows[i].ID == usage.ID {
+			r.rows[i].PromptTokens, r.rows[i].CompletionTokens = usage.PromptTokens, usage.CompletionTokens
+		}
+	}
+	return nil
+}
+
+func (r *memoryAIUsageRepo) CountByUserSince(ctx context.Context, userID uint, since time.Time) (int64, error) {
+	var n int64
+	for _, u := range r.rows {
+		if u.UserID == userID && !u.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type stubSubscriptionRepo struct {
+	repository.SubscriptionRepository
+	sub *models.Subscription
+}
+
+func (r stubSubscriptionRepo) FindCurrentByUserID(ctx context.Context, userID uint, now time.Time) (*models.Subscription, error) {
+	if r.sub == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.sub, nil
+}
+
+type stubServicePlanRepo struct {
+	repository.ServicePlanRepository
+	plan *models.ServicePlan
+}
+
+func (r stubServicePlanRepo) FindByID(ctx context.Context, id uint) (*models.ServicePlan, error) {
+	return r.plan, nil
+}
+
+func TestAIUsageQuotaByPlan(t *testing.T) {
+	ctx := context.Background()
+	usage := &memoryAIUsageRepo{}
+
+	// Free users get the config default (5 a day).
+	free := &aiUsageService{usageRepo: usage, subRepo: stubSubscriptionRepo{}}
+	for i := 0; i < 5; i++ {
+		qc, err := free.admit(ctx, 1)
+		if err != nil {
+			t.Fatalf("message %d: %v", i+1, err)
+		}
+		free.record(ctx, 1, qc, 1, uint(i+1), aiTurnUsage{promptTokens: 1000, completionTokens: 200})
+	}
+	if _, err := free.admit(ctx, 1); !errors.Is(err, ErrAIQuotaExceeded) {
+		t.Fatalf("6th free message should exceed the daily quota, got %v", err)
+	}
+	if len(usage.rows) != 5 || usage.rows[4].PromptTokens != 1000 {
+		t.Fatalf("recorded turns should fill in their reservations, got %+v", usage.rows)
+	}
+
+	// A VIP plan with unlimited daily messages is not blocked by the same usage.
+	plan := &models.ServicePlan{AIDailyMessages: AIQuotaUnlimited, AIMonthlyMessages: 100, CoachID: 9}
+	plan.ID = 3
+	vip := &aiUsageService{
+		usageRepo: usage,
+		subRepo:   stubSubscriptionRepo{sub: &models.Subscription{ServicePlanID: 3}},
+		planRepo:  stubServicePlanRepo{plan: plan},
+	}
+	qc, err := vip.admit(ctx, 1)
+	if err != nil {
+		t.Fatalf("vip plan should be admitted: %v", err)
+	}
+	if qc.planID != 3 || qc.coachID != 9 {
+		t.Fatalf("usage should be attributed to the plan's coach cohort, got %+v", qc)
+	}
+	// The admitted turn is already reserved.
+	quota, err := vip.Quota(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.Tier != AIQuotaTierPaid || quota.DailyLimit != AIQuotaUnlimited || quota.MonthlyLimit != 100 || quota.DailyUsed != 6 {
+		t.Fatalf("unexpected quota %+v", quota)
+	}
+}
+
+// Parallel turns of a free user stop at the daily quota, and failed turns give
+// their reservation back.
+func TestAIUsageAdmitConcurrent(t *testing.T) {
+	ctx := context.Background()
+	db, err := config.NewSQLiteGORM(filepath.Join(t.TempDir(), "ai.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models.AllModels()...); err != nil {
+		t.Fatal(err)
+	}
+	svc := &aiUsageService{usageRepo: repository.NewAIUsageRepository(db), subRepo: stubSubscriptionRepo{}}
+
+	var admitted atomic.Int32
+	var mu sync.Mutex
+	var last *aiQuotaContext
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			qc, err := svc.admit(ctx, 1)
+			if errors.Is(err, ErrAIQuotaExceeded) {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			admitted.Add(1)
+			svc.record(ctx, 1, qc, 1, 1, aiTurnUsage{promptTokens: 10, completionTokens: 5})
+			mu.Lock()
+			last = qc
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if admitted.Load() != 5 {
+		t.Fatalf("%d parallel turns admitted, want the daily quota of 5", admitted.Load())
+	}
+
+	var tokens int64
+	if err := db.Model(&models.AIUsage{}).Select("COALESCE(SUM(prompt_tokens), 0)").Scan(&tokens).Error; err != nil {
+		t.Fatal(err)
+	}
+	if tokens != 50 {
+		t.Fatalf("recorded prompt tokens %d, want 50", tokens)
+	}
+
+	var counters []models.AIUsageCounter
+	if err := db.Find(&counters).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(counters) != 2 || counters[0].Used != 5 || counters[1].Used != 5 {
+		t.Fatalf("day and month counters %+v, want 5 turns each", counters)
+	}
+
+	svc.release(ctx, last)
+	qc, err := svc.admit(ctx, 1)
+	if err != nil {
+		t.Fatalf("a released turn should free its quota: %v", err)
+	}
+	if qc.usageID == 0 {
+		t.Fatal("admit should reserve a usage row")
+	}
+}
+
+func TestMemoryRateLimiter(t *testing.T) {
+	l := NewMemoryRateLimiter()
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow(context.Background(), "k", 3, time.Hour); !ok {
+			t.Fatalf("hit %d should be allowed", i+1)
+		}
+	}
+	if ok, _ := l.Allow(context.Background(), "k", 3, time.Hour); ok {
+		t.Fatal("4th hit should be limited")
+	}
+	if ok, _ := l.Allow(context.Background(), "other", 3, time.Hour); !ok {
+		t.Fatal("keys are limited independently")
+	}
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+var ErrAuditInvalidRange = errors.New("invalid audit log date range")
+
+const (
+	// auditExportMaxRows caps one CSV export; narrow the filters for more.
+	auditExportMaxRows = 10000
+	auditExportBatch   = 500
+	auditRedacted      = "[redacted]"
+)
+
+// auditIgnoredFields never count as a change: they move on every save.
+var auditIgnoredFields = map[string]bool{
+	"CreatedAt": true, "UpdatedAt": true, "DeletedAt": true,
+	"createdAt": true, "updatedAt": true, "deletedAt": true,
+}
+
+// AuditEntry is the audit record of the request in progress. The AuditTrail
+// middleware attaches one to the request context and saves it once the
+// handler returns; services describe what they changed with AuditChange.
+type AuditEntry struct {
+	ActorID        uint
+	ActorRole      string
+	ImpersonatorID uint
+	Action         string
+	TargetType     string
+	TargetID       string
+	Before         map[string]any
+	After          map[string]any
+
+	Method    string
+	Path      string
+	Status    int
+	IP        string
+	UserAgent string
+}
+
+type auditEntryKey struct{}
+
+// WithAuditEntry returns ctx carrying entry for AuditChange.
+func WithAuditEntry(ctx context.Context, entry *AuditEntry) context.Context {
+	return context.WithValue(ctx, auditEntryKey{}, entry)
+}
+
+// AuditEntryFrom returns the entry attached by WithAuditEntry, or nil.
+func AuditEntryFrom(ctx context.Context) *AuditEntry {
+	entry, _ := ctx.Value(auditEntryKey{}).(*AuditEntry)
+	return entry
+}
+
+// AuditChange notes that the target changed from before to after on the
+// request's audit entry. before is nil for creations and after for deletions.
+// Both are compared as JSON objects and only differing top-level fields are
+// kept. Without an entry in ctx (jobs, public routes) it does nothing.
+func AuditChange(ctx context.Context, targetType string, targetID uint, before, after any) {
+	entry := AuditEntryFrom(ctx)
+	if entry == nil {
+		return
+	}
+	entry.TargetType = targetType
+	entry.TargetID = strconv.FormatUint(uint64(targetID), 10)
+	entry.Before, entry.After = auditDiff(auditFields(before), auditFields(after))
+}
+
+// auditFields flattens v to its top-level JSON fields with secrets redacted.
+func auditFields(v any) map[string]any {
+	if v == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	for k := range fields {
+		if auditIgnoredFields[k] {
+			delete(fields, k)
+		} else if strings.Contains(strings.ToLower(k), "password") {
+			fields[k] = auditRedacted
+		}
+	}
+	return fields
+}
+
+// auditDiff keeps the fields whose values differ between before and after.
+// A nil side (creation or deletion) keeps every field of the other.
+func auditDiff(before, after map[string]any) (map[string]any, map[string]any) {
+	if before == nil || after == nil {
+		return before, after
+	}
+	b, a := map[string]any{}, map[string]any{}
+	for k, v := range before {
+		if w, ok := after[k]; !ok || !reflect.DeepEqual(v, w) {
+			b[k] = v
+		}
+	}
+	for k, w := range after {
+		if v, ok := before[k]; !ok || !reflect.DeepEqual(v, w) {
+			a[k] = w
+		}
+	}
+	return b, a
+}
+
+// AuditLogQuery holds the /admin/audit-log filters. From and To are
+// YYYY-MM-DD days, both inclusive.
+type AuditLogQuery struct {
+	ActorID        uint
+	ActorRole      string
+	ImpersonatorID uint
+	Action         string
+	TargetType     string
+	TargetID       string
+	From           string
+	To             string
+	Query          string
+}
+
+// AuditEventDTO is one row of the admin audit log.
+type AuditEventDTO struct {
+	ID             uint            `json:"id"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	ActorID        uint            `json:"actorId"`
+	ActorName      string          `json:"actorName"`
+	ActorRole      string          `json:"actorRole"`
+	ImpersonatorID *uint           `json:"impersonatorId,omitempty"`
+	Action         string          `json:"action"`
+	TargetType     string          `json:"targetType"`
+	TargetID       string          `json:"targetId"`
+	Before         json.RawMessage `json:"before,omitempty"`
+	After          json.RawMessage `json:"after,omitempty"`
+	Method         string          `json:"method"`
+	Path           string          `json:"path"`
+	Status         int             `json:"status"`
+	IP             string          `json:"ip"`
+	UserAgent      string          `json:"userAgent"`
+}
+
+type AuditEventListResponse struct {
+	Items    []AuditEventDTO `json:"items"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"pageSize"`
+	Total    int64           `json:"total"`
+}
+
+type AuditService interface {
+	// Record stores entry. The middleware calls it after the handler ran.
+	Record(ctx context.Context, entry *AuditEntry) error
+	List(ctx context.Context, q AuditLogQuery, page, pageSize int) (*AuditEventListResponse, error)
+	// ExportCSV writes the events matching q, newest first, as CSV to w.
+	ExportCSV(ctx context.Context, q AuditLogQuery, w io.Writer) error
+}
+
+type auditService struct {
+	repo     repository.AuditEventRepository
+	userRepo repository.UserRepository
+}
+
+func NewAuditService(repo repository.AuditEventRepository, userRepo repository.UserRepository) AuditService {
+	return &auditService{repo: repo, userRepo: userRepo}
+}
+
+func (s *auditService) Record(ctx context.Context, entry *AuditEntry) error {
+	event := &models.AuditEvent{
+		ActorID:    entry.ActorID,
+		ActorRole:  entry.ActorRole,
+		Action:     truncateRunes(entry.Action, 160),
+		TargetType: truncateRunes(entry.TargetType, 60),
+		TargetID:   truncateRunes(entry.TargetID, 64),
+		Method:     entry.Method,
+		Path:       truncateRunes(entry.Path, 255),
+		Status:     entry.Status,
+		IP:         truncateRunes(entry.IP, 64),
+		UserAgent:  truncateRunes(entry.UserAgent, 255),
+	}
+	if entry.ImpersonatorID > 0 {
+		id := entry.ImpersonatorID
+		event.ImpersonatorID = &id
+	}
+	var err error
+	if event.Before, err = auditJSON(entry.Before); err != nil {
+		return err
+	}
+	if event.After, err = auditJSON(entry.After); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, event)
+}
+
+func auditJSON(fields map[string]any) (string, error) {
+	if fields == nil {
+		return "", nil
+	}
+	raw, err := json.Marshal(fields)
+	return string(raw), err
+}
+
+func (s *auditService) List(ctx context.Context, q AuditLogQuery, page, pageSize int) (*AuditEventListResponse, error) {
+	filter, err := auditFilter(q, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	list, total, err := s.repo.List(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	out := &AuditEventListResponse{Items: make([]AuditEventDTO, 0, len(list)), Page: page, PageSize: pageSize, Total: total}
+	names := map[uint]string{}
+	for _, e := range list {
+		out.Items = append(out.Items, s.toDTO(ctx, e, names))
+	}
+	return out, nil
+}
+
+func (s *auditService) ExportCSV(ctx context.Context, q AuditLogQuery, w io.Writer) error {
+	filter, err := auditFilter(q, time.Now())
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"id", "createdAt", "actorId", "actorName", "actorRole", "impersonatorId", "action", "targetType", "targetId",
+		"before", "after", "method", "path", "status", "ip", "userAgent",
+	}); err != nil {
+		return err
+	}
+	names := map[uint]string{}
+	written := 0
+	for page := 1; written < auditExportMaxRows; page++ {
+		list, _, err := s.repo.List(ctx, filter, page, auditExportBatch)
+		if err != nil {
+			return err
+		}
+		for _, e := range list {
+			if written == auditExportMaxRows {
+				break
+			}
+			d := s.toDTO(ctx, e, names)
+			impersonator := ""
+			if d.ImpersonatorID != nil {
+				impersonator = strconv.FormatUint(uint64(*d.ImpersonatorID), 10)
+			}
+			if err := cw.Write([]string{
+				strconv.FormatUint(uint64(d.ID), 10),
+				d.CreatedAt.Format(time.RFC3339),
+				strconv.FormatUint(uint64(d.ActorID), 10),
+				d.ActorName,
+				d.ActorRole,
+				impersonator,
+				d.Action,
+				d.TargetType,
+				d.TargetID,
+				string(d.Before),
+				string(d.After),
+				d.Method,
+				d.Path,
+				strconv.Itoa(d.Status),
+				d.IP,
+				d.UserAgent,
+			}); err != nil {
+				return err
+			}
+			written++
+		}
+		if len(list) < auditExportBatch {
+			break
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (s *auditService) toDTO(ctx context.Context, e models.AuditEvent, names map[uint]string) AuditEventDTO {
+	d := AuditEventDTO{
+		ID:             e.ID,
+		CreatedAt:      e.CreatedAt,
+		ActorID:        e.ActorID,
+		ActorName:      s.actorName(ctx, e.ActorID, names),
+		ActorRole:      e.ActorRole,
+		ImpersonatorID: e.ImpersonatorID,
+		Action:         e.Action,
+		TargetType:     e.TargetType,
+		TargetID:       e.TargetID,
+		Method:         e.Method,
+		Path:           e.Path,
+		Status:         e.Status,
+		IP:             e.IP,
+		UserAgent:      e.UserAgent,
+	}
+	if e.Before != "" {
+		d.Before = json.RawMessage(e.Before)
+	}
+	if e.After != "" {
+		d.After = json.RawMessage(e.After)
+	}
+	return d
+}
+
+func (s *auditService) actorName(ctx context.Context, id uint, cache map[uint]string) string {
+	if name, ok := cache[id]; ok {
+		return name
+	}
+	name := ""
+	if u, err := s.userRepo.FindByID(ctx, id); err == nil && u != nil {
+		name = strings.TrimSpace(u.Name)
+	}
+	cache[id] = name
+	return name
+}
+
+// auditFilter validates q; the To day is made exclusive for the repository.
+func auditFilter(q AuditLogQuery, now time.Time) (repository.AuditEventFilter, error) {
+	filter := repository.AuditEventFilter{
+		ActorID:        q.ActorID,
+		ActorRole:      strings.TrimSpace(q.ActorRole),
+		ImpersonatorID: q.ImpersonatorID,
+		Action:         strings.TrimSpace(q.Action),
+		TargetType:     strings.TrimSpace(q.TargetType),
+		TargetID:       strings.TrimSpace(q.TargetID),
+		Query:          strings.TrimSpace(q.Query),
+	}
+	if from := strings.TrimSpace(q.From); from != "" {
+		t, err := time.ParseInLocation(aiDayLayout, from, now.Location())
+		if err != nil {
+			return filter, ErrAuditInvalidRange
+		}
+		filter.From = t
+	}
+	if to := strings.TrimSpace(q.To); to != "" {
+		t, err := time.ParseInLocation(aiDayLayout, to, now.Location())
+		if err != nil {
+			return filter, ErrAuditInvalidRange
+		}
+		filter.To = t.AddDate(0, 0, 1)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, ErrAuditInvalidRange
+	}
+	return filter, nil
+}
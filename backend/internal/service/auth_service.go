@@ -20,11 +20,31 @@ import (
 	"github.com/yourusername/fitness-management/internal/repository"
 )
 
-// AuthResult bundles the user and the generated tokens.
+// AuthResult bundles the user and the generated tokens. When the login needs
+// another step, Challenge is set instead of the tokens.
 type AuthResult struct {
 	User         *models.User
 	AccessToken  string
 	RefreshToken string
+	Challenge    *LoginChallenge
+}
+
+// LoginChallenge is the step a login still needs: a second factor
+// (Purpose two_factor, with the allowed Methods) or a new password
+// (Purpose password_change). Token is sent back to finish it.
+type LoginChallenge struct {
+	Purpose   string
+	Token     string
+	Methods   []string
+	ExpiresAt time.Time
+}
+
+// ImpersonationResult is a short-lived session an admin opens as another
+// user. It carries no refresh token.
+type ImpersonationResult struct {
+	User        *models.User
+	AccessToken string
+	ExpiresAt   time.Time
 }
 
 // AuthService defines authentication use cases.
@@ -41,8 +61,18 @@ type AuthService interface {
 	GetMe(ctx context.Context, userID uint) (*models.User, error)
 	ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) error
 	IssueSession(ctx context.Context, userID uint) (*AuthResult, error)
+	// Impersonate lets the admin impersonatorID act as userID. Admin accounts
+	// cannot be impersonated.
+	Impersonate(ctx context.Context, impersonatorID, userID uint) (*ImpersonationResult, error)
 	RequestPasswordResetOTP(ctx context.Context, phone string) error
 	ResetPasswordWithOTP(ctx context.Context, phone, code, newPassword string) error
+	// SendTwoFactorSMS texts a second-factor code for a two_factor challenge.
+	SendTwoFactorSMS(ctx context.Context, challengeToken string) error
+	// CompleteTwoFactor finishes a two_factor challenge with one of its
+	// methods. The result may still carry a password_change challenge.
+	CompleteTwoFactor(ctx context.Context, challengeToken, method, code string) (*AuthResult, error)
+	// CompletePasswordChange finishes a password_change challenge.
+	CompletePasswordChange(ctx context.Context, challengeToken, newPassword string) (*AuthResult, error)
 }
 
 type authService struct {
@@ -50,11 +80,16 @@ type authService struct {
 	coachProfileRepo repository.CoachProfileRepository
 	refreshTokenRepo repository.RefreshTokenRepository
 	otpRepo          repository.OtpRepository
+	twoFactor        TwoFactorService
 	otpTTL           time.Duration
 	otpResendCooldown time.Duration
+	otpMaxAttempts   int
 	defaultUserRole  string
 }
 
+// otpPurposeTwoFactor tags OtpCode rows sent as a login's second factor.
+const otpPurposeTwoFactor = "two_factor"
+
 // OTPCooldownError is returned when OTP resend is requested before the cooldown expires.
 type OTPCooldownError struct {
 	RetryAfterSeconds int
@@ -64,6 +99,16 @@ func (e *OTPCooldownError) Error() string {
 	return "otp resend cooldown"
 }
 
+// LoginLockedError is returned while password login is locked after too
+// many failed sign-ins.
+type LoginLockedError struct {
+	RetryAfterSeconds int
+}
+
+func (e *LoginLockedError) Error() string {
+	return "login temporarily locked"
+}
+
 var (
 	ErrEmailAlreadyExists = errors.New("email already exists")
 	ErrPhoneAlreadyExists = errors.New("phone already exists")
@@ -71,6 +116,13 @@ var (
 	ErrInvalidOTP         = errors.New("invalid or expired otp code")
 	ErrInvalidPassword    = errors.New("current password is incorrect")
 	ErrSlugAlreadyExists  = errors.New("slug already in use")
+	ErrCannotImpersonate  = errors.New("user cannot be impersonated")
+	ErrInvalidChallenge   = errors.New("invalid or expired login challenge")
+	ErrSamePassword       = errors.New("new password must differ from the current one")
+	// ErrTwoFactorSetupRequired rejects SMS-code login for a role that must
+	// use two factors but has no authenticator app yet; password login
+	// still works with SMS as the second factor.
+	ErrTwoFactorSetupRequired = errors.New("two-factor setup required for this login method")
 )
 
 // NewAuthService constructs a new AuthService.
@@ -79,6 +131,7 @@ func NewAuthService(
 	coachProfileRepo repository.CoachProfileRepository,
 	refreshTokenRepo repository.RefreshTokenRepository,
 	otpRepo repository.OtpRepository,
+	twoFactor TwoFactorService,
 ) AuthService {
 	ttlMinutes := config.Get().SMS.OtpTTLMinutes
 	if ttlMinutes <= 0 {
@@ -93,8 +146,10 @@ func NewAuthService(
 		coachProfileRepo: coachProfileRepo,
 		refreshTokenRepo: refreshTokenRepo,
 		otpRepo:          otpRepo,
+		twoFactor:        twoFactor,
 		otpTTL:           time.Duration(ttlMinutes) * time.Minute,
 		otpResendCooldown: time.Duration(cooldownSeconds) * time.Second,
+		otpMaxAttempts:   config.Get().SMS.OtpMaxAttempts,
 		defaultUserRole:  models.RoleStudent,
 	}
 }
@@ -260,12 +315,15 @@ func (s *authService) LoginWithPassword(ctx context.Context, identifier, passwor
 		}
 		return nil, err
 	}
+	if err := loginLocked(user, time.Now()); err != nil {
+		return nil, err
+	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, ErrInvalidCredentials
+		return nil, s.loginFailed(ctx, user, ErrInvalidCredentials)
 	}
 
-	return s.generateTokens(ctx, user)
+	return s.beginSession(ctx, user, false)
 }
 
 func (s *authService) RequestOTP(ctx context.Context, phone string) error {
@@ -318,7 +376,7 @@ func (s *authService) VerifyOTP(ctx context.Context, phone, code string) (*AuthR
 		return nil, err
 	}
 
-	return s.generateTokens(ctx, user)
+	return s.beginSession(ctx, user, true)
 }
 
 func (s *authService) consumeOTP(ctx context.Context, phone, purpose, code string) error {
@@ -329,10 +387,61 @@ func (s *authService) consumeOTP(ctx context.Context, phone, purpose, code strin
 		}
 		return err
 	}
-	if entry.Code != code {
+	claimed, err := s.otpRepo.ClaimAttempt(ctx, entry.ID, s.otpMaxAttempts)
+	if err != nil {
+		return err
+	}
+	if !claimed || entry.Code != code {
+		return ErrInvalidOTP
+	}
+	// A parallel request with the same code may have consumed it meanwhile.
+	used, err := s.otpRepo.MarkUsed(ctx, entry.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
 		return ErrInvalidOTP
 	}
-	return s.otpRepo.MarkUsed(ctx, entry.ID, time.Now())
+	return nil
+}
+
+// loginLocked returns *LoginLockedError while user's password login is
+// locked at now.
+func loginLocked(user *models.User, now time.Time) error {
+	if user.LoginLockedUntil == nil || !user.LoginLockedUntil.After(now) {
+		return nil
+	}
+	retryAfter := int((user.LoginLockedUntil.Sub(now) + time.Second - 1) / time.Second)
+	return &LoginLockedError{RetryAfterSeconds: retryAfter}
+}
+
+// loginFailed records a failed sign-in of user and returns err, or the lock
+// it triggered. From the lockout threshold on, every failure locks login
+// twice as long as the one before, up to the configured maximum.
+func (s *authService) loginFailed(ctx context.Context, user *models.User, err error) error {
+	failures, recordErr := s.userRepo.RecordLoginFailure(ctx, user.ID)
+	if recordErr != nil {
+		return recordErr
+	}
+	cfg := config.Get().Auth
+	if failures < cfg.LoginLockoutThreshold {
+		return err
+	}
+	maxLock := time.Duration(cfg.LoginLockoutMaxMinutes) * time.Minute
+	lock := time.Duration(cfg.LoginLockoutBaseSeconds) * time.Second
+	for i := cfg.LoginLockoutThreshold; i < failures && lock < maxLock; i++ {
+		lock *= 2
+	}
+	if lock > maxLock {
+		lock = maxLock
+	}
+	now := time.Now()
+	until := now.Add(lock)
+	if lockErr := s.userRepo.LockLogin(ctx, user.ID, until); lockErr != nil {
+		return lockErr
+	}
+	user.FailedLoginCount, user.LoginLockedUntil = failures, &until
+	return loginLocked(user, now)
 }
 
 func (s *authService) Logout(ctx context.Context, userID uint, refreshToken string) error {
@@ -411,18 +520,7 @@ func (s *authService) ResetPasswordWithOTP(ctx context.Context, phone, code, new
 		return errors.New("new password must be at least 8 characters")
 	}
 
-	entry, err := s.otpRepo.FindValidByPhoneAndPurpose(ctx, phone, "password_reset", time.Now())
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrInvalidOTP
-		}
-		return err
-	}
-	if entry.Code != code {
-		return ErrInvalidOTP
-	}
-
-	if err := s.otpRepo.MarkUsed(ctx, entry.ID, time.Now()); err != nil {
+	if err := s.consumeOTP(ctx, phone, "password_reset", code); err != nil {
 		return err
 	}
 
@@ -453,7 +551,151 @@ func (s *authService) ResetPasswordWithOTP(ctx context.Context, phone, code, new
 	return nil
 }
 
+// beginSession runs the steps after the first factor: a second factor when
+// the user enrolled in TOTP or config requires it for the role, then a forced
+// password change. viaSMS means the first factor was an SMS code, so SMS
+// cannot also be the second.
+func (s *authService) beginSession(ctx context.Context, user *models.User, viaSMS bool) (*AuthResult, error) {
+	if blocked := accountBlocked(user, time.Now()); blocked != nil {
+		return nil, blocked
+	}
+	enrolled, err := s.twoFactor.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !enrolled && !config.TwoFactorRequired(user.Role) {
+		return s.afterSecondFactor(ctx, user)
+	}
+	var methods []string
+	if enrolled {
+		methods = append(methods, models.TwoFactorMethodTOTP, models.TwoFactorMethodRecovery)
+	}
+	if !viaSMS {
+		methods = append(methods, models.TwoFactorMethodSMS)
+	}
+	if len(methods) == 0 {
+		return nil, ErrTwoFactorSetupRequired
+	}
+	return s.challenge(user, auth.PurposeTwoFactor, methods)
+}
+
+// afterSecondFactor issues tokens unless the password must change first.
+// Either way the user proved who they are, so failed sign-ins are forgotten.
+func (s *authService) afterSecondFactor(ctx context.Context, user *models.User) (*AuthResult, error) {
+	if user.FailedLoginCount > 0 || user.LoginLockedUntil != nil {
+		if err := s.userRepo.ResetLoginFailures(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.FailedLoginCount, user.LoginLockedUntil = 0, nil
+	}
+	if user.MustChangePassword {
+		return s.challenge(user, auth.PurposePasswordChange, nil)
+	}
+	return s.generateTokens(ctx, user)
+}
+
+func (s *authService) challenge(user *models.User, purpose string, methods []string) (*AuthResult, error) {
+	token, expiresAt, err := auth.GenerateChallengeToken(user.ID, user.Role, purpose)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthResult{
+		User:      user,
+		Challenge: &LoginChallenge{Purpose: purpose, Token: token, Methods: methods, ExpiresAt: expiresAt},
+	}, nil
+}
+
+// challengeUser resolves an open challenge token to its user.
+func (s *authService) challengeUser(ctx context.Context, challengeToken, purpose string) (*models.User, error) {
+	claims, err := auth.ParseChallengeToken(strings.TrimSpace(challengeToken), purpose)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	user, err := s.userRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *authService) SendTwoFactorSMS(ctx context.Context, challengeToken string) error {
+	user, err := s.challengeUser(ctx, challengeToken, auth.PurposeTwoFactor)
+	if err != nil {
+		return err
+	}
+	return s.sendOTP(ctx, digits.NormalizePhone(user.Phone), otpPurposeTwoFactor)
+}
+
+func (s *authService) CompleteTwoFactor(ctx context.Context, challengeToken, method, code string) (*AuthResult, error) {
+	user, err := s.challengeUser(ctx, challengeToken, auth.PurposeTwoFactor)
+	if err != nil {
+		return nil, err
+	}
+	if err := loginLocked(user, time.Now()); err != nil {
+		return nil, err
+	}
+	code = digits.ToEnglish(strings.TrimSpace(code))
+	if code == "" {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	switch method {
+	case models.TwoFactorMethodSMS:
+		if err := s.consumeOTP(ctx, digits.NormalizePhone(user.Phone), otpPurposeTwoFactor, code); err != nil {
+			if errors.Is(err, ErrInvalidOTP) {
+				return nil, s.loginFailed(ctx, user, ErrInvalidTwoFactorCode)
+			}
+			return nil, err
+		}
+	case models.TwoFactorMethodTOTP, models.TwoFactorMethodRecovery:
+		if err := s.twoFactor.Verify(ctx, user.ID, method, code); err != nil {
+			if errors.Is(err, ErrTwoFactorNotEnabled) || errors.Is(err, ErrInvalidTwoFactorCode) {
+				return nil, s.loginFailed(ctx, user, ErrInvalidTwoFactorCode)
+			}
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if blocked := accountBlocked(user, time.Now()); blocked != nil {
+		return nil, blocked
+	}
+	return s.afterSecondFactor(ctx, user)
+}
+
+func (s *authService) CompletePasswordChange(ctx context.Context, challengeToken, newPassword string) (*AuthResult, error) {
+	user, err := s.challengeUser(ctx, challengeToken, auth.PurposePasswordChange)
+	if err != nil {
+		return nil, err
+	}
+	newPassword = strings.TrimSpace(newPassword)
+	if len(newPassword) < 8 {
+		return nil, errors.New("new password must be at least 8 characters")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(newPassword)) == nil {
+		return nil, ErrSamePassword
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hashed)); err != nil {
+		return nil, err
+	}
+	user.Password, user.MustChangePassword = string(hashed), false
+	if err := s.refreshTokenRepo.DeleteByUserID(ctx, user.ID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return s.generateTokens(ctx, user)
+}
+
 func (s *authService) generateTokens(ctx context.Context, user *models.User) (*AuthResult, error) {
+	if blocked := accountBlocked(user, time.Now()); blocked != nil {
+		return nil, blocked
+	}
+
 	accessToken, _, err := auth.GenerateAccessToken(user.ID, user.Role)
 	if err != nil {
 		return nil, err
@@ -493,6 +735,27 @@ func (s *authService) IssueSession(ctx context.Context, userID uint) (*AuthResul
 	return s.generateTokens(ctx, user)
 }
 
+func (s *authService) Impersonate(ctx context.Context, impersonatorID, userID uint) (*ImpersonationResult, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	if user.ID == impersonatorID || user.Role == models.RoleAdmin {
+		return nil, ErrCannotImpersonate
+	}
+	if blocked := accountBlocked(user, time.Now()); blocked != nil {
+		return nil, blocked
+	}
+	token, expiresAt, err := auth.GenerateImpersonationToken(user.ID, user.Role, impersonatorID)
+	if err != nil {
+		return nil, err
+	}
+	return &ImpersonationResult{User: user, AccessToken: token, ExpiresAt: expiresAt}, nil
+}
+
 func generateOTPCode() (string, error) {
 	var b [8]byte
 	if _, err := rand.Read(b[:]); err != nil {
@@ -30,6 +30,9 @@ type CheckoutItemRequest struct {
 
 type CheckoutRequest struct {
 	Items []CheckoutItemRequest `json:"items"`
+	// DiscountPercent is an extra discount granted server-side (funnel
+	// recovery); it is never read from the client.
+	DiscountPercent int `json:"-"`
 }
 
 type CheckoutResponse struct {
@@ -0,0 +1,507 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+var ErrCoachAIInvalidDraft = errors.New("ai returned an invalid program draft")
+
+const (
+	coachAIInstructionsMaxRunes = 1000
+	coachAISummaryDefaultWeeks  = 4
+	coachAISummaryMaxWeeks      = 12
+	coachAIHistoryFetchLimit    = 100
+	coachAIHistoryPromptMax     = 40
+	coachAITicketMessageMax     = 3000
+	// Program drafts are a full week of exercises; chat replies are capped lower.
+	coachAIProgramMaxTokens = 2000
+	coachAIDraftMaxTokens   = 800
+)
+
+// CoachAIDraftRequest carries optional guidance from the coach, e.g. "be brief"
+// or "avoid overhead pressing".
+type CoachAIDraftRequest struct {
+	Instructions string `json:"instructions"`
+}
+
+// CoachAITicketReplyDraft is a suggested answer; the coach edits it and sends it
+// through PATCH /coach/tickets/:id/answer.
+type CoachAITicketReplyDraft struct {
+	TicketID uint     `json:"ticketId"`
+	Draft    string   `json:"draft"`
+	Warnings []string `json:"warnings"`
+}
+
+type CoachAISummaryRequest struct {
+	Weeks int `json:"weeks"` // default 4, max 12
+}
+
+// CoachAIStudentSummary is a check-in review summary of tracking and workout logs.
+type CoachAIStudentSummary struct {
+	StudentID      uint     `json:"studentId"`
+	From           string   `json:"from"`
+	Weeks          int      `json:"weeks"`
+	WorkoutsLogged int      `json:"workoutsLogged"`
+	Summary        string   `json:"summary"`
+	Warnings       []string `json:"warnings"`
+}
+
+type CoachAIProgramDraftRequest struct {
+	TemplateID   uint   `json:"templateId" binding:"required"`
+	Instructions string `json:"instructions"`
+}
+
+// CoachAIProgramDraft is a template adjusted for the student. Program is never
+// saved: it has the shape of POST /coach/students/:id/workout-programs so the
+// coach can review, edit and assign it.
+type CoachAIProgramDraft struct {
+	StudentID  uint                 `json:"studentId"`
+	TemplateID uint                 `json:"templateId"`
+	Program    ProgramAssignRequest `json:"program"`
+	Changes    []string             `json:"changes"`
+	Warnings   []string             `json:"warnings"`
+}
+
+// CoachAIService drafts ticket replies, check-in summaries and workout programs
+// for coaches. Nothing is applied: every result is a draft the coach reviews.
+type CoachAIService interface {
+	DraftTicketReply(ctx context.Context, coachID, ticketID uint, req *CoachAIDraftRequest) (*CoachAITicketReplyDraft, error)
+	SummarizeStudent(ctx context.Context, coachID, studentID uint, req *CoachAISummaryRequest) (*CoachAIStudentSummary, error)
+	DraftWorkoutProgram(ctx context.Context, coachID, studentID uint, req *CoachAIProgramDraftRequest) (*CoachAIProgramDraft, error)
+}
+
+type coachAIService struct {
+	coachStudentSvc   CoachStudentService
+	trackingSvc       TrackingService
+	workoutHistorySvc WorkoutHistoryService
+	ticketSvc         TicketService
+	coachProgramSvc   CoachProgramService
+	usage             AIUsageService // nil disables accounting
+	limiter           RateLimiter    // nil disables the per-minute limit
+	// provider is nil when the configured backend is unusable (providerErr says why).
+	provider    LLMProvider
+	providerErr error
+}
+
+func NewCoachAIService(
+	coachStudentSvc CoachStudentService,
+	trackingSvc TrackingService,
+	workoutHistorySvc WorkoutHistoryService,
+	ticketSvc TicketService,
+	coachProgramSvc CoachProgramService,
+	usage AIUsageService,
+	limiter RateLimiter,
+) CoachAIService {
+	provider, err := NewLLMProviderFromConfig(config.Get())
+	if err != nil {
+		log.Printf("coach ai: %v", err)
+	}
+	return &coachAIService{
+		coachStudentSvc:   coachStudentSvc,
+		trackingSvc:       trackingSvc,
+		workoutHistorySvc: workoutHistorySvc,
+		ticketSvc:         ticketSvc,
+		coachProgramSvc:   coachProgramSvc,
+		usage:             usage,
+		limiter:           limiter,
+		provider:          provider,
+		providerErr:       err,
+	}
+}
+
+const coachAIBasePrompt = `تو دستیار نگارش مربی‌های فیتینو هستی. خروجی تو پیش‌نویسی است که مربی پیش از استفاده بازبینی و ویرایش می‌کند.
+- فارسی، محترمانه و دقیق بنویس.
+- فقط از داده‌هایی که در پیام آمده استفاده کن و چیزی از خودت نساز؛ اگر داده‌ای نیست، بگو که نیست.
+- هرگز درباره استروئید، داروهای نیروزا یا مکمل‌های غیرمجاز چیزی پیشنهاد نکن.
+- تشخیص پزشکی نده؛ برای آسیب‌ها و دردهای جدی ارجاع به پزشک را پیشنهاد بده.`
+
+func (s *coachAIService) DraftTicketReply(ctx context.Context, coachID, ticketID uint, req *CoachAIDraftRequest) (*CoachAITicketReplyDraft, error) {
+	instructions, err := coachAIInstructions(req.Instructions)
+	if err != nil {
+		return nil, err
+	}
+	ticket, err := s.ticketSvc.GetForCoach(ctx, coachID, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.admit(ctx, coachID); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("پیش‌نویس پاسخ مربی به تیکت زیر را بنویس. فقط متن پاسخ را برگردان.\n\n")
+	fmt.Fprintf(&b, "شاگرد: %s\nعنوان: %s\nاولویت: %s\nپیام شاگرد:\n%s\n", ticket.StudentName, ticket.Title, ticket.Priority, truncateRunes(ticket.Message, coachAITicketMessageMax))
+	if ticket.Answer != "" {
+		fmt.Fprintf(&b, "\nپاسخ قبلی مربی:\n%s\n", truncateRunes(ticket.Answer, coachAITicketMessageMax))
+	}
+	if instructions != "" {
+		fmt.Fprintf(&b, "\nتوضیح مربی برای این پاسخ: %s\n", instructions)
+	}
+
+	draft, err := s.complete(ctx, coachID, b.String(), coachAIDraftMaxTokens)
+	if err != nil {
+		return nil, err
+	}
+	out := &CoachAITicketReplyDraft{TicketID: ticket.ID, Draft: draft, Warnings: []string{}}
+	if hitsSteroidTopic(draft) {
+		out.Warnings = append(out.Warnings, "پیش‌نویس به داروهای نیروزا اشاره دارد؛ پیش از ارسال بازبینی کنید.")
+	}
+	return out, nil
+}
+
+func (s *coachAIService) SummarizeStudent(ctx context.Context, coachID, studentID uint, req *CoachAISummaryRequest) (*CoachAIStudentSummary, error) {
+	weeks := req.Weeks
+	if weeks <= 0 {
+		weeks = coachAISummaryDefaultWeeks
+	}
+	if weeks > coachAISummaryMaxWeeks {
+		weeks = coachAISummaryMaxWeeks
+	}
+	detail, err := s.coachStudentSvc.GetStudent(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	tracking, err := s.trackingSvc.GetCoachStudentTracking(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	from := time.Now().AddDate(0, 0, -7*weeks)
+	fromDay := from.Format(aiDayLayout)
+	workouts, err := s.recentWorkouts(ctx, studentID, detail.SubscriptionID, from)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.admit(ctx, coachID); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "خلاصه‌ای برای بازبینی چک‌این این شاگرد در %d هفته اخیر (از %s) بنویس: روند وزن، نظم ثبت وزن و عکس، تعداد و نظم جلسات تمرین، یادداشت‌های شاگرد و مواردی که مربی باید پیگیری کند. کوتاه و فهرست‌وار بنویس.\n\n", weeks, fromDay)
+	writeCoachAIProfile(&b, detail)
+	st := tracking.TrackingStatus
+	fmt.Fprintf(&b, "\nپایش: فاصله دوره‌ها %d روز، موعد بعدی %s، وزن دوره فعلی ثبت شده: %t\n", st.FrequencyDays, st.NextDueDate, st.WeightSubmitted)
+	for _, a := range st.Alerts {
+		fmt.Fprintf(&b, "- هشدار: %s\n", a.Message)
+	}
+	b.WriteString("وزن‌ها:\n")
+	weighIns := 0
+	for _, w := range st.WeightHistory {
+		if w.Date < fromDay {
+			continue
+		}
+		weighIns++
+		fmt.Fprintf(&b, "- %s: %s kg\n", w.Date, strconv.FormatFloat(w.Weight, 'f', -1, 64))
+	}
+	if weighIns == 0 {
+		b.WriteString("- وزنی در این بازه ثبت نشده است\n")
+	}
+	if tracking.ExerciseSwaps != nil && len(tracking.ExerciseSwaps.Items) > 0 {
+		b.WriteString("جایگزینی حرکات توسط شاگرد:\n")
+		for _, sw := range tracking.ExerciseSwaps.Items {
+			fmt.Fprintf(&b, "- %s ← %s (%s)\n", sw.ReplacementExercise, sw.OriginalExercise, sw.Reason)
+		}
+	}
+	fmt.Fprintf(&b, "جلسات تمرین ثبت‌شده (%d):\n", len(workouts))
+	for i, w := range workouts {
+		if i == coachAIHistoryPromptMax {
+			fmt.Fprintf(&b, "- و %d جلسه دیگر\n", len(workouts)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- %s %s: %d حرکت، %d دقیقه", w.CompletedAt[:min(len(w.CompletedAt), 10)], w.DayLabel, w.ExerciseCount, w.DurationMin)
+		if w.Notes != "" {
+			fmt.Fprintf(&b, "، یادداشت: %s", truncateRunes(w.Notes, 200))
+		}
+		b.WriteString("\n")
+	}
+
+	summary, err := s.complete(ctx, coachID, b.String(), coachAIDraftMaxTokens)
+	if err != nil {
+		return nil, err
+	}
+	out := &CoachAIStudentSummary{
+		StudentID:      studentID,
+		From:           fromDay,
+		Weeks:          weeks,
+		WorkoutsLogged: len(workouts),
+		Summary:        summary,
+		Warnings:       []string{},
+	}
+	if detail.SubscriptionID == 0 {
+		out.Warnings = append(out.Warnings, "شاگرد اشتراک فعالی با شما ندارد؛ جلسات تمرین همه اشتراک‌ها در نظر گرفته شد.")
+	}
+	return out, nil
+}
+
+// recentWorkouts returns the student's logged sessions completed since from,
+// newest first.
+func (s *coachAIService) recentWorkouts(ctx context.Context, studentID, subscriptionID uint, from time.Time) ([]WorkoutHistoryItemDTO, error) {
+	res, err := s.workoutHistorySvc.ListHistory(ctx, studentID, 1, coachAIHistoryFetchLimit, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]WorkoutHistoryItemDTO, 0, len(res.Items))
+	for _, it := range res.Items {
+		completed, err := time.Parse(time.RFC3339, it.CompletedAt)
+		if err == nil && completed.Before(from) {
+			break
+		}
+		out = append(out, it)
+	}
+	return out, nil
+}
+
+// coachAIProgramReply is the JSON the model returns for a program draft.
+type coachAIProgramReply struct {
+	Days    map[string][]coachAIDraftExercise `json:"days"`
+	Notes   string                            `json:"notes"`
+	Changes []string                          `json:"changes"`
+}
+
+type coachAIDraftExercise struct {
+	Name string `json:"name"`
+	Sets int    `json:"sets"`
+	Reps string `json:"reps"`
+}
+
+func (s *coachAIService) DraftWorkoutProgram(ctx context.Context, coachID, studentID uint, req *CoachAIProgramDraftRequest) (*CoachAIProgramDraft, error) {
+	instructions, err := coachAIInstructions(req.Instructions)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := s.coachStudentSvc.GetStudent(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	template, err := s.coachProgramSvc.WorkoutTemplatePlan(ctx, coachID, req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.admit(ctx, coachID); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("قالب تمرینی زیر را برای این شاگرد تنظیم کن، با توجه به آسیب‌ها، محدودیت‌ها و هدفش. تا حد امکان از حرکات همین قالب استفاده کن؛ برای حرکتی که به ناحیه آسیب‌دیده فشار می‌آورد جایگزین ایمن‌تر بگذار یا حذفش کن.\n")
+	b.WriteString(`فقط یک شیء JSON برگردان، بدون توضیح اضافه: {"days":{"sat":[{"name":"...","sets":3,"reps":"10-12"}]},"changes":["تغییر و دلیل آن"],"notes":"یادداشت برای شاگرد"}` + "\n")
+	b.WriteString("کلید روزها فقط sat, sun, mon, tue, wed, thu, fri است و روزهای استراحت را نیاور.\n\n")
+	writeCoachAIProfile(&b, detail)
+	if instructions != "" {
+		fmt.Fprintf(&b, "توضیح مربی: %s\n", instructions)
+	}
+	fmt.Fprintf(&b, "\nقالب «%s»:\n", template.Title)
+	for _, day := range allDayKeys {
+		plan, ok := template.PlanByDay[day]
+		if !ok || plan.Workout == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s (%s):\n", day, plan.Workout.Title)
+		for _, ex := range plan.Workout.Exercises {
+			fmt.Fprintf(&b, "- %s: %d × %s\n", ex.Name, ex.Sets, ex.Reps)
+		}
+	}
+
+	raw, err := s.complete(ctx, coachID, b.String(), coachAIProgramMaxTokens)
+	if err != nil {
+		return nil, err
+	}
+	var reply coachAIProgramReply
+	if err := json.Unmarshal([]byte(coachAIExtractJSON(raw)), &reply); err != nil || len(reply.Days) == 0 {
+		return nil, ErrCoachAIInvalidDraft
+	}
+	program, warnings, err := buildCoachAIProgram(template, &reply, detectInjuryRegions(detail.Injuries+" "+detail.PhysicalLimitations))
+	if err != nil {
+		return nil, err
+	}
+	changes := reply.Changes
+	if changes == nil {
+		changes = []string{}
+	}
+	return &CoachAIProgramDraft{
+		StudentID:  studentID,
+		TemplateID: req.TemplateID,
+		Program:    *program,
+		Changes:    changes,
+		Warnings:   warnings,
+	}, nil
+}
+
+// buildCoachAIProgram turns the model's days into a program body. Exercises from
+// the template keep their library data; anything else, unknown day keys and
+// exercises that still load an injured region are reported as warnings.
+func buildCoachAIProgram(template *ProgramAssignRequest, reply *coachAIProgramReply, injuryRegions []string) (*ProgramAssignRequest, []string, error) {
+	known := map[string]MeWorkoutExerciseDTO{}
+	for _, plan := range template.PlanByDay {
+		if plan.Workout == nil {
+			continue
+		}
+		for _, ex := range plan.Workout.Exercises {
+			known[strings.ToLower(strings.TrimSpace(ex.Name))] = ex
+		}
+	}
+
+	warnings := []string{}
+	program := &ProgramAssignRequest{
+		Title:         template.Title,
+		DurationWeeks: template.DurationWeeks,
+		Notes:         strings.TrimSpace(reply.Notes),
+		Schedule:      &MeScheduleDTO{Weekly: []string{}, RestDays: []string{}},
+		PlanByDay:     map[string]MeDayPlanDTO{},
+	}
+	days := map[string][]coachAIDraftExercise{}
+	for key, exercises := range reply.Days {
+		day := strings.ToLower(strings.TrimSpace(key))
+		if !slices.Contains(allDayKeys, day) {
+			warnings = append(warnings, fmt.Sprintf("روز نامعتبر «%s» در پیش‌نویس نادیده گرفته شد.", key))
+			continue
+		}
+		days[day] = append(days[day], exercises...)
+	}
+	for _, day := range allDayKeys {
+		exercises := days[day]
+		if len(exercises) == 0 {
+			program.Schedule.RestDays = append(program.Schedule.RestDays, day)
+			continue
+		}
+		workout := &MeWorkoutDTO{Title: "تمرین " + workoutDayLabels[day], Steps: []string{}}
+		if plan, ok := template.PlanByDay[day]; ok && plan.Workout != nil {
+			workout.Title, workout.DurationMin, workout.Calories = plan.Workout.Title, plan.Workout.DurationMin, plan.Workout.Calories
+		}
+		for _, draft := range exercises {
+			name := strings.TrimSpace(draft.Name)
+			if name == "" {
+				continue
+			}
+			ex, ok := known[strings.ToLower(name)]
+			if !ok {
+				ex = MeWorkoutExerciseDTO{Name: name}
+				warnings = append(warnings, fmt.Sprintf("حرکت «%s» در قالب نیست و به کتابخانه حرکات متصل نشده است.", name))
+			}
+			ex.SetsDetails = nil
+			if draft.Sets > 0 {
+				ex.Sets = draft.Sets
+			}
+			if r := strings.TrimSpace(draft.Reps); r != "" {
+				ex.Reps = r
+			}
+			for _, region := range injuryRegions {
+				if exerciseLoadsRegion(region, ex.Name, &models.Exercise{Target: ex.Target, BodyPart: ex.BodyPart}) {
+					warnings = append(warnings, fmt.Sprintf("حرکت «%s» به ناحیه آسیب‌دیده (%s) فشار می‌آورد.", ex.Name, injuryRegionLabels[region]))
+					break
+				}
+			}
+			workout.Exercises = append(workout.Exercises, ex)
+		}
+		if len(workout.Exercises) == 0 {
+			program.Schedule.RestDays = append(program.Schedule.RestDays, day)
+			continue
+		}
+		program.Schedule.Weekly = append(program.Schedule.Weekly, day)
+		program.PlanByDay[day] = MeDayPlanDTO{Workout: workout}
+	}
+	if len(program.Schedule.Weekly) == 0 {
+		return nil, nil, ErrCoachAIInvalidDraft
+	}
+	if hitsSteroidTopic(program.Notes) {
+		program.Notes = ""
+		warnings = append(warnings, "یادداشت پیش‌نویس به داروهای نیروزا اشاره داشت و حذف شد.")
+	}
+	return program, warnings, nil
+}
+
+func writeCoachAIProfile(b *strings.Builder, d *CoachStudentDetail) {
+	fmt.Fprintf(b, "شاگرد: %s\n", d.FullName)
+	if d.Gender != "" {
+		fmt.Fprintf(b, "جنسیت: %s\n", d.Gender)
+	}
+	if d.HeightCm != nil {
+		fmt.Fprintf(b, "قد: %s cm\n", strconv.FormatFloat(*d.HeightCm, 'f', -1, 64))
+	}
+	if d.WeightKg != nil {
+		fmt.Fprintf(b, "وزن پروفایل: %s kg\n", strconv.FormatFloat(*d.WeightKg, 'f', -1, 64))
+	}
+	if d.PrimaryGoal != "" {
+		fmt.Fprintf(b, "هدف: %s\n", d.PrimaryGoal)
+	}
+	if d.Injuries != "" {
+		fmt.Fprintf(b, "آسیب‌ها: %s\n", truncateRunes(d.Injuries, 500))
+	}
+	if d.PhysicalLimitations != "" {
+		fmt.Fprintf(b, "محدودیت‌های جسمی: %s\n", truncateRunes(d.PhysicalLimitations, 500))
+	}
+	if d.OrderNote != "" {
+		fmt.Fprintf(b, "توضیح سفارش: %s\n", truncateRunes(d.OrderNote, 500))
+	}
+}
+
+// admit applies the per-coach rate limit. Coach drafts have no quota, but their
+// cost is recorded against the coach.
+func (s *coachAIService) admit(ctx context.Context, coachID uint) error {
+	if s.provider == nil {
+		return ErrAINotConfigured
+	}
+	if s.limiter == nil {
+		return nil
+	}
+	ok, err := s.limiter.Allow(ctx, "ai:coach:"+strconv.FormatUint(uint64(coachID), 10), config.Get().AI.RatePerMinute, time.Minute)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAIRateLimited
+	}
+	return nil
+}
+
+func (s *coachAIService) complete(ctx context.Context, coachID uint, prompt string, maxTokens int) (string, error) {
+	req := LLMRequest{
+		Messages: []LLMMessage{
+			{Role: "system", Content: coachAIBasePrompt},
+			{Role: "user", Content: prompt},
+		},
+		MaxTokens: maxTokens,
+	}
+	resp, err := s.provider.Complete(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if s.usage != nil {
+		usage := aiTurnUsage{feature: models.AIUsageFeatureCoachDraft, provider: s.provider.Name(), model: config.Get().OpenAI.Model}
+		usage.add(req, resp)
+		s.usage.record(ctx, coachID, &aiQuotaContext{coachID: coachID}, 0, 0, usage)
+	}
+	out := strings.TrimSpace(resp.Content)
+	if out == "" {
+		return "", fmt.Errorf("%w: empty reply", ErrAIUpstream)
+	}
+	return out, nil
+}
+
+func coachAIInstructions(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if utf8.RuneCountInString(s) > coachAIInstructionsMaxRunes {
+		return "", ErrAIInvalidInput
+	}
+	return s, nil
+}
+
+// coachAIExtractJSON strips code fences and prose around the object the model
+// was asked to return.
+func coachAIExtractJSON(s string) string {
+	start, end := strings.Index(s, "{"), strings.LastIndex(s, "}")
+	if start < 0 || end < start {
+		return s
+	}
+	return s[start : end+1]
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type stubCoachStudentService struct {
+	CoachStudentService
+	detail *CoachStudentDetail
+}
+
+func (s stubCoachStudentService) GetStudent(ctx context.Context, coachID, studentID uint) (*CoachStudentDetail, error) {
+	return s.detail, nil
+}
+
+type stubCoachProgramService struct {
+	CoachProgramService
+	plan *ProgramAssignRequest
+}
+
+func (s stubCoachProgramService) WorkoutTemplatePlan(ctx context.Context, coachID, templateID uint) (*ProgramAssignRequest, error) {
+	return s.plan, nil
+}
+
+func TestDraftWorkoutProgramIsValidatedAgainstTemplate(t *testing.T) {
+	template := &ProgramAssignRequest{
+		Title:         "فول بادی",
+		DurationWeeks: 4,
+		PlanByDay: map[string]MeDayPlanDTO{
+			"sat": {Workout: &MeWorkoutDTO{Title: "روز اول", Exercises: []MeWorkoutExerciseDTO{
+				{ExerciseID: 11, Name: "Squat", Sets: 4, Reps: "8"},
+				{ExerciseID: 12, Name: "Bench Press", Sets: 4, Reps: "8"},
+			}}},
+		},
+	}
+	reply := "```json\n" + `{"days":{"Sat":[{"name":"bench press","sets":3,"reps":"10"},{"name":"Back Squat","sets":3,"reps":"12"}],"xyz":[{"name":"Plank"}]},"changes":["حجم کمتر"],"notes":"آرام شروع کن"}` + "\n```"
+	svc := &coachAIService{
+		coachStudentSvc: stubCoachStudentService{detail: &CoachStudentDetail{Injuries: "درد زانو"}},
+		coachProgramSvc: stubCoachProgramService{plan: template},
+		provider:        NewFakeLLMProvider(reply),
+	}
+
+	draft, err := svc.DraftWorkoutProgram(context.Background(), 1, 2, &CoachAIProgramDraftRequest{TemplateID: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day, ok := draft.Program.PlanByDay["sat"]
+	if !ok || len(day.Workout.Exercises) != 2 {
+		t.Fatalf("expected the sat workout with two exercises, got %+v", draft.Program.PlanByDay)
+	}
+	bench := day.Workout.Exercises[0]
+	if bench.ExerciseID != 12 || bench.Sets != 3 || bench.Reps != "10" {
+		t.Fatalf("template exercise should keep its library link with the draft's sets/reps, got %+v", bench)
+	}
+	if day.Workout.Exercises[1].ExerciseID != 0 {
+		t.Fatal("exercise outside the template must not be linked")
+	}
+	if len(draft.Program.Schedule.Weekly) != 1 || len(draft.Program.Schedule.RestDays) != 6 {
+		t.Fatalf("unexpected schedule %+v", draft.Program.Schedule)
+	}
+	// Invalid day, unknown exercise and the squat loading an injured knee are flagged.
+	joined := strings.Join(draft.Warnings, "\n")
+	for _, want := range []string{"xyz", "Back Squat", "زانو"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("warnings should mention %q, got %v", want, draft.Warnings)
+		}
+	}
+}
@@ -230,29 +230,15 @@ func (s *coachDashboardService) ProgressSeries(ctx context.Context, coachID uint
 	startDay := now.AddDate(0, 0, -(days - 1))
 	start := time.Date(startDay.Year(), startDay.Month(), startDay.Day(), 0, 0, 0, 0, time.Local)
 
-	type row struct {
-		Day string
-		Cnt int
-	}
-	var rows []row
+	var completed []time.Time
 	if err := s.db.WithContext(ctx).
 		Model(&models.WorkoutSession{}).
-		Select("DATE(workout_sessions.completed_at) AS day, COUNT(*) AS cnt").
 		Joins("JOIN subscriptions ON subscriptions.id = workout_sessions.subscription_id AND subscriptions.deleted_at IS NULL").
 		Where("subscriptions.coach_id = ? AND workout_sessions.completed_at >= ?", coachID, start).
-		Group("DATE(workout_sessions.completed_at)").
-		Scan(&rows).Error; err != nil {
+		Pluck("workout_sessions.completed_at", &completed).Error; err != nil {
 		return nil, err
 	}
-
-	counts := make(map[string]int, len(rows))
-	for _, r := range rows {
-		key := r.Day
-		if len(key) > 10 {
-			key = key[:10]
-		}
-		counts[key] = r.Cnt
-	}
+	counts := countPerDay(completed)
 
 	out := make([]ProgressPoint, 0, days)
 	for i := 0; i < days; i++ {
@@ -17,6 +17,7 @@ var (
 	ErrCoachNoActiveSubscription = errors.New("student has no active subscription with this coach")
 	ErrCoachProgramNotFound      = errors.New("program not found")
 	ErrCoachTemplateNotFound     = errors.New("template not found")
+	ErrCoachTemplateForbidden    = errors.New("template not accessible")
 )
 
 type ProgramAssignRequest struct {
@@ -36,6 +37,8 @@ type CoachStudentProgramsResponse struct {
 
 type WorkoutTemplateSummary struct {
 	ID       uint   `json:"id"`
+	CoachID  *uint  `json:"coachId,omitempty"`
+	IsCustom bool   `json:"isCustom"`
 	Title    string `json:"title"`
 	Type     string `json:"type,omitempty"`
 	Gender   string `json:"gender,omitempty"`
@@ -48,6 +51,8 @@ type WorkoutTemplateSummary struct {
 
 type NutritionTemplateSummary struct {
 	ID         uint   `json:"id"`
+	CoachID    *uint  `json:"coachId,omitempty"`
+	IsCustom   bool   `json:"isCustom"`
 	Title      string `json:"title"`
 	Type       string `json:"type,omitempty"`
 	Gender     string `json:"gender,omitempty"`
@@ -69,12 +74,17 @@ type CoachProgramService interface {
 	UpdateWorkoutProgram(ctx context.Context, coachID, studentID, programID uint, req *ProgramAssignRequest) (*CoachStudentProgramsResponse, error)
 	AssignNutritionProgram(ctx context.Context, coachID, studentID uint, req *ProgramAssignRequest) (*CoachStudentProgramsResponse, error)
 	UpdateNutritionProgram(ctx context.Context, coachID, studentID, programID uint, req *ProgramAssignRequest) (*CoachStudentProgramsResponse, error)
-	ListWorkoutTemplates(ctx context.Context, page, pageSize int, query string) (*TemplateListResponse, error)
-	ListNutritionTemplates(ctx context.Context, page, pageSize int, query string) (*TemplateListResponse, error)
-	GetWorkoutTemplate(ctx context.Context, id uint) (*AdminWorkoutTemplateDetail, error)
-	GetNutritionTemplate(ctx context.Context, id uint) (*AdminNutritionTemplateDetail, error)
+	// ListWorkoutTemplates mixes platform templates with the coach's own;
+	// source narrows it to all | mine | platform. coachID 0 skips owner scoping.
+	ListWorkoutTemplates(ctx context.Context, coachID uint, page, pageSize int, query, source string) (*TemplateListResponse, error)
+	ListNutritionTemplates(ctx context.Context, coachID uint, page, pageSize int, query, source string) (*TemplateListResponse, error)
+	GetWorkoutTemplate(ctx context.Context, coachID, id uint) (*AdminWorkoutTemplateDetail, error)
+	GetNutritionTemplate(ctx context.Context, coachID, id uint) (*AdminNutritionTemplateDetail, error)
 	AssignWorkoutFromTemplate(ctx context.Context, coachID, studentID, templateID uint) (*CoachStudentProgramsResponse, error)
 	AssignNutritionFromTemplate(ctx context.Context, coachID, studentID, templateID uint) (*CoachStudentProgramsResponse, error)
+	// WorkoutTemplatePlan returns a workout template as an unsaved program body,
+	// in the shape AssignWorkoutProgram accepts.
+	WorkoutTemplatePlan(ctx context.Context, coachID, templateID uint) (*ProgramAssignRequest, error)
 }
 
 type coachProgramService struct {
@@ -425,17 +435,36 @@ func (s *coachProgramService) UpdateNutritionProgram(ctx context.Context, coachI
 	}, nil
 }
 
-func (s *coachProgramService) ListWorkoutTemplates(ctx context.Context, page, pageSize int, query string) (*TemplateListResponse, error) {
+func coachTemplateFilter(coachID uint, query, source string) repository.TemplateListFilter {
+	filter := repository.TemplateListFilter{Query: query, Source: source}
+	if coachID > 0 {
+		filter.CoachID = &coachID
+	}
+	return filter
+}
+
+// coachCanAccessTemplate reports whether a coach may read/assign a template:
+// platform templates are shared, coach templates are private to their owner.
+func coachCanAccessTemplate(coachID uint, owner *uint) bool {
+	if owner == nil {
+		return true
+	}
+	return *owner == coachID
+}
+
+func (s *coachProgramService) ListWorkoutTemplates(ctx context.Context, coachID uint, page, pageSize int, query, source string) (*TemplateListResponse, error) {
+	filter := coachTemplateFilter(coachID, query, source)
 	// Legacy picker: no pagination → full list
 	if page <= 0 && pageSize <= 0 && strings.TrimSpace(query) == "" {
-		templates, err := s.templateRepo.ListWorkoutTemplates(ctx)
+		templates, err := s.templateRepo.ListWorkoutTemplates(ctx, filter)
 		if err != nil {
 			return nil, err
 		}
 		items := make([]any, 0, len(templates))
 		for _, t := range templates {
 			items = append(items, WorkoutTemplateSummary{
-				ID: t.ID, Title: t.Title, Type: t.Type, Gender: t.Gender,
+				ID: t.ID, CoachID: t.CoachID, IsCustom: t.CoachID != nil,
+				Title: t.Title, Type: t.Type, Gender: t.Gender,
 				Location: t.Location, DayCount: t.DayCount, Target: t.Target,
 				Level: t.Level, Injury: t.Injury,
 			})
@@ -448,7 +477,7 @@ func (s *coachProgramService) ListWorkoutTemplates(ctx context.Context, page, pa
 	if pageSize <= 0 {
 		pageSize = 20
 	}
-	templates, total, err := s.templateRepo.ListWorkoutTemplatesPaged(ctx, page, pageSize, query)
+	templates, total, err := s.templateRepo.ListWorkoutTemplatesPaged(ctx, page, pageSize, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -457,25 +486,23 @@ func (s *coachProgramService) ListWorkoutTemplates(ctx context.Context, page, pa
 		var c int64
 		_ = s.db.WithContext(ctx).Model(&models.TemplateProgramItem{}).
 			Where("workout_template_id = ?", t.ID).Count(&c)
-		items = append(items, AdminWorkoutTemplateSummary{
-			ID: t.ID, Title: t.Title, Type: t.Type, Gender: t.Gender,
-			Location: t.Location, DayCount: t.DayCount, Target: t.Target,
-			Level: t.Level, Injury: t.Injury, ItemCount: int(c),
-		})
+		items = append(items, workoutTemplateSummary(&t, int(c)))
 	}
 	return &TemplateListResponse{Items: items, Total: int(total), Page: page, PageSize: pageSize}, nil
 }
 
-func (s *coachProgramService) ListNutritionTemplates(ctx context.Context, page, pageSize int, query string) (*TemplateListResponse, error) {
+func (s *coachProgramService) ListNutritionTemplates(ctx context.Context, coachID uint, page, pageSize int, query, source string) (*TemplateListResponse, error) {
+	filter := coachTemplateFilter(coachID, query, source)
 	if page <= 0 && pageSize <= 0 && strings.TrimSpace(query) == "" {
-		templates, err := s.templateRepo.ListNutritionTemplates(ctx)
+		templates, err := s.templateRepo.ListNutritionTemplates(ctx, filter)
 		if err != nil {
 			return nil, err
 		}
 		items := make([]any, 0, len(templates))
 		for _, t := range templates {
 			items = append(items, NutritionTemplateSummary{
-				ID: t.ID, Title: t.Title, Type: t.Type, Gender: t.Gender,
+				ID: t.ID, CoachID: t.CoachID, IsCustom: t.CoachID != nil,
+				Title: t.Title, Type: t.Type, Gender: t.Gender,
 				Target: t.Target, Limitation: t.Limitation, Calorie: t.Calorie,
 			})
 		}
@@ -487,7 +514,7 @@ func (s *coachProgramService) ListNutritionTemplates(ctx context.Context, page,
 	if pageSize <= 0 {
 		pageSize = 20
 	}
-	templates, total, err := s.templateRepo.ListNutritionTemplatesPaged(ctx, page, pageSize, query)
+	templates, total, err := s.templateRepo.ListNutritionTemplatesPaged(ctx, page, pageSize, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -496,16 +523,12 @@ func (s *coachProgramService) ListNutritionTemplates(ctx context.Context, page,
 		var c int64
 		_ = s.db.WithContext(ctx).Model(&models.TemplateMeal{}).
 			Where("nutrition_template_id = ?", t.ID).Count(&c)
-		items = append(items, AdminNutritionTemplateSummary{
-			ID: t.ID, Title: t.Title, Type: t.Type, Gender: t.Gender,
-			Target: t.Target, Limitation: t.Limitation, Calorie: t.Calorie,
-			IsPro: t.IsPro, MealCount: int(c),
-		})
+		items = append(items, nutritionTemplateSummary(&t, int(c)))
 	}
 	return &TemplateListResponse{Items: items, Total: int(total), Page: page, PageSize: pageSize}, nil
 }
 
-func (s *coachProgramService) GetWorkoutTemplate(ctx context.Context, id uint) (*AdminWorkoutTemplateDetail, error) {
+func (s *coachProgramService) findWorkoutTemplate(ctx context.Context, coachID, id uint) (*models.WorkoutTemplate, error) {
 	t, err := s.templateRepo.FindWorkoutTemplateByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -513,10 +536,13 @@ func (s *coachProgramService) GetWorkoutTemplate(ctx context.Context, id uint) (
 		}
 		return nil, err
 	}
-	return workoutTemplateToDetail(ctx, s.exerciseRepo, t), nil
+	if !coachCanAccessTemplate(coachID, t.CoachID) {
+		return nil, ErrCoachTemplateForbidden
+	}
+	return t, nil
 }
 
-func (s *coachProgramService) GetNutritionTemplate(ctx context.Context, id uint) (*AdminNutritionTemplateDetail, error) {
+func (s *coachProgramService) findNutritionTemplate(ctx context.Context, coachID, id uint) (*models.NutritionTemplate, error) {
 	t, err := s.templateRepo.FindNutritionTemplateByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -524,6 +550,25 @@ func (s *coachProgramService) GetNutritionTemplate(ctx context.Context, id uint)
 		}
 		return nil, err
 	}
+	if !coachCanAccessTemplate(coachID, t.CoachID) {
+		return nil, ErrCoachTemplateForbidden
+	}
+	return t, nil
+}
+
+func (s *coachProgramService) GetWorkoutTemplate(ctx context.Context, coachID, id uint) (*AdminWorkoutTemplateDetail, error) {
+	t, err := s.findWorkoutTemplate(ctx, coachID, id)
+	if err != nil {
+		return nil, err
+	}
+	return workoutTemplateToDetail(ctx, s.exerciseRepo, t), nil
+}
+
+func (s *coachProgramService) GetNutritionTemplate(ctx context.Context, coachID, id uint) (*AdminNutritionTemplateDetail, error) {
+	t, err := s.findNutritionTemplate(ctx, coachID, id)
+	if err != nil {
+		return nil, err
+	}
 	return nutritionTemplateToDetail(t), nil
 }
 
@@ -533,11 +578,8 @@ func (s *coachProgramService) AssignWorkoutFromTemplate(ctx context.Context, coa
 		return nil, err
 	}
 
-	template, err := s.templateRepo.FindWorkoutTemplateByID(ctx, templateID)
+	template, err := s.findWorkoutTemplate(ctx, coachID, templateID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrCoachTemplateNotFound
-		}
 		return nil, err
 	}
 
@@ -560,17 +602,44 @@ func (s *coachProgramService) AssignWorkoutFromTemplate(ctx context.Context, coa
 	return resp, nil
 }
 
+func (s *coachProgramService) WorkoutTemplatePlan(ctx context.Context, coachID, templateID uint) (*ProgramAssignRequest, error) {
+	template, err := s.findWorkoutTemplate(ctx, coachID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	planByDay := workoutTemplateToPlanByDay(template)
+	durationWeeks := template.DayCount
+	if durationWeeks <= 0 {
+		durationWeeks = 4
+	}
+	schedule := &MeScheduleDTO{Weekly: []string{}, RestDays: []string{}}
+	for _, key := range allDayKeys {
+		if day, ok := planByDay[key]; ok && day.Workout != nil {
+			schedule.Weekly = append(schedule.Weekly, key)
+		} else {
+			schedule.RestDays = append(schedule.RestDays, key)
+		}
+	}
+	title := strings.TrimSpace(template.Title)
+	if title == "" {
+		title = "برنامه تمرین"
+	}
+	return &ProgramAssignRequest{
+		Title:         title,
+		DurationWeeks: durationWeeks,
+		Schedule:      schedule,
+		PlanByDay:     planByDay,
+	}, nil
+}
+
 func (s *coachProgramService) AssignNutritionFromTemplate(ctx context.Context, coachID, studentID, templateID uint) (*CoachStudentProgramsResponse, error) {
 	sub, err := s.resolveActiveSubscription(ctx, coachID, studentID)
 	if err != nil {
 		return nil, err
 	}
 
-	template, err := s.templateRepo.FindNutritionTemplateByID(ctx, templateID)
+	template, err := s.findNutritionTemplate(ctx, coachID, templateID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrCoachTemplateNotFound
-		}
 		return nil, err
 	}
 
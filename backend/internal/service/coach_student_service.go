@@ -33,6 +33,10 @@ type CoachStudentDetail struct {
 	OrderNote               string     `json:"orderNote,omitempty"`
 	FunnelAnalysisTitle     string     `json:"funnelAnalysisTitle,omitempty"`
 	FunnelAnalysisBody      string     `json:"funnelAnalysisBody,omitempty"`
+	Gender                  string     `json:"gender,omitempty"`
+	PrimaryGoal             string     `json:"primaryGoal,omitempty"`
+	Injuries                string     `json:"injuries,omitempty"`
+	PhysicalLimitations     string     `json:"physicalLimitations,omitempty"`
 }
 
 type CoachStudentService interface {
@@ -199,10 +203,14 @@ func (s *coachStudentService) GetStudent(ctx context.Context, coachID, studentID
 	}
 
 	detail := &CoachStudentDetail{
-		AdminStudentItem: *item,
-		Email:            user.Email,
-		HeightCm:         user.HeightCm,
-		WeightKg:         user.WeightKg,
+		AdminStudentItem:    *item,
+		Email:               user.Email,
+		HeightCm:            user.HeightCm,
+		WeightKg:            user.WeightKg,
+		Gender:              user.Gender,
+		PrimaryGoal:         user.PrimaryGoal,
+		Injuries:            strings.TrimSpace(user.Injuries),
+		PhysicalLimitations: strings.TrimSpace(user.PhysicalLimitations),
 	}
 
 	sub, err := s.subRepo.FindCurrentByUserIDAndCoachID(ctx, studentID, coachID, now)
@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+var ErrCoachNoProgramToSave = errors.New("student has no active program to save")
+
+// CoachTemplateService manages a coach's private template library. Platform
+// templates (CoachID nil) stay read-only for coaches; they can only be duplicated.
+// Editing a template listed on the marketplace sends the listing back to admin
+// review; deleting it unlists it.
+type CoachTemplateService interface {
+	CreateWorkoutTemplate(ctx context.Context, coachID uint, req *AdminWorkoutTemplateUpsertRequest) (*AdminWorkoutTemplateDetail, error)
+	UpdateWorkoutTemplate(ctx context.Context, coachID, id uint, req *AdminWorkoutTemplateUpsertRequest) (*AdminWorkoutTemplateDetail, error)
+	DuplicateWorkoutTemplate(ctx context.Context, coachID, id uint) (*AdminWorkoutTemplateDetail, error)
+	DeleteWorkoutTemplate(ctx context.Context, coachID, id uint) error
+	// SaveStudentWorkoutAsTemplate copies the student's active workout program into
+	// the coach's library. req carries template metadata; its Items are ignored.
+	SaveStudentWorkoutAsTemplate(ctx context.Context, coachID, studentID uint, req *AdminWorkoutTemplateUpsertRequest) (*AdminWorkoutTemplateDetail, error)
+
+	CreateNutritionTemplate(ctx context.Context, coachID uint, req *AdminNutritionTemplateUpsertRequest) (*AdminNutritionTemplateDetail, error)
+	UpdateNutritionTemplate(ctx context.Context, coachID, id uint, req *AdminNutritionTemplateUpsertRequest) (*AdminNutritionTemplateDetail, error)
+	DuplicateNutritionTemplate(ctx context.Context, coachID, id uint) (*AdminNutritionTemplateDetail, error)
+	DeleteNutritionTemplate(ctx context.Context, coachID, id uint) error
+	// SaveStudentNutritionAsTemplate copies the first planned day of the student's
+	// active nutrition program (templates hold one day applied to the whole week).
+	SaveStudentNutritionAsTemplate(ctx context.Context, coachID, studentID uint, req *AdminNutritionTemplateUpsertRequest) (*AdminNutritionTemplateDetail, error)
+}
+
+type coachTemplateService struct {
+	templateRepo    repository.TemplateRepository
+	listingRepo     repository.TemplateListingRepository
+	exerciseRepo    repository.ExerciseRepository
+	subRepo         repository.SubscriptionRepository
+	programRepo     repository.ProgramRepository
+	coachStudentSvc CoachStudentService
+}
+
+func NewCoachTemplateService(
+	templateRepo repository.TemplateRepository,
+	listingRepo repository.TemplateListingRepository,
+	exerciseRepo repository.ExerciseRepository,
+	subRepo repository.SubscriptionRepository,
+	programRepo repository.ProgramRepository,
+	coachStudentSvc CoachStudentService,
+) CoachTemplateService {
+	return &coachTemplateService{
+		templateRepo:    templateRepo,
+		listingRepo:     listingRepo,
+		exerciseRepo:    exerciseRepo,
+		subRepo:         subRepo,
+		programRepo:     programRepo,
+		coachStudentSvc: coachStudentSvc,
+	}
+}
+
+func (s *coachTemplateService) workoutDetail(ctx context.Context, id uint) (*AdminWorkoutTemplateDetail, error) {
+	t, err := s.templateRepo.FindWorkoutTemplateByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachTemplateNotFound
+		}
+		return nil, err
+	}
+	return workoutTemplateToDetail(ctx, s.exerciseRepo, t), nil
+}
+
+func (s *coachTemplateService) nutritionDetail(ctx context.Context, id uint) (*AdminNutritionTemplateDetail, error) {
+	t, err := s.templateRepo.FindNutritionTemplateByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachTemplateNotFound
+		}
+		return nil, err
+	}
+	return nutritionTemplateToDetail(t), nil
+}
+
+// findOwnedWorkout loads a template the coach may modify (their own only).
+func (s *coachTemplateService) findOwnedWorkout(ctx context.Context, coachID, id uint) (*models.WorkoutTemplate, error) {
+	t, err := s.templateRepo.FindWorkoutTemplateByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachTemplateNotFound
+		}
+		return nil, err
+	}
+	if t.CoachID == nil || *t.CoachID != coachID {
+		return nil, ErrCoachTemplateForbidden
+	}
+	return t, nil
+}
+
+func (s *coachTemplateService) findOwnedNutrition(ctx context.Context, coachID, id uint) (*models.NutritionTemplate, error) {
+	t, err := s.templateRepo.FindNutritionTemplateByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachTemplateNotFound
+		}
+		return nil, err
+	}
+	if t.CoachID == nil || *t.CoachID != coachID {
+		return nil, ErrCoachTemplateForbidden
+	}
+	return t, nil
+}
+
+func (s *coachTemplateService) CreateWorkoutTemplate(ctx context.Context, coachID uint, req *AdminWorkoutTemplateUpsertRequest) (*AdminWorkoutTemplateDetail, error) {
+	owner := coachID
+	t, err := newWorkoutTemplate(ctx, s.templateRepo, &owner, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.CreateWorkoutTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return s.workoutDetail(ctx, t.ID)
+}
+
+func (s *coachTemplateService) UpdateWorkoutTemplate(ctx context.Context, coachID, id uint, req *AdminWorkoutTemplateUpsertRequest) (*AdminWorkoutTemplateDetail, error) {
+	t, err := s.findOwnedWorkout(ctx, coachID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := updateWorkoutTemplate(ctx, s.templateRepo, t, req); err != nil {
+		return nil, err
+	}
+	if err := s.listingRepo.ReopenByTemplate(ctx, models.TemplateKindWorkout, id); err != nil {
+		return nil, err
+	}
+	return s.workoutDetail(ctx, id)
+}
+
+func (s *coachTemplateService) DuplicateWorkoutTemplate(ctx context.Context, coachID, id uint) (*AdminWorkoutTemplateDetail, error) {
+	src, err := s.templateRepo.FindWorkoutTemplateByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachTemplateNotFound
+		}
+		return nil, err
+	}
+	if !coachCanAccessTemplate(coachID, src.CoachID) {
+		return nil, ErrCoachTemplateForbidden
+	}
+	detail := workoutTemplateToDetail(ctx, s.exerciseRepo, src)
+	owner := coachID
+	t, err := newWorkoutTemplate(ctx, s.templateRepo, &owner, &AdminWorkoutTemplateUpsertRequest{
+		Title:    duplicateTemplateTitle(src.Title),
+		Type:     src.Type,
+		Gender:   src.Gender,
+		Location: src.Location,
+		DayCount: src.DayCount,
+		Target:   src.Target,
+		Level:    src.Level,
+		Injury:   src.Injury,
+		Items:    detail.Items,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// A duplicate of a marketplace copy is still someone else's work.
+	t.AcquiredListingID = src.AcquiredListingID
+	if err := s.templateRepo.CreateWorkoutTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return s.workoutDetail(ctx, t.ID)
+}
+
+func (s *coachTemplateService) DeleteWorkoutTemplate(ctx context.Context, coachID, id uint) error {
+	if _, err := s.findOwnedWorkout(ctx, coachID, id); err != nil {
+		return err
+	}
+	if err := s.templateRepo.DeleteWorkoutTemplate(ctx, id); err != nil {
+		return err
+	}
+	return s.listingRepo.UnlistByTemplate(ctx, models.TemplateKindWorkout, id)
+}
+
+func (s *coachTemplateService) resolveStudentSubscription(ctx context.Context, coachID, studentID uint) (*models.Subscription, error) {
+	ok, err := s.coachStudentSvc.CanAccessStudent(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCoachStudentForbidden
+	}
+	sub, err := s.subRepo.FindCurrentByUserIDAndCoachID(ctx, studentID, coachID, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachNoActiveSubscription
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *coachTemplateService) SaveStudentWorkoutAsTemplate(ctx context.Context, coachID, studentID uint, req *AdminWorkoutTemplateUpsertRequest) (*AdminWorkoutTemplateDetail, error) {
+	sub, err := s.resolveStudentSubscription(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	program, err := s.programRepo.FindActiveWorkoutBySubscriptionID(ctx, sub.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if program == nil {
+		return nil, ErrCoachNoProgramToSave
+	}
+	items, err := s.programRepo.FindWorkoutItemsByProgramID(ctx, program.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrCoachNoProgramToSave
+	}
+
+	meta := *req
+	if strings.TrimSpace(meta.Title) == "" {
+		meta.Title = program.Title
+	}
+	meta.Items = programItemsToTemplateItems(items)
+	meta.DayCount = 0
+	return s.CreateWorkoutTemplate(ctx, coachID, &meta)
+}
+
+func (s *coachTemplateService) CreateNutritionTemplate(ctx context.Context, coachID uint, req *AdminNutritionTemplateUpsertRequest) (*AdminNutritionTemplateDetail, error) {
+	owner := coachID
+	t, err := newNutritionTemplate(ctx, s.templateRepo, &owner, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.CreateNutritionTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return s.nutritionDetail(ctx, t.ID)
+}
+
+func (s *coachTemplateService) UpdateNutritionTemplate(ctx context.Context, coachID, id uint, req *AdminNutritionTemplateUpsertRequest) (*AdminNutritionTemplateDetail, error) {
+	t, err := s.findOwnedNutrition(ctx, coachID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := updateNutritionTemplate(ctx, s.templateRepo, t, req); err != nil {
+		return nil, err
+	}
+	if err := s.listingRepo.ReopenByTemplate(ctx, models.TemplateKindNutrition, id); err != nil {
+		return nil, err
+	}
+	return s.nutritionDetail(ctx, id)
+}
+
+func (s *coachTemplateService) DuplicateNutritionTemplate(ctx context.Context, coachID, id uint) (*AdminNutritionTemplateDetail, error) {
+	src, err := s.templateRepo.FindNutritionTemplateByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachTemplateNotFound
+		}
+		return nil, err
+	}
+	if !coachCanAccessTemplate(coachID, src.CoachID) {
+		return nil, ErrCoachTemplateForbidden
+	}
+	detail := nutritionTemplateToDetail(src)
+	owner := coachID
+	t, err := newNutritionTemplate(ctx, s.templateRepo, &owner, &AdminNutritionTemplateUpsertRequest{
+		Title:       duplicateTemplateTitle(src.Title),
+		Type:        src.Type,
+		Gender:      src.Gender,
+		Target:      src.Target,
+		Limitation:  src.Limitation,
+		Calorie:     src.Calorie,
+		Description: src.Description,
+		IsPro:       src.IsPro,
+		Meals:       detail.Meals,
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.AcquiredListingID = src.AcquiredListingID
+	if err := s.templateRepo.CreateNutritionTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return s.nutritionDetail(ctx, t.ID)
+}
+
+func (s *coachTemplateService) DeleteNutritionTemplate(ctx context.Context, coachID, id uint) error {
+	if _, err := s.findOwnedNutrition(ctx, coachID, id); err != nil {
+		return err
+	}
+	if err := s.templateRepo.DeleteNutritionTemplate(ctx, id); err != nil {
+		return err
+	}
+	return s.listingRepo.UnlistByTemplate(ctx, models.TemplateKindNutrition, id)
+}
+
+func (s *coachTemplateService) SaveStudentNutritionAsTemplate(ctx context.Context, coachID, studentID uint, req *AdminNutritionTemplateUpsertRequest) (*AdminNutritionTemplateDetail, error) {
+	sub, err := s.resolveStudentSubscription(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	program, err := s.programRepo.FindActiveNutritionBySubscriptionID(ctx, sub.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if program == nil {
+		return nil, ErrCoachNoProgramToSave
+	}
+	items, err := s.programRepo.FindNutritionItemsByProgramID(ctx, program.ID)
+	if err != nil {
+		return nil, err
+	}
+	meals, calories := nutritionItemsToTemplateMeals(items)
+	if len(meals) == 0 {
+		return nil, ErrCoachNoProgramToSave
+	}
+
+	meta := *req
+	if strings.TrimSpace(meta.Title) == "" {
+		meta.Title = program.Title
+	}
+	if strings.TrimSpace(meta.Description) == "" {
+		meta.Description = program.Notes
+	}
+	if meta.Calorie <= 0 {
+		meta.Calorie = calories
+	}
+	meta.Meals = meals
+	return s.CreateNutritionTemplate(ctx, coachID, &meta)
+}
+
+func duplicateTemplateTitle(title string) string {
+	return strings.TrimSpace(title) + " (کپی)"
+}
+
+func programItemsToTemplateItems(items []models.ProgramItem) []AdminTemplateItemDTO {
+	out := make([]AdminTemplateItemDTO, 0, len(items))
+	for _, it := range items {
+		ex := programItemToExerciseDTO(it)
+		if ex.Name == "" {
+			continue
+		}
+		dto := AdminTemplateItemDTO{
+			DayNumber:         it.DayNumber,
+			OrderIndex:        it.OrderIndex,
+			Exercise:          ex.Name,
+			Notes:             it.Notes,
+			SupersetID:        it.SupersetID,
+			WorkoutSystemType: ex.WorkoutSystemType,
+		}
+		if ex.ExerciseID > 0 {
+			id := ex.ExerciseID
+			dto.ExerciseID = &id
+		}
+		for _, st := range ex.SetsDetails {
+			dto.SetsDetails = append(dto.SetsDetails, AdminTemplateSetDTO{
+				SetNumber: st.SetNumber,
+				Reps:      st.Reps,
+				IsAMRAP:   st.IsAMRAP,
+			})
+		}
+		out = append(out, dto)
+	}
+	return out
+}
+
+// nutritionItemsToTemplateMeals groups the first planned day by meal slot and
+// returns the meals plus that day's total calories.
+func nutritionItemsToTemplateMeals(items []models.NutritionItem) ([]AdminNutritionMealDTO, int) {
+	day := 0
+	for _, it := range items {
+		if _, ok := dayNumberToKey[it.DayNumber]; ok && (day == 0 || it.DayNumber < day) {
+			day = it.DayNumber
+		}
+	}
+	if day == 0 {
+		return nil, 0
+	}
+
+	bySlot := map[string]*AdminNutritionMealDTO{}
+	total := 0
+	for _, it := range items {
+		if it.DayNumber != day {
+			continue
+		}
+		slot := it.MealSlot
+		if !IsValidMealSlot(slot) {
+			slot = MealSlotSnack1
+		}
+		meal := bySlot[slot]
+		if meal == nil {
+			meal = &AdminNutritionMealDTO{
+				MealOrder: mealSlotRank(slot) + 1,
+				MealName:  MealSlotLabel(slot),
+			}
+			bySlot[slot] = meal
+		}
+		value := mealMultiplier(it.Multiplier)
+		unit, ok := templateUnitFromQuantity(it.Quantity, value)
+		item := AdminNutritionMealItemDTO{
+			OrderIndex: len(meal.Items) + 1,
+			FoodName:   it.Food,
+			Unit:       unit,
+			Value:      value,
+		}
+		if !ok {
+			item.Description = strings.TrimSpace(it.Quantity)
+		}
+		if it.FoodID != nil && *it.FoodID > 0 {
+			id := *it.FoodID
+			item.FoodID = &id
+		}
+		meal.Items = append(meal.Items, item)
+		meal.MealCalorie += it.Calories
+		total += it.Calories
+	}
+
+	out := make([]AdminNutritionMealDTO, 0, len(bySlot))
+	for _, slot := range mealSlotOrder {
+		if meal := bySlot[slot]; meal != nil {
+			out = append(out, *meal)
+		}
+	}
+	return out, total
+}
+
+// templateUnitFromQuantity recovers the unit from a quantity rendered by
+// formatTemplateFoodQuantity ("2 عدد" for value 2). Free-text quantities that
+// don't start with the multiplier report ok=false.
+func templateUnitFromQuantity(quantity string, value float64) (string, bool) {
+	fields := strings.Fields(strings.TrimSpace(quantity))
+	if len(fields) == 0 {
+		return "", true
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || math.Abs(v-value) > 1e-9 {
+		return "", false
+	}
+	return strings.Join(fields[1:], " "), true
+}
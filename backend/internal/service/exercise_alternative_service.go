@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+var (
+	ErrExerciseSwapNotAllowed     = errors.New("exercise swaps are not enabled by your coach")
+	ErrExerciseSwapNotFound       = errors.New("exercise swap not found")
+	ErrExerciseSwapInvalidItem    = errors.New("program item not found in your active program")
+	ErrExerciseSwapNotAlternative = errors.New("replacement is not an approved alternative for this exercise")
+)
+
+const (
+	defaultAlternativeLimit = 10
+	maxAlternativeLimit     = 50
+	// minAlternativeScore drops candidates that only share a body part loosely.
+	minAlternativeScore = 20
+)
+
+// homeEquipment is the equipment assumed available for location=home.
+var homeEquipment = []string{
+	"وزن بدن", "دمبل", "باند", "باند مقاومت", "کتل بل", "توپ پایداری",
+	"توپ پزشکی", "طناب", "غلتک", "غلتک چرخ", "توپ بوسو",
+}
+
+// ExerciseAlternativeOptions narrows alternatives to what the student can use.
+// An explicit Equipment list wins over Location.
+type ExerciseAlternativeOptions struct {
+	Equipment []string
+	Location  string // home | gym
+	Limit     int
+}
+
+type ExerciseAlternativeDTO struct {
+	Exercise      AdminExerciseItem `json:"exercise"`
+	Score         int               `json:"score"`
+	SharedMuscles []string          `json:"sharedMuscles"`
+	Reasons       []string          `json:"reasons"`
+}
+
+type ExerciseAlternativesResponse struct {
+	Exercise      AdminExerciseItem        `json:"exercise"`
+	InjuryRegions []string                 `json:"injuryRegions"`
+	Equipment     []string                 `json:"equipment"`
+	Items         []ExerciseAlternativeDTO `json:"items"`
+	ExcludedCount int                      `json:"excludedCount"`
+}
+
+type ExerciseSwapDTO struct {
+	ID                    uint   `json:"id"`
+	WorkoutProgramID      uint   `json:"workoutProgramId"`
+	ProgramItemID         uint   `json:"programItemId"`
+	OriginalExerciseID    *uint  `json:"originalExerciseId,omitempty"`
+	OriginalExercise      string `json:"originalExercise"`
+	ReplacementExerciseID uint   `json:"replacementExerciseId"`
+	ReplacementExercise   string `json:"replacementExercise"`
+	Reason                string `json:"reason,omitempty"`
+	CreatedAt             string `json:"createdAt"`
+}
+
+type ExerciseSwapsResponse struct {
+	Allowed          bool              `json:"allowed"`
+	WorkoutProgramID uint              `json:"workoutProgramId,omitempty"`
+	Items            []ExerciseSwapDTO `json:"items"`
+}
+
+type ExerciseSwapRequest struct {
+	ProgramItemID         uint   `json:"programItemId" binding:"required"`
+	ReplacementExerciseID uint   `json:"replacementExerciseId" binding:"required"`
+	Reason                string `json:"reason"`
+}
+
+// ExerciseAlternativeService ranks substitute exercises and manages student swaps.
+type ExerciseAlternativeService interface {
+	// Alternatives ranks substitutes for exerciseID, excluding ones contraindicated
+	// for the requesting user's injuries.
+	Alternatives(ctx context.Context, userID, exerciseID uint, opts ExerciseAlternativeOptions) (*ExerciseAlternativesResponse, error)
+	// CoachAlternatives is Alternatives from the coach catalog, using studentID's
+	// injuries when studentID > 0.
+	CoachAlternatives(ctx context.Context, coachID, exerciseID, studentID uint, opts ExerciseAlternativeOptions) (*ExerciseAlternativesResponse, error)
+	ListMySwaps(ctx context.Context, userID uint) (*ExerciseSwapsResponse, error)
+	SwapExercise(ctx context.Context, userID uint, req *ExerciseSwapRequest) (*ExerciseSwapDTO, error)
+	RevertSwap(ctx context.Context, userID, swapID uint) error
+	SetSwapPermission(ctx context.Context, coachID, studentID uint, allowed bool) (*ExerciseSwapsResponse, error)
+}
+
+type exerciseAlternativeService struct {
+	exerciseRepo    repository.ExerciseRepository
+	swapRepo        repository.ExerciseSwapRepository
+	subRepo         repository.SubscriptionRepository
+	programRepo     repository.ProgramRepository
+	userRepo        repository.UserRepository
+	coachStudentSvc CoachStudentService
+}
+
+func NewExerciseAlternativeService(
+	exerciseRepo repository.ExerciseRepository,
+	swapRepo repository.ExerciseSwapRepository,
+	subRepo repository.SubscriptionRepository,
+	programRepo repository.ProgramRepository,
+	userRepo repository.UserRepository,
+	coachStudentSvc CoachStudentService,
+) ExerciseAlternativeService {
+	return &exerciseAlternativeService{
+		exerciseRepo:    exerciseRepo,
+		swapRepo:        swapRepo,
+		subRepo:         subRepo,
+		programRepo:     programRepo,
+		userRepo:        userRepo,
+		coachStudentSvc: coachStudentSvc,
+	}
+}
+
+func (s *exerciseAlternativeService) Alternatives(ctx context.Context, userID, exerciseID uint, opts ExerciseAlternativeOptions) (*ExerciseAlternativesResponse, error) {
+	ex, err := s.findExercise(ctx, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	// Students only see the global catalog plus their coach's custom exercises.
+	var scope *uint
+	if sub, err := s.subRepo.FindCurrentByUserID(ctx, userID, time.Now()); err == nil && sub.CoachID > 0 {
+		scope = &sub.CoachID
+	}
+	if ex.CoachID != nil && (scope == nil || *ex.CoachID != *scope) {
+		return nil, ErrExerciseForbidden
+	}
+	return s.rank(ctx, ex, scope, s.injuryRegions(ctx, userID), opts)
+}
+
+func (s *exerciseAlternativeService) CoachAlternatives(ctx context.Context, coachID, exerciseID, studentID uint, opts ExerciseAlternativeOptions) (*ExerciseAlternativesResponse, error) {
+	ex, err := s.findExercise(ctx, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	if !coachCanAccessExercise(coachID, ex) {
+		return nil, ErrExerciseForbidden
+	}
+	var regions []string
+	if studentID > 0 {
+		ok, err := s.coachStudentSvc.CanAccessStudent(ctx, coachID, studentID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrCoachStudentForbidden
+		}
+		regions = s.injuryRegions(ctx, studentID)
+	}
+	return s.rank(ctx, ex, &coachID, regions, opts)
+}
+
+func (s *exerciseAlternativeService) ListMySwaps(ctx context.Context, userID uint) (*ExerciseSwapsResponse, error) {
+	sub, err := s.subRepo.FindCurrentByUserID(ctx, userID, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &ExerciseSwapsResponse{Items: []ExerciseSwapDTO{}}, nil
+		}
+		return nil, err
+	}
+	return s.swapsForSubscription(ctx, sub)
+}
+
+func (s *exerciseAlternativeService) SwapExercise(ctx context.Context, userID uint, req *ExerciseSwapRequest) (*ExerciseSwapDTO, error) {
+	sub, err := s.subRepo.FindCurrentByUserID(ctx, userID, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExerciseSwapInvalidItem
+		}
+		return nil, err
+	}
+	if !sub.AllowExerciseSwaps {
+		return nil, ErrExerciseSwapNotAllowed
+	}
+	program, err := s.programRepo.FindActiveWorkoutBySubscriptionID(ctx, sub.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExerciseSwapInvalidItem
+		}
+		return nil, err
+	}
+	item, err := s.swapRepo.FindProgramItem(ctx, req.ProgramItemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExerciseSwapInvalidItem
+		}
+		return nil, err
+	}
+	if item.WorkoutProgramID != program.ID {
+		return nil, ErrExerciseSwapInvalidItem
+	}
+
+	original, err := s.programItemExercise(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	alternatives, err := s.Alternatives(ctx, userID, original.ID, ExerciseAlternativeOptions{Limit: maxAlternativeLimit})
+	if err != nil {
+		return nil, err
+	}
+	var replacement *AdminExerciseItem
+	for i := range alternatives.Items {
+		if alternatives.Items[i].Exercise.ID == req.ReplacementExerciseID {
+			replacement = &alternatives.Items[i].Exercise
+			break
+		}
+	}
+	if replacement == nil {
+		return nil, ErrExerciseSwapNotAlternative
+	}
+
+	reason := []rune(strings.TrimSpace(req.Reason))
+	if len(reason) > 255 {
+		reason = reason[:255]
+	}
+	swap := &models.ExerciseSwap{
+		UserID:                userID,
+		SubscriptionID:        sub.ID,
+		WorkoutProgramID:      program.ID,
+		ProgramItemID:         item.ID,
+		OriginalExerciseID:    &original.ID,
+		OriginalExercise:      strings.TrimSpace(item.Exercise),
+		ReplacementExerciseID: replacement.ID,
+		ReplacementExercise:   replacement.Name,
+		Reason:                string(reason),
+	}
+	if swap.OriginalExercise == "" {
+		swap.OriginalExercise = original.Name
+	}
+	if err := s.swapRepo.Create(ctx, swap); err != nil {
+		return nil, err
+	}
+	dto := exerciseSwapToDTO(swap)
+	return &dto, nil
+}
+
+func (s *exerciseAlternativeService) RevertSwap(ctx context.Context, userID, swapID uint) error {
+	swap, err := s.swapRepo.FindByID(ctx, swapID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrExerciseSwapNotFound
+		}
+		return err
+	}
+	if swap.UserID != userID {
+		return ErrExerciseSwapNotFound
+	}
+	return s.swapRepo.Delete(ctx, swap.ID)
+}
+
+func (s *exerciseAlternativeService) SetSwapPermission(ctx context.Context, coachID, studentID uint, allowed bool) (*ExerciseSwapsResponse, error) {
+	ok, err := s.coachStudentSvc.CanAccessStudent(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCoachStudentForbidden
+	}
+	sub, err := s.subRepo.FindCurrentByUserIDAndCoachID(ctx, studentID, coachID, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachNoActiveSubscription
+		}
+		return nil, err
+	}
+	if err := s.subRepo.SetAllowExerciseSwaps(ctx, sub.ID, allowed); err != nil {
+		return nil, err
+	}
+	sub.AllowExerciseSwaps = allowed
+	return s.swapsForSubscription(ctx, sub)
+}
+
+func (s *exerciseAlternativeService) swapsForSubscription(ctx context.Context, sub *models.Subscription) (*ExerciseSwapsResponse, error) {
+	return loadExerciseSwaps(ctx, s.programRepo, s.swapRepo, sub)
+}
+
+// loadExerciseSwaps returns the live swaps on the subscription's active workout program.
+func loadExerciseSwaps(ctx context.Context, programRepo repository.ProgramRepository, swapRepo repository.ExerciseSwapRepository, sub *models.Subscription) (*ExerciseSwapsResponse, error) {
+	resp := &ExerciseSwapsResponse{Allowed: sub.AllowExerciseSwaps, Items: []ExerciseSwapDTO{}}
+	program, err := programRepo.FindActiveWorkoutBySubscriptionID(ctx, sub.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return resp, nil
+		}
+		return nil, err
+	}
+	resp.WorkoutProgramID = program.ID
+	swaps, err := swapRepo.ListByProgram(ctx, program.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range swaps {
+		resp.Items = append(resp.Items, exerciseSwapToDTO(&swaps[i]))
+	}
+	return resp, nil
+}
+
+func (s *exerciseAlternativeService) findExercise(ctx context.Context, id uint) (*models.Exercise, error) {
+	ex, err := s.exerciseRepo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExerciseNotFound
+		}
+		return nil, err
+	}
+	return ex, nil
+}
+
+// programItemExercise resolves the catalog exercise behind a program item, falling back
+// to a name lookup for items saved before ExerciseID was recorded.
+func (s *exerciseAlternativeService) programItemExercise(ctx context.Context, item *models.ProgramItem) (*models.Exercise, error) {
+	if item.ExerciseID != nil && *item.ExerciseID > 0 {
+		return s.findExercise(ctx, *item.ExerciseID)
+	}
+	name := strings.TrimSpace(item.Exercise)
+	if name == "" {
+		return nil, ErrExerciseNotFound
+	}
+	list, err := s.exerciseRepo.FindByNames(ctx, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrExerciseNotFound
+	}
+	return &list[0], nil
+}
+
+func (s *exerciseAlternativeService) injuryRegions(ctx context.Context, userID uint) []string {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	return detectInjuryRegions(user.Injuries + " " + user.PhysicalLimitations)
+}
+
+func (s *exerciseAlternativeService) rank(ctx context.Context, ex *models.Exercise, scope *uint, regions []string, opts ExerciseAlternativeOptions) (*ExerciseAlternativesResponse, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultAlternativeLimit
+	}
+	if limit > maxAlternativeLimit {
+		limit = maxAlternativeLimit
+	}
+	equipment := alternativeEquipment(opts)
+
+	candidates, err := s.exerciseRepo.ListAlternativeCandidates(ctx, ex, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ExerciseAlternativesResponse{
+		Exercise:      exerciseToItem(ex),
+		InjuryRegions: regions,
+		Equipment:     equipment,
+		Items:         []ExerciseAlternativeDTO{},
+	}
+	if resp.InjuryRegions == nil {
+		resp.InjuryRegions = []string{}
+	}
+	if resp.Equipment == nil {
+		resp.Equipment = []string{}
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		if len(equipment) > 0 && !slices.Contains(equipment, strings.TrimSpace(c.Equipment)) {
+			continue
+		}
+		if exerciseContraindicated(c, regions) {
+			resp.ExcludedCount++
+			continue
+		}
+		score, shared, reasons := scoreAlternative(ex, c)
+		if score < minAlternativeScore {
+			continue
+		}
+		resp.Items = append(resp.Items, ExerciseAlternativeDTO{
+			Exercise:      exerciseToItem(c),
+			Score:         score,
+			SharedMuscles: shared,
+			Reasons:       reasons,
+		})
+	}
+	sort.SliceStable(resp.Items, func(i, j int) bool {
+		if resp.Items[i].Score != resp.Items[j].Score {
+			return resp.Items[i].Score > resp.Items[j].Score
+		}
+		return resp.Items[i].Exercise.Name < resp.Items[j].Exercise.Name
+	})
+	if len(resp.Items) > limit {
+		resp.Items = resp.Items[:limit]
+	}
+	return resp, nil
+}
+
+func alternativeEquipment(opts ExerciseAlternativeOptions) []string {
+	var out []string
+	for _, e := range opts.Equipment {
+		if e = strings.TrimSpace(e); e != "" && !slices.Contains(out, e) {
+			out = append(out, e)
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	if strings.EqualFold(strings.TrimSpace(opts.Location), "home") {
+		return append([]string(nil), homeEquipment...)
+	}
+	return nil
+}
+
+// exerciseContraindicated applies the curated Contraindications tags and, as a
+// fallback for untagged catalog rows, the same load heuristics used for template
+// suggestions.
+func exerciseContraindicated(ex *models.Exercise, regions []string) bool {
+	if len(regions) == 0 {
+		return false
+	}
+	tagged := decodeStringSlice(ex.Contraindications)
+	for _, r := range regions {
+		if slices.Contains(tagged, r) || exerciseLoadsRegion(r, ex.Name, ex) {
+			return true
+		}
+	}
+	return false
+}
+
+func scoreAlternative(original, candidate *models.Exercise) (int, []string, []string) {
+	score := 0
+	var reasons []string
+	shared := []string{}
+
+	origTarget := strings.TrimSpace(original.Target)
+	candTarget := strings.TrimSpace(candidate.Target)
+	origSecondary := decodeStringSlice(original.SecondaryMuscles)
+	candSecondary := decodeStringSlice(candidate.SecondaryMuscles)
+
+	if origTarget != "" && origTarget == candTarget {
+		score += 50
+		shared = append(shared, origTarget)
+		reasons = append(reasons, "عضله هدف یکسان")
+	} else {
+		if origTarget != "" && slices.Contains(candSecondary, origTarget) {
+			score += 15
+			shared = append(shared, origTarget)
+			reasons = append(reasons, "عضله هدف به‌عنوان عضله کمکی درگیر است")
+		}
+		if candTarget != "" && slices.Contains(origSecondary, candTarget) {
+			score += 15
+			if !slices.Contains(shared, candTarget) {
+				shared = append(shared, candTarget)
+			}
+		}
+	}
+
+	common := 0
+	for _, m := range candSecondary {
+		if m != "" && slices.Contains(origSecondary, m) && !slices.Contains(shared, m) {
+			shared = append(shared, m)
+			common++
+		}
+	}
+	if common > 0 {
+		score += min(common*8, 24)
+		reasons = append(reasons, "عضلات کمکی مشترک")
+	}
+	if original.BodyPart != "" && original.BodyPart == candidate.BodyPart {
+		score += 10
+	}
+	if original.MuscleGroup != "" && original.MuscleGroup == candidate.MuscleGroup {
+		score += 5
+	}
+	if original.Equipment != "" && original.Equipment == candidate.Equipment {
+		score += 3
+	} else if candidate.Equipment != "" {
+		reasons = append(reasons, "تجهیزات متفاوت: "+candidate.Equipment)
+	}
+	return min(score, 100), shared, reasons
+}
+
+func exerciseSwapToDTO(s *models.ExerciseSwap) ExerciseSwapDTO {
+	return ExerciseSwapDTO{
+		ID:                    s.ID,
+		WorkoutProgramID:      s.WorkoutProgramID,
+		ProgramItemID:         s.ProgramItemID,
+		OriginalExerciseID:    s.OriginalExerciseID,
+		OriginalExercise:      s.OriginalExercise,
+		ReplacementExerciseID: s.ReplacementExerciseID,
+		ReplacementExercise:   s.ReplacementExercise,
+		Reason:                s.Reason,
+		CreatedAt:             s.CreatedAt.Format(time.RFC3339),
+	}
+}
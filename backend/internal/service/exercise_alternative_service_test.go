@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+func TestScoreAlternativePrefersSameTarget(t *testing.T) {
+	bench := &models.Exercise{Name: "پرس سینه هالتر", Target: "سینه ها", BodyPart: "سینه", Equipment: "هالتر",
+		SecondaryMuscles: `["سه سر","دلت ها"]`}
+	dumbbell := &models.Exercise{Name: "پرس سینه دمبل", Target: "سینه ها", BodyPart: "سینه", Equipment: "دمبل",
+		SecondaryMuscles: `["سه سر"]`}
+	dips := &models.Exercise{Name: "دیپ پارالل", Target: "سه سر", BodyPart: "بازو", Equipment: "وزن بدن",
+		SecondaryMuscles: `["سینه ها"]`}
+
+	same, _, _ := scoreAlternative(bench, dumbbell)
+	cross, _, _ := scoreAlternative(bench, dips)
+	if same <= cross || same < minAlternativeScore || cross < minAlternativeScore {
+		t.Fatalf("same-target score %d should beat cross-target %d and both pass the threshold", same, cross)
+	}
+}
+
+func TestExerciseContraindicated(t *testing.T) {
+	tagged := &models.Exercise{Name: "پل باسن", Target: "باسن", Contraindications: `["back"]`}
+	if !exerciseContraindicated(tagged, []string{"back"}) {
+		t.Fatal("tagged contraindication should exclude")
+	}
+	if exerciseContraindicated(tagged, []string{"wrist"}) {
+		t.Fatal("unrelated injury should not exclude")
+	}
+	squat := &models.Exercise{Name: "اسکوات گابلت", Target: "چهار"}
+	if !exerciseContraindicated(squat, []string{"knee"}) {
+		t.Fatal("untagged squat should fall back to the knee load heuristic")
+	}
+}
+
+func TestAlternativeEquipment(t *testing.T) {
+	if got := alternativeEquipment(ExerciseAlternativeOptions{Location: "home"}); len(got) != len(homeEquipment) {
+		t.Fatalf("home preset not applied: %v", got)
+	}
+	got := alternativeEquipment(ExerciseAlternativeOptions{Location: "home", Equipment: []string{" دمبل", "دمبل", ""}})
+	if len(got) != 1 || got[0] != "دمبل" {
+		t.Fatalf("explicit equipment should win and be deduplicated: %v", got)
+	}
+	if got := alternativeEquipment(ExerciseAlternativeOptions{Location: "gym"}); got != nil {
+		t.Fatalf("gym should not filter equipment: %v", got)
+	}
+}
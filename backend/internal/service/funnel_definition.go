@@ -0,0 +1,463 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/pkg/slug"
+)
+
+var (
+	ErrFunnelSlugTaken  = errors.New("funnel slug already taken")
+	ErrFunnelForbidden  = errors.New("funnel does not belong to this coach")
+	ErrFunnelInvalidDef = errors.New("invalid funnel definition")
+)
+
+// Lead fields a funnel question can ask for. Each maps to a FunnelLead column.
+const (
+	FunnelAnswerPrimaryGoal        = "primaryGoal"
+	FunnelAnswerActivityLevel      = "activityLevel"
+	FunnelAnswerTrainingEnv        = "trainingEnv"
+	FunnelAnswerExperience         = "experience"
+	FunnelAnswerNutritionChallenge = "nutritionChallenge"
+	FunnelAnswerMainObstacle       = "mainObstacle"
+	FunnelAnswerCommitment         = "commitment"
+)
+
+var funnelAnswerKeys = []string{
+	FunnelAnswerPrimaryGoal,
+	FunnelAnswerActivityLevel,
+	FunnelAnswerTrainingEnv,
+	FunnelAnswerExperience,
+	FunnelAnswerNutritionChallenge,
+	FunnelAnswerMainObstacle,
+	FunnelAnswerCommitment,
+}
+
+const (
+	funnelOptionValueMax = 30 // FunnelLead answer columns are size:30
+	funnelScenarioMax    = 20
+	funnelSlugMax        = 80
+)
+
+// FunnelQuestionOption is one answer. Scenario, when set, selects the analysis
+// copy for leads that pick it.
+type FunnelQuestionOption struct {
+	Value    string `json:"value"`
+	Label    string `json:"label"`
+	Emoji    string `json:"emoji,omitempty"`
+	Scenario string `json:"scenario,omitempty"`
+}
+
+// FunnelQuestion asks for one lead field; Key is one of the FunnelAnswer* keys.
+type FunnelQuestion struct {
+	Key      string                 `json:"key"`
+	Title    string                 `json:"title"`
+	Subtitle string                 `json:"subtitle,omitempty"`
+	Required bool                   `json:"required"`
+	Options  []FunnelQuestionOption `json:"options"`
+}
+
+// FunnelAnalysisCopy is the analysis shown to leads of one scenario.
+type FunnelAnalysisCopy struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// defaultFunnelQuestions is the original seven-question set, used by funnels
+// that do not define their own.
+var defaultFunnelQuestions = []FunnelQuestion{
+	{
+		Key: FunnelAnswerPrimaryGoal, Title: "هدف اصلی شما برای تغییر استایل چیست؟", Required: true,
+		Options: []FunnelQuestionOption{
+			{Value: "weight_loss", Label: "چربی‌سوزی سریع و کات بدون ریزش عضلات", Emoji: "🔥", Scenario: "A"},
+			{Value: "muscle_gain", Label: "ساخت عضلات باکیفیت و حجم‌دهی عضلانی", Emoji: "💪", Scenario: "B"},
+			{Value: "fitness", Label: "افزایش قدرت، انرژی روزانه و فیتنس عمومی", Emoji: "⚡", Scenario: "C"},
+		},
+	},
+	{
+		Key: FunnelAnswerActivityLevel, Title: "وضعیت فعالیت روزانه شما (خارج از باشگاه) چطور است؟", Required: true,
+		Options: []FunnelQuestionOption{
+			{Value: "sedentary", Label: "پشت‌میزنشین و کم‌تحرک (کارمندی / دانشجویی)", Emoji: "🪑"},
+			{Value: "moderate", Label: "تحرک متوسط (پیاده‌روی روزانه یا کار سرپا)", Emoji: "🚶"},
+			{Value: "active", Label: "بسیار پرتحرک (کار بدنی سنگین یا ورزشکار فعال)", Emoji: "🏃"},
+		},
+	},
+	{
+		Key: FunnelAnswerTrainingEnv, Title: "شرایط و ترجیح شما برای انجام تمرینات چگونه است؟",
+		Options: []FunnelQuestionOption{
+			{Value: "home", Label: "فقط در خانه (با کش، دمبل یا وزن بدن)", Emoji: "🏠"},
+			{Value: "gym", Label: "در باشگاه بدنسازی (دسترسی به تمام دستگاه‌ها)", Emoji: "🏋️"},
+		},
+	},
+	{
+		Key: FunnelAnswerExperience, Title: "چقدر با محیط باشگاه و حرکات بدنسازی آشنایی دارید؟",
+		Options: []FunnelQuestionOption{
+			{Value: "beginner", Label: "مبتدی", Emoji: "🌱"},
+			{Value: "intermediate", Label: "متوسط", Emoji: "📘"},
+			{Value: "advanced", Label: "پیشرفته", Emoji: "🏅"},
+		},
+	},
+	{
+		Key: FunnelAnswerNutritionChallenge, Title: "بزرگ‌ترین چالش شما در رعایت رژیم غذایی چیست؟",
+		Options: []FunnelQuestionOption{
+			{Value: "sweets", Label: "اشتیاق شدید به شیرینی‌جات و ریزه‌خواری عصبی", Emoji: "🍫"},
+			{Value: "low_appetite", Label: "کم‌اشتهایی شدید", Emoji: "🍽️"},
+			{Value: "no_time", Label: "نداشتن وقت برای آشپزی و آماده‌سازی وعده‌ها", Emoji: "⏰"},
+		},
+	},
+	{
+		Key: FunnelAnswerMainObstacle, Title: "بزرگترین مانعی که در برنامه‌های قبلی شما را متوقف کرد چه بود؟", Required: true,
+		Options: []FunnelQuestionOption{
+			{Value: "motivation", Label: "رها شدن توسط مربی و عدم نظارت و پیگیری مداوم", Emoji: "🎯"},
+			{Value: "plateau", Label: "استپ وزنی و نتیجه نگرفتن از رژیم‌های سخت", Emoji: "📉"},
+			{Value: "knowledge", Label: "تا به حال مسیر اصولی را شروع نکرده‌ام", Emoji: "🚀"},
+		},
+	},
+	{
+		Key: FunnelAnswerCommitment, Title: "چقدر برای رسیدن به این هدف مصمم هستید؟",
+		Options: []FunnelQuestionOption{
+			{Value: "flexible", Label: "می‌خواهم با یک برنامه منعطف و بدون فشار زیاد شروع کنم", Emoji: "🌿"},
+			{Value: "max_results", Label: "کاملاً آماده‌ام؛ سریع‌ترین و بهترین نتیجه را می‌خواهم", Emoji: "🔥"},
+		},
+	},
+}
+
+// FunnelDTO is a funnel as coaches and admins manage it.
+type FunnelDTO struct {
+	ID                uint                          `json:"id"`
+	Slug              string                        `json:"slug"`
+	Title             string                        `json:"title"`
+	CoachID           uint                          `json:"coachId"`
+	CoachName         string                        `json:"coachName"`
+	PlanIDs           []uint                        `json:"planIds"` // empty = all active plans
+	Questions         []FunnelQuestion              `json:"questions"`
+	DefaultQuestions  bool                          `json:"defaultQuestions"`
+	AnalysisCopy      map[string]FunnelAnalysisCopy `json:"analysisCopy"`
+	FreeAccessEnabled bool                          `json:"freeAccessEnabled"`
+	IsActive          bool                          `json:"isActive"`
+	CreatedAt         time.Time                     `json:"createdAt"`
+	UpdatedAt         time.Time                     `json:"updatedAt"`
+}
+
+type FunnelListResponse struct {
+	Items    []FunnelDTO `json:"items"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"pageSize"`
+	Total    int64       `json:"total"`
+}
+
+// FunnelUpsertRequest creates or replaces a funnel. CoachID is only read from
+// admins; coaches always own what they create. Empty Questions restores the
+// built-in set.
+type FunnelUpsertRequest struct {
+	Slug              string                        `json:"slug"`
+	Title             string                        `json:"title"`
+	CoachID           uint                          `json:"coachId"`
+	PlanIDs           []uint                        `json:"planIds"`
+	Questions         []FunnelQuestion              `json:"questions"`
+	AnalysisCopy      map[string]FunnelAnalysisCopy `json:"analysisCopy"`
+	FreeAccessEnabled *bool                         `json:"freeAccessEnabled"`
+	IsActive          *bool                         `json:"isActive"`
+}
+
+func (s *funnelService) ListFunnels(ctx context.Context, coachID uint, page, pageSize int) (*FunnelListResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	funnels, total, err := s.funnels.List(ctx, coachID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	names := map[uint]string{}
+	items := make([]FunnelDTO, 0, len(funnels))
+	for i := range funnels {
+		f := &funnels[i]
+		if _, ok := names[f.CoachID]; !ok {
+			names[f.CoachID] = s.coachName(ctx, f.CoachID)
+		}
+		items = append(items, funnelToDTO(f, names[f.CoachID]))
+	}
+	return &FunnelListResponse{Items: items, Page: page, PageSize: pageSize, Total: total}, nil
+}
+
+func (s *funnelService) GetFunnel(ctx context.Context, coachID, id uint) (*FunnelDTO, error) {
+	funnel, err := s.ownFunnel(ctx, coachID, id)
+	if err != nil {
+		return nil, err
+	}
+	dto := funnelToDTO(funnel, s.coachName(ctx, funnel.CoachID))
+	return &dto, nil
+}
+
+func (s *funnelService) CreateFunnel(ctx context.Context, coachID, actorID uint, req *FunnelUpsertRequest) (*FunnelDTO, error) {
+	if req == nil {
+		return nil, ErrFunnelInvalidDef
+	}
+	owner := coachID
+	if owner == 0 {
+		owner = req.CoachID
+	}
+	funnel := &models.Funnel{CoachID: owner, FreeAccessEnabled: true, IsActive: true, CreatedByID: actorID}
+	if err := s.applyFunnelRequest(ctx, funnel, req); err != nil {
+		return nil, err
+	}
+	if err := s.funnels.Create(ctx, funnel); err != nil {
+		return nil, err
+	}
+	dto := funnelToDTO(funnel, s.coachName(ctx, funnel.CoachID))
+	return &dto, nil
+}
+
+func (s *funnelService) UpdateFunnel(ctx context.Context, coachID, id uint, req *FunnelUpsertRequest) (*FunnelDTO, error) {
+	if req == nil {
+		return nil, ErrFunnelInvalidDef
+	}
+	funnel, err := s.ownFunnel(ctx, coachID, id)
+	if err != nil {
+		return nil, err
+	}
+	if coachID == 0 && req.CoachID > 0 {
+		funnel.CoachID = req.CoachID
+	}
+	if err := s.applyFunnelRequest(ctx, funnel, req); err != nil {
+		return nil, err
+	}
+	if err := s.funnels.Update(ctx, funnel); err != nil {
+		return nil, err
+	}
+	dto := funnelToDTO(funnel, s.coachName(ctx, funnel.CoachID))
+	return &dto, nil
+}
+
+func (s *funnelService) DeleteFunnel(ctx context.Context, coachID, id uint) error {
+	if _, err := s.ownFunnel(ctx, coachID, id); err != nil {
+		return err
+	}
+	return s.funnels.Delete(ctx, id)
+}
+
+func (s *funnelService) ownFunnel(ctx context.Context, coachID, id uint) (*models.Funnel, error) {
+	funnel, err := s.funnels.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFunnelNotFound
+		}
+		return nil, err
+	}
+	if coachID > 0 && funnel.CoachID != coachID {
+		return nil, ErrFunnelForbidden
+	}
+	return funnel, nil
+}
+
+// applyFunnelRequest validates req against funnel.CoachID and copies it onto funnel.
+func (s *funnelService) applyFunnelRequest(ctx context.Context, funnel *models.Funnel, req *FunnelUpsertRequest) error {
+	key := slug.Normalize(req.Slug)
+	title := strings.TrimSpace(req.Title)
+	if key == "" || len(key) > funnelSlugMax || title == "" || utf8.RuneCountInString(title) > 255 {
+		return ErrFunnelInvalidDef
+	}
+	if existing, err := s.funnels.FindBySlug(ctx, key); err == nil && existing.ID != funnel.ID {
+		return ErrFunnelSlugTaken
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	coach, err := s.userRepo.FindByID(ctx, funnel.CoachID)
+	if err != nil || coach.Role != models.RoleCoach {
+		return ErrFunnelInvalidDef
+	}
+	// Admin approval lives on the coach profile (see ApprovedCoachOnly).
+	profile, err := s.coachRepo.FindByUserID(ctx, funnel.CoachID)
+	if err != nil || profile.Status != models.CoachProfileStatusApproved {
+		return ErrFunnelInvalidDef
+	}
+	planIDs := uniqueUints(req.PlanIDs)
+	for _, id := range planIDs {
+		if _, err := s.planRepo.FindByIDAndCoachID(ctx, id, funnel.CoachID); err != nil {
+			return ErrFunnelInvalidDef
+		}
+	}
+	if err := validateFunnelQuestions(req.Questions); err != nil {
+		return err
+	}
+	for scenario, text := range req.AnalysisCopy {
+		if scenario == "" || utf8.RuneCountInString(scenario) > funnelScenarioMax || strings.TrimSpace(text.Title) == "" {
+			return ErrFunnelInvalidDef
+		}
+	}
+
+	funnel.Slug = key
+	funnel.Title = title
+	funnel.PlanIDs = encodeFunnelJSON(planIDs, len(planIDs))
+	funnel.Questions = encodeFunnelJSON(req.Questions, len(req.Questions))
+	funnel.AnalysisCopy = encodeFunnelJSON(req.AnalysisCopy, len(req.AnalysisCopy))
+	if req.FreeAccessEnabled != nil {
+		funnel.FreeAccessEnabled = *req.FreeAccessEnabled
+	}
+	if req.IsActive != nil {
+		funnel.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func validateFunnelQuestions(questions []FunnelQuestion) error {
+	seen := map[string]bool{}
+	for _, q := range questions {
+		if !slices.Contains(funnelAnswerKeys, q.Key) || seen[q.Key] || strings.TrimSpace(q.Title) == "" || len(q.Options) == 0 {
+			return ErrFunnelInvalidDef
+		}
+		seen[q.Key] = true
+		values := map[string]bool{}
+		for _, o := range q.Options {
+			if o.Value == "" || len(o.Value) > funnelOptionValueMax || values[o.Value] ||
+				strings.TrimSpace(o.Label) == "" || utf8.RuneCountInString(o.Scenario) > funnelScenarioMax {
+				return ErrFunnelInvalidDef
+			}
+			values[o.Value] = true
+		}
+	}
+	return nil
+}
+
+// funnelAnswers holds a lead's answers by FunnelAnswer* key.
+type funnelAnswers map[string]string
+
+func (a funnelAnswers) applyTo(lead *models.FunnelLead) {
+	lead.PrimaryGoal = a[FunnelAnswerPrimaryGoal]
+	lead.ActivityLevel = a[FunnelAnswerActivityLevel]
+	lead.TrainingEnv = a[FunnelAnswerTrainingEnv]
+	lead.Experience = a[FunnelAnswerExperience]
+	lead.NutritionChallenge = a[FunnelAnswerNutritionChallenge]
+	lead.MainObstacle = a[FunnelAnswerMainObstacle]
+	lead.Commitment = a[FunnelAnswerCommitment]
+}
+
+// validateFunnelAnswers checks req against the funnel's questions and returns
+// the answers to store with the lead's scenario. Answers to questions the
+// funnel does not ask are dropped.
+func validateFunnelAnswers(questions []FunnelQuestion, req *CreateFunnelLeadRequest) (funnelAnswers, string, error) {
+	given := map[string]string{
+		FunnelAnswerPrimaryGoal:        req.PrimaryGoal,
+		FunnelAnswerActivityLevel:      req.ActivityLevel,
+		FunnelAnswerTrainingEnv:        req.TrainingEnv,
+		FunnelAnswerExperience:         req.Experience,
+		FunnelAnswerNutritionChallenge: req.NutritionChallenge,
+		FunnelAnswerMainObstacle:       req.MainObstacle,
+		FunnelAnswerCommitment:         req.Commitment,
+	}
+	answers := funnelAnswers{}
+	scenario := ""
+	for _, q := range questions {
+		v := strings.TrimSpace(given[q.Key])
+		if v == "" {
+			if q.Required {
+				return nil, "", ErrFunnelInvalidInput
+			}
+			continue
+		}
+		i := slices.IndexFunc(q.Options, func(o FunnelQuestionOption) bool { return o.Value == v })
+		if i < 0 {
+			return nil, "", ErrFunnelInvalidInput
+		}
+		answers[q.Key] = v
+		if scenario == "" {
+			scenario = q.Options[i].Scenario
+		}
+	}
+	if scenario == "" {
+		scenario = strings.TrimSpace(req.Scenario)
+		if utf8.RuneCountInString(scenario) > funnelScenarioMax {
+			return nil, "", ErrFunnelInvalidInput
+		}
+	}
+	return answers, scenario, nil
+}
+
+// funnelQuestions returns the funnel's question set, or the built-in one.
+func funnelQuestions(funnel *models.Funnel) []FunnelQuestion {
+	var out []FunnelQuestion
+	if strings.TrimSpace(funnel.Questions) != "" {
+		_ = json.Unmarshal([]byte(funnel.Questions), &out)
+	}
+	if len(out) == 0 {
+		return defaultFunnelQuestions
+	}
+	return out
+}
+
+func decodeFunnelPlanIDs(raw string) []uint {
+	var out []uint
+	if strings.TrimSpace(raw) != "" {
+		_ = json.Unmarshal([]byte(raw), &out)
+	}
+	return out
+}
+
+func decodeFunnelAnalysisCopy(raw string) map[string]FunnelAnalysisCopy {
+	out := map[string]FunnelAnalysisCopy{}
+	if strings.TrimSpace(raw) != "" {
+		_ = json.Unmarshal([]byte(raw), &out)
+	}
+	return out
+}
+
+// encodeFunnelJSON stores empty lists and maps as "" so they read back as defaults.
+func encodeFunnelJSON(v any, n int) string {
+	if n == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func funnelToDTO(f *models.Funnel, coachName string) FunnelDTO {
+	var questions []FunnelQuestion
+	if strings.TrimSpace(f.Questions) != "" {
+		_ = json.Unmarshal([]byte(f.Questions), &questions)
+	}
+	planIDs := decodeFunnelPlanIDs(f.PlanIDs)
+	if planIDs == nil {
+		planIDs = []uint{}
+	}
+	return FunnelDTO{
+		ID:                f.ID,
+		Slug:              f.Slug,
+		Title:             f.Title,
+		CoachID:           f.CoachID,
+		CoachName:         coachName,
+		PlanIDs:           planIDs,
+		Questions:         funnelQuestions(f),
+		DefaultQuestions:  len(questions) == 0,
+		AnalysisCopy:      decodeFunnelAnalysisCopy(f.AnalysisCopy),
+		FreeAccessEnabled: f.FreeAccessEnabled,
+		IsActive:          f.IsActive,
+		CreatedAt:         f.CreatedAt,
+		UpdatedAt:         f.UpdatedAt,
+	}
+}
+
+func uniqueUints(in []uint) []uint {
+	out := make([]uint, 0, len(in))
+	for _, v := range in {
+		if v > 0 && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
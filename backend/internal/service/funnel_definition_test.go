@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+func TestValidateFunnelAnswers(t *testing.T) {
+	questions := []FunnelQuestion{
+		{Key: FunnelAnswerMainObstacle, Title: "مانع", Required: true, Options: []FunnelQuestionOption{
+			{Value: "plateau", Label: "استپ"},
+		}},
+		{Key: FunnelAnswerPrimaryGoal, Title: "هدف", Options: []FunnelQuestionOption{
+			{Value: "fat_loss", Label: "چربی‌سوزی", Scenario: "cut"},
+		}},
+	}
+
+	answers, scenario, err := validateFunnelAnswers(questions, &CreateFunnelLeadRequest{
+		MainObstacle: "plateau",
+		PrimaryGoal:  "fat_loss",
+		Commitment:   "max_results", // not asked by this funnel
+		Scenario:     "A",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scenario != "cut" {
+		t.Fatalf("scenario should come from the chosen option, got %q", scenario)
+	}
+	if _, ok := answers[FunnelAnswerCommitment]; ok {
+		t.Fatal("answers to questions the funnel does not ask must be dropped")
+	}
+
+	if _, _, err := validateFunnelAnswers(questions, &CreateFunnelLeadRequest{PrimaryGoal: "fat_loss"}); !errors.Is(err, ErrFunnelInvalidInput) {
+		t.Fatalf("missing required answer should be rejected, got %v", err)
+	}
+	if _, _, err := validateFunnelAnswers(questions, &CreateFunnelLeadRequest{MainObstacle: "knowledge"}); !errors.Is(err, ErrFunnelInvalidInput) {
+		t.Fatalf("unknown option should be rejected, got %v", err)
+	}
+	if _, scenario, _ := validateFunnelAnswers(questions, &CreateFunnelLeadRequest{MainObstacle: "plateau", Scenario: "B"}); scenario != "B" {
+		t.Fatalf("request scenario is the fallback, got %q", scenario)
+	}
+}
+
+// A coach approved through the admin coach review can run funnels; User.CoachStatus
+// is only written by seeds and must not matter.
+func TestFunnelDefinitionRequiresApprovedCoachProfile(t *testing.T) {
+	ctx := context.Background()
+	db, err := config.NewSQLiteGORM(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models.AllModels()...); err != nil {
+		t.Fatal(err)
+	}
+	coach := &models.User{Name: "coach", Email: "coach@test.local", Phone: "09120000001", Password: "x", Role: models.RoleCoach}
+	if err := db.Create(coach).Error; err != nil {
+		t.Fatal(err)
+	}
+	// The profile a newly registered coach gets.
+	if err := db.Create(&models.CoachProfile{UserID: coach.ID, Slug: "coach", DisplayName: "coach", Status: models.CoachProfileStatusPending}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	coachRepo := repository.NewCoachProfileRepository(db)
+	svc := NewFunnelService(repository.NewFunnelRepository(db), repository.NewFunnelExperimentRepository(db),
+		repository.NewFunnelLeadRepository(db), coachRepo, repository.NewServicePlanRepository(db),
+		repository.NewUserRepository(db), nil, nil, nil)
+	req := &FunnelUpsertRequest{Slug: "summer", Title: "Summer cut"}
+
+	if _, err := svc.CreateFunnel(ctx, coach.ID, coach.ID, req); !errors.Is(err, ErrFunnelInvalidDef) {
+		t.Fatalf("pending coach creating a funnel: %v", err)
+	}
+	approved := models.CoachProfileStatusApproved
+	if _, err := NewAdminCoachService(coachRepo, repository.NewCoachAchievementRepository(db)).
+		UpdateCoach(ctx, coach.ID, &AdminCoachPatchRequest{Status: &approved}); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := svc.CreateFunnel(ctx, coach.ID, coach.ID, req)
+	if err != nil {
+		t.Fatalf("approved coach creating a funnel: %v", err)
+	}
+	updated, err := svc.UpdateFunnel(ctx, coach.ID, created.ID, &FunnelUpsertRequest{Slug: "summer", Title: "Summer cut 2"})
+	if err != nil || updated.Title != "Summer cut 2" {
+		t.Fatalf("approved coach updating a funnel: %+v (%v)", updated, err)
+	}
+	// An admin creating a funnel on the coach's behalf.
+	if _, err := svc.CreateFunnel(ctx, 0, 99, &FunnelUpsertRequest{Slug: "winter", Title: "Winter bulk", CoachID: coach.ID}); err != nil {
+		t.Fatalf("admin creating a funnel for the coach: %v", err)
+	}
+}
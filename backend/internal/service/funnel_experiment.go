@@ -0,0 +1,507 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+var (
+	ErrFunnelExperimentNotFound = errors.New("funnel experiment not found")
+	ErrFunnelExperimentInvalid  = errors.New("invalid funnel experiment")
+	ErrFunnelExperimentConflict = errors.New("another experiment is running on this funnel")
+	ErrFunnelExperimentStatus   = errors.New("invalid experiment status transition")
+)
+
+const (
+	funnelVariantsMin       = 2
+	funnelVariantsMax       = 5
+	funnelVariantKeyMax     = 40
+	funnelVisitorIDMax      = 64
+	funnelSignificanceLevel = 0.05
+)
+
+// FunnelVariant is one arm of an experiment. Empty fields keep the funnel's own
+// setting, so the control is usually just {key, name, weight}.
+type FunnelVariant struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	// PlanOrder lists plan IDs to show first, in order; the rest follow.
+	PlanOrder     []uint                        `json:"planOrder,omitempty"`
+	DefaultPlanID uint                          `json:"defaultPlanId,omitempty"`
+	Headline      string                        `json:"headline,omitempty"`
+	AnalysisCopy  map[string]FunnelAnalysisCopy `json:"analysisCopy,omitempty"`
+	// QuestionOrder lists question keys to ask first, in order.
+	QuestionOrder []string `json:"questionOrder,omitempty"`
+}
+
+type FunnelExperimentRequest struct {
+	Name     string          `json:"name"`
+	Variants []FunnelVariant `json:"variants"`
+}
+
+type FunnelExperimentDTO struct {
+	ID        uint            `json:"id"`
+	FunnelID  uint            `json:"funnelId"`
+	Name      string          `json:"name"`
+	Status    string          `json:"status"`
+	Variants  []FunnelVariant `json:"variants"`
+	StartedAt *time.Time      `json:"startedAt,omitempty"`
+	EndedAt   *time.Time      `json:"endedAt,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// FunnelVariantStatsDTO compares one variant against the control (first
+// variant) with a two-proportion z-test on the payment rate.
+type FunnelVariantStatsDTO struct {
+	Key            string  `json:"key"`
+	Name           string  `json:"name"`
+	Weight         int     `json:"weight"`
+	Control        bool    `json:"control"`
+	Leads          int64   `json:"leads"`
+	Paid           int64   `json:"paid"`
+	PaymentRate    float64 `json:"paymentRate"`
+	PaidRevenue    int64   `json:"paidRevenue"`
+	RevenuePerLead int64   `json:"revenuePerLead"`
+	LiftPercent    float64 `json:"liftPercent"`
+	PValue         float64 `json:"pValue"`
+	Significant    bool    `json:"significant"` // p < 0.05 vs the control
+}
+
+type FunnelExperimentStatsDTO struct {
+	ID       uint                    `json:"id"`
+	Name     string                  `json:"name"`
+	Status   string                  `json:"status"`
+	Variants []FunnelVariantStatsDTO `json:"variants"`
+}
+
+func (s *funnelService) ListExperiments(ctx context.Context, coachID, funnelID uint) ([]FunnelExperimentDTO, error) {
+	if _, err := s.ownFunnel(ctx, coachID, funnelID); err != nil {
+		return nil, err
+	}
+	rows, err := s.experiments.ListByFunnel(ctx, funnelID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]FunnelExperimentDTO, 0, len(rows))
+	for i := range rows {
+		out = append(out, experimentToDTO(&rows[i]))
+	}
+	return out, nil
+}
+
+func (s *funnelService) CreateExperiment(ctx context.Context, coachID, funnelID uint, req *FunnelExperimentRequest) (*FunnelExperimentDTO, error) {
+	funnel, err := s.ownFunnel(ctx, coachID, funnelID)
+	if err != nil {
+		return nil, err
+	}
+	exp := &models.FunnelExperiment{FunnelID: funnel.ID, Status: models.FunnelExperimentDraft}
+	if err := s.applyExperimentRequest(ctx, funnel, exp, req); err != nil {
+		return nil, err
+	}
+	if err := s.experiments.Create(ctx, exp); err != nil {
+		return nil, err
+	}
+	dto := experimentToDTO(exp)
+	return &dto, nil
+}
+
+// UpdateExperiment edits a draft; running and stopped experiments are frozen so
+// their results stay comparable.
+func (s *funnelService) UpdateExperiment(ctx context.Context, coachID, funnelID, id uint, req *FunnelExperimentRequest) (*FunnelExperimentDTO, error) {
+	funnel, exp, err := s.ownExperiment(ctx, coachID, funnelID, id)
+	if err != nil {
+		return nil, err
+	}
+	if exp.Status != models.FunnelExperimentDraft {
+		return nil, ErrFunnelExperimentStatus
+	}
+	if err := s.applyExperimentRequest(ctx, funnel, exp, req); err != nil {
+		return nil, err
+	}
+	if err := s.experiments.Update(ctx, exp); err != nil {
+		return nil, err
+	}
+	dto := experimentToDTO(exp)
+	return &dto, nil
+}
+
+func (s *funnelService) DeleteExperiment(ctx context.Context, coachID, funnelID, id uint) error {
+	_, exp, err := s.ownExperiment(ctx, coachID, funnelID, id)
+	if err != nil {
+		return err
+	}
+	if exp.Status == models.FunnelExperimentRunning {
+		return ErrFunnelExperimentStatus
+	}
+	return s.experiments.Delete(ctx, exp.ID)
+}
+
+func (s *funnelService) StartExperiment(ctx context.Context, coachID, funnelID, id uint) (*FunnelExperimentDTO, error) {
+	_, exp, err := s.ownExperiment(ctx, coachID, funnelID, id)
+	if err != nil {
+		return nil, err
+	}
+	if exp.Status != models.FunnelExperimentDraft {
+		return nil, ErrFunnelExperimentStatus
+	}
+	if running, err := s.experiments.FindRunningByFunnel(ctx, exp.FunnelID); err == nil && running != nil {
+		return nil, ErrFunnelExperimentConflict
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	now := time.Now()
+	exp.Status = models.FunnelExperimentRunning
+	exp.StartedAt = &now
+	if err := s.experiments.Update(ctx, exp); err != nil {
+		return nil, err
+	}
+	dto := experimentToDTO(exp)
+	return &dto, nil
+}
+
+func (s *funnelService) StopExperiment(ctx context.Context, coachID, funnelID, id uint) (*FunnelExperimentDTO, error) {
+	_, exp, err := s.ownExperiment(ctx, coachID, funnelID, id)
+	if err != nil {
+		return nil, err
+	}
+	if exp.Status != models.FunnelExperimentRunning {
+		return nil, ErrFunnelExperimentStatus
+	}
+	now := time.Now()
+	exp.Status = models.FunnelExperimentStopped
+	exp.EndedAt = &now
+	if err := s.experiments.Update(ctx, exp); err != nil {
+		return nil, err
+	}
+	dto := experimentToDTO(exp)
+	return &dto, nil
+}
+
+func (s *funnelService) ownExperiment(ctx context.Context, coachID, funnelID, id uint) (*models.Funnel, *models.FunnelExperiment, error) {
+	funnel, err := s.ownFunnel(ctx, coachID, funnelID)
+	if err != nil {
+		return nil, nil, err
+	}
+	exp, err := s.experiments.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrFunnelExperimentNotFound
+		}
+		return nil, nil, err
+	}
+	if exp.FunnelID != funnel.ID {
+		return nil, nil, ErrFunnelExperimentNotFound
+	}
+	return funnel, exp, nil
+}
+
+func (s *funnelService) applyExperimentRequest(ctx context.Context, funnel *models.Funnel, exp *models.FunnelExperiment, req *FunnelExperimentRequest) error {
+	if req == nil {
+		return ErrFunnelExperimentInvalid
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > 120 {
+		return ErrFunnelExperimentInvalid
+	}
+	if len(req.Variants) < funnelVariantsMin || len(req.Variants) > funnelVariantsMax {
+		return ErrFunnelExperimentInvalid
+	}
+	plans, err := s.loadFunnelPlans(ctx, funnel)
+	if err != nil {
+		return err
+	}
+	planIDs := make([]uint, 0, len(plans))
+	for _, p := range plans {
+		planIDs = append(planIDs, p.ID)
+	}
+	questionKeys := make([]string, 0, len(funnelQuestions(funnel)))
+	for _, q := range funnelQuestions(funnel) {
+		questionKeys = append(questionKeys, q.Key)
+	}
+
+	variants := make([]FunnelVariant, 0, len(req.Variants))
+	seen := map[string]bool{}
+	for _, v := range req.Variants {
+		v.Key = strings.TrimSpace(v.Key)
+		v.Name = strings.TrimSpace(v.Name)
+		v.Headline = strings.TrimSpace(v.Headline)
+		if v.Key == "" || len(v.Key) > funnelVariantKeyMax || seen[v.Key] || v.Weight <= 0 || v.Weight > 100 ||
+			utf8.RuneCountInString(v.Headline) > 255 {
+			return ErrFunnelExperimentInvalid
+		}
+		seen[v.Key] = true
+		if v.Name == "" {
+			v.Name = v.Key
+		}
+		for _, id := range v.PlanOrder {
+			if !slices.Contains(planIDs, id) {
+				return ErrFunnelExperimentInvalid
+			}
+		}
+		if v.DefaultPlanID > 0 && !slices.Contains(planIDs, v.DefaultPlanID) {
+			return ErrFunnelExperimentInvalid
+		}
+		for _, key := range v.QuestionOrder {
+			if !slices.Contains(questionKeys, key) {
+				return ErrFunnelExperimentInvalid
+			}
+		}
+		for scenario, text := range v.AnalysisCopy {
+			if scenario == "" || utf8.RuneCountInString(scenario) > funnelScenarioMax || strings.TrimSpace(text.Title) == "" {
+				return ErrFunnelExperimentInvalid
+			}
+		}
+		variants = append(variants, v)
+	}
+
+	exp.Name = name
+	exp.Variants = encodeFunnelJSON(variants, len(variants))
+	return nil
+}
+
+func experimentToDTO(exp *models.FunnelExperiment) FunnelExperimentDTO {
+	return FunnelExperimentDTO{
+		ID:        exp.ID,
+		FunnelID:  exp.FunnelID,
+		Name:      exp.Name,
+		Status:    exp.Status,
+		Variants:  decodeFunnelVariants(exp.Variants),
+		StartedAt: exp.StartedAt,
+		EndedAt:   exp.EndedAt,
+		CreatedAt: exp.CreatedAt,
+	}
+}
+
+func decodeFunnelVariants(raw string) []FunnelVariant {
+	out := []FunnelVariant{}
+	if strings.TrimSpace(raw) != "" {
+		_ = json.Unmarshal([]byte(raw), &out)
+	}
+	return out
+}
+
+// funnelAssignment is the variant a visitor or lead sees; nil fields mean the
+// funnel runs no experiment for them.
+type funnelAssignment struct {
+	experiment *models.FunnelExperiment
+	variant    *FunnelVariant
+}
+
+// assignVariant picks the running experiment's variant for visitorID. The
+// split is a hash of experiment and visitor, so the same visitor keeps the
+// same variant on every visit without storing anything.
+func (s *funnelService) assignVariant(ctx context.Context, funnel *models.Funnel, visitorID string) funnelAssignment {
+	if s.experiments == nil || visitorID == "" {
+		return funnelAssignment{}
+	}
+	exp, err := s.experiments.FindRunningByFunnel(ctx, funnel.ID)
+	if err != nil {
+		return funnelAssignment{}
+	}
+	return funnelAssignment{experiment: exp, variant: pickFunnelVariant(decodeFunnelVariants(exp.Variants), exp.ID, visitorID)}
+}
+
+// leadVariant is the variant stored on the lead, while its experiment runs.
+func (s *funnelService) leadVariant(ctx context.Context, lead *models.FunnelLead) funnelAssignment {
+	if s.experiments == nil || lead.ExperimentID == 0 {
+		return funnelAssignment{}
+	}
+	exp, err := s.experiments.FindByID(ctx, lead.ExperimentID)
+	if err != nil || exp.Status != models.FunnelExperimentRunning {
+		return funnelAssignment{}
+	}
+	for _, v := range decodeFunnelVariants(exp.Variants) {
+		if v.Key == lead.VariantKey {
+			return funnelAssignment{experiment: exp, variant: &v}
+		}
+	}
+	return funnelAssignment{}
+}
+
+func pickFunnelVariant(variants []FunnelVariant, experimentID uint, visitorID string) *FunnelVariant {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(visitorID))
+	_, _ = h.Write([]byte{byte(experimentID), byte(experimentID >> 8), byte(experimentID >> 16), byte(experimentID >> 24)})
+	bucket := int(h.Sum32() % uint32(total))
+	for i := range variants {
+		bucket -= variants[i].Weight
+		if bucket < 0 {
+			return &variants[i]
+		}
+	}
+	return nil
+}
+
+func (a funnelAssignment) applyTo(lead *models.FunnelLead) {
+	if a.experiment == nil || a.variant == nil {
+		return
+	}
+	lead.ExperimentID = a.experiment.ID
+	lead.VariantKey = a.variant.Key
+}
+
+// orderPlans moves the variant's PlanOrder to the front.
+func (a funnelAssignment) orderPlans(plans []FunnelPlanDTO) []FunnelPlanDTO {
+	if a.variant == nil || len(a.variant.PlanOrder) == 0 {
+		return plans
+	}
+	out := make([]FunnelPlanDTO, 0, len(plans))
+	for _, id := range a.variant.PlanOrder {
+		if i := slices.IndexFunc(plans, func(p FunnelPlanDTO) bool { return p.ID == id }); i >= 0 {
+			out = append(out, plans[i])
+		}
+	}
+	for _, p := range plans {
+		if !slices.Contains(a.variant.PlanOrder, p.ID) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// orderQuestions moves the variant's QuestionOrder to the front.
+func (a funnelAssignment) orderQuestions(questions []FunnelQuestion) []FunnelQuestion {
+	if a.variant == nil || len(a.variant.QuestionOrder) == 0 {
+		return questions
+	}
+	out := make([]FunnelQuestion, 0, len(questions))
+	for _, key := range a.variant.QuestionOrder {
+		if i := slices.IndexFunc(questions, func(q FunnelQuestion) bool { return q.Key == key }); i >= 0 {
+			out = append(out, questions[i])
+		}
+	}
+	for _, q := range questions {
+		if !slices.Contains(a.variant.QuestionOrder, q.Key) {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// analysisCopy merges the variant's overrides over the funnel's copy.
+func (a funnelAssignment) analysisCopy(funnel *models.Funnel) map[string]FunnelAnalysisCopy {
+	out := decodeFunnelAnalysisCopy(funnel.AnalysisCopy)
+	if a.variant != nil {
+		for scenario, text := range a.variant.AnalysisCopy {
+			out[scenario] = text
+		}
+	}
+	return out
+}
+
+func (a funnelAssignment) defaultPlanID() uint {
+	if a.variant == nil {
+		return 0
+	}
+	return a.variant.DefaultPlanID
+}
+
+// experimentStats reports the funnel's running experiment, or else its most
+// recently started one.
+func (s *funnelService) experimentStats(ctx context.Context, funnelID uint) (*FunnelExperimentStatsDTO, error) {
+	if s.experiments == nil || funnelID == 0 {
+		return nil, nil
+	}
+	rows, err := s.experiments.ListByFunnel(ctx, funnelID)
+	if err != nil {
+		return nil, err
+	}
+	var exp *models.FunnelExperiment
+	for i := range rows {
+		if rows[i].Status == models.FunnelExperimentRunning {
+			exp = &rows[i]
+			break
+		}
+		if exp == nil && rows[i].StartedAt != nil {
+			exp = &rows[i]
+		}
+	}
+	if exp == nil {
+		return nil, nil
+	}
+	raw, err := s.repo.VariantStats(ctx, exp.ID)
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string]int{}
+	for i, r := range raw {
+		byKey[r.VariantKey] = i
+	}
+
+	variants := decodeFunnelVariants(exp.Variants)
+	out := &FunnelExperimentStatsDTO{ID: exp.ID, Name: exp.Name, Status: exp.Status, Variants: make([]FunnelVariantStatsDTO, 0, len(variants))}
+	for i, v := range variants {
+		row := FunnelVariantStatsDTO{Key: v.Key, Name: v.Name, Weight: v.Weight, Control: i == 0}
+		if j, ok := byKey[v.Key]; ok {
+			row.Leads, row.Paid, row.PaidRevenue = raw[j].Total, raw[j].Paid, raw[j].PaidRevenue
+		}
+		if row.Leads > 0 {
+			row.PaymentRate = round1(float64(row.Paid) / float64(row.Leads) * 100)
+			row.RevenuePerLead = row.PaidRevenue / row.Leads
+		}
+		out.Variants = append(out.Variants, row)
+	}
+	if len(out.Variants) > 0 {
+		control := out.Variants[0]
+		for i := 1; i < len(out.Variants); i++ {
+			v := &out.Variants[i]
+			if control.PaymentRate > 0 {
+				v.LiftPercent = round1((v.PaymentRate - control.PaymentRate) / control.PaymentRate * 100)
+			}
+			v.PValue = twoProportionPValue(control.Paid, control.Leads, v.Paid, v.Leads)
+			v.Significant = v.PValue < funnelSignificanceLevel
+		}
+		out.Variants[0].PValue = 1
+	}
+	return out, nil
+}
+
+// twoProportionPValue is the two-sided p-value of a pooled two-proportion
+// z-test; 1 when either arm has no leads or nobody (or everybody) paid.
+func twoProportionPValue(x1, n1, x2, n2 int64) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	pooled := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 1
+	}
+	z := (float64(x2)/float64(n2) - float64(x1)/float64(n1)) / se
+	return math.Round(math.Erfc(math.Abs(z)/math.Sqrt2)*10000) / 10000
+}
+
+// normalizeFunnelVisitorID keeps a client-supplied visitor id when it looks
+// like one of ours, or mints a new one.
+func normalizeFunnelVisitorID(id string) string {
+	id = strings.TrimSpace(id)
+	if id != "" && len(id) <= funnelVisitorIDMax && strings.IndexFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) < 0 {
+		return id
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPickFunnelVariantIsStickyAndWeighted(t *testing.T) {
+	variants := []FunnelVariant{{Key: "control", Weight: 3}, {Key: "cip_first", Weight: 1}}
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		visitor := fmt.Sprintf("visitor-%d", i)
+		v := pickFunnelVariant(variants, 7, visitor)
+		if again := pickFunnelVariant(variants, 7, visitor); again.Key != v.Key {
+			t.Fatalf("visitor %s moved from %s to %s", visitor, v.Key, again.Key)
+		}
+		counts[v.Key]++
+	}
+	if share := float64(counts["cip_first"]) / 4000; share < 0.2 || share > 0.3 {
+		t.Fatalf("a 3:1 split should give ~25%% to cip_first, got %.2f", share)
+	}
+}
+
+func TestTwoProportionPValue(t *testing.T) {
+	if p := twoProportionPValue(50, 1000, 80, 1000); p >= funnelSignificanceLevel {
+		t.Fatalf("5%% vs 8%% on 1000 leads each should be significant, p=%v", p)
+	}
+	if p := twoProportionPValue(5, 100, 6, 100); p < funnelSignificanceLevel {
+		t.Fatalf("5%% vs 6%% on 100 leads each should not be significant, p=%v", p)
+	}
+	if p := twoProportionPValue(0, 0, 3, 10); p != 1 {
+		t.Fatalf("an empty arm gives p=1, got %v", p)
+	}
+}
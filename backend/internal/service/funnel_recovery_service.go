@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+var ErrFunnelRecoveryRange = errors.New("invalid funnel recovery date range")
+
+const (
+	// funnelRecoveryBatch caps the leads handled per step per run.
+	funnelRecoveryBatch       = 100
+	funnelRecoveryDefaultDays = 30
+	funnelRecoveryMaxDays     = 366
+)
+
+// FunnelRecoveryStepDTO is one reminder step in the recovery report; step 0 is
+// the total across steps.
+type FunnelRecoveryStepDTO struct {
+	Step             int     `json:"step"`
+	Sent             int64   `json:"sent"`
+	Failed           int64   `json:"failed"`
+	Recovered        int64   `json:"recovered"`
+	ConversionRate   float64 `json:"conversionRate"`
+	RecoveredRevenue int64   `json:"recoveredRevenue"`
+}
+
+type FunnelRecoveryReportDTO struct {
+	From     string                  `json:"from"`
+	To       string                  `json:"to"`
+	FunnelID uint                    `json:"funnelId,omitempty"`
+	Enabled  bool                    `json:"enabled"`
+	Steps    []FunnelRecoveryStepDTO `json:"steps"`
+	Total    FunnelRecoveryStepDTO   `json:"total"`
+}
+
+// FunnelRecoveryService texts leads that left funnel checkout unpaid, following
+// config funnel.recovery.
+type FunnelRecoveryService interface {
+	// RunDue sends every reminder due at now and returns how many were sent.
+	RunDue(ctx context.Context, now time.Time) (int, error)
+	// Start runs RunDue every interval until ctx is done.
+	Start(ctx context.Context)
+	// ResumeURL is the site checkout page for a checkout token from a reminder.
+	ResumeURL(ctx context.Context, token string) (string, error)
+	// Report covers the inclusive YYYY-MM-DD range; funnelID 0 is every funnel.
+	Report(ctx context.Context, funnelID uint, from, to string) (*FunnelRecoveryReportDTO, error)
+}
+
+type funnelRecoveryService struct {
+	funnels  repository.FunnelRepository
+	leads    repository.FunnelLeadRepository
+	recovery repository.FunnelRecoveryRepository
+	// send is SendFunnelRecoverySMS; tests swap it out.
+	send func(receptor, firstName, checkoutToken string, discountPercent int) error
+}
+
+func NewFunnelRecoveryService(
+	funnels repository.FunnelRepository,
+	leads repository.FunnelLeadRepository,
+	recovery repository.FunnelRecoveryRepository,
+) FunnelRecoveryService {
+	return &funnelRecoveryService{
+		funnels:  funnels,
+		leads:    leads,
+		recovery: recovery,
+		send:     SendFunnelRecoverySMS,
+	}
+}
+
+// parseRecoveryDelays reads the comma-separated minute delays, ignoring
+// invalid entries, in ascending order.
+func parseRecoveryDelays(raw string) []time.Duration {
+	var out []time.Duration
+	for _, part := range strings.Split(raw, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n <= 0 {
+			continue
+		}
+		out = append(out, time.Duration(n)*time.Minute)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func (s *funnelRecoveryService) RunDue(ctx context.Context, now time.Time) (int, error) {
+	cfg := config.Get().Funnel.Recovery
+	delays := parseRecoveryDelays(cfg.DelaysMinutes)
+	oldest := now.Add(-time.Duration(cfg.MaxAgeHours) * time.Hour)
+
+	sent := 0
+	for i, delay := range delays {
+		leads, err := s.recovery.ListDue(ctx, i, oldest, now.Add(-delay), funnelRecoveryBatch)
+		if err != nil {
+			return sent, err
+		}
+		step := i + 1
+		for j := range leads {
+			ok, err := s.remind(ctx, &leads[j], step, now)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+	return sent, nil
+}
+
+// remind claims step for lead and texts it. The claim comes first so two
+// replicas never text the same lead twice; a failed send is logged, not retried.
+func (s *funnelRecoveryService) remind(ctx context.Context, lead *models.FunnelLead, step int, now time.Time) (bool, error) {
+	cfg := config.Get().Funnel.Recovery
+	discount := 0
+	var expires *time.Time
+	if step == cfg.DiscountStep && cfg.DiscountPercent > 0 {
+		discount = cfg.DiscountPercent
+		t := now.Add(time.Duration(cfg.DiscountValidHours) * time.Hour)
+		expires = &t
+	}
+
+	// Someone who already bought elsewhere gets no more reminders; claim the
+	// step anyway so the lead leaves the queue.
+	subscribed, err := s.leads.PhoneHasActiveSubscription(ctx, lead.Phone)
+	if err != nil {
+		return false, err
+	}
+	if subscribed {
+		discount, expires = 0, nil
+	}
+
+	claimed, err := s.recovery.ClaimStep(ctx, lead.ID, step-1, discount, expires, now)
+	if err != nil || !claimed || subscribed {
+		return false, err
+	}
+
+	msg := &models.FunnelRecoveryMessage{
+		LeadID:          lead.ID,
+		FunnelID:        lead.FunnelID,
+		Step:            step,
+		DiscountPercent: discount,
+		Status:          models.FunnelRecoverySent,
+	}
+	if err := s.send(lead.Phone, lead.FirstName, lead.CheckoutToken, discount); err != nil {
+		log.Printf("funnel recovery: lead %d step %d: %v", lead.ID, step, err)
+		msg.Status = models.FunnelRecoveryFailed
+		msg.Error = truncateRunes(err.Error(), 255)
+	}
+	if err := s.recovery.CreateMessage(ctx, msg); err != nil {
+		return false, err
+	}
+	return msg.Status == models.FunnelRecoverySent, nil
+}
+
+func (s *funnelRecoveryService) Start(ctx context.Context) {
+	interval := time.Duration(config.Get().Funnel.Recovery.IntervalSeconds) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if n, err := s.RunDue(ctx, now); err != nil {
+					log.Printf("funnel recovery: %v", err)
+				} else if n > 0 {
+					log.Printf("funnel recovery: sent %d reminders", n)
+				}
+			}
+		}
+	}()
+}
+
+func (s *funnelRecoveryService) ResumeURL(ctx context.Context, token string) (string, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", ErrFunnelLeadNotFound
+	}
+	lead, err := s.leads.FindByCheckoutToken(ctx, token)
+	if err != nil || lead == nil {
+		return "", ErrFunnelLeadNotFound
+	}
+	slug := LegacyFunnelSlug()
+	if lead.FunnelID > 0 {
+		if funnel, ferr := s.funnels.FindByID(ctx, lead.FunnelID); ferr == nil && funnel != nil {
+			slug = funnel.Slug
+		}
+	}
+	return fmt.Sprintf("%s/%s/payment?token=%s", funnelSiteBaseURL(), slug, url.QueryEscape(lead.CheckoutToken)), nil
+}
+
+func (s *funnelRecoveryService) Report(ctx context.Context, funnelID uint, from, to string) (*FunnelRecoveryReportDTO, error) {
+	start, end, err := parseFunnelRecoveryRange(from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.recovery.StepStats(ctx, funnelID, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	out := &FunnelRecoveryReportDTO{
+		From:     start.Format(aiDayLayout),
+		To:       end.Format(aiDayLayout),
+		FunnelID: funnelID,
+		Enabled:  config.Get().Funnel.Recovery.Enabled,
+		Steps:    make([]FunnelRecoveryStepDTO, 0, len(rows)),
+	}
+	for _, r := range rows {
+		out.Steps = append(out.Steps, funnelRecoveryStep(r))
+		out.Total.Sent += r.Sent
+		out.Total.Failed += r.Failed
+		out.Total.Recovered += r.Recovered
+		out.Total.RecoveredRevenue += r.RecoveredRevenue
+	}
+	if out.Total.Sent > 0 {
+		out.Total.ConversionRate = round1(float64(out.Total.Recovered) * 100 / float64(out.Total.Sent))
+	}
+	return out, nil
+}
+
+func funnelRecoveryStep(r repository.FunnelRecoveryStepStats) FunnelRecoveryStepDTO {
+	dto := FunnelRecoveryStepDTO{
+		Step:             r.Step,
+		Sent:             r.Sent,
+		Failed:           r.Failed,
+		Recovered:        r.Recovered,
+		RecoveredRevenue: r.RecoveredRevenue,
+	}
+	if r.Sent > 0 {
+		dto.ConversionRate = round1(float64(r.Recovered) * 100 / float64(r.Sent))
+	}
+	return dto
+}
+
+// parseFunnelRecoveryRange validates an inclusive YYYY-MM-DD range, defaulting
+// to the last funnelRecoveryDefaultDays days, and returns both days at midnight.
+func parseFunnelRecoveryRange(from, to string, now time.Time) (time.Time, time.Time, error) {
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if strings.TrimSpace(to) != "" {
+		t, err := time.ParseInLocation(aiDayLayout, strings.TrimSpace(to), now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrFunnelRecoveryRange
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -(funnelRecoveryDefaultDays - 1))
+	if strings.TrimSpace(from) != "" {
+		t, err := time.ParseInLocation(aiDayLayout, strings.TrimSpace(from), now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrFunnelRecoveryRange
+		}
+		start = t
+	}
+	if start.After(end) || end.Sub(start) > funnelRecoveryMaxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, ErrFunnelRecoveryRange
+	}
+	return start, end, nil
+}
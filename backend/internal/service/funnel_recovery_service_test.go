@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+type stubRecoveryRepo struct {
+	repository.FunnelRecoveryRepository
+	due      map[int][]models.FunnelLead
+	claimed  map[uint]int
+	lost     map[uint]bool // claimed by another replica
+	messages []models.FunnelRecoveryMessage
+}
+
+func (r *stubRecoveryRepo) ListDue(_ context.Context, step int, _, _ time.Time, _ int) ([]models.FunnelLead, error) {
+	return r.due[step], nil
+}
+
+func (r *stubRecoveryRepo) ClaimStep(_ context.Context, leadID uint, step, discount int, _ *time.Time, _ time.Time) (bool, error) {
+	if r.lost[leadID] {
+		return false, nil
+	}
+	r.claimed[leadID] = discount
+	return true, nil
+}
+
+func (r *stubRecoveryRepo) CreateMessage(_ context.Context, msg *models.FunnelRecoveryMessage) error {
+	r.messages = append(r.messages, *msg)
+	return nil
+}
+
+type stubRecoveryLeads struct {
+	repository.FunnelLeadRepository
+	subscribed map[string]bool
+}
+
+func (r *stubRecoveryLeads) PhoneHasActiveSubscription(_ context.Context, phone string) (bool, error) {
+	return r.subscribed[phone], nil
+}
+
+func TestFunnelRecoveryRunDue(t *testing.T) {
+	cfg := config.Get().Funnel.Recovery
+	if len(parseRecoveryDelays(cfg.DelaysMinutes)) < 2 {
+		t.Skip("needs at least two recovery delays configured")
+	}
+
+	repo := &stubRecoveryRepo{
+		due: map[int][]models.FunnelLead{
+			0: {
+				{Model: gorm.Model{ID: 1}, Phone: "09120000001", CheckoutToken: "a"},
+				{Model: gorm.Model{ID: 2}, Phone: "09120000002", CheckoutToken: "b"},
+				{Model: gorm.Model{ID: 3}, Phone: "09120000003", CheckoutToken: "c"},
+			},
+			1: {{Model: gorm.Model{ID: 4}, Phone: "09120000004", CheckoutToken: "d"}},
+		},
+		claimed: map[uint]int{},
+		lost:    map[uint]bool{3: true},
+	}
+	leads := &stubRecoveryLeads{subscribed: map[string]bool{"09120000002": true}}
+	var texted []string
+	svc := &funnelRecoveryService{leads: leads, recovery: repo, send: func(phone, _, token string, _ int) error {
+		texted = append(texted, token)
+		if token == "d" {
+			return errors.New("provider down")
+		}
+		return nil
+	}}
+
+	sent, err := svc.RunDue(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 || len(texted) != 2 || texted[0] != "a" || texted[1] != "d" {
+		t.Fatalf("want only a delivered and d attempted, got sent=%d texted=%v", sent, texted)
+	}
+	if _, ok := repo.claimed[2]; !ok {
+		t.Fatal("a subscribed lead should still be claimed so it leaves the queue")
+	}
+	if len(repo.messages) != 2 || repo.messages[1].Status != models.FunnelRecoveryFailed || repo.messages[1].Step != 2 {
+		t.Fatalf("unexpected message log %+v", repo.messages)
+	}
+
+	wantDiscount := 0
+	if cfg.DiscountStep == 2 {
+		wantDiscount = cfg.DiscountPercent
+	}
+	if repo.claimed[4] != wantDiscount || repo.claimed[2] != 0 {
+		t.Fatalf("discount should follow discount_step, got %v", repo.claimed)
+	}
+}
+
+func TestApplyPercentDiscount(t *testing.T) {
+	if got := applyPercentDiscount(1_000_000, 15); got != 850_000 {
+		t.Fatalf("got %d", got)
+	}
+	if got := applyPercentDiscount(999, 0); got != 999 {
+		t.Fatalf("no discount should keep the amount, got %d", got)
+	}
+	lead := models.FunnelLead{RecoveryDiscountPercent: 10}
+	expired := time.Now().Add(-time.Minute)
+	lead.RecoveryDiscountExpiresAt = &expired
+	if lead.RecoveryDiscountActive(time.Now()) != 0 {
+		t.Fatal("expired discount must not apply")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"math"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -28,14 +29,23 @@ var (
 	ErrFunnelInvalidStatus     = errors.New("invalid status transition")
 	ErrFunnelAlreadySubscribed = errors.New("already has active subscription")
 	ErrFunnelInvalidOTP        = errors.New("invalid or expired funnel otp")
+	ErrFunnelNotFound          = errors.New("funnel not found")
+	ErrFunnelFreeAccessOff     = errors.New("free access is disabled for this funnel")
 )
 
 const FunnelOTPPurpose = "funnel"
 
-const (
-	// DefaultFunnelCoachSlug binds the /ali-rashidabadi funnel when config is empty.
-	DefaultFunnelCoachSlug = "ali-rashidabadi"
-)
+// DefaultFunnelSlug is served by the legacy /public/funnel/* routes when
+// funnel.default_slug is empty.
+const DefaultFunnelSlug = "ali-rashidabadi"
+
+// LegacyFunnelSlug is the funnel behind the legacy /public/funnel/* routes.
+func LegacyFunnelSlug() string {
+	if v := slug.Normalize(config.Get().Funnel.DefaultSlug); v != "" {
+		return v
+	}
+	return DefaultFunnelSlug
+}
 
 type FunnelPlanDTO struct {
 	ID           uint     `json:"id"`
@@ -49,18 +59,26 @@ type FunnelPlanDTO struct {
 }
 
 type FunnelConfigDTO struct {
-	FunnelKey       string          `json:"funnelKey"` // e.g. funnel_1
-	FunnelLabel     string          `json:"funnelLabel"`
-	CoachName       string          `json:"coachName"`
-	CoachID         uint            `json:"coachId"`
-	CoachSlug       string          `json:"coachSlug"`
-	PackageTitle    string          `json:"packageTitle"`
-	PackageSubtitle string          `json:"packageSubtitle"`
-	Amount          int64           `json:"amount"`
-	DurationDays    int             `json:"durationDays"`
-	Plans           []FunnelPlanDTO `json:"plans"`
-	DefaultPlanKey  string          `json:"defaultPlanKey"`
-	DefaultPlanID   uint            `json:"defaultPlanId"`
+	FunnelID        uint                          `json:"funnelId"`
+	FunnelKey       string                        `json:"funnelKey"` // funnel slug
+	FunnelLabel     string                        `json:"funnelLabel"`
+	CoachName       string                        `json:"coachName"`
+	CoachID         uint                          `json:"coachId"`
+	CoachSlug       string                        `json:"coachSlug"`
+	PackageTitle    string                        `json:"packageTitle"`
+	PackageSubtitle string                        `json:"packageSubtitle"`
+	Amount          int64                         `json:"amount"`
+	DurationDays    int                           `json:"durationDays"`
+	Plans           []FunnelPlanDTO               `json:"plans"`
+	DefaultPlanKey  string                        `json:"defaultPlanKey"`
+	DefaultPlanID   uint                          `json:"defaultPlanId"`
+	Questions       []FunnelQuestion              `json:"questions"`
+	Analysis        map[string]FunnelAnalysisCopy `json:"analysis"` // scenario → copy
+	FreeAccess      bool                          `json:"freeAccess"`
+	// VisitorID keeps the A/B assignment sticky; send it back with the lead.
+	VisitorID    string `json:"visitorId"`
+	ExperimentID uint   `json:"experimentId,omitempty"`
+	VariantKey   string `json:"variantKey,omitempty"`
 }
 
 type CreateFunnelLeadRequest struct {
@@ -77,11 +95,12 @@ type CreateFunnelLeadRequest struct {
 	NutritionChallenge string `json:"nutritionChallenge"`
 	MainObstacle       string `json:"mainObstacle"`
 	Commitment         string `json:"commitment"`
-	Scenario           string `json:"scenario"`
+	Scenario           string `json:"scenario"` // used when no chosen option sets one
 	AnalysisTitle      string `json:"analysisTitle"`
 	AnalysisBody       string `json:"analysisBody"`
 	UTMSource          string `json:"utmSource"`
 	UTMCampaign        string `json:"utmCampaign"`
+	VisitorID          string `json:"visitorId"` // from GET config, for the A/B variant
 }
 
 type FunnelOTPRequest struct {
@@ -114,6 +133,9 @@ type FunnelCheckoutDTO struct {
 	AnalysisTitle string          `json:"analysisTitle"`
 	PaidAt        *time.Time      `json:"paidAt,omitempty"`
 	Plans         []FunnelPlanDTO `json:"plans"`
+	// DiscountPercent is a recovery discount already applied to Amount.
+	DiscountPercent   int        `json:"discountPercent,omitempty"`
+	DiscountExpiresAt *time.Time `json:"discountExpiresAt,omitempty"`
 }
 
 type AdminFunnelLeadItem struct {
@@ -153,6 +175,8 @@ type FunnelStatsDTO struct {
 	ConversionRate float64 `json:"conversionRate"`
 	PaymentRate    float64 `json:"paymentRate"`
 	PaidRevenue    int64   `json:"paidRevenue"`
+	// Experiment is set for a single funnel that has run an A/B test.
+	Experiment *FunnelExperimentStatsDTO `json:"experiment,omitempty"`
 }
 
 type AdminFunnelLeadDetail struct {
@@ -182,32 +206,55 @@ type FunnelPayResponse struct {
 }
 
 type FunnelService interface {
-	GetConfig(ctx context.Context) FunnelConfigDTO
-	RequestLeadOTP(ctx context.Context, phone string) error
-	CreateLead(ctx context.Context, req *CreateFunnelLeadRequest) (*CreateFunnelLeadResponse, error)
-	GetCheckout(ctx context.Context, token string) (*FunnelCheckoutDTO, error)
-	SelectPlan(ctx context.Context, token string, req *SelectFunnelPlanRequest) (*FunnelCheckoutDTO, error)
-	StartPayment(ctx context.Context, token string) (*FunnelPayResponse, error)
-	StartFreeAccess(ctx context.Context, token string) (*AuthResult, error)
-	IssueSession(ctx context.Context, token string) (*AuthResult, error)
-	ListLeads(ctx context.Context, status, query string, page, pageSize int) (*AdminFunnelLeadListResponse, error)
+	// Public funnel flow; every call is scoped to the funnel with slug funnelSlug.
+	GetConfig(ctx context.Context, funnelSlug, visitorID string) (*FunnelConfigDTO, error)
+	RequestLeadOTP(ctx context.Context, funnelSlug, phone string) error
+	CreateLead(ctx context.Context, funnelSlug string, req *CreateFunnelLeadRequest) (*CreateFunnelLeadResponse, error)
+	GetCheckout(ctx context.Context, funnelSlug, token string) (*FunnelCheckoutDTO, error)
+	SelectPlan(ctx context.Context, funnelSlug, token string, req *SelectFunnelPlanRequest) (*FunnelCheckoutDTO, error)
+	StartPayment(ctx context.Context, funnelSlug, token string) (*FunnelPayResponse, error)
+	StartFreeAccess(ctx context.Context, funnelSlug, token string) (*AuthResult, error)
+	IssueSession(ctx context.Context, funnelSlug, token string) (*AuthResult, error)
+
+	// Funnel management. coachID scopes the call to one coach's funnels; 0 is an
+	// admin acting on any funnel.
+	ListFunnels(ctx context.Context, coachID uint, page, pageSize int) (*FunnelListResponse, error)
+	GetFunnel(ctx context.Context, coachID, id uint) (*FunnelDTO, error)
+	CreateFunnel(ctx context.Context, coachID, actorID uint, req *FunnelUpsertRequest) (*FunnelDTO, error)
+	UpdateFunnel(ctx context.Context, coachID, id uint, req *FunnelUpsertRequest) (*FunnelDTO, error)
+	DeleteFunnel(ctx context.Context, coachID, id uint) error
+
+	// A/B experiments on a funnel, scoped like the funnel calls above.
+	ListExperiments(ctx context.Context, coachID, funnelID uint) ([]FunnelExperimentDTO, error)
+	CreateExperiment(ctx context.Context, coachID, funnelID uint, req *FunnelExperimentRequest) (*FunnelExperimentDTO, error)
+	UpdateExperiment(ctx context.Context, coachID, funnelID, id uint, req *FunnelExperimentRequest) (*FunnelExperimentDTO, error)
+	DeleteExperiment(ctx context.Context, coachID, funnelID, id uint) error
+	StartExperiment(ctx context.Context, coachID, funnelID, id uint) (*FunnelExperimentDTO, error)
+	StopExperiment(ctx context.Context, coachID, funnelID, id uint) (*FunnelExperimentDTO, error)
+
+	// Admin CRM; funnelID 0 covers every funnel.
+	ListLeads(ctx context.Context, funnelID uint, status, query string, page, pageSize int) (*AdminFunnelLeadListResponse, error)
 	GetLeadByID(ctx context.Context, id uint) (*AdminFunnelLeadDetail, error)
 	PatchLead(ctx context.Context, id uint, req *PatchFunnelLeadRequest) error
 	DeleteLead(ctx context.Context, id uint) error
-	GetStats(ctx context.Context) (*FunnelStatsDTO, error)
+	GetStats(ctx context.Context, funnelID uint) (*FunnelStatsDTO, error)
 }
 
 type funnelService struct {
-	repo      repository.FunnelLeadRepository
-	coachRepo repository.CoachProfileRepository
-	planRepo  repository.ServicePlanRepository
-	userRepo  repository.UserRepository
-	orderRepo repository.OrderRepository
-	payment   PaymentService
-	auth      AuthService
+	funnels     repository.FunnelRepository
+	experiments repository.FunnelExperimentRepository
+	repo        repository.FunnelLeadRepository
+	coachRepo   repository.CoachProfileRepository
+	planRepo    repository.ServicePlanRepository
+	userRepo    repository.UserRepository
+	orderRepo   repository.OrderRepository
+	payment     PaymentService
+	auth        AuthService
 }
 
 func NewFunnelService(
+	funnels repository.FunnelRepository,
+	experiments repository.FunnelExperimentRepository,
 	repo repository.FunnelLeadRepository,
 	coachRepo repository.CoachProfileRepository,
 	planRepo repository.ServicePlanRepository,
@@ -217,21 +264,16 @@ func NewFunnelService(
 	auth AuthService,
 ) FunnelService {
 	return &funnelService{
-		repo:      repo,
-		coachRepo: coachRepo,
-		planRepo:  planRepo,
-		userRepo:  userRepo,
-		orderRepo: orderRepo,
-		payment:   payment,
-		auth:      auth,
-	}
-}
-
-func funnelCoachSlug() string {
-	if v := strings.TrimSpace(config.Get().Funnel.CoachSlug); v != "" {
-		return slug.Normalize(v)
+		funnels:     funnels,
+		experiments: experiments,
+		repo:        repo,
+		coachRepo:   coachRepo,
+		planRepo:    planRepo,
+		userRepo:    userRepo,
+		orderRepo:   orderRepo,
+		payment:     payment,
+		auth:        auth,
 	}
-	return DefaultFunnelCoachSlug
 }
 
 func planSellPrice(p *models.ServicePlan) int64 {
@@ -271,22 +313,76 @@ func toFunnelPlanDTO(p *models.ServicePlan) FunnelPlanDTO {
 	}
 }
 
-func (s *funnelService) loadFunnelCoach(ctx context.Context) (*models.CoachProfile, error) {
-	profile, err := s.coachRepo.FindBySlug(ctx, funnelCoachSlug())
+// activeFunnel loads the public funnel with slug funnelSlug.
+func (s *funnelService) activeFunnel(ctx context.Context, funnelSlug string) (*models.Funnel, error) {
+	key := slug.Normalize(funnelSlug)
+	if key == "" {
+		return nil, ErrFunnelNotFound
+	}
+	funnel, err := s.funnels.FindBySlug(ctx, key)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFunnelNotFound
+		}
+		return nil, err
+	}
+	if !funnel.IsActive {
+		return nil, ErrFunnelNotFound
+	}
+	return funnel, nil
+}
+
+// funnelLead loads the lead behind a checkout token, which must belong to funnel.
+func (s *funnelService) funnelLead(ctx context.Context, funnel *models.Funnel, token string) (*models.FunnelLead, error) {
+	lead, err := s.repo.FindByCheckoutToken(ctx, strings.TrimSpace(token))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, nil
+			return nil, ErrFunnelLeadNotFound
 		}
 		return nil, err
 	}
-	return profile, nil
+	if lead.FunnelID != funnel.ID {
+		return nil, ErrFunnelLeadNotFound
+	}
+	return lead, nil
 }
 
-func (s *funnelService) loadCoachPlans(ctx context.Context, coachUserID uint) ([]FunnelPlanDTO, error) {
-	if coachUserID == 0 {
-		return []FunnelPlanDTO{}, nil
+func (s *funnelService) coachName(ctx context.Context, coachUserID uint) string {
+	profile, err := s.coachRepo.FindByUserID(ctx, coachUserID)
+	if err != nil || profile == nil {
+		return ""
 	}
-	plans, err := s.planRepo.ListActiveByCoachID(ctx, coachUserID)
+	if name := strings.TrimSpace(profile.DisplayName); name != "" {
+		return name
+	}
+	return profile.Slug
+}
+
+// loadFunnelPlans returns the funnel coach's active plans, limited to the
+// funnel's eligible plans when it lists any.
+func (s *funnelService) loadFunnelPlans(ctx context.Context, funnel *models.Funnel) ([]models.ServicePlan, error) {
+	if funnel.CoachID == 0 {
+		return nil, nil
+	}
+	plans, err := s.planRepo.ListActiveByCoachID(ctx, funnel.CoachID)
+	if err != nil {
+		return nil, err
+	}
+	eligible := decodeFunnelPlanIDs(funnel.PlanIDs)
+	if len(eligible) == 0 {
+		return plans, nil
+	}
+	out := plans[:0]
+	for _, p := range plans {
+		if slices.Contains(eligible, p.ID) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (s *funnelService) funnelPlanDTOs(ctx context.Context, funnel *models.Funnel) ([]FunnelPlanDTO, error) {
+	plans, err := s.loadFunnelPlans(ctx, funnel)
 	if err != nil {
 		return nil, err
 	}
@@ -297,35 +393,35 @@ func (s *funnelService) loadCoachPlans(ctx context.Context, coachUserID uint) ([
 	return out, nil
 }
 
-func (s *funnelService) resolveSelectedPlan(ctx context.Context, coachUserID, planID uint, packageKey string) (*models.ServicePlan, error) {
+// resolveSelectedPlan picks the requested plan among the funnel's plans. When
+// none is requested it falls back to preferredID (the A/B variant's default),
+// then the popular plan, then the first one.
+func (s *funnelService) resolveSelectedPlan(ctx context.Context, funnel *models.Funnel, planID uint, packageKey string, preferredID uint) (*models.ServicePlan, error) {
 	if planID == 0 {
 		if id, err := strconv.ParseUint(strings.TrimSpace(packageKey), 10, 64); err == nil {
 			planID = uint(id)
 		}
 	}
-	if coachUserID == 0 {
+	plans, err := s.loadFunnelPlans(ctx, funnel)
+	if err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
 		return nil, ErrFunnelInvalidInput
 	}
 	if planID > 0 {
-		plan, err := s.planRepo.FindByIDAndCoachID(ctx, planID, coachUserID)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil, ErrFunnelInvalidInput
+		for i := range plans {
+			if plans[i].ID == planID {
+				return &plans[i], nil
 			}
-			return nil, err
-		}
-		if !plan.IsActive {
-			return nil, ErrFunnelInvalidInput
 		}
-		return plan, nil
-	}
-	plans, err := s.planRepo.ListActiveByCoachID(ctx, coachUserID)
-	if err != nil {
-		return nil, err
-	}
-	if len(plans) == 0 {
 		return nil, ErrFunnelInvalidInput
 	}
+	for i := range plans {
+		if preferredID > 0 && plans[i].ID == preferredID {
+			return &plans[i], nil
+		}
+	}
 	for i := range plans {
 		if plans[i].IsPopular {
 			return &plans[i], nil
@@ -334,27 +430,40 @@ func (s *funnelService) resolveSelectedPlan(ctx context.Context, coachUserID, pl
 	return &plans[0], nil
 }
 
-func (s *funnelService) GetConfig(ctx context.Context) FunnelConfigDTO {
-	slugKey := funnelCoachSlug()
-	dto := FunnelConfigDTO{
-		FunnelKey:   "funnel_1",
-		FunnelLabel: "فانل ۱ — اختصاصی علی رشیدآبادی",
-		CoachSlug:   slugKey,
-		Plans:       []FunnelPlanDTO{},
+func (s *funnelService) GetConfig(ctx context.Context, funnelSlug, visitorID string) (*FunnelConfigDTO, error) {
+	funnel, err := s.activeFunnel(ctx, funnelSlug)
+	if err != nil {
+		return nil, err
 	}
-	profile, err := s.loadFunnelCoach(ctx)
-	if err != nil || profile == nil {
-		return dto
+	visitorID = normalizeFunnelVisitorID(visitorID)
+	assignment := s.assignVariant(ctx, funnel, visitorID)
+	dto := &FunnelConfigDTO{
+		FunnelID:    funnel.ID,
+		FunnelKey:   funnel.Slug,
+		FunnelLabel: funnel.Title,
+		CoachID:     funnel.CoachID,
+		CoachName:   s.coachName(ctx, funnel.CoachID),
+		Plans:       []FunnelPlanDTO{},
+		Questions:   assignment.orderQuestions(funnelQuestions(funnel)),
+		Analysis:    assignment.analysisCopy(funnel),
+		FreeAccess:  funnel.FreeAccessEnabled,
+		VisitorID:   visitorID,
+	}
+	if assignment.variant != nil {
+		dto.ExperimentID = assignment.experiment.ID
+		dto.VariantKey = assignment.variant.Key
+		if assignment.variant.Headline != "" {
+			dto.FunnelLabel = assignment.variant.Headline
+		}
 	}
-	dto.CoachID = profile.UserID
-	dto.CoachName = strings.TrimSpace(profile.DisplayName)
-	if dto.CoachName == "" {
-		dto.CoachName = profile.Slug
+	if profile, err := s.coachRepo.FindByUserID(ctx, funnel.CoachID); err == nil && profile != nil {
+		dto.CoachSlug = profile.Slug
 	}
-	plans, err := s.loadCoachPlans(ctx, profile.UserID)
+	plans, err := s.funnelPlanDTOs(ctx, funnel)
 	if err != nil {
-		return dto
+		return nil, err
 	}
+	plans = assignment.orderPlans(plans)
 	dto.Plans = plans
 	if len(plans) > 0 {
 		def := plans[0]
@@ -364,6 +473,12 @@ func (s *funnelService) GetConfig(ctx context.Context) FunnelConfigDTO {
 				break
 			}
 		}
+		for _, p := range plans {
+			if p.ID == assignment.defaultPlanID() {
+				def = p
+				break
+			}
+		}
 		dto.PackageTitle = def.Title
 		dto.PackageSubtitle = def.Subtitle
 		dto.Amount = def.Amount
@@ -371,18 +486,22 @@ func (s *funnelService) GetConfig(ctx context.Context) FunnelConfigDTO {
 		dto.DefaultPlanKey = def.Key
 		dto.DefaultPlanID = def.ID
 	}
-	return dto
+	return dto, nil
 }
 
+// applyPlanToLead prices the lead at plan, less any recovery discount still valid.
 func applyPlanToLead(lead *models.FunnelLead, plan *models.ServicePlan) {
 	dto := toFunnelPlanDTO(plan)
 	lead.ServicePlanID = plan.ID
 	lead.PackageKey = dto.Key
 	lead.PackageTitle = dto.Title
-	lead.AmountCents = dto.Amount
+	lead.AmountCents = applyPercentDiscount(dto.Amount, lead.RecoveryDiscountActive(time.Now()))
 }
 
-func (s *funnelService) RequestLeadOTP(ctx context.Context, phone string) error {
+func (s *funnelService) RequestLeadOTP(ctx context.Context, funnelSlug, phone string) error {
+	if _, err := s.activeFunnel(ctx, funnelSlug); err != nil {
+		return err
+	}
 	phone = normalizePhone(phone)
 	if phone == "" {
 		return ErrFunnelInvalidInput
@@ -393,10 +512,14 @@ func (s *funnelService) RequestLeadOTP(ctx context.Context, phone string) error
 	return s.auth.RequestOTPForPurpose(ctx, phone, FunnelOTPPurpose)
 }
 
-func (s *funnelService) CreateLead(ctx context.Context, req *CreateFunnelLeadRequest) (*CreateFunnelLeadResponse, error) {
+func (s *funnelService) CreateLead(ctx context.Context, funnelSlug string, req *CreateFunnelLeadRequest) (*CreateFunnelLeadResponse, error) {
 	if req == nil {
 		return nil, ErrFunnelInvalidInput
 	}
+	funnel, err := s.activeFunnel(ctx, funnelSlug)
+	if err != nil {
+		return nil, err
+	}
 	firstName := strings.TrimSpace(req.FirstName)
 	lastName := strings.TrimSpace(req.LastName)
 	phone := normalizePhone(req.Phone)
@@ -407,14 +530,10 @@ func (s *funnelService) CreateLead(ctx context.Context, req *CreateFunnelLeadReq
 	if otpCode == "" {
 		return nil, ErrFunnelInvalidOTP
 	}
-	if !isValidPrimaryGoal(req.PrimaryGoal) || !isValidActivityLevel(req.ActivityLevel) || !isValidMainObstacle(req.MainObstacle) {
-		return nil, ErrFunnelInvalidInput
-	}
-	if !isValidTrainingEnv(req.TrainingEnv) || !isValidExperience(req.Experience) ||
-		!isValidNutritionChallenge(req.NutritionChallenge) || !isValidCommitment(req.Commitment) {
-		return nil, ErrFunnelInvalidInput
+	answers, scenario, err := validateFunnelAnswers(funnelQuestions(funnel), req)
+	if err != nil {
+		return nil, err
 	}
-
 	if hasSub, err := s.repo.PhoneHasActiveSubscription(ctx, phone); err != nil {
 		return nil, err
 	} else if hasSub {
@@ -431,39 +550,47 @@ func (s *funnelService) CreateLead(ctx context.Context, req *CreateFunnelLeadReq
 		return nil, err
 	}
 
-	profile, err := s.loadFunnelCoach(ctx)
-	if err != nil {
+	coachName := s.coachName(ctx, funnel.CoachID)
+	if coachName == "" {
+		return nil, ErrFunnelInvalidInput
+	}
+
+	existing, err := s.repo.FindLatestPendingByPhone(ctx, funnel.ID, phone)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
-	if profile == nil {
-		return nil, ErrFunnelInvalidInput
+	// A resumed checkout keeps the variant it was first shown.
+	var assignment funnelAssignment
+	if existing != nil {
+		assignment = s.leadVariant(ctx, existing)
 	}
-	coachName := strings.TrimSpace(profile.DisplayName)
-	if coachName == "" {
-		coachName = profile.Slug
+	if assignment.variant == nil {
+		assignment = s.assignVariant(ctx, funnel, normalizeFunnelVisitorID(req.VisitorID))
 	}
 
-	plan, err := s.resolveSelectedPlan(ctx, profile.UserID, req.PlanID, req.PackageKey)
+	analysisTitle := strings.TrimSpace(req.AnalysisTitle)
+	analysisBody := strings.TrimSpace(req.AnalysisBody)
+	if analysisTitle == "" && analysisBody == "" {
+		if text, ok := assignment.analysisCopy(funnel)[scenario]; ok {
+			analysisTitle, analysisBody = text.Title, text.Body
+		}
+	}
+	plan, err := s.resolveSelectedPlan(ctx, funnel, req.PlanID, req.PackageKey, assignment.defaultPlanID())
 	if err != nil {
 		return nil, err
 	}
 
 	// Resume unpaid checkout for same phone (registered or guest) → payment page.
-	if existing, err := s.repo.FindLatestPendingByPhone(ctx, phone); err == nil && existing != nil {
+	if existing != nil {
 		existing.FirstName = firstName
 		existing.LastName = lastName
-		existing.CoachID = profile.UserID
+		existing.CoachID = funnel.CoachID
 		existing.CoachName = coachName
-		existing.PrimaryGoal = req.PrimaryGoal
-		existing.ActivityLevel = req.ActivityLevel
-		existing.TrainingEnv = strings.TrimSpace(req.TrainingEnv)
-		existing.Experience = strings.TrimSpace(req.Experience)
-		existing.NutritionChallenge = strings.TrimSpace(req.NutritionChallenge)
-		existing.MainObstacle = req.MainObstacle
-		existing.Commitment = strings.TrimSpace(req.Commitment)
-		existing.Scenario = strings.TrimSpace(req.Scenario)
-		existing.AnalysisTitle = strings.TrimSpace(req.AnalysisTitle)
-		existing.AnalysisBody = strings.TrimSpace(req.AnalysisBody)
+		answers.applyTo(existing)
+		existing.Scenario = scenario
+		existing.AnalysisTitle = analysisTitle
+		existing.AnalysisBody = analysisBody
+		assignment.applyTo(existing)
 		applyPlanToLead(existing, plan)
 		if src := strings.TrimSpace(req.UTMSource); src != "" {
 			existing.UTMSource = src
@@ -479,35 +606,29 @@ func (s *funnelService) CreateLead(ctx context.Context, req *CreateFunnelLeadReq
 		}
 		return &CreateFunnelLeadResponse{
 			CheckoutToken: existing.CheckoutToken,
-			PaymentURL:    "/ali-rashidabadi/payment?token=" + existing.CheckoutToken,
+			PaymentURL:    funnelPaymentPath(funnel, existing.CheckoutToken),
 			Resumed:       true,
 		}, nil
-	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, err
 	}
 
 	token := generateFunnelToken()
 	lead := &models.FunnelLead{
-		CheckoutToken:      token,
-		CoachID:            profile.UserID,
-		CoachName:          coachName,
-		FirstName:          firstName,
-		LastName:           lastName,
-		Phone:              phone,
-		PrimaryGoal:        req.PrimaryGoal,
-		ActivityLevel:      req.ActivityLevel,
-		TrainingEnv:        strings.TrimSpace(req.TrainingEnv),
-		Experience:         strings.TrimSpace(req.Experience),
-		NutritionChallenge: strings.TrimSpace(req.NutritionChallenge),
-		MainObstacle:       req.MainObstacle,
-		Commitment:         strings.TrimSpace(req.Commitment),
-		Scenario:           strings.TrimSpace(req.Scenario),
-		AnalysisTitle:      strings.TrimSpace(req.AnalysisTitle),
-		AnalysisBody:       strings.TrimSpace(req.AnalysisBody),
-		Status:             models.FunnelStatusPendingPayment,
-		UTMSource:          strings.TrimSpace(req.UTMSource),
-		UTMCampaign:        strings.TrimSpace(req.UTMCampaign),
-	}
+		FunnelID:      funnel.ID,
+		CheckoutToken: token,
+		CoachID:       funnel.CoachID,
+		CoachName:     coachName,
+		FirstName:     firstName,
+		LastName:      lastName,
+		Phone:         phone,
+		Scenario:      scenario,
+		AnalysisTitle: analysisTitle,
+		AnalysisBody:  analysisBody,
+		Status:        models.FunnelStatusPendingPayment,
+		UTMSource:     strings.TrimSpace(req.UTMSource),
+		UTMCampaign:   strings.TrimSpace(req.UTMCampaign),
+	}
+	answers.applyTo(lead)
+	assignment.applyTo(lead)
 	applyPlanToLead(lead, plan)
 
 	if err := s.repo.Create(ctx, lead); err != nil {
@@ -516,31 +637,58 @@ func (s *funnelService) CreateLead(ctx context.Context, req *CreateFunnelLeadReq
 
 	return &CreateFunnelLeadResponse{
 		CheckoutToken: token,
-		PaymentURL:    "/ali-rashidabadi/payment?token=" + token,
+		PaymentURL:    funnelPaymentPath(funnel, token),
 		Resumed:       false,
 	}, nil
 }
 
-func (s *funnelService) GetCheckout(ctx context.Context, token string) (*FunnelCheckoutDTO, error) {
-	lead, err := s.repo.FindByCheckoutToken(ctx, strings.TrimSpace(token))
+// funnelPaymentPath is the site page where the lead picks a plan and pays.
+func funnelPaymentPath(funnel *models.Funnel, token string) string {
+	return "/" + funnel.Slug + "/payment?token=" + token
+}
+
+func (s *funnelService) GetCheckout(ctx context.Context, funnelSlug, token string) (*FunnelCheckoutDTO, error) {
+	funnel, err := s.activeFunnel(ctx, funnelSlug)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrFunnelLeadNotFound
-		}
 		return nil, err
 	}
-	return s.leadToCheckoutDTO(ctx, lead)
+	lead, err := s.funnelLead(ctx, funnel, token)
+	if err != nil {
+		return nil, err
+	}
+	if lead.Status == models.FunnelStatusPendingPayment && s.repriceLead(ctx, lead) {
+		if err := s.repo.Update(ctx, lead); err != nil {
+			return nil, err
+		}
+	}
+	return s.leadToCheckoutDTO(ctx, funnel, lead)
 }
 
-func (s *funnelService) SelectPlan(ctx context.Context, token string, req *SelectFunnelPlanRequest) (*FunnelCheckoutDTO, error) {
+// repriceLead re-applies the lead's plan in case a recovery discount started or
+// expired since the plan was picked, reporting whether the amount changed.
+func (s *funnelService) repriceLead(ctx context.Context, lead *models.FunnelLead) bool {
+	if lead.ServicePlanID == 0 {
+		return false
+	}
+	plan, err := s.planRepo.FindByID(ctx, lead.ServicePlanID)
+	if err != nil || !plan.IsActive {
+		return false
+	}
+	before := lead.AmountCents
+	applyPlanToLead(lead, plan)
+	return lead.AmountCents != before
+}
+
+func (s *funnelService) SelectPlan(ctx context.Context, funnelSlug, token string, req *SelectFunnelPlanRequest) (*FunnelCheckoutDTO, error) {
 	if req == nil {
 		return nil, ErrFunnelInvalidInput
 	}
-	lead, err := s.repo.FindByCheckoutToken(ctx, strings.TrimSpace(token))
+	funnel, err := s.activeFunnel(ctx, funnelSlug)
+	if err != nil {
+		return nil, err
+	}
+	lead, err := s.funnelLead(ctx, funnel, token)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrFunnelLeadNotFound
-		}
 		return nil, err
 	}
 	if lead.Status == models.FunnelStatusPaid {
@@ -550,34 +698,29 @@ func (s *funnelService) SelectPlan(ctx context.Context, token string, req *Selec
 		return nil, ErrFunnelInvalidStatus
 	}
 
-	coachID := lead.CoachID
-	if coachID == 0 {
-		if profile, err := s.loadFunnelCoach(ctx); err == nil && profile != nil {
-			coachID = profile.UserID
-			lead.CoachID = coachID
-		}
-	}
-	plan, err := s.resolveSelectedPlan(ctx, coachID, req.PlanID, req.PackageKey)
+	plan, err := s.resolveSelectedPlan(ctx, funnel, req.PlanID, req.PackageKey, s.leadVariant(ctx, lead).defaultPlanID())
 	if err != nil {
 		return nil, err
 	}
 	applyPlanToLead(lead, plan)
+	lead.CoachID = funnel.CoachID
 	lead.OrderID = 0 // force a fresh gateway order after plan change
 	if err := s.repo.Update(ctx, lead); err != nil {
 		return nil, err
 	}
-	return s.leadToCheckoutDTO(ctx, lead)
+	return s.leadToCheckoutDTO(ctx, funnel, lead)
 }
 
-func (s *funnelService) StartPayment(ctx context.Context, token string) (*FunnelPayResponse, error) {
+func (s *funnelService) StartPayment(ctx context.Context, funnelSlug, token string) (*FunnelPayResponse, error) {
 	if s.payment == nil || s.userRepo == nil {
 		return nil, ErrPaymentGatewayFailed
 	}
-	lead, err := s.repo.FindByCheckoutToken(ctx, strings.TrimSpace(token))
+	funnel, err := s.activeFunnel(ctx, funnelSlug)
+	if err != nil {
+		return nil, err
+	}
+	lead, err := s.funnelLead(ctx, funnel, token)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrFunnelLeadNotFound
-		}
 		return nil, err
 	}
 	if lead.Status == models.FunnelStatusPaid {
@@ -586,6 +729,7 @@ func (s *funnelService) StartPayment(ctx context.Context, token string) (*Funnel
 	if lead.Status != models.FunnelStatusPendingPayment {
 		return nil, ErrFunnelInvalidStatus
 	}
+	s.repriceLead(ctx, lead)
 	if lead.ServicePlanID == 0 || lead.AmountCents <= 0 {
 		return nil, ErrFunnelInvalidInput
 	}
@@ -629,19 +773,23 @@ func (s *funnelService) StartPayment(ctx context.Context, token string) (*Funnel
 
 // StartFreeAccess creates/finds the student account and issues a session without payment.
 // Lead stays pending_payment so they can buy a plan later from the panel.
-func (s *funnelService) StartFreeAccess(ctx context.Context, token string) (*AuthResult, error) {
+func (s *funnelService) StartFreeAccess(ctx context.Context, funnelSlug, token string) (*AuthResult, error) {
 	if s.auth == nil || s.userRepo == nil {
 		return nil, ErrFunnelInvalidStatus
 	}
-	lead, err := s.repo.FindByCheckoutToken(ctx, strings.TrimSpace(token))
+	funnel, err := s.activeFunnel(ctx, funnelSlug)
+	if err != nil {
+		return nil, err
+	}
+	lead, err := s.funnelLead(ctx, funnel, token)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrFunnelLeadNotFound
-		}
 		return nil, err
 	}
 	if lead.Status == models.FunnelStatusPaid {
-		return s.IssueSession(ctx, token)
+		return s.IssueSession(ctx, funnelSlug, token)
+	}
+	if !funnel.FreeAccessEnabled {
+		return nil, ErrFunnelFreeAccessOff
 	}
 	if lead.Status != models.FunnelStatusPendingPayment && lead.Status != models.FunnelStatusFailed {
 		return nil, ErrFunnelInvalidStatus
@@ -654,15 +802,16 @@ func (s *funnelService) StartFreeAccess(ctx context.Context, token string) (*Aut
 	return s.auth.IssueSession(ctx, user.ID)
 }
 
-func (s *funnelService) IssueSession(ctx context.Context, token string) (*AuthResult, error) {
+func (s *funnelService) IssueSession(ctx context.Context, funnelSlug, token string) (*AuthResult, error) {
 	if s.auth == nil || s.userRepo == nil {
 		return nil, ErrFunnelInvalidStatus
 	}
-	lead, err := s.repo.FindByCheckoutToken(ctx, strings.TrimSpace(token))
+	funnel, err := s.activeFunnel(ctx, funnelSlug)
+	if err != nil {
+		return nil, err
+	}
+	lead, err := s.funnelLead(ctx, funnel, token)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrFunnelLeadNotFound
-		}
 		return nil, err
 	}
 	if lead.Status != models.FunnelStatusPaid {
@@ -691,7 +840,7 @@ func (s *funnelService) IssueSession(ctx context.Context, token string) (*AuthRe
 func (s *funnelService) resolveFunnelOrderID(ctx context.Context, lead *models.FunnelLead, userID uint) (uint, error) {
 	if lead.OrderID > 0 && s.orderRepo != nil {
 		if order, err := s.orderRepo.GetByID(ctx, lead.OrderID); err == nil && order != nil {
-			if order.UserID == userID && order.Status == "pending" {
+			if order.UserID == userID && order.Status == "pending" && order.TotalAmountCents == lead.AmountCents {
 				items, itemErr := s.orderRepo.GetOrderItems(ctx, order.ID)
 				if itemErr == nil && len(items) > 0 && items[0].PlanID == lead.ServicePlanID {
 					return order.ID, nil
@@ -701,7 +850,8 @@ func (s *funnelService) resolveFunnelOrderID(ctx context.Context, lead *models.F
 	}
 
 	prepared, err := s.payment.PrepareCheckoutOrder(ctx, userID, &CheckoutRequest{
-		Items: []CheckoutItemRequest{{PlanID: lead.ServicePlanID, Qty: 1}},
+		Items:           []CheckoutItemRequest{{PlanID: lead.ServicePlanID, Qty: 1}},
+		DiscountPercent: lead.RecoveryDiscountActive(time.Now()),
 	})
 	if err != nil {
 		return 0, err
@@ -783,7 +933,7 @@ func hashFunnelPassword(raw string) (string, error) {
 	return string(b), nil
 }
 
-func (s *funnelService) ListLeads(ctx context.Context, status, query string, page, pageSize int) (*AdminFunnelLeadListResponse, error) {
+func (s *funnelService) ListLeads(ctx context.Context, funnelID uint, status, query string, page, pageSize int) (*AdminFunnelLeadListResponse, error) {
 	if page <= 0 {
 		page = 1
 	}
@@ -794,7 +944,7 @@ func (s *funnelService) ListLeads(ctx context.Context, status, query string, pag
 		pageSize = 100
 	}
 
-	leads, total, err := s.repo.List(ctx, status, query, page, pageSize)
+	leads, total, err := s.repo.List(ctx, funnelID, status, query, page, pageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -877,17 +1027,22 @@ func (s *funnelService) PatchLead(ctx context.Context, id uint, req *PatchFunnel
 }
 
 func (s *funnelService) DeleteLead(ctx context.Context, id uint) error {
-	if _, err := s.repo.FindByID(ctx, id); err != nil {
+	lead, err := s.repo.FindByID(ctx, id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return ErrFunnelLeadNotFound
 		}
 		return err
 	}
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	AuditChange(ctx, "funnel_lead", id, lead, nil)
+	return nil
 }
 
-func (s *funnelService) GetStats(ctx context.Context) (*FunnelStatsDTO, error) {
-	raw, err := s.repo.Stats(ctx)
+func (s *funnelService) GetStats(ctx context.Context, funnelID uint) (*FunnelStatsDTO, error) {
+	raw, err := s.repo.Stats(ctx, funnelID)
 	if err != nil {
 		return nil, err
 	}
@@ -906,6 +1061,9 @@ func (s *funnelService) GetStats(ctx context.Context) (*FunnelStatsDTO, error) {
 	if raw.Total > 0 {
 		dto.PaymentRate = round1(float64(raw.Paid) / float64(raw.Total) * 100)
 	}
+	if dto.Experiment, err = s.experimentStats(ctx, funnelID); err != nil {
+		return nil, err
+	}
 	return dto, nil
 }
 
@@ -913,21 +1071,17 @@ func round1(v float64) float64 {
 	return math.Round(v*10) / 10
 }
 
-func (s *funnelService) leadToCheckoutDTO(ctx context.Context, lead *models.FunnelLead) (*FunnelCheckoutDTO, error) {
-	plans, err := s.loadCoachPlans(ctx, lead.CoachID)
+func (s *funnelService) leadToCheckoutDTO(ctx context.Context, funnel *models.Funnel, lead *models.FunnelLead) (*FunnelCheckoutDTO, error) {
+	plans, err := s.funnelPlanDTOs(ctx, funnel)
 	if err != nil {
 		return nil, err
 	}
-	if len(plans) == 0 {
-		if profile, err := s.loadFunnelCoach(ctx); err == nil && profile != nil {
-			plans, _ = s.loadCoachPlans(ctx, profile.UserID)
-		}
-	}
+	plans = s.leadVariant(ctx, lead).orderPlans(plans)
 	key := strings.TrimSpace(lead.PackageKey)
 	if key == "" && lead.ServicePlanID > 0 {
 		key = strconv.FormatUint(uint64(lead.ServicePlanID), 10)
 	}
-	return &FunnelCheckoutDTO{
+	dto := &FunnelCheckoutDTO{
 		CheckoutToken: lead.CheckoutToken,
 		FirstName:     lead.FirstName,
 		LastName:      lead.LastName,
@@ -942,7 +1096,12 @@ func (s *funnelService) leadToCheckoutDTO(ctx context.Context, lead *models.Funn
 		AnalysisTitle: lead.AnalysisTitle,
 		PaidAt:        lead.PaidAt,
 		Plans:         plans,
-	}, nil
+	}
+	if pct := lead.RecoveryDiscountActive(time.Now()); pct > 0 {
+		dto.DiscountPercent = pct
+		dto.DiscountExpiresAt = lead.RecoveryDiscountExpiresAt
+	}
+	return dto, nil
 }
 
 func leadToAdminItem(lead *models.FunnelLead) AdminFunnelLeadItem {
@@ -979,7 +1138,7 @@ func derefString(p *string) string {
 	return *p
 }
 
-// funnelStage maps CRM status to a human pipeline stage for the Ali Rashidabadi funnel.
+// funnelStage maps CRM status to a human pipeline stage.
 // Index: 1=ارزیابی+ثبت لید, 2=در انتظار پرداخت, 3=خرید شده, 4=تماس گرفته شده
 func funnelStage(status string) (key, label string, index int) {
 	switch status {
@@ -1011,68 +1170,3 @@ func generateFunnelTrackingCode() string {
 func normalizePhone(phone string) string {
 	return digits.NormalizePhone(phone)
 }
-
-func isValidPrimaryGoal(v string) bool {
-	switch v {
-	case "weight_loss", "muscle_gain", "fitness":
-		return true
-	default:
-		return false
-	}
-}
-
-func isValidActivityLevel(v string) bool {
-	switch v {
-	case "sedentary", "moderate", "active":
-		return true
-	default:
-		return false
-	}
-}
-
-func isValidMainObstacle(v string) bool {
-	switch v {
-	case "motivation", "plateau", "knowledge":
-		return true
-	default:
-		return false
-	}
-}
-
-// New funnel answers are optional (empty allowed) so older clients keep working,
-// but if provided they must be one of the known values.
-func isValidTrainingEnv(v string) bool {
-	switch v {
-	case "", "home", "gym":
-		return true
-	default:
-		return false
-	}
-}
-
-func isValidExperience(v string) bool {
-	switch v {
-	case "", "beginner", "intermediate", "advanced":
-		return true
-	default:
-		return false
-	}
-}
-
-func isValidNutritionChallenge(v string) bool {
-	switch v {
-	case "", "sweets", "low_appetite", "no_time":
-		return true
-	default:
-		return false
-	}
-}
-
-func isValidCommitment(v string) bool {
-	switch v {
-	case "", "flexible", "max_results":
-		return true
-	default:
-		return false
-	}
-}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func solvePow(t *testing.T, ch *HumanChallenge) string {
+	t.Helper()
+	for i := 0; i < 1<<24; i++ {
+		nonce := strconv.Itoa(i)
+		sum := sha256.Sum256([]byte(ch.Challenge + ":" + nonce))
+		if leadingZeroBits(sum[:]) >= ch.Difficulty {
+			return nonce
+		}
+	}
+	t.Fatal("no proof-of-work solution found")
+	return ""
+}
+
+func TestProofOfWork(t *testing.T) {
+	ctx := context.Background()
+	pow := NewProofOfWork([]byte("secret"), 8, NewMemoryRateLimiter()).(*proofOfWork)
+	ch, err := pow.Challenge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := solvePow(t, ch)
+
+	verify := func(challenge, nonce string) error {
+		r := httptest.NewRequest("POST", "/feedbacks", nil)
+		r.Header.Set(PowChallengeHeader, challenge)
+		r.Header.Set(PowNonceHeader, nonce)
+		return pow.Verify(ctx, r)
+	}
+	if err := verify(ch.Challenge, nonce); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	if err := verify(ch.Challenge, nonce); !errors.Is(err, ErrHumanCheckFailed) {
+		t.Fatalf("replayed proof: %v", err)
+	}
+
+	// A challenge signed with another key, or an expired one, is refused.
+	other := NewProofOfWork([]byte("other"), 8, NewMemoryRateLimiter())
+	forged, _ := other.Challenge(ctx)
+	if err := verify(forged.Challenge, solvePow(t, forged)); !errors.Is(err, ErrHumanCheckFailed) {
+		t.Fatalf("forged challenge: %v", err)
+	}
+	fresh, _ := pow.Challenge(ctx)
+	freshNonce := solvePow(t, fresh)
+	pow.now = func() time.Time { return time.Now().Add(powChallengeTTL + time.Minute) }
+	if err := verify(fresh.Challenge, freshNonce); !errors.Is(err, ErrHumanCheckFailed) {
+		t.Fatalf("expired challenge: %v", err)
+	}
+}
@@ -0,0 +1,524 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/fitness-management/config"
+)
+
+const (
+	LLMProviderOpenAI           = "openai"
+	LLMProviderOpenAICompatible = "openai_compatible"
+	LLMProviderFake             = "fake"
+
+	openAIBaseURL = "https://api.openai.com/v1"
+)
+
+// ErrLLMStreamStopped is returned by a stream callback to end the stream early;
+// providers return the text received so far without an error.
+var ErrLLMStreamStopped = errors.New("llm stream stopped")
+
+// LLMMessage is one chat turn. Assistant turns that requested tools carry
+// ToolCalls; the results go back as role "tool" messages with ToolCallID set.
+type LLMMessage struct {
+	Role       string
+	Content    string
+	ToolCalls  []LLMToolCall
+	ToolCallID string
+}
+
+// LLMTool declares a function the model may call; Parameters is a JSON Schema object.
+type LLMTool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+}
+
+// LLMToolCall is a function call requested by the model; Arguments is raw JSON.
+type LLMToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+type LLMRequest struct {
+	Messages []LLMMessage
+	Tools    []LLMTool
+	// MaxTokens overrides the configured completion limit when > 0.
+	MaxTokens int
+}
+
+// LLMResponse is a finished completion: either content, or tool calls the caller
+// must answer before asking again. Token counts are 0 when the backend does not
+// report usage.
+type LLMResponse struct {
+	Content          string
+	ToolCalls        []LLMToolCall
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// LLMProvider is a chat-completion backend.
+type LLMProvider interface {
+	Name() string
+	Complete(ctx context.Context, req LLMRequest) (*LLMResponse, error)
+	// Stream calls onDelta for each content fragment as it arrives and returns the
+	// full reply. If onDelta returns ErrLLMStreamStopped the stream is closed and the
+	// partial reply returned.
+	Stream(ctx context.Context, req LLMRequest, onDelta func(string) error) (*LLMResponse, error)
+}
+
+// LLMProviderConfig configures the HTTP providers.
+type LLMProviderConfig struct {
+	BaseURL       string
+	APIKey        string
+	Model         string
+	Temperature   float64
+	MaxTokens     int
+	Timeout       time.Duration
+	StreamTimeout time.Duration
+}
+
+// NewLLMProviderFromConfig builds the provider selected by openai.provider. It
+// returns ErrAINotConfigured when openai has no API key or openai_compatible
+// has no base URL; in development that case falls back to the fake provider
+// with the dev notice. Self-hosted compatible servers may run without a key.
+func NewLLMProviderFromConfig(cfg config.Config) (LLMProvider, error) {
+	oai := cfg.OpenAI
+	pc := LLMProviderConfig{
+		BaseURL:       oai.BaseURL,
+		APIKey:        oai.APIKey,
+		Model:         oai.Model,
+		Temperature:   oai.Temperature,
+		MaxTokens:     oai.MaxTokens,
+		Timeout:       time.Duration(oai.TimeoutSeconds) * time.Second,
+		StreamTimeout: time.Duration(oai.StreamTimeoutSeconds) * time.Second,
+	}
+	switch oai.Provider {
+	case LLMProviderFake:
+		return NewFakeLLMProvider(), nil
+	case LLMProviderOpenAI:
+		if strings.TrimSpace(pc.APIKey) == "" {
+			return unconfiguredLLMProvider()
+		}
+		return NewOpenAIProvider(pc), nil
+	case LLMProviderOpenAICompatible:
+		if strings.TrimSpace(pc.BaseURL) == "" {
+			return unconfiguredLLMProvider()
+		}
+		return NewOpenAICompatibleProvider(pc), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown provider %q", ErrAINotConfigured, oai.Provider)
+	}
+}
+
+func unconfiguredLLMProvider() (LLMProvider, error) {
+	if config.IsDevelopment() {
+		return NewFakeLLMProvider(aiDevMockMsg), nil
+	}
+	return nil, ErrAINotConfigured
+}
+
+// openAICompatibleProvider speaks the OpenAI Chat Completions API, which OpenAI
+// itself and most hosted/self-hosted gateways implement.
+type openAICompatibleProvider struct {
+	name         string
+	cfg          LLMProviderConfig
+	client       *http.Client
+	streamClient *http.Client
+}
+
+// NewOpenAIProvider talks to api.openai.com; cfg.BaseURL is ignored.
+func NewOpenAIProvider(cfg LLMProviderConfig) LLMProvider {
+	cfg.BaseURL = openAIBaseURL
+	return newOpenAICompatibleProvider(LLMProviderOpenAI, cfg)
+}
+
+// NewOpenAICompatibleProvider talks to any Chat Completions server at cfg.BaseURL
+// (GapGPT, vLLM, llama.cpp server, …).
+func NewOpenAICompatibleProvider(cfg LLMProviderConfig) LLMProvider {
+	return newOpenAICompatibleProvider(LLMProviderOpenAICompatible, cfg)
+}
+
+func newOpenAICompatibleProvider(name string, cfg LLMProviderConfig) *openAICompatibleProvider {
+	cfg.BaseURL = strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = 500
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 45 * time.Second
+	}
+	if cfg.StreamTimeout <= 0 {
+		cfg.StreamTimeout = 2 * time.Minute
+	}
+	return &openAICompatibleProvider{
+		name:   name,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		// A stream is bounded by a context deadline instead: Client.Timeout would
+		// also cut off a slow but healthy body.
+		streamClient: &http.Client{},
+	}
+}
+
+func (p *openAICompatibleProvider) Name() string { return p.name }
+
+type openAIChatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Tools       []openAITool    `json:"tools,omitempty"`
+	Temperature float64         `json:"temperature"`
+	MaxTokens   int             `json:"max_tokens"`
+	Stream      bool            `json:"stream,omitempty"`
+	// StreamOptions asks for a final usage chunk so streamed turns are accounted
+	// with real token counts.
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+type openAIToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+func toOpenAIMessages(msgs []LLMMessage) []openAIMessage {
+	out := make([]openAIMessage, 0, len(msgs))
+	for _, m := range msgs {
+		om := openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for i, tc := range m.ToolCalls {
+			call := openAIToolCall{Index: i, ID: tc.ID, Type: "function"}
+			call.Function.Name, call.Function.Arguments = tc.Name, tc.Arguments
+			om.ToolCalls = append(om.ToolCalls, call)
+		}
+		out = append(out, om)
+	}
+	return out
+}
+
+func toOpenAITools(tools []LLMTool) []openAITool {
+	out := make([]openAITool, 0, len(tools))
+	for _, t := range tools {
+		ot := openAITool{Type: "function"}
+		ot.Function.Name, ot.Function.Description, ot.Function.Parameters = t.Name, t.Description, t.Parameters
+		out = append(out, ot)
+	}
+	return out
+}
+
+func fromOpenAIToolCalls(calls []openAIToolCall) []LLMToolCall {
+	out := make([]LLMToolCall, 0, len(calls))
+	for _, c := range calls {
+		out = append(out, LLMToolCall{ID: c.ID, Name: c.Function.Name, Arguments: c.Function.Arguments})
+	}
+	return out
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *openAICompatibleProvider) newRequest(ctx context.Context, req LLMRequest, stream bool) (*http.Request, error) {
+	payload := openAIChatRequest{
+		Model:       p.cfg.Model,
+		Messages:    toOpenAIMessages(req.Messages),
+		Tools:       toOpenAITools(req.Tools),
+		Temperature: p.cfg.Temperature,
+		MaxTokens:   p.cfg.MaxTokens,
+		Stream:      stream,
+	}
+	if req.MaxTokens > 0 {
+		payload.MaxTokens = req.MaxTokens
+	}
+	if stream {
+		payload.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	return httpReq, nil
+}
+
+func (p *openAICompatibleProvider) Complete(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	httpReq, err := p.newRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAIUpstream, err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var parsed openAIChatResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("%w: invalid response", ErrAIUpstream)
+	}
+	if resp.StatusCode >= 300 {
+		msg := "upstream error"
+		if parsed.Error != nil && parsed.Error.Message != "" {
+			msg = parsed.Error.Message
+		}
+		return nil, fmt.Errorf("%w: %s", ErrAIUpstream, msg)
+	}
+	if len(parsed.Choices) == 0 {
+		return nil, fmt.Errorf("%w: empty choices", ErrAIUpstream)
+	}
+	msg := parsed.Choices[0].Message
+	out := &LLMResponse{Content: msg.Content}
+	if len(msg.ToolCalls) > 0 {
+		out.ToolCalls = fromOpenAIToolCalls(msg.ToolCalls)
+	}
+	if parsed.Usage != nil {
+		out.PromptTokens, out.CompletionTokens = parsed.Usage.PromptTokens, parsed.Usage.CompletionTokens
+	}
+	return out, nil
+}
+
+func (p *openAICompatibleProvider) Stream(ctx context.Context, req LLMRequest, onDelta func(string) error) (*LLMResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.StreamTimeout)
+	defer cancel()
+
+	httpReq, err := p.newRequest(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.streamClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAIUpstream, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		var parsed openAIChatResponse
+		msg := "upstream error"
+		if json.Unmarshal(raw, &parsed) == nil && parsed.Error != nil && parsed.Error.Message != "" {
+			msg = parsed.Error.Message
+		}
+		return nil, fmt.Errorf("%w: %s", ErrAIUpstream, msg)
+	}
+
+	out := &LLMResponse{}
+	var content strings.Builder
+	// Tool calls arrive as fragments keyed by index: id and name first, then
+	// the arguments JSON in pieces.
+	var calls []openAIToolCall
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("%w: invalid stream chunk", ErrAIUpstream)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("%w: %s", ErrAIUpstream, chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			out.PromptTokens, out.CompletionTokens = chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		for _, frag := range chunk.Choices[0].Delta.ToolCalls {
+			for len(calls) <= frag.Index {
+				calls = append(calls, openAIToolCall{Index: len(calls)})
+			}
+			c := &calls[frag.Index]
+			if frag.ID != "" {
+				c.ID = frag.ID
+			}
+			if frag.Function.Name != "" {
+				c.Function.Name = frag.Function.Name
+			}
+			c.Function.Arguments += frag.Function.Arguments
+		}
+		delta := chunk.Choices[0].Delta.Content
+		if delta == "" {
+			continue
+		}
+		content.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			if errors.Is(err, ErrLLMStreamStopped) {
+				break
+			}
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil && content.Len() == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrAIUpstream, err)
+	}
+	out.Content = content.String()
+	if len(calls) > 0 {
+		out.ToolCalls = fromOpenAIToolCalls(calls)
+	}
+	return out, nil
+}
+
+// FakeLLMProvider is a deterministic provider for tests and local development. It
+// returns its replies in order (repeating the last one) and records every request.
+// With no replies configured it echoes the last user message. Tool-call rounds
+// queued with QueueToolCalls are answered first, while the request offers tools.
+type FakeLLMProvider struct {
+	mu        sync.Mutex
+	replies   []string
+	calls     int
+	toolCalls [][]LLMToolCall
+	Requests  []LLMRequest
+}
+
+func NewFakeLLMProvider(replies ...string) *FakeLLMProvider {
+	return &FakeLLMProvider{replies: replies}
+}
+
+func (p *FakeLLMProvider) Name() string { return LLMProviderFake }
+
+// QueueToolCalls makes the next request that offers tools answer with calls.
+func (p *FakeLLMProvider) QueueToolCalls(calls ...LLMToolCall) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.toolCalls = append(p.toolCalls, calls)
+}
+
+func (p *FakeLLMProvider) nextToolCalls(req LLMRequest) []LLMToolCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(req.Tools) == 0 || len(p.toolCalls) == 0 {
+		return nil
+	}
+	p.Requests = append(p.Requests, req)
+	calls := p.toolCalls[0]
+	p.toolCalls = p.toolCalls[1:]
+	return calls
+}
+
+func (p *FakeLLMProvider) next(req LLMRequest) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Requests = append(p.Requests, req)
+	p.calls++
+	if len(p.replies) == 0 {
+		for i := len(req.Messages) - 1; i >= 0; i-- {
+			if req.Messages[i].Role == "user" {
+				return "پاسخ آزمایشی: " + req.Messages[i].Content
+			}
+		}
+		return "پاسخ آزمایشی"
+	}
+	return p.replies[min(p.calls, len(p.replies))-1]
+}
+
+func (p *FakeLLMProvider) Complete(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	if calls := p.nextToolCalls(req); calls != nil {
+		return &LLMResponse{ToolCalls: calls}, nil
+	}
+	reply := p.next(req)
+	return &LLMResponse{Content: reply, CompletionTokens: estimateAITokens(reply)}, nil
+}
+
+// Stream emits the reply word by word.
+func (p *FakeLLMProvider) Stream(ctx context.Context, req LLMRequest, onDelta func(string) error) (*LLMResponse, error) {
+	if calls := p.nextToolCalls(req); calls != nil {
+		return &LLMResponse{ToolCalls: calls}, nil
+	}
+	reply := p.next(req)
+	var sent strings.Builder
+	for _, word := range strings.SplitAfter(reply, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if word == "" {
+			continue
+		}
+		sent.WriteString(word)
+		if err := onDelta(word); err != nil {
+			if errors.Is(err, ErrLLMStreamStopped) {
+				break
+			}
+			return nil, err
+		}
+	}
+	return &LLMResponse{Content: sent.String(), CompletionTokens: estimateAITokens(sent.String())}, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yourusername/fitness-management/config"
+)
+
+func TestOpenAICompatibleProviderStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body openAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !body.Stream || body.Model != "local-model" {
+			t.Errorf("unexpected request %+v (%v)", body, err)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("no key configured, got Authorization %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, part := range []string{"سلام", " دوست", " من"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", part)
+		}
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := NewOpenAICompatibleProvider(LLMProviderConfig{BaseURL: srv.URL + "/", Model: "local-model"})
+	var deltas []string
+	resp, err := p.Stream(context.Background(), LLMRequest{Messages: []LLMMessage{{Role: "user", Content: "hi"}}}, func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "سلام دوست من" || len(deltas) != 3 || resp.PromptTokens != 12 || resp.CompletionTokens != 3 {
+		t.Fatalf("unexpected stream result %+v deltas=%q", resp, deltas)
+	}
+}
+
+func TestNewLLMProviderFromConfigKeylessCompatible(t *testing.T) {
+	var cfg config.Config
+	cfg.OpenAI.Provider = LLMProviderOpenAICompatible
+	cfg.OpenAI.BaseURL = "http://127.0.0.1:8081/v1"
+	p, err := NewLLMProviderFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != LLMProviderOpenAICompatible {
+		t.Fatalf("keyless openai_compatible got provider %q", p.Name())
+	}
+}
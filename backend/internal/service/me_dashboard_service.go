@@ -96,26 +96,13 @@ func (s *meDashboardService) Summary(ctx context.Context, userID uint, days int)
 	// Progress series: completed sessions per day, zero-filled.
 	startDay := now.AddDate(0, 0, -(days - 1))
 	start := time.Date(startDay.Year(), startDay.Month(), startDay.Day(), 0, 0, 0, 0, time.Local)
-	type dayRow struct {
-		Day string
-		Cnt int
-	}
-	var rows []dayRow
+	var completed []time.Time
 	if err := s.db.WithContext(ctx).Model(&models.WorkoutSession{}).
-		Select("DATE(completed_at) AS day, COUNT(*) AS cnt").
 		Where("user_id = ? AND completed_at >= ?", uid, start).
-		Group("DATE(completed_at)").
-		Scan(&rows).Error; err != nil {
+		Pluck("completed_at", &completed).Error; err != nil {
 		return nil, err
 	}
-	counts := make(map[string]int, len(rows))
-	for _, r := range rows {
-		key := r.Day
-		if len(key) > 10 {
-			key = key[:10]
-		}
-		counts[key] = r.Cnt
-	}
+	counts := countPerDay(completed)
 	series := make([]ProgressPoint, 0, days)
 	for i := 0; i < days; i++ {
 		key := start.AddDate(0, 0, i).Format("2006-01-02")
@@ -255,6 +242,16 @@ func epley(weight float64, reps int) float64 {
 	return weight * (1 + float64(reps)/30.0)
 }
 
+// countPerDay buckets timestamps by local calendar day (YYYY-MM-DD). Done in
+// Go because SQL date functions and their time zone handling differ by driver.
+func countPerDay(times []time.Time) map[string]int {
+	out := make(map[string]int, len(times))
+	for _, t := range times {
+		out[t.In(time.Local).Format("2006-01-02")]++
+	}
+	return out
+}
+
 func startOfISOWeek(t time.Time) time.Time {
 	// ISO week starts Monday.
 	wd := int(t.Weekday())
@@ -0,0 +1,680 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/pkg/digits"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+var (
+	ErrCalculatorMissingInput     = errors.New("not enough body data for the selected formula")
+	ErrCalculatorInvalidFormula   = errors.New("invalid formula")
+	ErrCalculatorInvalidActivity  = errors.New("invalid activity level")
+	ErrCalculatorInvalidStrategy  = errors.New("invalid macro strategy")
+	ErrCalculatorInvalidSplit     = errors.New("custom macro split must add up to 100")
+	ErrCalculatorInvalidTarget    = errors.New("target calories must be between 800 and 6000")
+	ErrCalculatorTemplateNoEnergy = errors.New("template has no calorie value to scale from")
+)
+
+const (
+	CalculatorFormulaMifflin = "mifflin"
+	CalculatorFormulaKatch   = "katch"
+
+	MacroStrategyBalanced    = "balanced"
+	MacroStrategyHighProtein = "high_protein"
+	MacroStrategyLowCarb     = "low_carb"
+	MacroStrategyKeto        = "keto"
+	MacroStrategyCustom      = "custom"
+
+	defaultCalorieTolerance  = 200
+	defaultCalorieMatchLimit = 10
+	minTargetCalories        = 800
+	maxTargetCalories        = 6000
+	// Template portions are rounded to quarter servings so scaled plans stay practical.
+	templateMultiplierStep = 0.25
+)
+
+// activityFactors are the usual PAL multipliers; the funnel only collects
+// sedentary | moderate | active, the other two can be picked by the coach.
+var activityFactors = map[string]float64{
+	"sedentary":   1.2,
+	"light":       1.375,
+	"moderate":    1.55,
+	"active":      1.725,
+	"very_active": 1.9,
+}
+
+// goalAdjustmentPercent is the default surplus/deficit applied to TDEE per goal.
+var goalAdjustmentPercent = map[string]float64{
+	"weight_loss": -20,
+	"muscle_gain": 10,
+	"fitness":     0,
+}
+
+// MacroSplit is a percentage-of-calories split used by the custom strategy.
+type MacroSplit struct {
+	ProteinPercent float64 `json:"proteinPercent"`
+	FatPercent     float64 `json:"fatPercent"`
+	CarbsPercent   float64 `json:"carbsPercent"`
+}
+
+// NutritionCalculatorRequest holds explicit inputs; when a student is given, any
+// field left empty is filled from the student's profile and latest funnel answers.
+type NutritionCalculatorRequest struct {
+	Formula           string      `json:"formula"` // mifflin | katch | "" (katch when body fat is known)
+	Gender            string      `json:"gender"`
+	Age               int         `json:"age"`
+	HeightCm          *float64    `json:"heightCm"`
+	WeightKg          *float64    `json:"weightKg"`
+	BodyFatPercent    *float64    `json:"bodyFatPercent"`
+	ActivityLevel     string      `json:"activityLevel"`
+	Goal              string      `json:"goal"`
+	AdjustmentPercent *float64    `json:"adjustmentPercent"`
+	AdjustmentKcal    *int        `json:"adjustmentKcal"`
+	Strategy          string      `json:"strategy"`
+	CustomSplit       *MacroSplit `json:"customSplit"`
+	TemplateTolerance int         `json:"templateTolerance"`
+}
+
+type NutritionCalculatorInputs struct {
+	Gender         string   `json:"gender,omitempty"`
+	Age            int      `json:"age,omitempty"`
+	HeightCm       *float64 `json:"heightCm,omitempty"`
+	WeightKg       *float64 `json:"weightKg,omitempty"`
+	BodyFatPercent *float64 `json:"bodyFatPercent,omitempty"`
+	ActivityLevel  string   `json:"activityLevel"`
+	Goal           string   `json:"goal"`
+}
+
+type MacroTargets struct {
+	Strategy      string  `json:"strategy"`
+	ProteinGrams  int     `json:"proteinGrams"`
+	FatGrams      int     `json:"fatGrams"`
+	CarbsGrams    int     `json:"carbsGrams"`
+	ProteinPerKg  float64 `json:"proteinPerKg,omitempty"`
+	ProteinKcal   int     `json:"proteinKcal"`
+	FatKcal       int     `json:"fatKcal"`
+	CarbsKcal     int     `json:"carbsKcal"`
+	ProteinTarget string  `json:"proteinTarget"`
+}
+
+type NutritionCalculatorResult struct {
+	StudentID      uint                      `json:"studentId,omitempty"`
+	Formula        string                    `json:"formula"`
+	Inputs         NutritionCalculatorInputs `json:"inputs"`
+	BMR            int                       `json:"bmr"`
+	ActivityFactor float64                   `json:"activityFactor"`
+	TDEE           int                       `json:"tdee"`
+	AdjustmentKcal int                       `json:"adjustmentKcal"`
+	TargetCalories int                       `json:"targetCalories"`
+	Macros         MacroTargets              `json:"macros"`
+	Warnings       []string                  `json:"warnings"`
+	Templates      []NutritionTemplateMatch  `json:"templates"`
+}
+
+// NutritionTemplateMatch is a nutrition template ranked by distance from a calorie target.
+type NutritionTemplateMatch struct {
+	Template    NutritionTemplateSummary `json:"template"`
+	CalorieDiff int                      `json:"calorieDiff"`
+	ScaleFactor float64                  `json:"scaleFactor"`
+}
+
+type ScaleNutritionTemplateRequest struct {
+	TargetCalories int    `json:"targetCalories"`
+	Title          string `json:"title"`
+	Notes          string `json:"notes"`
+	DurationWeeks  int    `json:"durationWeeks"`
+}
+
+// ScaledNutritionTemplate is a template's plan with every portion multiplied toward a target.
+type ScaledNutritionTemplate struct {
+	TemplateID     uint                    `json:"templateId"`
+	Title          string                  `json:"title"`
+	BaseCalories   int                     `json:"baseCalories"`
+	TargetCalories int                     `json:"targetCalories"`
+	ScaleFactor    float64                 `json:"scaleFactor"`
+	ScaledCalories int                     `json:"scaledCalories"`
+	Warnings       []string                `json:"warnings"`
+	PlanByDay      map[string]MeDayPlanDTO `json:"planByDay"`
+}
+
+// NutritionCalculatorService computes energy and macro targets and connects them
+// to nutrition templates (calorie-proximity listing and portion scaling).
+type NutritionCalculatorService interface {
+	Calculate(ctx context.Context, coachID uint, req *NutritionCalculatorRequest) (*NutritionCalculatorResult, error)
+	CalculateForStudent(ctx context.Context, coachID, studentID uint, req *NutritionCalculatorRequest) (*NutritionCalculatorResult, error)
+	// MatchTemplates lists templates within tolerance of targetCalories, closest first.
+	MatchTemplates(ctx context.Context, coachID uint, targetCalories, tolerance, limit int) ([]NutritionTemplateMatch, error)
+	ScaleTemplate(ctx context.Context, coachID, templateID uint, targetCalories int) (*ScaledNutritionTemplate, error)
+	// AssignScaledTemplate scales a template to the request target (or the student's
+	// computed target when omitted) and assigns it as the student's nutrition program.
+	AssignScaledTemplate(ctx context.Context, coachID, studentID, templateID uint, req *ScaleNutritionTemplateRequest) (*CoachStudentProgramsResponse, error)
+}
+
+type nutritionCalculatorService struct {
+	templateRepo    repository.TemplateRepository
+	foodRepo        repository.FoodRepository
+	userRepo        repository.UserRepository
+	funnelRepo      repository.FunnelLeadRepository
+	coachStudentSvc CoachStudentService
+	programSvc      CoachProgramService
+}
+
+func NewNutritionCalculatorService(
+	templateRepo repository.TemplateRepository,
+	foodRepo repository.FoodRepository,
+	userRepo repository.UserRepository,
+	funnelRepo repository.FunnelLeadRepository,
+	coachStudentSvc CoachStudentService,
+	programSvc CoachProgramService,
+) NutritionCalculatorService {
+	return &nutritionCalculatorService{
+		templateRepo:    templateRepo,
+		foodRepo:        foodRepo,
+		userRepo:        userRepo,
+		funnelRepo:      funnelRepo,
+		coachStudentSvc: coachStudentSvc,
+		programSvc:      programSvc,
+	}
+}
+
+func (s *nutritionCalculatorService) Calculate(ctx context.Context, coachID uint, req *NutritionCalculatorRequest) (*NutritionCalculatorResult, error) {
+	if req == nil {
+		req = &NutritionCalculatorRequest{}
+	}
+	result, err := CalculateNutritionTargets(req)
+	if err != nil {
+		return nil, err
+	}
+	result.Templates, err = s.MatchTemplates(ctx, coachID, result.TargetCalories, req.TemplateTolerance, 5)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *nutritionCalculatorService) CalculateForStudent(ctx context.Context, coachID, studentID uint, req *NutritionCalculatorRequest) (*NutritionCalculatorResult, error) {
+	merged, err := s.studentRequest(ctx, coachID, studentID, req)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.Calculate(ctx, coachID, merged)
+	if err != nil {
+		return nil, err
+	}
+	result.StudentID = studentID
+	return result, nil
+}
+
+// studentRequest fills the empty fields of req from the student's profile and funnel lead.
+func (s *nutritionCalculatorService) studentRequest(ctx context.Context, coachID, studentID uint, req *NutritionCalculatorRequest) (*NutritionCalculatorRequest, error) {
+	ok, err := s.coachStudentSvc.CanAccessStudent(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCoachStudentForbidden
+	}
+	user, err := s.userRepo.FindByID(ctx, studentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachStudentForbidden
+		}
+		return nil, err
+	}
+	var lead *models.FunnelLead
+	if s.funnelRepo != nil && strings.TrimSpace(user.Phone) != "" {
+		if l, err := s.funnelRepo.FindLatestByPhone(ctx, user.Phone); err == nil {
+			lead = l
+		}
+	}
+
+	merged := NutritionCalculatorRequest{}
+	if req != nil {
+		merged = *req
+	}
+	if merged.Gender == "" {
+		merged.Gender = normalizeSuggestionGender(user.Gender)
+	}
+	if merged.Age <= 0 && user.BirthDate != nil {
+		merged.Age = ageOn(*user.BirthDate, time.Now())
+	}
+	if merged.HeightCm == nil {
+		merged.HeightCm = user.HeightCm
+	}
+	if merged.WeightKg == nil {
+		merged.WeightKg = user.WeightKg
+	}
+	if merged.BodyFatPercent == nil {
+		merged.BodyFatPercent = user.BodyFatPercent
+	}
+	if merged.Goal == "" {
+		merged.Goal = normalizeSuggestionGoal(user.PrimaryGoal)
+		if merged.Goal == "" {
+			for _, g := range user.GetGoals() {
+				if merged.Goal = normalizeSuggestionGoal(g); merged.Goal != "" {
+					break
+				}
+			}
+		}
+	}
+	if lead != nil {
+		if merged.ActivityLevel == "" {
+			merged.ActivityLevel = strings.TrimSpace(lead.ActivityLevel)
+		}
+		if merged.Goal == "" {
+			merged.Goal = normalizeSuggestionGoal(lead.PrimaryGoal)
+		}
+	}
+	return &merged, nil
+}
+
+func (s *nutritionCalculatorService) MatchTemplates(ctx context.Context, coachID uint, targetCalories, tolerance, limit int) ([]NutritionTemplateMatch, error) {
+	if targetCalories < minTargetCalories || targetCalories > maxTargetCalories {
+		return nil, ErrCalculatorInvalidTarget
+	}
+	if tolerance <= 0 {
+		tolerance = defaultCalorieTolerance
+	}
+	if limit <= 0 {
+		limit = defaultCalorieMatchLimit
+	}
+	filter := coachTemplateFilter(coachID, "", "all")
+	filter.CalorieMin = max(targetCalories-tolerance, 1)
+	filter.CalorieMax = targetCalories + tolerance
+	templates, err := s.templateRepo.ListNutritionTemplates(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(templates, func(i, j int) bool {
+		return absInt(templates[i].Calorie-targetCalories) < absInt(templates[j].Calorie-targetCalories)
+	})
+	if len(templates) > limit {
+		templates = templates[:limit]
+	}
+	out := make([]NutritionTemplateMatch, 0, len(templates))
+	for i := range templates {
+		t := &templates[i]
+		out = append(out, NutritionTemplateMatch{
+			Template: NutritionTemplateSummary{
+				ID: t.ID, CoachID: t.CoachID, IsCustom: t.CoachID != nil,
+				Title: t.Title, Type: t.Type, Gender: t.Gender,
+				Target: t.Target, Limitation: t.Limitation, Calorie: t.Calorie,
+			},
+			CalorieDiff: t.Calorie - targetCalories,
+			ScaleFactor: roundTo(float64(targetCalories)/float64(t.Calorie), 0.01),
+		})
+	}
+	return out, nil
+}
+
+func (s *nutritionCalculatorService) ScaleTemplate(ctx context.Context, coachID, templateID uint, targetCalories int) (*ScaledNutritionTemplate, error) {
+	if targetCalories < minTargetCalories || targetCalories > maxTargetCalories {
+		return nil, ErrCalculatorInvalidTarget
+	}
+	t, err := s.templateRepo.FindNutritionTemplateByID(ctx, templateID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachTemplateNotFound
+		}
+		return nil, err
+	}
+	if !coachCanAccessTemplate(coachID, t.CoachID) {
+		return nil, ErrCoachTemplateForbidden
+	}
+
+	planByDay := enrichNutritionPlan(ctx, s.foodRepo, nutritionTemplateToPlanByDay(t))
+	base := t.Calorie
+	if base <= 0 {
+		base = int(math.Round(firstDayCalories(planByDay)))
+	}
+	if base <= 0 {
+		return nil, ErrCalculatorTemplateNoEnergy
+	}
+
+	factor := float64(targetCalories) / float64(base)
+	var warnings []string
+	if factor < 0.5 || factor > 2 {
+		warnings = append(warnings, fmt.Sprintf("ضریب مقیاس %.2f است؛ بهتر است قالبی با کالری نزدیک‌تر انتخاب شود", factor))
+	}
+	scaled := scaleNutritionPlan(planByDay, factor, targetCalories)
+	scaled = enrichNutritionPlan(ctx, s.foodRepo, scaled)
+
+	return &ScaledNutritionTemplate{
+		TemplateID:     t.ID,
+		Title:          t.Title,
+		BaseCalories:   base,
+		TargetCalories: targetCalories,
+		ScaleFactor:    roundTo(factor, 0.01),
+		ScaledCalories: int(math.Round(firstDayCalories(scaled))),
+		Warnings:       warnings,
+		PlanByDay:      scaled,
+	}, nil
+}
+
+func (s *nutritionCalculatorService) AssignScaledTemplate(ctx context.Context, coachID, studentID, templateID uint, req *ScaleNutritionTemplateRequest) (*CoachStudentProgramsResponse, error) {
+	if req == nil {
+		req = &ScaleNutritionTemplateRequest{}
+	}
+	target := req.TargetCalories
+	proteinTarget := ""
+	if target <= 0 {
+		calc, err := s.CalculateForStudent(ctx, coachID, studentID, nil)
+		if err != nil {
+			return nil, err
+		}
+		target = calc.TargetCalories
+		proteinTarget = calc.Macros.ProteinTarget
+	}
+	scaled, err := s.ScaleTemplate(ctx, coachID, templateID, target)
+	if err != nil {
+		return nil, err
+	}
+	if proteinTarget != "" {
+		for key, day := range scaled.PlanByDay {
+			if day.Nutrition != nil {
+				day.Nutrition.ProteinTarget = proteinTarget
+				scaled.PlanByDay[key] = day
+			}
+		}
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = strings.TrimSpace(scaled.Title)
+	}
+	return s.programSvc.AssignNutritionProgram(ctx, coachID, studentID, &ProgramAssignRequest{
+		Title:         title,
+		DurationWeeks: req.DurationWeeks,
+		Notes:         strings.TrimSpace(req.Notes),
+		PlanByDay:     scaled.PlanByDay,
+	})
+}
+
+// CalculateNutritionTargets runs the BMR → TDEE → goal adjustment → macro pipeline
+// on explicit inputs. It does not touch the database.
+func CalculateNutritionTargets(req *NutritionCalculatorRequest) (*NutritionCalculatorResult, error) {
+	inputs := NutritionCalculatorInputs{
+		Gender:         normalizeSuggestionGender(req.Gender),
+		Age:            req.Age,
+		HeightCm:       positiveOrNil(req.HeightCm),
+		WeightKg:       positiveOrNil(req.WeightKg),
+		BodyFatPercent: positiveOrNil(req.BodyFatPercent),
+		ActivityLevel:  strings.ToLower(strings.TrimSpace(req.ActivityLevel)),
+		Goal:           normalizeSuggestionGoal(req.Goal),
+	}
+	var warnings []string
+	if inputs.ActivityLevel == "" {
+		inputs.ActivityLevel = "moderate"
+		warnings = append(warnings, "سطح فعالیت مشخص نبود؛ «متوسط» در نظر گرفته شد")
+	}
+	factor, ok := activityFactors[inputs.ActivityLevel]
+	if !ok {
+		return nil, ErrCalculatorInvalidActivity
+	}
+	if inputs.Goal == "" {
+		inputs.Goal = "fitness"
+	}
+
+	formula := strings.ToLower(strings.TrimSpace(req.Formula))
+	if formula == "" {
+		formula = CalculatorFormulaMifflin
+		if inputs.BodyFatPercent != nil {
+			formula = CalculatorFormulaKatch
+		}
+	}
+	var bmr float64
+	switch formula {
+	case CalculatorFormulaMifflin:
+		if inputs.WeightKg == nil || inputs.HeightCm == nil || inputs.Age <= 0 || inputs.Gender == "" {
+			return nil, fmt.Errorf("%w: mifflin needs weight, height, age and gender", ErrCalculatorMissingInput)
+		}
+		bmr = MifflinStJeorBMR(*inputs.WeightKg, *inputs.HeightCm, inputs.Age, inputs.Gender)
+	case CalculatorFormulaKatch:
+		if inputs.WeightKg == nil || inputs.BodyFatPercent == nil || *inputs.BodyFatPercent >= 70 {
+			return nil, fmt.Errorf("%w: katch needs weight and body fat percent", ErrCalculatorMissingInput)
+		}
+		bmr = KatchMcArdleBMR(*inputs.WeightKg, *inputs.BodyFatPercent)
+	default:
+		return nil, ErrCalculatorInvalidFormula
+	}
+
+	tdee := bmr * factor
+	var adjustment float64
+	switch {
+	case req.AdjustmentKcal != nil:
+		adjustment = float64(*req.AdjustmentKcal)
+	case req.AdjustmentPercent != nil:
+		adjustment = tdee * *req.AdjustmentPercent / 100
+	default:
+		adjustment = tdee * goalAdjustmentPercent[inputs.Goal] / 100
+	}
+	target := tdee + adjustment
+	if floor := minimumCalories(inputs.Gender); target < floor {
+		warnings = append(warnings, fmt.Sprintf("کالری هدف به حداقل ایمن %.0f افزایش یافت", floor))
+		target = floor
+	}
+	target = math.Round(target/10) * 10
+
+	macros, err := macroTargets(req.Strategy, req.CustomSplit, inputs.Goal, target, leanReferenceWeight(inputs))
+	if err != nil {
+		return nil, err
+	}
+
+	return &NutritionCalculatorResult{
+		Formula:        formula,
+		Inputs:         inputs,
+		BMR:            int(math.Round(bmr)),
+		ActivityFactor: factor,
+		TDEE:           int(math.Round(tdee)),
+		AdjustmentKcal: int(math.Round(adjustment)),
+		TargetCalories: int(target),
+		Macros:         macros,
+		Warnings:       warnings,
+		Templates:      []NutritionTemplateMatch{},
+	}, nil
+}
+
+// MifflinStJeorBMR returns resting energy in kcal/day.
+func MifflinStJeorBMR(weightKg, heightCm float64, age int, gender string) float64 {
+	bmr := 10*weightKg + 6.25*heightCm - 5*float64(age)
+	if gender == "female" {
+		return bmr - 161
+	}
+	return bmr + 5
+}
+
+// KatchMcArdleBMR returns resting energy in kcal/day from lean body mass.
+func KatchMcArdleBMR(weightKg, bodyFatPercent float64) float64 {
+	lean := weightKg * (1 - bodyFatPercent/100)
+	return 370 + 21.6*lean
+}
+
+func macroTargets(strategy string, split *MacroSplit, goal string, calories, refWeight float64) (MacroTargets, error) {
+	strategy = strings.ToLower(strings.TrimSpace(strategy))
+	if strategy == "" {
+		strategy = MacroStrategyBalanced
+		if goal == "weight_loss" {
+			strategy = MacroStrategyHighProtein
+		}
+	}
+
+	var proteinKcal, fatKcal, carbsKcal float64
+	proteinPerKg := 0.0
+	switch strategy {
+	case MacroStrategyBalanced, MacroStrategyHighProtein, MacroStrategyLowCarb, MacroStrategyKeto:
+		if refWeight <= 0 {
+			return MacroTargets{}, fmt.Errorf("%w: weight is required for %s", ErrCalculatorMissingInput, strategy)
+		}
+		proteinPerKg = map[string]float64{
+			MacroStrategyBalanced:    1.8,
+			MacroStrategyHighProtein: 2.2,
+			MacroStrategyLowCarb:     2.0,
+			MacroStrategyKeto:        1.8,
+		}[strategy]
+		// Protein never takes more than 40% of calories, whatever the body weight.
+		proteinKcal = math.Min(proteinPerKg*refWeight*4, calories*0.4)
+		switch strategy {
+		case MacroStrategyBalanced:
+			fatKcal = calories * 0.30
+			carbsKcal = calories - proteinKcal - fatKcal
+		case MacroStrategyHighProtein:
+			fatKcal = calories * 0.25
+			carbsKcal = calories - proteinKcal - fatKcal
+		case MacroStrategyLowCarb:
+			carbsKcal = calories * 0.20
+			fatKcal = calories - proteinKcal - carbsKcal
+		case MacroStrategyKeto:
+			carbsKcal = math.Min(calories*0.05, 50*4)
+			fatKcal = calories - proteinKcal - carbsKcal
+		}
+	case MacroStrategyCustom:
+		if split == nil || split.ProteinPercent < 0 || split.FatPercent < 0 || split.CarbsPercent < 0 ||
+			math.Abs(split.ProteinPercent+split.FatPercent+split.CarbsPercent-100) > 0.5 {
+			return MacroTargets{}, ErrCalculatorInvalidSplit
+		}
+		proteinKcal = calories * split.ProteinPercent / 100
+		fatKcal = calories * split.FatPercent / 100
+		carbsKcal = calories * split.CarbsPercent / 100
+	default:
+		return MacroTargets{}, ErrCalculatorInvalidStrategy
+	}
+
+	out := MacroTargets{
+		Strategy:     strategy,
+		ProteinGrams: int(math.Round(proteinKcal / 4)),
+		FatGrams:     int(math.Round(fatKcal / 9)),
+		CarbsGrams:   int(math.Round(math.Max(carbsKcal, 0) / 4)),
+		ProteinKcal:  int(math.Round(proteinKcal)),
+		FatKcal:      int(math.Round(fatKcal)),
+		CarbsKcal:    int(math.Round(math.Max(carbsKcal, 0))),
+	}
+	if refWeight > 0 {
+		out.ProteinPerKg = roundTo(float64(out.ProteinGrams)/refWeight, 0.1)
+	}
+	out.ProteinTarget = fmt.Sprintf("%d گرم", out.ProteinGrams)
+	return out, nil
+}
+
+// leanReferenceWeight is the weight protein is dosed against: total weight, or an
+// adjusted weight (lean mass at 15% fat) for clients with a known high body fat.
+func leanReferenceWeight(in NutritionCalculatorInputs) float64 {
+	if in.WeightKg == nil {
+		return 0
+	}
+	w := *in.WeightKg
+	if in.BodyFatPercent != nil && *in.BodyFatPercent > 25 {
+		return w * (1 - *in.BodyFatPercent/100) / 0.85
+	}
+	return w
+}
+
+func minimumCalories(gender string) float64 {
+	if gender == "female" {
+		return 1200
+	}
+	return 1500
+}
+
+// scaleNutritionPlan multiplies each portion by factor (rounded to a practical step)
+// and pins the day's calorie target; the protein target is scaled with the portions.
+// Food-linked meals get their macros rebuilt by enrichNutritionPlan afterwards;
+// free-text meals keep the proportionally scaled values.
+func scaleNutritionPlan(planByDay map[string]MeDayPlanDTO, factor float64, targetCalories int) map[string]MeDayPlanDTO {
+	out := make(map[string]MeDayPlanDTO, len(planByDay))
+	for key, day := range planByDay {
+		if day.Nutrition == nil {
+			out[key] = day
+			continue
+		}
+		meals := make([]MeMealDTO, 0, len(day.Nutrition.Meals))
+		for _, meal := range day.Nutrition.Meals {
+			base := mealMultiplier(meal.Multiplier)
+			next := math.Max(roundTo(base*factor, templateMultiplierStep), templateMultiplierStep)
+			ratio := next / base
+			meal.Multiplier = next
+			meal.Amount *= ratio
+			meal.Detail = formatTemplateFoodQuantity(roundTo(meal.Amount, 0.01), meal.Unit)
+			meal.Calories *= ratio
+			meal.Protein *= ratio
+			meal.Carbs *= ratio
+			meal.Fat *= ratio
+			meal.Fiber = scaleNullableFloat(meal.Fiber, ratio)
+			meal.Sugar = scaleNullableFloat(meal.Sugar, ratio)
+			meals = append(meals, meal)
+		}
+		out[key] = MeDayPlanDTO{
+			Workout: day.Workout,
+			Nutrition: &MeNutritionDTO{
+				CaloriesTarget: targetCalories,
+				ProteinTarget:  scaleProteinTarget(day.Nutrition.ProteinTarget, factor),
+				Meals:          meals,
+			},
+		}
+	}
+	return out
+}
+
+var proteinTargetGramsRe = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// scaleProteinTarget multiplies the gram amounts in a free-text protein target
+// such as "120 گرم" or "100-120g" by factor, rounded to whole grams.
+func scaleProteinTarget(target string, factor float64) string {
+	normalized := digits.ToEnglish(target)
+	if !proteinTargetGramsRe.MatchString(normalized) {
+		return target
+	}
+	return proteinTargetGramsRe.ReplaceAllStringFunc(normalized, func(n string) string {
+		grams, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return n
+		}
+		return strconv.Itoa(int(math.Round(grams * factor)))
+	})
+}
+
+func firstDayCalories(planByDay map[string]MeDayPlanDTO) float64 {
+	for _, key := range allDayKeys {
+		day, ok := planByDay[key]
+		if !ok || day.Nutrition == nil {
+			continue
+		}
+		total := 0.0
+		for _, m := range day.Nutrition.Meals {
+			total += m.Calories
+		}
+		return total
+	}
+	return 0
+}
+
+func ageOn(birth, now time.Time) int {
+	age := now.Year() - birth.Year()
+	if now.Month() < birth.Month() || (now.Month() == birth.Month() && now.Day() < birth.Day()) {
+		age--
+	}
+	return age
+}
+
+func positiveOrNil(v *float64) *float64 {
+	if v == nil || *v <= 0 {
+		return nil
+	}
+	return v
+}
+
+func roundTo(v, step float64) float64 {
+	return math.Round(v/step) / math.Round(1/step)
+}
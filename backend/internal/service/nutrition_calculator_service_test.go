@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestBMRFormulas(t *testing.T) {
+	// 80kg, 180cm, 30y male: 800 + 1125 - 150 + 5
+	if got := MifflinStJeorBMR(80, 180, 30, "male"); got != 1780 {
+		t.Fatalf("mifflin male=%v want 1780", got)
+	}
+	if got := MifflinStJeorBMR(60, 165, 25, "female"); math.Abs(got-1345.25) > 0.001 {
+		t.Fatalf("mifflin female=%v want 1345.25", got)
+	}
+	// 80kg at 20% fat → 64kg lean: 370 + 21.6*64
+	if got := KatchMcArdleBMR(80, 20); math.Abs(got-1752.4) > 0.001 {
+		t.Fatalf("katch=%v want 1752.4", got)
+	}
+}
+
+func TestCalculateNutritionTargets(t *testing.T) {
+	w, h := 80.0, 180.0
+	res, err := CalculateNutritionTargets(&NutritionCalculatorRequest{
+		Gender: "male", Age: 30, HeightCm: &h, WeightKg: &w,
+		ActivityLevel: "moderate", Goal: "weight_loss",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1780 * 1.55 = 2759 → -20% = 2207.2 → rounded to 2210
+	if res.Formula != CalculatorFormulaMifflin || res.TDEE != 2759 || res.TargetCalories != 2210 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if res.Macros.Strategy != MacroStrategyHighProtein || res.Macros.ProteinGrams != 176 {
+		t.Fatalf("unexpected macros %+v", res.Macros)
+	}
+	total := res.Macros.ProteinKcal + res.Macros.FatKcal + res.Macros.CarbsKcal
+	if absInt(total-res.TargetCalories) > 2 {
+		t.Fatalf("macro kcal %d should add up to %d", total, res.TargetCalories)
+	}
+
+	if _, err := CalculateNutritionTargets(&NutritionCalculatorRequest{Formula: "katch", WeightKg: &w}); !errors.Is(err, ErrCalculatorMissingInput) {
+		t.Fatalf("katch without body fat should fail, got %v", err)
+	}
+}
+
+func TestScaleNutritionPlanRoundsPortions(t *testing.T) {
+	plan := map[string]MeDayPlanDTO{
+		"saturday": {Nutrition: &MeNutritionDTO{CaloriesTarget: 2000, ProteinTarget: "۱۰۰-۱۲۰ گرم", Meals: []MeMealDTO{
+			{Title: "برنج", Multiplier: 2, Amount: 2, Unit: "کفگیر", Calories: 400},
+		}}},
+	}
+	out := scaleNutritionPlan(plan, 1.2, 2400)
+	meal := out["saturday"].Nutrition.Meals[0]
+	if meal.Multiplier != 2.5 || out["saturday"].Nutrition.CaloriesTarget != 2400 {
+		t.Fatalf("unexpected scaled meal %+v", meal)
+	}
+	if meal.Calories != 500 || meal.Detail != "2.5 کفگیر" {
+		t.Fatalf("free-text meal should scale proportionally, got %+v", meal)
+	}
+	if got := out["saturday"].Nutrition.ProteinTarget; got != "120-144 گرم" {
+		t.Fatalf("protein target should scale with the portions, got %q", got)
+	}
+	if plan["saturday"].Nutrition.Meals[0].Multiplier != 2 {
+		t.Fatal("source plan must not be mutated")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/url"
 	"strconv"
 	"strings"
@@ -23,6 +24,10 @@ var (
 	ErrPaymentGatewayFailed   = errors.New("payment gateway failed")
 )
 
+// orderStatusRefundPending marks a paid order that delivered nothing, e.g. a
+// second purchase of a marketplace listing; support refunds it by hand.
+const orderStatusRefundPending = "refund_pending"
+
 type ZarinpalPaymentResponse struct {
 	TransactionID uint   `json:"transaction_id"`
 	OrderID       uint   `json:"orderId"`
@@ -212,6 +217,14 @@ type preparedOrder struct {
 	CoachID      uint
 }
 
+// applyPercentDiscount takes percent (0-99) off amount; the discount rounds down.
+func applyPercentDiscount(amount int64, percent int) int64 {
+	if percent <= 0 || percent >= 100 {
+		return amount
+	}
+	return amount - amount*int64(percent)/100
+}
+
 func (s *paymentService) preparePendingOrder(ctx context.Context, userID uint, req *CheckoutRequest) (*preparedOrder, error) {
 	if req == nil || len(req.Items) == 0 {
 		return nil, ErrCheckoutEmptyCart
@@ -283,6 +296,7 @@ func (s *paymentService) preparePendingOrder(ctx context.Context, userID uint, r
 		if ln.plan.DiscountPriceCents > 0 {
 			unit = ln.plan.DiscountPriceCents
 		}
+		unit = applyPercentDiscount(unit, req.DiscountPercent)
 		lineTotal := unit
 		total += lineTotal
 		orderItems = append(orderItems, models.OrderItem{
@@ -307,6 +321,7 @@ func (s *paymentService) preparePendingOrder(ctx context.Context, userID uint, r
 			PaymentMethod:    "زرین‌پال",
 			PaymentGateway:   PaymentGatewayZarinpal,
 			TrackingCode:     trackingCode,
+			DiscountPercent:  req.DiscountPercent,
 			TotalAmountCents: total,
 		}
 		if err := tx.Create(order).Error; err != nil {
@@ -346,6 +361,9 @@ func (s *paymentService) fulfillPaidOrder(ctx context.Context, order *models.Ord
 	if len(items) == 0 {
 		return errors.New("order has no items")
 	}
+	if items[0].ItemType == models.OrderItemTypeTemplate {
+		return s.fulfillTemplateOrder(ctx, order, &items[0], authority, refID)
+	}
 
 	planID := items[0].PlanID
 	plan, err := s.planRepo.FindByID(ctx, planID)
@@ -411,6 +429,79 @@ func (s *paymentService) fulfillPaidOrder(ctx context.Context, order *models.Ord
 	})
 }
 
+// fulfillTemplateOrder completes a coach marketplace purchase: the order is marked
+// paid and the listing's template is copied into the buyer's library. A buyer who
+// already owns the listing gets nothing and the order is left for a refund.
+func (s *paymentService) fulfillTemplateOrder(ctx context.Context, order *models.Order, item *models.OrderItem, authority, refID string) error {
+	var listing models.TemplateListing
+	if err := s.db.WithContext(ctx).First(&listing, item.TemplateListingID).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	acquired := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Order
+		if err := tx.First(&current, order.ID).Error; err != nil {
+			return err
+		}
+		if current.Status == "paid" || current.Status == orderStatusRefundPending {
+			return nil
+		}
+
+		// A second paid order for a listing the buyer already owns delivers
+		// nothing; it is kept out of the sales and flagged for a refund.
+		var existing int64
+		if err := tx.Model(&models.TemplateAcquisition{}).
+			Where("listing_id = ? AND buyer_id = ?", listing.ID, order.UserID).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		status, txnStatus := "paid", "success"
+		if existing > 0 {
+			status, txnStatus = orderStatusRefundPending, orderStatusRefundPending
+		}
+
+		if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+			"status":            status,
+			"paid_at":           now,
+			"gateway_authority": authority,
+			"gateway_ref_id":    refID,
+			"payment_gateway":   PaymentGatewayZarinpal,
+			"payment_method":    "زرین‌پال",
+		}).Error; err != nil {
+			return err
+		}
+
+		txn := &models.Transaction{
+			OrderID:     order.ID,
+			UserID:      order.UserID,
+			AmountCents: order.TotalAmountCents,
+			Status:      txnStatus,
+			Reference:   refID,
+			Gateway:     PaymentGatewayZarinpal,
+			Date:        now,
+		}
+		if err := tx.Create(txn).Error; err != nil {
+			return err
+		}
+
+		if existing > 0 {
+			log.Printf("payment: order %d paid for listing %d that user %d already owns; refund pending", order.ID, listing.ID, order.UserID)
+			return nil
+		}
+		if _, err := acquireTemplateListing(ctx, tx, &listing, order.UserID, order.ID, order.TotalAmountCents); err != nil {
+			return err
+		}
+		acquired = true
+		return nil
+	})
+	if err == nil && acquired {
+		notifyListingAcquired(ctx, s.db, &listing)
+	}
+	return err
+}
+
 func (s *paymentService) markOrderFailed(ctx context.Context, order *models.Order) error {
 	return s.db.WithContext(ctx).Model(&models.Order{}).
 		Where("id = ? AND status = ?", order.ID, "pending").
@@ -436,9 +527,13 @@ func (s *paymentService) markFunnelLeadPaid(ctx context.Context, orderID uint, r
 			lead.TrackingCode = &code
 		}
 		lead.PaidAt = &now
+		if lead.RecoveryStep > 0 && lead.RecoveredAt == nil {
+			lead.RecoveredAt = &now
+			lead.RecoveredByStep = lead.RecoveryStep
+		}
 		_ = s.funnelRepo.Update(ctx, lead)
 	}
-	return s.buildFunnelSuccessURL(lead)
+	return s.buildFunnelSuccessURL(ctx, lead)
 }
 
 func (s *paymentService) funnelSuccessURL(ctx context.Context, orderID uint) string {
@@ -449,13 +544,22 @@ func (s *paymentService) funnelSuccessURL(ctx context.Context, orderID uint) str
 	if err != nil || lead == nil {
 		return ""
 	}
-	return s.buildFunnelSuccessURL(lead)
+	return s.buildFunnelSuccessURL(ctx, lead)
 }
 
-func (s *paymentService) buildFunnelSuccessURL(lead *models.FunnelLead) string {
-	if lead == nil || strings.TrimSpace(lead.CheckoutToken) == "" {
-		return ""
+// funnelLeadSlug is the site path of the funnel the lead came through.
+func (s *paymentService) funnelLeadSlug(ctx context.Context, lead *models.FunnelLead) string {
+	if s.db != nil && lead.FunnelID > 0 {
+		var funnel models.Funnel
+		if err := s.db.WithContext(ctx).Select("slug").First(&funnel, lead.FunnelID).Error; err == nil && funnel.Slug != "" {
+			return funnel.Slug
+		}
 	}
+	return LegacyFunnelSlug()
+}
+
+// funnelSiteBaseURL is the web app origin, taken from the payment result URL.
+func funnelSiteBaseURL() string {
 	webURL := strings.TrimSpace(config.Get().Payments.Zarinpal.WebResultURL)
 	base := "https://fitinoo.ir"
 	if webURL != "" {
@@ -463,13 +567,21 @@ func (s *paymentService) buildFunnelSuccessURL(lead *models.FunnelLead) string {
 			base = u.Scheme + "://" + u.Host
 		}
 	}
+	return strings.TrimRight(base, "/")
+}
+
+func (s *paymentService) buildFunnelSuccessURL(ctx context.Context, lead *models.FunnelLead) string {
+	if lead == nil || strings.TrimSpace(lead.CheckoutToken) == "" {
+		return ""
+	}
 	code := ""
 	if lead.TrackingCode != nil {
 		code = strings.TrimSpace(*lead.TrackingCode)
 	}
 	return fmt.Sprintf(
-		"%s/ali-rashidabadi/success?token=%s&code=%s",
-		strings.TrimRight(base, "/"),
+		"%s/%s/success?token=%s&code=%s",
+		funnelSiteBaseURL(),
+		s.funnelLeadSlug(ctx, lead),
 		url.QueryEscape(lead.CheckoutToken),
 		url.QueryEscape(code),
 	)
@@ -483,16 +595,10 @@ func (s *paymentService) funnelPayResultURL(ctx context.Context, orderID uint, s
 	if err != nil || lead == nil || strings.TrimSpace(lead.CheckoutToken) == "" {
 		return ""
 	}
-	webURL := strings.TrimSpace(config.Get().Payments.Zarinpal.WebResultURL)
-	base := "https://fitinoo.ir"
-	if webURL != "" {
-		if u, err := url.Parse(webURL); err == nil && u.Scheme != "" && u.Host != "" {
-			base = u.Scheme + "://" + u.Host
-		}
-	}
 	return fmt.Sprintf(
-		"%s/ali-rashidabadi/payment/result?status=%s&token=%s&tx_id=%d&ref_id=%s",
-		strings.TrimRight(base, "/"),
+		"%s/%s/payment/result?status=%s&token=%s&tx_id=%d&ref_id=%s",
+		funnelSiteBaseURL(),
+		s.funnelLeadSlug(ctx, lead),
 		url.QueryEscape(status),
 		url.QueryEscape(lead.CheckoutToken),
 		orderID,
@@ -0,0 +1,769 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/pkg/digits"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+// dataExportStaleAfter is how long a pending export may wait before the
+// periodic run builds it (the request's own goroutine died with a restart);
+// a processing one that long is given up on.
+const dataExportStaleAfter = 15 * time.Minute
+
+var (
+	ErrDataExportNotFound        = errors.New("data export not found")
+	ErrDataExportNotReady        = errors.New("data export not ready")
+	ErrAccountDeletionNotAllowed = errors.New("account deletion is only available to students")
+	ErrAccountDeletionNotPending = errors.New("no account deletion scheduled")
+)
+
+// DataExportDTO is one personal data export of the current user.
+type DataExportDTO struct {
+	ID          uint       `json:"id"`
+	Status      string     `json:"status"`
+	SizeBytes   int64      `json:"sizeBytes,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	DownloadURL string     `json:"downloadUrl,omitempty"`
+}
+
+// AccountDeletionDTO tells whether the current user's account is due for
+// deletion, and when.
+type AccountDeletionDTO struct {
+	Scheduled   bool       `json:"scheduled"`
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty"`
+	GraceDays   int        `json:"graceDays"`
+}
+
+type PrivacyService interface {
+	// RequestExport queues a ZIP of the user's personal data and builds it in
+	// the background. An export still in progress is returned as is.
+	RequestExport(ctx context.Context, userID uint) (*DataExportDTO, error)
+	ListExports(ctx context.Context, userID uint) ([]DataExportDTO, error)
+	// ExportFile returns the path of a ready export on disk.
+	ExportFile(ctx context.Context, userID, exportID uint) (string, error)
+	DeletionStatus(ctx context.Context, userID uint) (*AccountDeletionDTO, error)
+	// RequestDeletion schedules the account for anonymization after the
+	// grace period. password confirms it is the owner asking.
+	RequestDeletion(ctx context.Context, userID uint, password string) (*AccountDeletionDTO, error)
+	CancelDeletion(ctx context.Context, userID uint) (*AccountDeletionDTO, error)
+	// RunDue builds stranded exports, expires old ones and anonymizes the
+	// accounts whose grace period ended by now.
+	RunDue(ctx context.Context, now time.Time) error
+	// Start calls RunDue every privacy.interval_seconds until ctx is done.
+	Start(ctx context.Context)
+}
+
+type privacyService struct {
+	db       *gorm.DB
+	userRepo repository.UserRepository
+}
+
+func NewPrivacyService(db *gorm.DB, userRepo repository.UserRepository) PrivacyService {
+	return &privacyService{db: db, userRepo: userRepo}
+}
+
+func (s *privacyService) RequestExport(ctx context.Context, userID uint) (*DataExportDTO, error) {
+	var current models.DataExport
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND status IN ?", userID, []string{models.DataExportStatusPending, models.DataExportStatusProcessing}).
+		Order("id DESC").
+		First(&current).Error
+	if err == nil {
+		return toDataExportDTO(&current), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	export := &models.DataExport{UserID: userID, Status: models.DataExportStatusPending}
+	if err := s.db.WithContext(ctx).Create(export).Error; err != nil {
+		return nil, err
+	}
+	go s.buildExport(context.Background(), export.ID)
+	return toDataExportDTO(export), nil
+}
+
+func (s *privacyService) ListExports(ctx context.Context, userID uint) ([]DataExportDTO, error) {
+	var rows []models.DataExport
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Limit(20).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]DataExportDTO, 0, len(rows))
+	for i := range rows {
+		out = append(out, *toDataExportDTO(&rows[i]))
+	}
+	return out, nil
+}
+
+func (s *privacyService) ExportFile(ctx context.Context, userID, exportID uint) (string, error) {
+	var export models.DataExport
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", exportID, userID).First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrDataExportNotFound
+		}
+		return "", err
+	}
+	if export.Status != models.DataExportStatusReady || export.FilePath == "" ||
+		(export.ExpiresAt != nil && !export.ExpiresAt.After(time.Now())) {
+		return "", ErrDataExportNotReady
+	}
+	return export.FilePath, nil
+}
+
+func toDataExportDTO(e *models.DataExport) *DataExportDTO {
+	dto := &DataExportDTO{
+		ID:          e.ID,
+		Status:      e.Status,
+		SizeBytes:   e.SizeBytes,
+		Error:       e.Error,
+		CreatedAt:   e.CreatedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+	}
+	if e.Status == models.DataExportStatusReady {
+		dto.DownloadURL = fmt.Sprintf("/me/data-exports/%d/download", e.ID)
+	}
+	return dto
+}
+
+// buildExport writes the ZIP of a pending export. Whoever flips it to
+// processing first builds it, so the request goroutine and RunDue never
+// both do.
+func (s *privacyService) buildExport(ctx context.Context, exportID uint) {
+	claim := s.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", exportID, models.DataExportStatusPending).
+		Update("status", models.DataExportStatusProcessing)
+	if claim.Error != nil {
+		log.Printf("data export %d: claim: %v", exportID, claim.Error)
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+	var export models.DataExport
+	if err := s.db.WithContext(ctx).First(&export, exportID).Error; err != nil {
+		log.Printf("data export %d: %v", exportID, err)
+		return
+	}
+
+	path, size, err := s.writeExport(ctx, &export)
+	now := time.Now()
+	if err != nil {
+		log.Printf("data export %d: %v", exportID, err)
+		if uerr := s.db.WithContext(ctx).Model(&export).Updates(map[string]any{
+			"status":       models.DataExportStatusFailed,
+			"error":        truncateRunes(err.Error(), 500),
+			"completed_at": now,
+		}).Error; uerr != nil {
+			log.Printf("data export %d: mark failed: %v", exportID, uerr)
+		}
+		return
+	}
+	expiresAt := now.Add(time.Duration(config.Get().Privacy.ExportTTLHours) * time.Hour)
+	if err := s.db.WithContext(ctx).Model(&export).Updates(map[string]any{
+		"status":       models.DataExportStatusReady,
+		"file_path":    path,
+		"size_bytes":   size,
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}).Error; err != nil {
+		log.Printf("data export %d: mark ready: %v", exportID, err)
+		_ = os.Remove(path)
+		return
+	}
+	s.notify(ctx, export.UserID, "فایل داده‌های شما آماده است",
+		"خروجی اطلاعات حساب شما آماده دانلود است و تا "+strconv.Itoa(config.Get().Privacy.ExportTTLHours)+" ساعت در دسترس می‌ماند.")
+}
+
+// writeExport writes the ZIP next to its final name and renames it into
+// place once complete.
+func (s *privacyService) writeExport(ctx context.Context, export *models.DataExport) (string, int64, error) {
+	dir := config.Get().Privacy.ExportDir
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", 0, fmt.Errorf("creating export dir: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("user-%d-export-%d.zip", export.UserID, export.ID))
+	tmp := path + ".part"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", 0, err
+	}
+	zw := zip.NewWriter(f)
+	err = s.writeArchive(ctx, zw, export.UserID)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return "", 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+type profileExport struct {
+	ID                  uint       `json:"id"`
+	Name                string     `json:"name"`
+	Email               string     `json:"email"`
+	Phone               string     `json:"phone"`
+	Role                string     `json:"role"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	BirthDate           *time.Time `json:"birthDate,omitempty"`
+	NationalID          string     `json:"nationalId,omitempty"`
+	Gender              string     `json:"gender,omitempty"`
+	Goals               []string   `json:"goals,omitempty"`
+	PrimaryGoal         string     `json:"primaryGoal,omitempty"`
+	HeightCm            *float64   `json:"heightCm,omitempty"`
+	WeightKg            *float64   `json:"weightKg,omitempty"`
+	TargetWeightKg      *float64   `json:"targetWeightKg,omitempty"`
+	BodyCondition       string     `json:"bodyCondition,omitempty"`
+	BodyFatPercent      *float64   `json:"bodyFatPercent,omitempty"`
+	MedicalHistory      string     `json:"medicalHistory,omitempty"`
+	Injuries            string     `json:"injuries,omitempty"`
+	PhysicalLimitations string     `json:"physicalLimitations,omitempty"`
+}
+
+type ticketExport struct {
+	ID         uint       `json:"id"`
+	CoachID    uint       `json:"coachId"`
+	Title      string     `json:"title"`
+	Priority   string     `json:"priority"`
+	Status     string     `json:"status"`
+	Message    string     `json:"message"`
+	Answer     string     `json:"answer,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	AnsweredAt *time.Time `json:"answeredAt,omitempty"`
+}
+
+// writeArchive lays out the export: profile.json, one CSV per log,
+// tickets.json and the photo files under photos/.
+func (s *privacyService) writeArchive(ctx context.Context, zw *zip.Writer, userID uint) error {
+	db := s.db.WithContext(ctx)
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "profile.json", profileExport{
+		ID: user.ID, Name: user.Name, Email: user.Email, Phone: user.Phone, Role: user.Role, CreatedAt: user.CreatedAt,
+		BirthDate: user.BirthDate, NationalID: user.NationalID, Gender: user.Gender, Goals: user.GetGoals(),
+		PrimaryGoal: user.PrimaryGoal, HeightCm: user.HeightCm, WeightKg: user.WeightKg, TargetWeightKg: user.TargetWeightKg,
+		BodyCondition: user.BodyCondition, BodyFatPercent: user.BodyFatPercent, MedicalHistory: user.MedicalHistory,
+		Injuries: user.Injuries, PhysicalLimitations: user.PhysicalLimitations,
+	}); err != nil {
+		return err
+	}
+
+	var checkIns []models.CheckIn
+	if err := db.Where("user_id = ?", userID).Order("check_in_date").Find(&checkIns).Error; err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(checkIns))
+	for _, c := range checkIns {
+		rows = append(rows, []string{exportTime(c.CheckInDate), exportFloat(c.Weight), exportFloat(c.Waist), exportFloat(c.Chest), exportFloat(c.Hip), c.Notes})
+	}
+	if err := writeZipCSV(zw, "check_ins.csv", []string{"date", "weightKg", "waistCm", "chestCm", "hipCm", "notes"}, rows); err != nil {
+		return err
+	}
+
+	var sessions []models.WorkoutSession
+	if err := db.Where("user_id = ?", userID).Order("completed_at").Find(&sessions).Error; err != nil {
+		return err
+	}
+	rows = make([][]string, 0, len(sessions))
+	for _, w := range sessions {
+		rows = append(rows, []string{strconv.FormatUint(uint64(w.ID), 10), exportTime(w.CompletedAt), w.ProgramTitle, w.DayLabel,
+			strconv.Itoa(w.ExerciseCount), strconv.Itoa(w.DurationMin), w.Notes})
+	}
+	if err := writeZipCSV(zw, "workout_sessions.csv", []string{"id", "completedAt", "program", "day", "exercises", "durationMin", "notes"}, rows); err != nil {
+		return err
+	}
+
+	var sets []models.WorkoutSetLog
+	if err := db.Where("user_id = ?", userID).Order("performed_at").Find(&sets).Error; err != nil {
+		return err
+	}
+	rows = make([][]string, 0, len(sets))
+	for _, l := range sets {
+		rows = append(rows, []string{strconv.FormatUint(uint64(l.WorkoutSessionID), 10), exportTime(l.PerformedAt), l.ExerciseName,
+			strconv.Itoa(l.SetNumber), exportFloat(l.WeightKg), strconv.Itoa(l.Reps)})
+	}
+	if err := writeZipCSV(zw, "workout_sets.csv", []string{"sessionId", "performedAt", "exercise", "set", "weightKg", "reps"}, rows); err != nil {
+		return err
+	}
+
+	var foods []models.DailyFoodLog
+	if err := db.Where("user_id = ?", userID).Order("log_date").Find(&foods).Error; err != nil {
+		return err
+	}
+	rows = make([][]string, 0, len(foods))
+	for _, f := range foods {
+		rows = append(rows, []string{f.LogDate.Format("2006-01-02"), f.MealType, f.FoodName, f.Quantity,
+			exportFloat(f.Calories), exportFloat(f.Protein), exportFloat(f.Carbs), exportFloat(f.Fat)})
+	}
+	if err := writeZipCSV(zw, "food_logs.csv", []string{"date", "meal", "food", "quantity", "calories", "proteinG", "carbsG", "fatG"}, rows); err != nil {
+		return err
+	}
+
+	var orders []models.Order
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&orders).Error; err != nil {
+		return err
+	}
+	rows = make([][]string, 0, len(orders))
+	orderIDs := make([]uint, 0, len(orders))
+	for _, o := range orders {
+		orderIDs = append(orderIDs, o.ID)
+		paidAt := ""
+		if o.PaidAt != nil {
+			paidAt = exportTime(*o.PaidAt)
+		}
+		rows = append(rows, []string{strconv.FormatUint(uint64(o.ID), 10), exportTime(o.CreatedAt), o.Status, o.TrackingCode,
+			strconv.FormatInt(o.TotalAmountCents, 10), strconv.Itoa(o.DiscountPercent), o.PaymentMethod, paidAt})
+	}
+	if err := writeZipCSV(zw, "orders.csv", []string{"id", "createdAt", "status", "trackingCode", "totalAmountCents", "discountPercent", "paymentMethod", "paidAt"}, rows); err != nil {
+		return err
+	}
+	var items []models.OrderItem
+	if len(orderIDs) > 0 {
+		if err := db.Where("order_id IN ?", orderIDs).Order("order_id, id").Find(&items).Error; err != nil {
+			return err
+		}
+	}
+	rows = make([][]string, 0, len(items))
+	for _, it := range items {
+		rows = append(rows, []string{strconv.FormatUint(uint64(it.OrderID), 10), it.ItemType, it.Title, strconv.Itoa(it.Qty),
+			strconv.FormatInt(it.UnitPriceCents, 10), strconv.FormatInt(it.LineTotalCents, 10)})
+	}
+	if err := writeZipCSV(zw, "order_items.csv", []string{"orderId", "type", "title", "qty", "unitPriceCents", "lineTotalCents"}, rows); err != nil {
+		return err
+	}
+
+	var tickets []models.Ticket
+	if err := db.Where("student_id = ?", userID).Order("id").Find(&tickets).Error; err != nil {
+		return err
+	}
+	ticketsOut := make([]ticketExport, 0, len(tickets))
+	for _, t := range tickets {
+		ticketsOut = append(ticketsOut, ticketExport{ID: t.ID, CoachID: t.CoachID, Title: t.Title, Priority: t.Priority, Status: t.Status,
+			Message: t.Message, Answer: t.Answer, CreatedAt: t.CreatedAt, AnsweredAt: t.AnsweredAt})
+	}
+	if err := writeZipJSON(zw, "tickets.json", ticketsOut); err != nil {
+		return err
+	}
+
+	var photos []models.UserPhoto
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&photos).Error; err != nil {
+		return err
+	}
+	rows = make([][]string, 0, len(photos)+1)
+	if user.AvatarURL != "" {
+		if name, ok, err := copyUploadToZip(zw, user.AvatarURL, "photos/avatar"); err != nil {
+			return err
+		} else if ok {
+			rows = append(rows, []string{name, "avatar", "", ""})
+		}
+	}
+	for _, p := range photos {
+		name, ok, err := copyUploadToZip(zw, p.FilePath, fmt.Sprintf("photos/%d_%s", p.ID, p.Type))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		checkIn := ""
+		if p.CheckInDate != nil {
+			checkIn = exportTime(*p.CheckInDate)
+		}
+		rows = append(rows, []string{name, p.Type, exportTime(p.UploadedAt), checkIn})
+	}
+	return writeZipCSV(zw, "photos.csv", []string{"file", "type", "uploadedAt", "checkInDate"}, rows)
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeZipCSV(zw *zip.Writer, name string, header []string, rows [][]string) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// copyUploadToZip stores the uploaded file behind urlPath as base plus its
+// extension. Files that are gone, or live outside the upload dir, are
+// skipped.
+func copyUploadToZip(zw *zip.Writer, urlPath, base string) (string, bool, error) {
+	path, ok := uploadedFilePath(urlPath)
+	if !ok {
+		return "", false, nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	defer src.Close()
+	name := base + strings.ToLower(filepath.Ext(path))
+	w, err := zw.Create(name)
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return "", false, err
+	}
+	return name, true, nil
+}
+
+// uploadedFilePath maps a stored /uploads/... URL to its file in the upload dir.
+func uploadedFilePath(urlPath string) (string, bool) {
+	rel, ok := strings.CutPrefix(strings.TrimSpace(urlPath), "/uploads/")
+	if !ok || rel == "" || strings.Contains(rel, "..") {
+		return "", false
+	}
+	return filepath.Join(meGetUploadDir(), filepath.FromSlash(rel)), true
+}
+
+func exportTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func exportFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func (s *privacyService) DeletionStatus(ctx context.Context, userID uint) (*AccountDeletionDTO, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toAccountDeletionDTO(user), nil
+}
+
+func (s *privacyService) RequestDeletion(ctx context.Context, userID uint, password string) (*AccountDeletionDTO, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role != models.RoleStudent {
+		return nil, ErrAccountDeletionNotAllowed
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, ErrInvalidPassword
+	}
+	if user.DeletionScheduledAt != nil {
+		return toAccountDeletionDTO(user), nil
+	}
+	at := time.Now().AddDate(0, 0, config.Get().Privacy.DeletionGraceDays)
+	if err := s.db.WithContext(ctx).Model(user).Update("deletion_scheduled_at", at).Error; err != nil {
+		return nil, err
+	}
+	user.DeletionScheduledAt = &at
+	s.notify(ctx, user.ID, "حذف حساب کاربری زمان‌بندی شد",
+		"حساب کاربری شما پس از "+strconv.Itoa(config.Get().Privacy.DeletionGraceDays)+" روز حذف می‌شود. تا آن زمان می‌توانید درخواست را لغو کنید.")
+	return toAccountDeletionDTO(user), nil
+}
+
+func (s *privacyService) CancelDeletion(ctx context.Context, userID uint) (*AccountDeletionDTO, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletionScheduledAt == nil {
+		return nil, ErrAccountDeletionNotPending
+	}
+	if err := s.db.WithContext(ctx).Model(user).Update("deletion_scheduled_at", nil).Error; err != nil {
+		return nil, err
+	}
+	user.DeletionScheduledAt = nil
+	s.notify(ctx, user.ID, "درخواست حذف حساب لغو شد", "حساب کاربری شما حذف نخواهد شد.")
+	return toAccountDeletionDTO(user), nil
+}
+
+func toAccountDeletionDTO(u *models.User) *AccountDeletionDTO {
+	return &AccountDeletionDTO{
+		Scheduled:   u.DeletionScheduledAt != nil,
+		ScheduledAt: u.DeletionScheduledAt,
+		GraceDays:   config.Get().Privacy.DeletionGraceDays,
+	}
+}
+
+func (s *privacyService) Start(ctx context.Context) {
+	interval := time.Duration(config.Get().Privacy.IntervalSeconds) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := s.RunDue(ctx, now); err != nil {
+					log.Printf("privacy: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *privacyService) RunDue(ctx context.Context, now time.Time) error {
+	db := s.db.WithContext(ctx)
+	stale := now.Add(-dataExportStaleAfter)
+	if err := db.Model(&models.DataExport{}).
+		Where("status = ? AND updated_at < ?", models.DataExportStatusProcessing, stale).
+		Updates(map[string]any{"status": models.DataExportStatusFailed, "error": "interrupted", "completed_at": now}).Error; err != nil {
+		return err
+	}
+	var pending []uint
+	if err := db.Model(&models.DataExport{}).
+		Where("status = ? AND created_at < ?", models.DataExportStatusPending, stale).
+		Pluck("id", &pending).Error; err != nil {
+		return err
+	}
+	for _, id := range pending {
+		s.buildExport(ctx, id)
+	}
+
+	var expired []models.DataExport
+	if err := db.Where("status = ? AND expires_at <= ?", models.DataExportStatusReady, now).Find(&expired).Error; err != nil {
+		return err
+	}
+	for i := range expired {
+		if err := os.Remove(expired[i].FilePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("data export %d: remove file: %v", expired[i].ID, err)
+			continue
+		}
+		if err := db.Model(&expired[i]).Updates(map[string]any{"status": models.DataExportStatusExpired, "file_path": ""}).Error; err != nil {
+			return err
+		}
+	}
+
+	var due []models.User
+	if err := db.Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", now).Find(&due).Error; err != nil {
+		return err
+	}
+	for i := range due {
+		if err := s.anonymize(ctx, &due[i], now); err != nil {
+			log.Printf("privacy: anonymize user %d: %v", due[i].ID, err)
+			continue
+		}
+		log.Printf("privacy: anonymized user %d", due[i].ID)
+	}
+	return nil
+}
+
+// anonymize erases a deleted account: personal rows and uploaded files go,
+// the user row is wiped down to placeholders and soft-deleted. Orders,
+// order items, transactions and subscriptions stay, still pointing at the
+// user ID, so the books keep adding up; audit events about the account
+// stay with their payloads redacted.
+func (s *privacyService) anonymize(ctx context.Context, user *models.User, now time.Time) error {
+	var photos []models.UserPhoto
+	if err := s.db.WithContext(ctx).Where("user_id = ?", user.ID).Find(&photos).Error; err != nil {
+		return err
+	}
+	var exports []models.DataExport
+	if err := s.db.WithContext(ctx).Where("user_id = ?", user.ID).Find(&exports).Error; err != nil {
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := redactAuditTrail(tx, user); err != nil {
+			return err
+		}
+		for _, model := range []any{
+			&models.UserPhoto{}, &models.CheckIn{}, &models.DailyFoodLog{}, &models.WorkoutSession{},
+			&models.WorkoutSetLog{}, &models.ExerciseSwap{}, &models.AIMessage{}, &models.AIToolInvocation{},
+			&models.AIConversation{}, &models.Notification{}, &models.RefreshToken{}, &models.UserTwoFactor{},
+			&models.TwoFactorRecoveryCode{}, &models.DataExport{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("student_id = ?", user.ID).Delete(&models.Ticket{}).Error; err != nil {
+			return err
+		}
+		phones := digits.PhoneVariants(user.Phone)
+		if err := tx.Unscoped().Where("phone IN ?", phones).Delete(&models.OtpCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.MobileDevice{}).Where("user_id = ?", user.ID).Update("user_id", nil).Error; err != nil {
+			return err
+		}
+		// Leads keep the number as typed, so match every spelling of it.
+		if err := tx.Unscoped().Model(&models.FunnelLead{}).Where("phone IN ?", phones).Updates(map[string]any{
+			"first_name":          "",
+			"last_name":           "",
+			"phone":               "",
+			"primary_goal":        "",
+			"activity_level":      "",
+			"training_env":        "",
+			"experience":          "",
+			"nutrition_challenge": "",
+			"main_obstacle":       "",
+			"commitment":          "",
+			"analysis_title":      "",
+			"analysis_body":       "",
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Order{}).Where("user_id = ?", user.ID).Update("note", "").Error; err != nil {
+			return err
+		}
+		return tx.Model(user).Updates(map[string]any{
+			"name":                  "کاربر حذف‌شده",
+			"email":                 fmt.Sprintf("deleted-%d@deleted.invalid", user.ID),
+			"phone":                 fmt.Sprintf("deleted-%d", user.ID),
+			"password":              "!",
+			"avatar_url":            "",
+			"birth_date":            nil,
+			"national_id":           "",
+			"gender":                "",
+			"goals":                 "[]",
+			"primary_goal":          "",
+			"height_cm":             nil,
+			"weight_kg":             nil,
+			"target_weight_kg":      nil,
+			"body_condition":        "",
+			"body_fat_percent":      nil,
+			"medical_history":       "",
+			"injuries":              "",
+			"physical_limitations":  "",
+			"status_reason":         "",
+			"deletion_scheduled_at": nil,
+			"anonymized_at":         now,
+			"deleted_at":            now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Files go last: a failed transaction must not leave rows pointing at
+	// photos that no longer exist.
+	for _, p := range photos {
+		if path, ok := uploadedFilePath(p.FilePath); ok {
+			_ = os.Remove(path)
+		}
+	}
+	if path, ok := uploadedFilePath(user.AvatarURL); ok {
+		_ = os.Remove(path)
+	}
+	if err := os.RemoveAll(filepath.Join(meGetUploadDir(), "users", strconv.FormatUint(uint64(user.ID), 10))); err != nil {
+		log.Printf("privacy: remove uploads of user %d: %v", user.ID, err)
+	}
+	for _, e := range exports {
+		if e.FilePath != "" {
+			_ = os.Remove(e.FilePath)
+		}
+	}
+	return nil
+}
+
+// auditErasedPayload replaces the before/after payloads of audit events about
+// a deleted account; the events themselves stay in the log.
+const auditErasedPayload = `{"redacted":"account deleted"}`
+
+// redactAuditTrail blanks the recorded changes to user's profile, status,
+// roles, photos and funnel leads, and the IP and user agent of requests the
+// user made. It runs before those rows are deleted, while the photo and lead
+// ids can still be looked up.
+func redactAuditTrail(tx *gorm.DB, user *models.User) error {
+	var photoIDs, leadIDs []uint
+	if err := tx.Unscoped().Model(&models.UserPhoto{}).Where("user_id = ?", user.ID).Pluck("id", &photoIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.FunnelLead{}).Where("phone IN ?", digits.PhoneVariants(user.Phone)).Pluck("id", &leadIDs).Error; err != nil {
+		return err
+	}
+	ids := func(list []uint) []string {
+		out := make([]string, 0, len(list))
+		for _, id := range list {
+			out = append(out, strconv.FormatUint(uint64(id), 10))
+		}
+		return out
+	}
+	targets := map[string][]string{
+		"user_photo":  ids(photoIDs),
+		"funnel_lead": ids(leadIDs),
+	}
+	for _, t := range []string{"student", "coach", "user_status", "two_factor", "admin_roles"} {
+		targets[t] = ids([]uint{user.ID})
+	}
+	for targetType, targetIDs := range targets {
+		if len(targetIDs) == 0 {
+			continue
+		}
+		if err := tx.Model(&models.AuditEvent{}).
+			Where("target_type = ? AND target_id IN ?", targetType, targetIDs).
+			Where("before_json <> '' OR after_json <> ''").
+			Updates(map[string]any{"before_json": auditErasedPayload, "after_json": auditErasedPayload}).Error; err != nil {
+			return err
+		}
+	}
+	// Requests the user made, directly or as an impersonating admin, keep
+	// their action but lose where they came from.
+	return tx.Model(&models.AuditEvent{}).
+		Where("actor_id = ? OR impersonator_id = ?", user.ID, user.ID).
+		Updates(map[string]any{"ip": "", "user_agent": ""}).Error
+}
+
+func (s *privacyService) notify(ctx context.Context, userID uint, title, message string) {
+	n := &models.Notification{
+		UserID:  userID,
+		Type:    models.NotificationTypePrivacy,
+		Title:   title,
+		Message: message,
+	}
+	if err := s.db.WithContext(ctx).Create(n).Error; err != nil {
+		log.Printf("notify: create privacy notification failed user=%d err=%v", userID, err)
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+// RateLimiter counts hits per key in fixed windows. Allow records a hit and
+// reports whether key is still within limit for the current window.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+}
+
+// rateLimitPurgeInterval is how often the DB limiter deletes expired windows.
+const rateLimitPurgeInterval = 10 * time.Minute
+
+// NewRateLimiterFromConfig returns the limiter selected by rate_limit.store.
+func NewRateLimiterFromConfig(cfg config.Config, repo repository.RateLimitRepository) RateLimiter {
+	if cfg.RateLimit.Store == "memory" || repo == nil {
+		return NewMemoryRateLimiter()
+	}
+	return NewDBRateLimiter(repo)
+}
+
+func rateLimitWindow(now time.Time, window time.Duration) time.Time {
+	return now.Truncate(window)
+}
+
+type dbRateLimiter struct {
+	repo repository.RateLimitRepository
+
+	mu         sync.Mutex
+	lastPurged time.Time
+}
+
+// NewDBRateLimiter keeps counters in the database so every replica sees the same
+// counts and they survive restarts.
+func NewDBRateLimiter(repo repository.RateLimitRepository) RateLimiter {
+	return &dbRateLimiter{repo: repo}
+}
+
+func (l *dbRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	now := time.Now()
+	start := rateLimitWindow(now, window)
+	hits, err := l.repo.Hit(ctx, key+":"+strconv.FormatInt(start.Unix(), 10), start.Add(window))
+	if err != nil {
+		return false, err
+	}
+	l.purge(ctx, now)
+	return hits <= limit, nil
+}
+
+func (l *dbRateLimiter) purge(ctx context.Context, now time.Time) {
+	l.mu.Lock()
+	if now.Sub(l.lastPurged) < rateLimitPurgeInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastPurged = now
+	l.mu.Unlock()
+	if _, err := l.repo.DeleteExpired(ctx, now); err != nil {
+		log.Printf("rate limit purge: %v", err)
+	}
+}
+
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]memoryRateWindow
+}
+
+type memoryRateWindow struct {
+	start, end time.Time
+	hits       int
+}
+
+// memoryRateSweepSize is the map size above which expired windows are swept.
+const memoryRateSweepSize = 1024
+
+// NewMemoryRateLimiter keeps counters in process memory; they reset on restart
+// and are not shared between replicas.
+func NewMemoryRateLimiter() RateLimiter {
+	return &memoryRateLimiter{windows: map[string]memoryRateWindow{}}
+}
+
+func (l *memoryRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	now := time.Now()
+	start := rateLimitWindow(now, window)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w := l.windows[key]
+	if !w.start.Equal(start) {
+		w = memoryRateWindow{start: start, end: start.Add(window)}
+	}
+	w.hits++
+	l.windows[key] = w
+	if len(l.windows) > memoryRateSweepSize {
+		for k, other := range l.windows {
+			if !other.end.After(now) {
+				delete(l.windows, k)
+			}
+		}
+	}
+	return w.hits <= limit, nil
+}
@@ -402,10 +402,15 @@ func (s *siteSettingsService) UpdateAcademy(ctx context.Context, items []Academy
 	if err != nil {
 		return err
 	}
+	before := *row
 	if row.AcademyItems, err = json.Marshal(items); err != nil {
 		return err
 	}
-	return s.repo.Save(ctx, row)
+	if err := s.repo.Save(ctx, row); err != nil {
+		return err
+	}
+	AuditChange(ctx, "site_settings", row.ID, before, row)
+	return nil
 }
 
 func (s *siteSettingsService) GetFAQ(ctx context.Context) ([]FAQGroupDTO, error) {
@@ -431,10 +436,15 @@ func (s *siteSettingsService) UpdateFAQ(ctx context.Context, groups []FAQGroupDT
 	if err != nil {
 		return err
 	}
+	before := *row
 	if row.FAQGroups, err = json.Marshal(groups); err != nil {
 		return err
 	}
-	return s.repo.Save(ctx, row)
+	if err := s.repo.Save(ctx, row); err != nil {
+		return err
+	}
+	AuditChange(ctx, "site_settings", row.ID, before, row)
+	return nil
 }
 
 func (s *siteSettingsService) Update(ctx context.Context, dto *SiteSettingsDTO) error {
@@ -447,6 +457,7 @@ func (s *siteSettingsService) Update(ctx context.Context, dto *SiteSettingsDTO)
 			return err
 		}
 	}
+	before := *row
 	heroURL := ""
 	if dto.HeroImage != nil && dto.HeroImage.URL != "" {
 		heroURL = dto.HeroImage.URL
@@ -475,7 +486,13 @@ func (s *siteSettingsService) Update(ctx context.Context, dto *SiteSettingsDTO)
 		return err
 	}
 	if row.ID == 0 {
-		return s.repo.FirstOrCreate(ctx, row)
+		err = s.repo.FirstOrCreate(ctx, row)
+	} else {
+		err = s.repo.Save(ctx, row)
+	}
+	if err != nil {
+		return err
 	}
-	return s.repo.Save(ctx, row)
+	AuditChange(ctx, "site_settings", row.ID, before, row)
+	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -18,6 +19,21 @@ import (
 // ErrSMSSendFailed is returned when the SMS provider rejects or fails the request.
 var ErrSMSSendFailed = errors.New("failed to send sms")
 
+// SMSSender delivers one Verify Lookup message; tokens fill %token%,
+// %token2% and %token3% in order.
+type SMSSender func(receptor, template string, tokens ...string) error
+
+// smsOverride, when set, replaces both Kavenegar and the dev console.
+var smsOverride SMSSender
+
+// SetSMSSender routes every SMS through send until restore is called. It is
+// meant for tests that need to read OTP codes or assert notifications.
+func SetSMSSender(send SMSSender) (restore func()) {
+	prev := smsOverride
+	smsOverride = send
+	return func() { smsOverride = prev }
+}
+
 // Kavenegar Verify Lookup — https://api.kavenegar.com/v1/{API-KEY}/verify/lookup.json
 // Required: receptor, token, template. Optional: type=sms (default), token2, token3, tag.
 
@@ -90,18 +106,39 @@ func validateLookupToken(token string) error {
 
 // SendVerification sends an OTP via Kavenegar Verify Lookup (اعتبارسنجی).
 func SendVerification(receptor, token, template string) (*kavenegarResponse, error) {
+	return sendLookup(receptor, template, token)
+}
+
+// sendLookup sends a Verify Lookup template; tokens fill %token%, %token2% and
+// %token3% in order.
+func sendLookup(receptor, template string, tokens ...string) (*kavenegarResponse, error) {
 	receptor = strings.TrimSpace(receptor)
 	template = strings.TrimSpace(template)
-	token = strings.TrimSpace(token)
+	if len(tokens) == 0 || len(tokens) > 3 {
+		return nil, errors.New("one to three lookup tokens are required")
+	}
+	for i := range tokens {
+		tokens[i] = strings.TrimSpace(tokens[i])
+	}
+	token := tokens[0]
 
 	if receptor == "" || template == "" {
 		return nil, errors.New("receptor and template are required")
 	}
-	if err := validateLookupToken(token); err != nil {
-		if errors.Is(err, ErrSMSSendFailed) {
-			return nil, fmt.Errorf("%w: %s", ErrSMSSendFailed, "فرمت کد OTP برای کاوه‌نگار نامعتبر است")
+	for _, t := range tokens {
+		if err := validateLookupToken(t); err != nil {
+			if errors.Is(err, ErrSMSSendFailed) {
+				return nil, fmt.Errorf("%w: %s", ErrSMSSendFailed, "فرمت کد OTP برای کاوه‌نگار نامعتبر است")
+			}
+			return nil, err
+		}
+	}
+
+	if smsOverride != nil {
+		if err := smsOverride(receptor, template, tokens...); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSMSSendFailed, err)
 		}
-		return nil, err
+		return &kavenegarResponse{Return: kavenegarReturn{Status: 200, Message: "sms override"}}, nil
 	}
 
 	cfg := config.Get().SMS
@@ -112,6 +149,9 @@ func SendVerification(receptor, token, template string) (*kavenegarResponse, err
 		log.Printf("sms: LOCAL/DEV console OTP (no provider call) env=%s", config.Get().App.Env)
 		log.Printf("========== LOCAL OTP ==========")
 		log.Printf("code: %s", token)
+		if len(tokens) > 1 {
+			log.Printf("extra tokens: %s", strings.Join(tokens[1:], " "))
+		}
 		log.Printf("phone: %s", receptor)
 		log.Printf("template: %s", template)
 		log.Printf("================================")
@@ -130,6 +170,12 @@ func SendVerification(receptor, token, template string) (*kavenegarResponse, err
 	params := url.Values{}
 	params.Set("receptor", receptor)
 	params.Set("token", token)
+	if len(tokens) > 1 {
+		params.Set("token2", tokens[1])
+	}
+	if len(tokens) > 2 {
+		params.Set("token3", tokens[2])
+	}
 	params.Set("template", template)
 	params.Set("type", "sms")
 
@@ -218,6 +264,23 @@ func SendProgramReadySMS(receptor, firstName string) error {
 	return err
 }
 
+// SendFunnelRecoverySMS reminds a funnel lead to finish checkout. token2 is the
+// checkout token for the resume link; a discount uses its own template with
+// the percent as token3.
+func SendFunnelRecoverySMS(receptor, firstName, checkoutToken string, discountPercent int) error {
+	name := sanitizeLookupName(firstName)
+	if name == "" {
+		name = "کاربر"
+	}
+	cfg := config.Get().SMS
+	if discountPercent > 0 {
+		_, err := sendLookup(receptor, cfg.FunnelDiscountPattern, name, checkoutToken, strconv.Itoa(discountPercent))
+		return err
+	}
+	_, err := sendLookup(receptor, cfg.FunnelRecoveryPattern, name, checkoutToken)
+	return err
+}
+
 func sanitizeLookupName(name string) string {
 	name = strings.TrimSpace(name)
 	if name == "" {
@@ -0,0 +1,859 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+var (
+	ErrListingNotFound        = errors.New("listing not found")
+	ErrListingForbidden       = errors.New("listing belongs to another coach")
+	ErrListingInvalidKind     = errors.New("kind must be workout or nutrition")
+	ErrListingInvalidPrice    = errors.New("price must not be negative")
+	ErrListingAlreadyOpen     = errors.New("template already has a pending or approved listing")
+	ErrListingNotPending      = errors.New("listing is not pending review")
+	ErrListingOwnTemplate     = errors.New("cannot acquire your own listing")
+	ErrListingAlreadyAcquired = errors.New("listing already acquired")
+	ErrListingResale          = errors.New("templates acquired from the marketplace cannot be listed")
+)
+
+// TemplateListingPreviewDay lists exercise names of one workout day (no sets/reps).
+type TemplateListingPreviewDay struct {
+	DayNumber int      `json:"dayNumber"`
+	Exercises []string `json:"exercises"`
+}
+
+// TemplateListingPreviewMeal summarizes one meal without its food lines.
+type TemplateListingPreviewMeal struct {
+	MealName    string `json:"mealName"`
+	MealCalorie int    `json:"mealCalorie"`
+	ItemCount   int    `json:"itemCount"`
+}
+
+// TemplateListingPreview is what a coach sees before acquiring: structure, not the full program.
+type TemplateListingPreview struct {
+	Type          string                       `json:"type"`
+	Gender        string                       `json:"gender"`
+	Target        string                       `json:"target"`
+	Level         string                       `json:"level,omitempty"`
+	Location      string                       `json:"location,omitempty"`
+	DayCount      int                          `json:"dayCount,omitempty"`
+	ExerciseCount int                          `json:"exerciseCount,omitempty"`
+	Days          []TemplateListingPreviewDay  `json:"days,omitempty"`
+	Calorie       int                          `json:"calorie,omitempty"`
+	Meals         []TemplateListingPreviewMeal `json:"meals,omitempty"`
+}
+
+type TemplateListingDTO struct {
+	ID          uint       `json:"id"`
+	Kind        string     `json:"kind"`
+	TemplateID  uint       `json:"templateId"`
+	AuthorID    uint       `json:"authorId"`
+	AuthorName  string     `json:"authorName"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Price       int64      `json:"price"`
+	IsFree      bool       `json:"isFree"`
+	Status      string     `json:"status"`
+	ReviewNote  string     `json:"reviewNote,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewedAt,omitempty"`
+	IsMine      bool       `json:"isMine"`
+	Acquired    bool       `json:"acquired"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type TemplateListingDetail struct {
+	TemplateListingDTO
+	Preview *TemplateListingPreview `json:"preview,omitempty"`
+}
+
+type TemplateListingListResponse struct {
+	Items    []TemplateListingDTO `json:"items"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"pageSize"`
+	Total    int64                `json:"total"`
+}
+
+type TemplateListingUpsertRequest struct {
+	Kind        string `json:"kind"`
+	TemplateID  uint   `json:"templateId"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Price       int64  `json:"price"`
+}
+
+type TemplateListingReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// TemplateAcquireResponse is returned by acquire: free listings are copied right away,
+// paid ones return a ZarinPal payment and are copied on the payment callback.
+type TemplateAcquireResponse struct {
+	Acquired   bool                     `json:"acquired"`
+	Kind       string                   `json:"kind"`
+	TemplateID uint                     `json:"templateId,omitempty"`
+	Payment    *ZarinpalPaymentResponse `json:"payment,omitempty"`
+}
+
+type TemplateListingReportRow struct {
+	TemplateListingDTO
+	Acquisitions   int64      `json:"acquisitions"`
+	Revenue        int64      `json:"revenue"`
+	LastAcquiredAt *time.Time `json:"lastAcquiredAt,omitempty"`
+}
+
+type TemplateMarketplaceMonth struct {
+	Year         int   `json:"year"`
+	Month        int   `json:"month"`
+	Acquisitions int   `json:"acquisitions"`
+	Revenue      int64 `json:"revenue"`
+}
+
+// TemplateMarketplaceReport is the author's revenue and usage overview.
+type TemplateMarketplaceReport struct {
+	TotalListings     int                        `json:"totalListings"`
+	ApprovedListings  int                        `json:"approvedListings"`
+	TotalAcquisitions int64                      `json:"totalAcquisitions"`
+	TotalRevenue      int64                      `json:"totalRevenue"`
+	Listings          []TemplateListingReportRow `json:"listings"`
+	Monthly           []TemplateMarketplaceMonth `json:"monthly"`
+}
+
+// TemplateMarketplaceService lets coaches publish templates to other coaches and
+// acquire copies of approved listings into their own library.
+type TemplateMarketplaceService interface {
+	Browse(ctx context.Context, coachID uint, page, pageSize int, query, kind string) (*TemplateListingListResponse, error)
+	GetListing(ctx context.Context, coachID, id uint) (*TemplateListingDetail, error)
+	Acquire(ctx context.Context, coachID, id uint) (*TemplateAcquireResponse, error)
+
+	ListMyListings(ctx context.Context, coachID uint) ([]TemplateListingDTO, error)
+	Publish(ctx context.Context, coachID uint, req *TemplateListingUpsertRequest) (*TemplateListingDTO, error)
+	UpdateListing(ctx context.Context, coachID, id uint, req *TemplateListingUpsertRequest) (*TemplateListingDTO, error)
+	Unpublish(ctx context.Context, coachID, id uint) error
+	Report(ctx context.Context, coachID uint) (*TemplateMarketplaceReport, error)
+
+	AdminList(ctx context.Context, page, pageSize int, status, query string) (*TemplateListingListResponse, error)
+	AdminGet(ctx context.Context, id uint) (*TemplateListingDetail, error)
+	Approve(ctx context.Context, adminID, id uint, note string) (*TemplateListingDTO, error)
+	Reject(ctx context.Context, adminID, id uint, note string) (*TemplateListingDTO, error)
+}
+
+type templateMarketplaceService struct {
+	db           *gorm.DB
+	listingRepo  repository.TemplateListingRepository
+	templateRepo repository.TemplateRepository
+	userRepo     repository.UserRepository
+	paymentSvc   PaymentService
+}
+
+func NewTemplateMarketplaceService(
+	db *gorm.DB,
+	listingRepo repository.TemplateListingRepository,
+	templateRepo repository.TemplateRepository,
+	userRepo repository.UserRepository,
+	paymentSvc PaymentService,
+) TemplateMarketplaceService {
+	return &templateMarketplaceService{
+		db:           db,
+		listingRepo:  listingRepo,
+		templateRepo: templateRepo,
+		userRepo:     userRepo,
+		paymentSvc:   paymentSvc,
+	}
+}
+
+func (s *templateMarketplaceService) Browse(ctx context.Context, coachID uint, page, pageSize int, query, kind string) (*TemplateListingListResponse, error) {
+	filter := repository.TemplateListingFilter{
+		Status: models.TemplateListingStatusApproved,
+		Kind:   kind,
+		Query:  query,
+	}
+	list, total, err := s.listingRepo.List(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(list))
+	for _, l := range list {
+		ids = append(ids, l.ID)
+	}
+	acquired, err := s.listingRepo.AcquiredListingIDs(ctx, coachID, ids)
+	if err != nil {
+		return nil, err
+	}
+	names := map[uint]string{}
+	items := make([]TemplateListingDTO, 0, len(list))
+	for i := range list {
+		dto := s.toDTO(ctx, &list[i], coachID, names)
+		dto.Acquired = acquired[list[i].ID]
+		// Moderation notes are between the author and admins.
+		dto.ReviewNote = ""
+		items = append(items, dto)
+	}
+	return &TemplateListingListResponse{Items: items, Page: page, PageSize: pageSize, Total: total}, nil
+}
+
+func (s *templateMarketplaceService) GetListing(ctx context.Context, coachID, id uint) (*TemplateListingDetail, error) {
+	listing, err := s.findListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if listing.AuthorID != coachID && listing.Status != models.TemplateListingStatusApproved {
+		return nil, ErrListingNotFound
+	}
+	detail, err := s.toDetail(ctx, listing, coachID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.listingRepo.FindAcquisition(ctx, listing.ID, coachID); err == nil {
+		detail.Acquired = true
+	}
+	if listing.AuthorID != coachID {
+		detail.ReviewNote = ""
+	}
+	return detail, nil
+}
+
+func (s *templateMarketplaceService) Acquire(ctx context.Context, coachID, id uint) (*TemplateAcquireResponse, error) {
+	listing, err := s.findListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if listing.Status != models.TemplateListingStatusApproved {
+		return nil, ErrListingNotFound
+	}
+	if listing.AuthorID == coachID {
+		return nil, ErrListingOwnTemplate
+	}
+	if _, err := s.listingRepo.FindAcquisition(ctx, listing.ID, coachID); err == nil {
+		return nil, ErrListingAlreadyAcquired
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if listing.PriceCents <= 0 {
+		var acq *models.TemplateAcquisition
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			acq, err = acquireTemplateListing(ctx, tx, listing, coachID, 0, 0)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifyListingAcquired(ctx, s.db, listing)
+		return &TemplateAcquireResponse{Acquired: true, Kind: acq.TemplateKind, TemplateID: acq.TemplateID}, nil
+	}
+
+	orderID, err := s.createListingOrder(ctx, coachID, listing)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := s.paymentSvc.RequestZarinpalForOrder(ctx, coachID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateAcquireResponse{Acquired: false, Kind: listing.TemplateKind, Payment: payment}, nil
+}
+
+// createListingOrder opens a pending order for a paid listing. The author is stored as
+// the order's coach so the sale counts toward their income. A buyer who already has a
+// pending order for the listing at its current price gets that order back, so a
+// second checkout cannot charge them twice.
+func (s *templateMarketplaceService) createListingOrder(ctx context.Context, buyerID uint, listing *models.TemplateListing) (uint, error) {
+	var orderID uint
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Serialise checkouts of the listing so two requests cannot both miss
+		// the other's pending order.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			First(&models.TemplateListing{}, listing.ID).Error; err != nil {
+			return err
+		}
+		var pending []models.Order
+		if err := tx.Joins("JOIN order_items ON order_items.order_id = orders.id AND order_items.deleted_at IS NULL").
+			Where("orders.user_id = ? AND orders.status = ? AND order_items.template_listing_id = ?", buyerID, "pending", listing.ID).
+			Find(&pending).Error; err != nil {
+			return err
+		}
+		for _, o := range pending {
+			if o.TotalAmountCents == listing.PriceCents && orderID == 0 {
+				orderID = o.ID
+				continue
+			}
+			// Superseded by a price change (or a duplicate from before this check).
+			if err := tx.Model(&models.Order{}).Where("id = ? AND status = ?", o.ID, "pending").
+				Update("status", "failed").Error; err != nil {
+				return err
+			}
+		}
+		if orderID != 0 {
+			return nil
+		}
+
+		order := &models.Order{
+			UserID:           buyerID,
+			CoachID:          listing.AuthorID,
+			Status:           "pending",
+			PaymentMethod:    "زرین‌پال",
+			PaymentGateway:   PaymentGatewayZarinpal,
+			TrackingCode:     generateTrackingCode(),
+			TotalAmountCents: listing.PriceCents,
+		}
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+		item := &models.OrderItem{
+			OrderID:           order.ID,
+			ItemType:          models.OrderItemTypeTemplate,
+			TemplateListingID: listing.ID,
+			RefID:             fmt.Sprintf("tl%d", listing.ID),
+			Title:             listing.Title,
+			Qty:               1,
+			UnitPriceCents:    listing.PriceCents,
+			LineTotalCents:    listing.PriceCents,
+		}
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		orderID = order.ID
+		return nil
+	})
+	return orderID, err
+}
+
+func (s *templateMarketplaceService) ListMyListings(ctx context.Context, coachID uint) ([]TemplateListingDTO, error) {
+	list, err := s.listingRepo.ListByAuthor(ctx, coachID)
+	if err != nil {
+		return nil, err
+	}
+	names := map[uint]string{}
+	out := make([]TemplateListingDTO, 0, len(list))
+	for i := range list {
+		out = append(out, s.toDTO(ctx, &list[i], coachID, names))
+	}
+	return out, nil
+}
+
+func (s *templateMarketplaceService) Publish(ctx context.Context, coachID uint, req *TemplateListingUpsertRequest) (*TemplateListingDTO, error) {
+	kind := strings.TrimSpace(req.Kind)
+	if kind != models.TemplateKindWorkout && kind != models.TemplateKindNutrition {
+		return nil, ErrListingInvalidKind
+	}
+	if req.Price < 0 {
+		return nil, ErrListingInvalidPrice
+	}
+	templateTitle, err := s.ownedTemplateTitle(ctx, coachID, kind, req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.listingRepo.FindOpenByTemplate(ctx, kind, req.TemplateID); err == nil {
+		return nil, ErrListingAlreadyOpen
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = templateTitle
+	}
+	listing := &models.TemplateListing{
+		AuthorID:     coachID,
+		TemplateKind: kind,
+		TemplateID:   req.TemplateID,
+		Title:        title,
+		Description:  strings.TrimSpace(req.Description),
+		PriceCents:   req.Price,
+		Status:       models.TemplateListingStatusPending,
+	}
+	if err := s.listingRepo.Create(ctx, listing); err != nil {
+		return nil, err
+	}
+	dto := s.toDTO(ctx, listing, coachID, map[uint]string{})
+	return &dto, nil
+}
+
+// UpdateListing edits title/description/price. Kind and template are fixed once
+// published; any change sends the listing back to admin review.
+func (s *templateMarketplaceService) UpdateListing(ctx context.Context, coachID, id uint, req *TemplateListingUpsertRequest) (*TemplateListingDTO, error) {
+	listing, err := s.findOwnedListing(ctx, coachID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Price < 0 {
+		return nil, ErrListingInvalidPrice
+	}
+	if listing.Status == models.TemplateListingStatusUnlisted || listing.Status == models.TemplateListingStatusRejected {
+		// Re-opening must not create a second open listing for the same template.
+		if _, err := s.listingRepo.FindOpenByTemplate(ctx, listing.TemplateKind, listing.TemplateID); err == nil {
+			return nil, ErrListingAlreadyOpen
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	if title := strings.TrimSpace(req.Title); title != "" {
+		listing.Title = title
+	}
+	listing.Description = strings.TrimSpace(req.Description)
+	listing.PriceCents = req.Price
+	listing.Status = models.TemplateListingStatusPending
+	listing.ReviewNote = ""
+	listing.ReviewedAt = nil
+	listing.ReviewedByID = nil
+	if err := s.listingRepo.Save(ctx, listing); err != nil {
+		return nil, err
+	}
+	dto := s.toDTO(ctx, listing, coachID, map[uint]string{})
+	return &dto, nil
+}
+
+func (s *templateMarketplaceService) Unpublish(ctx context.Context, coachID, id uint) error {
+	listing, err := s.findOwnedListing(ctx, coachID, id)
+	if err != nil {
+		return err
+	}
+	listing.Status = models.TemplateListingStatusUnlisted
+	return s.listingRepo.Save(ctx, listing)
+}
+
+func (s *templateMarketplaceService) Report(ctx context.Context, coachID uint) (*TemplateMarketplaceReport, error) {
+	list, err := s.listingRepo.ListByAuthor(ctx, coachID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.listingRepo.StatsByAuthor(ctx, coachID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TemplateMarketplaceReport{
+		TotalListings: len(list),
+		Listings:      make([]TemplateListingReportRow, 0, len(list)),
+	}
+	names := map[uint]string{}
+	for i := range list {
+		st := stats[list[i].ID]
+		if list[i].Status == models.TemplateListingStatusApproved {
+			report.ApprovedListings++
+		}
+		report.TotalAcquisitions += st.Acquisitions
+		report.TotalRevenue += st.RevenueCents
+		report.Listings = append(report.Listings, TemplateListingReportRow{
+			TemplateListingDTO: s.toDTO(ctx, &list[i], coachID, names),
+			Acquisitions:       st.Acquisitions,
+			Revenue:            st.RevenueCents,
+			LastAcquiredAt:     st.LastAcquiredAt,
+		})
+	}
+	sort.SliceStable(report.Listings, func(i, j int) bool {
+		return report.Listings[i].Revenue > report.Listings[j].Revenue
+	})
+
+	// Last 6 calendar months, oldest first, zero-filled.
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -5, 0)
+	acquisitions, err := s.listingRepo.ListAcquisitionsByAuthorSince(ctx, coachID, start)
+	if err != nil {
+		return nil, err
+	}
+	report.Monthly = make([]TemplateMarketplaceMonth, 6)
+	for i := range report.Monthly {
+		m := start.AddDate(0, i, 0)
+		report.Monthly[i] = TemplateMarketplaceMonth{Year: m.Year(), Month: int(m.Month())}
+	}
+	for _, a := range acquisitions {
+		at := a.CreatedAt.In(time.Local)
+		idx := (at.Year()-start.Year())*12 + int(at.Month()) - int(start.Month())
+		if idx < 0 || idx >= len(report.Monthly) {
+			continue
+		}
+		report.Monthly[idx].Acquisitions++
+		report.Monthly[idx].Revenue += a.PriceCents
+	}
+	return report, nil
+}
+
+func (s *templateMarketplaceService) AdminList(ctx context.Context, page, pageSize int, status, query string) (*TemplateListingListResponse, error) {
+	list, total, err := s.listingRepo.List(ctx, repository.TemplateListingFilter{Status: status, Query: query}, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	names := map[uint]string{}
+	items := make([]TemplateListingDTO, 0, len(list))
+	for i := range list {
+		items = append(items, s.toDTO(ctx, &list[i], 0, names))
+	}
+	return &TemplateListingListResponse{Items: items, Page: page, PageSize: pageSize, Total: total}, nil
+}
+
+func (s *templateMarketplaceService) AdminGet(ctx context.Context, id uint) (*TemplateListingDetail, error) {
+	listing, err := s.findListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toDetail(ctx, listing, 0)
+}
+
+func (s *templateMarketplaceService) Approve(ctx context.Context, adminID, id uint, note string) (*TemplateListingDTO, error) {
+	return s.review(ctx, adminID, id, models.TemplateListingStatusApproved, note)
+}
+
+func (s *templateMarketplaceService) Reject(ctx context.Context, adminID, id uint, note string) (*TemplateListingDTO, error) {
+	return s.review(ctx, adminID, id, models.TemplateListingStatusRejected, note)
+}
+
+func (s *templateMarketplaceService) review(ctx context.Context, adminID, id uint, status, note string) (*TemplateListingDTO, error) {
+	listing, err := s.findListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if listing.Status != models.TemplateListingStatusPending {
+		return nil, ErrListingNotPending
+	}
+	now := time.Now()
+	reviewer := adminID
+	listing.Status = status
+	listing.ReviewNote = strings.TrimSpace(note)
+	listing.ReviewedAt = &now
+	listing.ReviewedByID = &reviewer
+	if err := s.listingRepo.Save(ctx, listing); err != nil {
+		return nil, err
+	}
+
+	n := &models.Notification{
+		UserID:  listing.AuthorID,
+		Type:    models.NotificationTypeMarketplace,
+		Title:   "قالب شما در بازارچه منتشر شد",
+		Message: fmt.Sprintf("«%s» تایید شد و برای سایر مربیان قابل مشاهده است.", listing.Title),
+	}
+	if status == models.TemplateListingStatusRejected {
+		n.Title = "قالب شما در بازارچه رد شد"
+		n.Message = fmt.Sprintf("«%s» تایید نشد.", listing.Title)
+		if listing.ReviewNote != "" {
+			n.Message += " " + listing.ReviewNote
+		}
+	}
+	if err := s.db.WithContext(ctx).Create(n).Error; err != nil {
+		log.Printf("notify: marketplace review notification failed listing=%d err=%v", listing.ID, err)
+	}
+
+	dto := s.toDTO(ctx, listing, 0, map[uint]string{})
+	return &dto, nil
+}
+
+func (s *templateMarketplaceService) findListing(ctx context.Context, id uint) (*models.TemplateListing, error) {
+	listing, err := s.listingRepo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrListingNotFound
+		}
+		return nil, err
+	}
+	return listing, nil
+}
+
+func (s *templateMarketplaceService) findOwnedListing(ctx context.Context, coachID, id uint) (*models.TemplateListing, error) {
+	listing, err := s.findListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if listing.AuthorID != coachID {
+		return nil, ErrListingForbidden
+	}
+	return listing, nil
+}
+
+// ownedTemplateTitle checks the coach owns the template and returns its title.
+// Platform templates cannot be sold, nor can copies acquired from the
+// marketplace or duplicates of them.
+func (s *templateMarketplaceService) ownedTemplateTitle(ctx context.Context, coachID uint, kind string, templateID uint) (string, error) {
+	var owner *uint
+	var title string
+	var acquiredFrom uint
+	switch kind {
+	case models.TemplateKindWorkout:
+		t, err := s.templateRepo.FindWorkoutTemplateByID(ctx, templateID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", ErrCoachTemplateNotFound
+			}
+			return "", err
+		}
+		owner, title, acquiredFrom = t.CoachID, t.Title, t.AcquiredListingID
+	default:
+		t, err := s.templateRepo.FindNutritionTemplateByID(ctx, templateID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", ErrCoachTemplateNotFound
+			}
+			return "", err
+		}
+		owner, title, acquiredFrom = t.CoachID, t.Title, t.AcquiredListingID
+	}
+	if owner == nil || *owner != coachID {
+		return "", ErrCoachTemplateForbidden
+	}
+	if acquiredFrom != 0 {
+		return "", ErrListingResale
+	}
+	return title, nil
+}
+
+func (s *templateMarketplaceService) authorName(ctx context.Context, id uint, cache map[uint]string) string {
+	if name, ok := cache[id]; ok {
+		return name
+	}
+	name := ""
+	if u, err := s.userRepo.FindByID(ctx, id); err == nil && u != nil {
+		name = strings.TrimSpace(u.Name)
+	}
+	cache[id] = name
+	return name
+}
+
+func (s *templateMarketplaceService) toDTO(ctx context.Context, l *models.TemplateListing, viewerID uint, names map[uint]string) TemplateListingDTO {
+	return TemplateListingDTO{
+		ID:          l.ID,
+		Kind:        l.TemplateKind,
+		TemplateID:  l.TemplateID,
+		AuthorID:    l.AuthorID,
+		AuthorName:  s.authorName(ctx, l.AuthorID, names),
+		Title:       l.Title,
+		Description: l.Description,
+		Price:       l.PriceCents,
+		IsFree:      l.PriceCents <= 0,
+		Status:      l.Status,
+		ReviewNote:  l.ReviewNote,
+		ReviewedAt:  l.ReviewedAt,
+		IsMine:      viewerID > 0 && l.AuthorID == viewerID,
+		CreatedAt:   l.CreatedAt,
+	}
+}
+
+func (s *templateMarketplaceService) toDetail(ctx context.Context, l *models.TemplateListing, viewerID uint) (*TemplateListingDetail, error) {
+	detail := &TemplateListingDetail{TemplateListingDTO: s.toDTO(ctx, l, viewerID, map[uint]string{})}
+	switch l.TemplateKind {
+	case models.TemplateKindWorkout:
+		t, err := s.templateRepo.FindWorkoutTemplateByID(ctx, l.TemplateID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return detail, nil
+			}
+			return nil, err
+		}
+		detail.Preview = workoutListingPreview(t)
+	case models.TemplateKindNutrition:
+		t, err := s.templateRepo.FindNutritionTemplateByID(ctx, l.TemplateID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return detail, nil
+			}
+			return nil, err
+		}
+		detail.Preview = nutritionListingPreview(t)
+	}
+	return detail, nil
+}
+
+func workoutListingPreview(t *models.WorkoutTemplate) *TemplateListingPreview {
+	p := &TemplateListingPreview{
+		Type:          t.Type,
+		Gender:        t.Gender,
+		Target:        t.Target,
+		Level:         t.Level,
+		Location:      t.Location,
+		DayCount:      t.DayCount,
+		ExerciseCount: len(t.Items),
+	}
+	dayIndex := map[int]int{}
+	for _, it := range t.Items {
+		idx, ok := dayIndex[it.DayNumber]
+		if !ok {
+			idx = len(p.Days)
+			dayIndex[it.DayNumber] = idx
+			p.Days = append(p.Days, TemplateListingPreviewDay{DayNumber: it.DayNumber})
+		}
+		p.Days[idx].Exercises = append(p.Days[idx].Exercises, it.Exercise)
+	}
+	return p
+}
+
+func nutritionListingPreview(t *models.NutritionTemplate) *TemplateListingPreview {
+	p := &TemplateListingPreview{
+		Type:    t.Type,
+		Gender:  t.Gender,
+		Target:  t.Target,
+		Calorie: t.Calorie,
+	}
+	for _, m := range t.Meals {
+		p.Meals = append(p.Meals, TemplateListingPreviewMeal{
+			MealName:    m.MealName,
+			MealCalorie: m.MealCalorie,
+			ItemCount:   len(m.Items),
+		})
+	}
+	return p
+}
+
+// acquireTemplateListing copies the listing's template into the buyer's library and
+// records the acquisition. db may be a transaction (the payment callback runs this
+// inside the order's fulfillment transaction).
+func acquireTemplateListing(ctx context.Context, db *gorm.DB, listing *models.TemplateListing, buyerID, orderID uint, price int64) (*models.TemplateAcquisition, error) {
+	templateRepo := repository.NewTemplateRepository(db)
+	var copyID uint
+	switch listing.TemplateKind {
+	case models.TemplateKindWorkout:
+		src, err := templateRepo.FindWorkoutTemplateByID(ctx, listing.TemplateID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrCoachTemplateNotFound
+			}
+			return nil, err
+		}
+		sourceID, err := templateRepo.NextManualWorkoutSourceID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t := cloneWorkoutTemplate(src, buyerID, sourceID)
+		t.AcquiredListingID = listing.ID
+		if err := templateRepo.CreateWorkoutTemplate(ctx, t); err != nil {
+			return nil, err
+		}
+		copyID = t.ID
+	case models.TemplateKindNutrition:
+		src, err := templateRepo.FindNutritionTemplateByID(ctx, listing.TemplateID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrCoachTemplateNotFound
+			}
+			return nil, err
+		}
+		sourceID, err := templateRepo.NextManualNutritionSourceID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t := cloneNutritionTemplate(src, buyerID, sourceID)
+		t.AcquiredListingID = listing.ID
+		if err := templateRepo.CreateNutritionTemplate(ctx, t); err != nil {
+			return nil, err
+		}
+		copyID = t.ID
+	default:
+		return nil, ErrListingInvalidKind
+	}
+
+	acq := &models.TemplateAcquisition{
+		ListingID:    listing.ID,
+		BuyerID:      buyerID,
+		AuthorID:     listing.AuthorID,
+		TemplateKind: listing.TemplateKind,
+		TemplateID:   copyID,
+		OrderID:      orderID,
+		PriceCents:   price,
+	}
+	if err := db.WithContext(ctx).Create(acq).Error; err != nil {
+		return nil, err
+	}
+	return acq, nil
+}
+
+func notifyListingAcquired(ctx context.Context, db *gorm.DB, listing *models.TemplateListing) {
+	n := &models.Notification{
+		UserID:  listing.AuthorID,
+		Type:    models.NotificationTypeMarketplace,
+		Title:   "قالب شما دریافت شد",
+		Message: fmt.Sprintf("یک مربی «%s» را به کتابخانه خود اضافه کرد.", listing.Title),
+	}
+	if err := db.WithContext(ctx).Create(n).Error; err != nil {
+		log.Printf("notify: marketplace acquisition notification failed listing=%d err=%v", listing.ID, err)
+	}
+}
+
+func cloneWorkoutTemplate(src *models.WorkoutTemplate, coachID uint, sourceID int) *models.WorkoutTemplate {
+	owner := coachID
+	t := &models.WorkoutTemplate{
+		SourceID: sourceID,
+		Title:    src.Title,
+		Type:     src.Type,
+		Gender:   src.Gender,
+		Location: src.Location,
+		DayCount: src.DayCount,
+		Target:   src.Target,
+		Injury:   src.Injury,
+		Level:    src.Level,
+		CoachID:  &owner,
+		Items:    make([]models.TemplateProgramItem, 0, len(src.Items)),
+	}
+	for _, it := range src.Items {
+		item := models.TemplateProgramItem{
+			DayNumber:         it.DayNumber,
+			OrderIndex:        it.OrderIndex,
+			ExerciseID:        it.ExerciseID,
+			Exercise:          it.Exercise,
+			Notes:             it.Notes,
+			SupersetID:        it.SupersetID,
+			WorkoutSystemType: it.WorkoutSystemType,
+		}
+		for _, st := range it.SetsDetails {
+			item.SetsDetails = append(item.SetsDetails, models.TemplateProgramItemSet{
+				SetNumber: st.SetNumber,
+				SetType:   st.SetType,
+				Reps:      st.Reps,
+				IsAMRAP:   st.IsAMRAP,
+				SetHash:   st.SetHash,
+			})
+		}
+		t.Items = append(t.Items, item)
+	}
+	return t
+}
+
+func cloneNutritionTemplate(src *models.NutritionTemplate, coachID uint, sourceID int) *models.NutritionTemplate {
+	owner := coachID
+	t := &models.NutritionTemplate{
+		SourceID:    sourceID,
+		Title:       src.Title,
+		Type:        src.Type,
+		Gender:      src.Gender,
+		Target:      src.Target,
+		Limitation:  src.Limitation,
+		Calorie:     src.Calorie,
+		Description: src.Description,
+		IsPro:       src.IsPro,
+		Version:     src.Version,
+		CoachID:     &owner,
+		Meals:       make([]models.TemplateMeal, 0, len(src.Meals)),
+	}
+	for _, m := range src.Meals {
+		meal := models.TemplateMeal{
+			MealOrder:   m.MealOrder,
+			MealName:    m.MealName,
+			MealCalorie: m.MealCalorie,
+			StartTime:   m.StartTime,
+			EndTime:     m.EndTime,
+		}
+		for _, it := range m.Items {
+			meal.Items = append(meal.Items, models.TemplateMealItem{
+				MenuName:    it.MenuName,
+				OrderIndex:  it.OrderIndex,
+				FoodID:      it.FoodID,
+				FoodName:    it.FoodName,
+				FoodImage:   it.FoodImage,
+				Unit:        it.Unit,
+				Value:       it.Value,
+				Description: it.Description,
+			})
+		}
+		t.Meals = append(t.Meals, meal)
+	}
+	return t
+}
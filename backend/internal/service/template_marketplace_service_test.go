@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+// stubListingPayments hands out a payment for every listing order instead of
+// calling ZarinPal.
+type stubListingPayments struct {
+	PaymentService
+	orders []uint
+}
+
+func (p *stubListingPayments) RequestZarinpalForOrder(_ context.Context, _, orderID uint) (*ZarinpalPaymentResponse, error) {
+	p.orders = append(p.orders, orderID)
+	return &ZarinpalPaymentResponse{OrderID: orderID, Authority: fmt.Sprintf("A%d", orderID)}, nil
+}
+
+type marketplaceFixture struct {
+	db        *gorm.DB
+	market    TemplateMarketplaceService
+	templates CoachTemplateService
+	payments  *stubListingPayments
+}
+
+func newMarketplaceFixture(t *testing.T) *marketplaceFixture {
+	t.Helper()
+	db, err := config.NewSQLiteGORM(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models.AllModels()...); err != nil {
+		t.Fatal(err)
+	}
+	templateRepo := repository.NewTemplateRepository(db)
+	listingRepo := repository.NewTemplateListingRepository(db)
+	payments := &stubListingPayments{}
+	return &marketplaceFixture{
+		db:     db,
+		market: NewTemplateMarketplaceService(db, listingRepo, templateRepo, repository.NewUserRepository(db), payments),
+		templates: NewCoachTemplateService(templateRepo, listingRepo, repository.NewExerciseRepository(db),
+			nil, nil, nil),
+		payments: payments,
+	}
+}
+
+func (f *marketplaceFixture) coach(t *testing.T, n int) uint {
+	t.Helper()
+	u := &models.User{Name: fmt.Sprintf("coach %d", n), Email: fmt.Sprintf("coach%d@test.local", n),
+		Phone: fmt.Sprintf("0912000%04d", n), Password: "x", Role: models.RoleCoach}
+	if err := f.db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u.ID
+}
+
+func (f *marketplaceFixture) workout(t *testing.T, coachID uint, title string) uint {
+	t.Helper()
+	detail, err := f.templates.CreateWorkoutTemplate(context.Background(), coachID, &AdminWorkoutTemplateUpsertRequest{
+		Title: title,
+		Items: []AdminTemplateItemDTO{
+			{DayNumber: 1, OrderIndex: 1, Exercise: "Squat", SetsDetails: []AdminTemplateSetDTO{{SetNumber: 1, Reps: "8"}}},
+			{DayNumber: 2, OrderIndex: 1, Exercise: "Bench press", SetsDetails: []AdminTemplateSetDTO{{SetNumber: 1, Reps: "10"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return detail.ID
+}
+
+func (f *marketplaceFixture) listingStatus(t *testing.T, id uint) string {
+	t.Helper()
+	var l models.TemplateListing
+	if err := f.db.First(&l, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return l.Status
+}
+
+func TestTemplateMarketplaceFreeListing(t *testing.T) {
+	ctx := context.Background()
+	f := newMarketplaceFixture(t)
+	author, buyer, admin := f.coach(t, 1), f.coach(t, 2), f.coach(t, 3)
+	templateID := f.workout(t, author, "Push pull legs")
+
+	if _, err := f.market.Publish(ctx, buyer, &TemplateListingUpsertRequest{Kind: models.TemplateKindWorkout, TemplateID: templateID}); !errors.Is(err, ErrCoachTemplateForbidden) {
+		t.Fatalf("publishing another coach's template: %v", err)
+	}
+	listing, err := f.market.Publish(ctx, author, &TemplateListingUpsertRequest{Kind: models.TemplateKindWorkout, TemplateID: templateID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listing.Status != models.TemplateListingStatusPending || listing.Title != "Push pull legs" {
+		t.Fatalf("new listing %+v", listing)
+	}
+	if _, err := f.market.Acquire(ctx, buyer, listing.ID); !errors.Is(err, ErrListingNotFound) {
+		t.Fatalf("acquiring a pending listing: %v", err)
+	}
+	if _, err := f.market.Approve(ctx, admin, listing.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if page, err := f.market.Browse(ctx, buyer, 1, 20, "", ""); err != nil || page.Total != 1 {
+		t.Fatalf("browse after approval: %+v (%v)", page, err)
+	}
+
+	// Changing the template after approval needs another review.
+	if _, err := f.templates.UpdateWorkoutTemplate(ctx, author, templateID, &AdminWorkoutTemplateUpsertRequest{Title: "Push pull legs v2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.listingStatus(t, listing.ID); got != models.TemplateListingStatusPending {
+		t.Fatalf("listing %s after the template was edited, want pending", got)
+	}
+	if _, err := f.market.Approve(ctx, admin, listing.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired, err := f.market.Acquire(ctx, buyer, listing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired.Acquired || acquired.TemplateID == 0 || acquired.TemplateID == templateID {
+		t.Fatalf("acquire %+v", acquired)
+	}
+	if _, err := f.market.Acquire(ctx, buyer, listing.ID); !errors.Is(err, ErrListingAlreadyAcquired) {
+		t.Fatalf("second acquire: %v", err)
+	}
+	if _, err := f.market.Acquire(ctx, author, listing.ID); !errors.Is(err, ErrListingOwnTemplate) {
+		t.Fatalf("author acquiring own listing: %v", err)
+	}
+	var copied models.WorkoutTemplate
+	if err := f.db.Preload("Items").First(&copied, acquired.TemplateID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if copied.CoachID == nil || *copied.CoachID != buyer || len(copied.Items) != 2 || copied.AcquiredListingID != listing.ID {
+		t.Fatalf("buyer's copy %+v", copied)
+	}
+
+	// Neither the bought copy nor a duplicate of it can be resold.
+	if _, err := f.market.Publish(ctx, buyer, &TemplateListingUpsertRequest{Kind: models.TemplateKindWorkout, TemplateID: copied.ID}); !errors.Is(err, ErrListingResale) {
+		t.Fatalf("listing an acquired copy: %v", err)
+	}
+	dup, err := f.templates.DuplicateWorkoutTemplate(ctx, buyer, copied.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.market.Publish(ctx, buyer, &TemplateListingUpsertRequest{Kind: models.TemplateKindWorkout, TemplateID: dup.ID}); !errors.Is(err, ErrListingResale) {
+		t.Fatalf("listing a duplicate of an acquired copy: %v", err)
+	}
+
+	if err := f.templates.DeleteWorkoutTemplate(ctx, author, templateID); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.listingStatus(t, listing.ID); got != models.TemplateListingStatusUnlisted {
+		t.Fatalf("listing %s after the template was deleted, want unlisted", got)
+	}
+}
+
+func TestTemplateMarketplacePaidListing(t *testing.T) {
+	ctx := context.Background()
+	f := newMarketplaceFixture(t)
+	author, buyer, admin := f.coach(t, 1), f.coach(t, 2), f.coach(t, 3)
+	listing, err := f.market.Publish(ctx, author, &TemplateListingUpsertRequest{
+		Kind: models.TemplateKindWorkout, TemplateID: f.workout(t, author, "Hypertrophy"), Price: 150_000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.market.Approve(ctx, admin, listing.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	started, err := f.market.Acquire(ctx, buyer, listing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.Acquired || started.Payment == nil || len(f.payments.orders) != 1 {
+		t.Fatalf("paid acquire %+v", started)
+	}
+	// Checking out again resumes the pending order instead of opening another.
+	if _, err := f.market.Acquire(ctx, buyer, listing.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.payments.orders) != 2 || f.payments.orders[1] != f.payments.orders[0] {
+		t.Fatalf("second checkout paid orders %v, want the first order again", f.payments.orders)
+	}
+	var order models.Order
+	if err := f.db.First(&order, f.payments.orders[0]).Error; err != nil {
+		t.Fatal(err)
+	}
+	var item models.OrderItem
+	if err := f.db.Where("order_id = ?", order.ID).First(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	if order.CoachID != author || order.TotalAmountCents != 150_000 || item.TemplateListingID != listing.ID {
+		t.Fatalf("listing order %+v item %+v", order, item)
+	}
+
+	// The payment callback copies the template once, however often it runs.
+	payments := &paymentService{db: f.db}
+	for i := 0; i < 2; i++ {
+		if err := payments.fulfillTemplateOrder(ctx, &order, &item, "A1", "R1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var acquisitions []models.TemplateAcquisition
+	if err := f.db.Where("listing_id = ?", listing.ID).Find(&acquisitions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(acquisitions) != 1 || acquisitions[0].BuyerID != buyer || acquisitions[0].OrderID != order.ID || acquisitions[0].PriceCents != 150_000 {
+		t.Fatalf("acquisitions %+v", acquisitions)
+	}
+	if _, err := f.market.Acquire(ctx, buyer, listing.ID); !errors.Is(err, ErrListingAlreadyAcquired) {
+		t.Fatalf("acquire after paying: %v", err)
+	}
+
+	// A second order paid for the same listing (opened before checkouts were
+	// deduplicated) delivers nothing and waits for a refund.
+	dup := models.Order{UserID: buyer, CoachID: author, Status: "pending", PaymentMethod: "x", TrackingCode: "DUP", TotalAmountCents: 150_000}
+	if err := f.db.Create(&dup).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := payments.fulfillTemplateOrder(ctx, &dup, &item, "A2", "R2"); err != nil {
+		t.Fatal(err)
+	}
+	var txn models.Transaction
+	if err := f.db.First(&dup, dup.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := f.db.Where("order_id = ?", dup.ID).First(&txn).Error; err != nil {
+		t.Fatal(err)
+	}
+	if dup.Status != orderStatusRefundPending || txn.Status != orderStatusRefundPending {
+		t.Fatalf("duplicate order %s with transaction %s, want %s", dup.Status, txn.Status, orderStatusRefundPending)
+	}
+
+	report, err := f.market.Report(ctx, author)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalAcquisitions != 1 || report.TotalRevenue != 150_000 {
+		t.Fatalf("author report %+v", report)
+	}
+}
@@ -0,0 +1,710 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+var ErrNoTemplateSuggestion = errors.New("no suitable template found for this student")
+
+const (
+	defaultSuggestionLimit = 5
+	maxExcludedSuggestions = 20
+)
+
+// TemplateMatchReason explains one scoring criterion. Points may be negative.
+type TemplateMatchReason struct {
+	Criterion string `json:"criterion"` // gender | goal | level | location | injury | days | limitation | calorie | meals
+	Matched   bool   `json:"matched"`
+	Points    int    `json:"points"`
+	Note      string `json:"note"`
+}
+
+type WorkoutTemplateSuggestion struct {
+	Template WorkoutTemplateSummary `json:"template"`
+	Score    int                    `json:"score"`
+	Reasons  []TemplateMatchReason  `json:"reasons"`
+}
+
+type NutritionTemplateSuggestion struct {
+	Template NutritionTemplateSummary `json:"template"`
+	Score    int                      `json:"score"`
+	Reasons  []TemplateMatchReason    `json:"reasons"`
+}
+
+// ExcludedTemplate is a template removed by a hard rule (injury conflict).
+type ExcludedTemplate struct {
+	ID     uint   `json:"id"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// TemplateSuggestionProfile is the normalized view of the student used for matching.
+type TemplateSuggestionProfile struct {
+	Gender             string   `json:"gender,omitempty"`
+	Goal               string   `json:"goal,omitempty"`
+	Level              string   `json:"level,omitempty"`
+	Location           string   `json:"location,omitempty"`
+	ActivityLevel      string   `json:"activityLevel,omitempty"`
+	Commitment         string   `json:"commitment,omitempty"`
+	NutritionChallenge string   `json:"nutritionChallenge,omitempty"`
+	InjuryRegions      []string `json:"injuryRegions"`
+	Limitations        []string `json:"limitations"`
+	EstimatedCalories  int      `json:"estimatedCalories,omitempty"`
+	FromFunnel         bool     `json:"fromFunnel"`
+}
+
+type TemplateSuggestionsResponse struct {
+	StudentID     uint                          `json:"studentId"`
+	Profile       TemplateSuggestionProfile     `json:"profile"`
+	Workout       []WorkoutTemplateSuggestion   `json:"workout"`
+	Nutrition     []NutritionTemplateSuggestion `json:"nutrition"`
+	Excluded      []ExcludedTemplate            `json:"excluded"`
+	ExcludedCount int                           `json:"excludedCount"`
+}
+
+// TemplateSuggestionService ranks the templates a coach can use against a student's
+// profile (and funnel answers when the student came through the funnel).
+type TemplateSuggestionService interface {
+	Suggest(ctx context.Context, coachID, studentID uint, limit int) (*TemplateSuggestionsResponse, error)
+	// AssignTop assigns the best-scoring workout or nutrition template via the regular
+	// assign-from-template flow.
+	AssignTop(ctx context.Context, coachID, studentID uint, kind string) (*CoachStudentProgramsResponse, error)
+}
+
+type templateSuggestionService struct {
+	templateRepo    repository.TemplateRepository
+	exerciseRepo    repository.ExerciseRepository
+	userRepo        repository.UserRepository
+	funnelRepo      repository.FunnelLeadRepository
+	coachStudentSvc CoachStudentService
+	programSvc      CoachProgramService
+}
+
+func NewTemplateSuggestionService(
+	templateRepo repository.TemplateRepository,
+	exerciseRepo repository.ExerciseRepository,
+	userRepo repository.UserRepository,
+	funnelRepo repository.FunnelLeadRepository,
+	coachStudentSvc CoachStudentService,
+	programSvc CoachProgramService,
+) TemplateSuggestionService {
+	return &templateSuggestionService{
+		templateRepo:    templateRepo,
+		exerciseRepo:    exerciseRepo,
+		userRepo:        userRepo,
+		funnelRepo:      funnelRepo,
+		coachStudentSvc: coachStudentSvc,
+		programSvc:      programSvc,
+	}
+}
+
+func (s *templateSuggestionService) Suggest(ctx context.Context, coachID, studentID uint, limit int) (*TemplateSuggestionsResponse, error) {
+	if limit <= 0 {
+		limit = defaultSuggestionLimit
+	}
+	ok, err := s.coachStudentSvc.CanAccessStudent(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCoachStudentForbidden
+	}
+	user, err := s.userRepo.FindByID(ctx, studentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoachStudentForbidden
+		}
+		return nil, err
+	}
+	var lead *models.FunnelLead
+	if s.funnelRepo != nil && strings.TrimSpace(user.Phone) != "" {
+		if l, err := s.funnelRepo.FindLatestByPhone(ctx, user.Phone); err == nil {
+			lead = l
+		}
+	}
+	profile := buildSuggestionProfile(user, lead)
+	filter := coachTemplateFilter(coachID, "", "all")
+
+	resp := &TemplateSuggestionsResponse{
+		StudentID: studentID,
+		Profile:   profile,
+		Workout:   []WorkoutTemplateSuggestion{},
+		Nutrition: []NutritionTemplateSuggestion{},
+		Excluded:  []ExcludedTemplate{},
+	}
+
+	workouts, err := s.templateRepo.ListWorkoutTemplates(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	loads, err := s.workoutRegionLoads(ctx, workouts, profile.InjuryRegions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range workouts {
+		t := &workouts[i]
+		if conflict := injuryConflict(profile.InjuryRegions, t.Injury, loads[t.ID]); conflict != "" {
+			resp.ExcludedCount++
+			if len(resp.Excluded) < maxExcludedSuggestions {
+				resp.Excluded = append(resp.Excluded, ExcludedTemplate{
+					ID:     t.ID,
+					Title:  t.Title,
+					Reason: fmt.Sprintf("تمرینات این قالب به ناحیه آسیب‌دیده (%s) فشار می‌آورد", injuryRegionLabels[conflict]),
+				})
+			}
+			continue
+		}
+		score, reasons := scoreWorkoutTemplate(profile, t)
+		resp.Workout = append(resp.Workout, WorkoutTemplateSuggestion{
+			Template: WorkoutTemplateSummary{
+				ID: t.ID, CoachID: t.CoachID, IsCustom: t.CoachID != nil,
+				Title: t.Title, Type: t.Type, Gender: t.Gender,
+				Location: t.Location, DayCount: t.DayCount, Target: t.Target,
+				Level: t.Level, Injury: t.Injury,
+			},
+			Score:   score,
+			Reasons: reasons,
+		})
+	}
+	sort.SliceStable(resp.Workout, func(i, j int) bool { return resp.Workout[i].Score > resp.Workout[j].Score })
+	if len(resp.Workout) > limit {
+		resp.Workout = resp.Workout[:limit]
+	}
+
+	nutritions, err := s.templateRepo.ListNutritionTemplates(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(nutritions))
+	for _, t := range nutritions {
+		ids = append(ids, t.ID)
+	}
+	mealCounts, err := s.templateRepo.CountNutritionTemplateMeals(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nutritions {
+		t := &nutritions[i]
+		score, reasons := scoreNutritionTemplate(profile, t, mealCounts[t.ID])
+		resp.Nutrition = append(resp.Nutrition, NutritionTemplateSuggestion{
+			Template: NutritionTemplateSummary{
+				ID: t.ID, CoachID: t.CoachID, IsCustom: t.CoachID != nil,
+				Title: t.Title, Type: t.Type, Gender: t.Gender,
+				Target: t.Target, Limitation: t.Limitation, Calorie: t.Calorie,
+			},
+			Score:   score,
+			Reasons: reasons,
+		})
+	}
+	sort.SliceStable(resp.Nutrition, func(i, j int) bool { return resp.Nutrition[i].Score > resp.Nutrition[j].Score })
+	if len(resp.Nutrition) > limit {
+		resp.Nutrition = resp.Nutrition[:limit]
+	}
+	return resp, nil
+}
+
+func (s *templateSuggestionService) AssignTop(ctx context.Context, coachID, studentID uint, kind string) (*CoachStudentProgramsResponse, error) {
+	suggestions, err := s.Suggest(ctx, coachID, studentID, 1)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case models.TemplateKindWorkout:
+		if len(suggestions.Workout) == 0 {
+			return nil, ErrNoTemplateSuggestion
+		}
+		return s.programSvc.AssignWorkoutFromTemplate(ctx, coachID, studentID, suggestions.Workout[0].Template.ID)
+	case models.TemplateKindNutrition:
+		if len(suggestions.Nutrition) == 0 {
+			return nil, ErrNoTemplateSuggestion
+		}
+		return s.programSvc.AssignNutritionFromTemplate(ctx, coachID, studentID, suggestions.Nutrition[0].Template.ID)
+	default:
+		return nil, ErrListingInvalidKind
+	}
+}
+
+// workoutRegionLoads maps template ID → injury regions its exercises load. Only computed
+// for the regions the student actually reports, so healthy students skip the item scan.
+func (s *templateSuggestionService) workoutRegionLoads(ctx context.Context, templates []models.WorkoutTemplate, regions []string) (map[uint]map[string]bool, error) {
+	out := map[uint]map[string]bool{}
+	if len(regions) == 0 || len(templates) == 0 {
+		return out, nil
+	}
+	ids := make([]uint, 0, len(templates))
+	for _, t := range templates {
+		ids = append(ids, t.ID)
+	}
+	items, err := s.templateRepo.ListWorkoutTemplateItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	exerciseIDs := make([]uint, 0, len(items))
+	seen := map[uint]bool{}
+	for _, it := range items {
+		if it.ExerciseID != nil && *it.ExerciseID > 0 && !seen[*it.ExerciseID] {
+			seen[*it.ExerciseID] = true
+			exerciseIDs = append(exerciseIDs, *it.ExerciseID)
+		}
+	}
+	exercises := map[uint]models.Exercise{}
+	if len(exerciseIDs) > 0 {
+		list, err := s.exerciseRepo.FindByIDs(ctx, exerciseIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range list {
+			exercises[e.ID] = e
+		}
+	}
+	for _, it := range items {
+		var ex *models.Exercise
+		if it.ExerciseID != nil {
+			if e, ok := exercises[*it.ExerciseID]; ok {
+				ex = &e
+			}
+		}
+		for _, region := range regions {
+			if exerciseLoadsRegion(region, it.Exercise, ex) {
+				if out[it.WorkoutTemplateID] == nil {
+					out[it.WorkoutTemplateID] = map[string]bool{}
+				}
+				out[it.WorkoutTemplateID][region] = true
+			}
+		}
+	}
+	return out, nil
+}
+
+func buildSuggestionProfile(user *models.User, lead *models.FunnelLead) TemplateSuggestionProfile {
+	p := TemplateSuggestionProfile{
+		Gender:        normalizeSuggestionGender(user.Gender),
+		Goal:          normalizeSuggestionGoal(user.PrimaryGoal),
+		InjuryRegions: detectInjuryRegions(user.Injuries + " " + user.PhysicalLimitations),
+		Limitations:   detectDietLimitations(user.PhysicalLimitations + " " + user.MedicalHistory),
+	}
+	if p.Goal == "" {
+		for _, g := range user.GetGoals() {
+			if p.Goal = normalizeSuggestionGoal(g); p.Goal != "" {
+				break
+			}
+		}
+	}
+	if lead != nil {
+		p.FromFunnel = true
+		p.Location = normalizeSuggestionLocation(lead.TrainingEnv)
+		p.Level = normalizeSuggestionLevel(lead.Experience)
+		p.ActivityLevel = strings.TrimSpace(lead.ActivityLevel)
+		p.Commitment = strings.TrimSpace(lead.Commitment)
+		p.NutritionChallenge = strings.TrimSpace(lead.NutritionChallenge)
+		if p.Goal == "" {
+			p.Goal = normalizeSuggestionGoal(lead.PrimaryGoal)
+		}
+	}
+	p.EstimatedCalories = estimateSuggestionCalories(user, p.Goal, p.ActivityLevel)
+	return p
+}
+
+// estimateSuggestionCalories uses the nutrition calculator when the profile allows it
+// and otherwise falls back to a coarse kcal/kg estimate; 0 when weight is unknown.
+func estimateSuggestionCalories(user *models.User, goal, activity string) int {
+	if user.WeightKg == nil || *user.WeightKg <= 0 {
+		return 0
+	}
+	req := &NutritionCalculatorRequest{
+		Gender:         user.Gender,
+		HeightCm:       user.HeightCm,
+		WeightKg:       user.WeightKg,
+		BodyFatPercent: user.BodyFatPercent,
+		ActivityLevel:  activity,
+		Goal:           goal,
+	}
+	if user.BirthDate != nil {
+		req.Age = ageOn(*user.BirthDate, time.Now())
+	}
+	if res, err := CalculateNutritionTargets(req); err == nil {
+		return res.TargetCalories
+	}
+	perKg := 30.0
+	switch activity {
+	case "sedentary":
+		perKg = 28
+	case "active":
+		perKg = 35
+	}
+	kcal := *user.WeightKg * perKg
+	switch goal {
+	case "weight_loss":
+		kcal -= 500
+	case "muscle_gain":
+		kcal += 300
+	}
+	return int(kcal)
+}
+
+func scoreWorkoutTemplate(p TemplateSuggestionProfile, t *models.WorkoutTemplate) (int, []TemplateMatchReason) {
+	reasons := []TemplateMatchReason{
+		genderReason(p.Gender, t.Gender),
+		goalReason(p.Goal, t.Target),
+	}
+
+	level := normalizeSuggestionLevel(t.Level)
+	switch {
+	case p.Level == "" || level == "":
+		reasons = append(reasons, TemplateMatchReason{Criterion: "level", Points: 8, Note: "سطح شاگرد یا قالب مشخص نیست"})
+	case p.Level == level:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "level", Matched: true, Points: 20, Note: "سطح قالب با تجربه شاگرد یکسان است"})
+	case levelDistance(p.Level, level) == 1:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "level", Points: 8, Note: "سطح قالب یک درجه با تجربه شاگرد فاصله دارد"})
+	default:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "level", Points: -10, Note: "سطح قالب با تجربه شاگرد فاصله زیادی دارد"})
+	}
+
+	location := normalizeSuggestionLocation(t.Location)
+	switch {
+	case p.Location == "":
+		reasons = append(reasons, TemplateMatchReason{Criterion: "location", Points: 10, Note: "محل تمرین شاگرد مشخص نیست"})
+	case location == "":
+		reasons = append(reasons, TemplateMatchReason{Criterion: "location", Matched: true, Points: 12, Note: "قالب برای هر محل تمرینی مناسب است"})
+	case location == p.Location:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "location", Matched: true, Points: 20, Note: "محل تمرین قالب با شاگرد یکسان است"})
+	default:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "location", Points: -20, Note: "قالب برای محل تمرین دیگری طراحی شده است"})
+	}
+
+	templateRegions := detectInjuryRegions(t.Injury)
+	switch {
+	case len(p.InjuryRegions) == 0 && len(templateRegions) == 0:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "injury", Matched: true, Points: 10, Note: "آسیب‌دیدگی گزارش نشده است"})
+	case len(p.InjuryRegions) == 0:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "injury", Points: 0, Note: "قالب برای شرایط آسیب‌دیدگی طراحی شده است"})
+	case regionsOverlap(p.InjuryRegions, templateRegions):
+		reasons = append(reasons, TemplateMatchReason{Criterion: "injury", Matched: true, Points: 10, Note: "قالب برای آسیب‌دیدگی شاگرد طراحی شده است"})
+	default:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "injury", Matched: true, Points: 5, Note: "تمرینات قالب به ناحیه آسیب‌دیده فشار نمی‌آورد"})
+	}
+
+	wantFewDays := p.Commitment == "flexible" || p.ActivityLevel == "sedentary"
+	wantManyDays := p.Commitment == "max_results"
+	switch {
+	case wantFewDays && t.DayCount > 0 && t.DayCount <= 3:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "days", Matched: true, Points: 5, Note: "تعداد جلسات کم با برنامه منعطف شاگرد سازگار است"})
+	case wantManyDays && t.DayCount >= 4:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "days", Matched: true, Points: 5, Note: "تعداد جلسات بالا با هدف حداکثر نتیجه سازگار است"})
+	case wantFewDays || wantManyDays:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "days", Points: 0, Note: "تعداد جلسات با تعهد زمانی شاگرد هم‌خوانی کامل ندارد"})
+	}
+	return sumReasonPoints(reasons), reasons
+}
+
+func scoreNutritionTemplate(p TemplateSuggestionProfile, t *models.NutritionTemplate, mealCount int) (int, []TemplateMatchReason) {
+	reasons := []TemplateMatchReason{
+		genderReason(p.Gender, t.Gender),
+	}
+	goal := goalReason(p.Goal, t.Target)
+	// Diet target weighs more than in training: it decides the calorie direction.
+	if goal.Points > 0 {
+		goal.Points += 5
+	}
+	reasons = append(reasons, goal)
+
+	templateLimits := detectDietLimitations(t.Limitation)
+	switch {
+	case len(templateLimits) == 0 && len(p.Limitations) == 0:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "limitation", Matched: true, Points: 20, Note: "محدودیت غذایی گزارش نشده است"})
+	case len(templateLimits) == 0:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "limitation", Points: 5, Note: "قالب برای محدودیت غذایی شاگرد تنظیم نشده است"})
+	case regionsOverlap(p.Limitations, templateLimits):
+		reasons = append(reasons, TemplateMatchReason{Criterion: "limitation", Matched: true, Points: 25, Note: "قالب برای محدودیت غذایی شاگرد تنظیم شده است"})
+	default:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "limitation", Points: 0, Note: "قالب برای محدودیت دیگری تنظیم شده است"})
+	}
+
+	switch diff := absInt(t.Calorie - p.EstimatedCalories); {
+	case p.EstimatedCalories == 0 || t.Calorie <= 0:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "calorie", Points: 8, Note: "کالری قابل مقایسه نیست (وزن شاگرد یا کالری قالب ثبت نشده)"})
+	case diff <= 150:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "calorie", Matched: true, Points: 20, Note: fmt.Sprintf("کالری قالب نزدیک به نیاز تخمینی شاگرد (%d) است", p.EstimatedCalories)})
+	case diff <= 300:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "calorie", Points: 12, Note: fmt.Sprintf("کالری قالب حدود %d کالری با نیاز تخمینی فاصله دارد", diff)})
+	case diff <= 500:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "calorie", Points: 5, Note: fmt.Sprintf("کالری قالب حدود %d کالری با نیاز تخمینی فاصله دارد", diff)})
+	default:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "calorie", Points: 0, Note: "کالری قالب با نیاز تخمینی شاگرد فاصله زیادی دارد"})
+	}
+
+	switch {
+	case p.NutritionChallenge == "no_time" && mealCount > 0 && mealCount <= 4:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "meals", Matched: true, Points: 5, Note: "تعداد وعده کم برای شاگرد کم‌وقت مناسب است"})
+	case p.NutritionChallenge == "low_appetite" && mealCount >= 5:
+		reasons = append(reasons, TemplateMatchReason{Criterion: "meals", Matched: true, Points: 5, Note: "وعده‌های بیشتر و کوچک‌تر برای اشتهای کم مناسب است"})
+	}
+	return sumReasonPoints(reasons), reasons
+}
+
+func genderReason(student, templateGender string) TemplateMatchReason {
+	g := normalizeSuggestionGender(templateGender)
+	switch {
+	case student == "" || g == "":
+		return TemplateMatchReason{Criterion: "gender", Matched: true, Points: 12, Note: "قالب محدود به جنسیت خاصی نیست"}
+	case student == g:
+		return TemplateMatchReason{Criterion: "gender", Matched: true, Points: 20, Note: "جنسیت قالب با شاگرد یکسان است"}
+	default:
+		return TemplateMatchReason{Criterion: "gender", Points: -30, Note: "قالب برای جنسیت دیگری طراحی شده است"}
+	}
+}
+
+func goalReason(student, target string) TemplateMatchReason {
+	g := normalizeSuggestionGoal(target)
+	switch {
+	case student == "":
+		return TemplateMatchReason{Criterion: "goal", Points: 8, Note: "هدف شاگرد ثبت نشده است"}
+	case g == "":
+		return TemplateMatchReason{Criterion: "goal", Points: 5, Note: "هدف قالب مشخص نیست"}
+	case student == g:
+		return TemplateMatchReason{Criterion: "goal", Matched: true, Points: 25, Note: "هدف قالب با هدف اصلی شاگرد یکسان است"}
+	default:
+		return TemplateMatchReason{Criterion: "goal", Points: -5, Note: "هدف قالب با هدف شاگرد متفاوت است"}
+	}
+}
+
+func sumReasonPoints(reasons []TemplateMatchReason) int {
+	total := 0
+	for _, r := range reasons {
+		total += r.Points
+	}
+	if total < 0 {
+		return 0
+	}
+	if total > 100 {
+		return 100
+	}
+	return total
+}
+
+// injuryConflict returns the first student injury region the template loads without
+// being designed for it ("" when the template is safe).
+func injuryConflict(studentRegions []string, templateInjury string, loads map[string]bool) string {
+	if len(studentRegions) == 0 {
+		return ""
+	}
+	designedFor := detectInjuryRegions(templateInjury)
+	for _, region := range studentRegions {
+		if !loads[region] {
+			continue
+		}
+		if slices.Contains(designedFor, region) {
+			continue
+		}
+		return region
+	}
+	return ""
+}
+
+var injuryRegionLabels = map[string]string{
+	"knee":     "زانو",
+	"back":     "کمر",
+	"shoulder": "شانه",
+	"wrist":    "مچ دست",
+	"neck":     "گردن",
+	"ankle":    "مچ پا",
+}
+
+// injuryRegionOrder fixes the output order of detected regions.
+var injuryRegionOrder = []string{"knee", "back", "shoulder", "wrist", "neck", "ankle"}
+
+// injuryRegionKeywords match free-text injuries (student profile, template Injury field).
+var injuryRegionKeywords = map[string][]string{
+	"knee":     {"زانو", "منیسک", "مینیسک", "رباط صلیبی", "knee", "acl", "menisc"},
+	"back":     {"کمر", "دیسک", "ستون فقرات", "مهره", "سیاتیک", "back", "spine", "lumbar", "disc", "sciatica"},
+	"shoulder": {"شانه", "کتف", "روتاتور", "shoulder", "rotator"},
+	"wrist":    {"مچ دست", "wrist", "carpal"},
+	"neck":     {"گردن", "neck", "cervical"},
+	"ankle":    {"مچ پا", "قوزک", "آشیل", "ankle", "achilles"},
+}
+
+// regionExerciseNameKeywords match exercise names that put load on a region.
+var regionExerciseNameKeywords = map[string][]string{
+	"knee":     {"اسکوات", "اسکات", "لانج", "لانژ", "پرس پا", "جلو پا", "هاک", "پرش", "squat", "lunge", "leg press", "leg extension", "jump", "step-up", "step up"},
+	"back":     {"ددلیفت", "لیفت مرده", "گود مورنینگ", "فیله کمر", "deadlift", "good morning", "hyperextension", "bent over row"},
+	"shoulder": {"سرشانه", "پرس بالای سر", "نشر", "دیپ", "پرس نظامی", "overhead", "military press", "lateral raise", "upright row", "dip", "shoulder press"},
+	"wrist":    {"مچ", "wrist"},
+	"neck":     {"گردن", "شراگ", "neck", "shrug"},
+	"ankle":    {"ساق", "طناب", "calf", "skip"},
+}
+
+// regionExerciseTargets match Exercise.Target / BodyPart values (English and Persian datasets).
+var regionExerciseTargets = map[string][]string{
+	"knee":     {"quads", "چهار"},
+	"back":     {"spine", "lower back", "ستون فقرات", "پایین کمر"},
+	"shoulder": {"delts", "shoulders", "دلت ها", "شانه ها"},
+	"wrist":    {"forearms", "lower arms", "ساعدها", "پایین بازوها"},
+	"neck":     {"neck", "levator scapulae", "گردن", "کتف بالابر"},
+	"ankle":    {"calves", "lower legs", "گوساله ها", "پایین پاها"},
+}
+
+var suggestionNegations = map[string]bool{
+	"": true, "-": true, "ندارم": true, "ندارد": true, "خیر": true, "نه": true, "هیچ": true,
+	"هیچکدام": true, "none": true, "no": true, "nothing": true,
+}
+
+func detectInjuryRegions(text string) []string {
+	return detectKeywordGroups(text, injuryRegionOrder, injuryRegionKeywords)
+}
+
+func exerciseLoadsRegion(region, name string, ex *models.Exercise) bool {
+	if containsAnyKeyword(strings.ToLower(name), regionExerciseNameKeywords[region]) {
+		return true
+	}
+	if ex == nil {
+		return false
+	}
+	if containsAnyKeyword(strings.ToLower(ex.Name), regionExerciseNameKeywords[region]) {
+		return true
+	}
+	fields := strings.ToLower(ex.Target + " " + ex.BodyPart)
+	return containsAnyKeyword(fields, regionExerciseTargets[region])
+}
+
+var dietLimitationOrder = []string{"diabetes", "lactose", "gluten", "vegetarian", "kidney", "hypertension", "pregnancy"}
+
+var dietLimitationKeywords = map[string][]string{
+	"diabetes":     {"دیابت", "قند خون", "انسولین", "diabet", "insulin"},
+	"lactose":      {"لاکتوز", "لبنیات", "lactose", "dairy"},
+	"gluten":       {"گلوتن", "سلیاک", "gluten", "celiac"},
+	"vegetarian":   {"گیاه", "vegan", "vegetarian"},
+	"kidney":       {"کلیه", "kidney", "renal"},
+	"hypertension": {"فشار خون", "hypertension", "blood pressure"},
+	"pregnancy":    {"بارداری", "شیردهی", "pregnan", "breastfeed"},
+}
+
+func detectDietLimitations(text string) []string {
+	return detectKeywordGroups(text, dietLimitationOrder, dietLimitationKeywords)
+}
+
+func detectKeywordGroups(text string, order []string, keywords map[string][]string) []string {
+	out := []string{}
+	t := strings.ToLower(strings.TrimSpace(text))
+	if suggestionNegations[t] {
+		return out
+	}
+	for _, key := range order {
+		if containsAnyKeyword(t, keywords[key]) {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+func containsAnyKeyword(text string, keywords []string) bool {
+	for _, kw := range keywords {
+		if strings.Contains(text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func regionsOverlap(a, b []string) bool {
+	for _, x := range a {
+		if slices.Contains(b, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSuggestionGender maps free-text gender ("آقایان", "female", "زنان و مردان")
+// to male | female, or "" when unspecified / both.
+func normalizeSuggestionGender(v string) string {
+	female, male := false, false
+	for _, tok := range strings.Fields(strings.ToLower(v)) {
+		switch {
+		case tok == "female" || strings.HasPrefix(tok, "زن") || strings.HasPrefix(tok, "بانو") ||
+			strings.HasPrefix(tok, "خانم") || strings.HasPrefix(tok, "دختر"):
+			female = true
+		case tok == "male" || strings.HasPrefix(tok, "مرد") || strings.HasPrefix(tok, "آقا") ||
+			strings.HasPrefix(tok, "پسر"):
+			male = true
+		}
+	}
+	switch {
+	case female && !male:
+		return models.GenderFemale
+	case male && !female:
+		return models.GenderMale
+	default:
+		return ""
+	}
+}
+
+// normalizeSuggestionGoal maps goal tags and template targets to weight_loss |
+// muscle_gain | fitness.
+func normalizeSuggestionGoal(v string) string {
+	t := strings.ToLower(strings.TrimSpace(v))
+	switch {
+	case t == "":
+		return ""
+	case containsAnyKeyword(t, []string{"weight_loss", "loss", "fat", "cut", "کاهش", "چربی", "لاغر", "کات"}):
+		return "weight_loss"
+	case containsAnyKeyword(t, []string{"muscle_gain", "gain", "bulk", "hypertroph", "mass", "strength", "حجم", "عضله", "افزایش", "هایپرتروفی", "قدرت"}):
+		return "muscle_gain"
+	case containsAnyKeyword(t, []string{"fitness", "endurance", "flexibility", "rehabilitation", "تناسب", "آمادگی", "سلامت", "عمومی", "استقامت"}):
+		return "fitness"
+	default:
+		return ""
+	}
+}
+
+func normalizeSuggestionLevel(v string) string {
+	t := strings.ToLower(strings.TrimSpace(v))
+	switch {
+	case t == "":
+		return ""
+	case containsAnyKeyword(t, []string{"beginner", "novice", "مبتدی", "تازه"}):
+		return "beginner"
+	case containsAnyKeyword(t, []string{"intermediate", "متوسط", "نیمه"}):
+		return "intermediate"
+	case containsAnyKeyword(t, []string{"advanced", "pro", "پیشرفته", "حرفه"}):
+		return "advanced"
+	default:
+		return ""
+	}
+}
+
+func levelDistance(a, b string) int {
+	rank := map[string]int{"beginner": 1, "intermediate": 2, "advanced": 3}
+	return absInt(rank[a] - rank[b])
+}
+
+func normalizeSuggestionLocation(v string) string {
+	t := strings.ToLower(strings.TrimSpace(v))
+	gym := containsAnyKeyword(t, []string{"gym", "باشگاه"})
+	home := containsAnyKeyword(t, []string{"home", "خانه", "منزل"})
+	switch {
+	case gym && !home:
+		return "gym"
+	case home && !gym:
+		return "home"
+	default:
+		return ""
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+func TestDetectInjuryRegions(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"ندارم", nil},
+		{"درد زانو و دیسک کمر", []string{"knee", "back"}},
+		{"Rotator cuff injury", []string{"shoulder"}},
+	}
+	for _, tc := range cases {
+		got := detectInjuryRegions(tc.text)
+		if len(got) != len(tc.want) {
+			t.Fatalf("detectInjuryRegions(%q)=%v want %v", tc.text, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("detectInjuryRegions(%q)=%v want %v", tc.text, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestInjuryConflictIsHardExclusion(t *testing.T) {
+	squat := &models.Exercise{Name: "اسکوات هالتر", Target: "چهار"}
+	loads := map[string]bool{}
+	if exerciseLoadsRegion("knee", squat.Name, squat) {
+		loads["knee"] = true
+	}
+	if got := injuryConflict([]string{"knee"}, "", loads); got != "knee" {
+		t.Fatalf("general template with squats should conflict with knee injury, got %q", got)
+	}
+	if got := injuryConflict([]string{"knee"}, "آسیب زانو", loads); got != "" {
+		t.Fatalf("knee-rehab template should not be excluded, got %q", got)
+	}
+	if got := injuryConflict(nil, "", loads); got != "" {
+		t.Fatalf("healthy student should never be excluded, got %q", got)
+	}
+}
+
+func TestNormalizeSuggestionGender(t *testing.T) {
+	cases := map[string]string{
+		"آقایان":       models.GenderMale,
+		"female":       models.GenderFemale,
+		"زنان و مردان": "",
+		"":             "",
+	}
+	for in, want := range cases {
+		if got := normalizeSuggestionGender(in); got != want {
+			t.Fatalf("normalizeSuggestionGender(%q)=%q want %q", in, got, want)
+		}
+	}
+}
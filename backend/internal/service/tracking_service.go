@@ -82,6 +82,7 @@ type CoachStudentTrackingDTO struct {
 	FullName       string             `json:"fullName"`
 	Phone          string             `json:"phone"`
 	TrackingStatus TrackingStatusDTO  `json:"tracking"`
+	ExerciseSwaps  *ExerciseSwapsResponse `json:"exerciseSwaps,omitempty"`
 }
 
 type TrackingService interface {
@@ -285,11 +286,17 @@ func (s *trackingService) GetCoachStudentTracking(ctx context.Context, coachID,
 	}
 	status.Alerts = coachAlertsFromStudent(status.Alerts, user.Name)
 
+	swaps, err := loadExerciseSwaps(ctx, repository.NewProgramRepository(s.db), repository.NewExerciseSwapRepository(s.db), sub)
+	if err != nil {
+		return nil, err
+	}
+
 	return &CoachStudentTrackingDTO{
 		StudentID:      studentID,
 		FullName:       user.Name,
 		Phone:          user.Phone,
 		TrackingStatus: *status,
+		ExerciseSwaps:  swaps,
 	}, nil
 }
 
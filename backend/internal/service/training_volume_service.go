@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+const (
+	defaultVolumeWeeks = 8
+	maxVolumeWeeks     = 26
+	// secondaryMuscleWeight is the fraction of a set credited to each secondary muscle.
+	secondaryMuscleWeight = 0.5
+	maxStrengthTrends     = 10
+	unmappedMuscle        = "نامشخص"
+)
+
+// Balance groups classify an exercise's primary Target (catalog labels are
+// Persian; English keys cover coach-created exercises).
+var (
+	pushMuscles = []string{"سینه ها", "دلت ها", "سه سر", "chest", "pectorals", "delts", "shoulders", "triceps"}
+	pullMuscles = []string{"قسمت بالایی پشت", "لات", "دوسر بازو", "تله ها", "ساعدها", "upper back", "lats", "biceps", "traps", "forearms"}
+	// upperOnlyMuscles are upper-body muscles that are neither push nor pull.
+	upperOnlyMuscles = []string{"سراتوس قدامی", "کتف بالابر", "serratus anterior", "levator scapulae"}
+	lowerMuscles     = []string{"باسن", "چهار", "همسترینگ", "گوساله ها", "آدم ربایان", "افزایش دهنده ها", "glutes", "quads", "hamstrings", "calves", "abductors", "adductors"}
+	coreMuscles      = []string{"عضلات شکم", "ستون فقرات", "abs", "spine"}
+)
+
+type MuscleWeekVolume struct {
+	Week      string  `json:"week"` // isoKey, e.g. 2026-07
+	WeekStart string  `json:"weekStart"`
+	Sets      float64 `json:"sets"`
+	TonnageKg float64 `json:"tonnageKg"`
+}
+
+// MuscleVolumeSeries is one muscle's weekly volume. Sets and tonnage credit the
+// primary target fully and each secondary muscle by secondaryMuscleWeight.
+type MuscleVolumeSeries struct {
+	Muscle         string             `json:"muscle"`
+	TotalSets      float64            `json:"totalSets"`
+	TotalTonnageKg float64            `json:"totalTonnageKg"`
+	Weekly         []MuscleWeekVolume `json:"weekly"`
+}
+
+type StrengthPoint struct {
+	Date     string  `json:"date"`
+	Est1RM   float64 `json:"est1rm"`
+	WeightKg float64 `json:"weightKg"`
+	Reps     int     `json:"reps"`
+}
+
+// ExerciseStrengthTrend is the best estimated 1RM per training day for one exercise.
+type ExerciseStrengthTrend struct {
+	ExerciseID    *uint           `json:"exerciseId,omitempty"`
+	ExerciseName  string          `json:"exerciseName"`
+	Points        []StrengthPoint `json:"points"`
+	ChangePercent float64         `json:"changePercent"`
+}
+
+// VolumeBalance compares primary-target hard sets across movement groups.
+type VolumeBalance struct {
+	PushSets        float64  `json:"pushSets"`
+	PullSets        float64  `json:"pullSets"`
+	PushPullRatio   float64  `json:"pushPullRatio,omitempty"`
+	UpperSets       float64  `json:"upperSets"`
+	LowerSets       float64  `json:"lowerSets"`
+	UpperLowerRatio float64  `json:"upperLowerRatio,omitempty"`
+	CoreSets        float64  `json:"coreSets"`
+	Notes           []string `json:"notes"`
+}
+
+type TrainingVolumeReport struct {
+	StudentID    uint                    `json:"studentId,omitempty"`
+	Weeks        []string                `json:"weeks"`
+	From         string                  `json:"from"`
+	To           string                  `json:"to"`
+	TotalSets    int                     `json:"totalSets"`
+	UnmappedSets int                     `json:"unmappedSets"`
+	Muscles      []MuscleVolumeSeries    `json:"muscles"`
+	Strength     []ExerciseStrengthTrend `json:"strength"`
+	Balance      VolumeBalance           `json:"balance"`
+}
+
+// TrainingVolumeService turns logged sets into per-muscle volume, strength trends and
+// movement balance for the student panel and the coach's view of a student.
+type TrainingVolumeService interface {
+	Report(ctx context.Context, userID uint, weeks int) (*TrainingVolumeReport, error)
+	StudentReport(ctx context.Context, coachID, studentID uint, weeks int) (*TrainingVolumeReport, error)
+}
+
+type trainingVolumeService struct {
+	db              *gorm.DB
+	exerciseRepo    repository.ExerciseRepository
+	coachStudentSvc CoachStudentService
+}
+
+func NewTrainingVolumeService(db *gorm.DB, exerciseRepo repository.ExerciseRepository, coachStudentSvc CoachStudentService) TrainingVolumeService {
+	return &trainingVolumeService{db: db, exerciseRepo: exerciseRepo, coachStudentSvc: coachStudentSvc}
+}
+
+func (s *trainingVolumeService) StudentReport(ctx context.Context, coachID, studentID uint, weeks int) (*TrainingVolumeReport, error) {
+	ok, err := s.coachStudentSvc.CanAccessStudent(ctx, coachID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCoachStudentForbidden
+	}
+	report, err := s.Report(ctx, studentID, weeks)
+	if err != nil {
+		return nil, err
+	}
+	report.StudentID = studentID
+	return report, nil
+}
+
+func (s *trainingVolumeService) Report(ctx context.Context, userID uint, weeks int) (*TrainingVolumeReport, error) {
+	if weeks <= 0 {
+		weeks = defaultVolumeWeeks
+	}
+	if weeks > maxVolumeWeeks {
+		weeks = maxVolumeWeeks
+	}
+	now := time.Now()
+	since := startOfISOWeek(now).AddDate(0, 0, -7*(weeks-1))
+
+	var logs []models.WorkoutSetLog
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND performed_at >= ? AND reps > 0", userID, since).
+		Order("performed_at ASC, id ASC").
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	exercises, err := s.resolveExercises(ctx, logs)
+	if err != nil {
+		return nil, err
+	}
+	return buildTrainingVolumeReport(logs, exercises, now, weeks), nil
+}
+
+// resolveExercises maps each log to its catalog exercise, by ExerciseID or, for
+// older logs without one, by exact name. Keys are log IDs.
+func (s *trainingVolumeService) resolveExercises(ctx context.Context, logs []models.WorkoutSetLog) (map[uint]*models.Exercise, error) {
+	idSet := map[uint]bool{}
+	nameSet := map[string]bool{}
+	for _, l := range logs {
+		if l.ExerciseID != nil && *l.ExerciseID > 0 {
+			idSet[*l.ExerciseID] = true
+		} else if n := strings.TrimSpace(l.ExerciseName); n != "" {
+			nameSet[n] = true
+		}
+	}
+	byID := map[uint]*models.Exercise{}
+	if len(idSet) > 0 {
+		ids := make([]uint, 0, len(idSet))
+		for id := range idSet {
+			ids = append(ids, id)
+		}
+		list, err := s.exerciseRepo.FindByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			byID[list[i].ID] = &list[i]
+		}
+	}
+	byName := map[string]*models.Exercise{}
+	if len(nameSet) > 0 {
+		names := make([]string, 0, len(nameSet))
+		for n := range nameSet {
+			names = append(names, n)
+		}
+		list, err := s.exerciseRepo.FindByNames(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			if _, ok := byName[list[i].Name]; !ok {
+				byName[list[i].Name] = &list[i]
+			}
+		}
+	}
+
+	out := make(map[uint]*models.Exercise, len(logs))
+	for _, l := range logs {
+		if l.ExerciseID != nil {
+			if ex, ok := byID[*l.ExerciseID]; ok {
+				out[l.ID] = ex
+				continue
+			}
+		}
+		if ex, ok := byName[strings.TrimSpace(l.ExerciseName)]; ok {
+			out[l.ID] = ex
+		}
+	}
+	return out, nil
+}
+
+func buildTrainingVolumeReport(logs []models.WorkoutSetLog, exercises map[uint]*models.Exercise, now time.Time, weeks int) *TrainingVolumeReport {
+	firstWeek := startOfISOWeek(now).AddDate(0, 0, -7*(weeks-1))
+	weekKeys := make([]string, 0, weeks)
+	weekStarts := make([]string, 0, weeks)
+	weekIndex := map[string]int{}
+	for i := 0; i < weeks; i++ {
+		start := firstWeek.AddDate(0, 0, 7*i)
+		y, w := start.ISOWeek()
+		key := isoKey(y, w)
+		weekIndex[key] = i
+		weekKeys = append(weekKeys, key)
+		weekStarts = append(weekStarts, start.Format("2006-01-02"))
+	}
+
+	report := &TrainingVolumeReport{
+		Weeks:    weekKeys,
+		From:     weekStarts[0],
+		To:       now.Format("2006-01-02"),
+		Muscles:  []MuscleVolumeSeries{},
+		Strength: []ExerciseStrengthTrend{},
+		Balance:  VolumeBalance{Notes: []string{}},
+	}
+
+	muscles := map[string]*MuscleVolumeSeries{}
+	credit := func(muscle string, week int, sets, tonnage float64) {
+		m, ok := muscles[muscle]
+		if !ok {
+			m = &MuscleVolumeSeries{Muscle: muscle, Weekly: make([]MuscleWeekVolume, weeks)}
+			for i := range m.Weekly {
+				m.Weekly[i] = MuscleWeekVolume{Week: weekKeys[i], WeekStart: weekStarts[i]}
+			}
+			muscles[muscle] = m
+		}
+		m.Weekly[week].Sets += sets
+		m.Weekly[week].TonnageKg += tonnage
+		m.TotalSets += sets
+		m.TotalTonnageKg += tonnage
+	}
+
+	type trendKey struct {
+		id   uint
+		name string
+	}
+	type dayBest struct {
+		date string
+		set  models.WorkoutSetLog
+	}
+	trends := map[trendKey]map[string]models.WorkoutSetLog{}
+
+	for _, l := range logs {
+		y, w := l.PerformedAt.ISOWeek()
+		week, ok := weekIndex[isoKey(y, w)]
+		if !ok {
+			continue
+		}
+		report.TotalSets++
+		tonnage := l.WeightKg * float64(l.Reps)
+		ex := exercises[l.ID]
+		if ex == nil || strings.TrimSpace(ex.Target) == "" {
+			report.UnmappedSets++
+			credit(unmappedMuscle, week, 1, tonnage)
+		} else {
+			target := strings.TrimSpace(ex.Target)
+			credit(target, week, 1, tonnage)
+			for _, m := range decodeStringSlice(ex.SecondaryMuscles) {
+				if m = strings.TrimSpace(m); m != "" && m != target {
+					credit(m, week, secondaryMuscleWeight, tonnage*secondaryMuscleWeight)
+				}
+			}
+			addBalanceSet(&report.Balance, target)
+		}
+
+		if l.WeightKg > 0 {
+			key := trendKey{name: strings.TrimSpace(l.ExerciseName)}
+			if ex != nil {
+				key = trendKey{id: ex.ID, name: ex.Name}
+			}
+			if trends[key] == nil {
+				trends[key] = map[string]models.WorkoutSetLog{}
+			}
+			day := l.PerformedAt.Format("2006-01-02")
+			if cur, ok := trends[key][day]; !ok || epley(l.WeightKg, l.Reps) > epley(cur.WeightKg, cur.Reps) {
+				trends[key][day] = l
+			}
+		}
+	}
+
+	for _, m := range muscles {
+		m.TotalSets = roundTo(m.TotalSets, 0.1)
+		m.TotalTonnageKg = roundTo(m.TotalTonnageKg, 0.1)
+		for i := range m.Weekly {
+			m.Weekly[i].Sets = roundTo(m.Weekly[i].Sets, 0.1)
+			m.Weekly[i].TonnageKg = roundTo(m.Weekly[i].TonnageKg, 0.1)
+		}
+		report.Muscles = append(report.Muscles, *m)
+	}
+	sort.Slice(report.Muscles, func(i, j int) bool {
+		if report.Muscles[i].TotalSets != report.Muscles[j].TotalSets {
+			return report.Muscles[i].TotalSets > report.Muscles[j].TotalSets
+		}
+		return report.Muscles[i].Muscle < report.Muscles[j].Muscle
+	})
+
+	for key, days := range trends {
+		points := make([]dayBest, 0, len(days))
+		for d, l := range days {
+			points = append(points, dayBest{date: d, set: l})
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i].date < points[j].date })
+		trend := ExerciseStrengthTrend{ExerciseName: key.name, Points: make([]StrengthPoint, 0, len(points))}
+		if key.id > 0 {
+			id := key.id
+			trend.ExerciseID = &id
+		}
+		for _, p := range points {
+			trend.Points = append(trend.Points, StrengthPoint{
+				Date:     p.date,
+				Est1RM:   roundTo(epley(p.set.WeightKg, p.set.Reps), 0.1),
+				WeightKg: p.set.WeightKg,
+				Reps:     p.set.Reps,
+			})
+		}
+		if first, last := trend.Points[0].Est1RM, trend.Points[len(trend.Points)-1].Est1RM; first > 0 {
+			trend.ChangePercent = roundTo((last-first)/first*100, 0.1)
+		}
+		report.Strength = append(report.Strength, trend)
+	}
+	// Most-trained exercises first: they are the ones whose trend means something.
+	sort.Slice(report.Strength, func(i, j int) bool {
+		a, b := report.Strength[i], report.Strength[j]
+		if len(a.Points) != len(b.Points) {
+			return len(a.Points) > len(b.Points)
+		}
+		return a.ExerciseName < b.ExerciseName
+	})
+	if len(report.Strength) > maxStrengthTrends {
+		report.Strength = report.Strength[:maxStrengthTrends]
+	}
+
+	finishBalance(&report.Balance)
+	return report
+}
+
+func addBalanceSet(b *VolumeBalance, target string) {
+	t := strings.ToLower(target)
+	switch {
+	case containsFold(pushMuscles, t):
+		b.PushSets++
+		b.UpperSets++
+	case containsFold(pullMuscles, t):
+		b.PullSets++
+		b.UpperSets++
+	case containsFold(upperOnlyMuscles, t):
+		b.UpperSets++
+	case containsFold(lowerMuscles, t):
+		b.LowerSets++
+	case containsFold(coreMuscles, t):
+		b.CoreSets++
+	}
+}
+
+func finishBalance(b *VolumeBalance) {
+	if b.PullSets > 0 {
+		b.PushPullRatio = roundTo(b.PushSets/b.PullSets, 0.01)
+	}
+	if b.LowerSets > 0 {
+		b.UpperLowerRatio = roundTo(b.UpperSets/b.LowerSets, 0.01)
+	}
+	switch {
+	case b.PushSets > 0 && b.PullSets == 0:
+		b.Notes = append(b.Notes, "هیچ ست کششی (پشت/جلو بازو) ثبت نشده است")
+	case b.PushPullRatio > 1.5:
+		b.Notes = append(b.Notes, "حجم حرکات فشاری بیش از ۱.۵ برابر حرکات کششی است")
+	case b.PushPullRatio > 0 && b.PushPullRatio < 0.67:
+		b.Notes = append(b.Notes, "حجم حرکات کششی بیش از ۱.۵ برابر حرکات فشاری است")
+	}
+	switch {
+	case b.UpperSets > 0 && b.LowerSets == 0:
+		b.Notes = append(b.Notes, "هیچ ست پایین‌تنه ثبت نشده است")
+	case b.UpperLowerRatio > 2:
+		b.Notes = append(b.Notes, "حجم بالاتنه بیش از دو برابر پایین‌تنه است")
+	case b.UpperLowerRatio > 0 && b.UpperLowerRatio < 0.5:
+		b.Notes = append(b.Notes, "حجم پایین‌تنه بیش از دو برابر بالاتنه است")
+	}
+}
+
+func containsFold(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
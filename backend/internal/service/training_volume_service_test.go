@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/internal/models"
+)
+
+func TestBuildTrainingVolumeReport(t *testing.T) {
+	now := time.Date(2026, 7, 16, 12, 0, 0, 0, time.UTC) // Thursday
+	bench := &models.Exercise{Model: gorm.Model{ID: 1}, Name: "Bench Press", Target: "سینه ها", SecondaryMuscles: `["سه سر","دلت ها"]`}
+	row := &models.Exercise{Model: gorm.Model{ID: 2}, Name: "Barbell Row", Target: "قسمت بالایی پشت", SecondaryMuscles: `["دوسر بازو"]`}
+
+	set := func(id uint, name string, at time.Time, kg float64, reps int) models.WorkoutSetLog {
+		return models.WorkoutSetLog{Model: gorm.Model{ID: id}, ExerciseName: name, PerformedAt: at, WeightKg: kg, Reps: reps}
+	}
+	lastWeek := now.AddDate(0, 0, -7)
+	logs := []models.WorkoutSetLog{
+		set(1, "Bench Press", lastWeek, 60, 10),
+		set(2, "Bench Press", lastWeek, 60, 8),
+		set(3, "Bench Press", now, 70, 8),
+		set(4, "Barbell Row", now, 50, 10),
+		set(5, "Mystery", now, 0, 20),
+		set(6, "Bench Press", now.AddDate(0, 0, -70), 100, 1), // outside the window
+	}
+	exercises := map[uint]*models.Exercise{1: bench, 2: bench, 3: bench, 4: row}
+
+	r := buildTrainingVolumeReport(logs, exercises, now, 4)
+	if len(r.Weeks) != 4 || r.TotalSets != 5 || r.UnmappedSets != 1 {
+		t.Fatalf("unexpected totals weeks=%v total=%d unmapped=%d", r.Weeks, r.TotalSets, r.UnmappedSets)
+	}
+	byMuscle := map[string]MuscleVolumeSeries{}
+	for _, m := range r.Muscles {
+		byMuscle[m.Muscle] = m
+	}
+	chest := byMuscle["سینه ها"]
+	if chest.TotalSets != 3 || chest.Weekly[2].Sets != 2 || chest.Weekly[3].Sets != 1 || chest.Weekly[3].TonnageKg != 560 {
+		t.Fatalf("unexpected chest volume %+v", chest)
+	}
+	if tri := byMuscle["سه سر"]; tri.TotalSets != 1.5 || tri.TotalTonnageKg != 820 {
+		t.Fatalf("secondary muscles should get half credit, got %+v", tri)
+	}
+	if r.Balance.PushSets != 3 || r.Balance.PullSets != 1 || r.Balance.PushPullRatio != 3 || len(r.Balance.Notes) == 0 {
+		t.Fatalf("unexpected balance %+v", r.Balance)
+	}
+	if len(r.Strength) == 0 || r.Strength[0].ExerciseName != "Bench Press" || len(r.Strength[0].Points) != 2 {
+		t.Fatalf("unexpected strength trends %+v", r.Strength)
+	}
+	if r.Strength[0].ChangePercent <= 0 {
+		t.Fatalf("bench e1RM went up, got change %v", r.Strength[0].ChangePercent)
+	}
+}
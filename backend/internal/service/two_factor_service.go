@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/fitness-management/config"
+	"github.com/yourusername/fitness-management/internal/models"
+	"github.com/yourusername/fitness-management/internal/pkg/digits"
+	"github.com/yourusername/fitness-management/internal/pkg/totp"
+	"github.com/yourusername/fitness-management/internal/repository"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrTwoFactorNotAllowed     = errors.New("two-factor authentication is for admin and coach accounts")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorSetupMissing   = errors.New("two-factor setup has not been started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+)
+
+// TwoFactorStatusDTO is the caller's two-factor state for the security page.
+type TwoFactorStatusDTO struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recoveryCodesLeft"`
+}
+
+// TwoFactorSetupDTO carries the new secret. OTPAuthURI is what the panel
+// renders as a QR code; Secret is for typing in by hand.
+type TwoFactorSetupDTO struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+// TwoFactorRecoveryCodesDTO holds freshly issued recovery codes. They are
+// shown this once.
+type TwoFactorRecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type TwoFactorService interface {
+	Status(ctx context.Context, userID uint) (*TwoFactorStatusDTO, error)
+	// BeginSetup stores a new, not yet enabled TOTP secret for userID.
+	BeginSetup(ctx context.Context, userID uint) (*TwoFactorSetupDTO, error)
+	// Enable turns TOTP on once code proves the app has the secret, and
+	// issues recovery codes.
+	Enable(ctx context.Context, userID uint, code string) (*TwoFactorRecoveryCodesDTO, error)
+	// Disable needs the password and a TOTP or recovery code.
+	Disable(ctx context.Context, userID uint, password, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) (*TwoFactorRecoveryCodesDTO, error)
+	// Enabled reports whether userID has TOTP turned on.
+	Enabled(ctx context.Context, userID uint) (bool, error)
+	// Verify checks a TOTP or recovery code at login. Each code works once.
+	Verify(ctx context.Context, userID uint, method, code string) error
+}
+
+type twoFactorService struct {
+	repo     repository.TwoFactorRepository
+	userRepo repository.UserRepository
+	now      func() time.Time
+}
+
+func NewTwoFactorService(repo repository.TwoFactorRepository, userRepo repository.UserRepository) TwoFactorService {
+	return &twoFactorService{repo: repo, userRepo: userRepo, now: time.Now}
+}
+
+// twoFactorAllowed reports whether role may enroll in TOTP.
+func twoFactorAllowed(role string) bool {
+	return role == models.RoleAdmin || role == models.RoleCoach
+}
+
+func (s *twoFactorService) Status(ctx context.Context, userID uint) (*TwoFactorStatusDTO, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := &TwoFactorStatusDTO{Required: config.TwoFactorRequired(user.Role)}
+	tf, err := s.enabled(ctx, userID)
+	if err != nil && !errors.Is(err, ErrTwoFactorNotEnabled) {
+		return nil, err
+	}
+	if tf != nil {
+		out.Enabled = true
+		if out.RecoveryCodesLeft, err = s.repo.CountUnusedRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (s *twoFactorService) BeginSetup(ctx context.Context, userID uint) (*TwoFactorSetupDTO, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !twoFactorAllowed(user.Role) {
+		return nil, ErrTwoFactorNotAllowed
+	}
+	tf, err := s.repo.Find(ctx, userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		tf = &models.UserTwoFactor{UserID: userID}
+	case err != nil:
+		return nil, err
+	case tf.EnabledAt != nil:
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if tf.Secret, err = totp.GenerateSecret(); err != nil {
+		return nil, err
+	}
+	tf.LastStep = 0
+	if err := s.repo.Save(ctx, tf); err != nil {
+		return nil, err
+	}
+	account := user.Phone
+	if account == "" {
+		account = user.Email
+	}
+	return &TwoFactorSetupDTO{
+		Secret:     tf.Secret,
+		OTPAuthURI: totp.ProvisioningURI(config.Get().Auth.TOTPIssuer, account, tf.Secret),
+	}, nil
+}
+
+func (s *twoFactorService) Enable(ctx context.Context, userID uint, code string) (*TwoFactorRecoveryCodesDTO, error) {
+	tf, err := s.repo.Find(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorSetupMissing
+		}
+		return nil, err
+	}
+	if tf.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if err := s.checkTOTP(ctx, tf, code); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	tf.EnabledAt = &now
+	if err := s.repo.Save(ctx, tf); err != nil {
+		return nil, err
+	}
+	AuditChange(ctx, "two_factor", userID, map[string]any{"enabled": false}, map[string]any{"enabled": true})
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *twoFactorService) Disable(ctx context.Context, userID uint, password, code string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return ErrInvalidPassword
+	}
+	method := models.TwoFactorMethodTOTP
+	if strings.Contains(code, "-") {
+		method = models.TwoFactorMethodRecovery
+	}
+	if err := s.Verify(ctx, userID, method, code); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	AuditChange(ctx, "two_factor", userID, map[string]any{"enabled": true}, map[string]any{"enabled": false})
+	return nil
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) (*TwoFactorRecoveryCodesDTO, error) {
+	if err := s.Verify(ctx, userID, models.TwoFactorMethodTOTP, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *twoFactorService) Enabled(ctx context.Context, userID uint) (bool, error) {
+	tf, err := s.enabled(ctx, userID)
+	if errors.Is(err, ErrTwoFactorNotEnabled) {
+		return false, nil
+	}
+	return tf != nil, err
+}
+
+func (s *twoFactorService) Verify(ctx context.Context, userID uint, method, code string) error {
+	tf, err := s.enabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	switch method {
+	case models.TwoFactorMethodTOTP:
+		return s.checkTOTP(ctx, tf, code)
+	case models.TwoFactorMethodRecovery:
+		ok, err := s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code), s.now())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	default:
+		return ErrInvalidTwoFactorCode
+	}
+}
+
+func (s *twoFactorService) enabled(ctx context.Context, userID uint) (*models.UserTwoFactor, error) {
+	tf, err := s.repo.Find(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorNotEnabled
+		}
+		return nil, err
+	}
+	if tf.EnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return tf, nil
+}
+
+// checkTOTP accepts code once: its time step must be newer than the last
+// accepted one.
+func (s *twoFactorService) checkTOTP(ctx context.Context, tf *models.UserTwoFactor, code string) error {
+	step, ok := totp.Validate(tf.Secret, digits.ToEnglish(code), s.now())
+	if !ok || step <= tf.LastStep {
+		return ErrInvalidTwoFactorCode
+	}
+	advanced, err := s.repo.AdvanceStep(ctx, tf.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidTwoFactorCode
+	}
+	tf.LastStep = step
+	return nil
+}
+
+func (s *twoFactorService) issueRecoveryCodes(ctx context.Context, userID uint) (*TwoFactorRecoveryCodesDTO, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return &TwoFactorRecoveryCodesDTO{RecoveryCodes: codes}, nil
+}
+
+// recoveryAlphabet leaves out i, l, o and 1, which are easy to misread. Its 32
+// characters keep byte%len unbiased.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz023456789"
+
+// newRecoveryCode returns a code like "k7m2p-x9qfa".
+func newRecoveryCode() (string, error) {
+	var b [10]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	out := make([]byte, 0, 11)
+	for i, v := range b {
+		if i == 5 {
+			out = append(out, '-')
+		}
+		out = append(out, recoveryAlphabet[int(v)%len(recoveryAlphabet)])
+	}
+	return string(out), nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(strings.ReplaceAll(code, "-", ""), " ", "")
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
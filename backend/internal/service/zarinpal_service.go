@@ -63,6 +63,9 @@ func (z *ZarinpalClient) sandbox() bool {
 }
 
 func (z *ZarinpalClient) apiBase() string {
+	if base := config.Get().Payments.Zarinpal.APIBaseURL; base != "" {
+		return base
+	}
 	if z.sandbox() {
 		return "https://sandbox.zarinpal.com/pg/v4/payment"
 	}
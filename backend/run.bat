@@ -2,7 +2,8 @@
 setlocal EnableExtensions EnableDelayedExpansion
 
 REM Fitness backend launcher — checks prerequisites step by step, then starts the API server.
-REM Database and tables are created automatically on startup (see config/database.go).
+REM The database is created on startup (see config/database.go); tables come from
+REM the versioned migrations applied by "go run ./cmd/migrate up" below.
 
 cd /d "%~dp0"
 
@@ -70,6 +71,11 @@ go build -o nul ./cmd/app
 if errorlevel 1 call :die "go build ./cmd/app failed"
 call :ok "Build check passed"
 
+call :step "Applying database migrations"
+go run ./cmd/migrate up
+if errorlevel 1 call :die "go run ./cmd/migrate up failed"
+call :ok "Schema is up to date"
+
 call :step "Preparing upload directory"
 if not exist "!UPLOAD_DIR!" mkdir "!UPLOAD_DIR!"
 call :ok "Upload directory ready: !UPLOAD_DIR!\"
@@ -87,7 +93,7 @@ echo   API:     http://localhost:!PORT!
 echo   Swagger: http://localhost:!PORT!/swagger/index.html
 echo   DB:      !DB_USER!@!DB_HOST!:!DB_PORT!/!DB_NAME!
 echo.
-echo   Database and tables are created automatically if missing.
+echo   Schema changes: go run ./cmd/migrate create ^<name^> ^| up ^| down ^| status
 echo   Default admin: admin@gmail.com / 12345678
 echo.
 echo   Press Ctrl+C to stop.
@@ -1,6 +1,7 @@
 #!/usr/bin/env bash
 # Fitness backend launcher — checks prerequisites step by step, then starts the API server.
-# Database and tables are created automatically on startup (see config/database.go).
+# The database is created on startup (see config/database.go); tables come from
+# the versioned migrations applied by `go run ./cmd/migrate up` below.
 
 set -euo pipefail
 
@@ -160,7 +161,15 @@ else
   die "go build ./cmd/app failed"
 fi
 
-# 8. Uploads directory
+# 8. Schema migrations
+step "Applying database migrations"
+if go run ./cmd/migrate up; then
+  ok "Schema is up to date"
+else
+  die "go run ./cmd/migrate up failed"
+fi
+
+# 9. Uploads directory
 step "Preparing upload directory"
 UPLOAD_DIR="$(read_env_var UPLOAD_DIR uploads)"
 mkdir -p "$UPLOAD_DIR"
@@ -179,7 +188,7 @@ echo "  API:     http://localhost:${PORT}"
 echo "  Swagger: http://localhost:${PORT}/swagger/index.html"
 echo "  DB:      ${DB_USER}@${DB_HOST}:${DB_PORT}/${DB_NAME}"
 echo ""
-echo "  Database and tables are created automatically if missing."
+echo "  Schema changes: go run ./cmd/migrate create <name> | up | down | status"
 echo "  Default admin: admin@gmail.com / 12345678"
 echo ""
 echo "Press Ctrl+C to stop."